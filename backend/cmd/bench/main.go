@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/jrudman25/livepulse/internal/benchmarks"
+)
+
+func main() {
+	ops := flag.Int("ops", 1000000, "operations per scenario")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "concurrent workers per scenario")
+	sessions := flag.Int("sessions", 1000, "sessions used by the manager scenario")
+	subscribers := flag.Int("subscribers", 500, "subscribers used by the broadcast scenario")
+	target := flag.Float64("target", 50000, "target peak events/sec used for sizing")
+	asJSON := flag.Bool("json", false, "emit the capacity report as JSON")
+	flag.Parse()
+
+	for name, value := range map[string]int{"ops": *ops, "workers": *workers, "sessions": *sessions, "subscribers": *subscribers} {
+		if value <= 0 {
+			fmt.Fprintf(os.Stderr, "bench: -%s must be positive, got %d\n", name, value)
+			os.Exit(2)
+		}
+	}
+	if *target <= 0 {
+		fmt.Fprintf(os.Stderr, "bench: -target must be positive, got %g\n", *target)
+		os.Exit(2)
+	}
+
+	cores := runtime.GOMAXPROCS(0)
+	results := []benchmarks.Result{
+		benchmarks.ReactionContention(*workers, *ops),
+		benchmarks.ManagerSessions(*sessions, *workers, *ops),
+		benchmarks.QueueThroughput(*workers, *workers, *ops),
+		// Every subscriber receives each message, so ops are split among them
+		benchmarks.BroadcastFanout(*subscribers, max(*ops / *subscribers, 1)),
+	}
+
+	// The slowest ingest-path scenario bounds what one core can sustain
+	bottleneck := results[0]
+	for _, r := range results[:3] {
+		if r.PerCore < bottleneck.PerCore {
+			bottleneck = r
+		}
+	}
+	coresNeeded := int(math.Ceil(*target / bottleneck.PerCore))
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+			"cores":        cores,
+			"results":      results,
+			"bottleneck":   bottleneck.Name,
+			"target":       *target,
+			"cores_needed": coresNeeded,
+		})
+		return
+	}
+
+	fmt.Printf("LivePulse capacity report (%d cores, %d workers)\n\n", cores, *workers)
+	for _, r := range results {
+		fmt.Println(r)
+	}
+	fmt.Printf("\nBottleneck: %s at %.0f events/sec/core\n", bottleneck.Name, bottleneck.PerCore)
+	fmt.Printf("Cores needed for %.0f events/sec: %d\n", *target, coresNeeded)
+}
@@ -0,0 +1,45 @@
+// Command lambda runs LivePulse ingestion as an AWS Lambda function for
+// tenants whose volume does not justify running ingestion servers. It
+// accepts events from API Gateway or SQS, validates them and forwards them
+// to a LivePulse cluster (LAMBDA_SINK=forward) or appends them to the
+// cluster's ingestion stream (LAMBDA_SINK=stream).
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jrudman25/livepulse/config"
+	"github.com/jrudman25/livepulse/internal/serverless"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	var sink serverless.Sink
+	switch cfg.Lambda.Sink {
+	case "forward":
+		if cfg.Lambda.ForwardURL == "" || cfg.Lambda.ForwardToken == "" {
+			log.Fatal("LAMBDA_FORWARD_URL and LAMBDA_FORWARD_TOKEN are required for the forward sink")
+		}
+		sink = serverless.NewForwardSink(cfg.Lambda.ForwardURL, cfg.Lambda.ForwardToken)
+	case "stream":
+		if len(cfg.Stream.Keys) == 0 {
+			log.Fatal("STREAM_KEYS is required for the stream sink")
+		}
+		redisClient, err := storage.NewRedisClient(cfg.Redis.URL)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		sink = serverless.NewStreamSink(redisClient, cfg.Stream.Keys[0], int64(cfg.Lambda.StreamMaxLen))
+	}
+
+	lambda.Start(serverless.NewHandler(sink).Handle)
+}
@@ -0,0 +1,462 @@
+// Command lpctl administers a LivePulse deployment from the terminal: it
+// lists sessions, shows live stats, adds milestones, ends sessions, bans
+// users and tails what a session broadcasts.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+const usage = `Usage: lpctl [flags] <command> [args]
+
+Commands:
+  sessions [-tenant ID] [-status S] [-tag T] [-prefix P]   list sessions
+  stats <session-id>                                       show live stats
+  milestone [-description D] <session-id> <type> <threshold>
+                                                           add a milestone
+  end [-reason R] [-immediate] <session-id>...             end sessions
+  bans <session-id>                                        list bans
+  ban [-reason R] <session-id> <user-id>                   ban a user
+  unban <session-id> <user-id>                             lift a ban
+  tail <session-id>                                        stream broadcasts
+
+Flags:
+`
+
+func main() {
+	server := flag.String("server", envOr("LPCTL_SERVER", "http://localhost:8080"), "LivePulse base URL (LPCTL_SERVER)")
+	token := flag.String("token", os.Getenv("LPCTL_TOKEN"), "admin or moderator session token (LPCTL_TOKEN)")
+	asJSON := flag.Bool("json", false, "print raw JSON responses")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &client{
+		server: strings.TrimSuffix(*server, "/"),
+		token:  *token,
+		json:   *asJSON,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+	commands := map[string]func(*client, []string) error{
+		"sessions":  listSessions,
+		"stats":     showStats,
+		"milestone": addMilestone,
+		"end":       endSessions,
+		"bans":      listBans,
+		"ban":       banUser,
+		"unban":     unbanUser,
+		"tail":      tailBroadcasts,
+	}
+	name, args := flag.Arg(0), flag.Args()[1:]
+	command, exists := commands[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "lpctl: unknown command %q\n\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := command(c, args); err != nil {
+		fmt.Fprintf(os.Stderr, "lpctl %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// client calls the LivePulse HTTP API
+type client struct {
+	server string
+	token  string
+	json   bool
+	http   *http.Client
+}
+
+// request builds an authenticated request, encoding body as JSON if set
+func (c *client) request(method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	target := c.server + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do sends a request and returns the response body, turning error statuses
+// into errors. With -json the body is printed and nil is returned, so
+// callers skip their own formatting.
+func (c *client) do(method, path string, query url.Values, body interface{}) ([]byte, error) {
+	req, err := c.request(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, responseError(resp.StatusCode, data)
+	}
+	if c.json {
+		os.Stdout.Write(data)
+		return nil, nil
+	}
+	return data, nil
+}
+
+// responseError describes a failed response, preferring the API's coded
+// error body over its raw text
+func responseError(status int, body []byte) error {
+	var coded api.ErrorResponse
+	if json.Unmarshal(body, &coded) == nil && coded.Code != "" {
+		return fmt.Errorf("%s (%s, HTTP %d)", coded.Message, coded.Code, status)
+	}
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return fmt.Errorf("%s (HTTP %d)", text, status)
+	}
+	return fmt.Errorf("HTTP %d", status)
+}
+
+// table writes aligned columns to stdout
+func table() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+// parseArgs parses a command's flags and checks its positional arguments
+func parseArgs(fs *flag.FlagSet, args []string, min int, names string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < min {
+		return nil, fmt.Errorf("usage: lpctl %s %s", fs.Name(), names)
+	}
+	return fs.Args(), nil
+}
+
+func listSessions(c *client, args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ContinueOnError)
+	tenant := fs.String("tenant", "", "only sessions of this tenant")
+	status := fs.String("status", "", "only sessions with this status: live, closing or ended")
+	tag := fs.String("tag", "", "only sessions with this tag")
+	prefix := fs.String("prefix", "", "only sessions whose name starts with this")
+	if _, err := parseArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for key, value := range map[string]string{"tenant_id": *tenant, "status": *status, "tag": *tag, "name_prefix": *prefix} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	data, err := c.do(http.MethodGet, "/api/admin/sessions", query, nil)
+	if err != nil || data == nil {
+		return err
+	}
+	var resp struct {
+		Sessions []sessions.Session `json:"sessions"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+
+	tw := table()
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tCREATED\tTAGS")
+	for _, session := range resp.Sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", session.ID, session.Name, session.Status,
+			session.CreatedAt.Local().Format(time.DateTime), strings.Join(session.Tags, ","))
+	}
+	return tw.Flush()
+}
+
+func showStats(c *client, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args, 1, "<session-id>")
+	if err != nil {
+		return err
+	}
+
+	data, err := c.do(http.MethodGet, "/api/sessions/stats", url.Values{"session_id": {positional[0]}}, nil)
+	if err != nil || data == nil {
+		return err
+	}
+	var snapshot aggregation.StatsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	tw := table()
+	fmt.Fprintf(tw, "Session\t%s\n", positional[0])
+	fmt.Fprintf(tw, "Active users\t%d (peak %d, sustained %d)\n", snapshot.ActiveUserCount, snapshot.PeakConcurrentUsers, snapshot.SustainedPeakUsers)
+	if peak := snapshot.Peak; peak != nil {
+		fmt.Fprintf(tw, "Peaked at\t%s, %d reactions/min\n", peak.At.Format(time.RFC3339), peak.ReactionsPerMinute)
+	}
+	fmt.Fprintf(tw, "Unique users\t%d\n", snapshot.UniqueUsers)
+	fmt.Fprintf(tw, "Total reactions\t%d\n", snapshot.TotalReactions)
+	types := make([]events.ReactionType, 0, len(snapshot.ReactionCounts))
+	for reactionType := range snapshot.ReactionCounts {
+		types = append(types, reactionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, reactionType := range types {
+		fmt.Fprintf(tw, "  %s\t%d\n", reactionType, snapshot.ReactionCounts[reactionType])
+	}
+	fmt.Fprintf(tw, "Weighted reactions\t%d (hype %d)\n", snapshot.WeightedReactions, snapshot.HypeScore)
+	if boost := snapshot.Boost; boost != nil {
+		fmt.Fprintf(tw, "Boost\t%dx until %s\n", boost.Multiplier, boost.EndsAt.Format(time.RFC3339))
+	}
+	if !snapshot.StartTime.IsZero() {
+		fmt.Fprintf(tw, "Running for\t%s\n", time.Duration(snapshot.Duration*float64(time.Second)).Round(time.Second))
+	}
+	return tw.Flush()
+}
+
+func addMilestone(c *client, args []string) error {
+	fs := flag.NewFlagSet("milestone", flag.ContinueOnError)
+	description := fs.String("description", "", "text shown when the milestone is reached")
+	positional, err := parseArgs(fs, args, 3, "<session-id> <type> <threshold>")
+	if err != nil {
+		return err
+	}
+	threshold, err := strconv.ParseInt(positional[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("threshold must be a whole number")
+	}
+
+	definitions := []milestones.Definition{{
+		Type:        milestones.MilestoneType(positional[1]),
+		Threshold:   threshold,
+		Description: *description,
+	}}
+	data, err := c.do(http.MethodPost, "/api/admin/sessions/milestones", url.Values{"session_id": {positional[0]}}, definitions)
+	if err != nil || data == nil {
+		return err
+	}
+	var resp struct {
+		Milestones []*milestones.Milestone `json:"milestones"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+
+	tw := table()
+	fmt.Fprintln(tw, "ID\tTYPE\tTHRESHOLD\tACHIEVED")
+	for _, milestone := range resp.Milestones {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%t\n", milestone.ID, milestone.Type, milestone.Threshold, milestone.Achieved)
+	}
+	return tw.Flush()
+}
+
+func endSessions(c *client, args []string) error {
+	fs := flag.NewFlagSet("end", flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason recorded in the action log")
+	immediate := fs.Bool("immediate", false, "skip the late-reaction grace period")
+	positional, err := parseArgs(fs, args, 1, "<session-id>...")
+	if err != nil {
+		return err
+	}
+
+	data, err := c.do(http.MethodPost, "/api/admin/sessions/end", nil, api.BulkEndRequest{
+		SessionIDs: positional,
+		Reason:     *reason,
+		Immediate:  *immediate,
+	})
+	if err != nil || data == nil {
+		return err
+	}
+	var resp struct {
+		SessionIDs  []string `json:"session_ids"`
+		GracePeriod int64    `json:"grace_period_ms"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+
+	ended := make(map[string]bool, len(resp.SessionIDs))
+	for _, sessionID := range resp.SessionIDs {
+		ended[sessionID] = true
+		fmt.Printf("%s ending (grace period %s)\n", sessionID, time.Duration(resp.GracePeriod)*time.Millisecond)
+	}
+	var skipped []string
+	for _, sessionID := range positional {
+		if !ended[sessionID] {
+			skipped = append(skipped, sessionID)
+		}
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("not live, left unchanged: %s", strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+func listBans(c *client, args []string) error {
+	fs := flag.NewFlagSet("bans", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args, 1, "<session-id>")
+	if err != nil {
+		return err
+	}
+
+	data, err := c.do(http.MethodGet, "/api/moderation/bans", url.Values{"session_id": {positional[0]}}, nil)
+	if err != nil || data == nil {
+		return err
+	}
+	var resp struct {
+		Bans []sessions.Ban `json:"bans"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+
+	tw := table()
+	fmt.Fprintln(tw, "USER\tBANNED\tREASON")
+	for _, ban := range resp.Bans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", ban.UserID, ban.BannedAt.Local().Format(time.DateTime), ban.Reason)
+	}
+	return tw.Flush()
+}
+
+func banUser(c *client, args []string) error {
+	fs := flag.NewFlagSet("ban", flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason shown to the user and recorded in the action log")
+	positional, err := parseArgs(fs, args, 2, "<session-id> <user-id>")
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"session_id": {positional[0]}, "user_id": {positional[1]}}
+	if *reason != "" {
+		query.Set("reason", *reason)
+	}
+	data, err := c.do(http.MethodPost, "/api/moderation/bans", query, nil)
+	if err != nil || data == nil {
+		return err
+	}
+	var resp struct {
+		Added bool `json:"added"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if !resp.Added {
+		fmt.Printf("%s was already banned from %s\n", positional[1], positional[0])
+		return nil
+	}
+	fmt.Printf("Banned %s from %s\n", positional[1], positional[0])
+	return nil
+}
+
+func unbanUser(c *client, args []string) error {
+	fs := flag.NewFlagSet("unban", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args, 2, "<session-id> <user-id>")
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"session_id": {positional[0]}, "user_id": {positional[1]}}
+	if _, err := c.do(http.MethodDelete, "/api/moderation/bans", query, nil); err != nil {
+		return err
+	}
+	if !c.json {
+		fmt.Printf("Lifted the ban on %s in %s\n", positional[1], positional[0])
+	}
+	return nil
+}
+
+func tailBroadcasts(c *client, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args, 1, "<session-id>")
+	if err != nil {
+		return err
+	}
+
+	req, err := c.request(http.MethodGet, "/api/admin/sessions/broadcasts", url.Values{"session_id": {positional[0]}}, nil)
+	if err != nil {
+		return err
+	}
+	stop, cancel := signal.NotifyContext(req.Context(), os.Interrupt)
+	defer cancel()
+
+	// The stream stays open until the session's hub drops us or we are interrupted
+	resp, err := http.DefaultClient.Do(req.WithContext(stop))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return responseError(resp.StatusCode, body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if c.json {
+			fmt.Printf("%s\n", line)
+			continue
+		}
+		var header struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(line, &header)
+		fmt.Printf("%s  %-20s %s\n", time.Now().Format(time.TimeOnly), header.Type, line)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(stop.Err(), context.Canceled) {
+		return err
+	}
+	return nil
+}
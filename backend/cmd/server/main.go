@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
@@ -12,10 +13,26 @@ import (
 	"github.com/jrudman25/livepulse/config"
 	"github.com/jrudman25/livepulse/internal/aggregation"
 	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/audit"
+	"github.com/jrudman25/livepulse/internal/cluster"
+	"github.com/jrudman25/livepulse/internal/dashboard"
 	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/diagnostics"
+	"github.com/jrudman25/livepulse/internal/experiments"
+	"github.com/jrudman25/livepulse/internal/exports"
+	"github.com/jrudman25/livepulse/internal/filters"
+	"github.com/jrudman25/livepulse/internal/fraud"
+	"github.com/jrudman25/livepulse/internal/ingest"
+	"github.com/jrudman25/livepulse/internal/metrics"
 	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/moderation"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/sessions"
 	"github.com/jrudman25/livepulse/internal/storage"
-	"github.com/TwiN/go-away"
+	"github.com/jrudman25/livepulse/internal/tokens"
+	"github.com/jrudman25/livepulse/internal/viewers"
+	"github.com/jrudman25/livepulse/internal/waves"
 )
 
 func main() {
@@ -27,6 +44,14 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Dump the effective configuration so misconfigured runs are obvious from the logs
+	if cfg.Profile != "" {
+		log.Printf("Config profile: %s", cfg.Profile)
+	}
+	for _, setting := range cfg.Settings() {
+		log.Printf("config %s=%s (%s)", setting.Key, setting.Value, setting.Source)
+	}
+
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
@@ -38,6 +63,11 @@ func main() {
 	if clerkSecret != "" {
 		api.SetClerkKey(clerkSecret)
 	}
+	api.SetRoles(cfg.Auth.AdminUserIDs, cfg.Auth.ModeratorUserIDs, cfg.Auth.ProducerUserIDs)
+	api.SetIngestKeys(cfg.Auth.IngestKeys)
+	if err := api.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
 	// Initialize Postgres
 	pgClient, err := storage.NewPostgresClient(context.Background(), cfg.Postgres.DatabaseURL)
@@ -51,6 +81,22 @@ func main() {
 	}
 	log.Println("Postgres initialized")
 
+	// Isolated tenants keep archives and viewer history in their own schema
+	tenantDBs := storage.NewTenantDatabases(pgClient)
+	defer tenantDBs.Close()
+	for _, tenant := range cfg.Postgres.IsolatedTenants {
+		tenantDB, err := storage.NewTenantPostgresClient(context.Background(), tenant.DatabaseURL, tenant.Schema)
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres for tenant %s: %v", tenant.TenantID, err)
+		}
+		if err := tenantDB.InitTenantSchema(context.Background(), tenant.Schema); err != nil {
+			tenantDB.Close()
+			log.Fatalf("Failed to init postgres schema for tenant %s: %v", tenant.TenantID, err)
+		}
+		tenantDBs.Isolate(tenant.TenantID, tenantDB)
+		log.Printf("Tenant %s isolated in schema %s", tenant.TenantID, tenant.Schema)
+	}
+
 	// Initialize Redis
 	redisClient, err := storage.NewRedisClient(cfg.Redis.URL)
 	if err != nil {
@@ -59,94 +105,739 @@ func main() {
 	defer redisClient.Close()
 	log.Println("Redis initialized")
 
+	if cfg.Server.Mode == "query" {
+		runQueryServer(cfg, pgClient, tenantDBs, redisClient)
+		return
+	}
+
+	// Create aggregation manager
+	aggManager := aggregation.NewManager()
+	aggManager.SetMaxTrackedUsers(cfg.Session.MaxTrackedUsers)
+	aggManager.SetDimensions(cfg.Session.Dimensions, cfg.Session.DimensionMaxValues)
+	aggManager.SetSustainedPeakWindow(cfg.Session.SustainedPeakWindow)
+
+	// A standby mirrors the primary's stats until the primary fails, then
+	// carries on starting up as the new primary
+	var promoted *cluster.ReplicaState
+	if cfg.Server.Mode == "standby" {
+		standbyCtx, stopStandby := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		state, err := cluster.NewStandby(redisClient, cfg.Cluster.InstanceID, cfg.Cluster.PrimaryLeaseTTL, aggManager).Run(standbyCtx)
+		stopStandby()
+		if err != nil {
+			log.Println("Standby stopped before promotion. Goodbye!")
+			return
+		}
+		promoted = &state
+	}
+
 	// Initialize API Fetcher (Cron)
 	apiFetcher := events.NewAPIFetcher(pgClient, os.Getenv("EXTERNAL_API_KEY"))
 	apiFetcher.Start()
 	defer apiFetcher.Stop()
 
-	// Create event queue
-	eventQueue := events.NewQueue(cfg.Worker.EventQueueSize)
-	log.Printf("Event queue created with size %d", cfg.Worker.EventQueueSize)
+	// Choose the event ID format before any events are created
+	idGenerator, _ := events.GeneratorFor(cfg.Events.IDFormat)
+	events.SetIDGenerator(idGenerator)
 
-	// Create aggregation manager
-	aggManager := aggregation.NewManager()
+	// Create the event transport: the in-process queue, or a shared stream
+	// when LivePulse runs as several cooperating services
+	var eventQueue events.Transport
+	if cfg.Worker.Transport == "redis_stream" {
+		streamTransport, err := events.NewStreamTransport(redisClient, cfg.Worker.StreamKey, cfg.Worker.StreamGroup, cfg.Cluster.InstanceID, int64(cfg.Worker.StreamMaxLen))
+		if err != nil {
+			log.Fatalf("Failed to join event stream %s: %v", cfg.Worker.StreamKey, err)
+		}
+		eventQueue = streamTransport
+		log.Printf("Event transport: stream %s, group %s, consumer %s", cfg.Worker.StreamKey, cfg.Worker.StreamGroup, cfg.Cluster.InstanceID)
+	} else {
+		queue := events.NewQueue(cfg.Worker.EventQueueSize)
+		for priority, types := range map[events.Priority][]string{
+			events.PriorityHigh: cfg.Worker.HighPriorityTypes,
+			events.PriorityLow:  cfg.Worker.LowPriorityTypes,
+		} {
+			for _, name := range types {
+				if _, known := events.NewPayload(events.EventType(name)); !known {
+					log.Fatalf("Unknown event type %q for the %s priority lane", name, priority)
+				}
+				queue.SetPriority(priority, events.EventType(name))
+			}
+		}
+		queue.SetAgingThreshold(events.PriorityNormal, cfg.Worker.NormalAging)
+		queue.SetAgingThreshold(events.PriorityLow, cfg.Worker.LowAging)
+		eventQueue = queue
+		log.Printf("Event queue created with %d events per lane", cfg.Worker.EventQueueSize)
+	}
+
+	// Coordinate session ownership when running multiple instances
+	clusterCtx, clusterCancel := context.WithCancel(context.Background())
+	defer clusterCancel()
+	var coordinator *cluster.Coordinator
+	if cfg.Cluster.Enabled {
+		coordinator = cluster.NewCoordinator(redisClient, cfg.Cluster.InstanceID, cfg.Cluster.LeaseTTL)
+		if err := coordinator.Start(clusterCtx, eventQueue.Enqueue); err != nil {
+			log.Fatalf("Failed to start session ownership coordinator: %v", err)
+		}
+	}
+
+	// Clustered instances checkpoint and restore only the sessions they own
+	var claim func(sessionID string) bool
+	if coordinator != nil {
+		aggManager.SetOwnership(coordinator.Owns)
+		claim = func(sessionID string) bool { return coordinator.Claim(context.Background(), sessionID) }
+
+		// Sessions taken over from a failed owner resume from its checkpoint
+		coordinator.SetHandoff(func(ctx context.Context, sessionID string) {
+			if resumed, err := aggManager.TakeOver(ctx, redisClient, sessionID); err != nil {
+				log.Printf("Error loading checkpoint of taken over session %s: %v", sessionID, err)
+			} else if resumed {
+				log.Printf("Resumed session %s from its previous owner's checkpoint", sessionID)
+			}
+		})
+	}
+
+	// Restore aggregation state from the last checkpoint unless promoted
+	// from standby with fresher mirrored state
+	if promoted != nil {
+		aggManager.ResetConnections()
+		log.Printf("Promoted from standby with %d mirrored sessions", aggManager.GetSessionCount())
+	} else if restored, err := aggManager.Restore(context.Background(), redisClient, claim); err != nil {
+		log.Printf("Error restoring session stats checkpoint: %v", err)
+	} else if restored > 0 {
+		log.Printf("Restored %d sessions from checkpoint", restored)
+	}
+	checkpointCtx, checkpointCancel := context.WithCancel(context.Background())
+	defer checkpointCancel()
+	aggManager.StartCheckpointing(checkpointCtx, redisClient, cfg.Session.CheckpointInterval)
 	log.Println("Aggregation manager initialized")
 
+	// Push session metrics to the configured time-series stores
+	var metricExporters []metrics.Exporter
+	awsCreds := metrics.AWSCredentials{
+		Region:          cfg.Metrics.AWSRegion,
+		AccessKeyID:     cfg.Metrics.AWSAccessKeyID,
+		SecretAccessKey: cfg.Metrics.AWSSecretAccessKey,
+		SessionToken:    cfg.Metrics.AWSSessionToken,
+	}
+	for _, name := range cfg.Metrics.Exporters {
+		switch name {
+		case "influxdb":
+			metricExporters = append(metricExporters, metrics.NewInfluxExporter(cfg.Metrics.InfluxURL, cfg.Metrics.InfluxToken, cfg.Metrics.InfluxOrg, cfg.Metrics.InfluxBucket))
+		case "cloudwatch":
+			metricExporters = append(metricExporters, metrics.NewCloudWatchExporter(awsCreds, cfg.Metrics.CloudWatchNamespace))
+		case "timestream":
+			metricExporters = append(metricExporters, metrics.NewTimestreamExporter(awsCreds, cfg.Metrics.TimestreamDatabase, cfg.Metrics.TimestreamTable))
+		}
+	}
+	metricsCtx, metricsCancel := context.WithCancel(context.Background())
+	defer metricsCancel()
+	metrics.NewPusher(aggManager, metricExporters).Start(metricsCtx, cfg.Metrics.Interval)
+	if len(metricExporters) > 0 {
+		log.Printf("Exporting session metrics to %v every %s", cfg.Metrics.Exporters, cfg.Metrics.Interval)
+	}
+
+	// Create session registry, reaction cap gate and lifecycle webhook notifier
+	sessionRegistry := sessions.NewRegistry()
+	reactionCaps := sessions.NewReactionCaps()
+	// Caps pick up usage from the stats restored or mirrored before promotion
+	reactionCaps.SetHistory(aggManager)
+	notifier := notifications.NewWebhookNotifier(cfg.Webhook.URLs, cfg.Webhook.Secret)
+	// Webhooks are stored in a Postgres outbox and retried until delivered
+	notifier.SetStore(pgClient, notifications.RetryPolicy{
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		Backoff:     cfg.Webhook.RetryBackoff,
+		MaxBackoff:  cfg.Webhook.MaxRetryBackoff,
+	})
+	notifierCtx, notifierCancel := context.WithCancel(context.Background())
+	defer notifierCancel()
+	notifier.Start(notifierCtx, cfg.Webhook.PollInterval)
+	// Session webhooks are stored too, so they outlive restarts and failovers
+	notifier.SetSessionWebhookStore(pgClient)
+	if restored, err := notifier.LoadSessionWebhooks(context.Background()); err != nil {
+		log.Printf("Error restoring session webhooks: %v", err)
+	} else if restored > 0 {
+		log.Printf("Restored %d session webhooks", restored)
+	}
+
+	// Alert subscribed devices when a session goes live or reaches a milestone
+	var pushProviders []notifications.PushProvider
+	if cfg.Push.FCMCredentialsFile != "" {
+		creds, err := os.ReadFile(cfg.Push.FCMCredentialsFile)
+		if err != nil {
+			log.Fatalf("Failed to read FCM credentials: %v", err)
+		}
+		fcm, err := notifications.NewFCMProvider(creds)
+		if err != nil {
+			log.Fatalf("Invalid FCM credentials: %v", err)
+		}
+		pushProviders = append(pushProviders, fcm)
+	}
+	if cfg.Push.APNsKeyFile != "" {
+		key, err := os.ReadFile(cfg.Push.APNsKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to read APNs key: %v", err)
+		}
+		apns, err := notifications.NewAPNsProvider(key, cfg.Push.APNsKeyID, cfg.Push.APNsTeamID, cfg.Push.APNsTopic, cfg.Push.APNsSandbox)
+		if err != nil {
+			log.Fatalf("Invalid APNs key: %v", err)
+		}
+		pushProviders = append(pushProviders, apns)
+	}
+	var pushNotifier *notifications.PushNotifier
+	if len(pushProviders) > 0 {
+		pushNotifier = notifications.NewPushNotifier(pgClient, pushProviders...)
+		notifier.AddSink(pushNotifier)
+		log.Printf("Sending push alerts through %d provider(s)", len(pushProviders))
+	}
+
+	// Create fraud guard enforcing per-user reaction limits
+	fraudCtx, fraudCancel := context.WithCancel(context.Background())
+	defer fraudCancel()
+	fraudGuard := fraud.NewGuard(pgClient, fraud.Limits{
+		ReactionsPerSecond:       cfg.Fraud.ReactionsPerSecond,
+		StrictReactionsPerSecond: cfg.Fraud.StrictReactionsPerSecond,
+		Burst:                    cfg.Fraud.Burst,
+		StrictScore:              cfg.Fraud.StrictScore,
+		ShadowScore:              cfg.Fraud.ShadowScore,
+	})
+	fraudGuard.Start(fraudCtx, cfg.Fraud.FlushInterval)
+
+	// Record viewer history off the pipeline, classifying joins as first-time
+	// or returning viewers once each batch is written
+	viewerCtx, viewerCancel := context.WithCancel(context.Background())
+	defer viewerCancel()
+	viewerRecorder := viewers.NewRecorder(
+		func(tenantID string) viewers.Store { return tenantDBs.For(tenantID) },
+		func(visit storage.ViewerVisit, kind string) { aggManager.ClassifyViewer(visit.SessionID, visit.UserID, kind) },
+	)
+	viewerRecorder.Start(viewerCtx, cfg.Postgres.ViewerFlushInterval)
+	sourceTracker := fraud.NewSourceTracker(cfg.Fraud.SourceSketchSize)
+
 	// Create WebSocket hub
+	api.SetHeartbeat(api.HeartbeatConfig{
+		PingInterval: cfg.WebSocket.PingInterval,
+		PongTimeout:  cfg.WebSocket.PongTimeout,
+		WriteTimeout: cfg.WebSocket.WriteTimeout,
+	})
 	wsHub := api.NewWebSocketHub()
+	if coordinator != nil {
+		// Relay broadcasts so every node serving a session sends the same stream
+		wsHub.SetRelay(cluster.NewRelay(clusterCtx, redisClient, cfg.Cluster.InstanceID))
+	}
 	log.Println("WebSocket hub initialized")
 
-	// Create milestone tracker with notification handler
-	tracker := milestones.NewTracker(func(achievement *milestones.MilestoneAchievement) {
+	// Coalesce stat changes into paced stats_update broadcasts
+	schedulerCtx, schedulerCancel := context.WithCancel(context.Background())
+	defer schedulerCancel()
+	scheduler := api.NewBroadcastScheduler(wsHub, aggManager, sessionRegistry, cfg.Broadcast.MinInterval, cfg.Broadcast.MaxInterval)
+	scheduler.SetAnimationBudget(cfg.Broadcast.AnimationBudget)
+	diagnostics.Label("broadcast", func() { scheduler.Start(schedulerCtx) })
+
+	// Milestones achieved together are celebrated one after another
+	celebrations := api.NewCelebrationCoordinator(wsHub, cfg.Broadcast.CelebrationGap)
+	diagnostics.Label("celebrations", func() { celebrations.Start(schedulerCtx) })
+
+	// Create milestone tracker; achievements are queued for celebration in
+	// every client of the session and delivered to webhook endpoints
+	tracker := milestones.NewTracker(celebrations.Broadcaster(func(achievement *milestones.MilestoneAchievement) {
 		log.Printf("MILESTONE ACHIEVED: %s - %s", achievement.SessionID, achievement.Milestone.Description)
 
-		// Broadcast to WebSocket clients
-		wsHub.BroadcastToSession(achievement.SessionID, map[string]interface{}{
-			"type":        "milestone_achieved",
-			"milestone":   achievement.Milestone,
-			"achieved_at": achievement.AchievedAt,
+		notifier.Notify(notifications.Event{
+			Type:       notifications.TypeMilestoneAchieved,
+			SessionID:  achievement.SessionID,
+			OccurredAt: achievement.AchievedAt,
+			Data:       achievement,
 		})
-	})
+	}))
+	tracker.SetUnlockHandler(api.MilestoneUnlockBroadcaster(wsHub, func(unlock *milestones.MilestoneUnlock) {
+		notifier.Notify(notifications.Event{
+			Type:       notifications.TypeMilestoneUnlocked,
+			SessionID:  unlock.SessionID,
+			OccurredAt: unlock.UnlockedAt,
+			Data:       unlock,
+		})
+	}))
+	tracker.SetCountSystemReactions(cfg.Milestone.CountSystemReactions)
+	if cfg.Milestone.Persist {
+		tracker.SetStore(redisClient, cfg.Milestone.PersistAttempts)
+	}
+	if promoted != nil {
+		sessionRegistry.Restore(promoted.Sessions)
+		tracker.Restore(promoted.Milestones)
+	} else if loaded, err := tracker.Load(context.Background()); err != nil {
+		log.Printf("Error loading persisted milestones: %v", err)
+	} else if loaded > 0 {
+		log.Printf("Loaded milestones of %d sessions", loaded)
+	}
+	milestoneCtx, milestoneCancel := context.WithCancel(context.Background())
+	defer milestoneCancel()
+	if cfg.Milestone.Persist {
+		tracker.StartPersisting(milestoneCtx, cfg.Milestone.PersistInterval)
+	}
 	log.Println("Milestone tracker initialized")
 
-	// Create event handler
-	eventHandler := func(event *events.Event) error {
-		// Update aggregation
-		aggManager.ProcessEvent(event)
+	// Export live sessions to S3 and webhooks on the configured schedules
+	exportCtx, exportCancel := context.WithCancel(context.Background())
+	defer exportCancel()
+	if len(cfg.Export.Schedules) > 0 {
+		schedules := make([]exports.Schedule, 0, len(cfg.Export.Schedules))
+		for _, expr := range cfg.Export.Schedules {
+			schedule, err := exports.ParseSchedule(expr)
+			if err != nil {
+				log.Fatalf("Invalid EXPORT_SCHEDULES: %v", err)
+			}
+			schedules = append(schedules, schedule)
+		}
+		kinds := make([]exports.Kind, 0, len(cfg.Export.Kinds))
+		for _, kind := range cfg.Export.Kinds {
+			kinds = append(kinds, exports.Kind(kind))
+		}
+		var destinations []exports.Destination
+		if cfg.Export.S3Bucket != "" {
+			destinations = append(destinations, exports.NewS3Destination(awsCreds, cfg.Export.S3Bucket, cfg.Export.S3Prefix))
+		}
+		if cfg.Export.Webhooks {
+			destinations = append(destinations, exports.NewWebhookDestination(notifier))
+		}
+		exporter := exports.NewExporter(schedules, kinds, sessionRegistry, aggManager, tracker, destinations)
+		exporter.Start(exportCtx, cfg.Export.Timeout)
+		log.Printf("Exporting %v of live sessions on %v, next at %s", cfg.Export.Kinds, cfg.Export.Schedules, exporter.Next(time.Now()).Format(time.RFC3339))
+	}
+
+	// Deployment-specific milestone types, evaluated alongside the built-ins
+	if err := milestones.RegisterEvaluator("average_watch_minutes", "minutes average watch time", milestones.AverageWatchMinutes); err != nil {
+		log.Fatalf("Failed to register milestone type: %v", err)
+	}
+
+	// Create campaign tracker for milestones spanning multiple sessions
+	campaignTracker := milestones.NewCampaignTracker(func(achievement *milestones.CampaignAchievement) {
+		log.Printf("CAMPAIGN MILESTONE ACHIEVED: %s - %s", achievement.Campaign.ID, achievement.Milestone.Description)
+
+		for _, sessionID := range achievement.Campaign.SessionIDs {
+			wsHub.BroadcastToSession(sessionID, api.NewCampaignMilestoneAchievedMessage(achievement))
+		}
+		notifier.Notify(notifications.Event{
+			Type:       notifications.TypeCampaignMilestoneAchieved,
+			OccurredAt: achievement.AchievedAt,
+			Data:       achievement,
+		})
+	})
+
+	// Audit a sample of sessions by recomputing stats from their raw events
+	auditCtx, auditCancel := context.WithCancel(context.Background())
+	defer auditCancel()
+	var auditor *audit.Auditor
+	if cfg.Audit.Enabled {
+		auditor = audit.NewAuditor(aggManager, redisClient, cfg.Audit.SampleRate)
+		auditor.SetEncoding(events.Encoding(cfg.Audit.Encoding))
+		auditor.Start(auditCtx, cfg.Audit.Interval)
+		log.Printf("Audit mode enabled for %.1f%% of sessions", cfg.Audit.SampleRate*100)
+	}
+
+	// Check archived totals of audited sessions against their raw events,
+	// including archives kept in isolated tenant schemas
+	var reconciler *audit.Reconciler
+	if auditor != nil && cfg.Audit.ReconcileInterval > 0 {
+		archives := []audit.Archive{pgClient}
+		for _, tenant := range cfg.Postgres.IsolatedTenants {
+			archives = append(archives, tenantDBs.For(tenant.TenantID))
+		}
+		reconciler = audit.NewReconciler(auditor, archives, cfg.Audit.ReconcileLookback)
+		reconciler.Start(auditCtx, cfg.Audit.ReconcileInterval)
+	}
+
+	// Normalize client-set timestamps against the server clock
+	latePolicy, _ := events.ParseLatePolicy(cfg.Events.LatePolicy)
+	skewPolicy := events.SkewPolicy{MaxSkew: cfg.Events.MaxSkew, Late: latePolicy}
+
+	// Accept/deny rules for incoming events, editable at runtime by admins
+	var filterRules []filters.Rule
+	if cfg.Events.FilterRules != "" {
+		if err := json.Unmarshal([]byte(cfg.Events.FilterRules), &filterRules); err != nil {
+			log.Fatalf("Invalid EVENT_FILTER_RULES: %v", err)
+		}
+	}
+	filterEngine, err := filters.NewEngine(filterRules)
+	if err != nil {
+		log.Fatalf("Invalid EVENT_FILTER_RULES: %v", err)
+	}
+
+	// Client-supplied event IDs may be retried, so drop repeats within the window
+	externalDeduper := events.NewDeduper(cfg.Stream.IdempotencyWindow)
+
+	// Stream processed events to standbys; a promoted standby keeps
+	// replicating so another standby can follow it
+	replicationCtx, replicationCancel := context.WithCancel(context.Background())
+	defer replicationCancel()
+	var primary *cluster.Primary
+	if cfg.Cluster.Replicate || promoted != nil {
+		primary = cluster.NewPrimary(redisClient, cfg.Cluster.InstanceID, cfg.Cluster.PrimaryLeaseTTL, func() cluster.ReplicaState {
+			return cluster.ReplicaState{
+				Sessions:   sessionRegistry.All(),
+				Milestones: tracker.Export(),
+			}
+		})
+		primary.Start(replicationCtx)
+	}
+
+	experimentManager := experiments.NewManager()
+
+	// Reaction waves measure participation against the audience present
+	// when the wave's window opens, and announce the result when it closes
+	waveManager := waves.NewManager(func(sessionID string) int {
+		if stats, exists := aggManager.GetSession(sessionID); exists {
+			return stats.GetActiveUserCount()
+		}
+		return 0
+	}, func(wave waves.Wave) {
+		log.Printf("Wave %s in session %s: %d of %d viewers took part", wave.ID, wave.SessionID, wave.Participants, wave.Audience)
+		wsHub.BroadcastToSession(wave.SessionID, api.NewWaveResultMessage(wave))
+	})
+	eventFeed := events.NewFeed(cfg.Events.FeedRetain)
+
+	// Build the event pipeline: shared admission stages run for every event,
+	// then the stages registered for its type
+	workerPool := events.NewWorkerPool(eventQueue, cfg.Worker.Count, nil)
+	workerPool.SetStageTimeout(cfg.Worker.StageTimeout)
+	workerPool.SetSessionLimits(cfg.Worker.SessionConcurrency, cfg.Worker.SessionBacklog)
+
+	systemSources := make(map[string]bool, len(cfg.Events.SystemSources))
+	for _, source := range cfg.Events.SystemSources {
+		systemSources[source] = true
+	}
+	admit := func(ctx context.Context, event *events.Event) error {
+		// Malformed or reserved session IDs never reach aggregation
+		if err := sessions.ValidateID(event.SessionID); err != nil {
+			log.Printf("Dropping event %s: %v", event.ID, err)
+			return events.ErrSkip
+		}
+		// Events sent to an alias count toward the session it mirrors
+		event.SessionID = sessionRegistry.Canonical(event.SessionID)
+		if !skewPolicy.Apply(event, time.Now().UTC()) {
+			return events.ErrSkip
+		}
+		if event.External && externalDeduper.Seen(event.ID) {
+			return events.ErrSkip
+		}
+		if !filterEngine.Apply(event) {
+			return events.ErrSkip
+		}
+
+		// Forward to the owning instance if another instance aggregates this session
+		if coordinator != nil {
+			local, err := coordinator.Route(ctx, event)
+			if err != nil {
+				log.Printf("Error routing event %s, processing locally: %v", event.ID, err)
+			}
+			if !local {
+				return events.ErrSkip
+			}
+		}
+
+		// Events for ended or deleted sessions are discarded rather than
+		// reviving them
+		if sessionRegistry.IsEnded(event.SessionID) || sessionRegistry.IsDeleted(event.SessionID) {
+			return events.ErrSkip
+		}
+		// Only configured integrations emit system events, and only they
+		// speak as a system identity
+		if event.IsSystem() && !systemSources[event.Source] {
+			return events.ErrSkip
+		}
+		if event.ClaimsSystemIdentity() {
+			return events.ErrSkip
+		}
+		// Banned users may only leave
+		if event.Type != events.EventTypeLeaveSession && sessionRegistry.IsBanned(event.SessionID, event.UserID) {
+			return events.ErrSkip
+		}
+		sessionRegistry.Touch(event.SessionID)
+		sourceTracker.Record(event)
+		return nil
+	}
+
+	// Closing sessions only accept late reactions from already joined users.
+	// Joins are classified first-time or returning for the session's viewer split.
+	// Fraud tiers: repeat offenders get stricter limits or shadow-counting.
+	admitJoin := func(ctx context.Context, event *events.Event) error {
+		if !sessionRegistry.AcceptsJoins(event.SessionID) {
+			return events.ErrSkip
+		}
+		fraudGuard.OnJoin(ctx, event.UserID)
 
-		// Check milestones
+		// Viewer history is written in batches; the join is classified once
+		// the tenant's history has been consulted
+		session, _ := sessionRegistry.Get(event.SessionID)
+		viewerRecorder.Record(storage.ViewerVisit{TenantID: session.TenantID, UserID: event.UserID, SessionID: event.SessionID, SeenAt: event.Timestamp})
+		return nil
+	}
+	admitReaction := func(_ context.Context, event *events.Event) error {
+		// Integrations are trusted and counted apart, outside fraud and caps
+		if event.IsSystem() {
+			return nil
+		}
+		if verdict := fraudGuard.CheckReaction(event.SessionID, event.UserID); verdict != fraud.VerdictAllow {
+			if verdict.Err() != nil {
+				wsHub.SendToUser(event.SessionID, event.UserID, api.NewReactionRateLimitedMessage(event.SessionID, event.ID))
+			}
+			return events.ErrSkip
+		}
+		session, _ := sessionRegistry.Get(event.SessionID)
+		verdict, soldOut := reactionCaps.Admit(session, event.UserID)
+		if verdict != sessions.CapAllow {
+			wsHub.SendToUser(event.SessionID, event.UserID, api.NewReactionRejectedMessage(session, event.ID, verdict))
+			return events.ErrSkip
+		}
+		if soldOut {
+			log.Printf("Session %s reached its reaction cap of %d", event.SessionID, session.ReactionCap)
+			wsHub.BroadcastToSession(event.SessionID, api.NewReactionCapReachedMessage(session))
+		}
+		// Waves count distinct participants, so they see the reaction before
+		// anonymity drops its sender
+		waveManager.Observe(event)
+
+		// Anonymous sessions drop the sender once fraud and caps have used it
+		if session.Features.AnonymousReactions {
+			event.UserID = ""
+		}
+		return nil
+	}
+
+	// Bucket the user into running experiments, then update aggregation,
+	// logging raw events of audited sessions
+	aggregate := func(ctx context.Context, event *events.Event) error {
+		experimentManager.Apply(event)
+		if auditor != nil {
+			auditor.Record(ctx, event)
+		}
+		if err := aggManager.ProcessEvent(event); err != nil {
+			return err
+		}
+		eventFeed.Publish(event)
+		if primary != nil {
+			primary.Replicate(ctx, event)
+		}
+		return nil
+	}
+
+	checkMilestones := func(_ context.Context, event *events.Event) error {
 		if stats, exists := aggManager.GetSession(event.SessionID); exists {
 			tracker.CheckMilestones(event.SessionID, stats)
+			campaignTracker.Observe(event.SessionID, stats)
 		}
+		return nil
+	}
 
-		// Broadcast event to WebSocket clients
-		switch event.Type {
-		case events.EventTypeReaction:
-			if reactionType, ok := event.GetReactionType(); ok {
-				wsHub.BroadcastToSession(event.SessionID, map[string]interface{}{
-					"type":          "reaction",
-					"user_id":       event.UserID,
-					"reaction_type": reactionType,
-					"timestamp":     event.Timestamp,
-				})
-			}
-		case events.EventTypeChat:
-			if text, authorName, ok := event.GetChatText(); ok {
-				// Censor profanity using go-away
-				cleanText := goaway.Censor(text)
-
-				chatMsg := &storage.ChatMessage{
-					ID:         event.ID,
-					UserID:     event.UserID,
-					SessionID:  event.SessionID,
-					Text:       cleanText,
-					AuthorName: authorName,
-					Timestamp:  event.Timestamp,
-				}
-				
-				// Save to Redis
-				if err := redisClient.SaveChatMessage(context.Background(), event.SessionID, chatMsg); err != nil {
-					log.Printf("Error saving chat message to redis: %v", err)
-				}
+	// Presence changes reach clients with the next paced stats_update
+	markPresence := func(_ context.Context, event *events.Event) error {
+		scheduler.MarkChanged(event.SessionID)
+		return nil
+	}
+	markReaction := func(_ context.Context, event *events.Event) error {
+		if reactionType, ok := event.GetReactionType(); ok {
+			scheduler.MarkReaction(event.SessionID, reactionType)
+		}
+		return nil
+	}
+
+	// Chat text passes through the configured content filters in order
+	contentFilters := moderation.Chain{}
+	if action := moderation.Action(cfg.Content.ProfanityAction); action != moderation.ActionAllow {
+		contentFilters = append(contentFilters, moderation.NewProfanityFilter(action))
+	}
+	if action := moderation.Action(cfg.Content.WordsAction); len(cfg.Content.Words) > 0 && action != moderation.ActionAllow {
+		contentFilters = append(contentFilters, moderation.NewWordListFilter(action, cfg.Content.Words))
+	}
+	if action := moderation.Action(cfg.Content.PatternAction); cfg.Content.Pattern != "" && action != moderation.ActionAllow {
+		patternFilter, err := moderation.NewPatternFilter(action, []string{cfg.Content.Pattern})
+		if err != nil {
+			log.Fatalf("Failed to create content filter: %v", err)
+		}
+		contentFilters = append(contentFilters, patternFilter)
+	}
+	if cfg.Content.URL != "" {
+		contentFilters = append(contentFilters, moderation.NewHTTPFilter(cfg.Content.URL, cfg.Content.Timeout))
+	}
+
+	moderateChat := func(ctx context.Context, event *events.Event) error {
+		text, authorName, ok := event.GetChatText()
+		if !ok {
+			return events.ErrSkip
+		}
 
-				// Broadcast
-				wsHub.BroadcastToSession(event.SessionID, map[string]interface{}{
-					"type":    "chat",
-					"message": chatMsg,
-				})
+		// Filter the text; rejected messages are only reported to their author
+		verdict := contentFilters.Check(ctx, moderation.Content{
+			SessionID: event.SessionID,
+			UserID:    event.UserID,
+			EventType: event.Type,
+			Text:      text,
+		})
+		if verdict.Action == moderation.ActionReject {
+			log.Printf("Content filter %s rejected chat message %s: %s", verdict.Filter, event.ID, verdict.Reason)
+			wsHub.SendToUser(event.SessionID, event.UserID, api.NewChatRejectedMessage(event.SessionID, event.ID))
+			return events.ErrSkip
+		}
+		chatMsg := &storage.ChatMessage{
+			ID:         event.ID,
+			UserID:     event.UserID,
+			SessionID:  event.SessionID,
+			Text:       verdict.Text,
+			AuthorName: authorName,
+			Timestamp:  event.Timestamp,
+		}
+
+		// Save to Redis
+		if err := redisClient.SaveChatMessage(ctx, event.SessionID, chatMsg); err != nil {
+			log.Printf("Error saving chat message to redis: %v", err)
+		}
+		if verdict.Action == moderation.ActionFlag {
+			flagged := storage.FlaggedMessage{ChatMessage: *chatMsg, Filter: verdict.Filter, Reason: verdict.Reason, FlaggedAt: time.Now().UTC()}
+			if err := redisClient.SaveFlaggedMessage(ctx, event.SessionID, flagged); err != nil {
+				log.Printf("Error queueing flagged chat message %s: %v", event.ID, err)
 			}
 		}
 
+		// Broadcast
+		wsHub.BroadcastToSession(event.SessionID, map[string]interface{}{
+			"type":    "chat",
+			"message": chatMsg,
+		})
 		return nil
 	}
 
-	// Create and start worker pool
-	workerPool := events.NewWorkerPool(eventQueue, cfg.Worker.Count, eventHandler)
-	workerPool.Start()
+	workerPool.Use(admit)
+	workerPool.Handle(events.EventTypeJoinSession, admitJoin, aggregate, checkMilestones, markPresence)
+	workerPool.Handle(events.EventTypeLeaveSession, aggregate, checkMilestones, markPresence)
+	workerPool.Handle(events.EventTypePresence, aggregate, checkMilestones, markPresence)
+	workerPool.Handle(events.EventTypeReaction, admitReaction, aggregate, checkMilestones, markReaction)
+	workerPool.Handle(events.EventTypeChat, sessionRegistry.AdmitFeatures, aggregate, checkMilestones, moderateChat)
+	workerPool.Handle(events.EventTypePollVote, sessionRegistry.AdmitFeatures, aggregate)
+
+	// Start worker pool
+	diagnostics.Label("workers", workerPool.Start)
 	log.Printf("Worker pool started with %d workers", cfg.Worker.Count)
 
+	// Events a previous instance could not drain before its deadline are
+	// spilled to Redis; pick them up before new traffic arrives
+	workerPool.SetSpill(events.StoreSpill(redisClient, events.Encoding(cfg.Audit.Encoding)))
+	if replayed, err := events.ReplaySpilled(context.Background(), redisClient, eventQueue); err != nil {
+		log.Printf("Error replaying spilled events: %v", err)
+	} else if replayed > 0 {
+		log.Printf("Replayed %d events spilled at the last shutdown", replayed)
+	}
+
+	// Consume upstream event streams, committing offsets only after processing
+	streamCtx, streamCancel := context.WithCancel(context.Background())
+	defer streamCancel()
+	streamDone := make(chan struct{})
+	if cfg.Stream.Enabled {
+		source := ingest.NewRedisStreamSource(redisClient, cfg.Stream.Keys)
+		consumer := ingest.NewConsumer(cfg.Stream.ConsumerName, source, redisClient, workerPool.Process, cfg.Stream.IdempotencyWindow, cfg.Stream.CommitInterval)
+		go diagnostics.Label("ingest", func() {
+			defer close(streamDone)
+			if err := consumer.Run(streamCtx); err != nil {
+				log.Printf("Stream consumer stopped: %v", err)
+			}
+		})
+		log.Printf("Stream consumer %s reading %v", cfg.Stream.ConsumerName, cfg.Stream.Keys)
+	} else {
+		close(streamDone)
+	}
+
+	// Subscribe to venue hardware publishing over MQTT
+	mqttDone := make(chan struct{})
+	if cfg.MQTT.Enabled {
+		patterns := make([]ingest.TopicPattern, 0, len(cfg.MQTT.Topics))
+		for _, topic := range cfg.MQTT.Topics {
+			pattern, err := ingest.ParseTopicPattern(topic)
+			if err != nil {
+				log.Fatalf("Invalid MQTT_TOPICS: %v", err)
+			}
+			patterns = append(patterns, pattern)
+		}
+		subscriber := ingest.NewMQTTSubscriber(cfg.MQTT.BrokerURL, cfg.MQTT.ClientID, patterns, byte(cfg.MQTT.QoS), cfg.MQTT.KeepAlive, workerPool.Process)
+		go diagnostics.Label("ingest", func() {
+			defer close(mqttDone)
+			if err := subscriber.Run(streamCtx); err != nil {
+				log.Printf("MQTT subscriber stopped: %v", err)
+			}
+		})
+		log.Printf("MQTT subscriber %s reading %v", cfg.MQTT.ClientID, cfg.MQTT.Topics)
+	} else {
+		close(mqttDone)
+	}
+
 	// Create API server
-	apiServer := api.NewServer(eventQueue, aggManager, tracker, wsHub, pgClient, apiFetcher)
+	apiServer := api.NewServer(eventQueue, aggManager, tracker, wsHub, pgClient, apiFetcher, sessionRegistry, notifier)
+	apiServer.SetCloseGracePeriod(cfg.Session.CloseGracePeriod)
+	// Boosts running when the sessions were restored still announce their end
+	aggManager.SetAdoptHandler(apiServer.ResumeBoost)
+	apiServer.ResumeBoosts()
+	apiServer.SetPurgeAfter(cfg.Session.PurgeAfter)
+	purgeCtx, purgeCancel := context.WithCancel(context.Background())
+	defer purgeCancel()
+	apiServer.StartPurging(purgeCtx)
+	apiServer.SetStatsCache(cfg.Session.StatsCacheSize, cfg.Session.StatsCacheMaxAge)
+	apiServer.SetCampaignTracker(campaignTracker)
+	apiServer.SetAuditor(auditor)
+	apiServer.SetReconciler(reconciler)
+	apiServer.SetFilterEngine(filterEngine)
+	apiServer.SetExperimentManager(experimentManager)
+	apiServer.SetWaveManager(waveManager)
+	apiServer.SetEventFeed(eventFeed)
+	apiServer.SetWorkerPool(workerPool)
+	if pushNotifier != nil {
+		apiServer.SetPushSubscriptions(pgClient, pushNotifier)
+	}
+	apiServer.SetActionLog(pgClient)
+	apiServer.SetFlagQueue(redisClient)
+	apiServer.SetTenantDatabases(tenantDBs)
+	apiServer.SetReactionCaps(reactionCaps)
+	apiServer.SetSourceTracker(sourceTracker)
+	apiServer.SetFraudGuard(fraudGuard)
+	apiServer.SetSystemSources(cfg.Events.SystemSources)
+	if cfg.Client.MinVersion != "" {
+		minVersion, ok := events.ParseClientVersion(cfg.Client.MinVersion)
+		if !ok {
+			log.Fatalf("CLIENT_MIN_VERSION %q is not a version such as 2.4.0", cfg.Client.MinVersion)
+		}
+		apiServer.SetMinClientVersion(minVersion, cfg.Client.RequireVersion)
+	}
+	apiServer.SetOverlaySecret(cfg.Overlay.Secret, cfg.Overlay.TokenTTL, cfg.Overlay.PushInterval)
+	if len(cfg.Viewer.KeyFiles) > 0 {
+		keys, err := tokens.LoadKeyFiles(cfg.Viewer.KeyFiles)
+		if err != nil {
+			log.Fatalf("Failed to load viewer token keys: %v", err)
+		}
+		viewerTokens, err := tokens.NewService(cfg.Viewer.Issuer, cfg.Viewer.TTL, keys)
+		if err != nil {
+			log.Fatalf("Invalid viewer token keys: %v", err)
+		}
+		apiServer.SetViewerTokens(viewerTokens)
+
+		// SIGHUP rereads the key files, so keys rotate without a restart
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		go func() {
+			for range reload {
+				keys, err := tokens.LoadKeyFiles(cfg.Viewer.KeyFiles)
+				if err == nil {
+					err = viewerTokens.SetKeys(keys)
+				}
+				if err != nil {
+					log.Printf("Viewer token key reload failed, keeping the current keys: %v", err)
+					continue
+				}
+				log.Printf("Viewer token keys reloaded, signing with %s", viewerTokens.JWKS().Keys[0].Kid)
+			}
+		}()
+	}
+	apiServer.SetJSONNaming(schema.Naming(cfg.Server.JSONNaming))
+	if len(cfg.Cluster.HubNodes) > 0 {
+		apiServer.SetHubRing(cluster.NewRing(cluster.DefaultReplicas, cfg.Cluster.HubNodes...))
+	}
+
+	// Read APIs are rate limited so dashboard polling cannot starve the rest
+	readLimiter := newReadLimiter(cfg)
 
 	// Set up HTTP routes
 	mux := http.NewServeMux()
@@ -157,12 +848,34 @@ func main() {
 	// Session management
 	mux.HandleFunc("/api/sessions", api.Chain(apiServer.HandleCreateSession, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware))
 	mux.HandleFunc("/api/sessions/join", api.Chain(apiServer.HandleJoinSession, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware))
-	mux.HandleFunc("/api/sessions/stats", api.Chain(apiServer.HandleGetStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware))
-	mux.HandleFunc("/api/sessions/milestones", api.Chain(apiServer.HandleGetMilestones, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware))
+	mux.HandleFunc("/api/sessions/stats", api.Chain(apiServer.HandleGetStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/milestones", api.Chain(apiServer.HandleGetMilestones, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/labels", api.Chain(apiServer.HandleGetLabels, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/reactions/by-minute", api.Chain(apiServer.HandleGetReactionsByMinute, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/retention", api.Chain(apiServer.HandleGetRetentionCurve, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/archive", api.Chain(apiServer.HandleGetSessionArchive, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/compare", api.Chain(apiServer.HandleCompareSessions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/archive/search", api.Chain(apiServer.HandleSearchSessionArchive, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/sessions/tags/rollup", api.Chain(apiServer.HandleGetTagRollup, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/sessions/control", api.Chain(apiServer.HandleControlMessages, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/push", api.Chain(apiServer.HandlePushSubscription, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, apiServer.ViewerMiddleware))
+	mux.HandleFunc("/api/sessions/viewer-token", api.Chain(apiServer.HandleIssueViewerToken, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware))
+	mux.HandleFunc("/.well-known/jwks.json", api.Chain(apiServer.HandleJWKS, api.LoggingMiddleware, api.CORSMiddleware))
+	mux.HandleFunc("/api/sessions/leaderboard", api.Chain(apiServer.HandleGetLeaderboard, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/waves", api.Chain(apiServer.HandleWaves, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/boost", api.Chain(apiServer.HandleBoost, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/webhooks", api.Chain(apiServer.HandleSessionWebhooks, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/reactions/system", api.Chain(apiServer.HandleEmitSystemReactions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/shoutouts", api.Chain(apiServer.HandlePickShoutouts, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/sessions/overlay", api.Chain(apiServer.HandleCreateOverlayURL, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/overlay", api.Chain(apiServer.HandleGetOverlay, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/moderation/flagged", api.Chain(apiServer.HandleGetFlaggedMessages, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ModeratorMiddleware))
+	mux.HandleFunc("/api/moderation/bans", api.Chain(apiServer.HandleSessionBans, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ModeratorMiddleware))
+	mux.HandleFunc("/api/sessions/users", api.Chain(apiServer.HandleGetSessionUsers, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ModeratorMiddleware))
 
 	// API integration routes
-	mux.HandleFunc("/api/events", api.Chain(apiServer.HandleGetLiveEvents, api.LoggingMiddleware, api.CORSMiddleware))
-	mux.HandleFunc("/api/events/single", api.Chain(apiServer.HandleGetEvent, api.LoggingMiddleware, api.CORSMiddleware))
+	mux.HandleFunc("/api/events", api.Chain(apiServer.HandleGetLiveEvents, api.LoggingMiddleware, api.CORSMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/events/single", api.Chain(apiServer.HandleGetEvent, api.LoggingMiddleware, api.CORSMiddleware, readLimiter.Middleware))
 	mux.HandleFunc("/api/favorites", api.Chain(apiServer.HandleToggleFavorite, api.LoggingMiddleware, api.CORSMiddleware, api.ClerkMiddleware))
 	
 	// Admin trigger for Ticketmaster
@@ -172,8 +885,58 @@ func main() {
 		w.Write([]byte(`{"status": "ticketmaster fetch triggered"}`))
 	}, api.LoggingMiddleware, api.CORSMiddleware))
 
+	// Engagement experiments
+	mux.HandleFunc("/api/admin/experiments", api.Chain(apiServer.HandleExperiments, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/experiments/results", api.Chain(apiServer.HandleGetExperimentResults, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/experiments/assignments", api.Chain(apiServer.HandleGetExperimentAssignments, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, apiServer.ViewerMiddleware))
+
+	// Campaigns
+	mux.HandleFunc("/api/campaigns", api.Chain(apiServer.HandleCreateCampaign, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/campaigns/progress", api.Chain(apiServer.HandleGetCampaign, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+
+	// Operational visibility
+	mux.HandleFunc("/api/ops/memory", api.Chain(apiServer.HandleGetMemoryUsage, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/stats-cache", api.Chain(apiServer.HandleGetStatsCache, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/queue", api.Chain(apiServer.HandleGetQueueLag, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/workers/sessions", api.Chain(apiServer.HandleGetSessionLoads, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/queue/resize", api.Chain(apiServer.HandleResizeQueue, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/actions", api.Chain(apiServer.HandleGetAdminActions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/notifications", api.Chain(apiServer.HandleGetFailedNotifications, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/notifications/redrive", api.Chain(apiServer.HandleRedriveNotifications, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/milestones", api.Chain(apiServer.HandleGetMilestonePersistence, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/audit", api.Chain(apiServer.HandleGetAuditStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/audit/reconciliation", api.Chain(apiServer.HandleGetReconciliation, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/sources", api.Chain(apiServer.HandleGetTopSources, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/recompute", api.Chain(apiServer.HandleRecomputeSessionStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+
+	// Admin ingestion filters
+	mux.HandleFunc("/api/admin/filters", api.Chain(apiServer.HandleFilterRules, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+
+	// Admin session lifecycle
+	mux.HandleFunc("/api/admin/sessions/events/stream", api.Chain(apiServer.HandleStreamSessionEvents, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/features", api.Chain(apiServer.HandleSessionFeatures, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/aliases", api.Chain(apiServer.HandleSessionAliases, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/reset", api.Chain(apiServer.HandleResetSessionStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/delete", api.Chain(apiServer.HandleDeleteSession, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/restore", api.Chain(apiServer.HandleRestoreSession, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/end", api.Chain(apiServer.HandleBulkEndSessions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions", api.Chain(apiServer.HandleListSessions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/milestones", api.Chain(apiServer.HandleAddMilestones, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/admin/sessions/broadcasts", api.Chain(apiServer.HandleTailBroadcasts, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+
 	// WebSocket
 	mux.HandleFunc("/ws", apiServer.HandleWebSocket)
+	mux.HandleFunc("/ws/overlay", api.Chain(apiServer.HandleOverlayWebSocket, api.LoggingMiddleware, api.RecoveryMiddleware))
+
+	// Built-in demo dashboard, for evaluating LivePulse without a frontend
+	if cfg.Dashboard.Enabled {
+		apiServer.SetDashboard(cfg.Dashboard.PushInterval)
+		mux.HandleFunc("/dashboard/", api.Chain(dashboard.Handler("/dashboard/").ServeHTTP, api.LoggingMiddleware, api.RecoveryMiddleware))
+		mux.HandleFunc("/dashboard/ws", api.Chain(apiServer.HandleDashboardWebSocket, api.LoggingMiddleware, api.RecoveryMiddleware))
+	}
+	mux.HandleFunc("/api/ingest/stream", api.Chain(apiServer.HandleIngestStream, api.LoggingMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.ProducerMiddleware))
+	mux.HandleFunc("/api/ingest/events", api.Chain(apiServer.HandleIngestBatch, api.LoggingMiddleware, api.RecoveryMiddleware, api.IngestAuthMiddleware))
+	mux.HandleFunc("/api/cluster/route", api.Chain(apiServer.HandleGetHubRoute, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
 
 	// Create HTTP server
 	httpServer := &http.Server{
@@ -184,14 +947,34 @@ func main() {
 	}
 
 	// Start HTTP server in a goroutine
-	go func() {
+	go diagnostics.Label("http", func() {
 		log.Printf("HTTP server listening on :%s", cfg.Server.Port)
 		log.Printf("WebSocket endpoint: ws://localhost:%s/ws", cfg.Server.Port)
 		log.Printf("API endpoint: http://localhost:%s/api", cfg.Server.Port)
+		if cfg.Dashboard.Enabled {
+			log.Printf("Dashboard: http://localhost:%s/dashboard/", cfg.Server.Port)
+		}
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("HTTP server error: %v", err)
 		}
-	}()
+	})
+
+	// Serve pprof and runtime diagnostics on their own port, kept off the
+	// public listener
+	var debugServer *http.Server
+	if cfg.Debug.Addr != "" {
+		debugServer = &http.Server{
+			Addr:        cfg.Debug.Addr,
+			Handler:     apiServer.DiagnosticsHandler(cfg.Debug.Token),
+			ReadTimeout: cfg.Server.ReadTimeout,
+		}
+		go func() {
+			log.Printf("Diagnostics listening on %s", cfg.Debug.Addr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Diagnostics server error: %v", err)
+			}
+		}()
+	}
 
 	// Wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -207,10 +990,70 @@ func main() {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
 	log.Println("HTTP server stopped")
+	if debugServer != nil {
+		debugServer.Close()
+	}
+
+	// Stop stream consumption and commit the final checkpoints
+	streamCancel()
+	<-streamDone
+	<-mqttDone
 
 	// Shutdown worker pool with drain
-	workerPool.ShutdownWithDrain()
-	log.Println("Worker pool stopped")
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Worker.DrainTimeout)
+	defer drainCancel()
+	if err := workerPool.ShutdownWithDrain(drainCtx); err != nil {
+		log.Printf("Worker pool shutdown error: %v", err)
+	}
+	drained := workerPool.DrainProgress()
+	log.Printf("Worker pool stopped: drained %d of %d queued events, spilled %d", drained.Drained, drained.Total, drained.Spilled)
+
+	// Checkpoint final stats so the next deploy restores warm state
+	checkpointCancel()
+	if err := aggManager.Checkpoint(context.Background(), redisClient); err != nil {
+		log.Printf("Error writing final stats checkpoint: %v", err)
+	}
+
+	// Persist milestone progress made while draining
+	milestoneCancel()
+	if err := tracker.Persist(context.Background()); err != nil {
+		log.Printf("Error persisting final milestones: %v", err)
+	}
+
+	// Persist any fraud score changes made while draining
+	fraudCancel()
+	fraudGuard.Flush(context.Background())
+
+	// Let a standby take over without waiting for the lease to expire
+	if primary != nil {
+		replicationCancel()
+		if err := primary.Release(context.Background()); err != nil {
+			log.Printf("Error releasing primary lease: %v", err)
+		}
+		log.Println("Primary lease released")
+	}
+
+	// Hand off owned sessions so another instance takes over immediately
+	if coordinator != nil {
+		coordinator.ReleaseAll(context.Background())
+		clusterCancel()
+		log.Println("Session ownership released")
+	}
 
 	log.Println("LivePulse shutdown complete. Goodbye!")
 }
+
+// newReadLimiter returns the read API rate limiter, or nil when disabled
+func newReadLimiter(cfg *config.Config) *api.RateLimiter {
+	if !cfg.RateLimit.Enabled {
+		return nil
+	}
+	log.Printf("Rate limiting read APIs: %d requests per client and %d overall per endpoint every %s", cfg.RateLimit.PerClient, cfg.RateLimit.Global, cfg.RateLimit.Window)
+	return api.NewRateLimiter(api.RateLimits{
+		Window:    cfg.RateLimit.Window,
+		Global:    cfg.RateLimit.Global,
+		PerClient: cfg.RateLimit.PerClient,
+		Endpoints: cfg.RateLimit.Endpoints,
+		APIKeys:   cfg.RateLimit.APIKeys,
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jrudman25/livepulse/config"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// runQueryServer serves read-only stats and archive APIs from persistence,
+// without ingestion, workers or WebSockets, so dashboard read traffic can be
+// scaled separately from the write path. Live stats are as fresh as the
+// checkpoints written by ingesting instances (STATS_CHECKPOINT_INTERVAL).
+func runQueryServer(cfg *config.Config, pgClient *storage.PostgresClient, tenantDBs *storage.TenantDatabases, redisClient *storage.RedisClient) {
+	log.Println("Starting in read-only query mode")
+
+	// Mirror the latest stats checkpoint into memory
+	aggManager := aggregation.NewManager()
+	refreshCtx, refreshCancel := context.WithCancel(context.Background())
+	defer refreshCancel()
+	refresh := func() {
+		if _, err := aggManager.Reload(refreshCtx, redisClient); err != nil {
+			log.Printf("Error reloading stats checkpoint: %v", err)
+		}
+	}
+	refresh()
+	go func() {
+		ticker := time.NewTicker(cfg.Server.QueryRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-refreshCtx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+
+	// Events are served from Postgres without running the fetch cron
+	apiFetcher := events.NewAPIFetcher(pgClient, os.Getenv("EXTERNAL_API_KEY"))
+	apiServer := api.NewServer(nil, aggManager, nil, nil, pgClient, apiFetcher, sessions.NewRegistry(), nil)
+	apiServer.SetActionLog(pgClient)
+	apiServer.SetTenantDatabases(tenantDBs)
+	apiServer.SetStatsCache(cfg.Session.StatsCacheSize, cfg.Session.StatsCacheMaxAge)
+	apiServer.SetOverlaySecret(cfg.Overlay.Secret, cfg.Overlay.TokenTTL, cfg.Overlay.PushInterval)
+
+	readLimiter := newReadLimiter(cfg)
+
+	// Only read routes are registered in query mode
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", api.Chain(apiServer.HandleHealth, api.LoggingMiddleware, api.CORSMiddleware))
+	mux.HandleFunc("/api/sessions/stats", api.Chain(apiServer.HandleGetStats, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/overlay", api.Chain(apiServer.HandleGetOverlay, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/labels", api.Chain(apiServer.HandleGetLabels, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/reactions/by-minute", api.Chain(apiServer.HandleGetReactionsByMinute, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/archive", api.Chain(apiServer.HandleGetSessionArchive, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/sessions/archive/search", api.Chain(apiServer.HandleSearchSessionArchive, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/sessions/tags/rollup", api.Chain(apiServer.HandleGetTagRollup, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/events", api.Chain(apiServer.HandleGetLiveEvents, api.LoggingMiddleware, api.CORSMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/events/single", api.Chain(apiServer.HandleGetEvent, api.LoggingMiddleware, api.CORSMiddleware, readLimiter.Middleware))
+	mux.HandleFunc("/api/ops/actions", api.Chain(apiServer.HandleGetAdminActions, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/memory", api.Chain(apiServer.HandleGetMemoryUsage, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+	mux.HandleFunc("/api/ops/stats-cache", api.Chain(apiServer.HandleGetStatsCache, api.LoggingMiddleware, api.CORSMiddleware, api.RecoveryMiddleware, api.ClerkMiddleware, api.AdminMiddleware))
+
+	httpServer := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      mux,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	go func() {
+		log.Printf("Query server listening on :%s", cfg.Server.Port)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("HTTP server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("\nShutting down...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	log.Println("LivePulse query server shutdown complete. Goodbye!")
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ReportsEveryInvalidValue(t *testing.T) {
+	t.Setenv("WORKER_COUNT", "lots")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+	t.Setenv("MILESTONE_THRESHOLDS", "100,many")
+
+	cfg, err := Load()
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+
+	var invalid Errors
+	require.True(t, errors.As(err, &invalid))
+	keys := make([]string, len(invalid))
+	for i, fieldErr := range invalid {
+		keys[i] = fieldErr.Key
+		assert.Equal(t, SourceEnv, fieldErr.Source)
+	}
+	assert.Equal(t, []string{"SERVER_READ_TIMEOUT", "WORKER_COUNT", "MILESTONE_THRESHOLDS"}, keys)
+
+	var fieldErr *FieldError
+	require.True(t, errors.As(err, &fieldErr), "each field error is reachable")
+	assert.Equal(t, `SERVER_READ_TIMEOUT="soon" (env): expected a duration like 15s or 2m`, fieldErr.Error())
+}
+
+func TestFieldError_RedactsSecretValues(t *testing.T) {
+	err := &FieldError{Key: "OVERLAY_TOKEN_TTL", Value: "forever", Expected: "a duration like 15s or 2m", Source: SourceEnv}
+	assert.Contains(t, err.Error(), "[redacted]")
+	assert.NotContains(t, err.Error(), "forever")
+}
+
+func TestValidate_DebugListenerRequiresAToken(t *testing.T) {
+	t.Setenv("DEBUG_ADDR", "127.0.0.1:6060")
+	t.Setenv("DEBUG_TOKEN", "short")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), "DEBUG_TOKEN")
+
+	t.Setenv("DEBUG_TOKEN", "debug-token-0123456789")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes a variable whose value could not be parsed
+type FieldError struct {
+	Key      string
+	Value    string
+	Expected string // e.g. "an integer" or "a duration like 15s"
+	Source   string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s=%q (%s): expected %s", e.Key, redact(e.Key, e.Value), e.Source, e.Expected)
+}
+
+// Errors lists every invalid variable found while loading the config
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid config variable(s):\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// Unwrap exposes each FieldError to errors.As
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
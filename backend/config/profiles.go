@@ -0,0 +1,183 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// profileFiles holds the built-in profiles, one dotenv file per APP_ENV value
+//
+//go:embed profiles/*.env
+var profileFiles embed.FS
+
+// Where a setting's effective value came from, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+)
+
+// Setting is one resolved configuration variable
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// resolver looks variables up through the config layers: environment
+// variables override the profile file, which overrides built-in defaults.
+// It records every lookup so the effective config can be reported.
+type resolver struct {
+	profile  map[string]string
+	settings []Setting
+	errs     Errors
+}
+
+// Profiles returns the names of the built-in config profiles
+func Profiles() []string {
+	entries, _ := profileFiles.ReadDir("profiles")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".env"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadProfile reads the named profile, or nothing when name is empty
+func loadProfile(name string) (map[string]string, error) {
+	if name == "" {
+		return map[string]string{}, nil
+	}
+	file, err := profileFiles.Open(path.Join("profiles", name+".env"))
+	if err != nil {
+		return nil, fmt.Errorf("APP_ENV %q is not a known profile (available: %s)", name, strings.Join(Profiles(), ", "))
+	}
+	defer file.Close()
+	return godotenv.Parse(file)
+}
+
+// get resolves a variable through the layers
+func (r *resolver) get(key, defaultValue string) string {
+	setting := Setting{Key: key, Value: defaultValue, Source: SourceDefault}
+	if value, ok := r.profile[key]; ok && value != "" {
+		setting.Value, setting.Source = value, SourceProfile
+	}
+	if value := os.Getenv(key); value != "" {
+		setting.Value, setting.Source = value, SourceEnv
+	}
+	r.settings = append(r.settings, setting)
+	return setting.Value
+}
+
+// invalid records a value that failed to parse
+func (r *resolver) invalid(key, expected string) {
+	setting := r.settings[len(r.settings)-1]
+	r.errs = append(r.errs, &FieldError{Key: key, Value: setting.Value, Expected: expected, Source: setting.Source})
+}
+
+// int resolves an integer variable
+func (r *resolver) int(key, defaultValue string) int {
+	val, err := strconv.Atoi(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil {
+		r.invalid(key, "an integer")
+	}
+	return val
+}
+
+// float resolves a decimal variable
+func (r *resolver) float(key, defaultValue string) float64 {
+	val, err := strconv.ParseFloat(strings.TrimSpace(r.get(key, defaultValue)), 64)
+	if err != nil {
+		r.invalid(key, "a number")
+	}
+	return val
+}
+
+// bool resolves a boolean variable
+func (r *resolver) bool(key, defaultValue string) bool {
+	val, err := strconv.ParseBool(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil {
+		r.invalid(key, "true or false")
+	}
+	return val
+}
+
+// duration resolves a Go duration variable
+func (r *resolver) duration(key, defaultValue string) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil {
+		r.invalid(key, "a duration like 15s or 2m")
+	}
+	return d
+}
+
+// intSlice resolves a comma-separated list of integers
+func (r *resolver) intSlice(key, defaultValue string) []int {
+	var result []int
+	for _, part := range parseStringSlice(r.get(key, defaultValue)) {
+		val, err := strconv.Atoi(part)
+		if err != nil {
+			r.invalid(key, "a comma-separated list of integers")
+			return nil
+		}
+		result = append(result, val)
+	}
+	return result
+}
+
+// quotas resolves a comma-separated list of name=count pairs
+func (r *resolver) quotas(key, defaultValue string) map[string]int {
+	result := make(map[string]int)
+	for _, part := range parseStringSlice(r.get(key, defaultValue)) {
+		name, count, found := strings.Cut(part, "=")
+		val, err := strconv.Atoi(strings.TrimSpace(count))
+		if !found || strings.TrimSpace(name) == "" || err != nil {
+			r.invalid(key, "a comma-separated list of name=count pairs")
+			return nil
+		}
+		result[strings.TrimSpace(name)] = val
+	}
+	return result
+}
+
+// secretMarkers flag variables whose values are never printed
+var secretMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "API_KEY"}
+
+// redact hides secret values and the password of connection URLs
+func redact(key, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, marker := range secretMarkers {
+		if strings.Contains(key, marker) {
+			return "[redacted]"
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+// Settings returns every variable the configuration was resolved from, in
+// load order, with secrets redacted
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, len(c.settings))
+	for i, setting := range c.settings {
+		setting.Value = redact(setting.Key, setting.Value)
+		settings[i] = setting
+	}
+	return settings
+}
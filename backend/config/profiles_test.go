@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func settingOf(t *testing.T, cfg *Config, key string) Setting {
+	t.Helper()
+	for _, setting := range cfg.Settings() {
+		if setting.Key == key {
+			return setting
+		}
+	}
+	t.Fatalf("%s was not resolved", key)
+	return Setting{}
+}
+
+func TestLoad_EnvironmentOverridesProfileOverridesDefaults(t *testing.T) {
+	t.Setenv("APP_ENV", "dev")
+	t.Setenv("WORKER_COUNT", "5")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "dev", cfg.Profile)
+
+	assert.Equal(t, 5, cfg.Worker.Count)
+	assert.Equal(t, Setting{Key: "WORKER_COUNT", Value: "5", Source: SourceEnv}, settingOf(t, cfg, "WORKER_COUNT"))
+	assert.Equal(t, 1000, cfg.Worker.EventQueueSize)
+	assert.Equal(t, SourceProfile, settingOf(t, cfg, "EVENT_QUEUE_SIZE").Source)
+	assert.Equal(t, Setting{Key: "SERVER_PORT", Value: "8080", Source: SourceDefault}, settingOf(t, cfg, "SERVER_PORT"))
+}
+
+func TestLoad_RejectsUnknownProfiles(t *testing.T) {
+	t.Setenv("APP_ENV", "qa")
+
+	_, err := Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dev, prod, staging")
+	assert.Equal(t, []string{"dev", "prod", "staging"}, Profiles())
+}
+
+func TestSettings_RedactSecretsAndURLPasswords(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET", "hunter2")
+	t.Setenv("DATABASE_URL", "postgres://app:hunter2@db:5432/livepulse")
+	t.Setenv("REDIS_URL", "redis://cache:6379/0")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "[redacted]", settingOf(t, cfg, "WEBHOOK_SECRET").Value)
+	assert.Equal(t, "postgres://app:xxxxx@db:5432/livepulse", settingOf(t, cfg, "DATABASE_URL").Value)
+	assert.Equal(t, "redis://cache:6379/0", settingOf(t, cfg, "REDIS_URL").Value)
+	assert.Equal(t, "postgres://app:hunter2@db:5432/livepulse", cfg.Postgres.DatabaseURL, "only the report is redacted")
+}
@@ -0,0 +1,211 @@
+package aggregation
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Accuracy selects how a session counts its users
+type Accuracy string
+
+const (
+	// AccuracyExact records every user who joined and every user's
+	// reactions exactly
+	AccuracyExact Accuracy = "exact"
+
+	// AccuracyApproximate counts distinct users with a HyperLogLog and
+	// per-user reactions with a count-min sketch from the first join, for
+	// sessions too large to record each user. Memory stays fixed and the
+	// error bounds are reported with the numbers.
+	AccuracyApproximate Accuracy = "approximate"
+)
+
+// IsValid reports whether the accuracy mode is known
+func (a Accuracy) IsValid() bool {
+	return a == AccuracyExact || a == AccuracyApproximate
+}
+
+// Count-min sketch dimensions: each count overshoots by at most e/width of
+// all reactions counted, except with probability e^-depth. 2048x4 counters
+// (64KB) keep the overshoot under 0.14% in 98% of estimates.
+const (
+	cmsWidth = 2048
+	cmsDepth = 4
+)
+
+// maxApproxLeaders bounds the users an approximate session keeps reaction
+// estimates for, the heaviest reactors, from which leaderboards are drawn
+const maxApproxLeaders = 256
+
+// countMinSketch estimates per-user reaction counts in fixed memory. It never
+// underestimates.
+type countMinSketch struct {
+	Counters []int64 `json:"counters"` // cmsDepth rows of cmsWidth
+	Total    int64   `json:"total"`
+}
+
+func newCountMinSketch() *countMinSketch {
+	return &countMinSketch{Counters: make([]int64, cmsWidth*cmsDepth)}
+}
+
+// slots returns the counter of value in each row
+func (c *countMinSketch) slots(value string) [cmsDepth]int {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	x := mix64(hasher.Sum64())
+	// Rows index with h1 + i*h2 (Kirsch-Mitzenmacher)
+	h1, h2 := x&0xffffffff, x>>32|1
+	var slots [cmsDepth]int
+	for row := range slots {
+		slots[row] = row*cmsWidth + int((h1+uint64(row)*h2)%cmsWidth)
+	}
+	return slots
+}
+
+// add counts one occurrence of value and returns its new estimate
+func (c *countMinSketch) add(value string) int64 {
+	c.Total++
+	estimate := int64(math.MaxInt64)
+	for _, slot := range c.slots(value) {
+		c.Counters[slot]++
+		if c.Counters[slot] < estimate {
+			estimate = c.Counters[slot]
+		}
+	}
+	return estimate
+}
+
+// estimate returns how often value was counted, possibly overshooting
+func (c *countMinSketch) estimate(value string) int64 {
+	estimate := int64(math.MaxInt64)
+	for _, slot := range c.slots(value) {
+		if c.Counters[slot] < estimate {
+			estimate = c.Counters[slot]
+		}
+	}
+	return estimate
+}
+
+// sizeBytes returns the memory held by the counters
+func (c *countMinSketch) sizeBytes() int {
+	return len(c.Counters) * 8
+}
+
+// ErrorBounds reports how far an approximate session's numbers may be off
+type ErrorBounds struct {
+	// UniqueUsersRelativeError is the standard error of unique_users as a
+	// fraction of the count, e.g. 0.008 for ±0.8%
+	UniqueUsersRelativeError float64 `json:"unique_users_relative_error"`
+
+	// UserReactionsMaxOvercount is how many reactions a per-user count may
+	// overstate by; per-user counts are never understated
+	UserReactionsMaxOvercount int64 `json:"user_reactions_max_overcount"`
+
+	// UserReactionsConfidence is the probability a per-user count is within
+	// UserReactionsMaxOvercount
+	UserReactionsConfidence float64 `json:"user_reactions_confidence"`
+}
+
+// SetAccuracy switches a session's accuracy mode. Sessions choose approximate
+// at creation; switching a session with users only starts approximating from
+// now on, and an approximate session cannot return to exact.
+func (s *SessionStats) SetAccuracy(accuracy Accuracy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if accuracy != AccuracyApproximate || s.userSketch != nil {
+		return
+	}
+	s.compactLocked()
+	s.approximateUserReactionsLocked()
+}
+
+// approximateUserReactionsLocked moves the exact per-user reaction counts
+// into a sketch, keeping estimates for the heaviest reactors only. Callers
+// must hold s.mu.
+func (s *SessionStats) approximateUserReactionsLocked() {
+	s.userSketch = newCountMinSketch()
+	for userID, count := range s.UserReactions {
+		s.userSketch.Total += count
+		for _, slot := range s.userSketch.slots(userID) {
+			s.userSketch.Counters[slot] += count
+		}
+	}
+	s.trimLeadersLocked()
+}
+
+// accuracyLocked returns the session's accuracy mode. Callers must hold s.mu.
+func (s *SessionStats) accuracyLocked() Accuracy {
+	if s.userSketch != nil {
+		return AccuracyApproximate
+	}
+	return AccuracyExact
+}
+
+// GetErrorBounds returns the error bounds of an approximate session, or nil
+// if its numbers are exact
+func (s *SessionStats) GetErrorBounds() *ErrorBounds {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.errorBoundsLocked()
+}
+
+// errorBoundsLocked returns the error bounds of an approximate session, or
+// nil for exact ones. Callers must hold s.mu.
+func (s *SessionStats) errorBoundsLocked() *ErrorBounds {
+	if s.userSketch == nil {
+		return nil
+	}
+	return &ErrorBounds{
+		UniqueUsersRelativeError:  1.04 / math.Sqrt(float64(int64(1)<<hllPrecision)),
+		UserReactionsMaxOvercount: int64(math.Ceil(math.E / cmsWidth * float64(s.userSketch.Total))),
+		UserReactionsConfidence:   1 - math.Exp(-cmsDepth),
+	}
+}
+
+// recordApproxReactionLocked counts a user's reaction in the sketch and keeps
+// the user's estimate if they are among the heaviest reactors. Callers must
+// hold s.mu.
+func (s *SessionStats) recordApproxReactionLocked(userID string) {
+	estimate := s.userSketch.add(userID)
+	if _, tracked := s.UserReactions[userID]; tracked || len(s.UserReactions) < maxApproxLeaders {
+		s.UserReactions[userID] = estimate
+		return
+	}
+	lightest, lightestCount := "", int64(math.MaxInt64)
+	for candidate, count := range s.UserReactions {
+		if count < lightestCount {
+			lightest, lightestCount = candidate, count
+		}
+	}
+	if estimate > lightestCount {
+		delete(s.UserReactions, lightest)
+		s.UserReactions[userID] = estimate
+	}
+}
+
+// trimLeadersLocked drops all but the heaviest maxApproxLeaders reactors.
+// Callers must hold s.mu.
+func (s *SessionStats) trimLeadersLocked() {
+	if len(s.UserReactions) <= maxApproxLeaders {
+		return
+	}
+	users := make([]string, 0, len(s.UserReactions))
+	for userID := range s.UserReactions {
+		users = append(users, userID)
+	}
+	sort.Slice(users, func(i, j int) bool { return s.UserReactions[users[i]] > s.UserReactions[users[j]] })
+	for _, userID := range users[maxApproxLeaders:] {
+		delete(s.UserReactions, userID)
+	}
+}
+
+// userReactionsLocked returns the reactions a user sent, estimated in
+// approximate sessions. Callers must hold s.mu.
+func (s *SessionStats) userReactionsLocked(userID string) int64 {
+	if s.userSketch != nil {
+		return s.userSketch.estimate(userID)
+	}
+	return s.UserReactions[userID]
+}
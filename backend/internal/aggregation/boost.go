@@ -0,0 +1,116 @@
+package aggregation
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Bounds on a boost's multiplier and how long it lasts
+const (
+	MinBoostMultiplier = 2
+	MaxBoostMultiplier = 10
+	MinBoostDuration   = 10 * time.Second
+	MaxBoostDuration   = 4 * time.Hour
+)
+
+// hypeWindow is the window the hype score sums weighted reactions over
+const hypeWindow = time.Minute
+
+// Boost multiplies the weight of a session's audience reactions for a window,
+// e.g. a "double hype hour". Raw reaction counts are unaffected; the boost
+// shows in the weighted total and the hype score.
+type Boost struct {
+	ID         string    `json:"id"`
+	Multiplier int       `json:"multiplier"`
+	Label      string    `json:"label,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+// activeAt reports whether the boost applies to a reaction received at t
+func (b *Boost) activeAt(t time.Time) bool {
+	return b != nil && !t.Before(b.StartedAt) && t.Before(b.EndsAt)
+}
+
+// StartBoost multiplies the weight of the session's reactions by multiplier
+// for duration from now. It fails with a conflict while another boost lasts.
+func (s *SessionStats) StartBoost(multiplier int, duration time.Duration, label string, now time.Time) (Boost, error) {
+	if multiplier < MinBoostMultiplier || multiplier > MaxBoostMultiplier {
+		return Boost{}, errs.Validation("multiplier must be between %d and %d", MinBoostMultiplier, MaxBoostMultiplier)
+	}
+	if duration < MinBoostDuration || duration > MaxBoostDuration {
+		return Boost{}, errs.Validation("duration must be between %s and %s", MinBoostDuration, MaxBoostDuration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.boost.activeAt(now) {
+		return Boost{}, errs.Conflict("a boost is already running until %s", s.boost.EndsAt.Format(time.RFC3339))
+	}
+	s.boost = &Boost{
+		ID:         events.NewEventID(),
+		Multiplier: multiplier,
+		Label:      label,
+		StartedAt:  now,
+		EndsAt:     now.Add(duration),
+	}
+	atomic.AddInt64(&s.version, 1)
+	return *s.boost, nil
+}
+
+// EndBoost ends the session's boost early, returning it as ended, or false
+// if no boost is running
+func (s *SessionStats) EndBoost(now time.Time) (Boost, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.boost.activeAt(now) {
+		return Boost{}, false
+	}
+	s.boost.EndsAt = now
+	atomic.AddInt64(&s.version, 1)
+	return *s.boost, true
+}
+
+// GetBoost returns the boost running at now, or nil
+func (s *SessionStats) GetBoost(now time.Time) *Boost {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.boostLocked(now)
+}
+
+// boostLocked returns a copy of the boost running at now, or nil. The
+// caller must hold s.mu.
+func (s *SessionStats) boostLocked(now time.Time) *Boost {
+	if !s.boost.activeAt(now) {
+		return nil
+	}
+	boost := *s.boost
+	return &boost
+}
+
+// recordWeightLocked counts a reaction received at towards the weighted
+// total and hype score, with the multiplier of the boost running then. The
+// caller must hold s.mu.
+func (s *SessionStats) recordWeightLocked(at time.Time) {
+	weight := int64(1)
+	if s.boost.activeAt(at) {
+		weight = int64(s.boost.Multiplier)
+	}
+	s.weightedReactions += weight
+	if s.hype == nil {
+		s.hype = &rateWindow{lastSecond: at.Unix()}
+	}
+	s.hype.addN(at.Unix(), weight)
+}
+
+// hypeScoreLocked returns the weighted reactions of the last hypeWindow.
+// The caller must hold s.mu.
+func (s *SessionStats) hypeScoreLocked(now time.Time) int64 {
+	if s.hype == nil {
+		return 0
+	}
+	return s.hype.sum(now.Unix(), hypeWindow)
+}
@@ -0,0 +1,47 @@
+package aggregation
+
+import (
+	"sync/atomic"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// maxClientVersions bounds the distinct client versions tracked per session
+const maxClientVersions = 32
+
+// overflowClientVersion collects joins once a session exceeds maxClientVersions
+const overflowClientVersion = "other"
+
+// RecordClientVersion counts a join under the app version the client
+// reported. Joins reporting no version are not counted; malformed versions
+// count as events.UnknownClientVersion.
+func (s *SessionStats) RecordClientVersion(version string) {
+	version = events.NormalizeClientVersion(version)
+	if version == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clientVersions == nil {
+		s.clientVersions = make(map[string]int64)
+	}
+	if _, known := s.clientVersions[version]; !known && len(s.clientVersions) >= maxClientVersions {
+		version = overflowClientVersion
+	}
+	s.clientVersions[version]++
+	atomic.AddInt64(&s.version, 1)
+}
+
+// clientVersionsLocked copies the joins per client version. Callers must
+// hold s.mu.
+func (s *SessionStats) clientVersionsLocked() map[string]int64 {
+	if len(s.clientVersions) == 0 {
+		return nil
+	}
+	versions := make(map[string]int64, len(s.clientVersions))
+	for version, joins := range s.clientVersions {
+		versions[version] = joins
+	}
+	return versions
+}
@@ -0,0 +1,87 @@
+package aggregation
+
+import (
+	"sync/atomic"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// overflowDimensionValue collects reactions for a dimension's values once
+// it has reached its cardinality cap
+const overflowDimensionValue = "other"
+
+// DimensionStats summarizes the reactions tagged with one dimension value
+type DimensionStats struct {
+	TotalReactions int64                         `json:"total_reactions"`
+	ReactionCounts map[events.ReactionType]int64 `json:"reaction_counts"`
+}
+
+// SetDimensions sets the reaction attributes aggregated by sessions created
+// from now on, e.g. "team" for a watch party. Each dimension tracks at most
+// maxValues distinct values; later values are counted as "other".
+func (m *Manager) SetDimensions(names []string, maxValues int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dimensions = append([]string(nil), names...)
+	m.maxDimensionValues = maxValues
+}
+
+// RecordDimensions counts a reaction under the value of each configured
+// dimension its attributes carry
+func (s *SessionStats) RecordDimensions(attributes map[string]string, reactionType events.ReactionType) {
+	if len(attributes) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recorded := false
+	for _, dimension := range s.dimensions {
+		value, tagged := attributes[dimension]
+		if !tagged {
+			continue
+		}
+		values, exists := s.DimensionReactions[dimension]
+		if !exists {
+			values = make(map[string]map[events.ReactionType]int64)
+			s.DimensionReactions[dimension] = values
+		}
+		if _, known := values[value]; !known {
+			if s.maxDimensionValues > 0 && len(values) >= s.maxDimensionValues {
+				value = overflowDimensionValue
+			}
+			if _, known := values[value]; !known {
+				values[value] = make(map[events.ReactionType]int64)
+			}
+		}
+		values[value][reactionType]++
+		recorded = true
+	}
+	if recorded {
+		atomic.AddInt64(&s.version, 1)
+	}
+}
+
+// getDimensionStats builds per-value stats for every dimension. Callers
+// must hold s.mu.
+func (s *SessionStats) getDimensionStats() map[string]map[string]DimensionStats {
+	if len(s.DimensionReactions) == 0 {
+		return nil
+	}
+	dimensions := make(map[string]map[string]DimensionStats, len(s.DimensionReactions))
+	for dimension, values := range s.DimensionReactions {
+		stats := make(map[string]DimensionStats, len(values))
+		for value, reactions := range values {
+			counts := make(map[events.ReactionType]int64, len(reactions))
+			var total int64
+			for reactionType, count := range reactions {
+				counts[reactionType] = count
+				total += count
+			}
+			stats[value] = DimensionStats{TotalReactions: total, ReactionCounts: counts}
+		}
+		dimensions[dimension] = stats
+	}
+	return dimensions
+}
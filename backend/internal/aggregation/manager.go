@@ -2,20 +2,30 @@ package aggregation
 
 import (
 	"sync"
+	"time"
 
+	"github.com/jrudman25/livepulse/internal/errs"
 	"github.com/jrudman25/livepulse/internal/events"
 )
 
 // Manager manages statistics for all active sessions
 type Manager struct {
-	sessions map[string]*SessionStats
-	mu       sync.RWMutex
+	sessions           map[string]*SessionStats
+	maxTrackedUsers    int
+	dimensions         []string // reaction attributes aggregated per value
+	maxDimensionValues int
+	sustainedWindow    time.Duration               // concurrency held this long counts as the sustained peak
+	owns               func(sessionID string) bool // nil owns every session
+	adopted            func(sessionID string)      // called with each session restored or taken over
+	removed            map[string]bool             // removed since the last checkpoint
+	mu                 sync.RWMutex
 }
 
 // NewManager creates a new aggregation manager
 func NewManager() *Manager {
 	return &Manager{
 		sessions: make(map[string]*SessionStats),
+		removed:  make(map[string]bool),
 	}
 }
 
@@ -39,10 +49,23 @@ func (m *Manager) GetOrCreateSession(sessionID string) *SessionStats {
 	}
 
 	stats = NewSessionStats(sessionID)
+	stats.maxTrackedUsers = m.maxTrackedUsers
+	stats.dimensions = m.dimensions
+	stats.maxDimensionValues = m.maxDimensionValues
+	stats.sustainedWindow = m.sustainedWindow
 	m.sessions[sessionID] = stats
+	delete(m.removed, sessionID)
 	return stats
 }
 
+// SetMaxTrackedUsers sets the per-session memory cap applied to sessions
+// created from now on, expressed as distinct users recorded exactly
+func (m *Manager) SetMaxTrackedUsers(limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxTrackedUsers = limit
+}
+
 // GetSession retrieves session statistics if it exists
 func (m *Manager) GetSession(sessionID string) (*SessionStats, bool) {
 	m.mu.RLock()
@@ -52,19 +75,53 @@ func (m *Manager) GetSession(sessionID string) (*SessionStats, bool) {
 	return stats, exists
 }
 
-// ProcessEvent processes an event and updates statistics
-func (m *Manager) ProcessEvent(event *events.Event) {
+// ProcessEvent processes an event and updates statistics. Events that
+// cannot be applied are reported with an errs.ErrValidation error.
+func (m *Manager) ProcessEvent(event *events.Event) error {
+	if event.SessionID == "" {
+		return errs.Validation("event %s has no session_id", event.ID)
+	}
 	stats := m.GetOrCreateSession(event.SessionID)
 
 	switch event.Type {
 	case events.EventTypeJoinSession:
+		stats.ClassifyViewer(event.UserID, event.GetViewer())
+		stats.AssignCohort(event.UserID, event.GetCohort())
+		stats.RecordClientVersion(event.GetClientVersion())
 		stats.AddUser(event.UserID)
 	case events.EventTypeLeaveSession:
-		stats.RemoveUser(event.UserID)
+		stats.RemoveConnection(event.UserID, event.GetLeaveState())
+	case events.EventTypePresence:
+		previous, state, ok := event.GetPresence()
+		if !ok {
+			return errs.Validation("presence event %s has an invalid state", event.ID)
+		}
+		stats.UpdatePresence(event.UserID, previous, state)
 	case events.EventTypeReaction:
-		if reactionType, ok := event.GetReactionType(); ok {
-			stats.IncrementReaction(reactionType)
+		reactionType, ok := event.GetReactionType()
+		if !ok {
+			return errs.Validation("reaction event %s has an unknown reaction type", event.ID)
 		}
+		// Integration reactions are counted on their own, outside the
+		// audience totals, timeline and velocity
+		if event.IsSystem() {
+			stats.RecordSystemReaction(event.Source, reactionType)
+			return nil
+		}
+		stats.CountReaction(event.UserID, reactionType)
+		stats.RecordDimensions(event.GetAttributes(), reactionType)
+		stats.RecordReactionSource(event.GetReactionSource(), reactionType)
+		stats.recordMinute(reactionType, event.Timestamp)
+		stats.recordVelocity(time.Now())
+	}
+	return nil
+}
+
+// ClassifyViewer counts a user who already joined a session as a
+// first-time or returning viewer, once their viewer history is known
+func (m *Manager) ClassifyViewer(sessionID, userID, kind string) {
+	if stats, exists := m.GetSession(sessionID); exists {
+		stats.ClassifyViewer(userID, kind)
 	}
 }
 
@@ -85,6 +142,19 @@ func (m *Manager) RemoveSession(sessionID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.sessions, sessionID)
+	m.removed[sessionID] = true
+}
+
+// SessionIDs returns the IDs of every tracked session
+func (m *Manager) SessionIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.sessions))
+	for sessionID := range m.sessions {
+		ids = append(ids, sessionID)
+	}
+	return ids
 }
 
 // GetSessionCount returns the number of active sessions
@@ -93,3 +163,25 @@ func (m *Manager) GetSessionCount() int {
 	defer m.mu.RUnlock()
 	return len(m.sessions)
 }
+
+// SessionReactions returns the audience reactions counted for a session,
+// or 0 if it is not tracked
+func (m *Manager) SessionReactions(sessionID string) int64 {
+	stats, ok := m.GetSession(sessionID)
+	if !ok {
+		return 0
+	}
+	return stats.GetTotalReactions()
+}
+
+// UserReactions returns the reactions a user sent in a session, estimated
+// in approximate sessions, or 0 if the session is not tracked
+func (m *Manager) UserReactions(sessionID, userID string) int64 {
+	stats, ok := m.GetSession(sessionID)
+	if !ok {
+		return 0
+	}
+	stats.mu.RLock()
+	defer stats.mu.RUnlock()
+	return stats.userReactionsLocked(userID)
+}
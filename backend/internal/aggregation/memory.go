@@ -0,0 +1,122 @@
+package aggregation
+
+import (
+	"sort"
+)
+
+// Rough per-entry overheads of Go maps, used for approximate accounting
+const (
+	mapEntryOverhead  = 48
+	timeValueSize     = 24
+	reactionEntrySize = 24
+)
+
+// MemoryUsage reports the approximate memory held by one session
+type MemoryUsage struct {
+	SessionID           string `json:"session_id"`
+	ActiveUsers         int    `json:"active_users"`
+	TrackedUsers        int    `json:"tracked_users"`
+	UserReactionEntries int    `json:"user_reaction_entries"`
+	Cohorts             int    `json:"cohorts"`
+	Compacted           bool   `json:"compacted"`
+	ApproxBytes         int    `json:"approx_bytes"`
+}
+
+// MemoryUsage estimates the memory held by the session's per-user state
+func (s *SessionStats) MemoryUsage() MemoryUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bytes := 0
+	for userID := range s.ActiveUsers {
+		// ActiveUsers and JoinTimes share the same keys
+		bytes += 2*(len(userID)+mapEntryOverhead) + presenceSize + timeValueSize
+	}
+	for userID := range s.UserReactions {
+		bytes += len(userID) + mapEntryOverhead + 8
+	}
+	for userID, cohort := range s.UserCohorts {
+		bytes += len(userID) + len(cohort) + mapEntryOverhead
+	}
+	for cohort, reactions := range s.CohortReactions {
+		bytes += len(cohort) + mapEntryOverhead + len(reactions)*reactionEntrySize
+	}
+	for dimension, values := range s.DimensionReactions {
+		bytes += len(dimension) + mapEntryOverhead
+		for value, reactions := range values {
+			bytes += len(value) + mapEntryOverhead + len(reactions)*reactionEntrySize
+		}
+	}
+	for source, reactions := range s.sources {
+		bytes += len(source) + mapEntryOverhead + len(reactions)*reactionEntrySize
+	}
+	for version := range s.clientVersions {
+		bytes += len(version) + mapEntryOverhead
+	}
+	if s.uniqueSketch != nil {
+		bytes += s.uniqueSketch.sizeBytes()
+	}
+	if s.userSketch != nil {
+		bytes += s.userSketch.sizeBytes()
+	}
+	for _, counts := range s.minuteCounts {
+		bytes += mapEntryOverhead + len(counts)*reactionEntrySize
+	}
+	bytes += len(s.audience) * 16
+	for userID := range s.departures {
+		bytes += len(userID) + mapEntryOverhead + 8
+	}
+	if s.velocity != nil {
+		bytes += velocitySlots * 8
+	}
+
+	return MemoryUsage{
+		SessionID:           s.SessionID,
+		ActiveUsers:         len(s.ActiveUsers),
+		TrackedUsers:        len(s.UserCohorts),
+		UserReactionEntries: len(s.UserReactions),
+		Cohorts:             len(s.CohortReactions),
+		Compacted:           s.uniqueSketch != nil,
+		ApproxBytes:         bytes,
+	}
+}
+
+// compactLocked replaces the exact record of every user who ever joined with
+// a cardinality sketch and drops per-user state for users no longer active.
+// Callers must hold s.mu.
+func (s *SessionStats) compactLocked() {
+	if s.uniqueSketch != nil {
+		return
+	}
+	sketch := newHyperLogLog()
+	for userID := range s.UserCohorts {
+		sketch.add(userID)
+	}
+	for userID := range s.UserCohorts {
+		if _, active := s.ActiveUsers[userID]; !active {
+			delete(s.UserCohorts, userID)
+		}
+	}
+	for userID := range s.UserReactions {
+		if _, active := s.ActiveUsers[userID]; !active {
+			delete(s.UserReactions, userID)
+		}
+	}
+	s.uniqueSketch = sketch
+	s.departures = nil
+}
+
+// GetMemoryUsage returns memory accounting for every session, largest first
+func (m *Manager) GetMemoryUsage() []MemoryUsage {
+	m.mu.RLock()
+	usage := make([]MemoryUsage, 0, len(m.sessions))
+	for _, stats := range m.sessions {
+		usage = append(usage, stats.MemoryUsage())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].ApproxBytes > usage[j].ApproxBytes
+	})
+	return usage
+}
@@ -0,0 +1,51 @@
+package aggregation
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PeakMoment describes the moment a session reached its peak concurrency,
+// since when the audience peaked is the first question after every show
+type PeakMoment struct {
+	Users              int       `json:"users"`
+	At                 time.Time `json:"at"`
+	ReactionsPerMinute int64     `json:"reactions_per_minute"` // reactions received in the minute before the peak
+	TotalReactions     int64     `json:"total_reactions"`      // reactions received by the peak
+}
+
+// recordPeakLocked raises the peak to the current concurrency if it is
+// higher, noting when and how busy the session was at that moment
+func (s *SessionStats) recordPeakLocked(now time.Time) {
+	if len(s.ActiveUsers) <= s.PeakConcurrentUsers {
+		return
+	}
+	s.setPeakLocked(now)
+}
+
+// setPeakLocked makes the current concurrency the peak. An empty session
+// has no peak moment.
+func (s *SessionStats) setPeakLocked(now time.Time) {
+	s.PeakConcurrentUsers = len(s.ActiveUsers)
+	if s.PeakConcurrentUsers == 0 {
+		s.peak = PeakMoment{}
+		return
+	}
+	s.peak = PeakMoment{
+		Users:          s.PeakConcurrentUsers,
+		At:             now,
+		TotalReactions: atomic.LoadInt64(s.TotalReactions),
+	}
+	if s.velocity != nil {
+		s.peak.ReactionsPerMinute = s.velocity.sum(now.Unix(), time.Minute)
+	}
+}
+
+// peakLocked returns the peak moment, or nil before anyone joined
+func (s *SessionStats) peakLocked() *PeakMoment {
+	if s.peak.At.IsZero() {
+		return nil
+	}
+	peak := s.peak
+	return &peak
+}
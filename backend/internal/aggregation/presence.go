@@ -0,0 +1,106 @@
+package aggregation
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// presenceSize approximates one Presence record and its state map
+const presenceSize = 8 + timeValueSize + mapEntryOverhead + 3*reactionEntrySize
+
+// presenceOrder ranks states from most to least engaged
+var presenceOrder = []events.PresenceState{events.PresenceActive, events.PresenceIdle, events.PresenceBackground}
+
+// Presence tracks a user's open connections and the state each reported
+type Presence struct {
+	Connections int                          `json:"connections"`
+	States      map[events.PresenceState]int `json:"states"` // state -> connections in that state
+	UpdatedAt   time.Time                    `json:"updated_at"`
+}
+
+func newPresence() *Presence {
+	return &Presence{States: make(map[events.PresenceState]int)}
+}
+
+// State returns the most engaged state across the user's connections, so a
+// user watching on a laptop with a backgrounded phone counts as active
+func (p *Presence) State() events.PresenceState {
+	for _, state := range presenceOrder {
+		if p.States[state] > 0 {
+			return state
+		}
+	}
+	return events.PresenceActive
+}
+
+// take moves one connection out of state, falling back to the least engaged
+// state if none is recorded there (e.g. after a lost transition)
+func (p *Presence) take(state events.PresenceState) {
+	if p.States[state] > 0 {
+		p.States[state]--
+		return
+	}
+	for i := len(presenceOrder) - 1; i >= 0; i-- {
+		if p.States[presenceOrder[i]] > 0 {
+			p.States[presenceOrder[i]]--
+			return
+		}
+	}
+}
+
+// UnmarshalJSON also accepts the bare connection counts written by older
+// checkpoints, treating those connections as active
+func (p *Presence) UnmarshalJSON(data []byte) error {
+	var connections int
+	if err := json.Unmarshal(data, &connections); err == nil {
+		*p = Presence{Connections: connections, States: map[events.PresenceState]int{events.PresenceActive: connections}}
+		return nil
+	}
+	type presence Presence
+	var decoded presence
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.States == nil {
+		decoded.States = make(map[events.PresenceState]int)
+	}
+	*p = Presence(decoded)
+	return nil
+}
+
+// UpdatePresence moves one of the user's connections from previous to state.
+// It is a no-op for users without an open connection.
+func (s *SessionStats) UpdatePresence(userID string, previous, state events.PresenceState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presence, exists := s.ActiveUsers[userID]
+	if !exists || previous == state {
+		return
+	}
+	presence.take(previous)
+	presence.States[state]++
+	presence.UpdatedAt = time.Now().UTC()
+	atomic.AddInt64(&s.version, 1)
+}
+
+// presenceCountsLocked returns the number of users in each state. Callers
+// must hold s.mu.
+func (s *SessionStats) presenceCountsLocked() map[events.PresenceState]int {
+	counts := make(map[events.PresenceState]int, len(presenceOrder))
+	for _, presence := range s.ActiveUsers {
+		counts[presence.State()]++
+	}
+	return counts
+}
+
+// GetWatchingUserCount returns the number of connected users who are
+// actively watching rather than idle or backgrounded
+func (s *SessionStats) GetWatchingUserCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.presenceCountsLocked()[events.PresenceActive]
+}
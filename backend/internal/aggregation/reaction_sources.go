@@ -0,0 +1,54 @@
+package aggregation
+
+import (
+	"sync/atomic"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// SourceStats summarizes the reactions sent from one UI surface
+type SourceStats struct {
+	TotalReactions int64                         `json:"total_reactions"`
+	ReactionCounts map[events.ReactionType]int64 `json:"reaction_counts"`
+}
+
+// RecordReactionSource counts an audience reaction under the UI surface it
+// was sent from. Reactions naming no surface are not attributed; unknown
+// surfaces, which only unvalidated sources can send, count as "other".
+func (s *SessionStats) RecordReactionSource(source events.ReactionSource, reactionType events.ReactionType) {
+	if source == "" {
+		return
+	}
+	if !source.IsValid() {
+		source = events.ReactionSourceOther
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sources == nil {
+		s.sources = make(map[events.ReactionSource]map[events.ReactionType]int64)
+	}
+	if s.sources[source] == nil {
+		s.sources[source] = make(map[events.ReactionType]int64)
+	}
+	s.sources[source][reactionType]++
+	atomic.AddInt64(&s.version, 1)
+}
+
+// getSourceStats builds per-surface stats. Callers must hold s.mu.
+func (s *SessionStats) getSourceStats() map[events.ReactionSource]SourceStats {
+	if len(s.sources) == 0 {
+		return nil
+	}
+	sources := make(map[events.ReactionSource]SourceStats, len(s.sources))
+	for source, reactions := range s.sources {
+		counts := make(map[events.ReactionType]int64, len(reactions))
+		var total int64
+		for reactionType, count := range reactions {
+			counts[reactionType] = count
+			total += count
+		}
+		sources[source] = SourceStats{TotalReactions: total, ReactionCounts: counts}
+	}
+	return sources
+}
@@ -0,0 +1,114 @@
+package aggregation
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// RangeMetrics are a session's derived metrics rebuilt from its raw events
+// over a range of whole minutes of the show
+type RangeMetrics struct {
+	Since              time.Time                     `json:"since"`
+	Until              time.Time                     `json:"until"`
+	Events             int                           `json:"events"`
+	TotalReactions     int64                         `json:"total_reactions"`
+	ReactionCounts     map[events.ReactionType]int64 `json:"reaction_counts"`
+	ReactionsPerMinute float64                       `json:"reactions_per_minute"`
+	Timeline           []MinuteBucket                `json:"timeline"`
+	Highlight          *MinuteBucket                 `json:"highlight,omitempty"` // busiest minute
+	Mood               events.ReactionType           `json:"mood,omitempty"`      // most sent reaction
+}
+
+// minuteOf returns the timeline minute a timestamp falls in
+func minuteOf(start, at time.Time) int {
+	minute := int(at.Sub(start) / time.Minute)
+	if minute < 0 {
+		minute = 0
+	}
+	if minute >= maxTimelineMinutes {
+		minute = maxTimelineMinutes - 1
+	}
+	return minute
+}
+
+// RecomputeRange rebuilds the metrics of a session started at start from its
+// raw events between since and until. The range is widened to whole minutes,
+// so the timeline it returns can replace the live one minute for minute.
+func RecomputeRange(start, since, until time.Time, evs []*events.Event) RangeMetrics {
+	first, last := minuteOf(start, since), minuteOf(start, until.Add(-time.Nanosecond))
+	metrics := RangeMetrics{
+		Since:          start.Add(time.Duration(first) * time.Minute),
+		Until:          start.Add(time.Duration(last+1) * time.Minute),
+		ReactionCounts: make(map[events.ReactionType]int64),
+		Timeline:       make([]MinuteBucket, last-first+1),
+	}
+	for i := range metrics.Timeline {
+		metrics.Timeline[i] = MinuteBucket{
+			Minute: first + i,
+			Start:  metrics.Since.Add(time.Duration(i) * time.Minute),
+			Counts: make(map[events.ReactionType]int64),
+		}
+	}
+
+	for _, event := range evs {
+		minute := minuteOf(start, event.Timestamp)
+		if minute < first || minute > last {
+			continue
+		}
+		metrics.Events++
+		reactionType, ok := event.GetReactionType()
+		if event.Type != events.EventTypeReaction || !ok {
+			continue
+		}
+		bucket := &metrics.Timeline[minute-first]
+		bucket.Counts[reactionType]++
+		bucket.Total++
+		metrics.ReactionCounts[reactionType]++
+		metrics.TotalReactions++
+	}
+
+	metrics.ReactionsPerMinute = float64(metrics.TotalReactions) / float64(len(metrics.Timeline))
+	for i := range metrics.Timeline {
+		bucket := metrics.Timeline[i]
+		if bucket.Total > 0 && (metrics.Highlight == nil || bucket.Total > metrics.Highlight.Total) {
+			metrics.Highlight = &bucket
+		}
+	}
+	types := make([]events.ReactionType, 0, len(metrics.ReactionCounts))
+	for reactionType := range metrics.ReactionCounts {
+		types = append(types, reactionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, reactionType := range types {
+		if metrics.Mood == "" || metrics.ReactionCounts[reactionType] > metrics.ReactionCounts[metrics.Mood] {
+			metrics.Mood = reactionType
+		}
+	}
+	return metrics
+}
+
+// ReplaceMinutes overwrites the live timeline with recomputed buckets, e.g.
+// after an incident dropped or double-counted events. Reaction totals are
+// left alone; only the per-minute breakdown is rebuilt.
+func (s *SessionStats) ReplaceMinutes(buckets []MinuteBucket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bucket := range buckets {
+		if bucket.Minute < 0 || bucket.Minute >= maxTimelineMinutes {
+			continue
+		}
+		for len(s.minuteCounts) <= bucket.Minute {
+			s.minuteCounts = append(s.minuteCounts, nil)
+		}
+		counts := make(map[events.ReactionType]int64, len(bucket.Counts))
+		for reactionType, count := range bucket.Counts {
+			counts[reactionType] = count
+		}
+		s.minuteCounts[bucket.Minute] = counts
+	}
+	atomic.AddInt64(&s.version, 1)
+}
@@ -0,0 +1,75 @@
+package aggregation
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// ResetScope selects the counters a reset clears
+type ResetScope string
+
+const (
+	ResetAll       ResetScope = "all"        // everything, as if the session started now
+	ResetReactions ResetScope = "reactions"  // reaction counts, timeline and velocity
+	ResetPeakUsers ResetScope = "peak_users" // peak concurrency, lowered to the current count
+)
+
+// IsValid reports whether the scope is one Reset understands
+func (scope ResetScope) IsValid() bool {
+	switch scope {
+	case ResetAll, ResetReactions, ResetPeakUsers:
+		return true
+	}
+	return false
+}
+
+// Reset clears a live session's counters without ending it, e.g. when a
+// rehearsal turns into the real show. Connected users stay active and keep
+// their cohorts.
+func (s *SessionStats) Reset(scope ResetScope) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scope == ResetAll || scope == ResetReactions {
+		for _, counter := range s.ReactionCounts {
+			atomic.StoreInt64(counter, 0)
+		}
+		atomic.StoreInt64(s.TotalReactions, 0)
+		s.UserReactions = make(map[string]int64)
+		if s.userSketch != nil {
+			s.userSketch = newCountMinSketch()
+		}
+		for cohort := range s.CohortReactions {
+			s.CohortReactions[cohort] = make(map[events.ReactionType]int64)
+		}
+		s.DimensionReactions = make(map[string]map[string]map[events.ReactionType]int64)
+		s.sources = nil
+		s.minuteCounts = nil
+		s.velocity = nil
+		s.weightedReactions = 0
+		s.hype = nil
+		s.system = nil
+	}
+	if scope == ResetAll || scope == ResetPeakUsers {
+		s.setPeakLocked(time.Now().UTC())
+		s.resetSustainedPeakLocked(time.Now().UTC())
+	}
+	if scope == ResetAll {
+		for userID := range s.UserCohorts {
+			if _, active := s.ActiveUsers[userID]; !active {
+				delete(s.UserCohorts, userID)
+			}
+		}
+		s.uniqueSketch = nil
+		s.viewers = ViewerSplit{}
+		s.clientVersions = nil
+		s.StartTime = time.Now().UTC()
+		// Connected users are the new show's first joiners
+		s.audience = []audienceMinute{{Joined: int64(len(s.ActiveUsers))}}
+		s.departures = nil
+	}
+	s.LastActivity = time.Now().UTC()
+	atomic.AddInt64(&s.version, 1)
+}
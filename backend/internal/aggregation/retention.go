@@ -0,0 +1,102 @@
+package aggregation
+
+import "time"
+
+// audienceMinute counts the users who first joined and who finally left
+// during one minute of a session
+type audienceMinute struct {
+	Joined int64 `json:"joined"`
+	Left   int64 `json:"left"`
+}
+
+// RetentionPoint is one minute of a session's drop-off curve: how many of
+// the users who had joined by the end of the minute were still present
+type RetentionPoint struct {
+	Minute       int       `json:"minute"` // minutes since session start
+	Start        time.Time `json:"start"`
+	Joined       int64     `json:"joined"`  // users who had joined so far
+	Left         int64     `json:"left"`    // users who left during the minute
+	Present      int64     `json:"present"` // joiners still present at the end of the minute
+	RetentionPct float64   `json:"retention_pct"`
+}
+
+// minuteOfLocked returns the timeline minute at falls in. Callers must hold
+// s.mu.
+func (s *SessionStats) minuteOfLocked(at time.Time) int {
+	minute := int(at.Sub(s.StartTime) / time.Minute)
+	if minute < 0 {
+		minute = 0
+	}
+	if minute >= maxTimelineMinutes {
+		minute = maxTimelineMinutes - 1
+	}
+	for len(s.audience) <= minute {
+		s.audience = append(s.audience, audienceMinute{})
+	}
+	return minute
+}
+
+// recordArrivalLocked counts a user's first socket joining. A user coming
+// back cancels their departure instead, so the curve counts each joiner
+// once; compacted sessions no longer remember who left, and count returning
+// users as joining again. Callers must hold s.mu.
+func (s *SessionStats) recordArrivalLocked(userID string, at time.Time) {
+	if minute, departed := s.departures[userID]; departed {
+		s.audience[minute].Left--
+		delete(s.departures, userID)
+		return
+	}
+	s.audience[s.minuteOfLocked(at)].Joined++
+}
+
+// recordDepartureLocked counts a user's last socket leaving. Callers must
+// hold s.mu.
+func (s *SessionStats) recordDepartureLocked(userID string, at time.Time) {
+	minute := s.minuteOfLocked(at)
+	s.audience[minute].Left++
+	if s.uniqueSketch != nil {
+		return
+	}
+	if s.departures == nil {
+		s.departures = make(map[string]int)
+	}
+	s.departures[userID] = minute
+}
+
+// GetRetentionCurve returns the zero-filled drop-off curve from session
+// start to now, for finding where a show loses its audience
+func (s *SessionStats) GetRetentionCurve() []RetentionPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	minutes := int(time.Since(s.StartTime)/time.Minute) + 1
+	if minutes < len(s.audience) {
+		minutes = len(s.audience)
+	}
+	if minutes > maxTimelineMinutes {
+		minutes = maxTimelineMinutes
+	}
+
+	curve := make([]RetentionPoint, minutes)
+	var joined, left int64
+	for i := range curve {
+		var minute audienceMinute
+		if i < len(s.audience) {
+			minute = s.audience[i]
+		}
+		joined += minute.Joined
+		left += minute.Left
+		point := RetentionPoint{
+			Minute:  i,
+			Start:   s.StartTime.Add(time.Duration(i) * time.Minute),
+			Joined:  joined,
+			Left:    minute.Left,
+			Present: joined - left,
+		}
+		if joined > 0 {
+			point.RetentionPct = float64(point.Present) / float64(joined) * 100
+		}
+		curve[i] = point
+	}
+	return curve
+}
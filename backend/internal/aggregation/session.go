@@ -1,6 +1,7 @@
 package aggregation
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,12 +12,39 @@ import (
 // SessionStats holds real-time statistics for a session
 type SessionStats struct {
 	SessionID         string
-	ActiveUsers       map[string]int // UserID -> active socket connection count
+	ActiveUsers       map[string]*Presence // UserID -> open connections and their presence states
+	JoinTimes         map[string]time.Time // UserID -> time the user's first socket joined
+	UserReactions     map[string]int64     // UserID -> reactions sent this session
+	UserCohorts       map[string]string    // UserID -> audience cohort tag
+	CohortReactions   map[string]map[events.ReactionType]int64
+	DimensionReactions map[string]map[string]map[events.ReactionType]int64 // dimension -> value -> reactions
 	ReactionCounts    map[events.ReactionType]*int64
 	TotalReactions    *int64
 	PeakConcurrentUsers int
+	peak              PeakMoment // when PeakConcurrentUsers was reached
+	sustainedWindow   time.Duration      // how long concurrency must hold to count as the sustained peak; 0 disables it
+	sustainedPeak     int                // highest concurrency held for sustainedWindow
+	levels            []concurrencyLevel // higher concurrency not yet held for sustainedWindow
+	system            *SystemReactions // reactions emitted by integrations, nil until one is
 	StartTime         time.Time
 	LastActivity      time.Time
+	version           int64 // bumped on every mutation, used for cache validation
+	maxTrackedUsers   int          // compaction threshold for per-user state; 0 disables
+	uniqueSketch      *hyperLogLog // replaces the exact user record once compacted
+	userSketch        *countMinSketch // estimates per-user reactions in approximate sessions
+	minuteCounts      []map[events.ReactionType]int64 // reactions per minute since StartTime
+	audience          []audienceMinute                // first joins and final departures per minute since StartTime
+	departures        map[string]int                  // minute each departed user left, until they return
+	velocity          *rateWindow                     // per-second reactions for velocity milestones
+	boost             *Boost                          // the current or last boost, nil if never boosted
+	weightedReactions int64                           // reactions counted with the multiplier of the boost running then
+	hype              *rateWindow                     // per-second weighted reactions for the hype score
+	viewers           ViewerSplit                     // first-time vs returning users, counted at first join
+	unclassified      map[string]bool                 // users whose first join arrived before their viewer history
+	dimensions        []string                        // reaction attributes aggregated per value
+	maxDimensionValues int                            // distinct values tracked per dimension; 0 is unbounded
+	sources           map[events.ReactionSource]map[events.ReactionType]int64 // audience reactions per UI surface
+	clientVersions    map[string]int64                // joins per client app version
 	mu                sync.RWMutex
 }
 
@@ -26,7 +54,12 @@ func NewSessionStats(sessionID string) *SessionStats {
 	
 	return &SessionStats{
 		SessionID:      sessionID,
-		ActiveUsers:    make(map[string]int),
+		ActiveUsers:    make(map[string]*Presence),
+		JoinTimes:      make(map[string]time.Time),
+		UserReactions:  make(map[string]int64),
+		UserCohorts:    make(map[string]string),
+		CohortReactions: make(map[string]map[events.ReactionType]int64),
+		DimensionReactions: make(map[string]map[string]map[events.ReactionType]int64),
 		ReactionCounts: map[events.ReactionType]*int64{
 			events.ReactionLike:     new(int64),
 			events.ReactionLove:     new(int64),
@@ -47,29 +80,58 @@ func (s *SessionStats) AddUser(userID string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.ActiveUsers[userID]++
-	s.LastActivity = time.Now().UTC()
-	
-	currentCount := len(s.ActiveUsers)
-	if currentCount > s.PeakConcurrentUsers {
-		s.PeakConcurrentUsers = currentCount
+	presence, exists := s.ActiveUsers[userID]
+	if !exists {
+		presence = newPresence()
+		s.ActiveUsers[userID] = presence
+		s.JoinTimes[userID] = time.Now().UTC()
+		s.recordArrivalLocked(userID, s.JoinTimes[userID])
 	}
+	// New connections start out active until their first heartbeat says otherwise
+	presence.Connections++
+	presence.States[events.PresenceActive]++
+	presence.UpdatedAt = time.Now().UTC()
+	s.LastActivity = time.Now().UTC()
+	atomic.AddInt64(&s.version, 1)
+	s.recordPeakLocked(s.LastActivity)
+	s.recordConcurrencyLocked(s.LastActivity)
 	
-	return currentCount
+	return len(s.ActiveUsers)
 }
 
 // RemoveUser removes a user from the active users set
 func (s *SessionStats) RemoveUser(userID string) int {
+	return s.RemoveConnection(userID, events.PresenceActive)
+}
+
+// RemoveConnection closes one of the user's connections whose last reported
+// presence state was state, removing the user once none remain
+func (s *SessionStats) RemoveConnection(userID string, state events.PresenceState) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.ActiveUsers[userID] > 1 {
-		s.ActiveUsers[userID]--
+	if presence, exists := s.ActiveUsers[userID]; exists && presence.Connections > 1 {
+		presence.Connections--
+		presence.take(state)
+		presence.UpdatedAt = time.Now().UTC()
 	} else {
+		if exists {
+			s.recordDepartureLocked(userID, time.Now().UTC())
+		}
 		delete(s.ActiveUsers, userID)
+		delete(s.JoinTimes, userID)
+		if s.uniqueSketch != nil {
+			// Compacted sessions only keep per-user state for active users
+			delete(s.UserCohorts, userID)
+			if s.userSketch == nil {
+				delete(s.UserReactions, userID)
+			}
+		}
 	}
 	
 	s.LastActivity = time.Now().UTC()
+	atomic.AddInt64(&s.version, 1)
+	s.recordConcurrencyLocked(s.LastActivity)
 	
 	return len(s.ActiveUsers)
 }
@@ -82,12 +144,14 @@ func (s *SessionStats) IncrementReaction(reactionType events.ReactionType) int64
 
 	if !exists {
 		// Unknown reaction type, just increment total
+		atomic.AddInt64(&s.version, 1)
 		return atomic.AddInt64(s.TotalReactions, 1)
 	}
 
 	// Atomically increment both the specific reaction and total
 	atomic.AddInt64(counter, 1)
 	total := atomic.AddInt64(s.TotalReactions, 1)
+	atomic.AddInt64(&s.version, 1)
 
 	s.mu.Lock()
 	s.LastActivity = time.Now().UTC()
@@ -96,6 +160,270 @@ func (s *SessionStats) IncrementReaction(reactionType events.ReactionType) int64
 	return total
 }
 
+// CountReaction counts an audience reaction in the session's totals and
+// attributes it to its sender and their cohort, under a single acquisition
+// of the session lock
+func (s *SessionStats) CountReaction(userID string, reactionType events.ReactionType) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := atomic.AddInt64(s.TotalReactions, 1)
+	if counter, exists := s.ReactionCounts[reactionType]; exists {
+		atomic.AddInt64(counter, 1)
+	}
+	s.LastActivity = time.Now().UTC()
+	s.recordUserReactionLocked(userID, reactionType)
+	return total
+}
+
+// maxCohorts bounds the number of distinct cohorts tracked per session
+const maxCohorts = 32
+
+// overflowCohort collects users once a session exceeds maxCohorts
+const overflowCohort = "other"
+
+// AssignCohort tags a user with the audience cohort they joined under
+func (s *SessionStats) AssignCohort(userID, cohort string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, known := s.CohortReactions[cohort]; !known {
+		if len(s.CohortReactions) >= maxCohorts {
+			cohort = overflowCohort
+		}
+		if _, known := s.CohortReactions[cohort]; !known {
+			s.CohortReactions[cohort] = make(map[events.ReactionType]int64)
+		}
+	}
+	s.UserCohorts[userID] = cohort
+	if s.uniqueSketch != nil {
+		s.uniqueSketch.add(userID)
+	} else if s.maxTrackedUsers > 0 && len(s.UserCohorts) > s.maxTrackedUsers {
+		s.compactLocked()
+	}
+	atomic.AddInt64(&s.version, 1)
+}
+
+// ViewerSplit counts a session's users by whether they had watched any of
+// the tenant's sessions before
+type ViewerSplit struct {
+	FirstTime int64 `json:"first_time"`
+	Returning int64 `json:"returning"`
+}
+
+// ClassifyViewer counts a joining user as a first-time or returning viewer.
+// Only a user's first join of the session counts, so it must be called
+// before AssignCohort records them. A first join without a classification
+// leaves the user pending, to be classified once by a later call when their
+// viewer history has been recorded. Once compacted, the split is
+// approximate for users who leave and rejoin.
+func (s *SessionStats) ClassifyViewer(userID, kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, seen := s.UserCohorts[userID]
+	if seen && !s.unclassified[userID] {
+		return
+	}
+	switch kind {
+	case events.ViewerFirstTime:
+		s.viewers.FirstTime++
+	case events.ViewerReturning:
+		s.viewers.Returning++
+	default:
+		if !seen {
+			if s.unclassified == nil {
+				s.unclassified = make(map[string]bool)
+			}
+			s.unclassified[userID] = true
+		}
+		return
+	}
+	delete(s.unclassified, userID)
+	atomic.AddInt64(&s.version, 1)
+}
+
+// SetMaxTrackedUsers sets how many distinct users a session records exactly
+// before it compacts to approximate unique counts
+func (s *SessionStats) SetMaxTrackedUsers(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTrackedUsers = limit
+}
+
+// uniqueUsersLocked returns the number of distinct users who joined and
+// whether it is approximate. Callers must hold s.mu.
+func (s *SessionStats) uniqueUsersLocked() (int64, bool) {
+	if s.uniqueSketch != nil {
+		return s.uniqueSketch.count(), true
+	}
+	return int64(len(s.UserCohorts)), false
+}
+
+// cohortOf returns the user's cohort. Callers must hold s.mu.
+func (s *SessionStats) cohortOf(userID string) string {
+	if cohort, ok := s.UserCohorts[userID]; ok {
+		return cohort
+	}
+	return events.DefaultCohort
+}
+
+// RecordUserReaction attributes a reaction to the user who sent it and to
+// their cohort's breakdown
+func (s *SessionStats) RecordUserReaction(userID string, reactionType events.ReactionType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordUserReactionLocked(userID, reactionType)
+}
+
+// recordUserReactionLocked attributes a reaction to its sender and their
+// cohort. Once more users reacted than maxTrackedUsers, the inactive ones
+// are compacted away, and if that is not enough per-user counts become
+// estimates kept for the heaviest reactors only. Callers must hold s.mu.
+func (s *SessionStats) recordUserReactionLocked(userID string, reactionType events.ReactionType) {
+	if userID != "" && s.userSketch != nil {
+		s.recordApproxReactionLocked(userID)
+	} else if userID != "" { // anonymous reactions only count toward the cohort
+		s.UserReactions[userID]++
+		if s.maxTrackedUsers > 0 && len(s.UserReactions) > s.maxTrackedUsers {
+			s.compactLocked()
+			if len(s.UserReactions) > s.maxTrackedUsers {
+				s.approximateUserReactionsLocked()
+			}
+		}
+	}
+	cohort := s.cohortOf(userID)
+	if _, known := s.CohortReactions[cohort]; !known {
+		s.CohortReactions[cohort] = make(map[events.ReactionType]int64)
+	}
+	s.CohortReactions[cohort][reactionType]++
+	atomic.AddInt64(&s.version, 1)
+}
+
+// CohortStats summarizes engagement for one audience cohort
+type CohortStats struct {
+	ActiveUserCount int                           `json:"active_user_count"`
+	ReactionCounts  map[events.ReactionType]int64 `json:"reaction_counts"`
+}
+
+// getCohortStats builds per-cohort stats. Callers must hold s.mu.
+func (s *SessionStats) getCohortStats() map[string]CohortStats {
+	cohorts := make(map[string]CohortStats, len(s.CohortReactions))
+	for cohort, reactions := range s.CohortReactions {
+		counts := make(map[events.ReactionType]int64, len(reactions))
+		for reactionType, count := range reactions {
+			counts[reactionType] = count
+		}
+		cohorts[cohort] = CohortStats{ReactionCounts: counts}
+	}
+	for userID := range s.ActiveUsers {
+		cohort := s.cohortOf(userID)
+		c, exists := cohorts[cohort]
+		if !exists {
+			c = CohortStats{ReactionCounts: make(map[events.ReactionType]int64)}
+		}
+		c.ActiveUserCount++
+		cohorts[cohort] = c
+	}
+	return cohorts
+}
+
+// Version returns a counter that changes whenever the statistics change
+func (s *SessionStats) Version() int64 {
+	return atomic.LoadInt64(&s.version)
+}
+
+// GetLastActivity returns the time of the most recent change
+func (s *SessionStats) GetLastActivity() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LastActivity
+}
+
+// RosterEntry describes a single active user in a session
+type RosterEntry struct {
+	UserID        string    `json:"user_id"`
+	JoinedAt      time.Time `json:"joined_at"`
+	ReactionCount int64     `json:"reaction_count"`
+}
+
+// GetRoster returns a page of active users ordered by join time, along with
+// the total number of active users
+func (s *SessionStats) GetRoster(offset, limit int) ([]RosterEntry, int) {
+	s.mu.RLock()
+	roster := make([]RosterEntry, 0, len(s.ActiveUsers))
+	for userID := range s.ActiveUsers {
+		roster = append(roster, RosterEntry{
+			UserID:        userID,
+			JoinedAt:      s.JoinTimes[userID],
+			ReactionCount: s.userReactionsLocked(userID),
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(roster, func(i, j int) bool {
+		if roster[i].JoinedAt.Equal(roster[j].JoinedAt) {
+			return roster[i].UserID < roster[j].UserID
+		}
+		return roster[i].JoinedAt.Before(roster[j].JoinedAt)
+	})
+
+	total := len(roster)
+	if offset >= total {
+		return []RosterEntry{}, total
+	}
+	end := offset + limit
+	if limit <= 0 || end > total {
+		end = total
+	}
+	return roster[offset:end], total
+}
+
+// GetAverageWatchTime returns how long active users have been watching on
+// average, measured from each user's first join
+func (s *SessionStats) GetAverageWatchTime() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.ActiveUsers) == 0 {
+		return 0
+	}
+	now := time.Now()
+	var total time.Duration
+	for userID := range s.ActiveUsers {
+		total += now.Sub(s.JoinTimes[userID])
+	}
+	return total / time.Duration(len(s.ActiveUsers))
+}
+
+// LeaderboardEntry ranks one user by the reactions they sent
+type LeaderboardEntry struct {
+	UserID        string `json:"user_id"`
+	ReactionCount int64  `json:"reaction_count"`
+}
+
+// GetLeaderboard returns the users who sent the most reactions this
+// session, ties broken by user ID
+func (s *SessionStats) GetLeaderboard(limit int) []LeaderboardEntry {
+	s.mu.RLock()
+	board := make([]LeaderboardEntry, 0, len(s.UserReactions))
+	for userID, count := range s.UserReactions {
+		board = append(board, LeaderboardEntry{UserID: userID, ReactionCount: count})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].ReactionCount == board[j].ReactionCount {
+			return board[i].UserID < board[j].UserID
+		}
+		return board[i].ReactionCount > board[j].ReactionCount
+	})
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	return board
+}
+
 // GetActiveUserCount returns the current number of active users
 func (s *SessionStats) GetActiveUserCount() int {
 	s.mu.RLock()
@@ -137,11 +465,30 @@ type StatsSnapshot struct {
 	SessionID           string                       `json:"session_id"`
 	ActiveUserCount     int                          `json:"active_user_count"`
 	PeakConcurrentUsers int                          `json:"peak_concurrent_users"`
+	Peak                *PeakMoment                  `json:"peak,omitempty"`
+	SustainedPeakUsers  int                          `json:"sustained_peak_users"` // highest concurrency held for the sustained peak window
 	TotalReactions      int64                        `json:"total_reactions"`
+	WeightedReactions   int64                        `json:"weighted_reactions"` // total with boosted reactions counted at their multiplier
+	HypeScore           int64                        `json:"hype_score"`         // weighted reactions of the last minute
+	Boost               *Boost                       `json:"boost,omitempty"`    // running boost
 	ReactionCounts      map[events.ReactionType]int64 `json:"reaction_counts"`
+	SystemReactions     *SystemReactions             `json:"system_reactions,omitempty"`
 	StartTime           time.Time                    `json:"start_time"`
 	LastActivity        time.Time                    `json:"last_activity"`
 	Duration            float64                      `json:"duration_seconds"`
+	Version             int64                        `json:"version"`
+	Cohorts             map[string]CohortStats       `json:"cohorts,omitempty"`
+	UniqueUsers         int64                        `json:"unique_users"`
+	UniqueUsersApprox   bool                         `json:"unique_users_approximate,omitempty"`
+	Accuracy            Accuracy                     `json:"accuracy"`
+	ErrorBounds         *ErrorBounds                 `json:"error_bounds,omitempty"`
+	WatchingUserCount   int                          `json:"watching_user_count"`
+	Presence            map[events.PresenceState]int `json:"presence,omitempty"`
+	Viewers             *ViewerSplit                 `json:"viewers,omitempty"`
+	Dimensions          map[string]map[string]DimensionStats `json:"dimensions,omitempty"`
+	Trend               *ReactionTrend               `json:"trend,omitempty"`
+	ReactionSources     map[events.ReactionSource]SourceStats `json:"reaction_sources,omitempty"` // per UI surface
+	ClientVersions      map[string]int64             `json:"client_versions,omitempty"`  // joins per client app version
 }
 
 // GetSnapshot returns a snapshot of the current statistics
@@ -149,14 +496,40 @@ func (s *SessionStats) GetSnapshot() StatsSnapshot {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	uniqueUsers, approx := s.uniqueUsersLocked()
+	presence := s.presenceCountsLocked()
+	var viewers *ViewerSplit
+	if s.viewers != (ViewerSplit{}) {
+		split := s.viewers
+		viewers = &split
+	}
 	return StatsSnapshot{
 		SessionID:           s.SessionID,
 		ActiveUserCount:     len(s.ActiveUsers),
 		PeakConcurrentUsers: s.PeakConcurrentUsers,
+		Peak:                s.peakLocked(),
+		SustainedPeakUsers:  s.sustainedPeakLocked(time.Now().UTC()),
 		TotalReactions:      atomic.LoadInt64(s.TotalReactions),
+		WeightedReactions:   s.weightedReactions,
+		HypeScore:           s.hypeScoreLocked(time.Now()),
+		Boost:               s.boostLocked(time.Now()),
 		ReactionCounts:      s.GetAllReactionCounts(),
+		SystemReactions:     s.systemLocked(),
 		StartTime:           s.StartTime,
 		LastActivity:        s.LastActivity,
 		Duration:            time.Since(s.StartTime).Seconds(),
+		Version:             atomic.LoadInt64(&s.version),
+		Cohorts:             s.getCohortStats(),
+		UniqueUsers:         uniqueUsers,
+		UniqueUsersApprox:   approx,
+		Accuracy:            s.accuracyLocked(),
+		ErrorBounds:         s.errorBoundsLocked(),
+		WatchingUserCount:   presence[events.PresenceActive],
+		Presence:            presence,
+		Viewers:             viewers,
+		Dimensions:          s.getDimensionStats(),
+		Trend:               s.trendLocked(time.Now()),
+		ReactionSources:     s.getSourceStats(),
+		ClientVersions:      s.clientVersionsLocked(),
 	}
 }
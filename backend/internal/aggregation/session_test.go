@@ -1,8 +1,13 @@
 package aggregation
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/jrudman25/livepulse/internal/events"
 )
@@ -22,8 +27,8 @@ func TestSessionStats_ActiveUserConcurrency(t *testing.T) {
 	if count != 1 {
 		t.Errorf("Expected 1 mathematically distinct active user, got %d", count)
 	}
-	if stats.ActiveUsers["userA"] != 2 {
-		t.Errorf("Expected 2 mathematical overlapping socket connections for userA, got %d", stats.ActiveUsers["userA"])
+	if stats.ActiveUsers["userA"].Connections != 2 {
+		t.Errorf("Expected 2 mathematical overlapping socket connections for userA, got %d", stats.ActiveUsers["userA"].Connections)
 	}
 
 	// 3. First socket cleanly disconnects
@@ -31,8 +36,8 @@ func TestSessionStats_ActiveUserConcurrency(t *testing.T) {
 	if count != 1 {
 		t.Errorf("Expected 1 active user to legally remain connected, got %d", count)
 	}
-	if stats.ActiveUsers["userA"] != 1 {
-		t.Errorf("Expected 1 remaining active socket for userA, got %d", stats.ActiveUsers["userA"])
+	if stats.ActiveUsers["userA"].Connections != 1 {
+		t.Errorf("Expected 1 remaining active socket for userA, got %d", stats.ActiveUsers["userA"].Connections)
 	}
 
 	// 4. Second socket fully disconnects
@@ -60,8 +65,8 @@ func TestSessionStats_ConcurrencyStress(t *testing.T) {
 	}
 	wg.Wait()
 
-	if stats.ActiveUsers["userA"] != workers {
-		t.Errorf("Expected %d socket states, got %d", workers, stats.ActiveUsers["userA"])
+	if stats.ActiveUsers["userA"].Connections != workers {
+		t.Errorf("Expected %d socket states, got %d", workers, stats.ActiveUsers["userA"].Connections)
 	}
 	if stats.GetActiveUserCount() != 1 {
 		t.Errorf("Expected 1 distinctly active user, got %d", stats.GetActiveUserCount())
@@ -101,3 +106,1012 @@ func TestSessionStats_Reactions(t *testing.T) {
 		t.Errorf("Expected 3 total reactions legally collected, got %d", total)
 	}
 }
+
+func TestSessionStats_RosterPagination(t *testing.T) {
+	stats := NewSessionStats("test-session-roster")
+
+	stats.AddUser("userA")
+	stats.AddUser("userB")
+	stats.AddUser("userC")
+	stats.RecordUserReaction("userB", events.ReactionFire)
+	stats.RecordUserReaction("userB", events.ReactionFire)
+
+	page, total := stats.GetRoster(0, 2)
+	if total != 3 {
+		t.Errorf("Expected 3 active users in roster, got %d", total)
+	}
+	if len(page) != 2 {
+		t.Fatalf("Expected page of 2 roster entries, got %d", len(page))
+	}
+
+	page, _ = stats.GetRoster(2, 2)
+	if len(page) != 1 {
+		t.Fatalf("Expected final page of 1 roster entry, got %d", len(page))
+	}
+
+	byUser := make(map[string]RosterEntry)
+	all, _ := stats.GetRoster(0, 0)
+	for _, entry := range all {
+		byUser[entry.UserID] = entry
+	}
+	if byUser["userB"].ReactionCount != 2 {
+		t.Errorf("Expected userB reaction count of 2, got %d", byUser["userB"].ReactionCount)
+	}
+	if byUser["userA"].JoinedAt.IsZero() {
+		t.Errorf("Expected userA join time to be recorded")
+	}
+
+	// Leaving fully removes the user from the roster
+	stats.RemoveUser("userA")
+	if _, total = stats.GetRoster(0, 10); total != 2 {
+		t.Errorf("Expected 2 users after userA left, got %d", total)
+	}
+}
+
+func TestSessionStats_Leaderboard(t *testing.T) {
+	stats := NewSessionStats("test-session-leaderboard")
+
+	stats.RecordUserReaction("userA", events.ReactionFire)
+	stats.RecordUserReaction("userB", events.ReactionFire)
+	stats.RecordUserReaction("userB", events.ReactionLove)
+	stats.RecordUserReaction("userC", events.ReactionFire)
+	stats.RecordUserReaction("", events.ReactionFire) // anonymous
+
+	board := stats.GetLeaderboard(2)
+	if len(board) != 2 {
+		t.Fatalf("Expected leaderboard of 2 entries, got %d", len(board))
+	}
+	if board[0].UserID != "userB" || board[0].ReactionCount != 2 {
+		t.Errorf("Expected userB to lead with 2 reactions, got %+v", board[0])
+	}
+	if board[1].UserID != "userA" {
+		t.Errorf("Expected userA to win the tie on user ID, got %s", board[1].UserID)
+	}
+	if all := stats.GetLeaderboard(0); len(all) != 3 {
+		t.Errorf("Expected anonymous reactions to stay off the leaderboard, got %d entries", len(all))
+	}
+}
+
+func TestSessionStats_ResetScopes(t *testing.T) {
+	stats := NewSessionStats("test-session-reset")
+	stats.AddUser("userA")
+	stats.AddUser("userB")
+	stats.RemoveUser("userB")
+	stats.IncrementReaction(events.ReactionFire)
+	stats.RecordUserReaction("userA", events.ReactionFire)
+
+	stats.Reset(ResetPeakUsers)
+	if stats.PeakConcurrentUsers != 1 {
+		t.Errorf("Expected peak lowered to the 1 active user, got %d", stats.PeakConcurrentUsers)
+	}
+	if stats.GetTotalReactions() != 1 {
+		t.Errorf("Expected a peak reset to keep reactions, got %d", stats.GetTotalReactions())
+	}
+
+	stats.Reset(ResetReactions)
+	snapshot := stats.GetSnapshot()
+	if snapshot.TotalReactions != 0 || snapshot.ReactionCounts[events.ReactionFire] != 0 {
+		t.Errorf("Expected reaction counts cleared, got %+v", snapshot.ReactionCounts)
+	}
+	if len(stats.GetLeaderboard(0)) != 0 {
+		t.Errorf("Expected per-user reaction counts cleared")
+	}
+	if snapshot.ActiveUserCount != 1 {
+		t.Errorf("Expected userA to stay active, got %d", snapshot.ActiveUserCount)
+	}
+
+	if ResetScope("everything").IsValid() {
+		t.Errorf("Expected unknown scopes to be rejected")
+	}
+}
+
+func TestManager_CohortBreakdown(t *testing.T) {
+	manager := NewManager()
+
+	manager.ProcessEvent(events.CohortJoinSessionEvent("s1", "reporter", "Press"))
+	manager.ProcessEvent(events.CohortJoinSessionEvent("s1", "superfan", "vip"))
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "viewer"))
+	manager.ProcessEvent(events.ReactionEvent("s1", "superfan", events.ReactionFire))
+	manager.ProcessEvent(events.ReactionEvent("s1", "superfan", events.ReactionFire))
+	manager.ProcessEvent(events.ReactionEvent("s1", "viewer", events.ReactionLike))
+
+	stats, _ := manager.GetSession("s1")
+	cohorts := stats.GetSnapshot().Cohorts
+
+	if cohorts["press"].ActiveUserCount != 1 {
+		t.Errorf("Expected 1 active press user, got %d", cohorts["press"].ActiveUserCount)
+	}
+	if cohorts["vip"].ReactionCounts[events.ReactionFire] != 2 {
+		t.Errorf("Expected 2 vip fire reactions, got %d", cohorts["vip"].ReactionCounts[events.ReactionFire])
+	}
+	if cohorts[events.DefaultCohort].ReactionCounts[events.ReactionLike] != 1 {
+		t.Errorf("Expected untagged users to land in the default cohort")
+	}
+}
+
+func TestManager_ViewerSplit(t *testing.T) {
+	manager := NewManager()
+
+	join := func(userID, kind string) {
+		event := events.JoinSessionEvent("s1", userID)
+		event.SetViewer(kind)
+		manager.ProcessEvent(event)
+	}
+	join("newcomer", events.ViewerFirstTime)
+	join("regular", events.ViewerReturning)
+	join("regular", events.ViewerReturning) // second socket of the same user
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "unclassified"))
+
+	stats, _ := manager.GetSession("s1")
+	viewers := stats.GetSnapshot().Viewers
+	if viewers == nil {
+		t.Fatal("Expected a viewer split in the snapshot")
+	}
+	if viewers.FirstTime != 1 || viewers.Returning != 1 {
+		t.Errorf("Expected 1 first-time and 1 returning viewer, got %+v", *viewers)
+	}
+
+	data, _ := json.Marshal(stats)
+	restored := NewSessionStats("")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if split := restored.GetSnapshot().Viewers; split == nil || *split != *viewers {
+		t.Errorf("Expected the viewer split to survive a round trip, got %+v", split)
+	}
+}
+
+func TestManager_PresenceStates(t *testing.T) {
+	manager := NewManager()
+
+	// "laptop" has two connections: one watching, one on a backgrounded phone
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "laptop"))
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "laptop"))
+	manager.ProcessEvent(events.PresenceEvent("s1", "laptop", events.PresenceActive, events.PresenceBackground))
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "mobile"))
+	manager.ProcessEvent(events.PresenceEvent("s1", "mobile", events.PresenceActive, events.PresenceBackground))
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "desk"))
+	manager.ProcessEvent(events.PresenceEvent("s1", "desk", events.PresenceActive, events.PresenceIdle))
+
+	stats, _ := manager.GetSession("s1")
+	snapshot := stats.GetSnapshot()
+	if snapshot.ActiveUserCount != 3 {
+		t.Errorf("Expected 3 connected users, got %d", snapshot.ActiveUserCount)
+	}
+	if snapshot.WatchingUserCount != 1 {
+		t.Errorf("Expected 1 actively watching user, got %d", snapshot.WatchingUserCount)
+	}
+	if snapshot.Presence[events.PresenceBackground] != 1 || snapshot.Presence[events.PresenceIdle] != 1 {
+		t.Errorf("Unexpected presence breakdown: %v", snapshot.Presence)
+	}
+
+	// Closing the watching connection leaves only the backgrounded one
+	manager.ProcessEvent(events.PresenceLeaveSessionEvent("s1", "laptop", events.PresenceActive))
+	if watching := stats.GetWatchingUserCount(); watching != 0 {
+		t.Errorf("Expected 0 watching users after the laptop left, got %d", watching)
+	}
+	if state := stats.ActiveUsers["laptop"].State(); state != events.PresenceBackground {
+		t.Errorf("Expected laptop to be backgrounded, got %s", state)
+	}
+}
+
+func TestPresence_DecodesLegacyConnectionCounts(t *testing.T) {
+	var presence Presence
+	if err := json.Unmarshal([]byte(`2`), &presence); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if presence.Connections != 2 || presence.State() != events.PresenceActive {
+		t.Errorf("Expected 2 active connections, got %+v", presence)
+	}
+}
+
+func TestSessionStats_CompactsPastTrackedUserCap(t *testing.T) {
+	stats := NewSessionStats("big-session")
+	stats.SetMaxTrackedUsers(100)
+
+	// 1,000 users pass through, only the last one stays connected
+	for i := 0; i < 1000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		stats.AssignCohort(userID, events.DefaultCohort)
+		stats.AddUser(userID)
+		stats.RecordUserReaction(userID, events.ReactionLike)
+		if i < 999 {
+			stats.RemoveUser(userID)
+		}
+	}
+
+	usage := stats.MemoryUsage()
+	if !usage.Compacted {
+		t.Fatalf("Expected session to compact after exceeding the cap")
+	}
+	if usage.TrackedUsers != 1 || usage.UserReactionEntries != 1 {
+		t.Errorf("Expected only the active user to be tracked, got %d users and %d reaction entries", usage.TrackedUsers, usage.UserReactionEntries)
+	}
+
+	snapshot := stats.GetSnapshot()
+	if !snapshot.UniqueUsersApprox {
+		t.Errorf("Expected unique user count to be marked approximate")
+	}
+	if snapshot.UniqueUsers < 970 || snapshot.UniqueUsers > 1030 {
+		t.Errorf("Expected roughly 1000 unique users, got %d", snapshot.UniqueUsers)
+	}
+	if snapshot.Cohorts[events.DefaultCohort].ReactionCounts[events.ReactionLike] != 1000 {
+		t.Errorf("Expected aggregate reaction counts to survive compaction")
+	}
+}
+
+func TestSessionStats_BoundsReactionsOfUsersWhoNeverJoined(t *testing.T) {
+	stats := NewSessionStats("ingested")
+	stats.SetMaxTrackedUsers(100)
+
+	// Ingested reactions arrive without joins, so compaction alone cannot
+	// shrink the per-user counts
+	for i := 0; i < 1000; i++ {
+		stats.CountReaction(fmt.Sprintf("user-%d", i), events.ReactionLike)
+	}
+	for i := 0; i < 5; i++ {
+		stats.CountReaction("heavy", events.ReactionFire)
+	}
+
+	usage := stats.MemoryUsage()
+	if usage.UserReactionEntries > maxApproxLeaders {
+		t.Errorf("Expected at most %d reaction entries, got %d", maxApproxLeaders, usage.UserReactionEntries)
+	}
+	if stats.GetErrorBounds() == nil {
+		t.Errorf("Expected per-user counts to be marked approximate")
+	}
+	if got := stats.GetTotalReactions(); got != 1005 {
+		t.Errorf("Expected 1005 total reactions, got %d", got)
+	}
+	if got := stats.UserReactions["heavy"]; got < 5 {
+		t.Errorf("Expected the heaviest reactor to keep at least 5 reactions, got %d", got)
+	}
+}
+
+type memoryStateStore struct {
+	states map[string][]byte
+	mu     sync.Mutex
+}
+
+func (m *memoryStateStore) SaveSessionStates(_ context.Context, states map[string][]byte, removed []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states == nil {
+		m.states = make(map[string][]byte)
+	}
+	for sessionID, data := range states {
+		m.states[sessionID] = data
+	}
+	for _, sessionID := range removed {
+		delete(m.states, sessionID)
+	}
+	return nil
+}
+
+func (m *memoryStateStore) LoadSessionStates(_ context.Context, sessionIDs ...string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states := make(map[string][]byte)
+	for sessionID, data := range m.states {
+		states[sessionID] = data
+	}
+	if len(sessionIDs) == 0 {
+		return states, nil
+	}
+	selected := make(map[string][]byte)
+	for _, sessionID := range sessionIDs {
+		if data, exists := states[sessionID]; exists {
+			selected[sessionID] = data
+		}
+	}
+	return selected, nil
+}
+
+func TestSessionStats_StateRoundTrip(t *testing.T) {
+	stats := NewSessionStats("s1")
+	stats.AssignCohort("userA", "vip")
+	stats.AddUser("userA")
+	stats.IncrementReaction(events.ReactionFire)
+	stats.RecordUserReaction("userA", events.ReactionFire)
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := NewSessionStats("")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if restored.SessionID != "s1" || restored.GetReactionCount(events.ReactionFire) != 1 || restored.GetTotalReactions() != 1 {
+		t.Errorf("Expected reaction counts to survive a round trip, got %+v", restored.GetSnapshot())
+	}
+	if restored.UserReactions["userA"] != 1 || restored.UserCohorts["userA"] != "vip" {
+		t.Errorf("Expected per-user state to survive a round trip")
+	}
+	if restored.Version() != stats.Version() || !restored.StartTime.Equal(stats.StartTime) {
+		t.Errorf("Expected version and start time to be preserved")
+	}
+
+	// Restored counters keep counting from where they left off
+	restored.IncrementReaction(events.ReactionFire)
+	if restored.GetReactionCount(events.ReactionFire) != 2 {
+		t.Errorf("Expected restored counter to increment to 2, got %d", restored.GetReactionCount(events.ReactionFire))
+	}
+}
+
+func TestManager_CheckpointAndRestore(t *testing.T) {
+	store := &memoryStateStore{}
+	manager := NewManager()
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	manager.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionLike))
+	manager.GetOrCreateSession("gone")
+	manager.RemoveSession("gone")
+
+	if err := manager.Checkpoint(context.Background(), store); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	fresh := NewManager()
+	restored, err := fresh.Restore(context.Background(), store, nil)
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected 1 restored session, got %d (%v)", restored, err)
+	}
+	stats, _ := fresh.GetSession("s1")
+	if stats.GetTotalReactions() != 1 || stats.PeakConcurrentUsers != 1 {
+		t.Errorf("Expected warm counters after restore, got %+v", stats.GetSnapshot())
+	}
+	if stats.GetActiveUserCount() != 0 {
+		t.Errorf("Expected connections to be counted again on reconnect, got %d active", stats.GetActiveUserCount())
+	}
+}
+
+func TestManager_CheckpointsLeaveOtherInstancesSessionsAlone(t *testing.T) {
+	store := &memoryStateStore{}
+	owners := map[string]string{"s1": "a", "s2": "b"}
+	instance := func(id string) *Manager {
+		manager := NewManager()
+		manager.SetOwnership(func(sessionID string) bool { return owners[sessionID] == id })
+		return manager
+	}
+	a, b := instance("a"), instance("b")
+	a.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionLike))
+	b.ProcessEvent(events.ReactionEvent("s2", "userB", events.ReactionLike))
+	b.ProcessEvent(events.ReactionEvent("s2", "userB", events.ReactionLike))
+
+	// A stale copy of s2 on instance a is not written over b's checkpoint
+	a.ProcessEvent(events.ReactionEvent("s2", "userB", events.ReactionLike))
+	a.Checkpoint(context.Background(), store)
+	b.Checkpoint(context.Background(), store)
+	a.Checkpoint(context.Background(), store)
+	if len(store.states) != 2 {
+		t.Fatalf("Expected both instances' checkpoints to survive, got %d", len(store.states))
+	}
+
+	// Restarting a restores only what it claims
+	restarted := NewManager()
+	restored, err := restarted.Restore(context.Background(), store, func(sessionID string) bool { return owners[sessionID] == "a" })
+	if err != nil || restored != 1 {
+		t.Fatalf("Expected 1 restored session, got %d (%v)", restored, err)
+	}
+	if _, exists := restarted.GetSession("s2"); exists {
+		t.Errorf("Expected another instance's session not to be restored")
+	}
+
+	// Ending a session deletes only its checkpoint
+	b.RemoveSession("s2")
+	b.Checkpoint(context.Background(), store)
+	if _, exists := store.states["s2"]; exists {
+		t.Errorf("Expected removed session's checkpoint to be deleted")
+	}
+	if _, exists := store.states["s1"]; !exists {
+		t.Errorf("Expected other sessions' checkpoints to be kept")
+	}
+}
+
+func TestManager_TakeOverResumesThePreviousOwnersCheckpoint(t *testing.T) {
+	store := &memoryStateStore{}
+	previous := NewManager()
+	previous.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	previous.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionFire))
+	previous.ProcessEvent(events.ReactionEvent("s2", "userA", events.ReactionFire))
+	previous.Checkpoint(context.Background(), store)
+
+	successor := NewManager()
+	resumed, err := successor.TakeOver(context.Background(), store, "s1")
+	if err != nil || !resumed {
+		t.Fatalf("Expected s1 to resume from its checkpoint, got %v (%v)", resumed, err)
+	}
+	stats, exists := successor.GetSession("s1")
+	if !exists || stats.GetReactionCount(events.ReactionFire) != 1 {
+		t.Fatalf("Expected the checkpointed reaction to carry over")
+	}
+	if stats.GetActiveUserCount() != 0 {
+		t.Errorf("Expected the previous owner's connections not to carry over")
+	}
+	if _, exists := successor.GetSession("s2"); exists {
+		t.Errorf("Expected only the taken over session to be loaded")
+	}
+
+	resumed, err = successor.TakeOver(context.Background(), store, "new")
+	if err != nil || resumed {
+		t.Errorf("Expected a session without a checkpoint not to resume, got %v (%v)", resumed, err)
+	}
+}
+
+func TestManager_ReloadMirrorsCheckpoint(t *testing.T) {
+	store := &memoryStateStore{}
+	writer := NewManager()
+	writer.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	writer.Checkpoint(context.Background(), store)
+
+	reader := NewManager()
+	reader.GetOrCreateSession("stale")
+	if _, err := reader.Reload(context.Background(), store); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if _, exists := reader.GetSession("stale"); exists {
+		t.Errorf("Expected sessions missing from the checkpoint to be dropped")
+	}
+	stats, exists := reader.GetSession("s1")
+	if !exists || stats.GetActiveUserCount() != 1 {
+		t.Errorf("Expected read-only mirror to keep active users from the checkpoint")
+	}
+}
+
+func TestManager_ReloadServesEveryOwnersCheckpoint(t *testing.T) {
+	store := &memoryStateStore{}
+	for _, sessionID := range []string{"s1", "s2"} {
+		writer := NewManager()
+		writer.SetOwnership(func(id string) bool { return id == sessionID })
+		writer.ProcessEvent(events.ReactionEvent(sessionID, "userA", events.ReactionLike))
+		writer.Checkpoint(context.Background(), store)
+	}
+
+	reader := NewManager()
+	if loaded, err := reader.Reload(context.Background(), store); err != nil || loaded != 2 {
+		t.Fatalf("Expected both instances' sessions, got %d (%v)", loaded, err)
+	}
+
+	// A corrupt checkpoint keeps serving the last good copy
+	store.states["s2"] = []byte("{")
+	reader.Reload(context.Background(), store)
+	stats, exists := reader.GetSession("s2")
+	if !exists || stats.GetTotalReactions() != 1 {
+		t.Errorf("Expected the previous copy of s2 to be kept")
+	}
+}
+
+func TestManager_ReactionsByMinute(t *testing.T) {
+	manager := NewManager()
+	stats := manager.GetOrCreateSession("s1")
+	stats.StartTime = stats.StartTime.Add(-3 * time.Minute)
+
+	first := events.ReactionEvent("s1", "u1", events.ReactionFire)
+	first.Timestamp = stats.StartTime.Add(30 * time.Second)
+	late := events.ReactionEvent("s1", "u1", events.ReactionCheer)
+	late.Timestamp = stats.StartTime.Add(2*time.Minute + time.Second)
+	manager.ProcessEvent(first)
+	manager.ProcessEvent(late)
+	manager.ProcessEvent(events.ReactionEvent("s1", "u2", events.ReactionFire))
+
+	buckets := stats.GetReactionsByMinute()
+	if len(buckets) != 4 {
+		t.Fatalf("Expected 4 zero-filled minutes, got %d", len(buckets))
+	}
+	if buckets[0].Counts[events.ReactionFire] != 1 || buckets[1].Total != 0 {
+		t.Errorf("Expected minute 0 to hold one fire reaction and minute 1 to be empty, got %+v", buckets[:2])
+	}
+	if buckets[2].Counts[events.ReactionCheer] != 1 || buckets[3].Counts[events.ReactionFire] != 1 {
+		t.Errorf("Expected reactions bucketed by event timestamp, got %+v", buckets[2:])
+	}
+}
+
+func TestRateWindow_CountsOnlyRecentSeconds(t *testing.T) {
+	w := &rateWindow{lastSecond: 1000}
+	for i := 0; i < 5; i++ {
+		w.add(1000)
+	}
+	w.add(1030)
+	w.add(1059)
+
+	if got := w.sum(1059, time.Minute); got != 7 {
+		t.Errorf("Expected 7 reactions in the last minute, got %d", got)
+	}
+	if got := w.sum(1059, 30*time.Second); got != 2 {
+		t.Errorf("Expected 2 reactions in the last 30s, got %d", got)
+	}
+
+	// A long quiet spell clears every slot
+	w.add(1059 + int64(velocitySlots) + 5)
+	if got := w.sum(1059+int64(velocitySlots)+5, MaxVelocityWindow); got != 1 {
+		t.Errorf("Expected stale slots to be cleared, got %d", got)
+	}
+}
+
+func TestManager_ReactionVelocity(t *testing.T) {
+	manager := NewManager()
+	for i := 0; i < 10; i++ {
+		manager.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionFire))
+	}
+
+	stats, _ := manager.GetSession("s1")
+	if got := stats.GetReactionVelocity(time.Minute); got != 10 {
+		t.Errorf("Expected 10 reactions in the last minute, got %d", got)
+	}
+}
+
+func TestSessionStats_ReactionTrendAgainstMedianBaseline(t *testing.T) {
+	stats := NewSessionStats("s1")
+	now := time.Now()
+	stats.StartTime = now.Add(-4*time.Minute - 30*time.Second)
+	if stats.GetReactionTrend() != nil {
+		t.Fatal("Expected no trend without reactions")
+	}
+
+	// A spike in minute 1 must not lift the median baseline
+	for minute, count := range []int{2, 40, 4, 4} {
+		for i := 0; i < count; i++ {
+			stats.recordMinute(events.ReactionFire, stats.StartTime.Add(time.Duration(minute)*time.Minute+time.Second))
+		}
+	}
+	for i := 0; i < 12; i++ {
+		stats.recordVelocity(now)
+	}
+
+	trend := stats.GetSnapshot().Trend
+	if trend == nil {
+		t.Fatal("Expected a trend once the baseline has enough minutes")
+	}
+	if trend.BaselinePerMinute != 4 || trend.BaselineMinutes != 4 {
+		t.Errorf("Expected a baseline of 4/min over 4 minutes, got %v over %d", trend.BaselinePerMinute, trend.BaselineMinutes)
+	}
+	if trend.CurrentPerMinute != 12 || trend.ChangePercent != 200 {
+		t.Errorf("Expected 12/min at +200%%, got %d at %v%%", trend.CurrentPerMinute, trend.ChangePercent)
+	}
+}
+
+func TestRecomputeRange_RebuildsTimelineForWholeMinutes(t *testing.T) {
+	manager := NewManager()
+	stats := manager.GetOrCreateSession("s1")
+	stats.StartTime = stats.StartTime.Add(-5 * time.Minute)
+	start := stats.StartTime
+
+	var logged []*events.Event
+	for _, offset := range []time.Duration{30 * time.Second, 90 * time.Second, 100 * time.Second, 110 * time.Second, 4 * time.Minute} {
+		reactionType := events.ReactionFire
+		if offset == 100*time.Second {
+			reactionType = events.ReactionCheer
+		}
+		event := events.ReactionEvent("s1", "u1", reactionType)
+		event.Timestamp = start.Add(offset)
+		logged = append(logged, event)
+	}
+
+	metrics := RecomputeRange(start, start.Add(70*time.Second), start.Add(3*time.Minute), logged)
+	if !metrics.Since.Equal(start.Add(time.Minute)) || !metrics.Until.Equal(start.Add(3*time.Minute)) {
+		t.Fatalf("Expected the range widened to minutes 1-2, got %v to %v", metrics.Since, metrics.Until)
+	}
+	if metrics.TotalReactions != 3 || len(metrics.Timeline) != 2 {
+		t.Fatalf("Expected 3 reactions over 2 minutes, got %d over %d", metrics.TotalReactions, len(metrics.Timeline))
+	}
+	if metrics.ReactionsPerMinute != 1.5 {
+		t.Errorf("Expected 1.5 reactions per minute, got %v", metrics.ReactionsPerMinute)
+	}
+	if metrics.Highlight == nil || metrics.Highlight.Minute != 1 || metrics.Mood != events.ReactionFire {
+		t.Errorf("Expected minute 1 as highlight and fire as mood, got %+v and %q", metrics.Highlight, metrics.Mood)
+	}
+
+	stats.ReplaceMinutes(metrics.Timeline)
+	buckets := stats.GetReactionsByMinute()
+	if buckets[1].Total != 3 || buckets[2].Total != 0 {
+		t.Errorf("Expected the live timeline rebuilt for minutes 1-2, got %+v", buckets[1:3])
+	}
+}
+
+func TestManager_AggregatesReactionsByDimension(t *testing.T) {
+	manager := NewManager()
+	manager.SetDimensions([]string{"team"}, 2)
+
+	send := func(team string, reactionType events.ReactionType) {
+		var attributes map[string]string
+		if team != "" {
+			attributes = map[string]string{"team": team, "ignored": "x"}
+		}
+		manager.ProcessEvent(events.AttributedReactionEvent("s1", "u1", reactionType, attributes))
+	}
+	send("red", events.ReactionFire)
+	send("red", events.ReactionCheer)
+	send("blue", events.ReactionFire)
+	send("green", events.ReactionFire) // over the cap of two values
+	send("", events.ReactionFire)
+
+	stats, _ := manager.GetSession("s1")
+	snapshot := stats.GetSnapshot()
+	teams := snapshot.Dimensions["team"]
+	if teams["red"].TotalReactions != 2 || teams["red"].ReactionCounts[events.ReactionCheer] != 1 {
+		t.Errorf("Expected 2 red reactions including 1 cheer, got %+v", teams["red"])
+	}
+	if teams["blue"].TotalReactions != 1 {
+		t.Errorf("Expected 1 blue reaction, got %+v", teams["blue"])
+	}
+	if teams[overflowDimensionValue].TotalReactions != 1 {
+		t.Errorf("Expected values past the cap to count as %q, got %+v", overflowDimensionValue, teams)
+	}
+	if _, tracked := snapshot.Dimensions["ignored"]; tracked {
+		t.Errorf("Expected unconfigured attributes to be ignored")
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Failed to serialize stats: %v", err)
+	}
+	restored := NewSessionStats("")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Failed to restore stats: %v", err)
+	}
+	if got := restored.GetSnapshot().Dimensions["team"]["red"].TotalReactions; got != 2 {
+		t.Errorf("Expected restored stats to keep 2 red reactions, got %d", got)
+	}
+}
+
+func TestManager_AggregatesReactionsBySource(t *testing.T) {
+	manager := NewManager()
+	send := func(source events.ReactionSource, reactionType events.ReactionType) {
+		manager.ProcessEvent(events.SourcedReactionEvent("s1", "u1", reactionType, source, nil))
+	}
+	send(events.ReactionSourceOverlayButton, events.ReactionFire)
+	send(events.ReactionSourceOverlayButton, events.ReactionCheer)
+	send(events.ReactionSourceTVRemote, events.ReactionFire)
+	send("smart_fridge", events.ReactionFire) // unvalidated sources count as other
+	send("", events.ReactionFire)
+
+	stats, _ := manager.GetSession("s1")
+	snapshot := stats.GetSnapshot()
+	overlay := snapshot.ReactionSources[events.ReactionSourceOverlayButton]
+	if overlay.TotalReactions != 2 || overlay.ReactionCounts[events.ReactionCheer] != 1 {
+		t.Errorf("Expected 2 overlay reactions including 1 cheer, got %+v", overlay)
+	}
+	if snapshot.ReactionSources[events.ReactionSourceTVRemote].TotalReactions != 1 {
+		t.Errorf("Expected 1 TV remote reaction, got %+v", snapshot.ReactionSources)
+	}
+	if snapshot.ReactionSources[events.ReactionSourceOther].TotalReactions != 1 {
+		t.Errorf("Expected unknown sources to count as other, got %+v", snapshot.ReactionSources)
+	}
+	if len(snapshot.ReactionSources) != 3 || snapshot.TotalReactions != 5 {
+		t.Errorf("Expected unattributed reactions in the total only, got %d sources and %d reactions", len(snapshot.ReactionSources), snapshot.TotalReactions)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Failed to serialize stats: %v", err)
+	}
+	restored := NewSessionStats("")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Failed to restore stats: %v", err)
+	}
+	if got := restored.GetSnapshot().ReactionSources[events.ReactionSourceOverlayButton].TotalReactions; got != 2 {
+		t.Errorf("Expected restored stats to keep 2 overlay reactions, got %d", got)
+	}
+
+	stats.Reset(ResetReactions)
+	if sources := stats.GetSnapshot().ReactionSources; sources != nil {
+		t.Errorf("Expected a reaction reset to clear source counts, got %+v", sources)
+	}
+}
+
+func TestManager_ClassifiesViewersOnceTheirHistoryIsKnown(t *testing.T) {
+	manager := NewManager()
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userB"))
+
+	manager.ClassifyViewer("s1", "userA", events.ViewerFirstTime)
+	manager.ClassifyViewer("s1", "userB", events.ViewerReturning)
+	// Late duplicates, e.g. from a rejoin, are not counted twice
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	manager.ClassifyViewer("s1", "userA", events.ViewerFirstTime)
+
+	stats, _ := manager.GetSession("s1")
+	viewers := stats.GetSnapshot().Viewers
+	if viewers == nil || viewers.FirstTime != 1 || viewers.Returning != 1 {
+		t.Errorf("Expected 1 first-time and 1 returning viewer, got %+v", viewers)
+	}
+}
+
+func TestManager_RecordsThePeakMoment(t *testing.T) {
+	manager := NewManager()
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userA"))
+	for i := 0; i < 3; i++ {
+		manager.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionFire))
+	}
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "userB"))
+	manager.ProcessEvent(events.LeaveSessionEvent("s1", "userB"))
+	manager.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionFire))
+
+	stats, _ := manager.GetSession("s1")
+	peak := stats.GetSnapshot().Peak
+	if peak == nil {
+		t.Fatal("Expected a peak moment once users joined")
+	}
+	if peak.Users != 2 || peak.TotalReactions != 3 || peak.ReactionsPerMinute != 3 {
+		t.Errorf("Expected the peak of 2 users after 3 reactions, got %+v", peak)
+	}
+	if time.Since(peak.At) > time.Minute {
+		t.Errorf("Expected the peak to be stamped when userB joined, got %s", peak.At)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := &SessionStats{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := restored.GetSnapshot().Peak; got == nil || *got != *peak {
+		t.Errorf("Expected the peak moment to survive a checkpoint, got %+v", got)
+	}
+
+	// Lowering the peak moves its moment to now
+	stats.Reset(ResetPeakUsers)
+	if lowered := stats.GetSnapshot().Peak; lowered.Users != 1 || lowered.TotalReactions != 4 {
+		t.Errorf("Expected the peak lowered to the 1 active user, got %+v", lowered)
+	}
+	manager.ProcessEvent(events.LeaveSessionEvent("s1", "userA"))
+	stats.Reset(ResetPeakUsers)
+	if stats.GetSnapshot().Peak != nil {
+		t.Error("Expected an empty session to have no peak moment")
+	}
+}
+
+func TestManager_SustainedPeakIgnoresShortSpikes(t *testing.T) {
+	manager := NewManager()
+	manager.SetSustainedPeakWindow(time.Minute)
+	for i := 0; i < 3; i++ {
+		manager.ProcessEvent(events.JoinSessionEvent("s1", fmt.Sprintf("viewer%d", i)))
+	}
+	// A reconnect storm briefly doubles the audience
+	for i := 0; i < 3; i++ {
+		manager.ProcessEvent(events.JoinSessionEvent("s1", fmt.Sprintf("storm%d", i)))
+	}
+	for i := 0; i < 3; i++ {
+		manager.ProcessEvent(events.LeaveSessionEvent("s1", fmt.Sprintf("storm%d", i)))
+	}
+	manager.ProcessEvent(events.LeaveSessionEvent("s1", "viewer2"))
+
+	stats, _ := manager.GetSession("s1")
+	snapshot := stats.GetSnapshot()
+	if snapshot.PeakConcurrentUsers != 6 {
+		t.Errorf("Expected the instantaneous peak of 6 users, got %d", snapshot.PeakConcurrentUsers)
+	}
+	if snapshot.SustainedPeakUsers != 0 {
+		t.Errorf("Expected no sustained peak before the window passed, got %d", snapshot.SustainedPeakUsers)
+	}
+
+	stats.mu.RLock()
+	later := stats.sustainedPeakLocked(time.Now().Add(time.Minute))
+	stats.mu.RUnlock()
+	if later != 2 {
+		t.Errorf("Expected the 2 users who stayed throughout as the sustained peak, got %d", later)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := &SessionStats{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := restored.sustainedPeakLocked(time.Now().Add(time.Minute)); got != 2 {
+		t.Errorf("Expected the sustained peak to survive a checkpoint, got %d", got)
+	}
+}
+
+func TestManager_CountsSystemReactionsApart(t *testing.T) {
+	manager := NewManager()
+	manager.ProcessEvent(events.ReactionEvent("s1", "userA", events.ReactionFire))
+	manager.ProcessEvent(events.SystemReactionEvent("s1", "trivia-bot", events.ReactionApplause))
+	manager.ProcessEvent(events.SystemReactionEvent("s1", "trivia-bot", events.ReactionApplause))
+
+	stats, _ := manager.GetSession("s1")
+	snapshot := stats.GetSnapshot()
+	if snapshot.TotalReactions != 1 || snapshot.ReactionCounts[events.ReactionApplause] != 0 {
+		t.Errorf("Expected only the organic reaction in the totals, got %d", snapshot.TotalReactions)
+	}
+	system := snapshot.SystemReactions
+	if system == nil || system.Total != 2 || system.ReactionCounts[events.ReactionApplause] != 2 || system.Sources["trivia-bot"] != 2 {
+		t.Fatalf("Expected 2 applause from trivia-bot, got %+v", system)
+	}
+	if leaders := stats.GetLeaderboard(10); len(leaders) != 1 || leaders[0].UserID != "userA" {
+		t.Errorf("Expected integrations off the leaderboard, got %+v", leaders)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := &SessionStats{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := restored.GetSystemReactions(); got == nil || got.Total != 2 {
+		t.Errorf("Expected system reactions to survive a checkpoint, got %+v", got)
+	}
+
+	stats.Reset(ResetReactions)
+	if stats.GetSystemReactions() != nil {
+		t.Error("Expected a reactions reset to clear system reactions")
+	}
+}
+
+func TestSessionStats_ApproximateAccuracyBoundsMemoryAndError(t *testing.T) {
+	stats := NewSessionStats("s1")
+	stats.SetAccuracy(AccuracyApproximate)
+
+	const users = 2000
+	for i := 0; i < users; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		stats.AssignCohort(userID, events.DefaultCohort)
+		stats.AddUser(userID)
+		for j := 0; j <= i%5; j++ {
+			stats.IncrementReaction(events.ReactionFire)
+			stats.RecordUserReaction(userID, events.ReactionFire)
+		}
+		stats.RemoveUser(userID)
+	}
+	for j := 0; j < 50; j++ {
+		stats.RecordUserReaction("superfan", events.ReactionFire)
+	}
+
+	snapshot := stats.GetSnapshot()
+	if snapshot.Accuracy != AccuracyApproximate || snapshot.ErrorBounds == nil {
+		t.Fatalf("Expected an approximate snapshot with error bounds, got %s %+v", snapshot.Accuracy, snapshot.ErrorBounds)
+	}
+	if diff := math.Abs(float64(snapshot.UniqueUsers-users)) / users; diff > 4*snapshot.ErrorBounds.UniqueUsersRelativeError {
+		t.Errorf("Expected about %d unique users, got %d", users, snapshot.UniqueUsers)
+	}
+	if len(stats.UserReactions) > maxApproxLeaders {
+		t.Errorf("Expected at most %d per-user entries, got %d", maxApproxLeaders, len(stats.UserReactions))
+	}
+
+	leaders := stats.GetLeaderboard(1)
+	if len(leaders) != 1 || leaders[0].UserID != "superfan" {
+		t.Fatalf("Expected superfan to lead, got %+v", leaders)
+	}
+	if over := leaders[0].ReactionCount - 50; over < 0 || over > snapshot.ErrorBounds.UserReactionsMaxOvercount {
+		t.Errorf("Expected superfan's estimate within %d of 50, got %d", snapshot.ErrorBounds.UserReactionsMaxOvercount, leaders[0].ReactionCount)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := &SessionStats{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if restored.GetSnapshot().Accuracy != AccuracyApproximate {
+		t.Error("Expected the accuracy mode to survive a checkpoint")
+	}
+
+	exact := NewSessionStats("s2")
+	if exact.GetSnapshot().Accuracy != AccuracyExact || exact.GetErrorBounds() != nil {
+		t.Error("Expected sessions to be exact by default")
+	}
+}
+
+func TestSessionStats_RetentionCurveCountsEachJoinerOnce(t *testing.T) {
+	stats := NewSessionStats("s1")
+	stats.StartTime = time.Now().UTC().Add(-3*time.Minute - 30*time.Second)
+	at := func(minute int) time.Time {
+		return stats.StartTime.Add(time.Duration(minute)*time.Minute + time.Second)
+	}
+
+	stats.mu.Lock()
+	for _, userID := range []string{"u1", "u2", "u3"} {
+		stats.recordArrivalLocked(userID, at(0))
+	}
+	stats.recordArrivalLocked("u4", at(1))
+	stats.recordDepartureLocked("u2", at(1))
+	stats.recordDepartureLocked("u3", at(2))
+	stats.recordArrivalLocked("u3", at(2)) // a reconnect is not a drop-off
+	stats.mu.Unlock()
+
+	curve := stats.GetRetentionCurve()
+	if len(curve) != 4 {
+		t.Fatalf("expected a point per minute since start, got %d", len(curve))
+	}
+	want := []struct {
+		joined, left, present int64
+		pct                   float64
+	}{{3, 0, 3, 100}, {4, 1, 3, 75}, {4, 0, 3, 75}, {4, 0, 3, 75}}
+	for i, w := range want {
+		p := curve[i]
+		if p.Minute != i || p.Joined != w.joined || p.Left != w.left || p.Present != w.present || p.RetentionPct != w.pct {
+			t.Errorf("minute %d: got %+v, want %+v", i, p, w)
+		}
+	}
+
+	// Departures survive a checkpoint, so users returning after a restart
+	// are not counted again
+	fresh := NewSessionStats("s2")
+	fresh.AddUser("u1")
+	fresh.RemoveUser("u1")
+	data, err := json.Marshal(fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewSessionStats("s2")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.AddUser("u1")
+	if p := restored.GetRetentionCurve()[0]; p.Joined != 1 || p.Present != 1 {
+		t.Errorf("expected the returning user to cancel their departure, got %+v", p)
+	}
+}
+
+func TestManager_BoostWeighsReactionsInItsWindow(t *testing.T) {
+	manager := NewManager()
+	manager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+	stats, _ := manager.GetSession("s1")
+
+	if _, err := stats.StartBoost(3, time.Minute, "triple hype", time.Now().UTC()); err != nil {
+		t.Fatalf("StartBoost failed: %v", err)
+	}
+	if _, err := stats.StartBoost(2, time.Minute, "", time.Now().UTC()); err == nil {
+		t.Errorf("expected a second boost to be refused while the first runs")
+	}
+	manager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+
+	snapshot := stats.GetSnapshot()
+	if snapshot.TotalReactions != 2 || snapshot.WeightedReactions != 4 || snapshot.HypeScore != 4 {
+		t.Errorf("expected the boosted reaction to weigh 3, got total %d, weighted %d, hype %d",
+			snapshot.TotalReactions, snapshot.WeightedReactions, snapshot.HypeScore)
+	}
+	if snapshot.Boost == nil || snapshot.Boost.Multiplier != 3 {
+		t.Errorf("expected the running boost in the snapshot, got %+v", snapshot.Boost)
+	}
+
+	if _, ended := stats.EndBoost(time.Now().UTC()); !ended {
+		t.Fatalf("expected the boost to end early")
+	}
+	manager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+	if snapshot := stats.GetSnapshot(); snapshot.WeightedReactions != 5 || snapshot.Boost != nil {
+		t.Errorf("expected reactions after the boost to weigh 1, got weighted %d, boost %+v", snapshot.WeightedReactions, snapshot.Boost)
+	}
+
+	// The weighted total survives a checkpoint
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewSessionStats("s1")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.GetSnapshot().WeightedReactions; got != 5 {
+		t.Errorf("expected the weighted total to be restored, got %d", got)
+	}
+}
+
+func TestManager_CountsJoinsByClientVersion(t *testing.T) {
+	manager := NewManager()
+	join := func(userID, version string) {
+		event := events.JoinSessionEvent("s1", userID)
+		event.SetPayload(&events.JoinPayload{ClientVersion: version})
+		manager.ProcessEvent(event)
+	}
+	join("u1", "2.4.1")
+	join("u2", "v2.4.1-beta")
+	join("u3", "nightly")
+	join("u4", "")
+
+	stats, _ := manager.GetSession("s1")
+	versions := stats.GetSnapshot().ClientVersions
+	if versions["2.4.1"] != 2 || versions[events.UnknownClientVersion] != 1 || len(versions) != 2 {
+		t.Errorf("expected 2 joins on 2.4.1 and 1 unknown, got %v", versions)
+	}
+}
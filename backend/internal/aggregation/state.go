@@ -0,0 +1,419 @@
+package aggregation
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// sessionState is the serialized form of the full SessionStats internals
+type sessionState struct {
+	SessionID           string                                   `json:"session_id"`
+	ActiveUsers         map[string]*Presence                     `json:"active_users"`
+	JoinTimes           map[string]time.Time                     `json:"join_times"`
+	UserReactions       map[string]int64                         `json:"user_reactions"`
+	UserCohorts         map[string]string                        `json:"user_cohorts"`
+	CohortReactions     map[string]map[events.ReactionType]int64 `json:"cohort_reactions"`
+	ReactionCounts      map[events.ReactionType]int64            `json:"reaction_counts"`
+	TotalReactions      int64                                    `json:"total_reactions"`
+	WeightedReactions   int64                                    `json:"weighted_reactions,omitempty"`
+	Boost               *Boost                                   `json:"boost,omitempty"`
+	PeakConcurrentUsers int                                      `json:"peak_concurrent_users"`
+	Peak                PeakMoment                               `json:"peak"`
+	SustainedWindow     time.Duration                            `json:"sustained_window,omitempty"`
+	SustainedPeak       int                                      `json:"sustained_peak,omitempty"`
+	Levels              []concurrencyLevel                       `json:"levels,omitempty"`
+	System              *SystemReactions                         `json:"system,omitempty"`
+	StartTime           time.Time                                `json:"start_time"`
+	LastActivity        time.Time                                `json:"last_activity"`
+	Version             int64                                    `json:"version"`
+	MaxTrackedUsers     int                                      `json:"max_tracked_users,omitempty"`
+	UniqueSketch        []byte                                   `json:"unique_sketch,omitempty"`
+	UserSketch          *countMinSketch                          `json:"user_sketch,omitempty"`
+	MinuteCounts        []map[events.ReactionType]int64          `json:"minute_counts,omitempty"`
+	Audience            []audienceMinute                         `json:"audience,omitempty"`
+	Departures          map[string]int                           `json:"departures,omitempty"`
+	Viewers             ViewerSplit                              `json:"viewers"`
+
+	Dimensions         []string                                            `json:"dimensions,omitempty"`
+	MaxDimensionValues int                                                 `json:"max_dimension_values,omitempty"`
+	DimensionReactions map[string]map[string]map[events.ReactionType]int64 `json:"dimension_reactions,omitempty"`
+
+	Sources        map[events.ReactionSource]map[events.ReactionType]int64 `json:"sources,omitempty"`
+	ClientVersions map[string]int64                                        `json:"client_versions,omitempty"`
+}
+
+// MarshalJSON serializes the complete internal state of the session, unlike
+// GetSnapshot which only exposes read-only aggregates
+func (s *SessionStats) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[events.ReactionType]int64, len(s.ReactionCounts))
+	for reactionType, counter := range s.ReactionCounts {
+		counts[reactionType] = atomic.LoadInt64(counter)
+	}
+
+	state := sessionState{
+		SessionID:           s.SessionID,
+		ActiveUsers:         s.ActiveUsers,
+		JoinTimes:           s.JoinTimes,
+		UserReactions:       s.UserReactions,
+		UserCohorts:         s.UserCohorts,
+		CohortReactions:     s.CohortReactions,
+		ReactionCounts:      counts,
+		TotalReactions:      atomic.LoadInt64(s.TotalReactions),
+		WeightedReactions:   s.weightedReactions,
+		Boost:               s.boost,
+		PeakConcurrentUsers: s.PeakConcurrentUsers,
+		Peak:                s.peak,
+		SustainedWindow:     s.sustainedWindow,
+		SustainedPeak:       s.sustainedPeak,
+		Levels:              s.levels,
+		System:              s.system,
+		StartTime:           s.StartTime,
+		LastActivity:        s.LastActivity,
+		Version:             atomic.LoadInt64(&s.version),
+		MaxTrackedUsers:     s.maxTrackedUsers,
+		UserSketch:          s.userSketch,
+		MinuteCounts:        s.minuteCounts,
+		Audience:            s.audience,
+		Departures:          s.departures,
+		Viewers:             s.viewers,
+		Dimensions:          s.dimensions,
+		MaxDimensionValues:  s.maxDimensionValues,
+		DimensionReactions:  s.DimensionReactions,
+		Sources:             s.sources,
+		ClientVersions:      s.clientVersions,
+	}
+	if s.uniqueSketch != nil {
+		state.UniqueSketch = s.uniqueSketch.registers
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores state written by MarshalJSON
+func (s *SessionStats) UnmarshalJSON(data []byte) error {
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	restored := NewSessionStats(state.SessionID)
+	for userID, presence := range state.ActiveUsers {
+		if presence != nil {
+			restored.ActiveUsers[userID] = presence
+		}
+	}
+	for userID, joinedAt := range state.JoinTimes {
+		restored.JoinTimes[userID] = joinedAt
+	}
+	for userID, count := range state.UserReactions {
+		restored.UserReactions[userID] = count
+	}
+	for userID, cohort := range state.UserCohorts {
+		restored.UserCohorts[userID] = cohort
+	}
+	for cohort, counts := range state.CohortReactions {
+		restored.CohortReactions[cohort] = counts
+	}
+	for dimension, values := range state.DimensionReactions {
+		restored.DimensionReactions[dimension] = values
+	}
+	for reactionType, count := range state.ReactionCounts {
+		if counter, exists := restored.ReactionCounts[reactionType]; exists {
+			*counter = count
+		}
+	}
+	*restored.TotalReactions = state.TotalReactions
+	// Checkpoints from before boosts have no weighted total; every reaction
+	// weighs at least one
+	restored.weightedReactions = max(state.WeightedReactions, state.TotalReactions)
+	restored.boost = state.Boost
+	restored.PeakConcurrentUsers = state.PeakConcurrentUsers
+	restored.peak = state.Peak
+	restored.sustainedWindow = state.SustainedWindow
+	restored.sustainedPeak = state.SustainedPeak
+	restored.levels = state.Levels
+	restored.system = state.System
+	restored.StartTime = state.StartTime
+	restored.LastActivity = state.LastActivity
+	restored.version = state.Version
+	restored.maxTrackedUsers = state.MaxTrackedUsers
+	restored.minuteCounts = state.MinuteCounts
+	restored.audience = state.Audience
+	restored.departures = state.Departures
+	restored.viewers = state.Viewers
+	restored.dimensions = state.Dimensions
+	restored.maxDimensionValues = state.MaxDimensionValues
+	restored.sources = state.Sources
+	restored.clientVersions = state.ClientVersions
+	if len(state.UniqueSketch) == 1<<hllPrecision {
+		restored.uniqueSketch = &hyperLogLog{registers: state.UniqueSketch}
+	}
+	if state.UserSketch != nil && len(state.UserSketch.Counters) == cmsWidth*cmsDepth {
+		restored.userSketch = state.UserSketch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SessionID = restored.SessionID
+	s.ActiveUsers = restored.ActiveUsers
+	s.JoinTimes = restored.JoinTimes
+	s.UserReactions = restored.UserReactions
+	s.UserCohorts = restored.UserCohorts
+	s.CohortReactions = restored.CohortReactions
+	s.DimensionReactions = restored.DimensionReactions
+	s.ReactionCounts = restored.ReactionCounts
+	s.TotalReactions = restored.TotalReactions
+	s.weightedReactions = restored.weightedReactions
+	s.boost = restored.boost
+	s.hype = nil
+	s.PeakConcurrentUsers = restored.PeakConcurrentUsers
+	s.peak = restored.peak
+	s.sustainedWindow = restored.sustainedWindow
+	s.sustainedPeak = restored.sustainedPeak
+	s.levels = restored.levels
+	s.system = restored.system
+	s.StartTime = restored.StartTime
+	s.LastActivity = restored.LastActivity
+	atomic.StoreInt64(&s.version, restored.version)
+	s.maxTrackedUsers = restored.maxTrackedUsers
+	s.uniqueSketch = restored.uniqueSketch
+	s.userSketch = restored.userSketch
+	s.minuteCounts = restored.minuteCounts
+	s.audience = restored.audience
+	s.departures = restored.departures
+	s.viewers = restored.viewers
+	s.dimensions = restored.dimensions
+	s.maxDimensionValues = restored.maxDimensionValues
+	s.sources = restored.sources
+	s.clientVersions = restored.clientVersions
+	return nil
+}
+
+// StateStore persists serialized session state between deploys. Each
+// session's checkpoint is stored separately, so instances owning different
+// sessions can share a store.
+type StateStore interface {
+	// SaveSessionStates writes the given checkpoints and deletes those of
+	// removed sessions, leaving every other session's checkpoint untouched
+	SaveSessionStates(ctx context.Context, states map[string][]byte, removed []string) error
+	// LoadSessionStates returns the given sessions' checkpoints, or every
+	// checkpoint when no sessions are given
+	LoadSessionStates(ctx context.Context, sessionIDs ...string) (map[string][]byte, error)
+}
+
+// SetOwnership limits checkpointing to the sessions owns reports this
+// instance aggregates, so an instance that lost a session to another never
+// overwrites the new owner's checkpoint
+func (m *Manager) SetOwnership(owns func(sessionID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owns = owns
+}
+
+// Checkpoint writes the full state of every owned session to the store and
+// deletes the checkpoints of sessions removed since the last one
+func (m *Manager) Checkpoint(ctx context.Context, store StateStore) error {
+	m.mu.Lock()
+	sessions := make([]*SessionStats, 0, len(m.sessions))
+	for sessionID, stats := range m.sessions {
+		if m.owns == nil || m.owns(sessionID) {
+			sessions = append(sessions, stats)
+		}
+	}
+	removed := make([]string, 0, len(m.removed))
+	for sessionID := range m.removed {
+		removed = append(removed, sessionID)
+	}
+	m.removed = make(map[string]bool)
+	m.mu.Unlock()
+
+	states := make(map[string][]byte, len(sessions))
+	for _, stats := range sessions {
+		data, err := json.Marshal(stats)
+		if err != nil {
+			log.Printf("Error serializing session %s: %v", stats.SessionID, err)
+			continue
+		}
+		states[stats.SessionID] = data
+	}
+	if err := store.SaveSessionStates(ctx, states, removed); err != nil {
+		// Retry the deletions with the next checkpoint
+		m.mu.Lock()
+		for _, sessionID := range removed {
+			if _, recreated := m.sessions[sessionID]; !recreated {
+				m.removed[sessionID] = true
+			}
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// loadStates decodes checkpointed sessions from the store, every one when
+// no sessions are given
+func loadStates(ctx context.Context, store StateStore, sessionIDs ...string) (map[string]*SessionStats, error) {
+	states, err := store.LoadSessionStates(ctx, sessionIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*SessionStats, len(states))
+	for sessionID, data := range states {
+		stats := NewSessionStats(sessionID)
+		if err := json.Unmarshal(data, stats); err != nil {
+			log.Printf("Skipping corrupt checkpoint for session %s: %v", sessionID, err)
+			continue
+		}
+		loaded[sessionID] = stats
+	}
+	return loaded, nil
+}
+
+// Restore loads checkpointed sessions into the manager and returns how many
+// were restored. Only sessions claim accepts are loaded; a nil claim
+// restores every session. Socket connections do not survive a restart, so
+// restored sessions start with no active users and clients are counted
+// again as they reconnect.
+func (m *Manager) Restore(ctx context.Context, store StateStore, claim func(sessionID string) bool) (int, error) {
+	loaded, err := loadStates(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for sessionID, stats := range loaded {
+		if claim != nil && !claim(sessionID) {
+			continue
+		}
+		m.adopt(stats)
+		restored++
+	}
+	return restored, nil
+}
+
+// TakeOver loads the checkpoint a session's previous owner left behind,
+// after this instance took over its lease. It reports false if there was
+// none, in which case the session keeps whatever this instance holds. As
+// with Restore, the previous owner's connections are not carried over.
+func (m *Manager) TakeOver(ctx context.Context, store StateStore, sessionID string) (bool, error) {
+	loaded, err := loadStates(ctx, store, sessionID)
+	if err != nil {
+		return false, err
+	}
+	stats, exists := loaded[sessionID]
+	if !exists {
+		return false, nil
+	}
+	m.adopt(stats)
+	return true, nil
+}
+
+// SetAdoptHandler sets a function called with each session Restore or
+// TakeOver installs, so timers kept outside the stats, such as a running
+// boost's end, can be resumed
+func (m *Manager) SetAdoptHandler(adopted func(sessionID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.adopted = adopted
+}
+
+// adopt installs restored session stats without their connections
+func (m *Manager) adopt(stats *SessionStats) {
+	stats.resetConnections()
+
+	m.mu.Lock()
+	if stats.maxTrackedUsers == 0 {
+		stats.maxTrackedUsers = m.maxTrackedUsers
+	}
+	if stats.sustainedWindow == 0 {
+		stats.sustainedWindow = m.sustainedWindow
+	}
+	m.sessions[stats.SessionID] = stats
+	delete(m.removed, stats.SessionID)
+	adopted := m.adopted
+	m.mu.Unlock()
+
+	if adopted != nil {
+		adopted(stats.SessionID)
+	}
+}
+
+// resetConnections forgets every connected user. They count as having left
+// until they reconnect.
+func (s *SessionStats) resetConnections() {
+	now := time.Now().UTC()
+	for userID := range s.ActiveUsers {
+		s.recordDepartureLocked(userID, now)
+	}
+	s.ActiveUsers = make(map[string]*Presence)
+	s.JoinTimes = make(map[string]time.Time)
+	s.recordConcurrencyLocked(now)
+}
+
+// ResetConnections forgets the connected users of every session, after a
+// standby took over from an instance whose sockets did not survive
+func (m *Manager) ResetConnections() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, stats := range m.sessions {
+		stats.mu.Lock()
+		stats.resetConnections()
+		stats.mu.Unlock()
+	}
+}
+
+// Reload replaces every session with the latest checkpoints as written by
+// the ingesting instances, for read-only instances serving stats. Each
+// session is read from its owner's own checkpoint; one that fails to decode
+// keeps its previous copy rather than disappearing from the API.
+func (m *Manager) Reload(ctx context.Context, store StateStore) (int, error) {
+	states, err := store.LoadSessionStates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	loaded := make(map[string]*SessionStats, len(states))
+	for sessionID, data := range states {
+		stats := NewSessionStats(sessionID)
+		if err := json.Unmarshal(data, stats); err != nil {
+			log.Printf("Keeping previous stats for session %s, checkpoint is corrupt: %v", sessionID, err)
+			if previous, exists := m.sessions[sessionID]; exists {
+				loaded[sessionID] = previous
+			}
+			continue
+		}
+		loaded[sessionID] = stats
+	}
+	m.sessions = loaded
+	return len(loaded), nil
+}
+
+// StartCheckpointing periodically checkpoints all sessions until the
+// context is cancelled
+func (m *Manager) StartCheckpointing(ctx context.Context, store StateStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Checkpoint(ctx, store); err != nil {
+					log.Printf("Error checkpointing session stats: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+package aggregation
+
+import "time"
+
+// concurrencyLevel is a concurrency the session has stayed at or above
+// since a moment
+type concurrencyLevel struct {
+	Users int       `json:"users"`
+	Since time.Time `json:"since"`
+}
+
+// SetSustainedPeakWindow sets how long concurrency must hold for sessions
+// created from now on to count it as their sustained peak. Zero disables
+// the sustained peak.
+func (m *Manager) SetSustainedPeakWindow(window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sustainedWindow = window
+}
+
+// recordConcurrencyLocked updates the sustained peak after the number of
+// active users changed. The levels held for less than the window are kept
+// in increasing order, each with when concurrency last rose to it, so a
+// reconnect storm that ends before the window passes leaves no trace.
+func (s *SessionStats) recordConcurrencyLocked(now time.Time) {
+	if s.sustainedWindow <= 0 {
+		return
+	}
+	s.settleLevelsLocked(now)
+
+	users := len(s.ActiveUsers)
+	since := now
+	for len(s.levels) > 0 && s.levels[len(s.levels)-1].Users > users {
+		// Concurrency has held at users since the oldest level it fell through
+		since = s.levels[len(s.levels)-1].Since
+		s.levels = s.levels[:len(s.levels)-1]
+	}
+	if users <= s.sustainedPeak || (len(s.levels) > 0 && s.levels[len(s.levels)-1].Users == users) {
+		return
+	}
+	s.levels = append(s.levels, concurrencyLevel{Users: users, Since: since})
+}
+
+// settleLevelsLocked raises the sustained peak to the levels that have now
+// held for the whole window. Lower levels can no longer raise it, so they
+// are dropped with them.
+func (s *SessionStats) settleLevelsLocked(now time.Time) {
+	settled := 0
+	for settled < len(s.levels) && now.Sub(s.levels[settled].Since) >= s.sustainedWindow {
+		s.sustainedPeak = s.levels[settled].Users
+		settled++
+	}
+	s.levels = s.levels[settled:]
+}
+
+// sustainedPeakLocked returns the highest concurrency held for the whole
+// window as of now, without settling levels so it is safe under a read lock
+func (s *SessionStats) sustainedPeakLocked(now time.Time) int {
+	peak := s.sustainedPeak
+	for _, level := range s.levels {
+		if now.Sub(level.Since) < s.sustainedWindow {
+			break
+		}
+		peak = level.Users
+	}
+	return peak
+}
+
+// resetSustainedPeakLocked restarts the sustained peak from the current
+// concurrency, which has to hold for a full window again to count
+func (s *SessionStats) resetSustainedPeakLocked(now time.Time) {
+	s.sustainedPeak = 0
+	s.levels = nil
+	s.recordConcurrencyLocked(now)
+}
@@ -0,0 +1,61 @@
+package aggregation
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// SystemReactions counts the reactions integrations emitted into a session,
+// kept apart from the audience's so organic engagement is never inflated
+type SystemReactions struct {
+	Total          int64                         `json:"total"`
+	ReactionCounts map[events.ReactionType]int64 `json:"reaction_counts"`
+	Sources        map[string]int64              `json:"sources"` // integration -> reactions emitted
+}
+
+// RecordSystemReaction counts a reaction emitted by an integration
+func (s *SessionStats) RecordSystemReaction(source string, reactionType events.ReactionType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.system == nil {
+		s.system = &SystemReactions{
+			ReactionCounts: make(map[events.ReactionType]int64),
+			Sources:        make(map[string]int64),
+		}
+	}
+	s.system.Total++
+	s.system.ReactionCounts[reactionType]++
+	s.system.Sources[source]++
+	s.LastActivity = time.Now().UTC()
+	atomic.AddInt64(&s.version, 1)
+}
+
+// GetSystemReactions returns a copy of the integration reaction counts, or
+// nil if no integration reacted
+func (s *SessionStats) GetSystemReactions() *SystemReactions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.systemLocked()
+}
+
+// systemLocked copies the integration reaction counts
+func (s *SessionStats) systemLocked() *SystemReactions {
+	if s.system == nil {
+		return nil
+	}
+	system := &SystemReactions{
+		Total:          s.system.Total,
+		ReactionCounts: make(map[events.ReactionType]int64, len(s.system.ReactionCounts)),
+		Sources:        make(map[string]int64, len(s.system.Sources)),
+	}
+	for reactionType, count := range s.system.ReactionCounts {
+		system.ReactionCounts[reactionType] = count
+	}
+	for source, count := range s.system.Sources {
+		system.Sources[source] = count
+	}
+	return system
+}
@@ -0,0 +1,78 @@
+package aggregation
+
+import (
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// maxTimelineMinutes bounds the per-minute breakdown kept for a session
+const maxTimelineMinutes = 24 * 60
+
+// MinuteBucket holds the reactions received during one minute of a session
+type MinuteBucket struct {
+	Minute int                           `json:"minute"` // minutes since session start
+	Start  time.Time                     `json:"start"`
+	Counts map[events.ReactionType]int64 `json:"counts"`
+	Total  int64                         `json:"total"`
+}
+
+// recordMinute attributes a reaction to the minute of the show it happened
+// in, using the event timestamp so re-bucketed late events land in their
+// original minute
+func (s *SessionStats) recordMinute(reactionType events.ReactionType, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minute := int(at.Sub(s.StartTime) / time.Minute)
+	if minute < 0 {
+		minute = 0
+	}
+	if minute >= maxTimelineMinutes {
+		minute = maxTimelineMinutes - 1
+	}
+	for len(s.minuteCounts) <= minute {
+		s.minuteCounts = append(s.minuteCounts, nil)
+	}
+	if s.minuteCounts[minute] == nil {
+		s.minuteCounts[minute] = make(map[events.ReactionType]int64)
+	}
+	s.minuteCounts[minute][reactionType]++
+}
+
+// GetReactionsByMinute returns a zero-filled per-minute breakdown of every
+// reaction type from session start to now
+func (s *SessionStats) GetReactionsByMinute() []MinuteBucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	minutes := int(time.Since(s.StartTime)/time.Minute) + 1
+	if minutes < len(s.minuteCounts) {
+		minutes = len(s.minuteCounts)
+	}
+	if minutes > maxTimelineMinutes {
+		minutes = maxTimelineMinutes
+	}
+
+	buckets := make([]MinuteBucket, minutes)
+	for i := range buckets {
+		counts := make(map[events.ReactionType]int64, len(s.ReactionCounts))
+		for reactionType := range s.ReactionCounts {
+			counts[reactionType] = 0
+		}
+		var total int64
+		if i < len(s.minuteCounts) {
+			for reactionType, count := range s.minuteCounts[i] {
+				counts[reactionType] = count
+				total += count
+			}
+		}
+		buckets[i] = MinuteBucket{
+			Minute: i,
+			Start:  s.StartTime.Add(time.Duration(i) * time.Minute),
+			Counts: counts,
+			Total:  total,
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,84 @@
+package aggregation
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Reaction trends compare the last minute against the median of the
+// trendBaselineMinutes completed minutes before it. Medians ignore short
+// spikes, so a single hype moment does not raise the bar for the next one.
+const (
+	trendBaselineMinutes    = 30
+	trendMinBaselineMinutes = 3 // completed minutes needed before a trend is reported
+)
+
+// ReactionTrend compares the current reaction rate with the session's usual
+// rate, e.g. ChangePercent 240 means 3.4 times the baseline
+type ReactionTrend struct {
+	CurrentPerMinute  int64   `json:"current_per_minute"`
+	BaselinePerMinute float64 `json:"baseline_per_minute"`
+	ChangePercent     float64 `json:"change_percent"`
+	BaselineMinutes   int     `json:"baseline_minutes"`
+}
+
+// trendLocked computes the session's reaction trend at now. It returns nil
+// until the session has enough history for a non-zero baseline. The caller
+// must hold s.mu.
+func (s *SessionStats) trendLocked(now time.Time) *ReactionTrend {
+	if s.velocity == nil {
+		return nil
+	}
+
+	// Minutes of the timeline before the one in progress, newest last
+	completed := int(now.Sub(s.StartTime) / time.Minute)
+	if completed > maxTimelineMinutes {
+		completed = maxTimelineMinutes
+	}
+	first := max(completed-trendBaselineMinutes, 0)
+	if completed-first < trendMinBaselineMinutes {
+		return nil
+	}
+	totals := make([]int64, 0, completed-first)
+	for minute := first; minute < completed; minute++ {
+		var total int64
+		if minute < len(s.minuteCounts) {
+			for _, count := range s.minuteCounts[minute] {
+				total += count
+			}
+		}
+		totals = append(totals, total)
+	}
+	baseline := median(totals)
+	if baseline == 0 {
+		return nil
+	}
+
+	current := s.velocity.sum(now.Unix(), time.Minute)
+	return &ReactionTrend{
+		CurrentPerMinute:  current,
+		BaselinePerMinute: baseline,
+		ChangePercent:     math.Round((float64(current)-baseline)/baseline*1000) / 10,
+		BaselineMinutes:   len(totals),
+	}
+}
+
+// GetReactionTrend returns the session's current reaction trend, or nil if
+// there is no baseline yet
+func (s *SessionStats) GetReactionTrend() *ReactionTrend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trendLocked(time.Now())
+}
+
+// median returns the middle value of totals, averaging the two middle values
+// of an even count. It reorders totals.
+func median(totals []int64) float64 {
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+	mid := len(totals) / 2
+	if len(totals)%2 == 0 {
+		return float64(totals[mid-1]+totals[mid]) / 2
+	}
+	return float64(totals[mid])
+}
@@ -0,0 +1,85 @@
+package aggregation
+
+import "time"
+
+// MaxVelocityWindow is the longest window reaction velocity can be measured over
+const MaxVelocityWindow = 10 * time.Minute
+
+// velocitySlots is the number of one-second buckets in the rate window
+const velocitySlots = int(MaxVelocityWindow / time.Second)
+
+// rateWindow counts reactions per second over the last MaxVelocityWindow
+type rateWindow struct {
+	slots      [velocitySlots]int64
+	lastSecond int64 // unix second of the most recent slot written
+}
+
+// advance clears slots for seconds that passed without reactions
+func (w *rateWindow) advance(second int64) {
+	if second <= w.lastSecond {
+		return
+	}
+	gap := second - w.lastSecond
+	if gap > int64(velocitySlots) {
+		gap = int64(velocitySlots)
+	}
+	for i := int64(1); i <= gap; i++ {
+		w.slots[(w.lastSecond+i)%int64(velocitySlots)] = 0
+	}
+	w.lastSecond = second
+}
+
+// add counts a reaction in the given second
+func (w *rateWindow) add(second int64) {
+	w.addN(second, 1)
+}
+
+// addN counts n in the given second
+func (w *rateWindow) addN(second, n int64) {
+	w.advance(second)
+	if w.lastSecond-second >= int64(velocitySlots) {
+		return
+	}
+	w.slots[second%int64(velocitySlots)] += n
+}
+
+// sum returns the reactions in the window ending at second
+func (w *rateWindow) sum(second int64, window time.Duration) int64 {
+	seconds := int64(window / time.Second)
+	if seconds > int64(velocitySlots) {
+		seconds = int64(velocitySlots)
+	}
+	var total int64
+	for s := second - seconds + 1; s <= second; s++ {
+		if s > w.lastSecond || w.lastSecond-s >= int64(velocitySlots) {
+			continue
+		}
+		total += w.slots[s%int64(velocitySlots)]
+	}
+	return total
+}
+
+// recordVelocity counts a reaction towards the rolling rate window. It uses
+// arrival time, since velocity celebrates what the audience is doing now.
+func (s *SessionStats) recordVelocity(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.velocity == nil {
+		s.velocity = &rateWindow{lastSecond: at.Unix()}
+	}
+	s.velocity.add(at.Unix())
+	s.recordWeightLocked(at)
+}
+
+// GetReactionVelocity returns the number of reactions received in the last
+// window, up to MaxVelocityWindow
+func (s *SessionStats) GetReactionVelocity(window time.Duration) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.velocity == nil {
+		return 0
+	}
+	return s.velocity.sum(time.Now().Unix(), window)
+}
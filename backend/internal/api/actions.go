@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Admin and moderation actions recorded in the action log
+const (
+	ActionSessionEnd       = "session.end"
+	ActionSessionClose     = "session.close"
+	ActionSessionFeatures  = "session.features"
+	ActionSessionAliases   = "session.aliases"
+	ActionSessionReset     = "session.reset"
+	ActionSessionRecompute = "session.recompute"
+	ActionSessionDelete    = "session.delete"
+	ActionSessionRestore   = "session.restore"
+	ActionFilterPut        = "filter.put"
+	ActionFilterDelete     = "filter.delete"
+	ActionExperimentCreate = "experiment.create"
+	ActionExperimentDelete = "experiment.delete"
+	ActionCampaignCreate   = "campaign.create"
+	ActionShoutoutPick     = "shoutout.pick"
+	ActionOverlayIssue     = "overlay.issue"
+	ActionViewerTokenIssue = "viewer_token.issue"
+	ActionQueueResize      = "queue.resize"
+	ActionMilestoneAdd     = "milestone.add"
+	ActionUserBan          = "user.ban"
+	ActionUserUnban        = "user.unban"
+	ActionWaveStart        = "wave.start"
+	ActionBoostStart       = "boost.start"
+	ActionBoostEnd         = "boost.end"
+	ActionWebhookRegister  = "webhook.register"
+	ActionWebhookRemove    = "webhook.remove"
+
+	ActionNotificationRedrive = "notification.redrive"
+)
+
+// ActionLog persists moderation and admin actions for compliance review
+type ActionLog interface {
+	AppendAdminAction(ctx context.Context, a storage.AdminAction) error
+	ListAdminActions(ctx context.Context, q storage.AdminActionQuery) ([]storage.AdminAction, error)
+}
+
+// SetActionLog enables persistence of moderation and admin actions
+func (s *Server) SetActionLog(actions ActionLog) {
+	s.actions = actions
+}
+
+// recordAction logs an action taken by the authenticated user of r. The
+// reason comes from the request's ?reason= unless the handler has its own.
+// Actions are always written to the process log, so a storage failure still
+// leaves a trail.
+func (s *Server) recordAction(r *http.Request, action, target, reason string, details interface{}) {
+	actor, _ := r.Context().Value("user_id").(string)
+	if reason == "" {
+		reason = r.URL.Query().Get("reason")
+	}
+	entry := storage.AdminAction{
+		Actor:      actor,
+		Action:     action,
+		Target:     target,
+		Reason:     reason,
+		OccurredAt: time.Now().UTC(),
+	}
+	if details != nil {
+		entry.Details, _ = json.Marshal(details)
+	}
+
+	log.Printf("ADMIN ACTION: %s by %q on %s (reason: %q)", action, actor, target, reason)
+	if s.actions == nil {
+		return
+	}
+	if err := s.actions.AppendAdminAction(r.Context(), entry); err != nil {
+		log.Printf("Error persisting admin action %s on %s: %v", action, target, err)
+	}
+}
+
+// HandleGetAdminActions lists recorded admin actions, newest first, filtered
+// by ?actor=, ?action=, ?target= and a ?since=/?until= RFC 3339 range. Pass
+// the last entry's ID as ?before_id= to fetch the next page.
+func (s *Server) HandleGetAdminActions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.actions == nil {
+		writeError(w, errs.Unavailable("action log not available"))
+		return
+	}
+
+	params := r.URL.Query()
+	q := storage.AdminActionQuery{
+		Actor:  params.Get("actor"),
+		Action: params.Get("action"),
+		Target: params.Get("target"),
+		Limit:  100,
+	}
+	for name, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if val := params.Get(name); val != "" {
+			t, err := time.Parse(time.RFC3339, val)
+			if err != nil {
+				writeError(w, errs.Validation("%s must be an RFC 3339 timestamp", name))
+				return
+			}
+			*dst = t
+		}
+	}
+	if val := params.Get("before_id"); val != "" {
+		id, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, errs.Validation("before_id must be a positive integer"))
+			return
+		}
+		q.BeforeID = id
+	}
+	if val, err := strconv.Atoi(params.Get("limit")); err == nil && val > 0 {
+		q.Limit = val
+	}
+	if q.Limit > 500 {
+		q.Limit = 500
+	}
+
+	actions, err := s.actions.ListAdminActions(r.Context(), q)
+	if err != nil {
+		log.Printf("Error listing admin actions: %v", err)
+		writeError(w, err)
+		return
+	}
+	if actions == nil {
+		actions = []storage.AdminAction{}
+	}
+
+	resp := map[string]interface{}{"actions": actions}
+	if len(actions) == q.Limit {
+		resp["next_before_id"] = actions[len(actions)-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryActionLog is an in-process ActionLog
+type memoryActionLog struct {
+	actions []storage.AdminAction
+	mu      sync.Mutex
+}
+
+func (m *memoryActionLog) AppendAdminAction(_ context.Context, a storage.AdminAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a.ID = int64(len(m.actions) + 1)
+	m.actions = append(m.actions, a)
+	return nil
+}
+
+func (m *memoryActionLog) ListAdminActions(_ context.Context, q storage.AdminActionQuery) ([]storage.AdminAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []storage.AdminAction
+	for i := len(m.actions) - 1; i >= 0 && len(out) < q.Limit; i-- {
+		a := m.actions[i]
+		if (q.Actor == "" || a.Actor == q.Actor) && (q.BeforeID == 0 || a.ID < q.BeforeID) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// asUser attaches an authenticated user ID as ClerkMiddleware would
+func asUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "user_id", userID))
+}
+
+func TestHandleBulkEndSessions_RecordsEachEndedSession(t *testing.T) {
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1", Name: "Keynote"})
+	registry.Create(sessions.Session{ID: "s2", Name: "Workshop"})
+
+	// Dry runs change nothing, so nothing is recorded
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", strings.NewReader(`{"dry_run":true}`))
+	server.HandleBulkEndSessions(httptest.NewRecorder(), asUser(req, "admin-1"))
+	assert.Empty(t, actions.actions)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", strings.NewReader(`{"name_prefix":"Key","immediate":true,"reason":"venue closed"}`))
+	rec := httptest.NewRecorder()
+	server.HandleBulkEndSessions(rec, asUser(req, "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, actions.actions, 1)
+	recorded := actions.actions[0]
+	assert.Equal(t, "admin-1", recorded.Actor)
+	assert.Equal(t, ActionSessionEnd, recorded.Action)
+	assert.Equal(t, "s1", recorded.Target)
+	assert.Equal(t, "venue closed", recorded.Reason)
+	assert.False(t, recorded.OccurredAt.IsZero())
+}
+
+func TestHandleGetAdminActions_PagesNewestFirst(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	for _, target := range []string{"r1", "r2", "r3"} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/admin/filters?reason=cleanup", nil)
+		server.recordAction(asUser(req, "admin-1"), ActionFilterDelete, target, "", nil)
+	}
+	assert.Equal(t, "cleanup", actions.actions[0].Reason)
+
+	rec := httptest.NewRecorder()
+	server.HandleGetAdminActions(rec, httptest.NewRequest(http.MethodGet, "/api/ops/actions?limit=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Less(t, strings.Index(body, `"r3"`), strings.Index(body, `"r2"`))
+	assert.NotContains(t, body, `"r1"`)
+	assert.Contains(t, body, `"next_before_id":2`)
+
+	rec = httptest.NewRecorder()
+	server.HandleGetAdminActions(rec, httptest.NewRequest(http.MethodGet, "/api/ops/actions?limit=2&before_id=2", nil))
+	assert.Contains(t, rec.Body.String(), `"r1"`)
+	assert.NotContains(t, rec.Body.String(), "next_before_id")
+
+	rec = httptest.NewRecorder()
+	server.HandleGetAdminActions(rec, httptest.NewRequest(http.MethodGet, "/api/ops/actions?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// tailBuffer is how many broadcasts a tail may fall behind before the hub
+// drops it
+const tailBuffer = 256
+
+// HandleListSessions lists the sessions this instance knows about, newest
+// first, filtered by ?tenant_id=, ?status=, ?tag= and ?name_prefix=.
+// Deleted sessions are listed instead with ?deleted=true.
+func (s *Server) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := sessions.Filter{
+		TenantID:   query.Get("tenant_id"),
+		NamePrefix: query.Get("name_prefix"),
+		Status:     sessions.Status(query.Get("status")),
+		Tag:        query.Get("tag"),
+		Deleted:    query.Get("deleted") == "true",
+	}
+	switch filter.Status {
+	case "", sessions.StatusLive, sessions.StatusClosing, sessions.StatusEnded:
+	default:
+		writeError(w, errs.Validation("status must be live, closing or ended"))
+		return
+	}
+
+	list := s.registry.List(filter)
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	if list == nil {
+		list = []sessions.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":    len(list),
+		"sessions": list,
+	})
+}
+
+// HandleAddMilestones adds milestones to a live session. The body is a
+// JSON array of milestone definitions, as accepted at session creation.
+func (s *Server) HandleAddMilestones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if !s.registry.AcceptsJoins(sessionID) {
+		writeError(w, errs.ErrSessionEnded)
+		return
+	}
+
+	var definitions []milestones.Definition
+	if err := json.NewDecoder(r.Body).Decode(&definitions); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	if len(definitions) == 0 {
+		writeError(w, errs.Validation("at least one milestone definition is required"))
+		return
+	}
+	for _, definition := range definitions {
+		if err := definition.Validate(); err != nil {
+			writeError(w, errs.Validation("invalid milestone definition: %v", err))
+			return
+		}
+	}
+
+	if err := s.tracker.AddMilestones(sessionID, definitions); err != nil {
+		writeError(w, errs.Validation("invalid milestone definition: %v", err))
+		return
+	}
+	s.recordAction(r, ActionMilestoneAdd, sessionID, "", definitions)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"milestones": s.tracker.GetSessionMilestones(sessionID),
+	})
+}
+
+// HandleTailBroadcasts streams every message this instance broadcasts to a
+// session as NDJSON, for operators watching what viewers receive
+func (s *Server) HandleTailBroadcasts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if s.wsHub == nil {
+		writeError(w, errs.NotFound("broadcasts are not served by this instance"))
+		return
+	}
+	if _, exists := s.registry.Get(sessionID); !exists {
+		writeError(w, errs.NotFound("session not found"))
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	messages, unsubscribe := s.wsHub.GetOrCreateSessionHub(sessionID).Subscribe(tailBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				log.Printf("Broadcast tail for session %s fell behind and was dropped", sessionID)
+				return
+			}
+			if _, err := w.Write(append(message, '\n')); err != nil {
+				return
+			}
+			controller.Flush()
+		}
+	}
+}
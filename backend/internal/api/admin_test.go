@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListSessions_FiltersAndSortsNewestFirst(t *testing.T) {
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	now := time.Now().UTC()
+	registry.Create(sessions.Session{ID: "old", Name: "Keynote", TenantID: "acme", CreatedAt: now.Add(-time.Hour)})
+	registry.Create(sessions.Session{ID: "new", Name: "Keynote", TenantID: "acme", CreatedAt: now})
+	registry.Create(sessions.Session{ID: "other", Name: "Keynote", TenantID: "globex", CreatedAt: now})
+	registry.End("other")
+
+	rec := httptest.NewRecorder()
+	server.HandleListSessions(rec, httptest.NewRequest(http.MethodGet, "/api/admin/sessions?tenant_id=acme&status=live", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Count    int                `json:"count"`
+		Sessions []sessions.Session `json:"sessions"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Equal(t, 2, resp.Count)
+	assert.Equal(t, "new", resp.Sessions[0].ID)
+	assert.Equal(t, "old", resp.Sessions[1].ID)
+
+	rec = httptest.NewRecorder()
+	server.HandleListSessions(rec, httptest.NewRequest(http.MethodGet, "/api/admin/sessions?status=paused", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleAddMilestones_AddsValidDefinitionsToLiveSessions(t *testing.T) {
+	registry := sessions.NewRegistry()
+	tracker := milestones.NewTracker(nil)
+	server := NewServer(nil, aggregation.NewManager(), tracker, nil, nil, nil, registry, nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1"})
+	registry.Create(sessions.Session{ID: "ended"})
+	registry.End("ended")
+
+	add := func(sessionID, body string) int {
+		rec := httptest.NewRecorder()
+		req := asUser(httptest.NewRequest(http.MethodPost, "/api/admin/sessions/milestones?session_id="+sessionID, strings.NewReader(body)), "admin-1")
+		server.HandleAddMilestones(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, add("s1", `[{"type":"total_reactions","threshold":500}]`))
+	require.Len(t, tracker.GetSessionMilestones("s1"), 1)
+	assert.Equal(t, int64(500), tracker.GetSessionMilestones("s1")[0].Threshold)
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionMilestoneAdd, actions.actions[0].Action)
+
+	assert.Equal(t, http.StatusBadRequest, add("s1", `[{"type":"total_reactions","threshold":0}]`))
+	assert.Equal(t, http.StatusBadRequest, add("s1", `[]`))
+	assert.Equal(t, http.StatusConflict, add("ended", `[{"type":"total_reactions","threshold":500}]`))
+	assert.Len(t, tracker.GetSessionMilestones("s1"), 1)
+}
+
+func TestHandleBulkEndSessions_EndsOnlyListedSessions(t *testing.T) {
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	registry.Create(sessions.Session{ID: "s2"})
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"session_ids":["s2","missing"],"immediate":true}`)
+	server.HandleBulkEndSessions(rec, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", body))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"ended_count":1`)
+	assert.False(t, registry.IsEnded("s1"))
+	assert.True(t, registry.IsEnded("s2"))
+}
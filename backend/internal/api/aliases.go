@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// SessionAliasesRequest replaces the IDs a session is simulcast under
+type SessionAliasesRequest struct {
+	Aliases []string `json:"aliases"` // local IDs in the session's tenant
+}
+
+// HandleSessionAliases reads (GET) or replaces (PUT) the aliases of the
+// session given by ?session_id=. Clients may join under any alias; their
+// events count toward the session and its broadcasts reach every alias.
+func (s *Server) HandleSessionAliases(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+
+	var session sessions.Session
+	switch r.Method {
+	case http.MethodGet:
+		var exists bool
+		if session, exists = s.registry.Get(sessionID); !exists {
+			writeError(w, errs.NotFound("session not found"))
+			return
+		}
+
+	case http.MethodPut:
+		var req SessionAliasesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		aliases, err := sessions.AliasIDs(sessionID, req.Aliases)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if session, err = s.registry.SetAliases(sessionID, aliases); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.recordAction(r, ActionSessionAliases, sessionID, "", session.Aliases)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	aliases := session.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"aliases":    aliases,
+	})
+}
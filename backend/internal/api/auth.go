@@ -2,18 +2,166 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/jwt"
+	"github.com/jrudman25/livepulse/internal/errs"
 )
 
+// roles maps Clerk user IDs to the privileges configured at startup
+var roles = struct {
+	admins     map[string]bool
+	moderators map[string]bool
+	producers  map[string]bool
+	mu         sync.RWMutex
+}{
+	admins:     make(map[string]bool),
+	moderators: make(map[string]bool),
+	producers:  make(map[string]bool),
+}
+
 // SetClerkKey initializes the Clerk SDK with the secret key
 func SetClerkKey(secret string) {
 	clerk.SetKey(secret)
 }
 
+// SetRoles configures which Clerk user IDs hold admin, moderator and producer
+// privileges. Admins implicitly hold moderator and producer privileges as well.
+func SetRoles(adminIDs, moderatorIDs, producerIDs []string) {
+	roles.mu.Lock()
+	defer roles.mu.Unlock()
+
+	roles.admins = make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		roles.admins[id] = true
+	}
+	roles.moderators = make(map[string]bool, len(moderatorIDs))
+	for _, id := range moderatorIDs {
+		roles.moderators[id] = true
+	}
+	roles.producers = make(map[string]bool, len(producerIDs))
+	for _, id := range producerIDs {
+		roles.producers[id] = true
+	}
+}
+
+// IngestKeyUserID is the user ID of requests authenticated by an ingest key
+const IngestKeyUserID = "ingest-key"
+
+// ingestKeys are the long-lived keys machine clients, such as the
+// serverless ingestion function, present instead of a Clerk session
+var ingestKeys = struct {
+	keys [][]byte
+	mu   sync.RWMutex
+}{}
+
+// SetIngestKeys configures the keys accepted by IngestAuthMiddleware
+func SetIngestKeys(keys []string) {
+	ingestKeys.mu.Lock()
+	defer ingestKeys.mu.Unlock()
+
+	ingestKeys.keys = make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			ingestKeys.keys = append(ingestKeys.keys, []byte(key))
+		}
+	}
+}
+
+// validIngestKey reports whether presented is a configured ingest key
+func validIngestKey(presented string) bool {
+	ingestKeys.mu.RLock()
+	defer ingestKeys.mu.RUnlock()
+
+	valid := false
+	for _, key := range ingestKeys.keys {
+		if subtle.ConstantTimeCompare([]byte(presented), key) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// IngestAuthMiddleware admits requests bearing a configured ingest key as
+// IngestKeyUserID, and requires a producer's Clerk session otherwise. Clerk
+// sessions expire within minutes, so machine clients use a key.
+func IngestAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	producer := ClerkMiddleware(ProducerMiddleware(next))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && validIngestKey(presented) {
+			ctx := context.WithValue(r.Context(), "user_id", IngestKeyUserID)
+			next(w, r.WithContext(ctx))
+			return
+		}
+		producer(w, r)
+	}
+}
+
+// IsAdmin reports whether the user holds admin privileges
+func IsAdmin(userID string) bool {
+	roles.mu.RLock()
+	defer roles.mu.RUnlock()
+	return roles.admins[userID]
+}
+
+// IsModerator reports whether the user holds moderator (or admin) privileges
+func IsModerator(userID string) bool {
+	roles.mu.RLock()
+	defer roles.mu.RUnlock()
+	return roles.moderators[userID] || roles.admins[userID]
+}
+
+// IsProducer reports whether the user may push control messages to sessions
+func IsProducer(userID string) bool {
+	roles.mu.RLock()
+	defer roles.mu.RUnlock()
+	return roles.producers[userID] || roles.admins[userID]
+}
+
+// ModeratorMiddleware rejects requests from users without moderator privileges.
+// It must run after ClerkMiddleware so the user ID is present in the context.
+func ModeratorMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(string)
+		if userID == "" || !IsModerator(userID) {
+			writeError(w, errs.Forbidden("moderator permissions required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// AdminMiddleware rejects requests from users without admin privileges.
+// It must run after ClerkMiddleware so the user ID is present in the context.
+func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(string)
+		if userID == "" || !IsAdmin(userID) {
+			writeError(w, errs.Forbidden("admin permissions required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ProducerMiddleware rejects requests from users without producer privileges.
+// It must run after ClerkMiddleware so the user ID is present in the context.
+func ProducerMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(string)
+		if userID == "" || !IsProducer(userID) {
+			writeError(w, errs.Forbidden("producer permissions required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
 // ClerkMiddleware verifies the Clerk JWT on incoming HTTP requests.
 func ClerkMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -21,7 +169,7 @@ func ClerkMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		sessionToken = strings.TrimPrefix(sessionToken, "Bearer ")
 
 		if sessionToken == "" {
-			http.Error(w, "Unauthorized: missing token", http.StatusUnauthorized)
+			writeError(w, fmt.Errorf("%w: missing token", errs.ErrUnauthorized))
 			return
 		}
 
@@ -29,7 +177,7 @@ func ClerkMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			Token: sessionToken,
 		})
 		if err != nil {
-			http.Error(w, "Unauthorized: invalid token", http.StatusUnauthorized)
+			writeError(w, fmt.Errorf("%w: invalid token", errs.ErrUnauthorized))
 			return
 		}
 
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// Reasons a boost_ended message gives
+const (
+	BoostEndExpired = "expired" // the boost's window passed
+	BoostEndStopped = "stopped" // a producer ended it early
+)
+
+// BoostMessage announces that a session's reaction weights are boosted or
+// back to normal. Reason is set when the boost ended.
+type BoostMessage struct {
+	Type      string            `json:"type"`
+	SessionID string            `json:"session_id"`
+	Boost     aggregation.Boost `json:"boost"`
+	Reason    string            `json:"reason,omitempty"`
+}
+
+// NewBoostStartedMessage builds the broadcast announcing a boost
+func NewBoostStartedMessage(sessionID string, boost aggregation.Boost) BoostMessage {
+	return BoostMessage{Type: MessageTypeBoostStarted, SessionID: sessionID, Boost: boost}
+}
+
+// NewBoostEndedMessage builds the broadcast announcing a boost ended
+func NewBoostEndedMessage(sessionID string, boost aggregation.Boost, reason string) BoostMessage {
+	return BoostMessage{Type: MessageTypeBoostEnded, SessionID: sessionID, Boost: boost, Reason: reason}
+}
+
+// boostTimers announces the end of each session's running boost once its
+// window passes, unless the boost is stopped or the session ends first
+type boostTimers struct {
+	timers map[string]*time.Timer
+	mu     sync.Mutex
+}
+
+func newBoostTimers() *boostTimers {
+	return &boostTimers{timers: make(map[string]*time.Timer)}
+}
+
+// schedule calls expire after d unless the session's timer is cancelled or
+// replaced first
+func (b *boostTimers) schedule(sessionID string, d time.Duration, expire func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		if b.take(sessionID, timer) {
+			expire()
+		}
+	})
+	b.timers[sessionID] = timer
+}
+
+// take removes the session's timer, reporting whether it was still timer
+func (b *boostTimers) take(sessionID string, timer *time.Timer) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timers[sessionID] != timer {
+		return false
+	}
+	delete(b.timers, sessionID)
+	return true
+}
+
+// cancel stops the session's timer, if one is pending
+func (b *boostTimers) cancel(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if timer, exists := b.timers[sessionID]; exists {
+		timer.Stop()
+		delete(b.timers, sessionID)
+	}
+}
+
+// scheduleBoostEnd announces the boost's end once its window passes
+func (s *Server) scheduleBoostEnd(sessionID string, boost aggregation.Boost, now time.Time) {
+	s.boostEnds.schedule(sessionID, boost.EndsAt.Sub(now), func() {
+		s.wsHub.BroadcastToSession(sessionID, NewBoostEndedMessage(sessionID, boost, BoostEndExpired))
+	})
+}
+
+// ResumeBoost schedules the end of a session's running boost, for sessions
+// restored from a checkpoint or a standby whose timers did not survive
+func (s *Server) ResumeBoost(sessionID string) {
+	stats, exists := s.aggManager.GetSession(sessionID)
+	if !exists {
+		return
+	}
+	now := time.Now().UTC()
+	if boost := stats.GetBoost(now); boost != nil {
+		s.scheduleBoostEnd(sessionID, *boost, now)
+	}
+}
+
+// ResumeBoosts schedules the end of every running boost, once the server
+// has restored its sessions
+func (s *Server) ResumeBoosts() {
+	for _, sessionID := range s.aggManager.SessionIDs() {
+		s.ResumeBoost(sessionID)
+	}
+}
+
+// StartBoostRequest represents the request body for a boost. Multiplier
+// defaults to 2, a "double hype" window.
+type StartBoostRequest struct {
+	SessionID       string `json:"session_id"`
+	Multiplier      int    `json:"multiplier,omitempty"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	Label           string `json:"label,omitempty"`
+}
+
+// HandleBoost starts a boost of a session's reaction weights (POST), or ends
+// the running one early (DELETE ?session_id=). Boosted reactions count at
+// the multiplier in the weighted total and hype score, and every client is
+// told when the boost starts and ends.
+func (s *Server) HandleBoost(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req StartBoostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		if req.SessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		if len(req.Label) > 100 {
+			writeError(w, errs.Validation("label exceeds 100 character limit"))
+			return
+		}
+		if req.Multiplier == 0 {
+			req.Multiplier = aggregation.MinBoostMultiplier
+		}
+		if s.registry.IsEnded(req.SessionID) {
+			writeError(w, errs.ErrSessionEnded)
+			return
+		}
+
+		now := time.Now().UTC()
+		stats := s.aggManager.GetOrCreateSession(req.SessionID)
+		boost, err := stats.StartBoost(req.Multiplier, time.Duration(req.DurationSeconds)*time.Second, req.Label, now)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		sessionID := req.SessionID
+		s.scheduleBoostEnd(sessionID, boost, now)
+		s.wsHub.BroadcastToSession(sessionID, NewBoostStartedMessage(sessionID, boost))
+		s.recordAction(r, ActionBoostStart, sessionID, "", boost)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(boost)
+
+	case http.MethodDelete:
+		sessionID := r.URL.Query().Get("session_id")
+		if sessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		stats, exists := s.aggManager.GetSession(sessionID)
+		if !exists {
+			writeError(w, errs.NotFound("no boost is running"))
+			return
+		}
+		boost, ended := stats.EndBoost(time.Now().UTC())
+		if !ended {
+			writeError(w, errs.NotFound("no boost is running"))
+			return
+		}
+		s.boostEnds.cancel(sessionID)
+		s.wsHub.BroadcastToSession(sessionID, NewBoostEndedMessage(sessionID, boost, BoostEndStopped))
+		s.recordAction(r, ActionBoostEnd, sessionID, "", boost)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(boost)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleBoost_AnnouncesStartAndEarlyEnd(t *testing.T) {
+	hub := NewWebSocketHub()
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(16)
+	defer unsubscribe()
+
+	next := func() BoostMessage {
+		select {
+		case data := <-updates:
+			var msg BoostMessage
+			require.NoError(t, json.Unmarshal(data, &msg))
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("no boost broadcast")
+			return BoostMessage{}
+		}
+	}
+	start := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleBoost(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/boost", strings.NewReader(body)), "producer"))
+		return rec
+	}
+
+	rec := start(`{"session_id":"s1","duration_seconds":3600,"label":"Double hype hour"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var boost aggregation.Boost
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&boost))
+	assert.Equal(t, 2, boost.Multiplier, "boosts double reactions by default")
+	assert.Equal(t, time.Hour, boost.EndsAt.Sub(boost.StartedAt))
+
+	msg := next()
+	assert.Equal(t, MessageTypeBoostStarted, msg.Type)
+	assert.Equal(t, "Double hype hour", msg.Boost.Label)
+
+	assert.Equal(t, http.StatusConflict, start(`{"session_id":"s1","duration_seconds":60}`).Code)
+	assert.Equal(t, http.StatusBadRequest, start(`{"session_id":"s1","multiplier":50,"duration_seconds":60}`).Code)
+
+	stop := func() int {
+		rec := httptest.NewRecorder()
+		server.HandleBoost(rec, asUser(httptest.NewRequest(http.MethodDelete, "/api/sessions/boost?session_id=s1", nil), "producer"))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, stop())
+	msg = next()
+	assert.Equal(t, MessageTypeBoostEnded, msg.Type)
+	assert.Equal(t, BoostEndStopped, msg.Reason)
+	assert.Equal(t, boost.ID, msg.Boost.ID)
+	assert.Equal(t, http.StatusNotFound, stop())
+}
+
+// checkpointStore keeps aggregation checkpoints in memory
+type checkpointStore struct {
+	states map[string][]byte
+}
+
+func (c *checkpointStore) SaveSessionStates(_ context.Context, states map[string][]byte, _ []string) error {
+	for sessionID, data := range states {
+		c.states[sessionID] = data
+	}
+	return nil
+}
+
+func (c *checkpointStore) LoadSessionStates(_ context.Context, _ ...string) (map[string][]byte, error) {
+	return c.states, nil
+}
+
+func TestResumeBoost_AnnouncesTheEndOfRestoredBoosts(t *testing.T) {
+	store := &checkpointStore{states: make(map[string][]byte)}
+	previous := aggregation.NewManager()
+	started := time.Now().UTC().Add(-aggregation.MinBoostDuration + 100*time.Millisecond)
+	_, err := previous.GetOrCreateSession("s1").StartBoost(2, aggregation.MinBoostDuration, "", started)
+	require.NoError(t, err)
+	require.NoError(t, previous.Checkpoint(context.Background(), store))
+
+	hub := NewWebSocketHub()
+	manager := aggregation.NewManager()
+	server := NewServer(nil, manager, nil, hub, nil, nil, sessions.NewRegistry(), nil)
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+	manager.SetAdoptHandler(server.ResumeBoost)
+	restored, err := manager.Restore(context.Background(), store, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, restored)
+
+	select {
+	case data := <-updates:
+		var msg BoostMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, MessageTypeBoostEnded, msg.Type)
+		assert.Equal(t, BoostEndExpired, msg.Reason)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the restored boost's end was never announced")
+	}
+}
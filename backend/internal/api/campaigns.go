@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/milestones"
+)
+
+// SetCampaignTracker enables campaign milestones spanning multiple sessions
+func (s *Server) SetCampaignTracker(campaigns *milestones.CampaignTracker) {
+	s.campaigns = campaigns
+}
+
+// CreateCampaignRequest represents the request body for creating a campaign
+type CreateCampaignRequest struct {
+	ID         string                  `json:"id,omitempty"`
+	Name       string                  `json:"name"`
+	SessionIDs []string                `json:"session_ids,omitempty"`
+	Milestones []milestones.Definition `json:"milestones"`
+}
+
+// HandleCreateCampaign creates a campaign whose milestones aggregate across sessions
+func (s *Server) HandleCreateCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.campaigns == nil {
+		writeError(w, errs.NotFound("campaigns are not enabled"))
+		return
+	}
+
+	var req CreateCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	if len(req.Milestones) == 0 {
+		writeError(w, errs.Validation("at least one milestone is required"))
+		return
+	}
+	if req.ID == "" {
+		req.ID = uuid.New().String()
+	}
+	if req.Name == "" {
+		req.Name = "Untitled Campaign"
+	}
+
+	campaign, err := s.campaigns.CreateCampaign(req.ID, req.Name, req.SessionIDs, req.Milestones)
+	if err != nil {
+		writeError(w, errs.Validation("invalid campaign: %v", err))
+		return
+	}
+	s.recordAction(r, ActionCampaignCreate, req.ID, "", req)
+
+	// Sessions that already have stats count towards the campaign immediately
+	for _, sessionID := range req.SessionIDs {
+		if stats, exists := s.aggManager.GetSession(sessionID); exists {
+			s.campaigns.Observe(sessionID, stats)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// HandleGetCampaign returns a campaign and its milestone progress
+func (s *Server) HandleGetCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	campaignID := r.URL.Query().Get("campaign_id")
+	if campaignID == "" {
+		writeError(w, errs.Validation("campaign_id is required"))
+		return
+	}
+	if s.campaigns == nil {
+		writeError(w, errs.NotFound("campaign not found"))
+		return
+	}
+
+	campaign, exists := s.campaigns.GetCampaign(campaignID)
+	if !exists {
+		writeError(w, errs.NotFound("campaign not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
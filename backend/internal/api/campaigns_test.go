@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaign_AggregatesAcrossSessions(t *testing.T) {
+	manager := aggregation.NewManager()
+	achieved := make(chan *milestones.CampaignAchievement, 1)
+	campaigns := milestones.NewCampaignTracker(func(a *milestones.CampaignAchievement) { achieved <- a })
+	server := NewServer(nil, manager, nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	server.SetCampaignTracker(campaigns)
+
+	body := `{"id":"devweek","name":"Dev Week","session_ids":["talk-1","talk-2"],
+		"milestones":[{"type":"total_reactions","threshold":3,"reaction_weights":{"applause":1,"cheer":1}}]}`
+	rec := httptest.NewRecorder()
+	server.HandleCreateCampaign(rec, httptest.NewRequest(http.MethodPost, "/api/campaigns", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	react := func(sessionID string, reactionType events.ReactionType) {
+		manager.ProcessEvent(events.ReactionEvent(sessionID, "u1", reactionType))
+		stats, _ := manager.GetSession(sessionID)
+		campaigns.Observe(sessionID, stats)
+	}
+	react("talk-1", events.ReactionApplause)
+	react("talk-1", events.ReactionFire) // not counted by the weights
+	react("talk-2", events.ReactionCheer)
+
+	campaign, ok := campaigns.GetCampaign("devweek")
+	require.True(t, ok)
+	assert.Equal(t, int64(2), campaign.Milestones[0].Progress)
+	assert.False(t, campaign.Milestones[0].Achieved)
+
+	// A session ending keeps its contribution
+	manager.RemoveSession("talk-1")
+	react("talk-2", events.ReactionCheer)
+
+	select {
+	case achievement := <-achieved:
+		assert.Equal(t, "devweek", achievement.Campaign.ID)
+		assert.Equal(t, int64(3), achievement.CurrentValue)
+	default:
+		// The notification runs on its own goroutine
+		campaign, _ = campaigns.GetCampaign("devweek")
+		assert.True(t, campaign.Milestones[0].Achieved)
+	}
+
+	rec = httptest.NewRecorder()
+	server.HandleGetCampaign(rec, httptest.NewRequest(http.MethodGet, "/api/campaigns/progress?campaign_id=devweek", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var progress milestones.Campaign
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&progress))
+	assert.Equal(t, []string{"talk-1", "talk-2"}, progress.SessionIDs)
+}
+
+func TestCampaign_RejectsDurationMilestones(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	server.SetCampaignTracker(milestones.NewCampaignTracker(nil))
+
+	body := `{"name":"Dev Week","milestones":[{"type":"session_duration","threshold":60}]}`
+	rec := httptest.NewRecorder()
+	server.HandleCreateCampaign(rec, httptest.NewRequest(http.MethodPost, "/api/campaigns", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
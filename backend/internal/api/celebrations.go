@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/milestones"
+)
+
+// CelebrationCoordinator spaces out milestone broadcasts within a session,
+// so milestones achieved within seconds of each other are celebrated one
+// after another instead of stacking their animations. Achievements waiting
+// for the gap to pass are celebrated highest priority first.
+type CelebrationCoordinator struct {
+	hub    *WebSocketHub
+	minGap time.Duration
+	queues map[string]*celebrationQueue
+	mu     sync.Mutex
+}
+
+// celebrationQueue holds a session's achievements waiting to be celebrated
+type celebrationQueue struct {
+	pending     []*milestones.MilestoneAchievement
+	lastShownAt time.Time
+}
+
+// NewCelebrationCoordinator creates a coordinator leaving at least minGap
+// between the celebrations of a session
+func NewCelebrationCoordinator(hub *WebSocketHub, minGap time.Duration) *CelebrationCoordinator {
+	return &CelebrationCoordinator{
+		hub:    hub,
+		minGap: minGap,
+		queues: make(map[string]*celebrationQueue),
+	}
+}
+
+// Broadcaster returns a milestone handler that queues achievements for
+// celebration, like MilestoneBroadcaster, and calls next (e.g. external
+// notifiers) right away since only clients' animations need spacing
+func (c *CelebrationCoordinator) Broadcaster(next milestones.NotificationHandler) milestones.NotificationHandler {
+	return func(achievement *milestones.MilestoneAchievement) {
+		c.Enqueue(achievement)
+		if next != nil {
+			next(achievement)
+		}
+	}
+}
+
+// Enqueue queues an achievement to be celebrated. It is broadcast on the
+// next tick once the session's gap has passed, so achievements of the same
+// evaluation are ordered by priority.
+func (c *CelebrationCoordinator) Enqueue(achievement *milestones.MilestoneAchievement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queue, exists := c.queues[achievement.SessionID]
+	if !exists {
+		queue = &celebrationQueue{}
+		c.queues[achievement.SessionID] = queue
+	}
+	queue.pending = append(queue.pending, achievement)
+}
+
+// Start runs the celebration loop until the context is cancelled
+func (c *CelebrationCoordinator) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				c.emitDue(now)
+			}
+		}
+	}()
+}
+
+// emitDue celebrates the next achievement of every session whose gap passed
+func (c *CelebrationCoordinator) emitDue(now time.Time) {
+	var due []MilestoneAchievedMessage
+
+	c.mu.Lock()
+	for sessionID, queue := range c.queues {
+		if len(queue.pending) == 0 {
+			// Forget sessions once they could celebrate straight away again
+			if now.Sub(queue.lastShownAt) >= c.minGap {
+				delete(c.queues, sessionID)
+			}
+			continue
+		}
+		if now.Sub(queue.lastShownAt) < c.minGap {
+			continue
+		}
+
+		next := 0
+		for i, achievement := range queue.pending {
+			if celebratesBefore(achievement, queue.pending[next]) {
+				next = i
+			}
+		}
+		message := NewMilestoneAchievedMessage(queue.pending[next])
+		queue.pending = append(queue.pending[:next], queue.pending[next+1:]...)
+		message.QueuedCelebrations = len(queue.pending)
+		queue.lastShownAt = now
+		due = append(due, message)
+	}
+	c.mu.Unlock()
+
+	for _, message := range due {
+		c.hub.BroadcastToSession(message.SessionID, message)
+	}
+}
+
+// celebratesBefore reports whether a should be celebrated before b: higher
+// priority first, then in the order they were achieved
+func celebratesBefore(a, b *milestones.MilestoneAchievement) bool {
+	if pa, pb := celebrationPriority(a), celebrationPriority(b); pa != pb {
+		return pa > pb
+	}
+	return a.AchievedAt.Before(b.AchievedAt)
+}
+
+// celebrationPriority returns an achievement's presentation priority
+func celebrationPriority(achievement *milestones.MilestoneAchievement) int {
+	if achievement.Milestone == nil || achievement.Milestone.Presentation == nil {
+		return 0
+	}
+	return achievement.Milestone.Presentation.Priority
+}
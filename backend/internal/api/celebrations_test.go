@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCelebrationCoordinator_SpacesOutMilestonesByPriority(t *testing.T) {
+	hub := NewWebSocketHub()
+	coordinator := NewCelebrationCoordinator(hub, 3*time.Second)
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(16)
+	defer unsubscribe()
+
+	achievedAt := time.Now()
+	notified := 0
+	broadcaster := coordinator.Broadcaster(func(*milestones.MilestoneAchievement) { notified++ })
+	for i, priority := range []int{0, 5, 0} {
+		broadcaster(&milestones.MilestoneAchievement{
+			SessionID:  "s1",
+			AchievedAt: achievedAt.Add(time.Duration(i) * time.Millisecond),
+			Milestone: &milestones.Milestone{
+				ID:           []string{"first", "headline", "third"}[i],
+				Presentation: &milestones.Presentation{Priority: priority},
+			},
+		})
+	}
+	assert.Equal(t, 3, notified, "notifiers are not held back")
+
+	next := func() *MilestoneAchievedMessage {
+		select {
+		case data := <-updates:
+			var msg MilestoneAchievedMessage
+			require.NoError(t, json.Unmarshal(data, &msg))
+			return &msg
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		}
+	}
+
+	now := time.Now()
+	coordinator.emitDue(now)
+	msg := next()
+	require.NotNil(t, msg)
+	assert.Equal(t, "headline", msg.Milestone.ID, "the highest priority is celebrated first")
+	assert.Equal(t, 2, msg.QueuedCelebrations)
+
+	coordinator.emitDue(now.Add(time.Second))
+	assert.Nil(t, next(), "celebrations wait for the gap")
+
+	coordinator.emitDue(now.Add(3 * time.Second))
+	msg = next()
+	require.NotNil(t, msg)
+	assert.Equal(t, "first", msg.Milestone.ID, "equal priorities are celebrated in the order achieved")
+
+	coordinator.emitDue(now.Add(6 * time.Second))
+	msg = next()
+	require.NotNil(t, msg)
+	assert.Equal(t, "third", msg.Milestone.ID)
+	assert.Zero(t, msg.QueuedCelebrations)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// clientVersionPolicy admits joins from clients at or above a minimum app
+// version. The zero policy admits every client.
+type clientVersionPolicy struct {
+	min      *events.ClientVersion
+	required bool // joins reporting no version are rejected too
+}
+
+// SetMinClientVersion rejects joins from clients older than min with an
+// upgrade_required error. With requireVersion, joins that report no
+// version, or a malformed one, are rejected as well; otherwise they are
+// admitted.
+func (s *Server) SetMinClientVersion(min events.ClientVersion, requireVersion bool) {
+	s.minVersion = clientVersionPolicy{min: &min, required: requireVersion}
+}
+
+// UpgradeRequiredResponse rejects a join from an outdated client, telling
+// it which version to upgrade to. Over WebSockets it is sent with Type
+// "error" before the connection closes.
+type UpgradeRequiredResponse struct {
+	Type          string    `json:"type,omitempty"`
+	Code          errs.Code `json:"code"`
+	Message       string    `json:"message"`
+	ClientVersion string    `json:"client_version,omitempty"`
+	MinVersion    string    `json:"min_version"`
+}
+
+// check returns the rejection for a join reporting version, or nil if the
+// join is admitted
+func (p clientVersionPolicy) check(version string) *UpgradeRequiredResponse {
+	if p.min == nil {
+		return nil
+	}
+	v, ok := events.ParseClientVersion(version)
+	if ok && !v.Less(*p.min) {
+		return nil
+	}
+	if !ok && !p.required {
+		return nil
+	}
+	message := fmt.Sprintf("client version %s is no longer supported; upgrade to %s or later", version, p.min)
+	if version == "" {
+		message = fmt.Sprintf("clients must report their version; upgrade to %s or later", p.min)
+	}
+	return &UpgradeRequiredResponse{
+		Code:          errs.CodeUpgradeRequired,
+		Message:       message,
+		ClientVersion: version,
+		MinVersion:    p.min.String(),
+	}
+}
+
+// writeUpgradeRequired sends the rejection of an outdated client's join
+func writeUpgradeRequired(w http.ResponseWriter, rejection *UpgradeRequiredResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusUpgradeRequired)
+	json.NewEncoder(w).Encode(rejection)
+}
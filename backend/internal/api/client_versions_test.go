@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleJoinSession_RejectsOutdatedClients(t *testing.T) {
+	queue := events.NewQueue(4)
+	registry := sessions.NewRegistry()
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	minVersion, _ := events.ParseClientVersion("2.4.0")
+	server.SetMinClientVersion(minVersion, false)
+
+	join := func(version string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleJoinSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/join?session_id=s1&user_id=u1&client_version="+version, nil))
+		return rec
+	}
+
+	rec := join("2.3.9")
+	require.Equal(t, http.StatusUpgradeRequired, rec.Code)
+	var rejection UpgradeRequiredResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rejection))
+	assert.Equal(t, errs.CodeUpgradeRequired, rejection.Code)
+	assert.Equal(t, "2.4.0", rejection.MinVersion)
+	assert.Equal(t, "2.3.9", rejection.ClientVersion)
+
+	assert.Equal(t, http.StatusOK, join("2.10.0").Code)
+	assert.Equal(t, http.StatusOK, join("").Code, "unversioned clients are admitted unless versions are required")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	event, ok := queue.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "2.10.0", event.GetClientVersion())
+
+	server.SetMinClientVersion(minVersion, true)
+	assert.Equal(t, http.StatusUpgradeRequired, join("").Code)
+}
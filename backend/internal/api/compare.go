@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// ShowMetrics are the headline numbers of one archived session
+type ShowMetrics struct {
+	SessionID          string    `json:"session_id"`
+	Name               string    `json:"name"`
+	EndedAt            time.Time `json:"ended_at"`
+	DurationSeconds    float64   `json:"duration_seconds"`
+	PeakUsers          int       `json:"peak_users"`
+	PeakAfterSeconds   *float64  `json:"peak_after_seconds,omitempty"` // from the start of the show
+	TotalReactions     int64     `json:"total_reactions"`
+	ReactionsPerMinute float64   `json:"reactions_per_minute"`
+	MilestonesAchieved int       `json:"milestones_achieved"`
+}
+
+// MilestoneComparison lines up when two shows reached the same milestone,
+// in seconds from the start of each show; nil where a show never reached it
+type MilestoneComparison struct {
+	Type                 milestones.MilestoneType `json:"type"`
+	Threshold            int64                    `json:"threshold"`
+	SessionAchievedAfter *float64                 `json:"session_achieved_after_seconds"`
+	WithAchievedAfter    *float64                 `json:"with_achieved_after_seconds"`
+}
+
+// SessionComparison sets a show beside an earlier one. Deltas are the
+// percentage change of each metric from the earlier show, nil where the
+// earlier show's value was zero.
+type SessionComparison struct {
+	Session    ShowMetrics           `json:"session"`
+	With       ShowMetrics           `json:"with"`
+	Deltas     map[string]*float64   `json:"deltas"`
+	Milestones []MilestoneComparison `json:"milestones"`
+}
+
+// archivedShow is an archived session decoded for comparison
+type archivedShow struct {
+	metrics    ShowMetrics
+	achievedAt map[milestoneKey]float64 // seconds from the start of the show
+}
+
+// milestoneKey identifies the same milestone across shows
+type milestoneKey struct {
+	milestoneType milestones.MilestoneType
+	threshold     int64
+}
+
+// decodeArchivedShow extracts the comparable metrics of an archive
+func decodeArchivedShow(archived *storage.SessionSnapshot) (archivedShow, error) {
+	var snapshot schema.SnapshotV1
+	if err := json.Unmarshal(archived.Snapshot, &snapshot); err != nil {
+		return archivedShow{}, fmt.Errorf("decoding snapshot of session %s: %w", archived.SessionID, err)
+	}
+	var achieved []schema.MilestoneV1
+	if len(archived.Milestones) > 0 {
+		if err := json.Unmarshal(archived.Milestones, &achieved); err != nil {
+			return archivedShow{}, fmt.Errorf("decoding milestones of session %s: %w", archived.SessionID, err)
+		}
+	}
+
+	show := archivedShow{
+		metrics: ShowMetrics{
+			SessionID:       archived.SessionID,
+			Name:            archived.Name,
+			EndedAt:         archived.EndedAt,
+			DurationSeconds: snapshot.DurationSeconds,
+			PeakUsers:       archived.PeakUsers,
+			TotalReactions:  archived.TotalReactions,
+		},
+		achievedAt: make(map[milestoneKey]float64),
+	}
+	if minutes := snapshot.DurationSeconds / 60; minutes > 0 {
+		show.metrics.ReactionsPerMinute = float64(archived.TotalReactions) / minutes
+	}
+	if archived.PeakAt != nil && !snapshot.StartTime.IsZero() {
+		after := archived.PeakAt.Sub(snapshot.StartTime).Seconds()
+		show.metrics.PeakAfterSeconds = &after
+	}
+	for _, milestone := range achieved {
+		if !milestone.Achieved || milestone.AchievedAt == nil {
+			continue
+		}
+		show.metrics.MilestonesAchieved++
+		show.achievedAt[milestoneKey{milestones.MilestoneType(milestone.Type), milestone.Threshold}] = milestone.AchievedAt.Sub(snapshot.StartTime).Seconds()
+	}
+	return show, nil
+}
+
+// percentChange returns the change from before to after in percent, or nil
+// if before is zero
+func percentChange(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	change := (after - before) / before * 100
+	return &change
+}
+
+// compareShows sets an archived show beside the one it is compared with
+func compareShows(session, with archivedShow) SessionComparison {
+	a, b := session.metrics, with.metrics
+	comparison := SessionComparison{
+		Session: a,
+		With:    b,
+		Deltas: map[string]*float64{
+			"duration_seconds":     percentChange(b.DurationSeconds, a.DurationSeconds),
+			"peak_users":           percentChange(float64(b.PeakUsers), float64(a.PeakUsers)),
+			"total_reactions":      percentChange(float64(b.TotalReactions), float64(a.TotalReactions)),
+			"reactions_per_minute": percentChange(b.ReactionsPerMinute, a.ReactionsPerMinute),
+			"milestones_achieved":  percentChange(float64(b.MilestonesAchieved), float64(a.MilestonesAchieved)),
+		},
+		Milestones: []MilestoneComparison{},
+	}
+
+	keys := make(map[milestoneKey]bool)
+	for key := range session.achievedAt {
+		keys[key] = true
+	}
+	for key := range with.achievedAt {
+		keys[key] = true
+	}
+	for key := range keys {
+		row := MilestoneComparison{Type: key.milestoneType, Threshold: key.threshold}
+		if after, ok := session.achievedAt[key]; ok {
+			row.SessionAchievedAfter = &after
+		}
+		if after, ok := with.achievedAt[key]; ok {
+			row.WithAchievedAfter = &after
+		}
+		comparison.Milestones = append(comparison.Milestones, row)
+	}
+	sort.Slice(comparison.Milestones, func(i, j int) bool {
+		mi, mj := comparison.Milestones[i], comparison.Milestones[j]
+		if mi.Type != mj.Type {
+			return mi.Type < mj.Type
+		}
+		return mi.Threshold < mj.Threshold
+	})
+	return comparison
+}
+
+// HandleCompareSessions compares an archived session with another of the
+// same tenant, e.g. this week's show with last week's:
+// GET /api/sessions/compare?session_id=X&with=Y
+func (s *Server) HandleCompareSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID, withID := r.URL.Query().Get("session_id"), r.URL.Query().Get("with")
+	if sessionID == "" || withID == "" {
+		writeError(w, errs.Validation("session_id and with are required"))
+		return
+	}
+	if sessionID == withID {
+		writeError(w, errs.Validation("a session cannot be compared with itself"))
+		return
+	}
+	tenantID := sessions.TenantOf(sessionID)
+	if sessions.TenantOf(withID) != tenantID {
+		writeError(w, errs.Validation("only sessions of the same tenant can be compared"))
+		return
+	}
+	if s.db == nil {
+		writeError(w, errs.Unavailable("archive not available"))
+		return
+	}
+
+	shows := make([]archivedShow, 0, 2)
+	for _, id := range []string{sessionID, withID} {
+		archived, err := s.archiveDB(tenantID).GetSessionSnapshot(r.Context(), id)
+		if err != nil {
+			log.Printf("Error loading archive for session %s: %v", id, err)
+			writeError(w, err)
+			return
+		}
+		if archived == nil {
+			writeError(w, errs.NotFound("session %s not archived", id))
+			return
+		}
+		show, err := decodeArchivedShow(archived)
+		if err != nil {
+			log.Printf("Error comparing sessions: %v", err)
+			writeError(w, err)
+			return
+		}
+		shows = append(shows, show)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(compareShows(shows[0], shows[1]))
+}
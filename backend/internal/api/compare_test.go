@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveOf builds an archive of a show that started at start and ran for
+// duration, with the given milestones achieved after the given offsets
+func archiveOf(t *testing.T, id string, start time.Time, duration time.Duration, peakUsers int, peakAfter time.Duration, reactions int64, achieved map[int64]time.Duration) *storage.SessionSnapshot {
+	t.Helper()
+	snapshot, err := json.Marshal(aggregation.StatsSnapshot{SessionID: id, StartTime: start, Duration: duration.Seconds()})
+	require.NoError(t, err)
+
+	var list []*milestones.Milestone
+	for threshold, after := range achieved {
+		at := start.Add(after)
+		list = append(list, &milestones.Milestone{Type: milestones.MilestoneTypeTotalReactions, Threshold: threshold, Achieved: true, AchievedAt: &at})
+	}
+	list = append(list, &milestones.Milestone{Type: milestones.MilestoneTypeTotalReactions, Threshold: 1000000})
+	encoded, err := json.Marshal(list)
+	require.NoError(t, err)
+
+	peakAt := start.Add(peakAfter)
+	return &storage.SessionSnapshot{
+		SessionID:      id,
+		PeakUsers:      peakUsers,
+		PeakAt:         &peakAt,
+		TotalReactions: reactions,
+		Snapshot:       snapshot,
+		Milestones:     encoded,
+		EndedAt:        start.Add(duration),
+	}
+}
+
+func TestCompareShows_ReportsMetricsDeltasAndMilestoneTimes(t *testing.T) {
+	lastWeek := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	thisWeek := lastWeek.AddDate(0, 0, 7)
+
+	session, err := decodeArchivedShow(archiveOf(t, "show-2", thisWeek, time.Hour, 150, 30*time.Minute, 12000, map[int64]time.Duration{100: 5 * time.Minute, 10000: 50 * time.Minute}))
+	require.NoError(t, err)
+	with, err := decodeArchivedShow(archiveOf(t, "show-1", lastWeek, time.Hour, 100, 40*time.Minute, 6000, map[int64]time.Duration{100: 10 * time.Minute}))
+	require.NoError(t, err)
+
+	comparison := compareShows(session, with)
+
+	assert.Equal(t, "show-2", comparison.Session.SessionID)
+	assert.Equal(t, 200.0, comparison.Session.ReactionsPerMinute)
+	assert.Equal(t, 100.0, comparison.With.ReactionsPerMinute)
+	require.NotNil(t, comparison.Session.PeakAfterSeconds)
+	assert.Equal(t, 1800.0, *comparison.Session.PeakAfterSeconds)
+	assert.Equal(t, 2, comparison.Session.MilestonesAchieved, "unachieved milestones are not counted")
+
+	require.NotNil(t, comparison.Deltas["peak_users"])
+	assert.InDelta(t, 50.0, *comparison.Deltas["peak_users"], 1e-9)
+	assert.InDelta(t, 100.0, *comparison.Deltas["total_reactions"], 1e-9)
+	assert.InDelta(t, 100.0, *comparison.Deltas["reactions_per_minute"], 1e-9)
+	assert.InDelta(t, 0.0, *comparison.Deltas["duration_seconds"], 1e-9)
+
+	require.Len(t, comparison.Milestones, 2)
+	first, second := comparison.Milestones[0], comparison.Milestones[1]
+	assert.Equal(t, int64(100), first.Threshold)
+	assert.Equal(t, 300.0, *first.SessionAchievedAfter)
+	assert.Equal(t, 600.0, *first.WithAchievedAfter)
+	assert.Equal(t, int64(10000), second.Threshold)
+	assert.Equal(t, 3000.0, *second.SessionAchievedAfter)
+	assert.Nil(t, second.WithAchievedAfter, "last week's show never got there")
+}
+
+func TestCompareShows_LeavesDeltasFromZeroUnset(t *testing.T) {
+	start := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	session, err := decodeArchivedShow(archiveOf(t, "show-2", start, time.Hour, 10, 0, 50, nil))
+	require.NoError(t, err)
+	with, err := decodeArchivedShow(archiveOf(t, "show-1", start, time.Hour, 0, 0, 0, nil))
+	require.NoError(t, err)
+
+	comparison := compareShows(session, with)
+	assert.Nil(t, comparison.Deltas["peak_users"])
+	assert.Nil(t, comparison.Deltas["total_reactions"])
+	assert.NotNil(t, comparison.Milestones, "encodes as an empty list")
+}
+
+func TestHandleCompareSessions_ValidatesTheSessions(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+
+	for _, query := range []string{"", "?session_id=a", "?session_id=a&with=a", "?session_id=acme:a&with=other:b"} {
+		rec := httptest.NewRecorder()
+		server.HandleCompareSessions(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/compare"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "query %q", query)
+	}
+
+	rec := httptest.NewRecorder()
+	server.HandleCompareSessions(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/compare?session_id=a&with=b", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "no archive configured")
+}
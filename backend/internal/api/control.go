@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// maxTrackedControls bounds how many control messages per session keep
+// delivery records
+const maxTrackedControls = 50
+
+// controlDeliveryTimeout bounds how long a producer waits for the hub to
+// fan a control message out
+const controlDeliveryTimeout = 2 * time.Second
+
+// ControlMessage is a producer announcement shown to everyone in a session,
+// e.g. "switching to Q&A now"
+type ControlMessage struct {
+	Type              string              `json:"type"`
+	ID                string              `json:"id"`
+	SessionID         string              `json:"session_id"`
+	Text              string              `json:"text"`
+	SuggestedReaction events.ReactionType `json:"suggested_reaction,omitempty"`
+	SentAt            time.Time           `json:"sent_at"`
+
+	// Wave is set when the message counts clients down to a reaction wave
+	Wave *WaveCue `json:"wave,omitempty"`
+}
+
+// ControlDelivery reports how far a control message got. Recipients counts
+// connections it was queued to; Acknowledged counts connections that
+// confirmed receipt with a control_ack message.
+type ControlDelivery struct {
+	MessageID    string    `json:"message_id"`
+	SessionID    string    `json:"session_id"`
+	SentBy       string    `json:"sent_by"`
+	SentAt       time.Time `json:"sent_at"`
+	Recipients   int       `json:"recipients"`
+	Dropped      int       `json:"dropped"`
+	Acknowledged int       `json:"acknowledged"`
+}
+
+// trackControl starts a delivery record, evicting the oldest past the cap
+func (h *SessionHub) trackControl(delivery *ControlDelivery) {
+	h.controlMu.Lock()
+	defer h.controlMu.Unlock()
+
+	h.controls[delivery.MessageID] = delivery
+	h.controlOrder = append(h.controlOrder, delivery.MessageID)
+	if len(h.controlOrder) > maxTrackedControls {
+		delete(h.controls, h.controlOrder[0])
+		h.controlOrder = h.controlOrder[1:]
+	}
+}
+
+// recordControlFanout stores the fan-out counts reported by the run loop
+func (h *SessionHub) recordControlFanout(messageID string, recipients, dropped int) {
+	h.controlMu.Lock()
+	defer h.controlMu.Unlock()
+
+	if delivery, exists := h.controls[messageID]; exists {
+		delivery.Recipients = recipients
+		delivery.Dropped = dropped
+	}
+}
+
+// acknowledgeControl counts a client's receipt confirmation
+func (h *SessionHub) acknowledgeControl(messageID string) bool {
+	h.controlMu.Lock()
+	defer h.controlMu.Unlock()
+
+	delivery, exists := h.controls[messageID]
+	if !exists {
+		return false
+	}
+	delivery.Acknowledged++
+	return true
+}
+
+// controlDelivery returns a copy of a control message's delivery record
+func (h *SessionHub) controlDelivery(messageID string) (ControlDelivery, bool) {
+	h.controlMu.Lock()
+	defer h.controlMu.Unlock()
+
+	delivery, exists := h.controls[messageID]
+	if !exists {
+		return ControlDelivery{}, false
+	}
+	return *delivery, true
+}
+
+// SendControl broadcasts a control message and waits until the hub has
+// queued it to every connection in the session. Delivery is tracked per
+// node, so control messages reach only this node's connections and are not
+// relayed.
+func (h *WebSocketHub) SendControl(ctx context.Context, msg ControlMessage, sentBy string) (ControlDelivery, error) {
+	out, err := newOutboundMessage(msg)
+	if err != nil {
+		return ControlDelivery{}, err
+	}
+
+	hub := h.GetOrCreateSessionHub(msg.SessionID)
+	hub.trackControl(&ControlDelivery{
+		MessageID: msg.ID,
+		SessionID: msg.SessionID,
+		SentBy:    sentBy,
+		SentAt:    msg.SentAt,
+	})
+
+	done := make(chan struct{})
+	out.delivered = func(recipients, dropped int) {
+		hub.recordControlFanout(msg.ID, recipients, dropped)
+		close(done)
+	}
+	select {
+	case hub.broadcast <- out:
+	case <-ctx.Done():
+		return ControlDelivery{}, ctx.Err()
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ControlDelivery{}, ctx.Err()
+	}
+	delivery, _ := hub.controlDelivery(msg.ID)
+	return delivery, nil
+}
+
+// ControlDelivery returns the delivery record of a recent control message
+func (h *WebSocketHub) ControlDelivery(sessionID, messageID string) (ControlDelivery, bool) {
+	h.mu.RLock()
+	hub, exists := h.sessions[sessionID]
+	h.mu.RUnlock()
+	if !exists {
+		return ControlDelivery{}, false
+	}
+	return hub.controlDelivery(messageID)
+}
+
+// SendControlRequest represents the request body for a producer control message
+type SendControlRequest struct {
+	SessionID         string              `json:"session_id"`
+	Text              string              `json:"text"`
+	SuggestedReaction events.ReactionType `json:"suggested_reaction,omitempty"`
+}
+
+// HandleControlMessages sends a control message to a session (POST) or
+// reports delivery of a recent one (GET ?session_id=&message_id=)
+func (s *Server) HandleControlMessages(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sessionID := r.URL.Query().Get("session_id")
+		messageID := r.URL.Query().Get("message_id")
+		if sessionID == "" || messageID == "" {
+			writeError(w, errs.Validation("session_id and message_id are required"))
+			return
+		}
+		delivery, exists := s.wsHub.ControlDelivery(sessionID, messageID)
+		if !exists {
+			writeError(w, errs.NotFound("control message not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(delivery)
+
+	case http.MethodPost:
+		var req SendControlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		if req.SessionID == "" || req.Text == "" {
+			writeError(w, errs.Validation("session_id and text are required"))
+			return
+		}
+		if len(req.Text) > 500 {
+			writeError(w, errs.Validation("text exceeds 500 character limit"))
+			return
+		}
+		if req.SuggestedReaction != "" && !req.SuggestedReaction.IsValid() {
+			writeError(w, errs.Validation("invalid suggested_reaction"))
+			return
+		}
+		if s.registry.IsEnded(req.SessionID) {
+			writeError(w, errs.ErrSessionEnded)
+			return
+		}
+
+		msg := ControlMessage{
+			Type:              MessageTypeControl,
+			ID:                events.NewEventID(),
+			SessionID:         req.SessionID,
+			Text:              req.Text,
+			SuggestedReaction: req.SuggestedReaction,
+			SentAt:            time.Now().UTC(),
+		}
+		userID, _ := r.Context().Value("user_id").(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), controlDeliveryTimeout)
+		defer cancel()
+		delivery, err := s.wsHub.SendControl(ctx, msg, userID)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w delivering control message", errs.ErrTimeout))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  msg,
+			"delivery": delivery,
+		})
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendControl_ReportsDeliveryAndAcks(t *testing.T) {
+	hub := NewWebSocketHub()
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+	_, unsubscribeSecond := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribeSecond()
+
+	msg := ControlMessage{
+		Type:              MessageTypeControl,
+		ID:                events.NewEventID(),
+		SessionID:         "s1",
+		Text:              "React with fire if you want an encore",
+		SuggestedReaction: events.ReactionFire,
+		SentAt:            time.Now().UTC(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	delivery, err := hub.SendControl(ctx, msg, "producer-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivery.Recipients)
+	assert.Equal(t, 0, delivery.Dropped)
+	assert.Equal(t, "producer-1", delivery.SentBy)
+
+	var received ControlMessage
+	require.NoError(t, json.Unmarshal(<-updates, &received))
+	assert.Equal(t, MessageTypeControl, received.Type)
+	assert.Equal(t, msg.ID, received.ID)
+	assert.Equal(t, events.ReactionFire, received.SuggestedReaction)
+
+	assert.True(t, hub.GetOrCreateSessionHub("s1").acknowledgeControl(msg.ID))
+	assert.False(t, hub.GetOrCreateSessionHub("s1").acknowledgeControl("unknown"))
+	delivery, ok := hub.ControlDelivery("s1", msg.ID)
+	require.True(t, ok)
+	assert.Equal(t, 1, delivery.Acknowledged)
+}
+
+func TestBroadcastToSession_FansOutToAliases(t *testing.T) {
+	registry := sessions.NewRegistry()
+	registry.Create(sessions.Session{ID: "show"})
+	_, err := registry.SetAliases("show", []string{"show-yt"})
+	require.NoError(t, err)
+	hub := NewWebSocketHub()
+	NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, registry, nil)
+
+	canonical, unsubscribe := hub.GetOrCreateSessionHub("show").Subscribe(4)
+	defer unsubscribe()
+	alias, unsubscribeAlias := hub.GetOrCreateSessionHub("show-yt").Subscribe(4)
+	defer unsubscribeAlias()
+
+	hub.BroadcastToSession("show", map[string]string{"type": "stats_update"})
+	for _, updates := range []<-chan []byte{canonical, alias} {
+		select {
+		case msg := <-updates:
+			assert.Contains(t, string(msg), "stats_update")
+		case <-time.After(time.Second):
+			t.Fatal("broadcast did not reach every alias")
+		}
+	}
+}
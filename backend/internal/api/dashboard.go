@@ -0,0 +1,114 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// dashboardMaxSessions bounds the live sessions the demo dashboard shows,
+// busiest first
+const dashboardMaxSessions = 50
+
+// DashboardPayload is the demo dashboard's view of every live session
+type DashboardPayload struct {
+	Type     string             `json:"type"`
+	Sessions []DashboardSession `json:"sessions"`
+}
+
+// DashboardSession is one live session's counters, rates and milestone
+// progress
+type DashboardSession struct {
+	SessionID          string                        `json:"session_id"`
+	Name               string                        `json:"name"`
+	ActiveUsers        int                           `json:"active_users"`
+	PeakUsers          int                           `json:"peak_users"`
+	TotalReactions     int64                         `json:"total_reactions"`
+	ReactionsPerMinute int64                         `json:"reactions_per_minute"`
+	ReactionCounts     map[events.ReactionType]int64 `json:"reaction_counts"`
+	Milestones         []DashboardMilestone          `json:"milestones"`
+}
+
+// DashboardMilestone is a milestone's progress toward its threshold
+type DashboardMilestone struct {
+	Description string `json:"description"`
+	Progress    int64  `json:"progress"`
+	Threshold   int64  `json:"threshold"`
+	Achieved    bool   `json:"achieved"`
+	Locked      bool   `json:"locked,omitempty"`
+}
+
+// SetDashboard enables the demo dashboard feed, refreshed every
+// pushInterval. A zero interval disables it.
+func (s *Server) SetDashboard(pushInterval time.Duration) {
+	s.dashboardInterval = pushInterval
+}
+
+// dashboardPayload builds the dashboard's view of the live sessions
+func (s *Server) dashboardPayload() DashboardPayload {
+	payload := DashboardPayload{Type: "dashboard", Sessions: []DashboardSession{}}
+	for _, session := range s.registry.List(sessions.Filter{Status: sessions.StatusLive}) {
+		view := DashboardSession{
+			SessionID:      session.ID,
+			Name:           session.Name,
+			ReactionCounts: map[events.ReactionType]int64{},
+			Milestones:     []DashboardMilestone{},
+		}
+		if stats, exists := s.aggManager.GetSession(session.ID); exists {
+			snapshot := stats.GetSnapshot()
+			view.ActiveUsers = snapshot.ActiveUserCount
+			view.PeakUsers = snapshot.PeakConcurrentUsers
+			view.TotalReactions = snapshot.TotalReactions
+			view.ReactionsPerMinute = stats.GetReactionVelocity(time.Minute)
+			view.ReactionCounts = snapshot.ReactionCounts
+		}
+		if s.tracker != nil {
+			for _, m := range s.tracker.GetSessionMilestones(session.ID) {
+				view.Milestones = append(view.Milestones, DashboardMilestone{
+					Description: m.Description,
+					Progress:    m.Progress,
+					Threshold:   m.Threshold,
+					Achieved:    m.Achieved,
+					Locked:      m.Locked,
+				})
+			}
+		}
+		payload.Sessions = append(payload.Sessions, view)
+	}
+
+	sort.Slice(payload.Sessions, func(i, j int) bool {
+		a, b := payload.Sessions[i], payload.Sessions[j]
+		if a.ActiveUsers != b.ActiveUsers {
+			return a.ActiveUsers > b.ActiveUsers
+		}
+		return a.SessionID < b.SessionID
+	})
+	if len(payload.Sessions) > dashboardMaxSessions {
+		payload.Sessions = payload.Sessions[:dashboardMaxSessions]
+	}
+	return payload
+}
+
+// HandleDashboardWebSocket streams the live sessions to the demo dashboard,
+// sending them on connect and whenever they change. The connection is
+// read-only and needs no authentication.
+func (s *Server) HandleDashboardWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.dashboardInterval <= 0 {
+		writeError(w, errs.NotFound("the dashboard is not enabled"))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Dashboard WebSocket upgrade error: %v", err)
+		return
+	}
+	streamReadOnly(conn, s.dashboardInterval, nil, "", func() interface{} {
+		return s.dashboardPayload()
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDashboardWebSocket_StreamsLiveSessionsBusiestFirst(t *testing.T) {
+	manager := aggregation.NewManager()
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, manager, nil, nil, nil, nil, registry, nil)
+
+	rec := httptest.NewRecorder()
+	server.HandleDashboardWebSocket(rec, httptest.NewRequest(http.MethodGet, "/dashboard/ws", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the dashboard is off until enabled")
+
+	server.SetDashboard(10 * time.Millisecond)
+	registry.Create(sessions.Session{ID: "quiet", Name: "Quiet show"})
+	registry.Create(sessions.Session{ID: "busy", Name: "Busy show"})
+	manager.ProcessEvent(events.JoinSessionEvent("busy", "u1"))
+	manager.ProcessEvent(events.JoinSessionEvent("busy", "u2"))
+	manager.ProcessEvent(events.ReactionEvent("busy", "u1", events.ReactionFire))
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleDashboardWebSocket))
+	defer ts.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var payload DashboardPayload
+	require.NoError(t, conn.ReadJSON(&payload))
+	require.Len(t, payload.Sessions, 2)
+	assert.Equal(t, "busy", payload.Sessions[0].SessionID)
+	assert.Equal(t, 2, payload.Sessions[0].ActiveUsers)
+	assert.Equal(t, int64(1), payload.Sessions[0].TotalReactions)
+	assert.Equal(t, int64(1), payload.Sessions[0].ReactionsPerMinute)
+	assert.Equal(t, "quiet", payload.Sessions[1].SessionID)
+
+	// Changes are pushed without the client asking
+	manager.ProcessEvent(events.JoinSessionEvent("quiet", "u3"))
+	require.NoError(t, conn.ReadJSON(&payload))
+	assert.Equal(t, 1, payload.Sessions[1].ActiveUsers)
+}
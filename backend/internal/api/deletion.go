@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// purgeCheckInterval is how often deleted sessions are checked for purging
+const purgeCheckInterval = time.Minute
+
+// DeletedSession is a soft-deleted session and when it will be purged
+type DeletedSession struct {
+	Session sessions.Session `json:"session"`
+	PurgeAt time.Time        `json:"purge_at"`
+}
+
+// SetPurgeAfter sets how long deleted sessions can be restored before they
+// are purged
+func (s *Server) SetPurgeAfter(d time.Duration) {
+	s.purgeAfter = d
+}
+
+// HandleDeleteSession soft-deletes the session given by ?session_id=. It
+// disappears from listings and its joins and events are refused, but its
+// stats and milestones are kept until it is purged, so it can be restored.
+func (s *Server) HandleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	session, ok := s.registry.Delete(sessionID)
+	if !ok {
+		writeError(w, errs.NotFound("session not found or already deleted"))
+		return
+	}
+	if s.statsCache != nil {
+		s.statsCache.remove(sessionID)
+	}
+	s.recordAction(r, ActionSessionDelete, sessionID, "", nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeletedSession{Session: session, PurgeAt: session.DeletedAt.Add(s.purgeAfter)})
+}
+
+// HandleRestoreSession restores the soft-deleted session given by
+// ?session_id= as it was when it was deleted
+func (s *Server) HandleRestoreSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	session, ok := s.registry.Undelete(sessionID)
+	if !ok {
+		writeError(w, errs.NotFound("session not found or not deleted"))
+		return
+	}
+	s.recordAction(r, ActionSessionRestore, sessionID, "", nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"session": session})
+}
+
+// PurgeDeleted forgets the sessions deleted longer ago than the purge
+// period, releasing their stats and milestones, and returns how many were
+// purged
+func (s *Server) PurgeDeleted(now time.Time) int {
+	purged := s.registry.Purge(now.Add(-s.purgeAfter))
+	for _, session := range purged {
+		s.releaseSession(session.ID)
+		log.Printf("Session %s purged, deleted at %s", session.ID, session.DeletedAt.Format(time.RFC3339))
+	}
+	return len(purged)
+}
+
+// StartPurging purges deleted sessions once their purge period is over,
+// until ctx is cancelled
+func (s *Server) StartPurging(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(purgeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.PurgeDeleted(now)
+			}
+		}
+	}()
+}
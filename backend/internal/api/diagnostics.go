@@ -0,0 +1,127 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jrudman25/livepulse/internal/diagnostics"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// maxProfileSeconds bounds CPU profiles and execution traces, which slow
+// the process down while they run
+const maxProfileSeconds = 30
+
+// BufferUsage reports how full the broadcast buffers of this instance are
+type BufferUsage struct {
+	Sessions          int     `json:"sessions"`
+	Clients           int     `json:"clients"`
+	BroadcastQueued   int     `json:"broadcast_queued"`
+	BroadcastCapacity int     `json:"broadcast_capacity"`
+	SendQueued        int     `json:"send_queued"`
+	SendCapacity      int     `json:"send_capacity"`
+	FullestSend       float64 `json:"fullest_send"` // fill of the fullest client buffer, 0 to 1
+}
+
+// BufferUsage sums the session broadcast queues and client send buffers
+func (h *WebSocketHub) BufferUsage() BufferUsage {
+	h.mu.RLock()
+	hubs := make([]*SessionHub, 0, len(h.sessions))
+	for _, hub := range h.sessions {
+		hubs = append(hubs, hub)
+	}
+	h.mu.RUnlock()
+
+	usage := BufferUsage{Sessions: len(hubs)}
+	for _, hub := range hubs {
+		usage.BroadcastQueued += len(hub.broadcast)
+		usage.BroadcastCapacity += cap(hub.broadcast)
+
+		hub.mu.RLock()
+		for client := range hub.clients {
+			usage.Clients++
+			usage.SendQueued += len(client.send)
+			usage.SendCapacity += cap(client.send)
+			if cap(client.send) > 0 {
+				if fill := float64(len(client.send)) / float64(cap(client.send)); fill > usage.FullestSend {
+					usage.FullestSend = fill
+				}
+			}
+		}
+		hub.mu.RUnlock()
+	}
+	return usage
+}
+
+// DiagnosticsHandler serves pprof profiles and a runtime summary to callers
+// presenting token as a bearer token. It is meant for a separate port that
+// is not exposed with the public API. Only one CPU profile or trace runs at
+// a time, for at most 30 seconds.
+func (s *Server) DiagnosticsHandler(token string) http.Handler {
+	var profiling sync.Mutex
+	exclusive := func(profile http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if val := r.URL.Query().Get("seconds"); val != "" {
+				seconds, err := strconv.ParseFloat(val, 64)
+				if err != nil || seconds <= 0 || seconds > maxProfileSeconds {
+					writeError(w, errs.Validation("seconds must be positive and at most %d", maxProfileSeconds))
+					return
+				}
+			}
+			if !profiling.TryLock() {
+				writeError(w, errs.Conflict("another profile is running"))
+				return
+			}
+			defer profiling.Unlock()
+			profile(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/profile", exclusive(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/trace", exclusive(pprof.Trace))
+	mux.HandleFunc("/debug/runtime", s.HandleGetRuntime)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			writeError(w, errs.ErrUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// HandleGetRuntime reports goroutines per subsystem, memory, the event
+// queue and broadcast buffer usage
+func (s *Server) HandleGetRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	report := map[string]interface{}{
+		"runtime": diagnostics.ReadRuntime(),
+	}
+	if queue, ok := s.localQueue(); ok {
+		report["queue"] = map[string]int{
+			"length":        queue.Len(),
+			"capacity":      queue.Cap(),
+			"lane_capacity": queue.LaneCap(),
+		}
+	}
+	if s.wsHub != nil {
+		report["broadcast"] = s.wsHub.BufferUsage()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/diagnostics"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDebugToken = "debug-token-0123456789"
+
+func TestDiagnosticsHandler_RequiresTheBearerToken(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, NewWebSocketHub(), nil, nil, sessions.NewRegistry(), nil)
+
+	get := func(handler http.Handler, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	handler := server.DiagnosticsHandler(testDebugToken)
+	assert.Equal(t, http.StatusUnauthorized, get(handler, ""))
+	assert.Equal(t, http.StatusUnauthorized, get(handler, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, get(handler, testDebugToken), "the token goes in a bearer header")
+	assert.Equal(t, http.StatusOK, get(handler, "Bearer "+testDebugToken))
+
+	assert.Equal(t, http.StatusUnauthorized, get(server.DiagnosticsHandler(""), "Bearer "), "an unset token never authenticates")
+}
+
+func TestDiagnosticsHandler_BoundsProfiles(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	handler := server.DiagnosticsHandler(testDebugToken)
+
+	for _, seconds := range []string{"0", "31", "forever"} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/profile?seconds="+seconds, nil)
+		req.Header.Set("Authorization", "Bearer "+testDebugToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "seconds=%s", seconds)
+	}
+}
+
+func TestHandleGetRuntime_ReportsGoroutinesAndBuffers(t *testing.T) {
+	hub := NewWebSocketHub()
+	server := NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, sessions.NewRegistry(), nil)
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+	client := &Client{hub: sessionHub, send: make(chan []byte, 4), sessionID: "s1", userID: "u1"}
+	client.send <- []byte("queued")
+	sessionHub.register <- client
+
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	diagnostics.Label("test-subsystem", func() {
+		go func() {
+			defer close(done)
+			close(started)
+			<-release
+		}()
+	})
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	var report struct {
+		Runtime   diagnostics.Runtime `json:"runtime"`
+		Broadcast BufferUsage         `json:"broadcast"`
+	}
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		server.HandleGetRuntime(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		return report.Broadcast.Clients == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, report.Runtime.GoroutinesBySubsystem["test-subsystem"])
+	assert.GreaterOrEqual(t, report.Runtime.GoroutinesBySubsystem["broadcast"], 1, "session hub loops")
+	assert.Positive(t, report.Runtime.GoroutinesBySubsystem[diagnostics.Unlabeled])
+	assert.Equal(t, 1, report.Broadcast.Sessions)
+	assert.Equal(t, 1, report.Broadcast.SendQueued)
+	assert.Equal(t, 4, report.Broadcast.SendCapacity)
+	assert.Equal(t, 0.25, report.Broadcast.FullestSend)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// ErrorResponse is the body of a failed request. Code is stable for clients
+// to branch on; Message is for people.
+type ErrorResponse struct {
+	Code    errs.Code `json:"code"`
+	Message string    `json:"message"`
+}
+
+// errorStatus maps error codes to HTTP statuses
+var errorStatus = map[errs.Code]int{
+	errs.CodeSessionEnded:    http.StatusConflict,
+	errs.CodeQueueFull:       http.StatusServiceUnavailable,
+	errs.CodeRateLimited:     http.StatusTooManyRequests,
+	errs.CodeUnauthorized:    http.StatusUnauthorized,
+	errs.CodeValidation:      http.StatusBadRequest,
+	errs.CodeUnavailable:     http.StatusServiceUnavailable,
+	errs.CodeContentRejected: http.StatusUnprocessableEntity,
+	errs.CodeBanned:          http.StatusForbidden,
+	errs.CodeNotFound:        http.StatusNotFound,
+	errs.CodeConflict:        http.StatusConflict,
+	errs.CodeForbidden:       http.StatusForbidden,
+	errs.CodeBadMethod:       http.StatusMethodNotAllowed,
+	errs.CodeTimeout:         http.StatusGatewayTimeout,
+	errs.CodeUpgradeRequired: http.StatusUpgradeRequired,
+}
+
+// newErrorResponse builds the body for err. Errors outside the taxonomy are
+// reported without their message, which may carry internal detail.
+func newErrorResponse(err error) (ErrorResponse, int) {
+	code := errs.CodeOf(err)
+	status, known := errorStatus[code]
+	if !known {
+		return ErrorResponse{Code: errs.CodeInternal, Message: "Internal error"}, http.StatusInternalServerError
+	}
+	return ErrorResponse{Code: code, Message: err.Error()}, status
+}
+
+// writeError sends err as a JSON ErrorResponse with the status for its code
+func writeError(w http.ResponseWriter, err error) {
+	resp, status := newErrorResponse(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
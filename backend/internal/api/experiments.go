@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/experiments"
+)
+
+// SetExperimentManager enables A/B experiments on engagement features
+func (s *Server) SetExperimentManager(manager *experiments.Manager) {
+	s.experiments = manager
+}
+
+// HandleExperiments lists (GET), creates (POST) and stops
+// (DELETE ?experiment_id=) experiments
+func (s *Server) HandleExperiments(w http.ResponseWriter, r *http.Request) {
+	if s.experiments == nil {
+		writeError(w, errs.NotFound("experiments are not enabled"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"experiments": s.experiments.List(),
+		})
+
+	case http.MethodPost:
+		var experiment experiments.Experiment
+		if err := json.NewDecoder(r.Body).Decode(&experiment); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		created, err := s.experiments.Create(experiment)
+		if err != nil {
+			writeError(w, errs.Validation("invalid experiment: %v", err))
+			return
+		}
+		s.recordAction(r, ActionExperimentCreate, created.ID, "", created)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+
+	case http.MethodDelete:
+		experimentID := r.URL.Query().Get("experiment_id")
+		if experimentID == "" {
+			writeError(w, errs.Validation("experiment_id is required"))
+			return
+		}
+		if !s.experiments.Delete(experimentID) {
+			writeError(w, errs.NotFound("experiment not found"))
+			return
+		}
+		s.recordAction(r, ActionExperimentDelete, experimentID, "", nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
+
+// HandleGetExperimentResults returns per-variant engagement for an experiment
+func (s *Server) HandleGetExperimentResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.experiments == nil {
+		writeError(w, errs.NotFound("experiments are not enabled"))
+		return
+	}
+
+	experimentID := r.URL.Query().Get("experiment_id")
+	if experimentID == "" {
+		writeError(w, errs.Validation("experiment_id is required"))
+		return
+	}
+	results, exists := s.experiments.Results(experimentID)
+	if !exists {
+		writeError(w, errs.NotFound("experiment not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// HandleGetExperimentAssignments tells the signed-in user which variant of
+// each experiment in the session to render
+func (s *Server) HandleGetExperimentAssignments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+
+	assignments := map[string]string{}
+	if s.experiments != nil {
+		assignments = s.experiments.Assignments(sessionID, userID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id":  sessionID,
+		"assignments": assignments,
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// exportBuffer is how many live entries an export subscriber may fall
+// behind before it is disconnected
+const exportBuffer = 1024
+
+// SetEventFeed enables the processed event export stream
+func (s *Server) SetEventFeed(feed *events.Feed) {
+	s.feed = feed
+}
+
+// HandleStreamSessionEvents streams a session's processed events as NDJSON,
+// one FeedEntry per line, optionally replaying retained events from ?from=
+func (s *Server) HandleStreamSessionEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.feed == nil {
+		writeError(w, errs.NotFound("event export is not enabled"))
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	from := int64(-1)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		offset, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || offset < 0 {
+			writeError(w, errs.Validation("from must be a non-negative offset"))
+			return
+		}
+		from = offset
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	backlog, entries, cancel := s.feed.Subscribe(sessionID, from, exportBuffer)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range backlog {
+		if err := encoder.Encode(entry); err != nil {
+			return
+		}
+	}
+	controller.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-entries:
+			if !ok {
+				log.Printf("Event export for session %s closed", sessionID)
+				return
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return
+			}
+			controller.Flush()
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// FeaturesUpdatedMessage tells clients a session's features changed mid-show
+// so they can show or hide polls, chat and the leaderboard
+type FeaturesUpdatedMessage struct {
+	Type      string            `json:"type"`
+	SessionID string            `json:"session_id"`
+	Features  sessions.Features `json:"features"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// channelAllowed reports whether a session's features let broadcasts on the
+// given channel through. Unknown sessions use the default features.
+func (s *Server) channelAllowed(sessionID, channel string) bool {
+	session, _ := s.registry.Get(sessionID)
+	switch channel {
+	case ChannelPolls:
+		return !session.Features.PollsDisabled
+	case ChannelChat:
+		return !session.Features.ChatDisabled
+	}
+	return true
+}
+
+// sessionFeatures returns the current features of a session
+func (s *Server) sessionFeatures(sessionID string) sessions.Features {
+	session, _ := s.registry.Get(sessionID)
+	return session.Features
+}
+
+// HandleSessionFeatures reads (GET) or changes (POST) the features of the
+// session given by ?session_id=. A POST body sets only the fields it names.
+func (s *Server) HandleSessionFeatures(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session, exists := s.registry.Get(sessionID)
+		if !exists {
+			writeError(w, errs.NotFound("session not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id": sessionID,
+			"features":   session.Features,
+		})
+
+	case http.MethodPost:
+		var update sessions.FeaturesUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		session, ok := s.registry.SetFeatures(sessionID, update)
+		if !ok {
+			writeError(w, errs.NotFound("session not found or already ended"))
+			return
+		}
+		s.recordAction(r, ActionSessionFeatures, sessionID, "", session.Features)
+		s.wsHub.BroadcastToSession(sessionID, FeaturesUpdatedMessage{
+			Type:      MessageTypeFeaturesUpdated,
+			SessionID: sessionID,
+			Features:  session.Features,
+			UpdatedAt: time.Now().UTC(),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id": sessionID,
+			"features":   session.Features,
+		})
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
+
+// HandleGetLeaderboard returns a session's top reactors when the session
+// shows its leaderboard
+func (s *Server) HandleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	if !s.sessionFeatures(sessionID).LeaderboardVisible {
+		writeError(w, errs.NotFound("leaderboard is not enabled for this session"))
+		return
+	}
+
+	limit := 10
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	board := []aggregation.LeaderboardEntry{}
+	var bounds *aggregation.ErrorBounds
+	if stats, exists := s.aggManager.GetSession(sessionID); exists {
+		board = stats.GetLeaderboard(limit)
+		bounds = stats.GetErrorBounds()
+	}
+
+	response := map[string]interface{}{
+		"session_id":  sessionID,
+		"leaderboard": board,
+	}
+	if bounds != nil {
+		// Approximate sessions rank estimated counts
+		response["error_bounds"] = bounds
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
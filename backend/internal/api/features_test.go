@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSessionFeatures_UpdatesMidShow(t *testing.T) {
+	registry := sessions.NewRegistry()
+	hub := NewWebSocketHub()
+	server := NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, registry, nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1", Features: sessions.Features{LeaderboardVisible: true}})
+
+	stream, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/sessions/features?session_id=s1", strings.NewReader(`{"chat_disabled":true}`))
+	rec := httptest.NewRecorder()
+	server.HandleSessionFeatures(rec, asUser(req, "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Fields the update leaves out keep their values
+	session, _ := registry.Get("s1")
+	assert.True(t, session.Features.ChatDisabled)
+	assert.True(t, session.Features.LeaderboardVisible)
+
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionSessionFeatures, actions.actions[0].Action)
+
+	select {
+	case data := <-stream:
+		var msg FeaturesUpdatedMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, MessageTypeFeaturesUpdated, msg.Type)
+		assert.True(t, msg.Features.ChatDisabled)
+	case <-time.After(time.Second):
+		t.Fatal("clients were not told about the change")
+	}
+
+	registry.End("s1")
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/sessions/features?session_id=s1", strings.NewReader(`{"chat_disabled":false}`))
+	server.HandleSessionFeatures(rec, asUser(req, "admin-1"))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "ended sessions cannot change")
+}
+
+func TestWebSocketHub_DropsBroadcastsOnDisabledChannels(t *testing.T) {
+	registry := sessions.NewRegistry()
+	hub := NewWebSocketHub()
+	NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1", Features: sessions.Features{ChatDisabled: true, PollsDisabled: true}})
+
+	stream, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat"})
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "poll_opened"})
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "stats_update"})
+
+	select {
+	case data := <-stream:
+		assert.Contains(t, string(data), `"stats_update"`, "chat and polls are dropped")
+	case <-time.After(time.Second):
+		t.Fatal("stats update was not delivered")
+	}
+}
+
+func TestHandleGetLeaderboard_RequiresVisibleLeaderboard(t *testing.T) {
+	registry := sessions.NewRegistry()
+	aggManager := aggregation.NewManager()
+	server := NewServer(nil, aggManager, nil, nil, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	aggManager.ProcessEvent(events.ReactionEvent("s1", "alice", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	server.HandleGetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/leaderboard?session_id=s1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	visible := true
+	registry.SetFeatures("s1", sessions.FeaturesUpdate{LeaderboardVisible: &visible})
+	rec = httptest.NewRecorder()
+	server.HandleGetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/leaderboard?session_id=s1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Leaderboard []aggregation.LeaderboardEntry `json:"leaderboard"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Leaderboard, 1)
+	assert.Equal(t, "alice", resp.Leaderboard[0].UserID)
+}
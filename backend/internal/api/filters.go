@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/filters"
+)
+
+// SetFilterEngine enables runtime management of ingestion filter rules
+func (s *Server) SetFilterEngine(engine *filters.Engine) {
+	s.filters = engine
+}
+
+// HandleFilterRules lists (GET), adds or replaces (POST) and deletes
+// (DELETE ?rule_id=) ingestion filter rules
+func (s *Server) HandleFilterRules(w http.ResponseWriter, r *http.Request) {
+	if s.filters == nil {
+		writeError(w, errs.NotFound("filters are not enabled"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules": s.filters.List(),
+		})
+
+	case http.MethodPost:
+		var rule filters.Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		if err := s.filters.Put(rule); err != nil {
+			writeError(w, errs.Validation("invalid rule: %v", err))
+			return
+		}
+		s.recordAction(r, ActionFilterPut, rule.ID, "", rule)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rule)
+
+	case http.MethodDelete:
+		ruleID := r.URL.Query().Get("rule_id")
+		if ruleID == "" {
+			writeError(w, errs.Validation("rule_id is required"))
+			return
+		}
+		if !s.filters.Delete(ruleID) {
+			writeError(w, errs.NotFound("rule not found"))
+			return
+		}
+		s.recordAction(r, ActionFilterDelete, ruleID, "", nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
@@ -2,91 +2,297 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/audit"
+	"github.com/jrudman25/livepulse/internal/cluster"
+	"github.com/jrudman25/livepulse/internal/errs"
 	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/experiments"
+	"github.com/jrudman25/livepulse/internal/filters"
+	"github.com/jrudman25/livepulse/internal/fraud"
 	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/sessions"
 	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/jrudman25/livepulse/internal/tokens"
+	"github.com/jrudman25/livepulse/internal/waves"
 )
 
 // Server holds the API server dependencies
 type Server struct {
-	eventQueue *events.Queue
-	aggManager *aggregation.Manager
-	tracker    *milestones.Tracker
-	wsHub      *WebSocketHub
-	db         *storage.PostgresClient
-	apiFetcher *events.APIFetcher
+	eventQueue    events.Transport
+	aggManager    *aggregation.Manager
+	tracker       *milestones.Tracker
+	wsHub         *WebSocketHub
+	db            *storage.PostgresClient
+	apiFetcher    *events.APIFetcher
+	registry      *sessions.Registry
+	notifier      *notifications.WebhookNotifier
+	closeGrace    time.Duration
+	campaigns     *milestones.CampaignTracker
+	auditor       *audit.Auditor
+	reconciler    *audit.Reconciler
+	filters       *filters.Engine
+	experiments   *experiments.Manager
+	feed          *events.Feed
+	workers       *events.WorkerPool
+	hubRing       *cluster.Ring
+	actions       ActionLog
+	pushSubs      PushSubscriptions
+	push          *notifications.PushNotifier
+	caps          *sessions.ReactionCaps
+	recomputes    *recomputeJobs
+	sources       *fraud.SourceTracker
+	statsCache    *snapshotCache
+	tenantDBs     *storage.TenantDatabases
+	overlay       *overlayConfig
+	viewerTokens  *tokens.Service
+	flagged       FlagQueue
+	fraudGuard    *fraud.Guard
+	waves         *waves.Manager
+	boostEnds     *boostTimers
+	minVersion    clientVersionPolicy
+	systemSources map[string]bool // integrations allowed to emit reactions
+	jsonNaming    schema.Naming
+	purgeAfter    time.Duration // how long deleted sessions are kept before they are purged
+
+	dashboardInterval time.Duration // zero while the dashboard is disabled
 }
 
 // NewServer creates a new API server
 func NewServer(
-	eventQueue *events.Queue,
+	eventQueue events.Transport,
 	aggManager *aggregation.Manager,
 	tracker *milestones.Tracker,
 	wsHub *WebSocketHub,
 	db *storage.PostgresClient,
 	apiFetcher *events.APIFetcher,
+	registry *sessions.Registry,
+	notifier *notifications.WebhookNotifier,
 ) *Server {
-	return &Server{
+	s := &Server{
 		eventQueue: eventQueue,
 		aggManager: aggManager,
 		tracker:    tracker,
 		wsHub:      wsHub,
 		db:         db,
 		apiFetcher: apiFetcher,
+		registry:   registry,
+		notifier:   notifier,
+		recomputes: newRecomputeJobs(),
+		boostEnds:  newBoostTimers(),
 	}
+	if wsHub != nil && registry != nil {
+		wsHub.SetChannelGate(s.channelAllowed)
+		wsHub.SetAliasResolver(registry.Channels)
+	}
+	return s
 }
 
 // CreateSessionRequest represents the request to create a session
 type CreateSessionRequest struct {
-	Name       string `json:"name"`
-	Milestones []int  `json:"milestones,omitempty"`
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// Optional caller-chosen ID, scoped to the tenant when one is given
+	SessionID string `json:"session_id,omitempty"`
+
+	Milestones []int `json:"milestones,omitempty"`
+
+	// Detailed milestone definitions, including presentation metadata
+	MilestoneDefinitions []milestones.Definition `json:"milestone_definitions,omitempty"`
+
+	// Optional broadcast pacing bounds in milliseconds
+	BroadcastMinIntervalMs int `json:"broadcast_min_interval_ms,omitempty"`
+	BroadcastMaxIntervalMs int `json:"broadcast_max_interval_ms,omitempty"`
+
+	// Optional reaction caps; once used up, reactions are rejected as sold out
+	ReactionCap     int64 `json:"reaction_cap,omitempty"`
+	UserReactionCap int64 `json:"user_reaction_cap,omitempty"`
+
+	// Optional features; omitted ones keep their defaults
+	Features sessions.Features `json:"features"`
+
+	// Optional campaign whose milestones this session contributes to
+	CampaignID string `json:"campaign_id,omitempty"`
+
+	// Optional tags grouping the session with others, e.g. "series:nba"
+	Tags []string `json:"tags,omitempty"`
+
+	// Optional IDs the session is simulcast under, scoped to its tenant.
+	// Events sent to any of them count toward this session's stats.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Optional accuracy mode; "approximate" bounds memory for very large
+	// audiences at the cost of reported error. Defaults to exact.
+	Accuracy aggregation.Accuracy `json:"accuracy,omitempty"`
+
+	// Optional webhooks receiving this session's notifications only, each
+	// signed with its own secret
+	Webhooks []SessionWebhookRequest `json:"webhooks,omitempty"`
 }
 
 // CreateSessionResponse represents the response when creating a session
 type CreateSessionResponse struct {
-	SessionID string `json:"session_id"`
-	Name      string `json:"name"`
-	CreatedAt string `json:"created_at"`
+	SessionID string   `json:"session_id"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	CreatedAt string   `json:"created_at"`
+
+	Webhooks []RegisteredSessionWebhook `json:"webhooks,omitempty"` // secrets are not shown again
 }
 
 // HandleCreateSession creates a new session
 func (s *Server) HandleCreateSession(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
 	var req CreateSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, errs.Validation("invalid request body"))
 		return
 	}
 
 	if req.Name == "" {
 		req.Name = "Untitled Event"
 	}
+	for _, definition := range req.MilestoneDefinitions {
+		if err := definition.Validate(); err != nil {
+			writeError(w, errs.Validation("invalid milestone definition: %v", err))
+			return
+		}
+	}
 
-	// Generate session ID
-	sessionID := uuid.New().String()
+	if req.ReactionCap < 0 || req.UserReactionCap < 0 {
+		writeError(w, errs.Validation("reaction caps must not be negative"))
+		return
+	}
+	if req.Accuracy != "" && !req.Accuracy.IsValid() {
+		writeError(w, errs.Validation("accuracy must be exact or approximate"))
+		return
+	}
+	if req.TenantID != "" {
+		if err := sessions.ValidateTenantID(req.TenantID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	tags, err := sessions.NormalizeTags(req.Tags)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	hooks, err := s.sessionWebhooksFrom(req.Webhooks)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if strings.Contains(req.SessionID, sessions.NamespaceSeparator) {
+		writeError(w, errs.Validation("session_id must not contain a tenant prefix; set tenant_id instead"))
+		return
+	}
+
+	// Generate session ID unless the caller chose one, namespaced by tenant
+	localID := req.SessionID
+	if localID == "" {
+		localID = uuid.New().String()
+	}
+	sessionID := sessions.NamespacedID(req.TenantID, localID)
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, exists := s.registry.Get(sessionID); exists {
+		writeError(w, errs.Conflict("session already exists"))
+		return
+	}
+	aliases, err := sessions.AliasIDs(sessionID, req.Aliases)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.registry.CheckAliases(sessionID, aliases); err != nil {
+		writeError(w, err)
+		return
+	}
 
 	// Initialize milestones
 	if len(req.Milestones) > 0 {
 		s.tracker.InitializeSession(sessionID, req.Milestones)
 	}
+	if len(req.MilestoneDefinitions) > 0 {
+		if err := s.tracker.AddMilestones(sessionID, req.MilestoneDefinitions); err != nil {
+			s.tracker.RemoveSession(sessionID)
+			writeError(w, errs.Validation("invalid milestone definition: %v", err))
+			return
+		}
+	}
+
+	if req.CampaignID != "" {
+		if s.campaigns == nil || s.campaigns.AddSession(req.CampaignID, sessionID) != nil {
+			s.tracker.RemoveSession(sessionID)
+			writeError(w, errs.Validation("campaign not found"))
+			return
+		}
+	}
+
+	// A concurrent create of the same ID may still win between Get and here
+	session, created := s.registry.CreateIfAbsent(sessions.Session{
+		ID:                   sessionID,
+		Name:                 req.Name,
+		TenantID:             req.TenantID,
+		BroadcastMinInterval: time.Duration(req.BroadcastMinIntervalMs) * time.Millisecond,
+		BroadcastMaxInterval: time.Duration(req.BroadcastMaxIntervalMs) * time.Millisecond,
+		ReactionCap:          req.ReactionCap,
+		UserReactionCap:      req.UserReactionCap,
+		Features:             req.Features,
+		Tags:                 tags,
+	})
+	if !created {
+		writeError(w, errs.Conflict("session already exists"))
+		return
+	}
+	if len(aliases) > 0 {
+		if _, err := s.registry.SetAliases(sessionID, aliases); err != nil {
+			// Another session claimed an alias since it was checked
+			log.Printf("Session %s created without its aliases: %v", sessionID, err)
+			aliases = nil
+		}
+	}
+
+	// Registered before session.created goes out, so the session's own
+	// webhooks receive it too
+	webhooks, err := s.registerSessionWebhooks(r.Context(), sessionID, hooks)
+	if err != nil {
+		log.Printf("Session %s created without its webhooks: %v", sessionID, err)
+	}
 
 	// Initialize aggregation
-	s.aggManager.GetOrCreateSession(sessionID)
+	s.aggManager.GetOrCreateSession(sessionID).SetAccuracy(req.Accuracy)
+	s.notifier.Notify(notifications.Event{
+		Type:       notifications.TypeSessionCreated,
+		SessionID:  sessionID,
+		OccurredAt: session.CreatedAt,
+		Data:       session,
+	})
 
 	response := CreateSessionResponse{
 		SessionID: sessionID,
 		Name:      req.Name,
+		Aliases:   aliases,
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Webhooks:  webhooks,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -96,7 +302,7 @@ func (s *Server) HandleCreateSession(w http.ResponseWriter, r *http.Request) {
 // HandleJoinSession allows a user to join a session
 func (s *Server) HandleJoinSession(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
@@ -104,16 +310,42 @@ func (s *Server) HandleJoinSession(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 
 	if sessionID == "" || userID == "" {
-		http.Error(w, "session_id and user_id are required", http.StatusBadRequest)
+		writeError(w, errs.Validation("session_id and user_id are required"))
+		return
+	}
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Create join event
-	event := events.JoinSessionEvent(sessionID, userID)
+	if !s.registry.AcceptsJoins(sessionID) {
+		writeError(w, errs.ErrSessionEnded)
+		return
+	}
+	if s.registry.IsBanned(sessionID, userID) {
+		writeError(w, errs.ErrBanned)
+		return
+	}
+	clientVersion := r.URL.Query().Get("client_version")
+	if rejection := s.minVersion.check(clientVersion); rejection != nil {
+		writeUpgradeRequired(w, rejection)
+		return
+	}
+
+	// Create join event, tagged with the caller's audience cohort if supplied
+	event := events.CohortJoinSessionEvent(sessionID, userID, r.URL.Query().Get("cohort"))
+	event.SetClientVersion(clientVersion)
+	event.SourceIP = clientIP(r)
+	if eventID := r.URL.Query().Get("event_id"); eventID != "" {
+		if err := event.SetExternalID(eventID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
 
 	// Enqueue event
-	if !s.eventQueue.Enqueue(event) {
-		http.Error(w, "Failed to enqueue event", http.StatusServiceUnavailable)
+	if err := s.eventQueue.Enqueue(r.Context(), event); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -125,16 +357,24 @@ func (s *Server) HandleJoinSession(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HandleGetStats returns current statistics for a session
+// HandleGetStats returns current statistics for a session. With
+// ?sections= it returns only the named sections, paging through the
+// breakdowns with ?cursor= and ?limit=.
 func (s *Server) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
 	sessionID := r.URL.Query().Get("session_id")
 	if sessionID == "" {
-		http.Error(w, "session_id is required", http.StatusBadRequest)
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+
+	naming, err := s.responseNaming(r)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -145,31 +385,206 @@ func (s *Server) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	snapshot := stats.GetSnapshot()
+	// Let polling clients skip identical payloads
+	etag := snapshotETag(stats)
+	if naming != schema.NamingSnake {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + string(naming) + `"`
+	}
+	lastActivity := stats.GetLastActivity()
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastActivity.Format(http.TimeFormat))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if since := r.URL.Query().Get("changed_since"); since != "" {
+		sinceTime, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			writeError(w, errs.Validation("changed_since must be an RFC3339 timestamp"))
+			return
+		}
+		if !lastActivity.After(sinceTime) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if r.URL.Query().Has("sections") || r.URL.Query().Has("cursor") {
+		s.writeSnapshotSections(w, r, naming, stats)
+		return
+	}
 
+	// The cache holds one body per session, in the default naming
+	if s.statsCache == nil || naming != s.defaultNaming() {
+		writeVersioned(w, naming, schema.SnapshotV1From(stats.GetSnapshot()))
+		return
+	}
+	body, cached := s.statsCache.get(sessionID, etag)
+	if !cached {
+		body, _ = schema.Marshal(schema.SnapshotV1From(stats.GetSnapshot()), naming)
+		body = append(body, '\n')
+		s.statsCache.put(sessionID, etag, body)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(snapshot)
+	w.Header().Set(schemaHeader, schema.V1)
+	w.Write(body)
+}
+
+// snapshotETag builds a weak validator for a session's statistics. The
+// snapshot's duration changes on every read, so the ETag tracks the mutation
+// version instead of hashing the payload.
+func snapshotETag(stats *aggregation.SessionStats) string {
+	return fmt.Sprintf(`W/"%d-%d"`, stats.StartTime.UnixNano(), stats.Version())
 }
 
-// HandleGetMilestones returns milestone progress for a session
+// etagMatches reports whether an If-None-Match header matches the ETag
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleGetMilestones returns milestone progress for a session, with a
+// forecast of when each open milestone will be achieved. Pass ?ends_at= (RFC
+// 3339) to learn whether each is on track to land before the session ends.
 func (s *Server) HandleGetMilestones(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	var deadline time.Time
+	if val := r.URL.Query().Get("ends_at"); val != "" {
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			writeError(w, errs.Validation("ends_at must be an RFC 3339 timestamp"))
+			return
+		}
+		deadline = parsed
+	}
+	naming, err := s.responseNaming(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	now := time.Now()
+	milestoneList := make([]schema.MilestoneV1, 0)
+	for _, milestone := range s.tracker.GetSessionMilestones(sessionID) {
+		milestoneList = append(milestoneList, schema.MilestoneV1From(milestone, milestone.Forecast(now, deadline)))
+	}
+
+	writeVersioned(w, naming, struct {
+		SessionID  string               `json:"session_id"`
+		Milestones []schema.MilestoneV1 `json:"milestones"`
+	}{sessionID, milestoneList})
+}
+
+// HandleGetSessionUsers returns a paginated roster of the users currently in a session
+func (s *Server) HandleGetSessionUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
 	sessionID := r.URL.Query().Get("session_id")
 	if sessionID == "" {
-		http.Error(w, "session_id is required", http.StatusBadRequest)
+		writeError(w, errs.Validation("session_id is required"))
 		return
 	}
 
-	milestoneList := s.tracker.GetSessionMilestones(sessionID)
+	offset := 0
+	if val, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && val > 0 {
+		offset = val
+	}
+	limit := 50
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	roster := []aggregation.RosterEntry{}
+	total := 0
+	if stats, exists := s.aggManager.GetSession(sessionID); exists {
+		roster, total = stats.GetRoster(offset, limit)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"session_id": sessionID,
-		"milestones": milestoneList,
+		"total":      total,
+		"offset":     offset,
+		"limit":      limit,
+		"users":      roster,
+	})
+}
+
+// HandleGetReactionsByMinute returns the per-minute reaction breakdown of a
+// session for engagement-over-time charts
+func (s *Server) HandleGetReactionsByMinute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+
+	stats, exists := s.aggManager.GetSession(sessionID)
+	if !exists {
+		writeError(w, errs.NotFound("session not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"start_time": stats.StartTime,
+		"minutes":    stats.GetReactionsByMinute(),
+	})
+}
+
+// HandleGetRetentionCurve returns the audience drop-off curve of a live
+// session: for each minute since start, how many joiners were still present
+func (s *Server) HandleGetRetentionCurve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+
+	stats, exists := s.aggManager.GetSession(sessionID)
+	if !exists {
+		writeError(w, errs.NotFound("session not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"start_time": stats.StartTime,
+		"minutes":    stats.GetRetentionCurve(),
 	})
 }
 
@@ -182,10 +597,224 @@ func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleGetMemoryUsage reports approximate per-session memory for operators
+func (s *Server) HandleGetMemoryUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	usage := s.aggManager.GetMemoryUsage()
+	totalBytes := 0
+	compacted := 0
+	for _, u := range usage {
+		totalBytes += u.ApproxBytes
+		if u.Compacted {
+			compacted++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_count":      len(usage),
+		"compacted_sessions": compacted,
+		"total_approx_bytes": totalBytes,
+		"sessions":           usage,
+	})
+}
+
+// localQueue returns the in-process event queue, or false when events
+// travel over a shared stream instead
+func (s *Server) localQueue() (*events.Queue, bool) {
+	queue, ok := s.eventQueue.(*events.Queue)
+	return queue, ok
+}
+
+// HandleGetQueueLag reports how long events wait in the queue before the
+// aggregation workers pick them up, and how many were promoted past higher
+// priority events after aging
+func (s *Server) HandleGetQueueLag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	queue, ok := s.localQueue()
+	if !ok {
+		writeError(w, errs.NotFound("event queue is not in-process"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"queue_length":    queue.Len(),
+		"queue_capacity":  queue.Cap(),
+		"lane_capacity":   queue.LaneCap(),
+		"age_at_dequeue":  queue.AgeStats(),
+		"promoted_events": queue.Promoted(),
+	})
+}
+
+// SetWorkerPool enables per-session worker load reporting
+func (s *Server) SetWorkerPool(pool *events.WorkerPool) {
+	s.workers = pool
+}
+
+// HandleGetSessionLoads reports how each recently active session uses the
+// workers, slowest first, to find sessions stalling the pipeline
+func (s *Server) HandleGetSessionLoads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.workers == nil {
+		writeError(w, errs.NotFound("no workers run on this instance"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": s.workers.SessionLoads(),
+	})
+}
+
+// ResizeQueueRequest is the new capacity of each event queue lane
+type ResizeQueueRequest struct {
+	Size int `json:"size"`
+}
+
+// HandleResizeQueue grows or shrinks the event queue lanes without
+// dropping queued events
+func (s *Server) HandleResizeQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	queue, ok := s.localQueue()
+	if !ok {
+		writeError(w, errs.NotFound("event queue is not in-process"))
+		return
+	}
+
+	var req ResizeQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	previous, previousLane := queue.Cap(), queue.LaneCap()
+	if err := queue.Resize(req.Size); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.recordAction(r, ActionQueueResize, "event_queue", "", map[string]int{"from": previousLane, "to": req.Size})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"previous_capacity": previous,
+		"queue_capacity":    queue.Cap(),
+		"lane_capacity":     queue.LaneCap(),
+		"queue_length":      queue.Len(),
+	})
+}
+
+// SetAuditor enables the aggregation audit report
+func (s *Server) SetAuditor(auditor *audit.Auditor) {
+	s.auditor = auditor
+}
+
+// HandleGetAuditStats reports aggregation audit results for operators
+func (s *Server) HandleGetAuditStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.auditor == nil {
+		writeError(w, errs.NotFound("audit mode is not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.auditor.Stats())
+}
+
+// SetReconciler enables the archive reconciliation report
+func (s *Server) SetReconciler(reconciler *audit.Reconciler) {
+	s.reconciler = reconciler
+}
+
+// HandleGetReconciliation reports discrepancies found between archived
+// session totals and the raw event log
+func (s *Server) HandleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.reconciler == nil {
+		writeError(w, errs.NotFound("archive reconciliation is not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.reconciler.Stats())
+}
+
+// HandleGetMilestonePersistence reports milestone writes and the version
+// conflicts they hit
+func (s *Server) HandleGetMilestonePersistence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.tracker == nil || !s.tracker.Persisting() {
+		writeError(w, errs.NotFound("milestone persistence is not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.tracker.PersistStats())
+}
+
+// SetSourceTracker enables the top event sources report
+func (s *Server) SetSourceTracker(sources *fraud.SourceTracker) {
+	s.sources = sources
+}
+
+// HandleGetTopSources reports a session's approximate heaviest event sources
+// by client and by hashed IP, up to ?limit= of each, for abuse triage
+func (s *Server) HandleGetTopSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.sources == nil {
+		writeError(w, errs.NotFound("source tracking is not enabled"))
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	limit := 10
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+
+	report, exists := s.sources.Top(sessionID, limit)
+	if !exists {
+		writeError(w, errs.NotFound("no events recorded for session"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // HandleGetLiveEvents surfaces Postgres events to the Next.js frontend
 func (s *Server) HandleGetLiveEvents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
@@ -205,7 +834,7 @@ func (s *Server) HandleGetLiveEvents(w http.ResponseWriter, r *http.Request) {
 
 	eventsData, err := s.db.GetUpcomingEvents(r.Context(), 200, offset, q)
 	if err != nil {
-		http.Error(w, "Failed to retrieve events", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -230,17 +859,17 @@ func (s *Server) HandleGetLiveEvents(w http.ResponseWriter, r *http.Request) {
 // HandleGetEvent surfaces a single event by ID securely
 func (s *Server) HandleGetEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		http.Error(w, "id required", http.StatusBadRequest)
+		writeError(w, errs.Validation("id required"))
 		return
 	}
 	event, err := s.db.GetEvent(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		writeError(w, errs.NotFound("event not found"))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -255,13 +884,13 @@ type FavoriteRequest struct {
 // HandleToggleFavorite toggles an event favorite natively on Postgres
 func (s *Server) HandleToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodDelete && r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, errs.ErrBadMethod)
 		return
 	}
 
 	userIDVal := r.Context().Value("user_id")
 	if userIDVal == nil {
-		http.Error(w, "Unauthorized context", http.StatusUnauthorized)
+		writeError(w, errs.ErrUnauthorized)
 		return
 	}
 	userID := userIDVal.(string)
@@ -269,7 +898,7 @@ func (s *Server) HandleToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		favorites, err := s.db.GetUserFavorites(r.Context(), userID)
 		if err != nil {
-			http.Error(w, "Failed to fetch favorites", http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -279,18 +908,18 @@ func (s *Server) HandleToggleFavorite(w http.ResponseWriter, r *http.Request) {
 
 	var req FavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.EventID == "" {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, errs.Validation("invalid request body"))
 		return
 	}
 
 	if r.Method == http.MethodPost {
 		if err := s.db.AddFavorite(r.Context(), userID, req.EventID); err != nil {
-			http.Error(w, "Failed to add favorite", http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 	} else if r.Method == http.MethodDelete {
 		if err := s.db.RemoveFavorite(r.Context(), userID, req.EventID); err != nil {
-			http.Error(w, "Failed to remove favorite", http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 	}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStatsTestServer() (*Server, *aggregation.Manager) {
+	manager := aggregation.NewManager()
+	return NewServer(nil, manager, nil, nil, nil, nil, sessions.NewRegistry(), nil), manager
+}
+
+func TestHandleGetStats_ReturnsNotModifiedForMatchingETag(t *testing.T) {
+	server, manager := newStatsTestServer()
+	manager.ProcessEvent(events.JoinSessionEvent("session-1", "user-1"))
+
+	rec := httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// Any change to the stats invalidates the ETag
+	manager.ProcessEvent(events.ReactionEvent("session-1", "user-1", events.ReactionFire))
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestHandleGetStats_ChangedSince(t *testing.T) {
+	server, manager := newStatsTestServer()
+	manager.ProcessEvent(events.JoinSessionEvent("session-1", "user-1"))
+
+	future := time.Now().Add(time.Minute).UTC().Format(time.RFC3339Nano)
+	rec := httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1&changed_since="+future, nil))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339Nano)
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1&changed_since="+past, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1&changed_since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleCreateSession_NamespacesAndRejectsCollisions(t *testing.T) {
+	server, _ := newStatsTestServer()
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := create(`{"name":"Keynote","tenant_id":"acme","session_id":"keynote"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"session_id":"acme:keynote"`)
+
+	// Another tenant may reuse the local ID without touching acme's stats
+	assert.Equal(t, http.StatusOK, create(`{"tenant_id":"globex","session_id":"keynote"}`).Code)
+	assert.Equal(t, http.StatusConflict, create(`{"tenant_id":"acme","session_id":"keynote"}`).Code)
+
+	assert.Equal(t, http.StatusBadRequest, create(`{"session_id":"acme:keynote"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"session_id":"_internal"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"tenant_id":"system","session_id":"x"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"session_id":"`+strings.Repeat("a", sessions.MaxIDLength+1)+`"}`).Code)
+}
+
+func TestHandleCreateSession_ConfiguresReactionCaps(t *testing.T) {
+	server, _ := newStatsTestServer()
+	rec := httptest.NewRecorder()
+	server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(`{"session_id":"drop","reaction_cap":3,"user_reaction_cap":2}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	session, exists := server.registry.Get("drop")
+	require.True(t, exists)
+	caps := sessions.NewReactionCaps()
+	admit := func(userID string) (sessions.CapVerdict, bool) { return caps.Admit(session, userID) }
+
+	verdict, soldOut := admit("u1")
+	assert.Equal(t, sessions.CapAllow, verdict)
+	assert.False(t, soldOut)
+	admit("u1")
+	verdict, _ = admit("u1")
+	assert.Equal(t, sessions.CapUserSoldOut, verdict, "u1 used up their own cap")
+
+	verdict, soldOut = admit("u2")
+	assert.Equal(t, sessions.CapAllow, verdict)
+	assert.True(t, soldOut, "the third admitted reaction sells the session out")
+	verdict, soldOut = admit("u3")
+	assert.Equal(t, sessions.CapSessionSoldOut, verdict)
+	assert.False(t, soldOut, "the sell-out is reported once")
+	assert.Equal(t, "session_sold_out", NewReactionRejectedMessage(session, "e1", verdict).Code)
+
+	rec = httptest.NewRecorder()
+	server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(`{"reaction_cap":-1}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestReactionCaps_ResumeFromRestoredStats(t *testing.T) {
+	manager := aggregation.NewManager()
+	for _, userID := range []string{"u1", "u1", "u2"} {
+		manager.ProcessEvent(events.ReactionEvent("drop", userID, events.ReactionFire))
+	}
+	session := sessions.Session{ID: "drop", ReactionCap: 4, UserReactionCap: 2}
+
+	// A restarted process counts what the restored stats already hold
+	caps := sessions.NewReactionCaps()
+	caps.SetHistory(manager)
+	verdict, _ := caps.Admit(session, "u1")
+	assert.Equal(t, sessions.CapUserSoldOut, verdict)
+	verdict, soldOut := caps.Admit(session, "u3")
+	assert.Equal(t, sessions.CapAllow, verdict)
+	assert.True(t, soldOut, "the fourth reaction overall sells the session out")
+	verdict, soldOut = caps.Admit(session, "u2")
+	assert.Equal(t, sessions.CapSessionSoldOut, verdict)
+	assert.False(t, soldOut)
+}
+
+func TestHandleCreateSession_SelectsAccuracy(t *testing.T) {
+	server, manager := newStatsTestServer()
+	create := func(body string) int {
+		rec := httptest.NewRecorder()
+		server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, create(`{"session_id":"stadium","accuracy":"approximate"}`))
+	require.Equal(t, http.StatusOK, create(`{"session_id":"club"}`))
+	assert.Equal(t, http.StatusBadRequest, create(`{"session_id":"arena","accuracy":"roughly"}`))
+
+	stadium, _ := manager.GetSession("stadium")
+	assert.Equal(t, aggregation.AccuracyApproximate, stadium.GetSnapshot().Accuracy)
+	assert.NotNil(t, stadium.GetErrorBounds())
+	club, _ := manager.GetSession("club")
+	assert.Equal(t, aggregation.AccuracyExact, club.GetSnapshot().Accuracy)
+	assert.Nil(t, club.GetErrorBounds())
+}
+
+func TestHandleGetStats_ServesCachedSnapshotUntilStatsChange(t *testing.T) {
+	server, manager := newStatsTestServer()
+	server.SetStatsCache(2, time.Minute)
+	manager.ProcessEvent(events.JoinSessionEvent("session-1", "user-1"))
+
+	get := func(sessionID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id="+sessionID, nil))
+		return rec
+	}
+	first := get("session-1")
+	second := get("session-1")
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, SnapshotCacheStats{Entries: 1, Capacity: 2, Hits: 1, Misses: 1, HitRate: 0.5}, server.statsCache.stats())
+
+	manager.ProcessEvent(events.ReactionEvent("session-1", "user-1", events.ReactionFire))
+	var snapshot aggregation.StatsSnapshot
+	require.NoError(t, json.NewDecoder(get("session-1").Body).Decode(&snapshot))
+	assert.Equal(t, int64(1), snapshot.TotalReactions, "a change must invalidate the cached snapshot")
+
+	// The least recently read session is evicted once the cache is full
+	manager.ProcessEvent(events.JoinSessionEvent("session-2", "user-1"))
+	manager.ProcessEvent(events.JoinSessionEvent("session-3", "user-1"))
+	get("session-2")
+	get("session-3")
+	stats := server.statsCache.stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(1), stats.Evictions)
+}
+
+func TestHandleJoinSession_ReturnsErrorCodes(t *testing.T) {
+	queue := events.NewQueue(1)
+	registry := sessions.NewRegistry()
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	registry.Create(sessions.Session{ID: "ended"})
+	registry.End("ended")
+
+	join := func(query string) (int, ErrorResponse) {
+		rec := httptest.NewRecorder()
+		server.HandleJoinSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/join?"+query, nil))
+		var resp ErrorResponse
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		}
+		return rec.Code, resp
+	}
+
+	status, resp := join("session_id=s1")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, errs.CodeValidation, resp.Code)
+
+	status, resp = join("session_id=ended&user_id=u1")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, errs.CodeSessionEnded, resp.Code)
+
+	status, _ = join("session_id=s1&user_id=u1")
+	require.Equal(t, http.StatusOK, status)
+	status, resp = join("session_id=s1&user_id=u2")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, errs.CodeQueueFull, resp.Code)
+}
+
+func TestHandlers_ReportTypedErrorCodes(t *testing.T) {
+	server, _ := newStatsTestServer()
+	server.registry.Create(sessions.Session{ID: "s1"})
+
+	call := func(handler http.HandlerFunc, method, target, body string) (int, ErrorResponse) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var resp ErrorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, resp
+	}
+
+	status, resp := call(server.HandleListSessions, http.MethodPost, "/api/admin/sessions", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+	assert.Equal(t, errs.CodeBadMethod, resp.Code)
+
+	status, resp = call(server.HandleCreateSession, http.MethodPost, "/api/sessions", `{"session_id":"s1"}`)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, errs.CodeConflict, resp.Code)
+	assert.Equal(t, "session already exists", resp.Message)
+
+	status, resp = call(server.HandleTailBroadcasts, http.MethodGet, "/api/admin/sessions/broadcasts?session_id=s1", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.CodeNotFound, resp.Code)
+}
+
+func TestHandleGetStats_NegotiatesSchema(t *testing.T) {
+	server, manager := newStatsTestServer()
+	manager.ProcessEvent(events.JoinSessionEvent("session-1", "user-1"))
+
+	rec := httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "v1", rec.Header().Get(schemaHeader))
+	assert.Contains(t, rec.Body.String(), `"active_user_count":1`)
+	snakeETag := rec.Header().Get("ETag")
+
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1&naming=camel", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"activeUserCount":1`)
+	assert.NotEqual(t, snakeETag, rec.Header().Get("ETag"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=session-1", nil)
+	req.Header.Set(schemaHeader, "v2")
+	rec = httptest.NewRecorder()
+	server.HandleGetStats(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
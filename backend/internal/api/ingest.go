@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// Flow control for ingestion streams. A producer may send up to the credit
+// of the last ack beyond the events received when it was sent; credit never
+// exceeds ingestWindow or the free space of the fullest lane an ingested
+// event may go to, so a backed-up queue slows producers down instead of
+// dropping their events.
+const (
+	ingestWindow      = 4096
+	ingestAckInterval = 250 * time.Millisecond
+)
+
+// IngestEvent is one event sent by a producer over an ingestion stream
+type IngestEvent struct {
+	Type      events.EventType `json:"type"`
+	SessionID string           `json:"session_id"`
+	UserID    string           `json:"user_id"`
+	EventID   string           `json:"event_id,omitempty"`
+
+	ReactionType  string            `json:"reaction_type,omitempty"`  // reactions
+	Source        string            `json:"source,omitempty"`         // reactions: UI surface sent from
+	Attributes    map[string]string `json:"attributes,omitempty"`     // reactions
+	Text          string            `json:"text,omitempty"`           // chat
+	AuthorName    string            `json:"author_name,omitempty"`    // chat
+	Cohort        string            `json:"cohort,omitempty"`         // joins
+	ClientVersion string            `json:"client_version,omitempty"` // joins: app version the user joined with
+}
+
+// IngestFrame is a batch of events. Close asks the server to send a final
+// ack and end the stream once the batch is processed.
+type IngestFrame struct {
+	Events []IngestEvent `json:"events"`
+	Close  bool          `json:"close,omitempty"`
+}
+
+// IngestAck reports a stream's progress. Received counts every event read
+// so far; the producer may send until Received+Credit.
+type IngestAck struct {
+	Type          string    `json:"type"` // "ack", or "summary" for the final ack
+	Received      int64     `json:"received"`
+	Accepted      int64     `json:"accepted"`
+	Rejected      int64     `json:"rejected"`
+	Credit        int64     `json:"credit"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorCode errs.Code `json:"last_error_code,omitempty"`
+}
+
+// MaxIngestBatch is the most events one ingestion request may carry
+const MaxIngestBatch = 500
+
+// IngestRejection reports why an event of a batch was not accepted
+type IngestRejection struct {
+	Index int       `json:"index"`
+	Error string    `json:"error"`
+	Code  errs.Code `json:"code"`
+}
+
+// IngestBatchResult reports what became of each event of a batch
+type IngestBatchResult struct {
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Rejections []IngestRejection `json:"rejections,omitempty"`
+}
+
+// ingestTypes are the event types producers may send, see ToEvent
+var ingestTypes = []events.EventType{
+	events.EventTypeReaction,
+	events.EventTypeChat,
+	events.EventTypeJoinSession,
+	events.EventTypeLeaveSession,
+}
+
+// errIngestWindowExceeded ends a stream that ignored its credit
+var errIngestWindowExceeded = errors.New("flow control window exceeded")
+
+// ToEvent validates an ingested event and builds the queue event for it
+func (e IngestEvent) ToEvent() (*events.Event, error) {
+	if e.UserID == "" {
+		return nil, errs.Validation("user_id is required")
+	}
+	if err := sessions.ValidateID(e.SessionID); err != nil {
+		return nil, err
+	}
+
+	var event *events.Event
+	switch e.Type {
+	case events.EventTypeReaction:
+		reactionType := events.ReactionType(e.ReactionType)
+		if !reactionType.IsValid() {
+			return nil, errs.Validation("unknown reaction_type %s", e.ReactionType)
+		}
+		if err := events.ValidateReactionSource(events.ReactionSource(e.Source)); err != nil {
+			return nil, err
+		}
+		if err := events.ValidateAttributes(e.Attributes); err != nil {
+			return nil, err
+		}
+		event = events.SourcedReactionEvent(e.SessionID, e.UserID, reactionType, events.ReactionSource(e.Source), e.Attributes)
+	case events.EventTypeChat:
+		if e.Text == "" || len(e.Text) > 500 {
+			return nil, errs.Validation("chat text must be 1-500 characters")
+		}
+		event = events.ChatEvent(e.SessionID, e.UserID, e.Text, e.AuthorName)
+	case events.EventTypeJoinSession:
+		event = events.CohortJoinSessionEvent(e.SessionID, e.UserID, e.Cohort)
+		event.SetClientVersion(e.ClientVersion)
+	case events.EventTypeLeaveSession:
+		event = events.LeaveSessionEvent(e.SessionID, e.UserID)
+	default:
+		return nil, errs.Validation("unsupported event type %s", e.Type)
+	}
+	if e.EventID != "" {
+		if err := event.SetExternalID(e.EventID); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// ingestStream tracks one producer connection
+type ingestStream struct {
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	received int64
+	accepted int64
+	rejected int64
+	allowed  int64        // received count the producer may send up to
+	lastErr  atomic.Value // ErrorResponse
+}
+
+// write sends a frame; gorilla connections allow one writer at a time
+func (st *ingestStream) write(v interface{}) error {
+	st.writeMu.Lock()
+	defer st.writeMu.Unlock()
+	st.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return st.conn.WriteJSON(v)
+}
+
+// ack grants fresh credit and reports progress. Without an in-process
+// queue to measure, the full window is granted.
+func (st *ingestStream) ack(kind string, queue *events.Queue) error {
+	received := atomic.LoadInt64(&st.received)
+	credit := int64(ingestWindow)
+	if queue != nil {
+		credit = min(credit, int64(queue.Free(ingestTypes...)))
+	}
+	if credit < 0 {
+		credit = 0
+	}
+	atomic.StoreInt64(&st.allowed, received+credit)
+
+	ack := IngestAck{
+		Type:     kind,
+		Received: received,
+		Accepted: atomic.LoadInt64(&st.accepted),
+		Rejected: atomic.LoadInt64(&st.rejected),
+		Credit:   credit,
+	}
+	lastErr, _ := st.lastErr.Load().(ErrorResponse)
+	ack.LastError, ack.LastErrorCode = lastErr.Message, lastErr.Code
+	return st.write(ack)
+}
+
+// HandleIngestStream accepts a long-lived stream of events from an upstream
+// gateway over a single WebSocket. Producers send IngestFrame batches and
+// receive an IngestAck every ingestAckInterval carrying the credit they may
+// send before the next one. Invalid events are counted as rejected without
+// ending the stream; exceeding the credit ends it.
+func (s *Server) HandleIngestStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Ingest stream upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	st := &ingestStream{conn: conn}
+	st.lastErr.Store(ErrorResponse{})
+	sourceIP := clientIP(r)
+	queue, _ := s.localQueue()
+	if err := st.ack("ack", queue); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(ingestAckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := st.ack("ack", queue); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var frame IngestFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Ingest stream from %s ended: %v", sourceIP, err)
+			}
+			return
+		}
+
+		received := atomic.AddInt64(&st.received, int64(len(frame.Events)))
+		if received > atomic.LoadInt64(&st.allowed) {
+			log.Printf("Ingest stream from %s exceeded its credit, closing", sourceIP)
+			st.write(map[string]string{"type": "error", "message": errIngestWindowExceeded.Error()})
+			return
+		}
+
+		for _, in := range frame.Events {
+			event, err := in.ToEvent()
+			if err == nil {
+				event.SourceIP = sourceIP
+				err = s.eventQueue.Enqueue(r.Context(), event)
+			}
+			if err != nil {
+				atomic.AddInt64(&st.rejected, 1)
+				resp, _ := newErrorResponse(err)
+				st.lastErr.Store(resp)
+				continue
+			}
+			atomic.AddInt64(&st.accepted, 1)
+		}
+
+		if frame.Close {
+			st.ack("summary", queue)
+			st.writeMu.Lock()
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			st.writeMu.Unlock()
+			return
+		}
+	}
+}
+
+// HandleIngestBatch accepts a single IngestFrame of events over plain HTTP,
+// for producers that send occasional batches rather than keep a stream open,
+// such as the serverless ingestion handler. Invalid events are rejected
+// individually; the rest are enqueued.
+func (s *Server) HandleIngestBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	var frame IngestFrame
+	if err := json.NewDecoder(r.Body).Decode(&frame); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	if len(frame.Events) == 0 {
+		writeError(w, errs.Validation("events are required"))
+		return
+	}
+	if len(frame.Events) > MaxIngestBatch {
+		writeError(w, errs.Validation("a batch carries at most %d events", MaxIngestBatch))
+		return
+	}
+
+	sourceIP := clientIP(r)
+	var result IngestBatchResult
+	for i, in := range frame.Events {
+		event, err := in.ToEvent()
+		if err == nil {
+			event.SourceIP = sourceIP
+			err = s.eventQueue.Enqueue(r.Context(), event)
+		}
+		if err != nil {
+			resp, _ := newErrorResponse(err)
+			result.Rejected++
+			result.Rejections = append(result.Rejections, IngestRejection{Index: i, Error: resp.Message, Code: resp.Code})
+			continue
+		}
+		result.Accepted++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialIngest opens an ingestion stream against a test server
+func dialIngest(t *testing.T, server *Server) *websocket.Conn {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleIngestStream))
+	t.Cleanup(ts.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestHandleIngestStream_AcksAndEnqueues(t *testing.T) {
+	queue := events.NewQueue(16)
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	conn := dialIngest(t, server)
+
+	var ack IngestAck
+	require.NoError(t, conn.ReadJSON(&ack))
+	assert.Equal(t, int64(16), ack.Credit, "credit is bounded by the queue's free space")
+
+	require.NoError(t, conn.WriteJSON(IngestFrame{
+		Events: []IngestEvent{
+			{Type: events.EventTypeReaction, SessionID: "s1", UserID: "u1", ReactionType: "fire", EventID: "0f4e1a52-9c7b-4d3e-8a61-2b5c7d9e0f13"},
+			{Type: events.EventTypeReaction, SessionID: "s1", UserID: "u1", ReactionType: "bogus"},
+			{Type: events.EventTypePresence, SessionID: "s1", UserID: "u1"},
+		},
+		Close: true,
+	}))
+
+	for ack.Type != "summary" {
+		require.NoError(t, conn.ReadJSON(&ack))
+	}
+	assert.Equal(t, int64(3), ack.Received)
+	assert.Equal(t, int64(1), ack.Accepted)
+	assert.Equal(t, int64(2), ack.Rejected)
+	assert.NotEmpty(t, ack.LastError)
+	assert.Equal(t, errs.CodeValidation, ack.LastErrorCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	event, ok := queue.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "0f4e1a52-9c7b-4d3e-8a61-2b5c7d9e0f13", event.ID)
+	assert.True(t, event.External)
+}
+
+func TestHandleIngestStream_CreditsTheFullestLane(t *testing.T) {
+	queue := events.NewQueue(4)
+	queue.SetPriority(events.PriorityHigh, events.EventTypeChat)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), events.ChatEvent("s1", "u1", "hi", "A")))
+	}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), events.ReactionEvent("s1", "u1", events.ReactionFire)))
+	}
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	conn := dialIngest(t, server)
+
+	var ack IngestAck
+	require.NoError(t, conn.ReadJSON(&ack))
+	assert.Equal(t, int64(1), ack.Credit, "the chat lane has room for one more event")
+}
+
+func TestHandleIngestStream_ClosesWhenCreditIsExceeded(t *testing.T) {
+	queue := events.NewQueue(2)
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	conn := dialIngest(t, server)
+
+	var ack IngestAck
+	require.NoError(t, conn.ReadJSON(&ack))
+	require.Equal(t, int64(2), ack.Credit)
+
+	batch := make([]IngestEvent, 3)
+	for i := range batch {
+		batch[i] = IngestEvent{Type: events.EventTypeJoinSession, SessionID: "s1", UserID: "u1"}
+	}
+	require.NoError(t, conn.WriteJSON(IngestFrame{Events: batch}))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			break // stream closed by the server
+		}
+		if msg["type"] == "error" {
+			assert.Equal(t, errIngestWindowExceeded.Error(), msg["message"])
+			return
+		}
+	}
+	t.Fatal("expected an error before the stream closed")
+}
+
+func TestIngestEvent_AttributesReactionSource(t *testing.T) {
+	event, err := IngestEvent{Type: events.EventTypeReaction, SessionID: "s1", UserID: "u1", ReactionType: "fire", Source: string(events.ReactionSourceMobileApp)}.ToEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.ReactionSourceMobileApp, event.GetReactionSource())
+
+	_, err = IngestEvent{Type: events.EventTypeReaction, SessionID: "s1", UserID: "u1", ReactionType: "fire", Source: "smart_fridge"}.ToEvent()
+	assert.Equal(t, errs.CodeValidation, errs.CodeOf(err), "unknown sources are rejected")
+}
+
+func TestHandleIngestBatch_RejectsInvalidEventsIndividually(t *testing.T) {
+	queue := events.NewQueue(16)
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+
+	body := `{"events":[
+		{"type":"reaction","session_id":"s1","user_id":"u1","reaction_type":"fire"},
+		{"type":"reaction","session_id":"s1","user_id":"","reaction_type":"fire"},
+		{"type":"join_session","session_id":"s1","user_id":"u2"}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/events", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.HandleIngestBatch(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result IngestBatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 1, result.Rejected)
+	require.Len(t, result.Rejections, 1)
+	assert.Equal(t, 1, result.Rejections[0].Index)
+	assert.Equal(t, errs.CodeValidation, result.Rejections[0].Code)
+	assert.Equal(t, 2, queue.Len())
+}
+
+func TestIngestAuthMiddleware_AcceptsIngestKeys(t *testing.T) {
+	SetIngestKeys([]string{"key-1"})
+	defer SetIngestKeys(nil)
+	var userID string
+	handler := IngestAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = r.Context().Value("user_id").(string)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/events", nil)
+	req.Header.Set("Authorization", "Bearer key-1")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, IngestKeyUserID, userID)
+
+	userID = ""
+	req.Header.Set("Authorization", "Bearer key-2")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "other tokens must be a valid Clerk session")
+	assert.Empty(t, userID)
+}
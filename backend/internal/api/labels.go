@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/locales"
+)
+
+// LabelsResponse carries display strings for LivePulse concepts in one locale
+type LabelsResponse struct {
+	Locale     string            `json:"locale"`
+	Available  []string          `json:"available"`
+	Reactions  map[string]string `json:"reactions"`
+	Milestones map[string]string `json:"milestones,omitempty"` // milestone ID -> description
+}
+
+// HandleGetLabels serves localized reaction labels, plus the milestone
+// descriptions of ?session_id= when given. The locale is negotiated from
+// Accept-Language; ?lang= takes precedence over the header.
+func (s *Server) HandleGetLabels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	acceptLanguage := r.Header.Get("Accept-Language")
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		acceptLanguage = lang + "," + acceptLanguage
+	}
+	catalog := locales.Negotiate(acceptLanguage)
+
+	resp := LabelsResponse{
+		Locale:    catalog.Locale,
+		Available: locales.Available(),
+		Reactions: catalog.Prefixed("reaction."),
+	}
+	if sessionID := r.URL.Query().Get("session_id"); sessionID != "" && s.tracker != nil {
+		resp.Milestones = make(map[string]string)
+		for _, m := range s.tracker.GetSessionMilestones(sessionID) {
+			resp.Milestones[m.ID] = m.LocalizedDescription(catalog)
+		}
+	}
+
+	w.Header().Set("Content-Language", catalog.Locale)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
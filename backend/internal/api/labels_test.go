@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetLabels_NegotiatesLocale(t *testing.T) {
+	tracker := milestones.NewTracker(nil)
+	tracker.AddMilestones("s1", []milestones.Definition{
+		{Type: milestones.MilestoneTypeTotalReactions, Threshold: 100},
+		{Type: milestones.MilestoneTypeConcurrentUsers, Threshold: 50, Description: "Full house"},
+	})
+	server := NewServer(nil, aggregation.NewManager(), tracker, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/labels?session_id=s1", nil)
+	req.Header.Set("Accept-Language", "es-ES,es;q=0.9,en;q=0.5")
+	rec := httptest.NewRecorder()
+	server.HandleGetLabels(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "es", rec.Header().Get("Content-Language"))
+
+	var resp LabelsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "Aplausos", resp.Reactions["applause"])
+	assert.Equal(t, "100 reacciones en total", resp.Milestones["s1_total_reactions_100"])
+	// Descriptions written by the producer are not translated
+	assert.Equal(t, "Full house", resp.Milestones["s1_concurrent_users_50"])
+
+	// ?lang= overrides the header
+	req = httptest.NewRequest(http.MethodGet, "/api/labels?lang=de", nil)
+	req.Header.Set("Accept-Language", "es")
+	rec = httptest.NewRecorder()
+	server.HandleGetLabels(rec, req)
+	assert.Equal(t, "de", rec.Header().Get("Content-Language"))
+}
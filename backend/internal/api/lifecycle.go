@@ -0,0 +1,485 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// EndedSession summarizes a session that was finalized
+type EndedSession struct {
+	Session    sessions.Session           `json:"session"`
+	Snapshot   *aggregation.StatsSnapshot `json:"snapshot,omitempty"`
+	Milestones interface{}                `json:"milestones,omitempty"`
+	// Retention is the audience drop-off curve, minute by minute
+	Retention []aggregation.RetentionPoint `json:"retention,omitempty"`
+}
+
+// SetReactionCaps sets the gate enforcing per-session reaction caps so its
+// usage is released when sessions end
+func (s *Server) SetReactionCaps(caps *sessions.ReactionCaps) {
+	s.caps = caps
+}
+
+// SetCloseGracePeriod sets how long closing sessions keep accepting late
+// reactions before they are finalized. Zero ends sessions immediately.
+func (s *Server) SetCloseGracePeriod(d time.Duration) {
+	s.closeGrace = d
+}
+
+// SetTenantDatabases routes archived snapshots of isolated tenants to their
+// own storage
+func (s *Server) SetTenantDatabases(tenantDBs *storage.TenantDatabases) {
+	s.tenantDBs = tenantDBs
+}
+
+// archiveDB returns the database holding a tenant's session archive
+func (s *Server) archiveDB(tenantID string) *storage.PostgresClient {
+	if s.tenantDBs == nil {
+		return s.db
+	}
+	return s.tenantDBs.For(tenantID)
+}
+
+// CloseSession starts the two-phase end of a live session. The session stops
+// accepting joins right away but keeps counting reactions from clients with
+// delayed delivery until the grace period elapses, then it is finalized by
+// EndSession. It returns false if the session was not live.
+func (s *Server) CloseSession(ctx context.Context, sessionID, reason string) (sessions.Session, bool) {
+	if s.closeGrace <= 0 {
+		ended, ok := s.EndSession(ctx, sessionID, reason)
+		if !ok {
+			return sessions.Session{}, false
+		}
+		return ended.Session, true
+	}
+
+	session, ok := s.registry.Close(sessionID)
+	if !ok {
+		return sessions.Session{}, false
+	}
+
+	closesAt := session.ClosingAt.Add(s.closeGrace)
+	if s.wsHub != nil {
+		s.wsHub.BroadcastToSession(sessionID, map[string]interface{}{
+			"type":      "session_closing",
+			"reason":    reason,
+			"closes_at": closesAt,
+		})
+	}
+
+	time.AfterFunc(s.closeGrace, func() {
+		s.EndSession(context.Background(), sessionID, reason)
+	})
+
+	log.Printf("Session %s closing (%s), finalizing at %s", sessionID, reason, closesAt.Format(time.RFC3339))
+	return session, true
+}
+
+// EndSession finalizes a live or closing session: the final snapshot is persisted,
+// connected clients and webhooks are told the session ended, and in-memory
+// state is released. It returns false if the session was already ended.
+func (s *Server) EndSession(ctx context.Context, sessionID, reason string) (*EndedSession, bool) {
+	session, ok := s.registry.End(sessionID)
+	if !ok {
+		return nil, false
+	}
+
+	ended := &EndedSession{Session: session}
+	if stats, exists := s.aggManager.GetSession(sessionID); exists {
+		snapshot := stats.GetSnapshot()
+		ended.Snapshot = &snapshot
+		ended.Retention = stats.GetRetentionCurve()
+	}
+	if s.tracker != nil {
+		if milestoneList := s.tracker.GetSessionMilestones(sessionID); milestoneList != nil {
+			ended.Milestones = milestoneList
+		}
+	}
+
+	if s.db != nil && ended.Snapshot != nil {
+		snapshotJSON, _ := json.Marshal(schema.SnapshotV1From(*ended.Snapshot))
+		milestoneList, _ := ended.Milestones.([]*milestones.Milestone)
+		milestonesJSON, _ := json.Marshal(schema.MilestonesV1From(milestoneList))
+		archived := storage.SessionSnapshot{
+			SessionID:      sessionID,
+			TenantID:       session.TenantID,
+			Name:           session.Name,
+			Tags:           session.Tags,
+			PeakUsers:      ended.Snapshot.PeakConcurrentUsers,
+			TotalReactions: ended.Snapshot.TotalReactions,
+			Snapshot:       snapshotJSON,
+			Milestones:     milestonesJSON,
+			EndedAt:        *session.EndedAt,
+		}
+		if len(ended.Retention) > 0 {
+			archived.Retention, _ = json.Marshal(schema.RetentionV1From(ended.Retention))
+		}
+		if ended.Snapshot.Peak != nil {
+			archived.PeakAt = &ended.Snapshot.Peak.At
+		}
+		if err := s.archiveDB(session.TenantID).SaveSessionSnapshot(ctx, archived); err != nil {
+			log.Printf("Error saving final snapshot for session %s: %v", sessionID, err)
+		}
+	}
+
+	if s.wsHub != nil {
+		s.wsHub.BroadcastToSession(sessionID, map[string]interface{}{
+			"type":     "session_ended",
+			"reason":   reason,
+			"ended_at": session.EndedAt,
+			"snapshot": ended.Snapshot,
+		})
+	}
+	s.notifier.Notify(notifications.Event{
+		Type:       notifications.TypeSessionEnded,
+		SessionID:  sessionID,
+		OccurredAt: *session.EndedAt,
+		Data: map[string]interface{}{
+			"reason":  reason,
+			"session": ended,
+		},
+	})
+
+	s.releaseSession(sessionID)
+
+	log.Printf("Session %s ended (%s)", sessionID, reason)
+	return ended, true
+}
+
+// releaseSession drops the in-memory state held for a session
+func (s *Server) releaseSession(sessionID string) {
+	s.aggManager.RemoveSession(sessionID)
+	if s.tracker != nil {
+		s.tracker.RemoveSession(sessionID)
+	}
+	if s.feed != nil {
+		s.feed.Remove(sessionID)
+	}
+	if s.caps != nil {
+		s.caps.Remove(sessionID)
+	}
+	if s.sources != nil {
+		s.sources.Remove(sessionID)
+	}
+	if s.waves != nil {
+		s.waves.Remove(sessionID)
+	}
+	s.boostEnds.cancel(sessionID)
+	s.notifier.RemoveSessionWebhooks(context.Background(), sessionID)
+	if s.statsCache != nil {
+		s.statsCache.remove(sessionID)
+	}
+}
+
+// HandleResetSessionStats clears a live session's counters without ending
+// it. ?scope= is all, reactions or peak_users; milestones that depend on
+// the cleared counters can be achieved again.
+func (s *Server) HandleResetSessionStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	scope := aggregation.ResetScope(r.URL.Query().Get("scope"))
+	if scope == "" {
+		scope = aggregation.ResetAll
+	}
+	if !scope.IsValid() {
+		writeError(w, errs.Validation("scope must be all, reactions or peak_users"))
+		return
+	}
+
+	session, exists := s.registry.Get(sessionID)
+	if !exists || session.Status == sessions.StatusEnded {
+		writeError(w, errs.NotFound("session not found or already ended"))
+		return
+	}
+	stats, exists := s.aggManager.GetSession(sessionID)
+	if !exists {
+		writeError(w, errs.NotFound("session has no stats"))
+		return
+	}
+
+	stats.Reset(scope)
+	if s.tracker != nil {
+		switch scope {
+		case aggregation.ResetAll:
+			s.tracker.Reset(sessionID)
+		case aggregation.ResetReactions:
+			s.tracker.Reset(sessionID, milestones.MilestoneTypeTotalReactions, milestones.MilestoneTypeReactionVelocity)
+		}
+	}
+	s.recordAction(r, ActionSessionReset, sessionID, "", map[string]string{"scope": string(scope)})
+
+	snapshot := stats.GetSnapshot()
+	if s.wsHub != nil {
+		s.wsHub.BroadcastToSession(sessionID, map[string]interface{}{
+			"type":     "stats_reset",
+			"scope":    scope,
+			"snapshot": snapshot,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"scope":      scope,
+		"snapshot":   snapshot,
+	})
+}
+
+// BulkEndRequest selects the live sessions to end
+type BulkEndRequest struct {
+	SessionIDs []string `json:"session_ids,omitempty"` // end only these sessions
+	TenantID   string   `json:"tenant_id,omitempty"`
+	NamePrefix string   `json:"name_prefix,omitempty"`
+	OlderThan  string   `json:"older_than,omitempty"` // Go duration, e.g. "2h"
+	Tag        string   `json:"tag,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	DryRun     bool     `json:"dry_run,omitempty"`
+	Immediate  bool     `json:"immediate,omitempty"` // skip the late-reaction grace period
+}
+
+// HandleBulkEndSessions gracefully ends every live session matching a
+// filter, or the listed sessions among them
+func (s *Server) HandleBulkEndSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	var req BulkEndRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+
+	filter := sessions.Filter{
+		TenantID:   req.TenantID,
+		NamePrefix: req.NamePrefix,
+		Status:     sessions.StatusLive,
+		Tag:        req.Tag,
+	}
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil || d < 0 {
+			writeError(w, errs.Validation("older_than must be a positive duration like 2h"))
+			return
+		}
+		filter.OlderThan = d
+	}
+	if req.Reason == "" {
+		req.Reason = "bulk_end"
+	}
+
+	var only map[string]bool
+	if len(req.SessionIDs) > 0 {
+		only = make(map[string]bool, len(req.SessionIDs))
+		for _, sessionID := range req.SessionIDs {
+			only[sessionID] = true
+		}
+	}
+
+	matched := s.registry.List(filter)
+	endedIDs := make([]string, 0, len(matched))
+	for _, session := range matched {
+		if only != nil && !only[session.ID] {
+			continue
+		}
+		if req.DryRun {
+			endedIDs = append(endedIDs, session.ID)
+			continue
+		}
+		if req.Immediate {
+			if _, ok := s.EndSession(r.Context(), session.ID, req.Reason); ok {
+				endedIDs = append(endedIDs, session.ID)
+				s.recordAction(r, ActionSessionEnd, session.ID, req.Reason, nil)
+			}
+			continue
+		}
+		if _, ok := s.CloseSession(r.Context(), session.ID, req.Reason); ok {
+			endedIDs = append(endedIDs, session.ID)
+			s.recordAction(r, ActionSessionClose, session.ID, req.Reason, nil)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dry_run":         req.DryRun,
+		"ended_count":     len(endedIDs),
+		"session_ids":     endedIDs,
+		"grace_period_ms": s.gracePeriodFor(req).Milliseconds(),
+	})
+}
+
+// gracePeriodFor returns the grace period applied to a bulk end request
+func (s *Server) gracePeriodFor(req BulkEndRequest) time.Duration {
+	if req.Immediate {
+		return 0
+	}
+	return s.closeGrace
+}
+
+// HandleGetSessionArchive returns the final snapshot of an ended session
+func (s *Server) HandleGetSessionArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	if s.db == nil {
+		writeError(w, errs.Unavailable("archive not available"))
+		return
+	}
+
+	archived, err := s.archiveDB(sessions.TenantOf(sessionID)).GetSessionSnapshot(r.Context(), sessionID)
+	if err != nil {
+		log.Printf("Error loading archive for session %s: %v", sessionID, err)
+		writeError(w, err)
+		return
+	}
+	if archived == nil {
+		writeError(w, errs.NotFound("session not archived"))
+		return
+	}
+	naming, err := s.responseNaming(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	v1, err := schema.ArchiveV1From(archived)
+	if err != nil {
+		log.Printf("Error decoding archive for session %s: %v", sessionID, err)
+		writeError(w, err)
+		return
+	}
+	writeVersioned(w, naming, v1)
+}
+
+// parseArchiveTime accepts either an RFC 3339 timestamp or a bare date
+func parseArchiveTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// HandleSearchSessionArchive lists archived sessions filtered by tenant, tag,
+// end date range and final metrics, newest first unless another sort is given.
+// Results carry summary metrics only; fetch a full snapshot via the archive
+// endpoint. Tenants with isolated storage are only searched when named by
+// ?tenant_id=.
+func (s *Server) HandleSearchSessionArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.db == nil {
+		writeError(w, errs.Unavailable("archive not available"))
+		return
+	}
+
+	params := r.URL.Query()
+	q := storage.ArchiveQuery{
+		TenantID: params.Get("tenant_id"),
+		Tag:      params.Get("tag"),
+		Sort:     storage.ArchiveSortEndedAt,
+		Limit:    50,
+	}
+	if val := params.Get("from"); val != "" {
+		t, err := parseArchiveTime(val)
+		if err != nil {
+			writeError(w, errs.Validation("from must be an RFC 3339 timestamp or YYYY-MM-DD date"))
+			return
+		}
+		q.EndedAfter = t
+	}
+	if val := params.Get("to"); val != "" {
+		t, err := parseArchiveTime(val)
+		if err != nil {
+			writeError(w, errs.Validation("to must be an RFC 3339 timestamp or YYYY-MM-DD date"))
+			return
+		}
+		q.EndedBefore = t
+	}
+	if !q.EndedAfter.IsZero() && !q.EndedBefore.IsZero() && !q.EndedBefore.After(q.EndedAfter) {
+		writeError(w, errs.Validation("to must be after from"))
+		return
+	}
+	if val := params.Get("min_peak_users"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			writeError(w, errs.Validation("min_peak_users must be a non-negative integer"))
+			return
+		}
+		q.MinPeakUsers = n
+	}
+	if val := params.Get("min_total_reactions"); val != "" {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, errs.Validation("min_total_reactions must be a non-negative integer"))
+			return
+		}
+		q.MinTotalReactions = n
+	}
+	if val := params.Get("sort"); val != "" {
+		if !storage.ValidArchiveSort(val) {
+			writeError(w, errs.Validation("sort must be one of ended_at, peak_users, total_reactions"))
+			return
+		}
+		q.Sort = val
+	}
+	if val, err := strconv.Atoi(params.Get("offset")); err == nil && val > 0 {
+		q.Offset = val
+	}
+	if val, err := strconv.Atoi(params.Get("limit")); err == nil && val > 0 {
+		q.Limit = val
+	}
+	if q.Limit > 200 {
+		q.Limit = 200
+	}
+
+	results, more, err := s.archiveDB(q.TenantID).SearchSessionSnapshots(r.Context(), q)
+	if err != nil {
+		log.Printf("Error searching session archive: %v", err)
+		writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []storage.SessionSnapshot{}
+	}
+
+	resp := map[string]interface{}{
+		"sessions": results,
+		"offset":   q.Offset,
+		"limit":    q.Limit,
+		"has_more": more,
+	}
+	if more {
+		resp["next_offset"] = q.Offset + len(results)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleBulkEndSessions_EndsOnlyMatchingTenant(t *testing.T) {
+	registry := sessions.NewRegistry()
+	manager := aggregation.NewManager()
+	server := NewServer(nil, manager, nil, nil, nil, nil, registry, nil)
+
+	registry.Create(sessions.Session{ID: "s1", Name: "Keynote", TenantID: "acme"})
+	registry.Create(sessions.Session{ID: "s2", Name: "Workshop", TenantID: "acme"})
+	registry.Create(sessions.Session{ID: "s3", Name: "Keynote", TenantID: "globex"})
+	for _, id := range []string{"s1", "s2", "s3"} {
+		manager.GetOrCreateSession(id)
+	}
+
+	body := strings.NewReader(`{"tenant_id":"acme","name_prefix":"Key"}`)
+	rec := httptest.NewRecorder()
+	server.HandleBulkEndSessions(rec, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", body))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"ended_count":1`)
+
+	assert.True(t, registry.IsEnded("s1"))
+	assert.False(t, registry.IsEnded("s2"))
+	assert.False(t, registry.IsEnded("s3"))
+	_, exists := manager.GetSession("s1")
+	assert.False(t, exists, "ended session stats should be released")
+}
+
+func TestHandleBulkEndSessions_DryRunLeavesSessionsLive(t *testing.T) {
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1", Name: "Keynote", TenantID: "acme"})
+
+	rec := httptest.NewRecorder()
+	server.HandleBulkEndSessions(rec, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", strings.NewReader(`{"dry_run":true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"s1"`)
+	assert.False(t, registry.IsEnded("s1"))
+
+	rec = httptest.NewRecorder()
+	server.HandleBulkEndSessions(rec, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/end", strings.NewReader(`{"older_than":"soon"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCloseSession_AcceptsLateReactionsUntilGraceElapses(t *testing.T) {
+	registry := sessions.NewRegistry()
+	manager := aggregation.NewManager()
+	server := NewServer(nil, manager, nil, nil, nil, nil, registry, nil)
+	server.SetCloseGracePeriod(50 * time.Millisecond)
+
+	registry.Create(sessions.Session{ID: "s1", Name: "Keynote"})
+	manager.GetOrCreateSession("s1")
+
+	_, ok := server.CloseSession(context.Background(), "s1", "host_ended")
+	require.True(t, ok)
+
+	session, _ := registry.Get("s1")
+	assert.Equal(t, sessions.StatusClosing, session.Status)
+	assert.False(t, registry.AcceptsJoins("s1"))
+	assert.False(t, registry.IsEnded("s1"), "late reactions are still accepted while closing")
+
+	rec := httptest.NewRecorder()
+	server.HandleJoinSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/join?session_id=s1&user_id=u1", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	_, ok = server.CloseSession(context.Background(), "s1", "host_ended")
+	assert.False(t, ok, "closing twice should be rejected")
+
+	assert.Eventually(t, func() bool { return registry.IsEnded("s1") }, time.Second, 10*time.Millisecond)
+	_, exists := manager.GetSession("s1")
+	assert.False(t, exists, "stats are finalized once the grace period elapses")
+}
+
+func TestHandleResetSessionStats_ClearsReactionsAndIsRecorded(t *testing.T) {
+	registry := sessions.NewRegistry()
+	aggManager := aggregation.NewManager()
+	server := NewServer(nil, aggManager, nil, nil, nil, nil, registry, nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1"})
+	aggManager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/sessions/reset?session_id=s1&scope=bogus", nil)
+	server.HandleResetSessionStats(rec, asUser(req, "admin-1"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/sessions/reset?session_id=s1&scope=reactions&reason=rehearsal+over", nil)
+	server.HandleResetSessionStats(rec, asUser(req, "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	stats, _ := aggManager.GetSession("s1")
+	assert.Zero(t, stats.GetTotalReactions())
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionSessionReset, actions.actions[0].Action)
+	assert.Equal(t, "rehearsal over", actions.actions[0].Reason)
+	assert.JSONEq(t, `{"scope":"reactions"}`, string(actions.actions[0].Details))
+}
+
+func TestHandleDeleteSession_HidesUntilRestoredOrPurged(t *testing.T) {
+	registry := sessions.NewRegistry()
+	aggManager := aggregation.NewManager()
+	server := NewServer(nil, aggManager, nil, nil, nil, nil, registry, nil)
+	server.SetPurgeAfter(time.Hour)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1"})
+	aggManager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	server.HandleDeleteSession(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/admin/sessions/delete?session_id=s1", nil), "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"purge_at"`)
+	assert.False(t, registry.AcceptsJoins("s1"))
+	assert.Empty(t, registry.List(sessions.Filter{}))
+
+	rec = httptest.NewRecorder()
+	server.HandleDeleteSession(rec, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/delete?session_id=s1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "sessions are deleted once")
+
+	rec = httptest.NewRecorder()
+	server.HandleRestoreSession(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/admin/sessions/restore?session_id=s1", nil), "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, registry.AcceptsJoins("s1"))
+	stats, exists := aggManager.GetSession("s1")
+	require.True(t, exists, "stats are kept while the session is deleted")
+	assert.Equal(t, int64(1), stats.GetTotalReactions())
+	require.Len(t, actions.actions, 2)
+	assert.Equal(t, ActionSessionDelete, actions.actions[0].Action)
+	assert.Equal(t, ActionSessionRestore, actions.actions[1].Action)
+
+	registry.Delete("s1")
+	assert.Zero(t, server.PurgeDeleted(time.Now()), "deleted sessions are kept for the purge period")
+	assert.Equal(t, 1, server.PurgeDeleted(time.Now().Add(2*time.Hour)))
+	_, exists = registry.Get("s1")
+	assert.False(t, exists)
+	_, exists = aggManager.GetSession("s1")
+	assert.False(t, exists, "purging releases the session's stats")
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// Broadcast message types with a fixed schema
+const (
+	MessageTypeMilestoneAchieved         = "milestone_achieved"
+	MessageTypeMilestoneUnlocked         = "milestone_unlocked"
+	MessageTypeCampaignMilestoneAchieved = "campaign_milestone_achieved"
+	MessageTypeControl                   = "control"
+	MessageTypeReactionRejected          = "reaction_rejected"
+	MessageTypeReactionCapReached        = "reaction_cap_reached"
+	MessageTypeFeaturesUpdated           = "features_updated"
+	MessageTypeChatRejected              = "chat_rejected"
+	MessageTypeUserBanned                = "user_banned"
+	MessageTypeWaveResult                = "wave_result"
+	MessageTypeBoostStarted              = "boost_started"
+	MessageTypeBoostEnded                = "boost_ended"
+	MessageTypeSnapshot                  = "snapshot"
+)
+
+// MilestoneAchievedMessage tells every client in a session to celebrate a
+// milestone at the same moment
+type MilestoneAchievedMessage struct {
+	Type         string                   `json:"type"`
+	SessionID    string                   `json:"session_id"`
+	Milestone    *milestones.Milestone    `json:"milestone"`
+	AchievedAt   time.Time                `json:"achieved_at"`
+	CurrentValue int64                    `json:"current_value"`
+	Presentation *milestones.Presentation `json:"presentation,omitempty"`
+
+	// QueuedCelebrations is how many more of the session's milestones are
+	// waiting to be celebrated after this one
+	QueuedCelebrations int `json:"queued_celebrations,omitempty"`
+}
+
+// NewMilestoneAchievedMessage builds the broadcast for a session milestone
+func NewMilestoneAchievedMessage(achievement *milestones.MilestoneAchievement) MilestoneAchievedMessage {
+	return MilestoneAchievedMessage{
+		Type:         MessageTypeMilestoneAchieved,
+		SessionID:    achievement.SessionID,
+		Milestone:    achievement.Milestone,
+		AchievedAt:   achievement.AchievedAt,
+		CurrentValue: achievement.CurrentValue,
+		Presentation: achievement.Milestone.Presentation,
+	}
+}
+
+// MilestoneUnlockedMessage tells clients a milestone's prerequisites were
+// achieved, e.g. to reveal a stretch goal. It is not a celebration.
+type MilestoneUnlockedMessage struct {
+	Type       string                `json:"type"`
+	SessionID  string                `json:"session_id"`
+	Milestone  *milestones.Milestone `json:"milestone"`
+	UnlockedAt time.Time             `json:"unlocked_at"`
+}
+
+// NewMilestoneUnlockedMessage builds the broadcast for an unlocked milestone
+func NewMilestoneUnlockedMessage(unlock *milestones.MilestoneUnlock) MilestoneUnlockedMessage {
+	return MilestoneUnlockedMessage{
+		Type:       MessageTypeMilestoneUnlocked,
+		SessionID:  unlock.SessionID,
+		Milestone:  unlock.Milestone,
+		UnlockedAt: unlock.UnlockedAt,
+	}
+}
+
+// CampaignMilestoneAchievedMessage announces a campaign milestone in every
+// session of the campaign
+type CampaignMilestoneAchievedMessage struct {
+	Type         string                   `json:"type"`
+	CampaignID   string                   `json:"campaign_id"`
+	CampaignName string                   `json:"campaign_name"`
+	Milestone    *milestones.Milestone    `json:"milestone"`
+	AchievedAt   time.Time                `json:"achieved_at"`
+	CurrentValue int64                    `json:"current_value"`
+	Presentation *milestones.Presentation `json:"presentation,omitempty"`
+}
+
+// NewCampaignMilestoneAchievedMessage builds the broadcast for a campaign milestone
+func NewCampaignMilestoneAchievedMessage(achievement *milestones.CampaignAchievement) CampaignMilestoneAchievedMessage {
+	return CampaignMilestoneAchievedMessage{
+		Type:         MessageTypeCampaignMilestoneAchieved,
+		CampaignID:   achievement.Campaign.ID,
+		CampaignName: achievement.Campaign.Name,
+		Milestone:    achievement.Milestone,
+		AchievedAt:   achievement.AchievedAt,
+		CurrentValue: achievement.CurrentValue,
+		Presentation: achievement.Milestone.Presentation,
+	}
+}
+
+// ReactionRejectedMessage tells a user their reaction was not counted. Code
+// distinguishes a sold-out session from the user's own cap running out and
+// from the rate limit.
+type ReactionRejectedMessage struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+	EventID   string `json:"event_id"`
+	Code      string `json:"code"`
+	Cap       int64  `json:"cap,omitempty"`
+}
+
+// NewReactionRejectedMessage builds the rejection sent to a capped user
+func NewReactionRejectedMessage(session sessions.Session, eventID string, verdict sessions.CapVerdict) ReactionRejectedMessage {
+	limit := session.ReactionCap
+	if verdict == sessions.CapUserSoldOut {
+		limit = session.UserReactionCap
+	}
+	return ReactionRejectedMessage{
+		Type:      MessageTypeReactionRejected,
+		SessionID: session.ID,
+		EventID:   eventID,
+		Code:      verdict.Code(),
+		Cap:       limit,
+	}
+}
+
+// NewReactionRateLimitedMessage builds the rejection sent to a user whose
+// reaction exceeded the rate limit
+func NewReactionRateLimitedMessage(sessionID, eventID string) ReactionRejectedMessage {
+	return ReactionRejectedMessage{
+		Type:      MessageTypeReactionRejected,
+		SessionID: sessionID,
+		EventID:   eventID,
+		Code:      string(errs.CodeRateLimited),
+	}
+}
+
+// ChatRejectedMessage tells a user their chat message was blocked by a
+// content filter and not published
+type ChatRejectedMessage struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	EventID   string    `json:"event_id"`
+	Code      errs.Code `json:"code"`
+}
+
+// NewChatRejectedMessage builds the rejection sent to a filtered user
+func NewChatRejectedMessage(sessionID, eventID string) ChatRejectedMessage {
+	return ChatRejectedMessage{
+		Type:      MessageTypeChatRejected,
+		SessionID: sessionID,
+		EventID:   eventID,
+		Code:      errs.CodeContentRejected,
+	}
+}
+
+// UserBannedMessage tells a user a moderator removed them from a session
+type UserBannedMessage struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	Reason    string    `json:"reason,omitempty"`
+	Code      errs.Code `json:"code"`
+}
+
+// NewUserBannedMessage builds the notice sent to a banned user
+func NewUserBannedMessage(sessionID, reason string) UserBannedMessage {
+	return UserBannedMessage{
+		Type:      MessageTypeUserBanned,
+		SessionID: sessionID,
+		Reason:    reason,
+		Code:      errs.CodeBanned,
+	}
+}
+
+// ReactionCapReachedMessage announces that a session's reactions sold out
+type ReactionCapReachedMessage struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	Cap       int64     `json:"cap"`
+	ReachedAt time.Time `json:"reached_at"`
+}
+
+// NewReactionCapReachedMessage builds the sell-out broadcast for a session
+func NewReactionCapReachedMessage(session sessions.Session) ReactionCapReachedMessage {
+	return ReactionCapReachedMessage{
+		Type:      MessageTypeReactionCapReached,
+		SessionID: session.ID,
+		Cap:       session.ReactionCap,
+		ReachedAt: time.Now().UTC(),
+	}
+}
+
+// SnapshotMessage answers a client's resync with the session's full
+// statistics, from which it applies later updates
+type SnapshotMessage struct {
+	Type      string                    `json:"type"`
+	SessionID string                    `json:"session_id"`
+	Snapshot  aggregation.StatsSnapshot `json:"snapshot"`
+}
+
+// NewSnapshotMessage builds the resync reply for a session
+func NewSnapshotMessage(sessionID string, snapshot aggregation.StatsSnapshot) SnapshotMessage {
+	return SnapshotMessage{
+		Type:      MessageTypeSnapshot,
+		SessionID: sessionID,
+		Snapshot:  snapshot,
+	}
+}
+
+// MilestoneBroadcaster returns a milestone handler that fans achievements
+// into the session's broadcast channel, then calls the next handler (e.g.
+// external notifiers) if one is given
+func MilestoneBroadcaster(hub *WebSocketHub, next milestones.NotificationHandler) milestones.NotificationHandler {
+	return func(achievement *milestones.MilestoneAchievement) {
+		hub.BroadcastToSession(achievement.SessionID, NewMilestoneAchievedMessage(achievement))
+		if next != nil {
+			next(achievement)
+		}
+	}
+}
+
+// MilestoneUnlockBroadcaster returns an unlock handler that fans unlocked
+// milestones into the session's broadcast channel, then calls next if given
+func MilestoneUnlockBroadcaster(hub *WebSocketHub, next milestones.UnlockHandler) milestones.UnlockHandler {
+	return func(unlock *milestones.MilestoneUnlock) {
+		hub.BroadcastToSession(unlock.SessionID, NewMilestoneUnlockedMessage(unlock))
+		if next != nil {
+			next(unlock)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMilestoneBroadcaster_FansInToSessionAndNotifiers(t *testing.T) {
+	hub := NewWebSocketHub()
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+
+	notified := make(chan *milestones.MilestoneAchievement, 1)
+	handler := MilestoneBroadcaster(hub, func(a *milestones.MilestoneAchievement) { notified <- a })
+
+	milestone := milestones.NewMilestone("s1", milestones.MilestoneTypeTotalReactions, 100)
+	milestone.Presentation = &milestones.Presentation{Icon: "trophy"}
+	handler(&milestones.MilestoneAchievement{
+		Milestone:    milestone,
+		SessionID:    "s1",
+		AchievedAt:   time.Now(),
+		CurrentValue: 101,
+	})
+
+	select {
+	case data := <-updates:
+		var msg MilestoneAchievedMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, MessageTypeMilestoneAchieved, msg.Type)
+		assert.Equal(t, "s1", msg.SessionID)
+		assert.Equal(t, milestone.ID, msg.Milestone.ID)
+		assert.Equal(t, int64(101), msg.CurrentValue)
+		require.NotNil(t, msg.Presentation)
+		assert.Equal(t, "trophy", msg.Presentation.Icon)
+	case <-time.After(time.Second):
+		t.Fatal("expected a milestone_achieved broadcast")
+	}
+
+	select {
+	case a := <-notified:
+		assert.Equal(t, "s1", a.SessionID)
+	default:
+		t.Fatal("expected the next handler to be called")
+	}
+}
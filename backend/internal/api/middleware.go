@@ -1,8 +1,12 @@
 package api
 
 import (
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,10 +19,21 @@ func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 
 		// Log the request
-		log.Printf("%s %s %s", r.Method, r.RequestURI, time.Since(start))
+		log.Printf("%s %s %s", r.Method, loggedURI(r), time.Since(start))
 	}
 }
 
+// loggedURI returns the request URI with bearer tokens in the query, such as
+// signed overlay tokens, redacted
+func loggedURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("token") {
+		return r.RequestURI
+	}
+	query.Set("token", "[redacted]")
+	return r.URL.Path + "?" + query.Encode()
+}
+
 // CORSMiddleware adds CORS headers
 func CORSMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +41,8 @@ func CORSMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// In production, restrict to specific origins
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -44,7 +60,7 @@ func RecoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("Panic recovered: %v", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				writeError(w, fmt.Errorf("panic: %v", err))
 			}
 		}()
 		next(w, r)
@@ -58,3 +74,72 @@ func Chain(handler http.HandlerFunc, middlewares ...func(http.HandlerFunc) http.
 	}
 	return handler
 }
+
+// trustedProxies are the networks whose X-Forwarded-For headers clientIP
+// believes
+var trustedProxies = struct {
+	networks []*net.IPNet
+	mu       sync.RWMutex
+}{}
+
+// SetTrustedProxies configures the reverse proxy CIDRs allowed to report a
+// caller's address in X-Forwarded-For. With none configured the header is
+// ignored.
+func SetTrustedProxies(cidrs []string) error {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("parse trusted proxy %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	trustedProxies.mu.Lock()
+	defer trustedProxies.mu.Unlock()
+	trustedProxies.networks = networks
+	return nil
+}
+
+// isTrustedProxy reports whether addr belongs to a configured proxy network
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	trustedProxies.mu.RLock()
+	defer trustedProxies.mu.RUnlock()
+	for _, network := range trustedProxies.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the caller's address. X-Forwarded-For is only believed
+// when the connection comes from a trusted proxy, and then the nearest hop
+// that isn't itself a trusted proxy is the caller: anything to its left was
+// supplied by the client and may be forged.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
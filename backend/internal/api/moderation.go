@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/fraud"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// FlagQueue holds chat messages content filters flagged for review
+type FlagQueue interface {
+	GetFlaggedMessages(ctx context.Context, sessionID string) ([]storage.FlaggedMessage, error)
+}
+
+// SetFlagQueue enables the moderator review queue
+func (s *Server) SetFlagQueue(queue FlagQueue) {
+	s.flagged = queue
+}
+
+// SetFraudGuard counts moderator bans towards users' fraud scores
+func (s *Server) SetFraudGuard(guard *fraud.Guard) {
+	s.fraudGuard = guard
+}
+
+// HandleGetFlaggedMessages lists a session's flagged chat messages, oldest
+// first, for moderators to review
+func (s *Server) HandleGetFlaggedMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.flagged == nil {
+		writeError(w, errs.NotFound("moderation queue is not enabled"))
+		return
+	}
+	sessionID := r.URL.Query().Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	messages, err := s.flagged.GetFlaggedMessages(r.Context(), sessionID)
+	if err != nil {
+		log.Printf("Error loading flagged messages for %s: %v", sessionID, err)
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": sessionID,
+		"messages":   messages,
+	})
+}
+
+// HandleSessionBans lists (GET), adds (POST) and lifts (DELETE) a session's
+// bans. Banning removes the user from the session straight away; joins are
+// then refused and the pipeline drops anything else they send to it.
+func (s *Server) HandleSessionBans(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	userID := r.URL.Query().Get("user_id")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodDelete:
+		if userID == "" {
+			writeError(w, errs.Validation("user_id is required"))
+			return
+		}
+	default:
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, exists := s.registry.Get(sessionID); !exists {
+		writeError(w, errs.NotFound("session not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		reason := r.URL.Query().Get("reason")
+		ban, added := s.registry.Ban(sessionID, userID, reason)
+		if added {
+			if s.fraudGuard != nil {
+				s.fraudGuard.RecordBan(userID)
+			}
+			s.removeBannedUser(r.Context(), sessionID, userID, reason)
+			s.recordAction(r, ActionUserBan, sessionID, reason, map[string]string{"user_id": userID})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id": sessionID,
+			"ban":        ban,
+			"added":      added,
+		})
+	case http.MethodDelete:
+		if !s.registry.Unban(sessionID, userID) {
+			writeError(w, errs.NotFound("user is not banned"))
+			return
+		}
+		s.recordAction(r, ActionUserUnban, sessionID, "", map[string]string{"user_id": userID})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id": sessionID,
+			"bans":       s.registry.Bans(sessionID),
+		})
+	}
+}
+
+// removeBannedUser tells a banned user why and closes their connections,
+// whose read pumps emit their leave events. A user without a connection on
+// this node, such as one who joined over HTTP, is sent a leave event here.
+func (s *Server) removeBannedUser(ctx context.Context, sessionID, userID, reason string) {
+	if s.wsHub != nil && s.wsHub.DisconnectUser(sessionID, userID, NewUserBannedMessage(sessionID, reason)) > 0 {
+		return
+	}
+	if err := s.eventQueue.Enqueue(ctx, events.LeaveSessionEvent(sessionID, userID)); err != nil {
+		log.Printf("Error removing banned user %s from session %s: %v", userID, sessionID, err)
+	}
+}
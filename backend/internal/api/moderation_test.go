@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSessionBans_BansRemovesAndUnbans(t *testing.T) {
+	queue := events.NewQueue(4)
+	registry := sessions.NewRegistry()
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	registry.Create(sessions.Session{ID: "s1"})
+
+	call := func(method, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleSessionBans(rec, asUser(httptest.NewRequest(method, "/api/moderation/bans?"+query, nil), "mod-1"))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "session_id=s1&user_id=troll&reason=spam")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"added":true`)
+	assert.True(t, registry.IsBanned("s1", "troll"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	leave, ok := queue.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, events.EventTypeLeaveSession, leave.Type)
+	assert.Equal(t, "troll", leave.UserID)
+
+	// Banning twice changes nothing and is not logged again
+	rec = call(http.MethodPost, "session_id=s1&user_id=troll")
+	assert.Contains(t, rec.Body.String(), `"added":false`)
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionUserBan, actions.actions[0].Action)
+	assert.Equal(t, "spam", actions.actions[0].Reason)
+
+	rec = call(http.MethodGet, "session_id=s1")
+	var listed struct {
+		Bans []sessions.Ban `json:"bans"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	require.Len(t, listed.Bans, 1)
+	assert.Equal(t, "spam", listed.Bans[0].Reason)
+
+	// Banned users cannot join again until the ban is lifted
+	join := httptest.NewRecorder()
+	server.HandleJoinSession(join, httptest.NewRequest(http.MethodPost, "/api/sessions/join?session_id=s1&user_id=troll", nil))
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(join.Body).Decode(&resp))
+	assert.Equal(t, http.StatusForbidden, join.Code)
+	assert.Equal(t, errs.CodeBanned, resp.Code)
+
+	assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, "session_id=s1&user_id=troll").Code)
+	assert.False(t, registry.IsBanned("s1", "troll"))
+	assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "session_id=s1&user_id=troll").Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "session_id=s1").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "session_id=missing").Code)
+}
+
+func TestHandleSessionBans_ClosesTheBannedUsersSockets(t *testing.T) {
+	serverConns := make(chan *websocket.Conn, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		serverConns <- conn
+	}))
+	defer srv.Close()
+
+	queue := events.NewQueue(8)
+	registry := sessions.NewRegistry()
+	hub := NewWebSocketHub()
+	server := NewServer(queue, aggregation.NewManager(), nil, hub, nil, nil, registry, nil)
+	registry.Create(sessions.Session{ID: "s1"})
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+
+	// The banned user has two tabs open
+	var viewers []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		viewer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		require.NoError(t, err)
+		defer viewer.Close()
+		viewers = append(viewers, viewer)
+		client := &Client{hub: sessionHub, conn: <-serverConns, send: make(chan []byte, 8), sessionID: "s1", userID: "troll"}
+		sessionHub.register <- client
+		go client.writePump()
+		go client.readPump(queue)
+	}
+
+	rec := httptest.NewRecorder()
+	server.HandleSessionBans(rec, httptest.NewRequest(http.MethodPost, "/api/moderation/bans?session_id=s1&user_id=troll&reason=spam", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	for _, viewer := range viewers {
+		viewer.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := viewer.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), MessageTypeUserBanned)
+		_, _, err = viewer.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseNoStatusReceived), "socket should be closed, got %v", err)
+	}
+
+	// Each closed socket leaves through its read pump
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		leave, ok := queue.Dequeue(ctx)
+		require.True(t, ok)
+		assert.Equal(t, events.EventTypeLeaveSession, leave.Type)
+	}
+}
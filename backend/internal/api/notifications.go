@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// HandleGetFailedNotifications lists webhook deliveries that ran out of
+// retries, oldest first, up to ?limit=
+func (s *Server) HandleGetFailedNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if !s.notifier.Durable() {
+		writeError(w, errs.NotFound("notification outbox is not enabled"))
+		return
+	}
+
+	limit := 100
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = min(val, 500)
+	}
+	failed, err := s.notifier.Failed(r.Context(), limit)
+	if err != nil {
+		log.Printf("Error listing failed notifications: %v", err)
+		writeError(w, err)
+		return
+	}
+	if failed == nil {
+		failed = []storage.NotificationDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"notifications": failed})
+}
+
+// HandleRedriveNotifications requeues failed webhook deliveries with a fresh
+// set of retries: those listed in ?ids= (comma-separated), or every failed
+// delivery with ?all=true
+func (s *Server) HandleRedriveNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if !s.notifier.Durable() {
+		writeError(w, errs.NotFound("notification outbox is not enabled"))
+		return
+	}
+
+	params := r.URL.Query()
+	var ids []int64
+	for _, part := range strings.Split(params.Get("ids"), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, errs.Validation("ids must be positive integers"))
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 && params.Get("all") != "true" {
+		writeError(w, errs.Validation("ids or all=true is required"))
+		return
+	}
+
+	count, err := s.notifier.Redrive(r.Context(), ids)
+	if err != nil {
+		log.Printf("Error redriving notifications: %v", err)
+		writeError(w, err)
+		return
+	}
+	target := "all"
+	if len(ids) > 0 {
+		target = params.Get("ids")
+	}
+	s.recordAction(r, ActionNotificationRedrive, target, "", map[string]int64{"redriven": count})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"redriven": count})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutbox is an in-process notifications.Store holding failed deliveries
+type memoryOutbox struct {
+	failed []storage.NotificationDelivery
+}
+
+func (m *memoryOutbox) EnqueueNotifications(context.Context, []storage.NotificationDelivery) error {
+	return nil
+}
+
+func (m *memoryOutbox) ClaimNotifications(context.Context, int, time.Duration) ([]storage.NotificationDelivery, error) {
+	return nil, nil
+}
+
+func (m *memoryOutbox) CompleteNotification(context.Context, int64) error { return nil }
+
+func (m *memoryOutbox) RetryNotification(context.Context, int64, string, time.Time) error {
+	return nil
+}
+
+func (m *memoryOutbox) FailNotification(context.Context, int64, string) error { return nil }
+
+func (m *memoryOutbox) ListFailedNotifications(_ context.Context, limit int) ([]storage.NotificationDelivery, error) {
+	return m.failed[:min(limit, len(m.failed))], nil
+}
+
+func (m *memoryOutbox) RedriveNotifications(_ context.Context, ids []int64) (int64, error) {
+	var kept []storage.NotificationDelivery
+	var count int64
+	for _, d := range m.failed {
+		redrive := len(ids) == 0
+		for _, id := range ids {
+			redrive = redrive || d.ID == id
+		}
+		if redrive {
+			count++
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	m.failed = kept
+	return count, nil
+}
+
+func TestHandleRedriveNotifications(t *testing.T) {
+	outbox := &memoryOutbox{failed: []storage.NotificationDelivery{
+		{ID: 1, Type: notifications.TypeMilestoneAchieved, URL: "https://example.com/a", Status: storage.NotificationFailed},
+		{ID: 2, Type: notifications.TypeSessionEnded, URL: "https://example.com/b", Status: storage.NotificationFailed},
+	}}
+	notifier := notifications.NewWebhookNotifier([]string{"https://example.com/a"}, "")
+	notifier.SetStore(outbox, notifications.RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute})
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, nil, notifier)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+
+	rec := httptest.NewRecorder()
+	server.HandleGetFailedNotifications(rec, httptest.NewRequest(http.MethodGet, "/api/ops/notifications", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed struct {
+		Notifications []storage.NotificationDelivery `json:"notifications"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	assert.Len(t, listed.Notifications, 2)
+
+	// Redriving everything must be asked for explicitly
+	rec = httptest.NewRecorder()
+	server.HandleRedriveNotifications(rec, httptest.NewRequest(http.MethodPost, "/api/ops/notifications/redrive", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/ops/notifications/redrive?ids=2", nil)
+	server.HandleRedriveNotifications(rec, asUser(req, "admin-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"redriven":1}`, rec.Body.String())
+	require.Len(t, outbox.failed, 1)
+	assert.Equal(t, int64(1), outbox.failed[0].ID)
+
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionNotificationRedrive, actions.actions[0].Action)
+	assert.Equal(t, "admin-1", actions.actions[0].Actor)
+	assert.Equal(t, "2", actions.actions[0].Target)
+}
+
+func TestHandleRedriveNotifications_NotEnabled(t *testing.T) {
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, nil, notifications.NewWebhookNotifier(nil, ""))
+
+	rec := httptest.NewRecorder()
+	server.HandleRedriveNotifications(rec, httptest.NewRequest(http.MethodPost, "/api/ops/notifications/redrive?all=true", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
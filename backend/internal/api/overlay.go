@@ -0,0 +1,292 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// maxOverlayTTL bounds how long an issued overlay URL stays valid
+const maxOverlayTTL = 365 * 24 * time.Hour
+
+// overlayConfig signs overlay URLs. Rotating the secret revokes every URL
+// issued with the old one.
+type overlayConfig struct {
+	secret       []byte
+	defaultTTL   time.Duration
+	pushInterval time.Duration
+}
+
+// OverlayPayload is the minimal public view of a session served to overlay
+// widgets such as OBS browser sources
+type OverlayPayload struct {
+	Type            string                        `json:"type"`
+	SessionID       string                        `json:"session_id"`
+	ActiveUsers     int                           `json:"active_users"`
+	TotalReactions  int64                         `json:"total_reactions"`
+	ReactionCounts  map[events.ReactionType]int64 `json:"reaction_counts"`
+	LatestMilestone *OverlayMilestone             `json:"latest_milestone,omitempty"`
+}
+
+// OverlayMilestone is the most recently achieved milestone of a session
+type OverlayMilestone struct {
+	Type         milestones.MilestoneType `json:"type"`
+	Threshold    int64                    `json:"threshold"`
+	Description  string                   `json:"description"`
+	AchievedAt   time.Time                `json:"achieved_at"`
+	Presentation *milestones.Presentation `json:"presentation,omitempty"`
+}
+
+// OverlayGrant is a signed overlay URL issued to a producer
+type OverlayGrant struct {
+	SessionID string    `json:"session_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	URL       string    `json:"url"`
+	WSURL     string    `json:"ws_url"`
+}
+
+// SetOverlaySecret enables overlay endpoints, signing their URLs with secret.
+// URLs last defaultTTL unless issued with another; connected overlays are
+// sent updates at most every pushInterval.
+func (s *Server) SetOverlaySecret(secret string, defaultTTL, pushInterval time.Duration) {
+	if secret == "" {
+		s.overlay = nil
+		return
+	}
+	s.overlay = &overlayConfig{secret: []byte(secret), defaultTTL: defaultTTL, pushInterval: pushInterval}
+}
+
+// signOverlay computes the signature binding a session to an expiry
+func (o *overlayConfig) signOverlay(sessionID, expires string) string {
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write([]byte(sessionID + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issue creates a token granting read access to one session's overlay
+func (o *overlayConfig) issue(sessionID string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + o.signOverlay(sessionID, expires)
+}
+
+// verify checks a token against the session it was presented for and
+// returns when it expires
+func (o *overlayConfig) verify(sessionID, token string, now time.Time) (time.Time, error) {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: malformed overlay token", errs.ErrUnauthorized)
+	}
+	if !hmac.Equal([]byte(signature), []byte(o.signOverlay(sessionID, expires))) {
+		return time.Time{}, fmt.Errorf("%w: invalid overlay token", errs.ErrUnauthorized)
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed overlay token", errs.ErrUnauthorized)
+	}
+	expiresAt := time.Unix(unix, 0).UTC()
+	if !now.Before(expiresAt) {
+		return time.Time{}, fmt.Errorf("%w: overlay token expired", errs.ErrUnauthorized)
+	}
+	return expiresAt, nil
+}
+
+// authorizeOverlay validates an overlay request's session and token
+func (s *Server) authorizeOverlay(r *http.Request) (string, time.Time, error) {
+	sessionID := r.URL.Query().Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt, err := s.overlay.verify(sessionID, r.URL.Query().Get("token"), time.Now())
+	return sessionID, expiresAt, err
+}
+
+// overlayPayload builds a session's overlay view. Unknown sessions report
+// zero counts, like the stats endpoint.
+func (s *Server) overlayPayload(sessionID string) OverlayPayload {
+	payload := OverlayPayload{
+		Type:           "overlay",
+		SessionID:      sessionID,
+		ReactionCounts: map[events.ReactionType]int64{},
+	}
+	if stats, exists := s.aggManager.GetSession(sessionID); exists {
+		snapshot := stats.GetSnapshot()
+		payload.ActiveUsers = snapshot.ActiveUserCount
+		payload.TotalReactions = snapshot.TotalReactions
+		payload.ReactionCounts = snapshot.ReactionCounts
+	}
+	if s.tracker != nil {
+		for _, m := range s.tracker.GetAchievedMilestones(sessionID) {
+			if m.AchievedAt == nil || (payload.LatestMilestone != nil && !m.AchievedAt.After(payload.LatestMilestone.AchievedAt)) {
+				continue
+			}
+			payload.LatestMilestone = &OverlayMilestone{
+				Type:         m.Type,
+				Threshold:    m.Threshold,
+				Description:  m.Description,
+				AchievedAt:   *m.AchievedAt,
+				Presentation: m.Presentation,
+			}
+		}
+	}
+	return payload
+}
+
+// HandleCreateOverlayURL issues a signed, read-only overlay URL for
+// ?session_id=, valid for ?ttl= (a duration) or the configured default
+func (s *Server) HandleCreateOverlayURL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.overlay == nil {
+		writeError(w, errs.NotFound("overlays are not enabled"))
+		return
+	}
+
+	params := r.URL.Query()
+	sessionID := params.Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	ttl := s.overlay.defaultTTL
+	if val := params.Get("ttl"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 || d > maxOverlayTTL {
+			writeError(w, errs.Validation("ttl must be a positive duration of at most %s", maxOverlayTTL))
+			return
+		}
+		ttl = d
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	token := s.overlay.issue(sessionID, expiresAt)
+	query := url.Values{"session_id": {sessionID}, "token": {token}}.Encode()
+	grant := OverlayGrant{
+		SessionID: sessionID,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		URL:       "/api/overlay?" + query,
+		WSURL:     "/ws/overlay?" + query,
+	}
+	s.recordAction(r, ActionOverlayIssue, sessionID, "", map[string]interface{}{"expires_at": expiresAt})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grant)
+}
+
+// HandleGetOverlay serves a session's overlay payload to holders of a
+// signed overlay URL. It needs no user authentication.
+func (s *Server) HandleGetOverlay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.overlay == nil {
+		writeError(w, errs.NotFound("overlays are not enabled"))
+		return
+	}
+	sessionID, _, err := s.authorizeOverlay(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.overlayPayload(sessionID))
+}
+
+// HandleOverlayWebSocket streams a session's overlay payload to holders of a
+// signed overlay URL, sending it on connect and whenever it changes. The
+// connection is read-only and closes when the token expires.
+func (s *Server) HandleOverlayWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.overlay == nil {
+		writeError(w, errs.NotFound("overlays are not enabled"))
+		return
+	}
+	sessionID, expiresAt, err := s.authorizeOverlay(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Overlay WebSocket upgrade error: %v", err)
+		return
+	}
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+	streamReadOnly(conn, s.overlay.pushInterval, expiry.C, "overlay token expired", func() interface{} {
+		return s.overlayPayload(sessionID)
+	})
+}
+
+// streamReadOnly sends build's payload on connect and whenever it changes,
+// checking every interval, until the client goes away or expired fires. The
+// connection is closed on return.
+func streamReadOnly(conn *websocket.Conn, interval time.Duration, expired <-chan time.Time, expiredReason string, build func() interface{}) {
+	defer conn.Close()
+
+	// Read-only clients send nothing but pongs; reading ends the stream when they go away
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(heartbeat.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(heartbeat.PongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	push := time.NewTicker(interval)
+	defer push.Stop()
+	ping := time.NewTicker(heartbeat.PingInterval)
+	defer ping.Stop()
+
+	var last []byte
+	for {
+		payload, _ := json.Marshal(build())
+		if string(payload) != string(last) {
+			conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+			last = payload
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-expired:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, expiredReason), time.Now().Add(time.Second))
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat.WriteTimeout)); err != nil {
+				return
+			}
+		case <-push.C:
+		}
+	}
+}
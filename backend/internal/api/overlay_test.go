@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOverlayTestServer() (*Server, *aggregation.Manager) {
+	manager := aggregation.NewManager()
+	server := NewServer(nil, manager, nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	server.SetOverlaySecret("test-secret", time.Hour, 10*time.Millisecond)
+	return server, manager
+}
+
+func TestHandleGetOverlay_ServesSignedURLs(t *testing.T) {
+	server, manager := newOverlayTestServer()
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	manager.ProcessEvent(events.JoinSessionEvent("s1", "u1"))
+	manager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	server.HandleCreateOverlayURL(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/overlay?session_id=s1&ttl=2h", nil), "producer-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var grant OverlayGrant
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&grant))
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), grant.ExpiresAt, time.Minute)
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionOverlayIssue, actions.actions[0].Action)
+
+	rec = httptest.NewRecorder()
+	server.HandleGetOverlay(rec, httptest.NewRequest(http.MethodGet, grant.URL, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var payload OverlayPayload
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&payload))
+	assert.Equal(t, 1, payload.ActiveUsers)
+	assert.Equal(t, int64(1), payload.TotalReactions)
+
+	// The token only opens the session it was issued for
+	rec = httptest.NewRecorder()
+	server.HandleGetOverlay(rec, httptest.NewRequest(http.MethodGet, "/api/overlay?session_id=s2&token="+grant.Token, nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	var failure ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&failure))
+	assert.Equal(t, errs.CodeUnauthorized, failure.Code)
+}
+
+func TestHandleGetOverlay_RejectsExpiredTokens(t *testing.T) {
+	server, _ := newOverlayTestServer()
+	token := server.overlay.issue("s1", time.Now().Add(-time.Second))
+
+	rec := httptest.NewRecorder()
+	server.HandleGetOverlay(rec, httptest.NewRequest(http.MethodGet, "/api/overlay?session_id=s1&token="+token, nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandleOverlayWebSocket_PushesChanges(t *testing.T) {
+	server, manager := newOverlayTestServer()
+	token := server.overlay.issue("s1", time.Now().Add(time.Hour))
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleOverlayWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?session_id=s1&token="+token, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var payload OverlayPayload
+	require.NoError(t, conn.ReadJSON(&payload))
+	assert.Equal(t, int64(0), payload.TotalReactions)
+
+	manager.ProcessEvent(events.ReactionEvent("s1", "u1", events.ReactionFire))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, conn.ReadJSON(&payload))
+	assert.Equal(t, "overlay", payload.Type)
+	assert.Equal(t, int64(1), payload.TotalReactions)
+}
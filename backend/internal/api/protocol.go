@@ -0,0 +1,372 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Broadcast protocol versions. Version 1 is the original unversioned format;
+// version 2 wraps every message in an envelope naming its channel; version 3
+// adds the session, a sequence number and a timestamp to the envelope so
+// clients can detect missed messages and resync.
+const (
+	ProtocolV1             = 1
+	ProtocolV2             = 2
+	ProtocolV3             = 3
+	CurrentProtocolVersion = ProtocolV3
+)
+
+// serverVersions lists the protocol versions reported in welcome messages
+var serverVersions = []int{ProtocolV1, ProtocolV2, ProtocolV3}
+
+// Channels clients can subscribe to with a hello message
+const (
+	ChannelTicker     = "ticker"     // compact running totals
+	ChannelSnapshots  = "snapshots"  // full stats snapshots
+	ChannelMilestones = "milestones" // milestone achievements and unlocks
+	ChannelPolls      = "polls"      // live polls
+	ChannelChat       = "chat"       // chat messages
+)
+
+// supportedChannels lists every channel in the order reported to clients
+var supportedChannels = []string{ChannelTicker, ChannelSnapshots, ChannelMilestones, ChannelPolls, ChannelChat}
+
+// channelFor maps a broadcast message type to its channel. Messages without
+// a channel (errors, lifecycle notices) are always delivered.
+func channelFor(msgType string) string {
+	switch msgType {
+	case "stats_update":
+		return ChannelSnapshots
+	case MessageTypeMilestoneAchieved, MessageTypeMilestoneUnlocked, MessageTypeCampaignMilestoneAchieved:
+		return ChannelMilestones
+	case "chat":
+		return ChannelChat
+	}
+	if strings.HasPrefix(msgType, "poll_") {
+		return ChannelPolls
+	}
+	return ""
+}
+
+// capabilities describes what a connected client negotiated
+type capabilities struct {
+	version  int
+	channels map[string]bool
+
+	// fields projects stats_update snapshots down to these dotted paths
+	// (e.g. "reaction_counts.fire"); empty sends full snapshots
+	fields    []string
+	fieldsKey string
+}
+
+// maxProjectedFields bounds how many snapshot fields a client may select
+const maxProjectedFields = 32
+
+// setFields normalizes the snapshot fields a client selected, returning
+// the ones that were rejected as malformed
+func (c *capabilities) setFields(requested []string) []string {
+	seen := make(map[string]bool, len(requested))
+	var rejected []string
+	for _, field := range requested {
+		parts := strings.Split(field, ".")
+		valid := len(parts) <= 2 && !seen[field] && len(c.fields) < maxProjectedFields
+		for _, part := range parts {
+			valid = valid && part != ""
+		}
+		if !valid {
+			rejected = append(rejected, field)
+			continue
+		}
+		seen[field] = true
+		c.fields = append(c.fields, field)
+	}
+	sort.Strings(c.fields)
+	c.fieldsKey = strings.Join(c.fields, ",")
+	return rejected
+}
+
+// defaultCapabilities matches what clients received before negotiation
+// existed, so clients that never say hello keep working unchanged
+func defaultCapabilities() capabilities {
+	return capabilities{
+		version: ProtocolV1,
+		channels: map[string]bool{
+			ChannelSnapshots:  true,
+			ChannelMilestones: true,
+			ChannelPolls:      true,
+			ChannelChat:       true,
+		},
+	}
+}
+
+// negotiate builds capabilities from a client's hello, returning the channels
+// that were not recognized
+func negotiate(requestedVersion int, requestedChannels []string) (capabilities, []string) {
+	caps := defaultCapabilities()
+	if requestedVersion > CurrentProtocolVersion {
+		requestedVersion = CurrentProtocolVersion
+	}
+	if requestedVersion >= ProtocolV1 {
+		caps.version = requestedVersion
+	}
+	if requestedChannels == nil {
+		return caps, nil
+	}
+
+	known := make(map[string]bool, len(supportedChannels))
+	for _, channel := range supportedChannels {
+		known[channel] = true
+	}
+	var unsupported []string
+	caps.channels = make(map[string]bool, len(requestedChannels))
+	for _, channel := range requestedChannels {
+		if known[channel] {
+			caps.channels[channel] = true
+		} else {
+			unsupported = append(unsupported, channel)
+		}
+	}
+	return caps, unsupported
+}
+
+// channelList returns the subscribed channels in a stable order
+func (c capabilities) channelList() []string {
+	channels := make([]string, 0, len(c.channels))
+	for _, channel := range supportedChannels {
+		if c.channels[channel] {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// outboundMessage is a broadcast encoded once per format so each client can
+// receive the variant it negotiated without re-marshaling per client
+type outboundMessage struct {
+	msgType string
+	legacy  []byte
+	ticker  []byte
+	wrapped map[string][]byte // channel -> v2 envelope
+
+	// delivered, if set, is called once the hub has fanned the message out
+	delivered func(recipients, dropped int)
+
+	// recipient, if set, limits delivery to that user's connections
+	recipient string
+
+	// disconnect closes the recipients' connections once the message is sent
+	disconnect bool
+
+	// Set by the session hub as it fans the message out, for v3 envelopes
+	sessionID string
+	seq       uint64
+	sentAt    time.Time
+
+	projected map[string][]byte   // fields key -> projected stats_update
+	sequenced map[string]Envelope // fields key -> v3 envelope without prev_seq
+}
+
+// newOutboundMessage encodes a broadcast in every format clients may need
+func newOutboundMessage(message interface{}) (*outboundMessage, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(data, &header)
+
+	out := &outboundMessage{
+		msgType:   header.Type,
+		legacy:    data,
+		wrapped:   make(map[string][]byte),
+		projected: make(map[string][]byte),
+		sequenced: make(map[string]Envelope),
+	}
+	if header.Type == "stats_update" {
+		out.ticker = tickerFrom(data)
+	}
+	return out, nil
+}
+
+// tickerFrom derives the compact ticker message from a stats update
+func tickerFrom(statsUpdate []byte) []byte {
+	var update struct {
+		Snapshot struct {
+			ActiveUserCount int   `json:"active_user_count"`
+			TotalReactions  int64 `json:"total_reactions"`
+		} `json:"snapshot"`
+		ReactionDeltas map[string]int64 `json:"reaction_deltas"`
+		RenderHints    json.RawMessage  `json:"render_hints,omitempty"`
+	}
+	if err := json.Unmarshal(statsUpdate, &update); err != nil {
+		log.Printf("Error deriving ticker message: %v", err)
+		return nil
+	}
+	ticker := map[string]interface{}{
+		"type":              "ticker",
+		"active_user_count": update.Snapshot.ActiveUserCount,
+		"total_reactions":   update.Snapshot.TotalReactions,
+		"reaction_deltas":   update.ReactionDeltas,
+	}
+	if len(update.RenderHints) > 0 {
+		ticker["render_hints"] = update.RenderHints
+	}
+	data, _ := json.Marshal(ticker)
+	return data
+}
+
+// encodeFor returns the messages a client with the given capabilities
+// should receive for this broadcast. prevSeq is the sequence number of the
+// last message the client was sent, echoed in v3 envelopes.
+func (o *outboundMessage) encodeFor(caps capabilities, prevSeq uint64) [][]byte {
+	channel := channelFor(o.msgType)
+	var messages [][]byte
+	if channel == "" || caps.channels[channel] {
+		payload, key := o.legacy, channel
+		if o.msgType == "stats_update" && len(caps.fields) > 0 {
+			payload, key = o.project(caps), channel+"|"+caps.fieldsKey
+		}
+		messages = append(messages, o.format(caps.version, channel, key, payload, prevSeq))
+	}
+	if o.ticker != nil && caps.channels[ChannelTicker] {
+		messages = append(messages, o.format(caps.version, ChannelTicker, ChannelTicker, o.ticker, prevSeq))
+	}
+	return messages
+}
+
+// project returns the stats update reduced to the client's selected
+// snapshot fields. Reaction deltas follow any reaction_counts selection.
+func (o *outboundMessage) project(caps capabilities) []byte {
+	if cached, ok := o.projected[caps.fieldsKey]; ok {
+		return cached
+	}
+
+	var update map[string]json.RawMessage
+	var snapshot map[string]json.RawMessage
+	if err := json.Unmarshal(o.legacy, &update); err != nil || json.Unmarshal(update["snapshot"], &snapshot) != nil {
+		return o.legacy
+	}
+
+	projected := make(map[string]interface{})
+	var reactionTypes []string
+	for _, field := range caps.fields {
+		top, sub, nested := strings.Cut(field, ".")
+		value, exists := snapshot[top]
+		if !exists {
+			continue
+		}
+		if !nested {
+			projected[top] = value
+			continue
+		}
+		if _, whole := projected[top].(json.RawMessage); whole {
+			continue // the whole object was already selected
+		}
+		var inner map[string]json.RawMessage
+		if json.Unmarshal(value, &inner) != nil {
+			continue
+		}
+		if subValue, exists := inner[sub]; exists {
+			parent, _ := projected[top].(map[string]json.RawMessage)
+			if parent == nil {
+				parent = make(map[string]json.RawMessage)
+				projected[top] = parent
+			}
+			parent[sub] = subValue
+		}
+		if top == "reaction_counts" {
+			reactionTypes = append(reactionTypes, sub)
+		}
+	}
+
+	data, _ := json.Marshal(projected)
+	update["snapshot"] = data
+	if reactionTypes != nil {
+		var deltas map[string]json.RawMessage
+		if json.Unmarshal(update["reaction_deltas"], &deltas) == nil {
+			filtered := make(map[string]json.RawMessage, len(reactionTypes))
+			for _, reactionType := range reactionTypes {
+				if delta, exists := deltas[reactionType]; exists {
+					filtered[reactionType] = delta
+				}
+			}
+			update["reaction_deltas"], _ = json.Marshal(filtered)
+		}
+	}
+	out, _ := json.Marshal(update)
+	o.projected[caps.fieldsKey] = out
+	return out
+}
+
+// format applies the protocol version's framing to a payload, caching the
+// framed result under key
+func (o *outboundMessage) format(version int, channel, key string, payload []byte, prevSeq uint64) []byte {
+	if version < ProtocolV2 {
+		return payload
+	}
+	var msgType string
+	if channel == ChannelTicker {
+		msgType = "ticker"
+	} else {
+		msgType = o.msgType
+	}
+	if version >= ProtocolV3 {
+		return o.sequence(msgType, channel, key, payload, prevSeq)
+	}
+	if cached, ok := o.wrapped[key]; ok {
+		return cached
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"v":       ProtocolV2,
+		"type":    msgType,
+		"channel": channel,
+		"data":    json.RawMessage(payload),
+	})
+	o.wrapped[key] = data
+	return data
+}
+
+// Envelope is the v3 framing of every message sent through a session hub.
+// Seq increases by one for each message the hub sends to any of the
+// session's connections, so a client only sees some of the numbers; PrevSeq
+// is the seq of the message the same connection received before this one,
+// zero for its first. A client that last received a seq other than PrevSeq
+// missed messages, e.g. across a reconnect, and should send a resync. The
+// snapshot answering a resync carries the seq of the session's latest
+// message rather than a number of its own.
+type Envelope struct {
+	V         int             `json:"v"`
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	SessionID string          `json:"session_id"`
+	Seq       uint64          `json:"seq"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+	PrevSeq   uint64          `json:"prev_seq"`
+}
+
+// sequence frames a payload in a v3 envelope. Everything but prev_seq is
+// shared by every connection and built once per key.
+func (o *outboundMessage) sequence(msgType, channel, key string, payload []byte, prevSeq uint64) []byte {
+	envelope, ok := o.sequenced[key]
+	if !ok {
+		envelope = Envelope{
+			V:         ProtocolV3,
+			Type:      msgType,
+			Channel:   channel,
+			SessionID: o.sessionID,
+			Seq:       o.seq,
+			Timestamp: o.sentAt,
+			Payload:   payload,
+		}
+		o.sequenced[key] = envelope
+	}
+	envelope.PrevSeq = prevSeq
+	data, _ := json.Marshal(envelope)
+	return data
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsUpdateMessage() map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "stats_update",
+		"snapshot":        map[string]interface{}{"active_user_count": 3, "total_reactions": 42},
+		"reaction_deltas": map[string]int64{"fire": 2},
+		"render_hints":    RenderHints{ReactionsPerSecond: 90, AnimationEvery: 5},
+	}
+}
+
+func TestOutboundMessage_LegacyClientsReceiveUnchangedPayload(t *testing.T) {
+	out, err := newOutboundMessage(statsUpdateMessage())
+	require.NoError(t, err)
+
+	legacy, _ := json.Marshal(statsUpdateMessage())
+	messages := out.encodeFor(defaultCapabilities(), 0)
+	require.Len(t, messages, 1)
+	assert.JSONEq(t, string(legacy), string(messages[0]))
+}
+
+func TestOutboundMessage_TickerOnlyV2Client(t *testing.T) {
+	caps, unsupported := negotiate(2, []string{"ticker", "karaoke"})
+	assert.Equal(t, []string{"karaoke"}, unsupported)
+
+	out, err := newOutboundMessage(statsUpdateMessage())
+	require.NoError(t, err)
+	messages := out.encodeFor(caps, 0)
+	require.Len(t, messages, 1, "full snapshots were not requested")
+
+	var envelope struct {
+		V       int             `json:"v"`
+		Type    string          `json:"type"`
+		Channel string          `json:"channel"`
+		Data    json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(messages[0], &envelope))
+	assert.Equal(t, ProtocolV2, envelope.V)
+	assert.Equal(t, ChannelTicker, envelope.Channel)
+	assert.JSONEq(t, `{"type":"ticker","active_user_count":3,"total_reactions":42,"reaction_deltas":{"fire":2},"render_hints":{"reactions_per_second":90,"animation_every":5}}`, string(envelope.Data))
+
+	// Unsubscribed channels are filtered, channel-less notices always arrive
+	chat, _ := newOutboundMessage(map[string]interface{}{"type": "chat"})
+	assert.Empty(t, chat.encodeFor(caps, 0))
+	ended, _ := newOutboundMessage(map[string]interface{}{"type": "session_ended"})
+	assert.Len(t, ended.encodeFor(caps, 0), 1)
+}
+
+func TestNegotiate_ClampsVersion(t *testing.T) {
+	caps, _ := negotiate(99, nil)
+	assert.Equal(t, CurrentProtocolVersion, caps.version)
+	assert.Equal(t, defaultCapabilities().channels, caps.channels)
+
+	caps, _ = negotiate(0, nil)
+	assert.Equal(t, ProtocolV1, caps.version)
+}
+
+func TestOutboundMessage_ProjectsSelectedSnapshotFields(t *testing.T) {
+	caps, _ := negotiate(1, nil)
+	rejected := caps.setFields([]string{"reaction_counts.fire", "active_user_count", "a.b.c", ""})
+	assert.Equal(t, []string{"a.b.c", ""}, rejected)
+
+	out, err := newOutboundMessage(map[string]interface{}{
+		"type": "stats_update",
+		"snapshot": map[string]interface{}{
+			"active_user_count": 3,
+			"total_reactions":   42,
+			"reaction_counts":   map[string]int64{"fire": 30, "like": 12},
+		},
+		"reaction_deltas":  map[string]int64{"fire": 2, "like": 1},
+		"next_interval_ms": 250,
+	})
+	require.NoError(t, err)
+
+	messages := out.encodeFor(caps, 0)
+	require.Len(t, messages, 1)
+	assert.JSONEq(t, `{
+		"type": "stats_update",
+		"snapshot": {"active_user_count": 3, "reaction_counts": {"fire": 30}},
+		"reaction_deltas": {"fire": 2},
+		"next_interval_ms": 250
+	}`, string(messages[0]))
+
+	// Clients without a selection still receive the full snapshot
+	full := out.encodeFor(defaultCapabilities(), 0)
+	assert.Contains(t, string(full[0]), `"total_reactions":42`)
+}
+
+func TestHandleHello_DoesNotBlockOnAClosedOrFullBuffer(t *testing.T) {
+	client := &Client{send: make(chan []byte, 1)}
+	hello := map[string]interface{}{"type": "hello", "protocol_version": float64(2)}
+
+	client.handleHello(hello)
+	require.Len(t, client.send, 1)
+	assert.Contains(t, string(<-client.send), `"welcome"`)
+
+	// A full buffer drops the welcome instead of stalling the read pump
+	client.send <- []byte("{}")
+	client.handleHello(hello)
+	assert.Len(t, client.send, 1)
+
+	// So does a client the hub already dropped
+	<-client.send
+	client.closeSend()
+	assert.NotPanics(t, func() { client.handleHello(hello) })
+}
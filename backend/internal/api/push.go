@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// maxPushTokenLength bounds device tokens; FCM's are the longest at a few
+// hundred characters
+const maxPushTokenLength = 4096
+
+// PushSubscriptions persists the devices subscribed to each session's push
+// alerts
+type PushSubscriptions interface {
+	AddPushSubscription(ctx context.Context, sub storage.PushSubscription) error
+	RemovePushSubscription(ctx context.Context, userID, sessionID, token string) error
+}
+
+// SetPushSubscriptions enables push alert subscriptions, stored in subs and
+// sent by push
+func (s *Server) SetPushSubscriptions(subs PushSubscriptions, push *notifications.PushNotifier) {
+	s.pushSubs = subs
+	s.push = push
+}
+
+// PushSubscriptionRequest represents a device subscribing to a session
+type PushSubscriptionRequest struct {
+	Platform string `json:"platform"` // "fcm" or "apns"
+	Token    string `json:"token"`
+}
+
+// HandlePushSubscription subscribes a device of the user to the push alerts
+// of ?session_id=, sent when it goes live and when it reaches a milestone,
+// or unsubscribes it with DELETE. Sessions may be subscribed to before they
+// are created, so followers of a recurring show hear when it starts.
+func (s *Server) HandlePushSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	userIDVal := r.Context().Value("user_id")
+	if userIDVal == nil {
+		writeError(w, errs.ErrUnauthorized)
+		return
+	}
+	userID := userIDVal.(string)
+
+	if s.pushSubs == nil {
+		writeError(w, errs.NotFound("push notifications are not configured"))
+		return
+	}
+	sessionID := r.URL.Query().Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req PushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	if len(req.Token) > maxPushTokenLength {
+		writeError(w, errs.Validation("token must be at most %d characters", maxPushTokenLength))
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if !s.push.Supports(req.Platform) {
+			writeError(w, errs.Validation("platform %q is not configured for push notifications", req.Platform))
+			return
+		}
+		err := s.pushSubs.AddPushSubscription(r.Context(), storage.PushSubscription{
+			UserID:    userID,
+			SessionID: sessionID,
+			Platform:  req.Platform,
+			Token:     req.Token,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	} else if err := s.pushSubs.RemovePushSubscription(r.Context(), userID, sessionID, req.Token); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPushSubscriptions is an in-process PushSubscriptions
+type memoryPushSubscriptions struct {
+	subs map[string]storage.PushSubscription // by session ID and token
+}
+
+func (m *memoryPushSubscriptions) AddPushSubscription(_ context.Context, sub storage.PushSubscription) error {
+	m.subs[sub.SessionID+"/"+sub.Token] = sub
+	return nil
+}
+
+func (m *memoryPushSubscriptions) RemovePushSubscription(_ context.Context, userID, sessionID, token string) error {
+	if m.subs[sessionID+"/"+token].UserID == userID {
+		delete(m.subs, sessionID+"/"+token)
+	}
+	return nil
+}
+
+// apnsOnly is a push provider that is never asked to send
+type apnsOnly struct{}
+
+func (apnsOnly) Platform() string { return notifications.PlatformAPNs }
+
+func (apnsOnly) Send(context.Context, string, notifications.PushMessage) error { return nil }
+
+func TestHandlePushSubscription(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil, nil, nil, nil)
+	subscribe := func(method, userID, sessionID, body string) int {
+		req := httptest.NewRequest(method, "/api/sessions/push?session_id="+sessionID, strings.NewReader(body))
+		if userID != "" {
+			req = asUser(req, userID)
+		}
+		rec := httptest.NewRecorder()
+		server.HandlePushSubscription(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNotFound, subscribe(http.MethodPost, "u1", "weekly-show", `{"platform":"apns","token":"t1"}`), "push is not configured")
+
+	subs := &memoryPushSubscriptions{subs: make(map[string]storage.PushSubscription)}
+	server.SetPushSubscriptions(subs, notifications.NewPushNotifier(nil, apnsOnly{}))
+
+	assert.Equal(t, http.StatusUnauthorized, subscribe(http.MethodPost, "", "weekly-show", `{"platform":"apns","token":"t1"}`))
+	assert.Equal(t, http.StatusBadRequest, subscribe(http.MethodPost, "u1", "", `{"platform":"apns","token":"t1"}`))
+	assert.Equal(t, http.StatusBadRequest, subscribe(http.MethodPost, "u1", "weekly-show", `{"platform":"fcm","token":"t1"}`), "platform without a provider")
+	assert.Equal(t, http.StatusBadRequest, subscribe(http.MethodPost, "u1", "weekly-show", `{"platform":"apns"}`))
+
+	// Sessions can be subscribed to before they exist
+	assert.Equal(t, http.StatusOK, subscribe(http.MethodPost, "u1", "weekly-show", `{"platform":"apns","token":"t1"}`))
+	require.Contains(t, subs.subs, "weekly-show/t1")
+	assert.Equal(t, "u1", subs.subs["weekly-show/t1"].UserID)
+
+	assert.Equal(t, http.StatusOK, subscribe(http.MethodDelete, "u2", "weekly-show", `{"token":"t1"}`))
+	assert.Contains(t, subs.subs, "weekly-show/t1", "users only unsubscribe their own devices")
+	assert.Equal(t, http.StatusOK, subscribe(http.MethodDelete, "u1", "weekly-show", `{"token":"t1"}`))
+	assert.Empty(t, subs.subs)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// RateLimits are request quotas for the HTTP API, counted per endpoint over
+// fixed windows. Zero leaves a quota unlimited.
+type RateLimits struct {
+	Window    time.Duration
+	Global    int            // all callers together
+	PerClient int            // each API key, user or client IP
+	Endpoints map[string]int // path -> per-client quota override
+	APIKeys   map[string]int // X-API-Key value -> per-client quota override
+}
+
+// RateLimiter enforces RateLimits and reports each caller's quota in
+// X-RateLimit-* headers. It is separate from the fraud guard's per-user
+// reaction limits and protects read APIs from polling storms.
+type RateLimiter struct {
+	limits RateLimits
+	now    func() time.Time
+	start  time.Time      // start of the current window
+	counts map[string]int // endpoint or endpoint|caller -> requests this window
+	mu     sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter enforcing limits
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		limits: limits,
+		now:    time.Now,
+		counts: make(map[string]int),
+	}
+}
+
+// caller identifies who a request counts against and their quota. Only
+// configured API keys are honored, so unknown keys count against the
+// signed-in user or, failing that, the IP.
+func (l *RateLimiter) caller(r *http.Request) (string, int) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		if quota, known := l.limits.APIKeys[key]; known {
+			return "key:" + key, quota
+		}
+	}
+	quota := l.limits.PerClient
+	if override, exists := l.limits.Endpoints[r.URL.Path]; exists {
+		quota = override
+	}
+	if userID, _ := r.Context().Value("user_id").(string); userID != "" {
+		return "user:" + userID, quota
+	}
+	return "ip:" + clientIP(r), quota
+}
+
+// take counts a request against the endpoint's global quota and the
+// caller's quota, returning the caller's remaining requests, when the
+// window resets and whether the request may proceed
+func (l *RateLimiter) take(endpoint, caller string, quota int) (int, time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if start := now.Truncate(l.limits.Window); !start.Equal(l.start) {
+		l.start = start
+		l.counts = make(map[string]int)
+	}
+	reset := l.start.Add(l.limits.Window)
+
+	key := endpoint + "|" + caller
+	used := l.counts[key]
+	if quota > 0 && used >= quota {
+		return 0, reset, false
+	}
+	if l.limits.Global > 0 && l.counts[endpoint] >= l.limits.Global {
+		return max(quota-used, 0), reset, false
+	}
+	l.counts[key]++
+	l.counts[endpoint]++
+	return max(quota-used-1, 0), reset, true
+}
+
+// Middleware rejects requests over quota with 429. A nil limiter lets every
+// request through, so routes can be wrapped whether or not limiting is on.
+// Placed after ClerkMiddleware it counts signed-in callers by user ID.
+func (l *RateLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, quota := l.caller(r)
+		remaining, reset, ok := l.take(r.URL.Path, caller, quota)
+		if quota > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		}
+		if !ok {
+			retryAfter := int(reset.Sub(l.now()).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, errs.ErrRateLimited)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimiter(limits RateLimits, now *time.Time) http.HandlerFunc {
+	limiter := NewRateLimiter(limits)
+	limiter.now = func() time.Time { return *now }
+	return limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func rateLimitedGet(handler http.HandlerFunc, path, ip, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestRateLimiter_EnforcesPerClientQuotaWithHeaders(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 30, 0, time.UTC)
+	handler := newTestRateLimiter(RateLimits{Window: time.Minute, PerClient: 2}, &now)
+
+	rec := rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, strconv.FormatInt(time.Date(2026, 10, 14, 12, 1, 0, 0, time.UTC).Unix(), 10), rec.Header().Get("X-RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "").Code)
+	rec = rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "31", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	// Quotas are per endpoint and per client
+	assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/labels", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.2", "").Code)
+
+	// The next window starts afresh
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "").Code)
+}
+
+func TestRateLimiter_APIKeyAndEndpointOverridesAndGlobalQuota(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	handler := newTestRateLimiter(RateLimits{
+		Window:    time.Minute,
+		Global:    5,
+		PerClient: 1,
+		Endpoints: map[string]int{"/api/overlay": 0},
+		APIKeys:   map[string]int{"dashboard": 3},
+	}, &now)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "dashboard").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "dashboard").Code)
+
+	// Unknown keys count against the caller's IP
+	assert.Equal(t, http.StatusOK, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "guess").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedGet(handler, "/api/sessions/stats", "10.0.0.1", "guess").Code)
+
+	// A zero endpoint override leaves the endpoint unlimited per client,
+	// though the global quota still applies
+	for i := 0; i < 5; i++ {
+		rec := rateLimitedGet(handler, "/api/overlay", "10.0.0.9", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedGet(handler, "/api/overlay", "10.0.0.9", "").Code)
+}
+
+func TestRateLimiter_NilLimiterPassesThrough(t *testing.T) {
+	var limiter *RateLimiter
+	called := false
+	limiter.Middleware(func(w http.ResponseWriter, r *http.Request) { called = true })(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, called)
+}
+
+func TestRateLimiter_CountsSignedInCallersByUser(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	handler := newTestRateLimiter(RateLimits{Window: time.Minute, PerClient: 1}, &now)
+
+	get := func(ip, userID string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions/stats", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		handler(rec, asUser(req, userID))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("10.0.0.1", "user_a"))
+	// Moving to another address doesn't earn the user a fresh quota
+	assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.2", "user_a"))
+	// while other users behind the same address keep theirs
+	assert.Equal(t, http.StatusOK, get("10.0.0.1", "user_b"))
+}
+
+func TestRateLimiter_IgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	require.NoError(t, SetTrustedProxies([]string{"10.1.0.0/16"}))
+	defer SetTrustedProxies(nil)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	handler := newTestRateLimiter(RateLimits{Window: time.Minute, PerClient: 1}, &now)
+
+	get := func(ip, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions/stats", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	// A client talking to us directly can't mint quotas by forging the header
+	assert.Equal(t, http.StatusOK, get("203.0.113.7", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, get("203.0.113.7", "198.51.100.2"))
+
+	// Behind a trusted proxy the nearest untrusted hop is the caller, so a
+	// forged leftmost entry doesn't change who is counted
+	assert.Equal(t, http.StatusOK, get("10.1.0.5", "198.51.100.9, 192.0.2.4"))
+	assert.Equal(t, http.StatusTooManyRequests, get("10.1.0.6", "198.51.100.10, 192.0.2.4, 10.1.0.2"))
+	assert.Equal(t, http.StatusOK, get("10.1.0.5", "192.0.2.5"))
+}
+
+func TestClientIP_FallsBackToTheProxyWhenEveryHopIsTrusted(t *testing.T) {
+	require.NoError(t, SetTrustedProxies([]string{"10.1.0.0/16"}))
+	defer SetTrustedProxies(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.0.5:1234"
+	req.Header.Set("X-Forwarded-For", "10.1.3.3")
+	assert.Equal(t, "10.1.3.3", clientIP(req))
+
+	req.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.1.0.5", clientIP(req))
+
+	assert.Error(t, SetTrustedProxies([]string{"10.1.0.0"}))
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// Recompute job states
+const (
+	RecomputeRunning = "running"
+	RecomputeDone    = "done"
+	RecomputeFailed  = "failed"
+)
+
+// Recompute jobs are kept in memory; finished jobs beyond the limit are
+// forgotten oldest first
+const (
+	maxRecomputeJobs = 100
+	recomputeTimeout = time.Minute
+)
+
+// RecomputeJob rebuilds a session's derived metrics for a time range in the
+// background. Result is set once Status is done.
+type RecomputeJob struct {
+	ID         string                    `json:"id"`
+	SessionID  string                    `json:"session_id"`
+	Since      time.Time                 `json:"since"`
+	Until      time.Time                 `json:"until"`
+	Apply      bool                      `json:"apply"`
+	Status     string                    `json:"status"`
+	Error      string                    `json:"error,omitempty"`
+	CreatedAt  time.Time                 `json:"created_at"`
+	FinishedAt *time.Time                `json:"finished_at,omitempty"`
+	Result     *aggregation.RangeMetrics `json:"result,omitempty"`
+}
+
+// recomputeJobs tracks recompute jobs by ID
+type recomputeJobs struct {
+	jobs  map[string]RecomputeJob
+	order []string // job IDs, oldest first
+	mu    sync.Mutex
+}
+
+func newRecomputeJobs() *recomputeJobs {
+	return &recomputeJobs{jobs: make(map[string]RecomputeJob)}
+}
+
+// put stores a job, evicting the oldest finished jobs over the limit
+func (j *recomputeJobs) put(job RecomputeJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, exists := j.jobs[job.ID]; !exists {
+		j.order = append(j.order, job.ID)
+	}
+	j.jobs[job.ID] = job
+	for i := 0; len(j.order) > maxRecomputeJobs && i < len(j.order); {
+		if j.jobs[j.order[i]].Status == RecomputeRunning {
+			i++
+			continue
+		}
+		delete(j.jobs, j.order[i])
+		j.order = append(j.order[:i], j.order[i+1:]...)
+	}
+}
+
+// get returns a job by ID
+func (j *recomputeJobs) get(id string) (RecomputeJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, exists := j.jobs[id]
+	return job, exists
+}
+
+// runRecompute replays the session's logged events and finishes the job,
+// replacing the live timeline for the range when the job applies
+func (s *Server) runRecompute(job RecomputeJob, stats *aggregation.SessionStats) {
+	ctx, cancel := context.WithTimeout(context.Background(), recomputeTimeout)
+	defer cancel()
+
+	logged, err := s.auditor.LoadEvents(ctx, job.SessionID)
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		log.Printf("Recompute job %s for session %s failed: %v", job.ID, job.SessionID, err)
+		job.Status, job.Error = RecomputeFailed, "failed to load persisted events"
+		s.recomputes.put(job)
+		return
+	}
+
+	result := aggregation.RecomputeRange(stats.GetSnapshot().StartTime, job.Since, job.Until, logged)
+	if job.Apply {
+		stats.ReplaceMinutes(result.Timeline)
+	}
+	job.Status, job.Result = RecomputeDone, &result
+	s.recomputes.put(job)
+	log.Printf("Recompute job %s for session %s replayed %d events (applied: %v)", job.ID, job.SessionID, result.Events, job.Apply)
+}
+
+// HandleRecomputeSessionStats rebuilds a session's derived metrics (reaction
+// rates, the busiest minute and the prevailing reaction) from its persisted
+// events between ?since= and ?until= RFC 3339 timestamps. POST starts a job
+// and returns it with 202; ?apply=true also replaces the live per-minute
+// timeline for the range. GET ?job_id= returns the job and, once done, its
+// result. Only audited sessions have persisted events.
+func (s *Server) HandleRecomputeSessionStats(w http.ResponseWriter, r *http.Request) {
+	if s.auditor == nil {
+		writeError(w, errs.NotFound("audit mode is not enabled"))
+		return
+	}
+	params := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodGet:
+		job, exists := s.recomputes.get(params.Get("job_id"))
+		if !exists {
+			writeError(w, errs.NotFound("recompute job not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+
+	case http.MethodPost:
+		sessionID := params.Get("session_id")
+		if sessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		var since, until time.Time
+		for name, dst := range map[string]*time.Time{"since": &since, "until": &until} {
+			t, err := time.Parse(time.RFC3339, params.Get(name))
+			if err != nil {
+				writeError(w, errs.Validation("%s must be an RFC 3339 timestamp", name))
+				return
+			}
+			*dst = t
+		}
+		if !since.Before(until) {
+			writeError(w, errs.Validation("since must be before until"))
+			return
+		}
+		if until.After(time.Now()) {
+			writeError(w, errs.Validation("until must not be in the future"))
+			return
+		}
+		if !s.auditor.Sampled(sessionID) {
+			writeError(w, errs.NotFound("session is not audited, so its events are not persisted"))
+			return
+		}
+		stats, exists := s.aggManager.GetSession(sessionID)
+		if !exists {
+			writeError(w, errs.NotFound("session has no stats"))
+			return
+		}
+
+		job := RecomputeJob{
+			ID:        uuid.New().String(),
+			SessionID: sessionID,
+			Since:     since.UTC(),
+			Until:     until.UTC(),
+			Apply:     params.Get("apply") == "true",
+			Status:    RecomputeRunning,
+			CreatedAt: time.Now().UTC(),
+		}
+		s.recomputes.put(job)
+		s.recordAction(r, ActionSessionRecompute, sessionID, "", job)
+		go s.runRecompute(job, stats)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/audit"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryEventLog keeps audited events in memory
+type memoryEventLog struct {
+	payloads map[string][][]byte
+}
+
+func (m *memoryEventLog) AppendAuditEvent(_ context.Context, sessionID string, payload []byte) error {
+	m.payloads[sessionID] = append(m.payloads[sessionID], payload)
+	return nil
+}
+
+func (m *memoryEventLog) LoadAuditEvents(_ context.Context, sessionID string) ([][]byte, error) {
+	return m.payloads[sessionID], nil
+}
+
+func TestHandleRecomputeSessionStats_RunsJobAndReturnsResult(t *testing.T) {
+	manager := aggregation.NewManager()
+	stats := manager.GetOrCreateSession("s1")
+	stats.StartTime = stats.StartTime.Add(-10 * time.Minute)
+	auditor := audit.NewAuditor(manager, &memoryEventLog{payloads: map[string][][]byte{}}, 1)
+	for i := 0; i < 4; i++ {
+		event := events.ReactionEvent("s1", "u1", events.ReactionLove)
+		event.Timestamp = stats.StartTime.Add(2*time.Minute + time.Duration(i)*time.Second)
+		auditor.Record(context.Background(), event)
+	}
+
+	server := NewServer(nil, manager, nil, nil, nil, nil, nil, nil)
+	server.SetAuditor(auditor)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+
+	since := stats.StartTime.Add(time.Minute).Format(time.RFC3339)
+	until := stats.StartTime.Add(4 * time.Minute).Format(time.RFC3339)
+	w := httptest.NewRecorder()
+	server.HandleRecomputeSessionStats(w, asUser(httptest.NewRequest(http.MethodPost,
+		"/api/admin/sessions/recompute?session_id=s1&apply=true&since="+since+"&until="+until, nil), "admin-1"))
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var job RecomputeJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, RecomputeRunning, job.Status)
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionSessionRecompute, actions.actions[0].Action)
+
+	require.Eventually(t, func() bool {
+		job, _ = server.recomputes.get(job.ID)
+		return job.Status == RecomputeDone
+	}, time.Second, 5*time.Millisecond)
+
+	w = httptest.NewRecorder()
+	server.HandleRecomputeSessionStats(w, httptest.NewRequest(http.MethodGet, "/api/admin/sessions/recompute?job_id="+job.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var done RecomputeJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&done))
+	require.NotNil(t, done.Result)
+	assert.Equal(t, int64(4), done.Result.TotalReactions)
+	assert.Equal(t, events.ReactionLove, done.Result.Mood)
+	assert.Equal(t, int64(4), stats.GetReactionsByMinute()[2].Total)
+}
+
+func TestHandleRecomputeSessionStats_RejectsBadRanges(t *testing.T) {
+	manager := aggregation.NewManager()
+	manager.GetOrCreateSession("s1")
+	server := NewServer(nil, manager, nil, nil, nil, nil, nil, nil)
+	server.SetAuditor(audit.NewAuditor(manager, &memoryEventLog{payloads: map[string][][]byte{}}, 1))
+
+	now := time.Now()
+	for _, query := range []string{
+		"session_id=s1&since=yesterday&until=" + now.Format(time.RFC3339),
+		"session_id=s1&since=" + now.Format(time.RFC3339) + "&until=" + now.Add(-time.Hour).Format(time.RFC3339),
+		"session_id=s1&since=" + now.Add(-time.Hour).Format(time.RFC3339) + "&until=" + now.Add(time.Hour).Format(time.RFC3339),
+	} {
+		w := httptest.NewRecorder()
+		server.HandleRecomputeSessionStats(w, httptest.NewRequest(http.MethodPost, "/api/admin/sessions/recompute?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/cluster"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// SetHubRing enables sticky routing of WebSocket connections to hub nodes
+func (s *Server) SetHubRing(ring *cluster.Ring) {
+	s.hubRing = ring
+}
+
+// HandleGetHubRoute returns the hub node a session's WebSocket clients
+// should connect to. Clients that land elsewhere are still served, since
+// broadcasts are relayed to every node, but keeping a session on one node
+// avoids the relay hop for most of its audience.
+func (s *Server) HandleGetHubRoute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.hubRing == nil {
+		writeError(w, errs.NotFound("hub routing is not enabled"))
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	node := s.hubRing.Node(sessionID)
+	if node == "" {
+		writeError(w, errs.Unavailable("no hub nodes available"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"session_id": sessionID,
+		"node":       node,
+	})
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// schedulerTick is the resolution at which sessions are checked for emission
+const schedulerTick = 25 * time.Millisecond
+
+// BroadcastScheduler coalesces stat changes and emits one stats_update per
+// session at an adaptive rate: close to the minimum interval while the
+// audience is busy, backing off towards the maximum interval when quiet.
+type BroadcastScheduler struct {
+	hub         *WebSocketHub
+	manager     *aggregation.Manager
+	registry    *sessions.Registry
+	minInterval time.Duration
+	maxInterval time.Duration
+	sessions    map[string]*scheduledSession
+	mu          sync.Mutex
+
+	animationBudget float64 // reaction animations per second clients can render
+}
+
+// defaultAnimationBudget is used until SetAnimationBudget overrides it
+const defaultAnimationBudget = 20
+
+// scheduledSession tracks pending changes for a single session
+type scheduledSession struct {
+	dirty        bool
+	changes      int
+	deltas       map[events.ReactionType]int64
+	rate         float64 // smoothed changes per second
+	reactions    int
+	reactionRate float64 // smoothed reactions per second
+	lastEmit     time.Time
+}
+
+// NewBroadcastScheduler creates a scheduler with deployment-wide interval bounds
+func NewBroadcastScheduler(hub *WebSocketHub, manager *aggregation.Manager, registry *sessions.Registry, minInterval, maxInterval time.Duration) *BroadcastScheduler {
+	return &BroadcastScheduler{
+		hub:         hub,
+		manager:     manager,
+		registry:    registry,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+		sessions:    make(map[string]*scheduledSession),
+
+		animationBudget: defaultAnimationBudget,
+	}
+}
+
+// SetAnimationBudget sets how many reaction animations per second clients are
+// expected to render before the render hints ask them to skip reactions
+func (s *BroadcastScheduler) SetAnimationBudget(perSecond float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if perSecond > 0 {
+		s.animationBudget = perSecond
+	}
+}
+
+// session returns the pending state for a session, creating it if needed.
+// Callers must hold s.mu.
+func (s *BroadcastScheduler) session(sessionID string) *scheduledSession {
+	ss, exists := s.sessions[sessionID]
+	if !exists {
+		ss = &scheduledSession{deltas: make(map[events.ReactionType]int64)}
+		s.sessions[sessionID] = ss
+	}
+	return ss
+}
+
+// MarkChanged records that a session's stats changed
+func (s *BroadcastScheduler) MarkChanged(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss := s.session(sessionID)
+	ss.dirty = true
+	ss.changes++
+}
+
+// MarkReaction records a reaction to include in the next update's deltas
+func (s *BroadcastScheduler) MarkReaction(sessionID string, reactionType events.ReactionType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss := s.session(sessionID)
+	ss.dirty = true
+	ss.changes++
+	ss.reactions++
+	ss.deltas[reactionType]++
+}
+
+// bounds returns the interval bounds for a session, honoring per-session overrides
+func (s *BroadcastScheduler) bounds(sessionID string) (time.Duration, time.Duration) {
+	minInterval, maxInterval := s.minInterval, s.maxInterval
+	if s.registry != nil {
+		if session, ok := s.registry.Get(sessionID); ok {
+			if session.BroadcastMinInterval > 0 {
+				minInterval = session.BroadcastMinInterval
+			}
+			if session.BroadcastMaxInterval > 0 {
+				maxInterval = session.BroadcastMaxInterval
+			}
+		}
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return minInterval, maxInterval
+}
+
+// adaptiveInterval shrinks the interval as the change rate grows
+func adaptiveInterval(rate float64, minInterval, maxInterval time.Duration) time.Duration {
+	interval := time.Duration(float64(maxInterval) / (1 + rate))
+	if interval < minInterval {
+		return minInterval
+	}
+	return interval
+}
+
+// RenderHints tell clients how to thin out reaction animations so every
+// viewer degrades the same way when the audience outpaces rendering
+type RenderHints struct {
+	ReactionsPerSecond float64 `json:"reactions_per_second"`
+	AnimationEvery     int     `json:"animation_every"` // animate 1 of every N reactions
+}
+
+// renderHintsFor computes hints from the smoothed reaction rate
+func renderHintsFor(reactionRate, budget float64) RenderHints {
+	every := 1
+	if budget > 0 && reactionRate > budget {
+		every = int(math.Ceil(reactionRate / budget))
+	}
+	return RenderHints{
+		ReactionsPerSecond: math.Round(reactionRate*10) / 10,
+		AnimationEvery:     every,
+	}
+}
+
+// Start runs the emission loop until the context is cancelled
+func (s *BroadcastScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.emitDue(now)
+			}
+		}
+	}()
+}
+
+// pendingUpdate is a coalesced update ready to broadcast
+type pendingUpdate struct {
+	sessionID string
+	deltas    map[events.ReactionType]int64
+	interval  time.Duration
+	hints     RenderHints
+}
+
+// emitDue broadcasts a stats_update for every dirty session whose interval elapsed
+func (s *BroadcastScheduler) emitDue(now time.Time) {
+	var due []pendingUpdate
+
+	s.mu.Lock()
+	for sessionID, ss := range s.sessions {
+		elapsed := now.Sub(ss.lastEmit)
+		if !ss.dirty {
+			// Forget sessions that have been quiet for a while
+			if elapsed > time.Minute {
+				delete(s.sessions, sessionID)
+			}
+			continue
+		}
+
+		minInterval, maxInterval := s.bounds(sessionID)
+		interval := adaptiveInterval(ss.rate, minInterval, maxInterval)
+		if elapsed < interval {
+			continue
+		}
+
+		instant := float64(ss.changes) / elapsed.Seconds()
+		instantReactions := float64(ss.reactions) / elapsed.Seconds()
+		if ss.lastEmit.IsZero() {
+			instant = 0
+			instantReactions = 0
+		}
+		ss.rate = 0.5*ss.rate + 0.5*instant
+		ss.reactionRate = 0.5*ss.reactionRate + 0.5*instantReactions
+
+		due = append(due, pendingUpdate{
+			sessionID: sessionID,
+			deltas:    ss.deltas,
+			interval:  interval,
+			hints:     renderHintsFor(ss.reactionRate, s.animationBudget),
+		})
+		ss.dirty = false
+		ss.changes = 0
+		ss.reactions = 0
+		ss.deltas = make(map[events.ReactionType]int64)
+		ss.lastEmit = now
+	}
+	s.mu.Unlock()
+
+	for _, update := range due {
+		stats, exists := s.manager.GetSession(update.sessionID)
+		if !exists {
+			continue
+		}
+		s.hub.BroadcastToSession(update.sessionID, map[string]interface{}{
+			"type":             "stats_update",
+			"snapshot":         stats.GetSnapshot(),
+			"reaction_deltas":  update.deltas,
+			"next_interval_ms": update.interval.Milliseconds(),
+			"render_hints":     update.hints,
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastScheduler_CoalescesReactions(t *testing.T) {
+	hub := NewWebSocketHub()
+	manager := aggregation.NewManager()
+	manager.GetOrCreateSession("s1")
+	scheduler := NewBroadcastScheduler(hub, manager, sessions.NewRegistry(), 100*time.Millisecond, 2*time.Second)
+
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(16)
+	defer unsubscribe()
+
+	for i := 0; i < 100; i++ {
+		scheduler.MarkReaction("s1", events.ReactionFire)
+	}
+	scheduler.emitDue(time.Now())
+
+	select {
+	case data := <-updates:
+		var msg struct {
+			Type           string                        `json:"type"`
+			ReactionDeltas map[events.ReactionType]int64 `json:"reaction_deltas"`
+		}
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, "stats_update", msg.Type)
+		assert.Equal(t, int64(100), msg.ReactionDeltas[events.ReactionFire])
+	case <-time.After(time.Second):
+		t.Fatal("expected a coalesced stats_update")
+	}
+
+	// Nothing changed, so the next tick stays silent
+	scheduler.emitDue(time.Now().Add(5 * time.Second))
+	select {
+	case <-updates:
+		t.Fatal("idle session should not broadcast")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAdaptiveInterval_SpeedsUpUnderLoad(t *testing.T) {
+	minInterval, maxInterval := 100*time.Millisecond, 2*time.Second
+
+	assert.Equal(t, maxInterval, adaptiveInterval(0, minInterval, maxInterval), "idle sessions use the max interval")
+	assert.Equal(t, time.Second, adaptiveInterval(1, minInterval, maxInterval))
+	assert.Equal(t, minInterval, adaptiveInterval(500, minInterval, maxInterval), "busy sessions are clamped to the min interval")
+}
+
+func TestRenderHints_ThinAnimationsAboveBudget(t *testing.T) {
+	assert.Equal(t, 1, renderHintsFor(15, 20).AnimationEvery, "rates within budget animate every reaction")
+	assert.Equal(t, 50, renderHintsFor(1000, 20).AnimationEvery)
+	assert.Equal(t, 3, renderHintsFor(41, 20).AnimationEvery, "partial overshoot rounds up")
+}
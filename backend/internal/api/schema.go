@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/schema"
+)
+
+// schemaHeader names the external schema version a client expects and a
+// response is written in
+const schemaHeader = "X-LivePulse-Schema"
+
+// SetJSONNaming sets the default case of field names in versioned
+// responses. Clients may override it with ?naming=.
+func (s *Server) SetJSONNaming(naming schema.Naming) {
+	s.jsonNaming = naming
+}
+
+// responseNaming negotiates the schema of a versioned response. Clients may
+// pin a version with ?schema= or the X-LivePulse-Schema header; v1 is the
+// only version, and the default.
+func (s *Server) responseNaming(r *http.Request) (schema.Naming, error) {
+	version := r.URL.Query().Get("schema")
+	if version == "" {
+		version = r.Header.Get(schemaHeader)
+	}
+	if version != "" && !strings.EqualFold(version, schema.V1) {
+		return "", errs.Validation("unsupported schema version %q; supported: %s", version, schema.V1)
+	}
+	if val := r.URL.Query().Get("naming"); val != "" {
+		return schema.ParseNaming(val)
+	}
+	return s.defaultNaming(), nil
+}
+
+// defaultNaming returns the configured field naming
+func (s *Server) defaultNaming() schema.Naming {
+	if s.jsonNaming == "" {
+		return schema.NamingSnake
+	}
+	return s.jsonNaming
+}
+
+// writeVersioned writes v, a schema type, in the negotiated field naming
+func writeVersioned(w http.ResponseWriter, naming schema.Naming, v interface{}) {
+	body, err := schema.Marshal(v, naming)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(schemaHeader, schema.V1)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/notifications"
+)
+
+// SessionWebhookRequest registers an endpoint receiving one session's
+// notifications. Types defaults to every session notification type, and
+// Secret to a generated one.
+type SessionWebhookRequest struct {
+	URL    string   `json:"url"`
+	Types  []string `json:"types,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// RegisteredSessionWebhook is a webhook as registered, with the secret its
+// deliveries are signed with. The secret is only shown at registration.
+type RegisteredSessionWebhook struct {
+	notifications.SessionWebhook
+	Secret string `json:"secret"`
+}
+
+// RegisterSessionWebhooksRequest represents the request body for adding
+// webhooks to an existing session
+type RegisterSessionWebhooksRequest struct {
+	SessionID string                  `json:"session_id"`
+	Webhooks  []SessionWebhookRequest `json:"webhooks"`
+}
+
+// sessionWebhooksFrom validates webhook requests before the session they
+// are for is set up, so a bad one fails the request without side effects
+func (s *Server) sessionWebhooksFrom(reqs []SessionWebhookRequest) ([]notifications.SessionWebhook, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	if s.notifier == nil {
+		return nil, errs.Unavailable("webhooks are not configured")
+	}
+	if len(reqs) > notifications.MaxSessionWebhooks {
+		return nil, errs.Validation("a session may register at most %d webhooks", notifications.MaxSessionWebhooks)
+	}
+	hooks := make([]notifications.SessionWebhook, 0, len(reqs))
+	for _, req := range reqs {
+		hook := notifications.SessionWebhook{URL: req.URL, Types: req.Types, Secret: req.Secret}
+		if err := hook.Validate(); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// registerSessionWebhooks registers validated webhooks for a session,
+// returning them with their secrets
+func (s *Server) registerSessionWebhooks(ctx context.Context, sessionID string, hooks []notifications.SessionWebhook) ([]RegisteredSessionWebhook, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	registered, err := s.notifier.RegisterSessionWebhooks(ctx, sessionID, hooks)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RegisteredSessionWebhook, 0, len(registered))
+	for _, hook := range registered {
+		out = append(out, RegisteredSessionWebhook{SessionWebhook: hook, Secret: hook.Secret})
+	}
+	return out, nil
+}
+
+// HandleSessionWebhooks manages the webhooks scoped to one session,
+// alongside the deployment-wide endpoints: list them (GET ?session_id=),
+// add more (POST) or remove one (DELETE ?session_id=&id=)
+func (s *Server) HandleSessionWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sessionID := r.URL.Query().Get("session_id")
+		if sessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		if _, exists := s.registry.Get(sessionID); !exists {
+			writeError(w, errs.NotFound("session not found"))
+			return
+		}
+		hooks := s.notifier.SessionWebhooks(sessionID)
+		if hooks == nil {
+			hooks = []notifications.SessionWebhook{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"session_id": sessionID, "webhooks": hooks})
+
+	case http.MethodPost:
+		var req RegisterSessionWebhooksRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		if req.SessionID == "" || len(req.Webhooks) == 0 {
+			writeError(w, errs.Validation("session_id and webhooks are required"))
+			return
+		}
+		if _, exists := s.registry.Get(req.SessionID); !exists {
+			writeError(w, errs.NotFound("session not found"))
+			return
+		}
+		if s.registry.IsEnded(req.SessionID) {
+			writeError(w, errs.ErrSessionEnded)
+			return
+		}
+		hooks, err := s.sessionWebhooksFrom(req.Webhooks)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		registered, err := s.registerSessionWebhooks(r.Context(), req.SessionID, hooks)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for _, hook := range registered {
+			s.recordAction(r, ActionWebhookRegister, req.SessionID, "", hook.SessionWebhook)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"session_id": req.SessionID, "webhooks": registered})
+
+	case http.MethodDelete:
+		sessionID := r.URL.Query().Get("session_id")
+		id := r.URL.Query().Get("id")
+		if sessionID == "" || id == "" {
+			writeError(w, errs.Validation("session_id and id are required"))
+			return
+		}
+		if !s.notifier.RemoveSessionWebhook(r.Context(), sessionID, id) {
+			writeError(w, errs.NotFound("webhook not found"))
+			return
+		}
+		s.recordAction(r, ActionWebhookRemove, sessionID, "", map[string]string{"id": id})
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateSession_RegistersSessionWebhooks(t *testing.T) {
+	notifier := notifications.NewWebhookNotifier(nil, "")
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), notifier)
+
+	rec := httptest.NewRecorder()
+	server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(
+		`{"session_id":"bad","webhooks":[{"url":"ftp://example.com/hook"}]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	_, exists := server.registry.Get("bad")
+	assert.False(t, exists, "an invalid webhook fails the create")
+
+	for _, url := range []string{"http://203.0.113.10/hook", "https://169.254.169.254/latest", "https://127.0.0.1/hook", "https://[::1]/hook"} {
+		rec = httptest.NewRecorder()
+		server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(
+			`{"session_id":"internal","webhooks":[{"url":"`+url+`"}]}`)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "%s is rejected", url)
+	}
+
+	rec = httptest.NewRecorder()
+	server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(
+		`{"session_id":"show","webhooks":[{"url":"https://203.0.113.10/hook","types":["milestone.achieved"]}]}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var created CreateSessionResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	require.Len(t, created.Webhooks, 1)
+	assert.NotEmpty(t, created.Webhooks[0].Secret, "a secret is generated and shown once")
+
+	rec = httptest.NewRecorder()
+	server.HandleSessionWebhooks(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/webhooks?session_id=show", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "https://203.0.113.10/hook")
+	assert.NotContains(t, rec.Body.String(), created.Webhooks[0].Secret, "listing does not reveal secrets")
+
+	rec = httptest.NewRecorder()
+	server.HandleSessionWebhooks(rec, httptest.NewRequest(http.MethodDelete, "/api/sessions/webhooks?session_id=show&id="+created.Webhooks[0].ID, nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, notifier.SessionWebhooks("show"))
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// maxShoutouts bounds how many users one pick may return
+const maxShoutouts = 100
+
+// ShoutoutPick is the result of a giveaway draw. Drawing again with the same
+// seed over candidates with the same digest returns the same winners.
+type ShoutoutPick struct {
+	SessionID       string   `json:"session_id"`
+	Seed            int64    `json:"seed"`
+	Weighted        bool     `json:"weighted"`
+	Candidates      int      `json:"candidates"`
+	CandidateDigest string   `json:"candidate_digest"`
+	Winners         []string `json:"winners"`
+}
+
+// shoutoutCandidate is an active user eligible for a draw
+type shoutoutCandidate struct {
+	userID string
+	weight int64
+}
+
+// shoutoutCandidates orders the roster by user ID so draws do not depend on
+// join order. Weighted draws give each user one ticket per reaction, so
+// users who never reacted are not eligible.
+func shoutoutCandidates(roster []aggregation.RosterEntry, weighted bool) []shoutoutCandidate {
+	candidates := make([]shoutoutCandidate, 0, len(roster))
+	for _, entry := range roster {
+		weight := int64(1)
+		if weighted {
+			weight = entry.ReactionCount
+		}
+		if weight > 0 {
+			candidates = append(candidates, shoutoutCandidate{userID: entry.UserID, weight: weight})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].userID < candidates[j].userID })
+	return candidates
+}
+
+// candidateDigest fingerprints the candidates and their weights so a draw
+// can be verified later without storing the whole roster
+func candidateDigest(candidates []shoutoutCandidate) string {
+	h := sha256.New()
+	for _, c := range candidates {
+		fmt.Fprintf(h, "%s:%d\n", c.userID, c.weight)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// drawShoutouts picks up to n distinct candidates, each draw choosing a
+// remaining candidate with probability proportional to its weight
+func drawShoutouts(candidates []shoutoutCandidate, n int, seed int64) []string {
+	rng := mathrand.New(mathrand.NewSource(seed))
+	pool := append([]shoutoutCandidate(nil), candidates...)
+
+	var total int64
+	for _, c := range pool {
+		total += c.weight
+	}
+
+	winners := make([]string, 0, n)
+	for len(winners) < n && len(pool) > 0 {
+		ticket := rng.Int63n(total)
+		i := 0
+		for ; ticket >= pool[i].weight; i++ {
+			ticket -= pool[i].weight
+		}
+		winners = append(winners, pool[i].userID)
+		total -= pool[i].weight
+		pool = append(pool[:i], pool[i+1:]...)
+	}
+	return winners
+}
+
+// HandlePickShoutouts draws ?count= random active users of a session for a
+// giveaway, weighted by reactions sent when ?weighted=true. Pass ?seed= to
+// repeat a draw; otherwise a random seed is chosen and returned. Every draw
+// is recorded in the action log.
+func (s *Server) HandlePickShoutouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	params := r.URL.Query()
+	sessionID := params.Get("session_id")
+	if sessionID == "" {
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	count := 1
+	if val := params.Get("count"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 || n > maxShoutouts {
+			writeError(w, errs.Validation("count must be between 1 and %d", maxShoutouts))
+			return
+		}
+		count = n
+	}
+	weighted := params.Get("weighted") == "true"
+
+	var seed int64
+	if val := params.Get("seed"); val != "" {
+		parsed, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			writeError(w, errs.Validation("seed must be an integer"))
+			return
+		}
+		seed = parsed
+	} else {
+		var buf [8]byte
+		rand.Read(buf[:])
+		seed = int64(binary.BigEndian.Uint64(buf[:]) >> 1)
+	}
+
+	stats, exists := s.aggManager.GetSession(sessionID)
+	if !exists {
+		writeError(w, errs.NotFound("session not found"))
+		return
+	}
+	roster, _ := stats.GetRoster(0, 0)
+	candidates := shoutoutCandidates(roster, weighted)
+
+	pick := ShoutoutPick{
+		SessionID:       sessionID,
+		Seed:            seed,
+		Weighted:        weighted,
+		Candidates:      len(candidates),
+		CandidateDigest: candidateDigest(candidates),
+		Winners:         drawShoutouts(candidates, count, seed),
+	}
+	s.recordAction(r, ActionShoutoutPick, sessionID, "", pick)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pick)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrawShoutouts_IsReproducibleAndDistinct(t *testing.T) {
+	candidates := []shoutoutCandidate{{"a", 1}, {"b", 5}, {"c", 2}, {"d", 1}}
+
+	first := drawShoutouts(candidates, 3, 42)
+	assert.Equal(t, first, drawShoutouts(candidates, 3, 42))
+	assert.Len(t, first, 3)
+	seen := map[string]bool{}
+	for _, winner := range first {
+		assert.False(t, seen[winner], "winners are distinct")
+		seen[winner] = true
+	}
+
+	assert.Len(t, drawShoutouts(candidates, 10, 42), 4, "cannot pick more users than there are")
+}
+
+func TestHandlePickShoutouts_WeightsByReactionsAndRecordsDraw(t *testing.T) {
+	aggManager := aggregation.NewManager()
+	server := NewServer(nil, aggManager, nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	actions := &memoryActionLog{}
+	server.SetActionLog(actions)
+	aggManager.ProcessEvent(events.JoinSessionEvent("s1", "lurker"))
+	aggManager.ProcessEvent(events.JoinSessionEvent("s1", "fan"))
+	aggManager.ProcessEvent(events.ReactionEvent("s1", "fan", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/shoutouts?session_id=s1&count=2&weighted=true&seed=7", nil)
+	server.HandlePickShoutouts(rec, asUser(req, "producer-1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var pick ShoutoutPick
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&pick))
+	assert.Equal(t, []string{"fan"}, pick.Winners, "users who never reacted hold no tickets")
+	assert.Equal(t, int64(7), pick.Seed)
+	assert.Equal(t, 1, pick.Candidates)
+
+	require.Len(t, actions.actions, 1)
+	assert.Equal(t, ActionShoutoutPick, actions.actions[0].Action)
+	assert.Contains(t, string(actions.actions[0].Details), pick.CandidateDigest)
+}
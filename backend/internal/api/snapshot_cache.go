@@ -0,0 +1,146 @@
+package api
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// SnapshotCacheStats reports the stats snapshot cache's effectiveness
+type SnapshotCacheStats struct {
+	Entries   int     `json:"entries"`
+	Capacity  int     `json:"capacity"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Evictions int64   `json:"evictions"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// cachedSnapshot is the encoded snapshot of one session at one version
+type cachedSnapshot struct {
+	sessionID string
+	etag      string
+	body      []byte
+	builtAt   time.Time
+}
+
+// snapshotCache keeps the encoded stats snapshots of the most recently read
+// sessions, so hot sessions polled by thousands of clients are locked and
+// marshaled once per change. Entries are keyed by the snapshot ETag, so any
+// mutation invalidates them; maxAge bounds how stale the snapshot's running
+// duration may get while a session is quiet.
+type snapshotCache struct {
+	capacity int
+	maxAge   time.Duration
+	entries  map[string]*list.Element // sessionID -> element holding *cachedSnapshot
+	order    *list.List               // most recently used first
+	mu       sync.Mutex
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func newSnapshotCache(capacity int, maxAge time.Duration) *snapshotCache {
+	return &snapshotCache{
+		capacity: capacity,
+		maxAge:   maxAge,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached body of a session if it is still at etag
+func (c *snapshotCache) get(sessionID, etag string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[sessionID]
+	if exists {
+		entry := elem.Value.(*cachedSnapshot)
+		if entry.etag == etag && time.Since(entry.builtAt) < c.maxAge {
+			c.order.MoveToFront(elem)
+			atomic.AddInt64(&c.hits, 1)
+			return entry.body, true
+		}
+	}
+	atomic.AddInt64(&c.misses, 1)
+	return nil, false
+}
+
+// put caches a session's encoded snapshot, evicting the least recently read
+// session when full
+func (c *snapshotCache) put(sessionID, etag string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cachedSnapshot{sessionID: sessionID, etag: etag, body: body, builtAt: time.Now()}
+	if elem, exists := c.entries[sessionID]; exists {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[sessionID] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedSnapshot).sessionID)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+// remove drops a session's entry once it has ended
+func (c *snapshotCache) remove(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[sessionID]; exists {
+		c.order.Remove(elem)
+		delete(c.entries, sessionID)
+	}
+}
+
+// stats returns the cache's counters
+func (c *snapshotCache) stats() SnapshotCacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	stats := SnapshotCacheStats{
+		Entries:   entries,
+		Capacity:  c.capacity,
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+	}
+	if reads := stats.Hits + stats.Misses; reads > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(reads)
+	}
+	return stats
+}
+
+// SetStatsCache caches encoded stats snapshots for up to capacity sessions,
+// each reused for at most maxAge while the session is unchanged
+func (s *Server) SetStatsCache(capacity int, maxAge time.Duration) {
+	s.statsCache = newSnapshotCache(capacity, maxAge)
+}
+
+// HandleGetStatsCache reports the stats snapshot cache's hit rate
+func (s *Server) HandleGetStatsCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.statsCache == nil {
+		writeError(w, errs.NotFound("stats cache is not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statsCache.stats())
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/schema"
+)
+
+// Snapshot sections selectable with ?sections=. The summary holds every
+// bounded field; the others are breakdowns that grow with the audience and
+// are served a page at a time.
+const (
+	SectionSummary         = "summary"
+	SectionCohorts         = "cohorts"
+	SectionDimensions      = "dimensions"
+	SectionReactionSources = "reaction_sources"
+)
+
+// Breakdown entries per page unless ?limit= asks for fewer or more
+const (
+	defaultSectionLimit = 100
+	maxSectionLimit     = 1000
+)
+
+// SnapshotPage is part of a session's snapshot: the requested sections,
+// with at most a page of entries from each breakdown. NextCursors holds a
+// cursor for each breakdown with entries left.
+type SnapshotPage struct {
+	SessionID       string                                   `json:"session_id"`
+	Version         int64                                    `json:"version"` // changes between pages mean the breakdowns moved on
+	Summary         *schema.SnapshotV1                       `json:"summary,omitempty"`
+	Cohorts         map[string]schema.CohortV1               `json:"cohorts,omitempty"`
+	Dimensions      map[string]map[string]schema.DimensionV1 `json:"dimensions,omitempty"`
+	ReactionSources map[string]schema.SourceV1               `json:"reaction_sources,omitempty"`
+	NextCursors     map[string]string                        `json:"next_cursors,omitempty"`
+}
+
+// sectionCursor resumes a breakdown after the last key served, a
+// dimension and its value for the dimensions section
+type sectionCursor struct {
+	Section string   `json:"s"`
+	After   []string `json:"a"`
+}
+
+// encode returns the cursor as an opaque query parameter value
+func (c sectionCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSectionCursor parses a cursor from a previous page
+func decodeSectionCursor(value string) (sectionCursor, error) {
+	var cursor sectionCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || len(cursor.After) == 0 {
+		return sectionCursor{}, errs.Validation("cursor is malformed")
+	}
+	return cursor, nil
+}
+
+// parseSections reads the sections and cursor of a partial snapshot
+// request. A cursor continues its own section, which is the only one
+// served alongside it.
+func parseSections(params map[string][]string) ([]string, *sectionCursor, error) {
+	var sections []string
+	for _, section := range strings.Split(strings.Join(params["sections"], ","), ",") {
+		switch section = strings.TrimSpace(section); section {
+		case "":
+		case SectionSummary, SectionCohorts, SectionDimensions, SectionReactionSources:
+			sections = append(sections, section)
+		default:
+			return nil, nil, errs.Validation("unknown section %q", section)
+		}
+	}
+
+	values := params["cursor"]
+	if len(values) == 0 || values[0] == "" {
+		return sections, nil, nil
+	}
+	cursor, err := decodeSectionCursor(values[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sections) > 1 || (len(sections) == 1 && sections[0] != cursor.Section) {
+		return nil, nil, errs.Validation("a cursor only continues the %s section", cursor.Section)
+	}
+	if cursor.Section == SectionSummary {
+		return nil, nil, errs.Validation("cursor is malformed")
+	}
+	return []string{cursor.Section}, &cursor, nil
+}
+
+// writeSnapshotSections serves the requested sections of a session's
+// snapshot, a page at a time for the breakdowns
+func (s *Server) writeSnapshotSections(w http.ResponseWriter, r *http.Request, naming schema.Naming, stats *aggregation.SessionStats) {
+	params := r.URL.Query()
+	sections, cursor, err := parseSections(params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	limit := defaultSectionLimit
+	if val, err := strconv.Atoi(params.Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+	if limit > maxSectionLimit {
+		limit = maxSectionLimit
+	}
+
+	snapshot := schema.SnapshotV1From(stats.GetSnapshot())
+	page := SnapshotPage{SessionID: snapshot.SessionID, Version: snapshot.Version}
+	for _, section := range sections {
+		var after []string
+		if cursor != nil {
+			after = cursor.After
+		}
+		var next []string
+		switch section {
+		case SectionSummary:
+			summary := snapshot
+			summary.Cohorts, summary.Dimensions, summary.ReactionSources = nil, nil, nil
+			page.Summary = &summary
+		case SectionCohorts:
+			var all [][]string
+			for cohort := range snapshot.Cohorts {
+				all = append(all, []string{cohort})
+			}
+			var keys [][]string
+			keys, next = pageKeys(all, after, limit)
+			page.Cohorts = make(map[string]schema.CohortV1, len(keys))
+			for _, key := range keys {
+				page.Cohorts[key[0]] = snapshot.Cohorts[key[0]]
+			}
+		case SectionDimensions:
+			var all [][]string
+			for dimension, values := range snapshot.Dimensions {
+				for value := range values {
+					all = append(all, []string{dimension, value})
+				}
+			}
+			var keys [][]string
+			keys, next = pageKeys(all, after, limit)
+			page.Dimensions = make(map[string]map[string]schema.DimensionV1)
+			for _, key := range keys {
+				if page.Dimensions[key[0]] == nil {
+					page.Dimensions[key[0]] = make(map[string]schema.DimensionV1)
+				}
+				page.Dimensions[key[0]][key[1]] = snapshot.Dimensions[key[0]][key[1]]
+			}
+		case SectionReactionSources:
+			var all [][]string
+			for source := range snapshot.ReactionSources {
+				all = append(all, []string{source})
+			}
+			var keys [][]string
+			keys, next = pageKeys(all, after, limit)
+			page.ReactionSources = make(map[string]schema.SourceV1, len(keys))
+			for _, key := range keys {
+				page.ReactionSources[key[0]] = snapshot.ReactionSources[key[0]]
+			}
+		}
+		if next != nil {
+			if page.NextCursors == nil {
+				page.NextCursors = make(map[string]string)
+			}
+			page.NextCursors[section] = sectionCursor{Section: section, After: next}.encode()
+		}
+	}
+
+	writeVersioned(w, naming, page)
+}
+
+// pageKeys sorts breakdown keys and returns at most limit of those after
+// the given key, with the key to resume after if entries are left
+func pageKeys(keys [][]string, after []string, limit int) ([][]string, []string) {
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+	start := sort.Search(len(keys), func(i int) bool { return compareKeys(keys[i], after) > 0 })
+	keys = keys[start:]
+	if len(keys) <= limit {
+		return keys, nil
+	}
+	return keys[:limit], keys[limit-1]
+}
+
+// compareKeys orders key paths element by element; a nil path sorts first
+func compareKeys(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
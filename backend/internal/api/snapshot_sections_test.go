@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetStats_PagesThroughSections(t *testing.T) {
+	manager := aggregation.NewManager()
+	server := NewServer(nil, manager, nil, nil, nil, nil, sessions.NewRegistry(), nil)
+	for i := 0; i < 5; i++ {
+		manager.ProcessEvent(events.CohortJoinSessionEvent("s1", fmt.Sprintf("u%d", i), fmt.Sprintf("cohort-%d", i)))
+	}
+
+	get := func(query string) (int, SnapshotPage) {
+		rec := httptest.NewRecorder()
+		server.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/stats?session_id=s1&"+query, nil))
+		var page SnapshotPage
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+		}
+		return rec.Code, page
+	}
+
+	code, page := get("sections=summary,cohorts&limit=2")
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, page.Summary)
+	assert.Equal(t, 5, page.Summary.ActiveUserCount)
+	assert.Nil(t, page.Summary.Cohorts, "breakdowns are only served as sections")
+	assert.Len(t, page.Cohorts, 2)
+	assert.Contains(t, page.Cohorts, "cohort-0")
+
+	seen := len(page.Cohorts)
+	for page.NextCursors[SectionCohorts] != "" {
+		code, page = get("cursor=" + url.QueryEscape(page.NextCursors[SectionCohorts]) + "&limit=2")
+		require.Equal(t, http.StatusOK, code)
+		assert.Nil(t, page.Summary, "a cursor only continues its section")
+		seen += len(page.Cohorts)
+	}
+	assert.Equal(t, 5, seen, "every cohort is served exactly once")
+
+	code, _ = get("sections=everything")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("sections=dimensions&cursor=bm90LWpzb24")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// maxSystemReactions bounds the reactions one request may emit
+const maxSystemReactions = 100
+
+// SetSystemSources lists the integrations allowed to emit reactions, e.g. a
+// trivia bot awarding applause. With none, system reactions are disabled.
+func (s *Server) SetSystemSources(sources []string) {
+	s.systemSources = make(map[string]bool, len(sources))
+	for _, source := range sources {
+		s.systemSources[source] = true
+	}
+}
+
+// SystemReactionRequest emits reactions into a session on behalf of an
+// integration
+type SystemReactionRequest struct {
+	SessionID    string `json:"session_id"`
+	Source       string `json:"source"`
+	ReactionType string `json:"reaction_type"`
+	Count        int    `json:"count,omitempty"` // defaults to 1
+}
+
+// HandleEmitSystemReactions queues reactions attributed to an integration's
+// system identity. They skip fraud checks and reaction caps and are counted
+// apart from the audience's reactions.
+func (s *Server) HandleEmitSystemReactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if len(s.systemSources) == 0 {
+		writeError(w, errs.NotFound("system reactions are not enabled"))
+		return
+	}
+
+	var req SystemReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errs.Validation("invalid request body"))
+		return
+	}
+	if err := sessions.ValidateID(req.SessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if !s.systemSources[req.Source] {
+		writeError(w, errs.Forbidden("source %q may not emit reactions", req.Source))
+		return
+	}
+	reactionType := events.ReactionType(req.ReactionType)
+	if !reactionType.IsValid() {
+		writeError(w, errs.Validation("unknown reaction_type %s", req.ReactionType))
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > maxSystemReactions {
+		writeError(w, errs.Validation("count must be between 1 and %d", maxSystemReactions))
+		return
+	}
+	if s.registry.IsEnded(req.SessionID) {
+		writeError(w, errs.ErrSessionEnded)
+		return
+	}
+
+	accepted := 0
+	for ; accepted < req.Count; accepted++ {
+		if err := s.eventQueue.Enqueue(r.Context(), events.SystemReactionEvent(req.SessionID, req.Source, reactionType)); err != nil {
+			if accepted == 0 {
+				writeError(w, err)
+				return
+			}
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_id": req.SessionID,
+		"source":     req.Source,
+		"user_id":    events.SystemUserPrefix + req.Source,
+		"accepted":   accepted,
+	})
+}
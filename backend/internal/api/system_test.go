@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleEmitSystemReactions_QueuesReactionsFromAllowedSources(t *testing.T) {
+	queue := events.NewQueue(8)
+	server := NewServer(queue, aggregation.NewManager(), nil, nil, nil, nil, sessions.NewRegistry(), nil)
+
+	post := func(req SystemReactionRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		server.HandleEmitSystemReactions(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/reactions/system", bytes.NewReader(body)), "producer-1"))
+		return rec
+	}
+
+	req := SystemReactionRequest{SessionID: "s1", Source: "trivia-bot", ReactionType: "applause", Count: 3}
+	assert.Equal(t, http.StatusNotFound, post(req).Code, "disabled without sources")
+
+	server.SetSystemSources([]string{"trivia-bot"})
+	assert.Equal(t, http.StatusForbidden, post(SystemReactionRequest{SessionID: "s1", Source: "poll-bot", ReactionType: "applause"}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(SystemReactionRequest{SessionID: "s1", Source: "trivia-bot", ReactionType: "bogus"}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(SystemReactionRequest{SessionID: "s1", Source: "trivia-bot", ReactionType: "applause", Count: maxSystemReactions + 1}).Code)
+
+	rec := post(req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, float64(3), resp["accepted"])
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		event, ok := queue.Dequeue(ctx)
+		require.True(t, ok)
+		assert.True(t, event.IsSystem())
+		assert.Equal(t, "system:trivia-bot", event.UserID)
+		reactionType, _ := event.GetReactionType()
+		assert.Equal(t, events.ReactionApplause, reactionType)
+	}
+	assert.Equal(t, 0, queue.Len())
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Tag rollup sources
+const (
+	RollupSourceLive    = "live"
+	RollupSourceArchive = "archive"
+	RollupSourceAll     = "all"
+)
+
+// TagRollup sums the stats of every session carrying a tag
+type TagRollup struct {
+	Tag              string           `json:"tag"`
+	Source           string           `json:"source"`
+	Sessions         int              `json:"sessions"`
+	LiveSessions     int              `json:"live_sessions"`
+	ArchivedSessions int              `json:"archived_sessions"`
+	ActiveUsers      int              `json:"active_users"` // live sessions only
+	PeakUsers        int              `json:"peak_users"`   // sum of each session's peak
+	TotalReactions   int64            `json:"total_reactions"`
+	ReactionCounts   map[string]int64 `json:"reaction_counts"`
+	LiveSessionIDs   []string         `json:"live_session_ids,omitempty"`
+}
+
+// rollupLive adds the in-memory stats of live and closing sessions carrying
+// tag. Sessions created at or after before are left out when it is set.
+func (s *Server) rollupLive(rollup *TagRollup, tenantID string, before time.Time) {
+	for _, session := range s.registry.List(sessions.Filter{TenantID: tenantID, Tag: rollup.Tag}) {
+		if session.Status == sessions.StatusEnded {
+			continue // counted from the archive
+		}
+		if !before.IsZero() && !session.CreatedAt.Before(before) {
+			continue
+		}
+		stats, exists := s.aggManager.GetSession(session.ID)
+		if !exists {
+			continue
+		}
+		snapshot := stats.GetSnapshot()
+		rollup.LiveSessions++
+		rollup.ActiveUsers += snapshot.ActiveUserCount
+		rollup.PeakUsers += snapshot.PeakConcurrentUsers
+		rollup.TotalReactions += snapshot.TotalReactions
+		for reaction, count := range snapshot.ReactionCounts {
+			rollup.ReactionCounts[string(reaction)] += count
+		}
+		rollup.LiveSessionIDs = append(rollup.LiveSessionIDs, session.ID)
+	}
+}
+
+// HandleGetTagRollup sums stats across every session sharing ?tag=, e.g.
+// all "series:nba" sessions today. ?source= picks live sessions from memory,
+// ended ones from the archive, or both (the default when the archive is
+// available). ?from= and ?to= bound archived sessions by end time and accept
+// "today" for the start of the current UTC day; live sessions have not ended
+// so ?from= never excludes them.
+func (s *Server) HandleGetTagRollup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+
+	params := r.URL.Query()
+	if params.Get("tag") == "" {
+		writeError(w, errs.Validation("tag is required"))
+		return
+	}
+	tags, err := sessions.NormalizeTags([]string{params.Get("tag")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	source := params.Get("source")
+	if source == "" {
+		source = RollupSourceLive
+		if s.db != nil {
+			source = RollupSourceAll
+		}
+	}
+	switch source {
+	case RollupSourceLive:
+	case RollupSourceArchive, RollupSourceAll:
+		if s.db == nil {
+			writeError(w, errs.Unavailable("archive not available"))
+			return
+		}
+	default:
+		writeError(w, errs.Validation("source must be one of live, archive, all"))
+		return
+	}
+
+	q := storage.ArchiveQuery{TenantID: params.Get("tenant_id"), Tag: tags[0]}
+	for name, dst := range map[string]*time.Time{"from": &q.EndedAfter, "to": &q.EndedBefore} {
+		val := params.Get(name)
+		if val == "" {
+			continue
+		}
+		if val == "today" {
+			*dst = time.Now().UTC().Truncate(24 * time.Hour)
+			continue
+		}
+		t, err := parseArchiveTime(val)
+		if err != nil {
+			writeError(w, errs.Validation("%s must be an RFC 3339 timestamp, YYYY-MM-DD date or today", name))
+			return
+		}
+		*dst = t
+	}
+	if !q.EndedAfter.IsZero() && !q.EndedBefore.IsZero() && !q.EndedBefore.After(q.EndedAfter) {
+		writeError(w, errs.Validation("to must be after from"))
+		return
+	}
+
+	rollup := TagRollup{Tag: q.Tag, Source: source, ReactionCounts: make(map[string]int64)}
+	if source != RollupSourceArchive {
+		s.rollupLive(&rollup, q.TenantID, q.EndedBefore)
+	}
+	if source != RollupSourceLive {
+		archived, err := s.archiveDB(q.TenantID).RollupSessionSnapshots(r.Context(), q)
+		if err != nil {
+			log.Printf("Error rolling up archived sessions tagged %s: %v", q.Tag, err)
+			writeError(w, err)
+			return
+		}
+		rollup.ArchivedSessions = archived.Sessions
+		rollup.PeakUsers += archived.PeakUsers
+		rollup.TotalReactions += archived.TotalReactions
+		for reaction, count := range archived.ReactionCounts {
+			rollup.ReactionCounts[reaction] += count
+		}
+	}
+	rollup.Sessions = rollup.LiveSessions + rollup.ArchivedSessions
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rollup)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetTagRollup_SumsLiveSessionsSharingTag(t *testing.T) {
+	server, manager := newStatsTestServer()
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.HandleCreateSession(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body)))
+		return rec
+	}
+	require.Equal(t, http.StatusOK, create(`{"session_id":"lakers","tags":["Series:NBA","lang:en"]}`).Code)
+	require.Equal(t, http.StatusOK, create(`{"session_id":"celtics","tags":["series:nba"]}`).Code)
+	require.Equal(t, http.StatusOK, create(`{"session_id":"chelsea","tags":["series:epl"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"session_id":"bad","tags":["no spaces"]}`).Code)
+
+	manager.ProcessEvent(events.JoinSessionEvent("lakers", "user-1"))
+	manager.ProcessEvent(events.ReactionEvent("lakers", "user-1", events.ReactionFire))
+	manager.ProcessEvent(events.JoinSessionEvent("celtics", "user-2"))
+	manager.ProcessEvent(events.ReactionEvent("celtics", "user-2", events.ReactionFire))
+	manager.ProcessEvent(events.ReactionEvent("celtics", "user-2", events.ReactionCheer))
+	manager.ProcessEvent(events.JoinSessionEvent("chelsea", "user-3"))
+	manager.ProcessEvent(events.ReactionEvent("chelsea", "user-3", events.ReactionFire))
+
+	rec := httptest.NewRecorder()
+	server.HandleGetTagRollup(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/tags/rollup?tag=series:nba&from=today", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var rollup TagRollup
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rollup))
+	assert.Equal(t, RollupSourceLive, rollup.Source)
+	assert.Equal(t, 2, rollup.Sessions)
+	assert.Equal(t, 2, rollup.ActiveUsers)
+	assert.Equal(t, int64(3), rollup.TotalReactions)
+	assert.Equal(t, int64(2), rollup.ReactionCounts[string(events.ReactionFire)])
+	assert.ElementsMatch(t, []string{"lakers", "celtics"}, rollup.LiveSessionIDs)
+
+	// The archive needs a database
+	rec = httptest.NewRecorder()
+	server.HandleGetTagRollup(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/tags/rollup?tag=series:nba&source=archive", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.HandleGetTagRollup(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/tags/rollup", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/tokens"
+)
+
+// jwksMaxAge is how long verifiers may cache the published keys. Rotated in
+// keys should be listed as verify-only for at least this long before they
+// start signing.
+const jwksMaxAge = 5 * time.Minute
+
+// ViewerToken is a short-lived token granting one viewer access to one
+// session
+type ViewerToken struct {
+	SessionID string    `json:"session_id"`
+	UserID    string    `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SetViewerTokens enables session-scoped viewer tokens, accepted alongside
+// Clerk tokens by the WebSocket handshake and ViewerMiddleware
+func (s *Server) SetViewerTokens(service *tokens.Service) {
+	s.viewerTokens = service
+}
+
+// HandleIssueViewerToken exchanges the caller's Clerk login for a viewer
+// token for ?session_id=. Producers may issue tokens for another viewer
+// with ?user_id=.
+func (s *Server) HandleIssueViewerToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.viewerTokens == nil {
+		writeError(w, errs.NotFound("viewer tokens are not enabled"))
+		return
+	}
+
+	params := r.URL.Query()
+	sessionID := params.Get("session_id")
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	callerID, _ := r.Context().Value("user_id").(string)
+	userID := callerID
+	if other := params.Get("user_id"); other != "" && other != callerID {
+		if !IsProducer(callerID) {
+			writeError(w, errs.Forbidden("producer permissions required to issue tokens for other users"))
+			return
+		}
+		userID = other
+	}
+
+	// Tokens for an alias are bound to the session it mirrors
+	canonical := s.registry.Canonical(sessionID)
+	if !s.registry.AcceptsJoins(canonical) {
+		writeError(w, errs.ErrSessionEnded)
+		return
+	}
+	if s.registry.IsBanned(canonical, userID) {
+		writeError(w, errs.ErrBanned)
+		return
+	}
+
+	token, expiresAt, err := s.viewerTokens.Issue(canonical, userID, time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if userID != callerID {
+		s.recordAction(r, ActionViewerTokenIssue, canonical, "", map[string]interface{}{"user_id": userID, "expires_at": expiresAt})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ViewerToken{SessionID: canonical, UserID: userID, Token: token, ExpiresAt: expiresAt})
+}
+
+// HandleJWKS publishes the public keys viewer tokens are signed with, so
+// other services can verify them without sharing a secret
+func (s *Server) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errs.ErrBadMethod)
+		return
+	}
+	if s.viewerTokens == nil {
+		writeError(w, errs.NotFound("viewer tokens are not enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	json.NewEncoder(w).Encode(s.viewerTokens.JWKS())
+}
+
+// authenticateViewer returns the user a token belongs to. Viewer tokens
+// must have been issued for sessionID; tokens that are not viewer tokens
+// are verified with Clerk.
+func (s *Server) authenticateViewer(ctx context.Context, sessionID, token string) (string, error) {
+	if s.viewerTokens != nil {
+		claims, err := s.viewerTokens.Verify(token, s.registry.Canonical(sessionID), time.Now())
+		if err == nil {
+			return claims.Subject, nil
+		}
+		if !errors.Is(err, tokens.ErrMalformed) && !errors.Is(err, tokens.ErrUnknownKey) {
+			return "", err
+		}
+	}
+	return VerifyTokenManually(ctx, token)
+}
+
+// ViewerMiddleware authenticates requests scoped to ?session_id= with either
+// a viewer token for that session or a Clerk token, like ClerkMiddleware
+func (s *Server) ViewerMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			writeError(w, fmt.Errorf("%w: missing token", errs.ErrUnauthorized))
+			return
+		}
+
+		userID, err := s.authenticateViewer(r.Context(), r.URL.Query().Get("session_id"), token)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: invalid token", errs.ErrUnauthorized))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "user_id", userID)
+		next(w, r.WithContext(ctx))
+	}
+}
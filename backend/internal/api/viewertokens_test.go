@@ -0,0 +1,70 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleIssueViewerToken_AdmitsOnlyItsSession(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	service, err := tokens.NewService("livepulse", time.Minute, []*ecdsa.PrivateKey{key})
+	require.NoError(t, err)
+	registry := sessions.NewRegistry()
+	server := NewServer(nil, aggregation.NewManager(), nil, nil, nil, nil, registry, nil)
+	server.SetViewerTokens(service)
+	registry.Create(sessions.Session{ID: "s1"})
+
+	rec := httptest.NewRecorder()
+	server.HandleIssueViewerToken(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/viewer-token?session_id=s1", nil), "u1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var issued ViewerToken
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&issued))
+	assert.Equal(t, "u1", issued.UserID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), issued.ExpiresAt, 2*time.Second)
+
+	var seen string
+	handler := server.ViewerMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = r.Context().Value("user_id").(string)
+	})
+	request := func(sessionID string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/experiments/assignments?session_id="+sessionID, nil)
+		req.Header.Set("Authorization", "Bearer "+issued.Token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, request("s1"))
+	assert.Equal(t, "u1", seen)
+	assert.Equal(t, http.StatusUnauthorized, request("s2"), "tokens are scoped to one session")
+
+	// Only producers may issue tokens for someone else
+	rec = httptest.NewRecorder()
+	server.HandleIssueViewerToken(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/viewer-token?session_id=s1&user_id=u2", nil), "u1"))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	registry.Ban("s1", "u3", "spam")
+	rec = httptest.NewRecorder()
+	server.HandleIssueViewerToken(rec, asUser(httptest.NewRequest(http.MethodPost, "/api/sessions/viewer-token?session_id=s1", nil), "u3"))
+	assert.Equal(t, http.StatusForbidden, rec.Code, "banned viewers get no token")
+
+	rec = httptest.NewRecorder()
+	server.HandleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var set tokens.KeySet
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&set))
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "P-256", set.Keys[0].Crv)
+}
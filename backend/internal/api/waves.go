@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/waves"
+)
+
+// defaultWaveText is shown with the countdown when the producer sends none
+const defaultWaveText = "Get ready to react together!"
+
+// WaveCue tells clients when to react together. CountdownMs is relative to
+// the control message's sent_at, for clients whose clocks are off.
+type WaveCue struct {
+	WaveID      string    `json:"wave_id"`
+	At          time.Time `json:"at"`
+	CountdownMs int64     `json:"countdown_ms"`
+	WindowMs    int64     `json:"window_ms"`
+}
+
+// WaveResultMessage reports a wave's participation once its window closed
+type WaveResultMessage struct {
+	Type string     `json:"type"`
+	Wave waves.Wave `json:"wave"`
+}
+
+// NewWaveResultMessage builds the broadcast announcing a wave's result
+func NewWaveResultMessage(wave waves.Wave) WaveResultMessage {
+	return WaveResultMessage{Type: MessageTypeWaveResult, Wave: wave}
+}
+
+// SetWaveManager enables producer-triggered reaction waves
+func (s *Server) SetWaveManager(manager *waves.Manager) {
+	s.waves = manager
+}
+
+// StartWaveRequest represents the request body for a reaction wave. Delay
+// and window default to 10s and 2s.
+type StartWaveRequest struct {
+	SessionID         string              `json:"session_id"`
+	Text              string              `json:"text,omitempty"`
+	SuggestedReaction events.ReactionType `json:"suggested_reaction,omitempty"`
+	DelayMs           int64               `json:"delay_ms,omitempty"`
+	WindowMs          int64               `json:"window_ms,omitempty"`
+}
+
+// HandleWaves starts a reaction wave (POST) or reports a session's recent
+// waves (GET ?session_id=[&wave_id=]). Starting a wave sends a control
+// message counting every client down to the same moment.
+func (s *Server) HandleWaves(w http.ResponseWriter, r *http.Request) {
+	if s.waves == nil {
+		writeError(w, errs.NotFound("waves are not enabled"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sessionID := r.URL.Query().Get("session_id")
+		if sessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if waveID := r.URL.Query().Get("wave_id"); waveID != "" {
+			wave, exists := s.waves.Get(sessionID, waveID)
+			if !exists {
+				writeError(w, errs.NotFound("wave not found"))
+				return
+			}
+			json.NewEncoder(w).Encode(wave)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"session_id": sessionID,
+			"waves":      s.waves.List(sessionID),
+		})
+
+	case http.MethodPost:
+		var req StartWaveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errs.Validation("invalid request body"))
+			return
+		}
+		if req.SessionID == "" {
+			writeError(w, errs.Validation("session_id is required"))
+			return
+		}
+		if len(req.Text) > 500 {
+			writeError(w, errs.Validation("text exceeds 500 character limit"))
+			return
+		}
+		if req.Text == "" {
+			req.Text = defaultWaveText
+		}
+		if s.registry.IsEnded(req.SessionID) {
+			writeError(w, errs.ErrSessionEnded)
+			return
+		}
+		delay, window := waves.DefaultDelay, waves.DefaultWindow
+		if req.DelayMs != 0 {
+			delay = time.Duration(req.DelayMs) * time.Millisecond
+		}
+		if req.WindowMs != 0 {
+			window = time.Duration(req.WindowMs) * time.Millisecond
+		}
+
+		wave, err := s.waves.Schedule(req.SessionID, req.SuggestedReaction, delay, window)
+		if errors.Is(err, waves.ErrInProgress) {
+			writeError(w, errs.Conflict("%v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, errs.Validation("invalid wave: %v", err))
+			return
+		}
+
+		sentAt := time.Now().UTC()
+		msg := ControlMessage{
+			Type:              MessageTypeControl,
+			ID:                events.NewEventID(),
+			SessionID:         req.SessionID,
+			Text:              req.Text,
+			SuggestedReaction: req.SuggestedReaction,
+			SentAt:            sentAt,
+			Wave: &WaveCue{
+				WaveID:      wave.ID,
+				At:          wave.At,
+				CountdownMs: wave.At.Sub(sentAt).Milliseconds(),
+				WindowMs:    wave.Window.Milliseconds(),
+			},
+		}
+		userID, _ := r.Context().Value("user_id").(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), controlDeliveryTimeout)
+		defer cancel()
+		delivery, err := s.wsHub.SendControl(ctx, msg, userID)
+		if err != nil {
+			// Clients were never counted down, so the wave must not run
+			s.waves.Cancel(req.SessionID, wave.ID)
+			writeError(w, fmt.Errorf("%w delivering wave countdown", errs.ErrTimeout))
+			return
+		}
+		s.recordAction(r, ActionWaveStart, req.SessionID, "", wave)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"wave":     wave,
+			"message":  msg,
+			"delivery": delivery,
+		})
+
+	default:
+		writeError(w, errs.ErrBadMethod)
+	}
+}
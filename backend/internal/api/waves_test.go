@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/waves"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWaves_CountsClientsDownToTheWave(t *testing.T) {
+	hub := NewWebSocketHub()
+	server := NewServer(nil, aggregation.NewManager(), nil, hub, nil, nil, sessions.NewRegistry(), nil)
+	server.SetWaveManager(waves.NewManager(func(string) int { return 0 }, nil))
+	updates, unsubscribe := hub.GetOrCreateSessionHub("s1").Subscribe(4)
+	defer unsubscribe()
+
+	start := func(body string) (int, map[string]json.RawMessage) {
+		rec := httptest.NewRecorder()
+		server.HandleWaves(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/waves", strings.NewReader(body)))
+		var resp map[string]json.RawMessage
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	status, resp := start(`{"session_id":"s1","suggested_reaction":"fire","window_ms":1500}`)
+	require.Equal(t, http.StatusCreated, status)
+	var wave waves.Wave
+	require.NoError(t, json.Unmarshal(resp["wave"], &wave))
+	assert.Equal(t, 1.5, wave.WindowSeconds)
+
+	var received ControlMessage
+	require.NoError(t, json.Unmarshal(<-updates, &received))
+	require.NotNil(t, received.Wave)
+	assert.Equal(t, defaultWaveText, received.Text)
+	assert.Equal(t, wave.ID, received.Wave.WaveID)
+	assert.True(t, wave.At.Equal(received.Wave.At))
+	assert.Equal(t, int64(1500), received.Wave.WindowMs)
+	assert.InDelta(t, waves.DefaultDelay.Milliseconds(), received.Wave.CountdownMs, 100)
+
+	status, resp = start(`{"session_id":"s1"}`)
+	assert.Equal(t, http.StatusConflict, status, "one wave at a time")
+	assert.JSONEq(t, `"`+string(errs.CodeConflict)+`"`, string(resp["code"]))
+	status, _ = start(`{"session_id":"s2","delay_ms":50}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	rec := httptest.NewRecorder()
+	server.HandleWaves(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/waves?session_id=s1&wave_id="+wave.ID, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed waves.Wave
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	assert.Equal(t, wave.ID, listed.ID)
+	assert.False(t, listed.Completed)
+}
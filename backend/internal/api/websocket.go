@@ -6,10 +6,22 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/diagnostics"
+	"github.com/jrudman25/livepulse/internal/errs"
 	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/logging"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// Broadcast log lines are sampled so a slow audience cannot flood the logs
+var (
+	slowClientLog = logging.NewSampler("Slow client disconnects", 10, time.Second)
+	relayErrorLog = logging.NewSampler("Broadcast relay errors", 10, time.Second)
 )
 
 var upgrader = websocket.Upgrader{
@@ -26,9 +38,47 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// HeartbeatConfig controls WebSocket liveness checks
+type HeartbeatConfig struct {
+	PingInterval time.Duration // how often the server pings each client
+	PongTimeout  time.Duration // how long without a pong or message before a connection is stale
+	WriteTimeout time.Duration
+}
+
+// heartbeat holds the active liveness settings
+var heartbeat = HeartbeatConfig{
+	PingInterval: 54 * time.Second,
+	PongTimeout:  60 * time.Second,
+	WriteTimeout: 10 * time.Second,
+}
+
+// SetHeartbeat configures WebSocket ping intervals and stale timeouts.
+// Zero fields keep their defaults.
+func SetHeartbeat(cfg HeartbeatConfig) {
+	if cfg.PingInterval > 0 {
+		heartbeat.PingInterval = cfg.PingInterval
+	}
+	if cfg.PongTimeout > 0 {
+		heartbeat.PongTimeout = cfg.PongTimeout
+	}
+	if cfg.WriteTimeout > 0 {
+		heartbeat.WriteTimeout = cfg.WriteTimeout
+	}
+}
+
+// BroadcastRelay fans session broadcasts out to every hub node serving the
+// session, looping them back to the publishing node as well
+type BroadcastRelay interface {
+	Publish(sessionID string, payload []byte) error
+	Join(sessionID string, deliver func(payload []byte))
+}
+
 // WebSocketHub manages WebSocket connections for all sessions
 type WebSocketHub struct {
 	sessions map[string]*SessionHub // sessionID -> SessionHub
+	relay    BroadcastRelay
+	gate     func(sessionID, channel string) bool
+	channels func(sessionID string) []string // IDs a session's clients connect under
 	mu       sync.RWMutex
 }
 
@@ -43,10 +93,15 @@ func NewWebSocketHub() *WebSocketHub {
 type SessionHub struct {
 	sessionID  string
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan *outboundMessage
 	register   chan *Client
 	unregister chan *Client
+	seq        uint64 // messages fanned out, numbering v3 envelopes; guarded by mu
 	mu         sync.RWMutex
+
+	controls     map[string]*ControlDelivery // control message ID -> delivery record
+	controlOrder []string
+	controlMu    sync.Mutex
 }
 
 // NewSessionHub creates a new session hub
@@ -54,18 +109,25 @@ func NewSessionHub(sessionID string) *SessionHub {
 	hub := &SessionHub{
 		sessionID:  sessionID,
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		controls:   make(map[string]*ControlDelivery),
+		broadcast:  make(chan *outboundMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
-	go hub.run()
+	go diagnostics.Label("broadcast", hub.run)
 	return hub
 }
 
 // run manages the session hub
 func (h *SessionHub) run() {
+	sweep := time.NewTicker(heartbeat.PongTimeout / 2)
+	defer sweep.Stop()
+
 	for {
 		select {
+		case now := <-sweep.C:
+			h.closeStale(now)
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -76,49 +138,222 @@ func (h *SessionHub) run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.closeSend()
 			}
 			h.mu.Unlock()
 			log.Printf("Client disconnected from session %s (total: %d)", h.sessionID, len(h.clients))
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			recipients, dropped := 0, 0
+			h.mu.Lock()
+			h.seq++
+			message.sessionID, message.seq, message.sentAt = h.sessionID, h.seq, time.Now().UTC()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
+				if message.recipient != "" && client.userID != message.recipient {
+					continue
+				}
+				frames := message.encodeFor(client.capabilities(), client.lastSeq)
+				queued := true
+				for _, data := range frames {
+					select {
+					case client.send <- data:
+						continue
+					default:
+						slowClientLog.Printf("Dropping slow client for user %s in session %s", client.userID, h.sessionID)
+						client.closeSend()
+						delete(h.clients, client)
+						if client.conn != nil {
+							// Ends the read pump, which emits the user's leave event
+							client.conn.Close()
+						}
+						queued = false
+					}
+					break
+				}
+				if queued && message.disconnect {
+					// The write pump sends the queued frames, then closes the connection
+					client.closeSend()
 					delete(h.clients, client)
 				}
+				if len(frames) == 0 && !message.disconnect {
+					continue
+				}
+				if queued {
+					client.lastSeq = message.seq
+					recipients++
+				} else {
+					dropped++
+				}
+			}
+			h.mu.Unlock()
+			if message.delivered != nil {
+				message.delivered(recipients, dropped)
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
 
+// closeStale closes connections that stopped answering pings. Closing the
+// connection ends its read pump, which unregisters the client and emits the
+// user's leave event so presence follows connectivity.
+func (h *SessionHub) closeStale(now time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		if client.conn == nil {
+			continue // in-process subscriber
+		}
+		if now.Sub(client.lastSeenAt()) > heartbeat.PongTimeout {
+			log.Printf("Closing stale connection for user %s in session %s", client.userID, h.sessionID)
+			client.conn.Close()
+		}
+	}
+}
+
+// Subscribe registers an in-process listener that receives every message
+// broadcast to the session. The returned function unsubscribes the listener.
+func (h *SessionHub) Subscribe(buffer int) (<-chan []byte, func()) {
+	client := &Client{
+		hub:       h,
+		send:      make(chan []byte, buffer),
+		sessionID: h.sessionID,
+	}
+	h.register <- client
+	return client.send, func() { h.unregister <- client }
+}
+
 // Client represents a WebSocket client
 type Client struct {
 	hub       *SessionHub
 	conn      *websocket.Conn
 	send      chan []byte
+	sendMu    sync.Mutex // guards closed against sends from the read pump
+	closed    bool       // send has been closed by the hub
 	sessionID string
 	userID    string
+	sourceIP  string
+	features  func() sessions.Features // the session's current features; nil uses the defaults
+	banned    func(userID string) bool  // whether a user is barred from the session; nil allows everyone
+	outdated  func(version string) *UpgradeRequiredResponse // rejects outdated clients; nil allows every version
+	authenticate func(token string) (string, error) // resolves the handshake token to a user; nil accepts Clerk tokens only
+	snapshot  func() aggregation.StatsSnapshot // the session's current statistics, sent on resync
+
+	caps   capabilities // negotiated via hello; zero means legacy defaults
+	capsMu sync.RWMutex
+
+	lastSeen int64 // unix nanos of the last pong or message
+	lastSeq  uint64 // seq of the last message the hub sent; guarded by the hub's mu
+
+	resyncMu      sync.Mutex
+	lastResync    time.Time // when the last resync snapshot was sent
+	resyncPending bool      // a throttled resync is scheduled
+
+	presence events.PresenceState // last state reported by heartbeats; only touched by readPump
+	acked    map[string]bool         // control messages this client confirmed; only touched by readPump
+}
+
+// reply queues a frame for the client from outside the hub, such as an
+// error or acknowledgement from the read pump. Frames are dropped if the
+// client's buffer is full or the hub has already dropped the client.
+func (c *Client) reply(frame []byte) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- frame:
+	default:
+	}
+}
+
+// closeSend closes the client's send channel, ending its write pump. Only
+// the hub calls it, once per client.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.closed = true
+	close(c.send)
+}
+
+// touch records that the client is alive and extends the read deadline
+func (c *Client) touch() {
+	atomic.StoreInt64(&c.lastSeen, time.Now().UnixNano())
+	c.conn.SetReadDeadline(time.Now().Add(heartbeat.PongTimeout))
+}
+
+// lastSeenAt returns when the client last showed signs of life
+func (c *Client) lastSeenAt() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastSeen))
+}
+
+// capabilities returns what the client negotiated
+func (c *Client) capabilities() capabilities {
+	c.capsMu.RLock()
+	defer c.capsMu.RUnlock()
+	if c.caps.version == 0 {
+		return defaultCapabilities()
+	}
+	return c.caps
+}
+
+// handleHello negotiates the protocol version and channels a client declared
+func (c *Client) handleHello(msg map[string]interface{}) {
+	requestedVersion, _ := msg["protocol_version"].(float64)
+	var requestedChannels []string
+	if raw, ok := msg["channels"].([]interface{}); ok {
+		requestedChannels = make([]string, 0, len(raw))
+		for _, channel := range raw {
+			if name, ok := channel.(string); ok {
+				requestedChannels = append(requestedChannels, name)
+			}
+		}
+	}
+
+	caps, unsupported := negotiate(int(requestedVersion), requestedChannels)
+	var rejectedFields []string
+	if raw, ok := msg["fields"].([]interface{}); ok {
+		requestedFields := make([]string, 0, len(raw))
+		for _, field := range raw {
+			if name, ok := field.(string); ok {
+				requestedFields = append(requestedFields, name)
+			}
+		}
+		rejectedFields = caps.setFields(requestedFields)
+	}
+	c.capsMu.Lock()
+	c.caps = caps
+	c.capsMu.Unlock()
+
+	welcome, _ := json.Marshal(map[string]interface{}{
+		"type":                 "welcome",
+		"protocol_version":     caps.version,
+		"channels":             caps.channelList(),
+		"unsupported_channels": unsupported,
+		"fields":               caps.fields,
+		"rejected_fields":      rejectedFields,
+		"server_versions":      serverVersions,
+		"available_channels":   supportedChannels,
+	})
+	c.reply(welcome)
 }
 
 // readPump reads messages from the WebSocket connection
-func (c *Client) readPump(eventQueue *events.Queue) {
+func (c *Client) readPump(eventQueue events.Transport) {
 	defer func() {
 		if c.userID != "" { // Only safely unregister and alert if formally authenticated!
 			c.hub.unregister <- c
-			leaveEvent := events.LeaveSessionEvent(c.sessionID, c.userID)
-			eventQueue.Enqueue(leaveEvent)
+			leaveEvent := events.PresenceLeaveSessionEvent(c.sessionID, c.userID, c.presence)
+			leaveEvent.SourceIP = c.sourceIP
+			eventQueue.Enqueue(context.Background(), leaveEvent)
 		}
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	c.touch()
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		c.touch()
 		return nil
 	})
 
@@ -130,12 +365,13 @@ func (c *Client) readPump(eventQueue *events.Queue) {
 			}
 			break
 		}
+		c.touch()
 
 		// Parse incoming message
 		var msg map[string]interface{}
 		if err := json.Unmarshal(message, &msg); err != nil {
 			log.Printf("Error parsing message: %v", err)
-			c.send <- []byte(`{"type":"error","message":"Invalid JSON payload structure"}`)
+			c.reply([]byte(`{"type":"error","code":"validation","message":"Invalid JSON payload structure"}`))
 			continue
 		}
 
@@ -144,24 +380,60 @@ func (c *Client) readPump(eventQueue *events.Queue) {
 			continue
 		}
 
+		// Protocol negotiation is allowed at any point, including before authentication
+		if msgType == "hello" {
+			c.handleHello(msg)
+			continue
+		}
+
+		// Application-level heartbeats for clients that cannot see WebSocket pings
+		if msgType == "heartbeat" {
+			c.reply([]byte(`{"type":"heartbeat_ack"}`))
+			c.updatePresence(msg, eventQueue)
+			continue
+		}
+
 		// Handle Authentication Handshake Securely First
 		if c.userID == "" {
 			if msgType == "authenticate" {
 				token, _ := msg["token"].(string)
-				userID, err := VerifyTokenManually(context.Background(), token)
+				var userID string
+				var err error
+				if c.authenticate != nil {
+					userID, err = c.authenticate(token)
+				} else {
+					userID, err = VerifyTokenManually(context.Background(), token)
+				}
 				if err != nil {
-					c.send <- []byte(`{"type":"error","message":"Authentication invalid or expired"}`)
+					c.reply([]byte(`{"type":"error","code":"unauthorized","message":"Authentication invalid or expired"}`))
 					break // exit pump, closing connection natively
 				}
+				if c.banned != nil && c.banned(userID) {
+					c.reply([]byte(`{"type":"error","code":"banned","message":"You are banned from this session"}`))
+					break
+				}
+				clientVersion, _ := msg["client_version"].(string)
+				if c.outdated != nil {
+					if rejection := c.outdated(clientVersion); rejection != nil {
+						rejection.Type = "error"
+						frame, _ := json.Marshal(rejection)
+						c.reply(frame)
+						break
+					}
+				}
 				
 				c.userID = userID
+				c.presence = events.PresenceActive
 				c.hub.register <- c
-				joinEvent := events.JoinSessionEvent(c.sessionID, c.userID)
-				eventQueue.Enqueue(joinEvent)
-				c.send <- []byte(`{"type":"authenticated"}`)
+				cohort, _ := msg["cohort"].(string)
+				joinEvent := events.CohortJoinSessionEvent(c.sessionID, c.userID, cohort)
+				joinEvent.SetClientVersion(clientVersion)
+				joinEvent.SourceIP = c.sourceIP
+				c.enqueue(eventQueue, joinEvent)
+				c.reply([]byte(`{"type":"authenticated"}`))
 				continue
 			} else {
-				c.send <- []byte(`{"type":"error","message":"You must authenticate before sending events"}`)
+				c.reply([]byte(`{"type":"error","code":"unauthorized","message":"You must authenticate before sending events"}`))
 				break // kill connection payload natively!
 			}
 		}
@@ -173,31 +445,183 @@ func (c *Client) readPump(eventQueue *events.Queue) {
 			if !ok {
 				continue
 			}
-			event := events.ReactionEvent(c.sessionID, c.userID, events.ReactionType(reactionType))
-			eventQueue.Enqueue(event)
+			attributes, ok := reactionAttributes(msg)
+			if !ok {
+				c.reply([]byte(`{"type":"error","code":"validation","message":"attributes must be an object of short string values"}`))
+				continue
+			}
+			source, _ := msg["source"].(string)
+			if events.ValidateReactionSource(events.ReactionSource(source)) != nil {
+				c.reply([]byte(`{"type":"error","code":"validation","message":"source must be overlay_button, chat_command, mobile_app, tv_remote, web or other"}`))
+				continue
+			}
+			event := events.SourcedReactionEvent(c.sessionID, c.userID, events.ReactionType(reactionType), events.ReactionSource(source), attributes)
+			if !c.applyEventID(event, msg) {
+				continue
+			}
+			c.enqueue(eventQueue, event)
+		case "resync":
+			c.requestResync()
+		case "control_ack":
+			messageID, _ := msg["message_id"].(string)
+			if messageID == "" || c.acked[messageID] {
+				continue
+			}
+			if c.hub.acknowledgeControl(messageID) {
+				if c.acked == nil {
+					c.acked = make(map[string]bool)
+				}
+				c.acked[messageID] = true
+			}
 		case "chat":
 			text, ok := msg["text"].(string)
 			if !ok {
 				continue
 			}
+			if c.features != nil && c.features().ChatDisabled {
+				c.reply([]byte(`{"type":"error","code":"chat_disabled","message":"Chat is turned off for this session"}`))
+				continue
+			}
 			authorName, _ := msg["author_name"].(string)
 			
 			// Simple content filter (expand this later)
 			if len(text) > 500 {
 				log.Printf("Chat message artificially blocked natively due to string boundaries.")
-				c.send <- []byte(`{"type":"error","message":"Message payload exceeded 500 character limit"}`)
+				c.reply([]byte(`{"type":"error","code":"validation","message":"Message payload exceeded 500 character limit"}`))
 				continue
 			}
 
 			event := events.ChatEvent(c.sessionID, c.userID, text, authorName)
-			eventQueue.Enqueue(event)
+			if !c.applyEventID(event, msg) {
+				continue
+			}
+			c.enqueue(eventQueue, event)
+		}
+	}
+}
+
+// resyncInterval is the least time between resync snapshots sent to one
+// client; requests in between are coalesced into one sent after it
+const resyncInterval = time.Second
+
+// requestResync sends a resync snapshot, at most once per resyncInterval.
+// A request inside the interval schedules a single snapshot for its end.
+func (c *Client) requestResync() {
+	c.resyncMu.Lock()
+	defer c.resyncMu.Unlock()
+	if c.resyncPending {
+		return
+	}
+	wait := resyncInterval - time.Since(c.lastResync)
+	if wait <= 0 {
+		c.lastResync = time.Now()
+		c.resync()
+		return
+	}
+	c.resyncPending = true
+	time.AfterFunc(wait, func() {
+		c.resyncMu.Lock()
+		defer c.resyncMu.Unlock()
+		c.resyncPending = false
+		c.lastResync = time.Now()
+		c.resync()
+	})
+}
+
+// resync replies with a full snapshot of the session. It holds the hub's
+// lock so the snapshot is queued after every message the client already
+// received, and carries the session's current seq without taking a new one.
+func (c *Client) resync() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	snapshot := aggregation.StatsSnapshot{SessionID: c.sessionID}
+	if c.snapshot != nil {
+		snapshot = c.snapshot()
+	}
+	data, err := newOutboundMessage(NewSnapshotMessage(c.sessionID, snapshot))
+	if err != nil {
+		log.Printf("Error marshaling snapshot for session %s: %v", c.sessionID, err)
+		return
+	}
+	data.sessionID, data.seq, data.sentAt = c.hub.sessionID, c.hub.seq, time.Now().UTC()
+	for _, frame := range data.encodeFor(c.capabilities(), c.lastSeq) {
+		c.reply(frame)
+	}
+	c.lastSeq = data.seq
+}
+
+// updatePresence publishes a presence transition when an authenticated
+// client's heartbeat reports a new state
+func (c *Client) updatePresence(msg map[string]interface{}, eventQueue events.Transport) {
+	raw, _ := msg["state"].(string)
+	state := events.PresenceState(raw)
+	if c.userID == "" || !state.IsValid() || state == c.presence {
+		return
+	}
+	event := events.PresenceEvent(c.sessionID, c.userID, c.presence, state)
+	event.SourceIP = c.sourceIP
+	c.presence = state
+	eventQueue.Enqueue(context.Background(), event)
+}
+
+// reactionAttributes reads the optional attributes object of a reaction
+// message, reporting false if it is malformed
+func reactionAttributes(msg map[string]interface{}) (map[string]string, bool) {
+	raw, present := msg["attributes"]
+	if !present {
+		return nil, true
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	attributes := make(map[string]string, len(object))
+	for key, value := range object {
+		text, ok := value.(string)
+		if !ok {
+			return nil, false
 		}
+		attributes[key] = text
+	}
+	return attributes, events.ValidateAttributes(attributes) == nil
+}
+
+// applyEventID stamps the connection's source address and adopts a
+// client-supplied event_id so resent messages are deduplicated. It reports
+// false, after telling the client, if the ID is invalid.
+func (c *Client) applyEventID(event *events.Event, msg map[string]interface{}) bool {
+	event.SourceIP = c.sourceIP
+	eventID, _ := msg["event_id"].(string)
+	if eventID == "" {
+		return true
+	}
+	if err := event.SetExternalID(eventID); err != nil {
+		c.reply([]byte(`{"type":"error","code":"validation","message":"event_id must be a UUID or ULID"}`))
+		return false
+	}
+	return true
+}
+
+// enqueue hands a client's event to the transport, telling the client with
+// a coded error frame when it was not accepted. The frame is skipped if the
+// client's buffer is full, as it would be while the queue is overloaded.
+func (c *Client) enqueue(eventQueue events.Transport, event *events.Event) {
+	err := eventQueue.Enqueue(context.Background(), event)
+	if err == nil {
+		return
 	}
+	resp, _ := newErrorResponse(err)
+	frame, _ := json.Marshal(struct {
+		Type string `json:"type"`
+		ErrorResponse
+	}{Type: "error", ErrorResponse: resp})
+	c.reply(frame)
 }
 
 // writePump writes messages to the WebSocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(heartbeat.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -206,7 +630,7 @@ func (c *Client) writePump() {
 	for {
 		select {
 		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteTimeout))
 			if !ok {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
@@ -230,7 +654,7 @@ func (c *Client) writePump() {
 			}
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteTimeout))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -249,21 +673,151 @@ func (h *WebSocketHub) GetOrCreateSessionHub(sessionID string) *SessionHub {
 	}
 
 	h.mu.Lock()
-	defer h.mu.Unlock()
-
 	// Double-check after acquiring write lock
 	if hub, exists := h.sessions[sessionID]; exists {
+		h.mu.Unlock()
 		return hub
 	}
-
 	hub = NewSessionHub(sessionID)
 	h.sessions[sessionID] = hub
+	relay := h.relay
+	h.mu.Unlock()
+
+	if relay != nil {
+		relay.Join(sessionID, func(payload []byte) {
+			h.deliverLocal(sessionID, json.RawMessage(payload))
+		})
+	}
 	return hub
 }
 
-// BroadcastToSession broadcasts a message to all clients in a session
+// SetRelay routes broadcasts through a relay so clients of the same session
+// on different hub nodes receive identical, ordered streams. It must be set
+// before any session hub is created.
+func (h *WebSocketHub) SetRelay(relay BroadcastRelay) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.relay = relay
+}
+
+// SetChannelGate makes broadcasts consult gate before delivery, so a
+// session can switch channels such as chat or polls off mid-show
+func (h *WebSocketHub) SetChannelGate(gate func(sessionID, channel string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gate = gate
+}
+
+// SetAliasResolver makes messages to a session reach the clients of every
+// ID channels returns for it, so a session simulcast under several IDs
+// streams to all of them
+func (h *WebSocketHub) SetAliasResolver(channels func(sessionID string) []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.channels = channels
+}
+
+// channelsOf returns the session IDs a message to sessionID is delivered to
+func (h *WebSocketHub) channelsOf(sessionID string) []string {
+	h.mu.RLock()
+	channels := h.channels
+	h.mu.RUnlock()
+	if channels == nil {
+		return []string{sessionID}
+	}
+	return channels(sessionID)
+}
+
+// BroadcastToSession broadcasts a message to all clients in a session and
+// its aliases, across every hub node when a relay is set. Messages on a
+// channel the session's gate closes are dropped before they are relayed.
 func (h *WebSocketHub) BroadcastToSession(sessionID string, message interface{}) {
-	data, err := json.Marshal(message)
+	h.mu.RLock()
+	relay, gate := h.relay, h.gate
+	h.mu.RUnlock()
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling broadcast message: %v", err)
+		return
+	}
+	if gate != nil {
+		var header struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(payload, &header)
+		if !gate(sessionID, channelFor(header.Type)) {
+			return
+		}
+	}
+
+	for _, channel := range h.channelsOf(sessionID) {
+		if relay != nil {
+			err := relay.Publish(channel, payload)
+			if err == nil {
+				continue
+			}
+			relayErrorLog.Printf("Error relaying broadcast for session %s, delivering locally: %v", channel, err)
+		}
+		h.deliverLocal(channel, json.RawMessage(payload))
+	}
+}
+
+// SendToUser delivers a message to one user's connections in a session and
+// its aliases on this node. Unlike broadcasts it is not relayed to other hub
+// nodes.
+func (h *WebSocketHub) SendToUser(sessionID, userID string, message interface{}) {
+	for _, channel := range h.channelsOf(sessionID) {
+		data, err := newOutboundMessage(message)
+		if err != nil {
+			log.Printf("Error marshaling message for user %s: %v", userID, err)
+			return
+		}
+		data.recipient = userID
+
+		h.mu.RLock()
+		hub, exists := h.sessions[channel]
+		h.mu.RUnlock()
+
+		if exists {
+			hub.broadcast <- data
+		}
+	}
+}
+
+// DisconnectUser sends a final message to a user's connections to a session
+// and its aliases on this node and then closes them. Each read pump emits
+// the user's leave event, as when the user disconnects themselves. It
+// returns how many connections were closed.
+func (h *WebSocketHub) DisconnectUser(sessionID, userID string, message interface{}) int {
+	total := 0
+	for _, channel := range h.channelsOf(sessionID) {
+		data, err := newOutboundMessage(message)
+		if err != nil {
+			log.Printf("Error marshaling message for user %s: %v", userID, err)
+			return total
+		}
+
+		h.mu.RLock()
+		hub, exists := h.sessions[channel]
+		h.mu.RUnlock()
+		if !exists {
+			continue
+		}
+
+		closed := make(chan int, 1)
+		data.recipient = userID
+		data.disconnect = true
+		data.delivered = func(recipients, dropped int) { closed <- recipients + dropped }
+		hub.broadcast <- data
+		total += <-closed
+	}
+	return total
+}
+
+// deliverLocal broadcasts a message to the clients connected to this node
+func (h *WebSocketHub) deliverLocal(sessionID string, message interface{}) {
+	data, err := newOutboundMessage(message)
 	if err != nil {
 		log.Printf("Error marshaling broadcast message: %v", err)
 		return
@@ -282,7 +836,11 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	sessionID := r.URL.Query().Get("session_id")
 
 	if sessionID == "" {
-		http.Error(w, "session_id is required", http.StatusBadRequest)
+		writeError(w, errs.Validation("session_id is required"))
+		return
+	}
+	if err := sessions.ValidateID(sessionID); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -302,9 +860,27 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		send:      make(chan []byte, 256),
 		sessionID: sessionID,
 		userID:    "", // Remains blank! Authenticated intrinsically inside readPump!
+		sourceIP:  clientIP(r),
+		// Clients of an alias see the session it mirrors
+		features: func() sessions.Features { return s.sessionFeatures(s.registry.Canonical(sessionID)) },
+		banned:   func(userID string) bool { return s.registry.IsBanned(s.registry.Canonical(sessionID), userID) },
+		outdated: s.minVersion.check,
+		// Viewer tokens only admit their holder to the session they were issued for
+		authenticate: func(token string) (string, error) {
+			return s.authenticateViewer(context.Background(), sessionID, token)
+		},
+		snapshot: func() aggregation.StatsSnapshot {
+			canonical := s.registry.Canonical(sessionID)
+			if stats, exists := s.aggManager.GetSession(canonical); exists {
+				return stats.GetSnapshot()
+			}
+			return aggregation.StatsSnapshot{SessionID: canonical}
+		},
 	}
 
 	// Start concurrent pumps instantly to seamlessly wait for Authentication Handshake Payload over encrypted channel
-	go client.writePump() // allows server to natively kickback JSON errors organically.
-	go client.readPump(s.eventQueue)
+	diagnostics.Label("websocket", func() {
+		go client.writePump() // allows server to natively kickback JSON errors organically.
+		go client.readPump(s.eventQueue)
+	})
 }
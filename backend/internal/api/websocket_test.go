@@ -1,10 +1,19 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/jrudman25/livepulse/internal/aggregation"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckOrigin_AllowsLocalhost(t *testing.T) {
@@ -40,3 +49,236 @@ func TestHandleWebSocket_RejectsMissingSessionID(t *testing.T) {
 	sessionID := ""
 	assert.Empty(t, sessionID, "empty session_id should be caught before upgrade")
 }
+
+func TestSessionHub_ClosesStaleConnections(t *testing.T) {
+	serverConns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		serverConns <- conn
+	}))
+	defer srv.Close()
+
+	clientConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	hub := NewSessionHub("s1")
+	client := &Client{hub: hub, conn: <-serverConns, send: make(chan []byte, 1), sessionID: "s1", userID: "u1"}
+	client.touch()
+	hub.register <- client
+
+	// A fresh connection survives the sweep
+	hub.closeStale(time.Now())
+	clientConn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err = clientConn.ReadMessage()
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "timeout"), "connection should still be open, got %v", err)
+
+	// Once the pong timeout passes, the server closes it
+	hub.closeStale(time.Now().Add(2 * heartbeat.PongTimeout))
+	assert.Error(t, client.conn.WriteMessage(websocket.TextMessage, []byte("{}")), "stale connection should be closed")
+}
+
+// memoryRelay is a BroadcastRelay shared by several hubs in one process,
+// delivering every payload to every joined hub in publish order
+type memoryRelay struct {
+	joined map[string][]func([]byte)
+	mu     sync.Mutex
+}
+
+func (m *memoryRelay) Publish(sessionID string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, deliver := range m.joined[sessionID] {
+		deliver(payload)
+	}
+	return nil
+}
+
+func (m *memoryRelay) Join(sessionID string, deliver func([]byte)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.joined[sessionID] = append(m.joined[sessionID], deliver)
+}
+
+func TestWebSocketHub_RelaysBroadcastsToEveryNode(t *testing.T) {
+	relay := &memoryRelay{joined: make(map[string][]func([]byte))}
+	nodeA, nodeB := NewWebSocketHub(), NewWebSocketHub()
+	nodeA.SetRelay(relay)
+	nodeB.SetRelay(relay)
+
+	streamA, closeA := nodeA.GetOrCreateSessionHub("s1").Subscribe(8)
+	defer closeA()
+	streamB, closeB := nodeB.GetOrCreateSessionHub("s1").Subscribe(8)
+	defer closeB()
+
+	// Only node A broadcasts, yet both nodes' clients see the same ordered stream
+	for i := 0; i < 3; i++ {
+		nodeA.BroadcastToSession("s1", map[string]interface{}{"type": "chat", "seq": i})
+	}
+	for i := 0; i < 3; i++ {
+		var fromA, fromB []byte
+		select {
+		case fromA = <-streamA:
+		case <-time.After(time.Second):
+			t.Fatal("node A client received nothing")
+		}
+		select {
+		case fromB = <-streamB:
+		case <-time.After(time.Second):
+			t.Fatal("node B client received nothing")
+		}
+		assert.JSONEq(t, string(fromA), string(fromB))
+		assert.Contains(t, string(fromA), `"seq":`+strconv.Itoa(i))
+	}
+}
+
+func TestWebSocketHub_SendToUserOnlyReachesThatUser(t *testing.T) {
+	hub := NewWebSocketHub()
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+	alice := &Client{hub: sessionHub, send: make(chan []byte, 4), sessionID: "s1", userID: "alice"}
+	bob := &Client{hub: sessionHub, send: make(chan []byte, 4), sessionID: "s1", userID: "bob"}
+	sessionHub.register <- alice
+	sessionHub.register <- bob
+
+	hub.SendToUser("s1", "alice", map[string]interface{}{"type": MessageTypeReactionRejected, "code": "user_sold_out"})
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat"})
+
+	select {
+	case data := <-alice.send:
+		assert.Contains(t, string(data), MessageTypeReactionRejected)
+	case <-time.After(time.Second):
+		t.Fatal("alice received nothing")
+	}
+	select {
+	case data := <-bob.send:
+		assert.Contains(t, string(data), `"chat"`, "bob only receives the broadcast")
+	case <-time.After(time.Second):
+		t.Fatal("bob received nothing")
+	}
+}
+
+func TestSessionHub_SequencesV3EnvelopesAndResyncs(t *testing.T) {
+	hub := NewWebSocketHub()
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+	v3, _ := negotiate(ProtocolV3, []string{ChannelChat})
+	alice := &Client{hub: sessionHub, send: make(chan []byte, 8), sessionID: "s1", userID: "alice", caps: v3}
+	bob := &Client{hub: sessionHub, send: make(chan []byte, 8), sessionID: "s1", userID: "bob", caps: v3}
+	bob.snapshot = func() aggregation.StatsSnapshot { return aggregation.StatsSnapshot{SessionID: "s1", TotalReactions: 7} }
+	sessionHub.register <- alice
+	sessionHub.register <- bob
+
+	receive := func(client *Client) Envelope {
+		select {
+		case data := <-client.send:
+			var envelope Envelope
+			require.NoError(t, json.Unmarshal(data, &envelope))
+			return envelope
+		case <-time.After(time.Second):
+			t.Fatalf("%s received nothing", client.userID)
+			return Envelope{}
+		}
+	}
+
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat", "text": "hi"})
+	hub.SendToUser("s1", "alice", map[string]interface{}{"type": MessageTypeReactionRejected})
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat", "text": "again"})
+
+	first := receive(alice)
+	assert.Equal(t, ProtocolV3, first.V)
+	assert.Equal(t, "chat", first.Type)
+	assert.Equal(t, ChannelChat, first.Channel)
+	assert.Equal(t, "s1", first.SessionID)
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.Zero(t, first.PrevSeq)
+	assert.False(t, first.Timestamp.IsZero())
+	assert.JSONEq(t, `{"type":"chat","text":"hi"}`, string(first.Payload))
+	assert.Equal(t, uint64(2), receive(alice).Seq)
+	assert.Equal(t, uint64(2), receive(alice).PrevSeq)
+
+	assert.Equal(t, uint64(1), receive(bob).Seq)
+	again := receive(bob)
+	assert.Equal(t, uint64(3), again.Seq)
+	assert.Equal(t, uint64(1), again.PrevSeq, "messages for other users are not gaps")
+
+	// A resync reply reaches only the asking connection, at the current seq
+	hub.SendToUser("s1", "alice", map[string]interface{}{"type": MessageTypeReactionRejected})
+	assert.Equal(t, uint64(4), receive(alice).Seq)
+	bob.resync()
+	snapshot := receive(bob)
+	assert.Equal(t, MessageTypeSnapshot, snapshot.Type)
+	assert.Equal(t, uint64(4), snapshot.Seq)
+	assert.Equal(t, uint64(3), snapshot.PrevSeq)
+	assert.Contains(t, string(snapshot.Payload), `"total_reactions":7`)
+	assert.Empty(t, alice.send)
+
+	hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat", "text": "after"})
+	receive(alice)
+	assert.Equal(t, uint64(4), receive(bob).PrevSeq, "the snapshot is not a gap")
+}
+
+func TestClient_ThrottlesAndCoalescesResyncs(t *testing.T) {
+	hub := NewWebSocketHub()
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+	client := &Client{hub: sessionHub, send: make(chan []byte, 8), sessionID: "s1", userID: "alice"}
+	snapshots := 0
+	client.snapshot = func() aggregation.StatsSnapshot {
+		snapshots++
+		return aggregation.StatsSnapshot{SessionID: "s1"}
+	}
+
+	for i := 0; i < 5; i++ {
+		client.requestResync()
+	}
+	require.Len(t, client.send, 1, "the first resync is answered at once")
+	<-client.send
+
+	select {
+	case data := <-client.send:
+		assert.Contains(t, string(data), MessageTypeSnapshot)
+	case <-time.After(2 * resyncInterval):
+		t.Fatal("the coalesced resync was never sent")
+	}
+	select {
+	case <-client.send:
+		t.Fatal("extra resyncs were not coalesced")
+	case <-time.After(resyncInterval / 2):
+	}
+	client.resyncMu.Lock()
+	defer client.resyncMu.Unlock()
+	assert.Equal(t, 2, snapshots)
+}
+
+func TestSessionHub_DroppedSlowClientSurvivesReplies(t *testing.T) {
+	serverConns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		serverConns <- conn
+	}))
+	defer srv.Close()
+
+	clientConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	hub := NewWebSocketHub()
+	sessionHub := hub.GetOrCreateSessionHub("s1")
+	client := &Client{hub: sessionHub, conn: <-serverConns, send: make(chan []byte, 1), sessionID: "s1", userID: "u1"}
+	sessionHub.register <- client
+
+	// The second broadcast overflows the buffer and the hub drops the client
+	for i := 0; i < 2; i++ {
+		hub.BroadcastToSession("s1", map[string]interface{}{"type": "chat", "seq": i})
+	}
+	require.Eventually(t, func() bool {
+		client.sendMu.Lock()
+		defer client.sendMu.Unlock()
+		return client.closed
+	}, time.Second, 5*time.Millisecond)
+
+	// The read pump may still answer the client; that must not panic
+	client.reply([]byte(`{"type":"heartbeat_ack"}`))
+	assert.Error(t, client.conn.WriteMessage(websocket.TextMessage, []byte("{}")), "dropped client's connection should be closed")
+}
@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// EventLog persists the raw events of audited sessions
+type EventLog interface {
+	AppendAuditEvent(ctx context.Context, sessionID string, payload []byte) error
+	LoadAuditEvents(ctx context.Context, sessionID string) ([][]byte, error)
+}
+
+// Divergence describes a mismatch between in-memory counters and counters
+// recomputed from the raw event log
+type Divergence struct {
+	SessionID      string                        `json:"session_id"`
+	DetectedAt     time.Time                     `json:"detected_at"`
+	MemoryTotal    int64                         `json:"memory_total"`
+	ReplayedTotal  int64                         `json:"replayed_total"`
+	ReactionDeltas map[events.ReactionType]int64 `json:"reaction_deltas"` // memory minus replayed
+}
+
+// Stats summarizes audit activity since startup
+type Stats struct {
+	SessionsAudited int64        `json:"sessions_audited"`
+	Skipped         int64        `json:"skipped"`
+	Divergences     int64        `json:"divergences"`
+	Recent          []Divergence `json:"recent,omitempty"`
+}
+
+// maxRecentDivergences bounds the divergences kept for reporting
+const maxRecentDivergences = 20
+
+// Auditor recomputes stats for a sample of sessions from their raw events and
+// compares them with the live counters, catching dropped or double-counted
+// events in production
+type Auditor struct {
+	manager    *aggregation.Manager
+	log        EventLog
+	sampleRate float64
+	encoding   events.Encoding
+
+	audited     int64
+	skipped     int64
+	divergences int64
+	recent      []Divergence
+	mu          sync.Mutex
+}
+
+// NewAuditor creates an auditor sampling the given fraction of sessions
+func NewAuditor(manager *aggregation.Manager, eventLog EventLog, sampleRate float64) *Auditor {
+	return &Auditor{
+		manager:    manager,
+		log:        eventLog,
+		sampleRate: sampleRate,
+		encoding:   events.EncodingJSON,
+	}
+}
+
+// SetEncoding sets how raw events are encoded in the log. Events already
+// logged in another encoding still load.
+func (a *Auditor) SetEncoding(encoding events.Encoding) {
+	a.encoding = encoding
+}
+
+// Sampled reports whether a session is audited. Sampling is deterministic so
+// every event of a sampled session is logged.
+func (a *Auditor) Sampled(sessionID string) bool {
+	if a.sampleRate <= 0 {
+		return false
+	}
+	hasher := fnv.New32a()
+	hasher.Write([]byte(sessionID))
+	return float64(hasher.Sum32()%10000) < a.sampleRate*10000
+}
+
+// Record logs an event of a sampled session. It must be called for exactly
+// the events passed to the aggregation manager.
+func (a *Auditor) Record(ctx context.Context, event *events.Event) {
+	if !a.Sampled(event.SessionID) {
+		return
+	}
+	payload, err := events.EncodeEvent(event, a.encoding)
+	if err != nil {
+		return
+	}
+	if err := a.log.AppendAuditEvent(ctx, event.SessionID, payload); err != nil {
+		log.Printf("Error logging audit event for session %s: %v", event.SessionID, err)
+	}
+}
+
+// LoadEvents returns the logged raw events of a sampled session in the order
+// they were processed, skipping entries that no longer decode
+func (a *Auditor) LoadEvents(ctx context.Context, sessionID string) ([]*events.Event, error) {
+	payloads, err := a.log.LoadAuditEvents(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	logged := make([]*events.Event, 0, len(payloads))
+	for _, payload := range payloads {
+		event, err := events.DecodeEvent(payload)
+		if err != nil {
+			continue
+		}
+		logged = append(logged, event)
+	}
+	return logged, nil
+}
+
+// Start audits every sampled session on each interval until the context is cancelled
+func (a *Auditor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.AuditAll(ctx)
+			}
+		}
+	}()
+}
+
+// AuditAll audits every sampled session currently tracked
+func (a *Auditor) AuditAll(ctx context.Context) {
+	for sessionID := range a.manager.GetAllSessions() {
+		if !a.Sampled(sessionID) {
+			continue
+		}
+		if _, err := a.AuditSession(ctx, sessionID); err != nil {
+			log.Printf("Error auditing session %s: %v", sessionID, err)
+		}
+	}
+}
+
+// AuditSession replays a session's raw events and compares the result with
+// the live counters. It returns nil when the counters agree or the session
+// changed during the audit and will be retried next round.
+func (a *Auditor) AuditSession(ctx context.Context, sessionID string) (*Divergence, error) {
+	stats, exists := a.manager.GetSession(sessionID)
+	if !exists {
+		return nil, nil
+	}
+
+	before := stats.Version()
+	memory := stats.GetSnapshot()
+	logged, err := a.LoadEvents(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Version() != before {
+		// Events arrived while reading the log, compare next round instead
+		atomic.AddInt64(&a.skipped, 1)
+		return nil, nil
+	}
+
+	replayed := replay(sessionID, logged)
+	atomic.AddInt64(&a.audited, 1)
+
+	deltas := make(map[events.ReactionType]int64)
+	for reactionType, count := range memory.ReactionCounts {
+		if delta := count - replayed.ReactionCounts[reactionType]; delta != 0 {
+			deltas[reactionType] = delta
+		}
+	}
+	if len(deltas) == 0 && memory.TotalReactions == replayed.TotalReactions {
+		return nil, nil
+	}
+
+	divergence := Divergence{
+		SessionID:      sessionID,
+		DetectedAt:     time.Now().UTC(),
+		MemoryTotal:    memory.TotalReactions,
+		ReplayedTotal:  replayed.TotalReactions,
+		ReactionDeltas: deltas,
+	}
+	atomic.AddInt64(&a.divergences, 1)
+	a.mu.Lock()
+	a.recent = append(a.recent, divergence)
+	if len(a.recent) > maxRecentDivergences {
+		a.recent = a.recent[len(a.recent)-maxRecentDivergences:]
+	}
+	a.mu.Unlock()
+
+	log.Printf("AUDIT DIVERGENCE: session %s memory total %d, replayed total %d, deltas %v",
+		sessionID, memory.TotalReactions, replayed.TotalReactions, deltas)
+	return &divergence, nil
+}
+
+// replay recomputes a session's stats from its logged raw events
+func replay(sessionID string, logged []*events.Event) aggregation.StatsSnapshot {
+	manager := aggregation.NewManager()
+	for _, event := range logged {
+		manager.ProcessEvent(event)
+	}
+	if stats, ok := manager.GetSession(sessionID); ok {
+		return stats.GetSnapshot()
+	}
+	return aggregation.NewSessionStats(sessionID).GetSnapshot()
+}
+
+// Stats returns audit counters and the most recent divergences
+func (a *Auditor) Stats() Stats {
+	a.mu.Lock()
+	recent := append([]Divergence(nil), a.recent...)
+	a.mu.Unlock()
+
+	return Stats{
+		SessionsAudited: atomic.LoadInt64(&a.audited),
+		Skipped:         atomic.LoadInt64(&a.skipped),
+		Divergences:     atomic.LoadInt64(&a.divergences),
+		Recent:          recent,
+	}
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryEventLog struct {
+	events map[string][][]byte
+}
+
+func (m *memoryEventLog) AppendAuditEvent(_ context.Context, sessionID string, payload []byte) error {
+	m.events[sessionID] = append(m.events[sessionID], payload)
+	return nil
+}
+
+func (m *memoryEventLog) LoadAuditEvents(_ context.Context, sessionID string) ([][]byte, error) {
+	return m.events[sessionID], nil
+}
+
+func TestAuditor_AgreesWhenEveryEventIsCounted(t *testing.T) {
+	manager := aggregation.NewManager()
+	eventLog := &memoryEventLog{events: make(map[string][][]byte)}
+	auditor := NewAuditor(manager, eventLog, 1)
+
+	for _, event := range []*events.Event{
+		events.JoinSessionEvent("s1", "u1"),
+		events.ReactionEvent("s1", "u1", events.ReactionFire),
+		events.ReactionEvent("s1", "u1", events.ReactionLike),
+	} {
+		auditor.Record(context.Background(), event)
+		manager.ProcessEvent(event)
+	}
+
+	divergence, err := auditor.AuditSession(context.Background(), "s1")
+	require.NoError(t, err)
+	assert.Nil(t, divergence)
+	assert.Equal(t, int64(1), auditor.Stats().SessionsAudited)
+}
+
+func TestAuditor_LoadsEventsLoggedInEitherEncoding(t *testing.T) {
+	manager := aggregation.NewManager()
+	eventLog := &memoryEventLog{events: make(map[string][][]byte)}
+	auditor := NewAuditor(manager, eventLog, 1)
+
+	first := events.ReactionEvent("s1", "u1", events.ReactionFire)
+	auditor.Record(context.Background(), first)
+	auditor.SetEncoding(events.EncodingCompact)
+	second := events.ReactionEvent("s1", "u1", events.ReactionCheer)
+	auditor.Record(context.Background(), second)
+	assert.NotEqual(t, byte('{'), eventLog.events["s1"][1][0], "the second event is logged compact")
+
+	logged, err := auditor.LoadEvents(context.Background(), "s1")
+	require.NoError(t, err)
+	require.Len(t, logged, 2)
+	assert.Equal(t, first.ID, logged[0].ID)
+	assert.Equal(t, second.ID, logged[1].ID)
+}
+
+func TestAuditor_DetectsDoubleCounting(t *testing.T) {
+	manager := aggregation.NewManager()
+	eventLog := &memoryEventLog{events: make(map[string][][]byte)}
+	auditor := NewAuditor(manager, eventLog, 1)
+
+	event := events.ReactionEvent("s1", "u1", events.ReactionFire)
+	auditor.Record(context.Background(), event)
+	manager.ProcessEvent(event)
+	manager.ProcessEvent(event) // counted twice, logged once
+
+	divergence, err := auditor.AuditSession(context.Background(), "s1")
+	require.NoError(t, err)
+	require.NotNil(t, divergence)
+	assert.Equal(t, int64(1), divergence.ReactionDeltas[events.ReactionFire])
+	assert.Equal(t, int64(2), divergence.MemoryTotal)
+	assert.Equal(t, int64(1), auditor.Stats().Divergences)
+
+	encoded, _ := json.Marshal(auditor.Stats())
+	assert.Contains(t, string(encoded), `"divergences":1`)
+}
+
+func TestAuditor_SamplingIsDeterministic(t *testing.T) {
+	auditor := NewAuditor(aggregation.NewManager(), nil, 0.5)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		id := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		if auditor.Sampled(id) {
+			sampled++
+		}
+		assert.Equal(t, auditor.Sampled(id), auditor.Sampled(id))
+	}
+	assert.InDelta(t, 500, sampled, 100)
+	assert.False(t, NewAuditor(nil, nil, 0).Sampled("s1"))
+}
@@ -0,0 +1,241 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Archive is a store of final session snapshots
+type Archive interface {
+	SearchSessionSnapshots(ctx context.Context, q storage.ArchiveQuery) ([]storage.SessionSnapshot, bool, error)
+	GetSessionSnapshot(ctx context.Context, sessionID string) (*storage.SessionSnapshot, error)
+}
+
+// Discrepancy describes an archived session whose persisted totals disagree
+// with the counts recomputed from its raw event log
+type Discrepancy struct {
+	SessionID      string           `json:"session_id"`
+	TenantID       string           `json:"tenant_id,omitempty"`
+	EndedAt        time.Time        `json:"ended_at"`
+	DetectedAt     time.Time        `json:"detected_at"`
+	ArchivedTotal  int64            `json:"archived_total"`
+	RawTotal       int64            `json:"raw_total"`
+	ReactionDeltas map[string]int64 `json:"reaction_deltas"` // archived minus raw
+}
+
+// ReconcileStats summarizes reconciliation runs since startup. Missing
+// counts reactions in the raw log that the archive lost, Extra reactions
+// the archive holds beyond the log.
+type ReconcileStats struct {
+	Runs          int64         `json:"runs"`
+	LastRunAt     *time.Time    `json:"last_run_at,omitempty"`
+	Reconciled    int64         `json:"sessions_reconciled"`
+	Unverifiable  int64         `json:"unverifiable"`
+	Discrepancies int64         `json:"discrepancies"`
+	Missing       int64         `json:"missing_reactions"`
+	Extra         int64         `json:"extra_reactions"`
+	Recent        []Discrepancy `json:"recent,omitempty"`
+}
+
+// reconcilePageSize is how many archived sessions are listed per query
+const reconcilePageSize = 100
+
+// Reconciler compares the final snapshots of recently ended audited
+// sessions with their raw event logs, catching reactions lost or counted
+// twice between aggregation and the archive. It only covers sessions the
+// auditor samples, while their logs are still retained. Sessions whose stats
+// were reset by an operator report the reset reactions as missing.
+type Reconciler struct {
+	auditor  *Auditor
+	archives []Archive
+	lookback time.Duration
+
+	runs          int64
+	sessions      int64
+	unverifiable  int64
+	discrepancies int64
+	missing       int64
+	extra         int64
+
+	mu         sync.Mutex
+	lastRunAt  *time.Time
+	reconciled map[string]time.Time // session ID to ended at, within the lookback
+	recent     []Discrepancy
+}
+
+// NewReconciler creates a reconciler checking sessions that ended within
+// lookback in any of the archives
+func NewReconciler(auditor *Auditor, archives []Archive, lookback time.Duration) *Reconciler {
+	return &Reconciler{
+		auditor:    auditor,
+		archives:   archives,
+		lookback:   lookback,
+		reconciled: make(map[string]time.Time),
+	}
+}
+
+// Start reconciles on each interval until the context is cancelled
+func (r *Reconciler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.ReconcileAll(ctx)
+			}
+		}
+	}()
+}
+
+// ReconcileAll checks every sampled session archived within the lookback
+// that has not been checked yet
+func (r *Reconciler) ReconcileAll(ctx context.Context) {
+	now := time.Now().UTC()
+	since := now.Add(-r.lookback)
+	r.mu.Lock()
+	for sessionID, endedAt := range r.reconciled {
+		if endedAt.Before(since) {
+			delete(r.reconciled, sessionID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, archive := range r.archives {
+		q := storage.ArchiveQuery{EndedAfter: since, Sort: storage.ArchiveSortEndedAt, Limit: reconcilePageSize}
+		for {
+			page, more, err := archive.SearchSessionSnapshots(ctx, q)
+			if err != nil {
+				log.Printf("Error listing archived sessions to reconcile: %v", err)
+				break
+			}
+			for _, archived := range page {
+				if !r.auditor.Sampled(archived.SessionID) || r.checked(archived.SessionID) {
+					continue
+				}
+				if _, err := r.ReconcileSession(ctx, archive, archived.SessionID); err != nil {
+					log.Printf("Error reconciling session %s: %v", archived.SessionID, err)
+				}
+			}
+			if !more {
+				break
+			}
+			q.Offset += len(page)
+		}
+	}
+
+	atomic.AddInt64(&r.runs, 1)
+	r.mu.Lock()
+	r.lastRunAt = &now
+	r.mu.Unlock()
+}
+
+// checked reports whether a session was already reconciled
+func (r *Reconciler) checked(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.reconciled[sessionID]
+	return ok
+}
+
+// ReconcileSession compares a session's archived snapshot with the counts
+// recomputed from its raw event log. It returns nil when they agree, or
+// when the session cannot be verified because its snapshot or log is gone.
+func (r *Reconciler) ReconcileSession(ctx context.Context, archive Archive, sessionID string) (*Discrepancy, error) {
+	archived, err := archive.GetSessionSnapshot(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if archived == nil {
+		return nil, nil
+	}
+	var snapshot schema.SnapshotV1
+	if err := json.Unmarshal(archived.Snapshot, &snapshot); err != nil {
+		return nil, err
+	}
+	logged, err := r.auditor.LoadEvents(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.reconciled[sessionID] = archived.EndedAt
+	r.mu.Unlock()
+	if len(logged) == 0 && archived.TotalReactions > 0 {
+		// The log expired or was never written; nothing to compare against
+		atomic.AddInt64(&r.unverifiable, 1)
+		return nil, nil
+	}
+	atomic.AddInt64(&r.sessions, 1)
+
+	raw := replay(sessionID, logged)
+	deltas := make(map[string]int64)
+	for reactionType, count := range snapshot.ReactionCounts {
+		if delta := count - raw.ReactionCounts[events.ReactionType(reactionType)]; delta != 0 {
+			deltas[reactionType] = delta
+		}
+	}
+	for reactionType, count := range raw.ReactionCounts {
+		if _, ok := snapshot.ReactionCounts[string(reactionType)]; !ok && count != 0 {
+			deltas[string(reactionType)] = -count
+		}
+	}
+	if len(deltas) == 0 && archived.TotalReactions == raw.TotalReactions {
+		return nil, nil
+	}
+
+	discrepancy := Discrepancy{
+		SessionID:      sessionID,
+		TenantID:       archived.TenantID,
+		EndedAt:        archived.EndedAt,
+		DetectedAt:     time.Now().UTC(),
+		ArchivedTotal:  archived.TotalReactions,
+		RawTotal:       raw.TotalReactions,
+		ReactionDeltas: deltas,
+	}
+	atomic.AddInt64(&r.discrepancies, 1)
+	if delta := discrepancy.ArchivedTotal - discrepancy.RawTotal; delta < 0 {
+		atomic.AddInt64(&r.missing, -delta)
+	} else {
+		atomic.AddInt64(&r.extra, delta)
+	}
+	r.mu.Lock()
+	r.recent = append(r.recent, discrepancy)
+	if len(r.recent) > maxRecentDivergences {
+		r.recent = r.recent[len(r.recent)-maxRecentDivergences:]
+	}
+	r.mu.Unlock()
+
+	log.Printf("RECONCILE DISCREPANCY: session %s archived total %d, raw total %d, deltas %v",
+		sessionID, archived.TotalReactions, raw.TotalReactions, deltas)
+	return &discrepancy, nil
+}
+
+// Stats returns reconciliation counters and the most recent discrepancies
+func (r *Reconciler) Stats() ReconcileStats {
+	r.mu.Lock()
+	recent := append([]Discrepancy(nil), r.recent...)
+	lastRunAt := r.lastRunAt
+	r.mu.Unlock()
+
+	return ReconcileStats{
+		Runs:          atomic.LoadInt64(&r.runs),
+		LastRunAt:     lastRunAt,
+		Reconciled:    atomic.LoadInt64(&r.sessions),
+		Unverifiable:  atomic.LoadInt64(&r.unverifiable),
+		Discrepancies: atomic.LoadInt64(&r.discrepancies),
+		Missing:       atomic.LoadInt64(&r.missing),
+		Extra:         atomic.LoadInt64(&r.extra),
+		Recent:        recent,
+	}
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryArchive struct {
+	snapshots map[string]storage.SessionSnapshot
+}
+
+func (m *memoryArchive) SearchSessionSnapshots(_ context.Context, q storage.ArchiveQuery) ([]storage.SessionSnapshot, bool, error) {
+	var results []storage.SessionSnapshot
+	for _, s := range m.snapshots {
+		if !s.EndedAt.Before(q.EndedAfter) {
+			results = append(results, s)
+		}
+	}
+	return results, false, nil
+}
+
+func (m *memoryArchive) GetSessionSnapshot(_ context.Context, sessionID string) (*storage.SessionSnapshot, error) {
+	s, ok := m.snapshots[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// archive stores a session's final stats as the lifecycle does on end
+func (m *memoryArchive) archive(stats aggregation.StatsSnapshot, endedAt time.Time) {
+	body, _ := json.Marshal(schema.SnapshotV1From(stats))
+	m.snapshots[stats.SessionID] = storage.SessionSnapshot{
+		SessionID:      stats.SessionID,
+		TotalReactions: stats.TotalReactions,
+		Snapshot:       body,
+		EndedAt:        endedAt,
+	}
+}
+
+func TestReconciler_ReportsReactionsLostBeforeTheArchive(t *testing.T) {
+	eventLog := &memoryEventLog{events: make(map[string][][]byte)}
+	auditor := NewAuditor(aggregation.NewManager(), eventLog, 1)
+	archive := &memoryArchive{snapshots: make(map[string]storage.SessionSnapshot)}
+	reconciler := NewReconciler(auditor, []Archive{archive}, time.Hour)
+
+	for _, sessionID := range []string{"s1", "s2"} {
+		manager := aggregation.NewManager()
+		for i, event := range []*events.Event{
+			events.JoinSessionEvent(sessionID, "u1"),
+			events.ReactionEvent(sessionID, "u1", events.ReactionFire),
+			events.ReactionEvent(sessionID, "u1", events.ReactionLike),
+		} {
+			auditor.Record(context.Background(), event)
+			if sessionID == "s1" && i == 2 {
+				continue // lost on the write path
+			}
+			manager.ProcessEvent(event)
+		}
+		stats, _ := manager.GetSession(sessionID)
+		archive.archive(stats.GetSnapshot(), time.Now().UTC())
+	}
+	archive.snapshots["old"] = storage.SessionSnapshot{SessionID: "old", EndedAt: time.Now().Add(-2 * time.Hour)}
+
+	reconciler.ReconcileAll(context.Background())
+	reconciler.ReconcileAll(context.Background())
+
+	stats := reconciler.Stats()
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Equal(t, int64(2), stats.Reconciled, "sessions are reconciled once, within the lookback")
+	assert.Equal(t, int64(1), stats.Discrepancies)
+	assert.Equal(t, int64(1), stats.Missing)
+	require.Len(t, stats.Recent, 1)
+	assert.Equal(t, "s1", stats.Recent[0].SessionID)
+	assert.Equal(t, int64(1), stats.Recent[0].ArchivedTotal)
+	assert.Equal(t, int64(2), stats.Recent[0].RawTotal)
+	assert.Equal(t, map[string]int64{string(events.ReactionLike): -1}, stats.Recent[0].ReactionDeltas)
+}
+
+func TestReconciler_SkipsSessionsWhoseLogExpired(t *testing.T) {
+	eventLog := &memoryEventLog{events: make(map[string][][]byte)}
+	archive := &memoryArchive{snapshots: map[string]storage.SessionSnapshot{
+		"s1": {SessionID: "s1", TotalReactions: 5, Snapshot: []byte(`{"total_reactions":5}`), EndedAt: time.Now()},
+	}}
+	reconciler := NewReconciler(NewAuditor(aggregation.NewManager(), eventLog, 1), []Archive{archive}, time.Hour)
+
+	discrepancy, err := reconciler.ReconcileSession(context.Background(), archive, "s1")
+	require.NoError(t, err)
+	assert.Nil(t, discrepancy)
+	assert.Equal(t, int64(1), reconciler.Stats().Unverifiable)
+}
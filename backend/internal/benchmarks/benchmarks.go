@@ -0,0 +1,169 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Result captures the throughput of a single capacity scenario
+type Result struct {
+	Name      string        `json:"name"`
+	Ops       int           `json:"ops"`
+	Duration  time.Duration `json:"duration"`
+	OpsPerSec float64       `json:"ops_per_sec"`
+	PerCore   float64       `json:"ops_per_sec_per_core"`
+}
+
+// newResult derives rates for a completed scenario
+func newResult(name string, ops int, elapsed time.Duration) Result {
+	rate := float64(ops) / elapsed.Seconds()
+	return Result{
+		Name:      name,
+		Ops:       ops,
+		Duration:  elapsed,
+		OpsPerSec: rate,
+		PerCore:   rate / float64(runtime.GOMAXPROCS(0)),
+	}
+}
+
+// String formats the result as a single report line
+func (r Result) String() string {
+	return fmt.Sprintf("%-28s %10d ops  %10s  %12.0f ops/s  %12.0f ops/s/core",
+		r.Name, r.Ops, r.Duration.Round(time.Millisecond), r.OpsPerSec, r.PerCore)
+}
+
+// reactionTypes cycles through every built-in reaction in the scenarios
+var reactionTypes = []events.ReactionType{
+	events.ReactionLike,
+	events.ReactionLove,
+	events.ReactionCheer,
+	events.ReactionApplause,
+	events.ReactionFire,
+	events.ReactionHeart,
+}
+
+// runParallel splits ops across workers and returns the elapsed wall time
+func runParallel(workers, ops int, fn func(worker, i int)) time.Duration {
+	var wg sync.WaitGroup
+	perWorker := ops / workers
+
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				fn(worker, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// ReactionContention measures IncrementReaction throughput with every worker
+// hammering the same hot session
+func ReactionContention(workers, ops int) Result {
+	stats := aggregation.NewSessionStats("bench-hot-session")
+	elapsed := runParallel(workers, ops, func(_, i int) {
+		stats.IncrementReaction(reactionTypes[i%len(reactionTypes)])
+	})
+	return newResult("reaction_contention", (ops/workers)*workers, elapsed)
+}
+
+// ManagerSessions measures Manager.ProcessEvent throughput with reactions
+// spread across many sessions, exercising the session map under concurrency
+func ManagerSessions(sessions, workers, ops int) Result {
+	manager := aggregation.NewManager()
+	prebuilt := make([]*events.Event, sessions)
+	for i := range prebuilt {
+		prebuilt[i] = events.ReactionEvent(fmt.Sprintf("bench-session-%d", i), "bench-user", events.ReactionFire)
+	}
+
+	elapsed := runParallel(workers, ops, func(worker, i int) {
+		manager.ProcessEvent(prebuilt[(worker*31+i)%sessions])
+	})
+	return newResult(fmt.Sprintf("manager_sessions_%d", sessions), (ops/workers)*workers, elapsed)
+}
+
+// QueueThroughput measures end-to-end Enqueue/Dequeue throughput with the
+// given number of producers and consumers sharing one queue
+func QueueThroughput(producers, consumers, ops int) Result {
+	queue := events.NewQueue(10000)
+	event := events.ReactionEvent("bench-session", "bench-user", events.ReactionFire)
+	perProducer := ops / producers
+	total := perProducer * producers
+
+	var consumed sync.WaitGroup
+	consumed.Add(total)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for c := 0; c < consumers; c++ {
+		go func() {
+			for {
+				if _, ok := queue.Dequeue(ctx); !ok {
+					return
+				}
+				consumed.Done()
+			}
+		}()
+	}
+
+	start := time.Now()
+	var produced sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		produced.Add(1)
+		go func() {
+			defer produced.Done()
+			for i := 0; i < perProducer; i++ {
+				// Back off while the buffer is full so the drop path is not measured
+				for queue.Free(event.Type) == 0 || queue.Enqueue(ctx, event) != nil {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	produced.Wait()
+	consumed.Wait()
+	return newResult("queue_throughput", total, time.Since(start))
+}
+
+// BroadcastFanout measures how many client deliveries per second a single
+// session hub sustains when broadcasting to the given number of subscribers
+func BroadcastFanout(subscribers, messages int) Result {
+	hub := api.NewWebSocketHub()
+	sessionHub := hub.GetOrCreateSessionHub("bench-broadcast")
+
+	var delivered sync.WaitGroup
+	delivered.Add(subscribers * messages)
+	unsubscribers := make([]func(), 0, subscribers)
+	for i := 0; i < subscribers; i++ {
+		ch, unsubscribe := sessionHub.Subscribe(messages)
+		unsubscribers = append(unsubscribers, unsubscribe)
+		go func() {
+			for range ch {
+				delivered.Done()
+			}
+		}()
+	}
+	defer func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}()
+
+	message := map[string]interface{}{"type": "reaction", "reaction_type": events.ReactionFire}
+	start := time.Now()
+	for i := 0; i < messages; i++ {
+		hub.BroadcastToSession("bench-broadcast", message)
+	}
+	delivered.Wait()
+	return newResult(fmt.Sprintf("broadcast_fanout_%d", subscribers), subscribers*messages, time.Since(start))
+}
@@ -0,0 +1,95 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+func BenchmarkIncrementReaction_Contended(b *testing.B) {
+	stats := aggregation.NewSessionStats("bench-hot-session")
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			stats.IncrementReaction(reactionTypes[i%len(reactionTypes)])
+			i++
+		}
+	})
+}
+
+func BenchmarkManager_ProcessEvent(b *testing.B) {
+	for _, sessions := range []int{1, 100, 10000} {
+		b.Run(fmt.Sprintf("sessions=%d", sessions), func(b *testing.B) {
+			manager := aggregation.NewManager()
+			prebuilt := make([]*events.Event, sessions)
+			for i := range prebuilt {
+				prebuilt[i] = events.ReactionEvent(fmt.Sprintf("bench-session-%d", i), "bench-user", events.ReactionFire)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					manager.ProcessEvent(prebuilt[i%sessions])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkQueue_EnqueueDequeue(b *testing.B) {
+	queue := events.NewQueue(10000)
+	event := events.ReactionEvent("bench-session", "bench-user", events.ReactionFire)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			queue.Enqueue(context.Background(), event)
+			queue.Dequeue(ctx)
+		}
+	})
+}
+
+func BenchmarkBroadcast_Fanout(b *testing.B) {
+	for _, subscribers := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			hub := api.NewWebSocketHub()
+			sessionHub := hub.GetOrCreateSessionHub("bench-broadcast")
+			done := make(chan struct{}, subscribers)
+			for i := 0; i < subscribers; i++ {
+				ch, unsubscribe := sessionHub.Subscribe(256)
+				defer unsubscribe()
+				go func() {
+					// Slow subscribers are dropped by the hub, so count a closed
+					// channel as finished rather than waiting forever
+					received, signalled := 0, false
+					for range ch {
+						received++
+						if received == b.N && !signalled {
+							signalled = true
+							done <- struct{}{}
+						}
+					}
+					if !signalled {
+						done <- struct{}{}
+					}
+				}()
+			}
+
+			message := map[string]interface{}{"type": "reaction", "reaction_type": events.ReactionFire}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hub.BroadcastToSession("bench-broadcast", message)
+			}
+			for i := 0; i < subscribers; i++ {
+				<-done
+			}
+		})
+	}
+}
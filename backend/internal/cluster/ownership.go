@@ -0,0 +1,312 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// LeaseStore is the shared storage used to coordinate session ownership
+// between LivePulse instances
+type LeaseStore interface {
+	AcquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	RenewLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, key, owner string) error
+	LeaseOwner(ctx context.Context, key string) (string, error)
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) <-chan []byte
+}
+
+// OwnershipStore is the shared storage a Coordinator runs over: leases for
+// ownership and a durable stream per instance for forwarded events
+type OwnershipStore interface {
+	LeaseStore
+	events.StreamBus
+}
+
+// Forwarded events wait in the owner's inbox stream, read by its "owner"
+// consumer group, until the owner acknowledges them
+const (
+	inboxGroup  = "owner"
+	inboxMaxLen = 100000
+)
+
+// Coordinator ensures exactly one instance aggregates each session. The
+// owning instance holds a lease per session; every other instance forwards
+// events for that session to the owner's inbox stream.
+type Coordinator struct {
+	store      OwnershipStore
+	instanceID string
+	ttl        time.Duration
+	now        func() time.Time
+	owned      map[string]bool        // sessionID -> lease held by this instance
+	owners     map[string]remoteOwner // sessionID -> another instance's lease, as last seen
+	handoff    func(ctx context.Context, sessionID string)
+	mu         sync.RWMutex
+}
+
+// remoteOwner caches the instance holding a session's lease. A lease is
+// renewed every third of its TTL, so an owner seen within that window can be
+// trusted without asking the store again.
+type remoteOwner struct {
+	instanceID string
+	expires    time.Time
+}
+
+// NewCoordinator creates a new session ownership coordinator
+func NewCoordinator(store OwnershipStore, instanceID string, ttl time.Duration) *Coordinator {
+	return &Coordinator{
+		store:      store,
+		instanceID: instanceID,
+		ttl:        ttl,
+		now:        time.Now,
+		owned:      make(map[string]bool),
+		owners:     make(map[string]remoteOwner),
+	}
+}
+
+// SetHandoff sets what runs when this instance acquires a session while
+// routing its events, before the event that triggered it is processed. It
+// restores the state the previous owner checkpointed.
+func (c *Coordinator) SetHandoff(handoff func(ctx context.Context, sessionID string)) {
+	c.handoff = handoff
+}
+
+// leaseKey returns the lease key for a session
+func leaseKey(sessionID string) string {
+	return "lease:session:" + sessionID
+}
+
+// inboxStream returns the stream an instance reads forwarded events from
+func inboxStream(instanceID string) string {
+	return "events:inbox:" + instanceID
+}
+
+// InstanceID returns the identifier this instance uses as lease owner
+func (c *Coordinator) InstanceID() string {
+	return c.instanceID
+}
+
+// Owns reports whether this instance currently holds the lease for a session
+func (c *Coordinator) Owns(sessionID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.owned[sessionID]
+}
+
+// OwnedSessions returns the IDs of every session this instance owns
+func (c *Coordinator) OwnedSessions() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.owned))
+	for id := range c.owned {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Route decides where an event is aggregated. It returns true when the event
+// should be processed locally, claiming the session if it has no live owner,
+// and false once the event has been forwarded to the current owner.
+func (c *Coordinator) Route(ctx context.Context, event *events.Event) (bool, error) {
+	if c.Owns(event.SessionID) {
+		return true, nil
+	}
+	if owner, ok := c.cachedOwner(event.SessionID); ok {
+		if err := c.forward(ctx, owner, event); err != nil {
+			c.forgetOwner(event.SessionID)
+			return true, err
+		}
+		return false, nil
+	}
+
+	key := leaseKey(event.SessionID)
+	for attempt := 0; attempt < 2; attempt++ {
+		acquired, err := c.store.AcquireLease(ctx, key, c.instanceID, c.ttl)
+		if err != nil {
+			// Availability over strict ownership: keep counting locally
+			return true, err
+		}
+		if acquired {
+			c.markOwned(event.SessionID)
+			log.Printf("Acquired ownership of session %s", event.SessionID)
+			if c.handoff != nil {
+				c.handoff(ctx, event.SessionID)
+			}
+			return true, nil
+		}
+
+		owner, err := c.store.LeaseOwner(ctx, key)
+		if err != nil {
+			return true, err
+		}
+		if owner == c.instanceID {
+			c.markOwned(event.SessionID)
+			return true, nil
+		}
+		if owner == "" {
+			// Lease expired between acquire and lookup, race for it again
+			continue
+		}
+
+		c.cacheOwner(event.SessionID, owner)
+		if err := c.forward(ctx, owner, event); err != nil {
+			c.forgetOwner(event.SessionID)
+			return true, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// forward appends an event to the owner's inbox stream
+func (c *Coordinator) forward(ctx context.Context, owner string, event *events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.store.AppendStream(ctx, inboxStream(owner), data, inboxMaxLen)
+}
+
+// cachedOwner returns the other instance last seen owning a session, if
+// that sighting is recent enough to trust
+func (c *Coordinator) cachedOwner(sessionID string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	owner, exists := c.owners[sessionID]
+	if !exists || !c.now().Before(owner.expires) {
+		return "", false
+	}
+	return owner.instanceID, true
+}
+
+// cacheOwner remembers another instance's lease for a third of its TTL
+func (c *Coordinator) cacheOwner(sessionID, owner string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owners[sessionID] = remoteOwner{instanceID: owner, expires: c.now().Add(c.ttl / 3)}
+}
+
+// forgetOwner drops a cached owner, so the next event looks the lease up
+func (c *Coordinator) forgetOwner(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.owners, sessionID)
+}
+
+// Claim takes ownership of a session if its lease is free or already held
+// by this instance, as after a restart. It reports whether this instance now
+// owns the session.
+func (c *Coordinator) Claim(ctx context.Context, sessionID string) bool {
+	if c.Owns(sessionID) {
+		return true
+	}
+	key := leaseKey(sessionID)
+	acquired, err := c.store.AcquireLease(ctx, key, c.instanceID, c.ttl)
+	if err == nil && !acquired {
+		var owner string
+		owner, err = c.store.LeaseOwner(ctx, key)
+		acquired = owner == c.instanceID
+	}
+	if err != nil {
+		log.Printf("Error claiming session %s: %v", sessionID, err)
+		return false
+	}
+	if acquired {
+		c.markOwned(sessionID)
+	}
+	return acquired
+}
+
+// markOwned records that this instance holds a session's lease
+func (c *Coordinator) markOwned(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owned[sessionID] = true
+	delete(c.owners, sessionID)
+}
+
+// Start reads events other instances forwarded to this one and keeps owned
+// leases alive until the context is cancelled. A forwarded event is
+// acknowledged once deliver accepts it; events it refuses, or that were in
+// flight when an instance stopped, are redelivered, so delivery is at least
+// once.
+func (c *Coordinator) Start(ctx context.Context, deliver func(context.Context, *events.Event) error) error {
+	inbox, err := events.NewStreamTransport(c.store, inboxStream(c.instanceID), inboxGroup, c.instanceID, inboxMaxLen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		inbox.Close()
+	}()
+	go func() {
+		for {
+			event, ok := inbox.Dequeue(ctx)
+			if !ok {
+				return
+			}
+			if err := deliver(ctx, event); err != nil {
+				log.Printf("Error delivering forwarded event %s, leaving it for redelivery: %v", event.ID, err)
+				continue
+			}
+			inbox.Ack(event)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(c.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.renew(ctx)
+			}
+		}
+	}()
+
+	log.Printf("Session ownership coordinator started as instance %s", c.instanceID)
+	return nil
+}
+
+// renew extends every owned lease, dropping the ones that were lost
+func (c *Coordinator) renew(ctx context.Context) {
+	for _, sessionID := range c.OwnedSessions() {
+		ok, err := c.store.RenewLease(ctx, leaseKey(sessionID), c.instanceID, c.ttl)
+		if err != nil {
+			log.Printf("Error renewing lease for session %s: %v", sessionID, err)
+			continue
+		}
+		if !ok {
+			c.mu.Lock()
+			delete(c.owned, sessionID)
+			c.mu.Unlock()
+			log.Printf("Lost ownership of session %s", sessionID)
+		}
+	}
+}
+
+// Release gives up ownership of a single session
+func (c *Coordinator) Release(ctx context.Context, sessionID string) error {
+	c.mu.Lock()
+	delete(c.owned, sessionID)
+	c.mu.Unlock()
+	return c.store.ReleaseLease(ctx, leaseKey(sessionID), c.instanceID)
+}
+
+// ReleaseAll gives up every owned session so another instance can take over
+// immediately instead of waiting for the leases to expire
+func (c *Coordinator) ReleaseAll(ctx context.Context) {
+	for _, sessionID := range c.OwnedSessions() {
+		if err := c.Release(ctx, sessionID); err != nil {
+			log.Printf("Error releasing lease for session %s: %v", sessionID, err)
+		}
+	}
+}
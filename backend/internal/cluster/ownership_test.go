@@ -0,0 +1,275 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLeaseStore is an in-process OwnershipStore used to simulate a cluster
+type memoryLeaseStore struct {
+	leases    map[string]string
+	published map[string][][]byte
+	streams   map[string][]storage.StreamEntry
+	read      map[string]int             // stream -> entries delivered to its group
+	acked     map[string]map[string]bool // stream -> acknowledged entry IDs
+	lookups   int                        // AcquireLease and LeaseOwner calls
+	mu        sync.Mutex
+}
+
+func newMemoryLeaseStore() *memoryLeaseStore {
+	return &memoryLeaseStore{
+		leases:    make(map[string]string),
+		published: make(map[string][][]byte),
+		streams:   make(map[string][]storage.StreamEntry),
+		read:      make(map[string]int),
+		acked:     make(map[string]map[string]bool),
+	}
+}
+
+func (m *memoryLeaseStore) AcquireLease(_ context.Context, key, owner string, _ time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups++
+	if _, held := m.leases[key]; held {
+		return false, nil
+	}
+	m.leases[key] = owner
+	return true, nil
+}
+
+func (m *memoryLeaseStore) RenewLease(_ context.Context, key, owner string, _ time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leases[key] == owner, nil
+}
+
+func (m *memoryLeaseStore) ReleaseLease(_ context.Context, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leases[key] == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+func (m *memoryLeaseStore) LeaseOwner(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups++
+	return m.leases[key], nil
+}
+
+func (m *memoryLeaseStore) Publish(_ context.Context, channel string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published[channel] = append(m.published[channel], payload)
+	return nil
+}
+
+func (m *memoryLeaseStore) Subscribe(_ context.Context, _ string) <-chan []byte {
+	return make(chan []byte)
+}
+
+func (m *memoryLeaseStore) AppendStream(_ context.Context, stream string, payload []byte, _ int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := fmt.Sprintf("%d-0", len(m.streams[stream])+1)
+	m.streams[stream] = append(m.streams[stream], storage.StreamEntry{Stream: stream, ID: id, Payload: payload})
+	return nil
+}
+
+func (m *memoryLeaseStore) EnsureGroup(context.Context, string, string) error {
+	return nil
+}
+
+func (m *memoryLeaseStore) ReadGroup(ctx context.Context, stream, _, _ string, _ int64, _ time.Duration) ([]storage.StreamEntry, error) {
+	m.mu.Lock()
+	entries := m.streams[stream][m.read[stream]:]
+	m.read[stream] = len(m.streams[stream])
+	m.mu.Unlock()
+	if len(entries) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryLeaseStore) ClaimStale(context.Context, string, string, string, time.Duration, int64) ([]storage.StreamEntry, error) {
+	return nil, nil
+}
+
+func (m *memoryLeaseStore) AckStream(_ context.Context, stream, _ string, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.acked[stream] == nil {
+		m.acked[stream] = make(map[string]bool)
+	}
+	for _, id := range ids {
+		m.acked[stream][id] = true
+	}
+	return nil
+}
+
+// forwarded returns how many events were appended to an instance's inbox
+func (m *memoryLeaseStore) forwarded(instanceID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.streams[inboxStream(instanceID)])
+}
+
+// expire simulates a lease timing out after its owner died
+func (m *memoryLeaseStore) expire(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases, key)
+}
+
+func TestCoordinator_FirstInstanceClaimsSession(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+	b := NewCoordinator(store, "instance-b", 15*time.Second)
+
+	local, err := a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	require.NoError(t, err)
+	assert.True(t, local, "first instance should claim an unowned session")
+	assert.True(t, a.Owns("session-1"))
+
+	local, err = b.Route(context.Background(), events.ReactionEvent("session-1", "user-2", events.ReactionFire))
+	require.NoError(t, err)
+	assert.False(t, local, "second instance should forward instead of aggregating")
+	assert.False(t, b.Owns("session-1"))
+	assert.Equal(t, 1, store.forwarded("instance-a"), "event should be forwarded to the owner's inbox")
+}
+
+func TestCoordinator_TakeoverAfterLeaseExpires(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+	b := NewCoordinator(store, "instance-b", 15*time.Second)
+
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	store.expire(leaseKey("session-1"))
+
+	local, err := b.Route(context.Background(), events.JoinSessionEvent("session-1", "user-2"))
+	require.NoError(t, err)
+	assert.True(t, local, "surviving instance should take over an expired session")
+	assert.True(t, b.Owns("session-1"))
+
+	// The old owner notices the loss on its next renewal
+	a.renew(context.Background())
+	assert.False(t, a.Owns("session-1"))
+}
+
+func TestCoordinator_ReleaseAllHandsOffSessions(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-2", "user-1"))
+	a.ReleaseAll(context.Background())
+
+	assert.Empty(t, a.OwnedSessions())
+	owner, _ := store.LeaseOwner(context.Background(), leaseKey("session-1"))
+	assert.Empty(t, owner)
+}
+
+func TestCoordinator_CachesRemoteOwnerForARenewalInterval(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+	b := NewCoordinator(store, "instance-b", 15*time.Second)
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	_, _ = b.Route(context.Background(), events.ReactionEvent("session-1", "user-2", events.ReactionFire))
+	lookups := store.lookups
+
+	// Further events go straight to the cached owner
+	for i := 0; i < 3; i++ {
+		local, err := b.Route(context.Background(), events.ReactionEvent("session-1", "user-2", events.ReactionFire))
+		require.NoError(t, err)
+		assert.False(t, local)
+	}
+	assert.Equal(t, lookups, store.lookups, "a cached owner needs no lease lookups")
+	assert.Equal(t, 4, store.forwarded("instance-a"))
+
+	// Once the owner may have missed a renewal, b checks the lease again and
+	// takes over the expired session
+	store.expire(leaseKey("session-1"))
+	now = now.Add(5 * time.Second)
+	local, err := b.Route(context.Background(), events.ReactionEvent("session-1", "user-2", events.ReactionFire))
+	require.NoError(t, err)
+	assert.True(t, local)
+	assert.True(t, b.Owns("session-1"))
+}
+
+func TestCoordinator_HandsOffOnlyWhenAcquiringASession(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+	b := NewCoordinator(store, "instance-b", 15*time.Second)
+	var handedOff []string
+	b.SetHandoff(func(_ context.Context, sessionID string) { handedOff = append(handedOff, sessionID) })
+
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	_, _ = b.Route(context.Background(), events.JoinSessionEvent("session-1", "user-2"))
+	assert.Empty(t, handedOff, "forwarding hands nothing off")
+
+	store.expire(leaseKey("session-1"))
+	b.forgetOwner("session-1")
+	_, _ = b.Route(context.Background(), events.JoinSessionEvent("session-1", "user-2"))
+	_, _ = b.Route(context.Background(), events.JoinSessionEvent("session-1", "user-3"))
+	assert.Equal(t, []string{"session-1"}, handedOff)
+}
+
+func TestCoordinator_AcknowledgesForwardedEventsOnceDelivered(t *testing.T) {
+	store := newMemoryLeaseStore()
+	a := NewCoordinator(store, "instance-a", 15*time.Second)
+	b := NewCoordinator(store, "instance-b", 15*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delivered := make(chan *events.Event, 2)
+	refused := false
+	require.NoError(t, a.Start(ctx, func(_ context.Context, event *events.Event) error {
+		if !refused {
+			refused = true
+			return errors.New("queue full")
+		}
+		delivered <- event
+		return nil
+	}))
+
+	_, _ = a.Route(context.Background(), events.JoinSessionEvent("session-1", "user-1"))
+	_, _ = b.Route(context.Background(), events.ReactionEvent("session-1", "user-2", events.ReactionFire))
+	_, _ = b.Route(context.Background(), events.ReactionEvent("session-1", "user-3", events.ReactionLike))
+
+	select {
+	case event := <-delivered:
+		assert.Equal(t, "user-3", event.UserID)
+		reactionType, ok := event.GetReactionType()
+		assert.True(t, ok)
+		assert.Equal(t, events.ReactionLike, reactionType)
+	case <-time.After(time.Second):
+		t.Fatal("forwarded event was not delivered")
+	}
+
+	stream := inboxStream("instance-a")
+	assert.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return store.acked[stream]["2-0"]
+	}, time.Second, 5*time.Millisecond)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.False(t, store.acked[stream]["1-0"], "a refused event stays pending for redelivery")
+}
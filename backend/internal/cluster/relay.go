@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// PubSub is the shared channel transport hub nodes relay broadcasts over
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) <-chan []byte
+}
+
+// relayEnvelope wraps a broadcast forwarded between hub nodes. Seq counts
+// the origin's broadcasts for the session so receivers can detect gaps.
+type relayEnvelope struct {
+	Origin  string          `json:"origin"`
+	Seq     uint64          `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// broadcastChannel returns the channel a session's broadcasts are relayed on
+func broadcastChannel(sessionID string) string {
+	return "broadcast:session:" + sessionID
+}
+
+// Relay lets several hub nodes serve the same session. Every broadcast is
+// published to the session's shared channel instead of being delivered
+// directly, and every node with local clients delivers what it reads back.
+// Because all nodes, including the origin, consume the same channel, their
+// clients receive identical streams in the same order.
+type Relay struct {
+	ctx        context.Context
+	store      PubSub
+	instanceID string
+
+	sent   map[string]uint64 // sessionID -> broadcasts published by this node
+	joined map[string]bool
+	mu     sync.Mutex
+}
+
+// NewRelay creates a broadcast relay whose subscriptions end with ctx
+func NewRelay(ctx context.Context, store PubSub, instanceID string) *Relay {
+	return &Relay{
+		ctx:        ctx,
+		store:      store,
+		instanceID: instanceID,
+		sent:       make(map[string]uint64),
+		joined:     make(map[string]bool),
+	}
+}
+
+// Publish relays a broadcast to every node serving the session
+func (r *Relay) Publish(sessionID string, payload []byte) error {
+	r.mu.Lock()
+	r.sent[sessionID]++
+	seq := r.sent[sessionID]
+	r.mu.Unlock()
+
+	data, err := json.Marshal(relayEnvelope{Origin: r.instanceID, Seq: seq, Payload: payload})
+	if err != nil {
+		return err
+	}
+	return r.store.Publish(r.ctx, broadcastChannel(sessionID), data)
+}
+
+// Join starts delivering a session's relayed broadcasts to deliver, in the
+// order the shared channel carries them. Joining twice is a no-op.
+func (r *Relay) Join(sessionID string, deliver func(payload []byte)) {
+	r.mu.Lock()
+	if r.joined[sessionID] {
+		r.mu.Unlock()
+		return
+	}
+	r.joined[sessionID] = true
+	r.mu.Unlock()
+
+	inbox := r.store.Subscribe(r.ctx, broadcastChannel(sessionID))
+	go func() {
+		last := make(map[string]uint64) // origin -> last seq delivered
+		for data := range inbox {
+			var env relayEnvelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				log.Printf("Error decoding relayed broadcast for session %s: %v", sessionID, err)
+				continue
+			}
+			prev := last[env.Origin]
+			switch {
+			case env.Seq <= prev && env.Seq != 1:
+				// Duplicate; a seq of 1 means the origin restarted its count
+				continue
+			case prev != 0 && env.Seq > prev+1:
+				log.Printf("Missed %d relayed broadcasts from %s for session %s", env.Seq-prev-1, env.Origin, sessionID)
+			}
+			last[env.Origin] = env.Seq
+			deliver(env.Payload)
+		}
+	}()
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPubSub fans every published payload out to all channel subscribers
+type memoryPubSub struct {
+	subscribers map[string][]chan []byte
+	mu          sync.Mutex
+}
+
+func newMemoryPubSub() *memoryPubSub {
+	return &memoryPubSub{subscribers: make(map[string][]chan []byte)}
+}
+
+func (m *memoryPubSub) Publish(_ context.Context, channel string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.subscribers[channel] {
+		sub <- payload
+	}
+	return nil
+}
+
+func (m *memoryPubSub) Subscribe(_ context.Context, channel string) <-chan []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub := make(chan []byte, 16)
+	m.subscribers[channel] = append(m.subscribers[channel], sub)
+	return sub
+}
+
+// collect returns a deliver func recording payloads and a way to read them
+func collect() (func([]byte), func(n int) []string) {
+	got := make(chan string, 16)
+	deliver := func(payload []byte) { got <- string(payload) }
+	read := func(n int) []string {
+		var out []string
+		for len(out) < n {
+			select {
+			case p := <-got:
+				out = append(out, p)
+			case <-time.After(200 * time.Millisecond):
+				return out
+			}
+		}
+		return out
+	}
+	return deliver, read
+}
+
+func TestRelay_DeliversIdenticalStreamsToEveryNode(t *testing.T) {
+	bus := newMemoryPubSub()
+	nodeA := NewRelay(context.Background(), bus, "node-a")
+	nodeB := NewRelay(context.Background(), bus, "node-b")
+
+	deliverA, readA := collect()
+	deliverB, readB := collect()
+	nodeA.Join("s1", deliverA)
+	nodeB.Join("s1", deliverB)
+	nodeB.Join("s1", deliverB) // joining again must not double deliver
+
+	require.NoError(t, nodeA.Publish("s1", []byte(`{"n":1}`)))
+	require.NoError(t, nodeB.Publish("s1", []byte(`{"n":2}`)))
+	require.NoError(t, nodeA.Publish("s1", []byte(`{"n":3}`)))
+
+	want := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}
+	assert.Equal(t, want, readA(3), "the origin receives its own broadcasts back in order")
+	assert.Equal(t, want, readB(3))
+	assert.Empty(t, readB(1))
+}
+
+func TestRelay_DropsDuplicateEnvelopes(t *testing.T) {
+	bus := newMemoryPubSub()
+	relay := NewRelay(context.Background(), bus, "node-a")
+	deliver, read := collect()
+	relay.Join("s1", deliver)
+
+	send := func(seq uint64, payload string) {
+		data, _ := json.Marshal(relayEnvelope{Origin: "node-b", Seq: seq, Payload: json.RawMessage(payload)})
+		require.NoError(t, bus.Publish(context.Background(), broadcastChannel("s1"), data))
+	}
+	send(1, `"a"`)
+	send(2, `"b"`)
+	send(2, `"b"`)
+	send(1, `"restart"`)
+
+	assert.Equal(t, []string{`"a"`, `"b"`, `"restart"`}, read(3))
+	assert.Empty(t, read(1))
+}
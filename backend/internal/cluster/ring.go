@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of virtual points each node places on the
+// ring, enough to spread sessions evenly across a handful of nodes
+const DefaultReplicas = 100
+
+// Ring assigns sessions to hub nodes by consistent hashing, so a session
+// keeps landing on the same node and only the sessions of a departed node
+// move when membership changes
+type Ring struct {
+	replicas int
+	points   []uint32          // sorted hashes of every virtual point
+	owners   map[uint32]string // point -> node
+	nodes    map[string]bool
+	mu       sync.RWMutex
+}
+
+// NewRing creates a ring holding the given nodes. A non-positive replica
+// count uses DefaultReplicas.
+func NewRing(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]bool),
+	}
+	r.Add(nodes...)
+	return r
+}
+
+// pointHash returns the ring position of one of a node's virtual points
+func pointHash(node string, replica int) uint32 {
+	return crc32.ChecksumIEEE([]byte(strconv.Itoa(replica) + "#" + node))
+}
+
+// Add places nodes on the ring, ignoring ones already present
+func (r *Ring) Add(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, node := range nodes {
+		if node == "" || r.nodes[node] {
+			continue
+		}
+		r.nodes[node] = true
+		for i := 0; i < r.replicas; i++ {
+			point := pointHash(node, i)
+			if _, taken := r.owners[point]; taken {
+				continue
+			}
+			r.owners[point] = node
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// Remove takes a node off the ring; its sessions move to their next node
+func (r *Ring) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.nodes[node] {
+		return
+	}
+	delete(r.nodes, node)
+
+	kept := r.points[:0]
+	for _, point := range r.points {
+		if r.owners[point] == node {
+			delete(r.owners, point)
+			continue
+		}
+		kept = append(kept, point)
+	}
+	r.points = kept
+}
+
+// Node returns the node a session is routed to, or "" if the ring is empty
+func (r *Ring) Node(sessionID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(sessionID))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Nodes returns every node on the ring in sorted order
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing_RoutesSessionsConsistently(t *testing.T) {
+	ring := NewRing(0, "node-a", "node-b", "node-c")
+	other := NewRing(0, "node-c", "node-a", "node-b")
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		assert.Equal(t, ring.Node(id), ring.Node(id))
+		assert.Equal(t, ring.Node(id), other.Node(id), "routing must not depend on node order")
+	}
+}
+
+func TestRing_RemoveOnlyMovesDepartedNodesSessions(t *testing.T) {
+	ring := NewRing(0, "node-a", "node-b", "node-c")
+	before := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		before[id] = ring.Node(id)
+	}
+
+	ring.Remove("node-b")
+	assert.Equal(t, []string{"node-a", "node-c"}, ring.Nodes())
+	for id, node := range before {
+		if node == "node-b" {
+			assert.NotEqual(t, "node-b", ring.Node(id))
+			continue
+		}
+		assert.Equal(t, node, ring.Node(id), "session %s should stay on its node", id)
+	}
+}
+
+func TestRing_SpreadsSessionsAcrossNodes(t *testing.T) {
+	ring := NewRing(0, "node-a", "node-b", "node-c")
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		counts[ring.Node(fmt.Sprintf("session-%d", i))]++
+	}
+	for _, node := range ring.Nodes() {
+		assert.Greater(t, counts[node], 500, "node %s is underused", node)
+	}
+}
+
+func TestRing_EmptyRingRoutesNowhere(t *testing.T) {
+	assert.Equal(t, "", NewRing(0).Node("session-1"))
+}
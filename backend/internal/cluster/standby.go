@@ -0,0 +1,247 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// primaryLeaseKey is held by the instance standbys follow
+const primaryLeaseKey = "lease:primary"
+
+// replicationChannel carries the primary's processed events to standbys
+const replicationChannel = "replication:primary"
+
+// ReplicaState is the metadata a standby cannot rebuild from events alone:
+// registered sessions and their milestones
+type ReplicaState struct {
+	Sessions   []sessions.Session                 `json:"sessions"`
+	Milestones map[string][]*milestones.Milestone `json:"milestones"`
+}
+
+// replicationMessage is one processed event or state heartbeat. Seq counts
+// the origin's messages so standbys can detect gaps.
+type replicationMessage struct {
+	Origin string        `json:"origin"`
+	Seq    uint64        `json:"seq"`
+	Event  *events.Event `json:"event,omitempty"`
+	State  *ReplicaState `json:"state,omitempty"`
+}
+
+// Primary holds the primary lease and replicates every processed event to
+// standby instances, along with a state heartbeat each third of the lease
+type Primary struct {
+	store      LeaseStore
+	instanceID string
+	ttl        time.Duration
+	state      func() ReplicaState
+	seq        atomic.Uint64
+	held       bool
+}
+
+// NewPrimary creates a primary whose heartbeats carry the state returned by state
+func NewPrimary(store LeaseStore, instanceID string, ttl time.Duration, state func() ReplicaState) *Primary {
+	return &Primary{
+		store:      store,
+		instanceID: instanceID,
+		ttl:        ttl,
+		state:      state,
+	}
+}
+
+// Replicate streams a processed event to standbys
+func (p *Primary) Replicate(ctx context.Context, event *events.Event) {
+	p.publish(ctx, replicationMessage{Event: event})
+}
+
+// publish stamps and sends a replication message
+func (p *Primary) publish(ctx context.Context, msg replicationMessage) {
+	msg.Origin = p.instanceID
+	msg.Seq = p.seq.Add(1)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error encoding replication message: %v", err)
+		return
+	}
+	if err := p.store.Publish(ctx, replicationChannel, data); err != nil {
+		log.Printf("Error replicating to standbys: %v", err)
+	}
+}
+
+// heartbeat claims or renews the primary lease and sends the current state
+func (p *Primary) heartbeat(ctx context.Context) {
+	var ok bool
+	var err error
+	if p.held {
+		ok, err = p.store.RenewLease(ctx, primaryLeaseKey, p.instanceID, p.ttl)
+	} else {
+		ok, err = p.store.AcquireLease(ctx, primaryLeaseKey, p.instanceID, p.ttl)
+		if err == nil && !ok {
+			// A promoted standby already holds the lease under our ID
+			var owner string
+			owner, err = p.store.LeaseOwner(ctx, primaryLeaseKey)
+			ok = owner == p.instanceID
+		}
+	}
+	if err != nil {
+		log.Printf("Error renewing primary lease: %v", err)
+		return
+	}
+	if !ok {
+		if p.held {
+			log.Printf("Lost the primary lease; standbys now follow another instance")
+		}
+		p.held = false
+		return
+	}
+	if !p.held {
+		log.Printf("Instance %s is the primary", p.instanceID)
+	}
+	p.held = true
+
+	state := p.state()
+	p.publish(ctx, replicationMessage{State: &state})
+}
+
+// Start heartbeats until the context is cancelled
+func (p *Primary) Start(ctx context.Context) {
+	p.heartbeat(ctx)
+	if !p.held {
+		log.Printf("Primary lease is held by another instance; standbys will not follow %s until it is released", p.instanceID)
+	}
+	go func() {
+		ticker := time.NewTicker(p.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.heartbeat(ctx)
+			}
+		}
+	}()
+}
+
+// Release gives up the primary lease so a standby takes over immediately
+// instead of waiting for it to expire
+func (p *Primary) Release(ctx context.Context) error {
+	return p.store.ReleaseLease(ctx, primaryLeaseKey, p.instanceID)
+}
+
+// Standby mirrors a primary's aggregation state from its replication feed
+// so it can take over mid-show, losing only events still in flight
+type Standby struct {
+	store      LeaseStore
+	instanceID string
+	ttl        time.Duration
+	manager    *aggregation.Manager
+	state      ReplicaState
+	origin     string
+	lastSeq    uint64
+}
+
+// NewStandby creates a standby mirroring events into manager
+func NewStandby(store LeaseStore, instanceID string, ttl time.Duration, manager *aggregation.Manager) *Standby {
+	return &Standby{
+		store:      store,
+		instanceID: instanceID,
+		ttl:        ttl,
+		manager:    manager,
+	}
+}
+
+// apply mirrors one replication message
+func (s *Standby) apply(msg replicationMessage) {
+	if msg.Origin != s.origin {
+		// A new primary numbers its messages from one
+		s.origin, s.lastSeq = msg.Origin, 0
+	}
+	if s.lastSeq != 0 && msg.Seq > s.lastSeq+1 {
+		log.Printf("Standby missed %d replication messages from %s", msg.Seq-s.lastSeq-1, msg.Origin)
+	}
+	s.lastSeq = msg.Seq
+
+	switch {
+	case msg.Event != nil:
+		if err := s.manager.ProcessEvent(msg.Event); err != nil {
+			log.Printf("Error mirroring event %s: %v", msg.Event.ID, err)
+		}
+	case msg.State != nil:
+		s.state = *msg.State
+		for _, session := range msg.State.Sessions {
+			if session.Status == sessions.StatusEnded {
+				s.manager.RemoveSession(session.ID)
+			}
+		}
+	}
+}
+
+// receive decodes and applies a replication payload
+func (s *Standby) receive(data []byte) {
+	var msg replicationMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Error decoding replication message: %v", err)
+		return
+	}
+	s.apply(msg)
+}
+
+// Run mirrors the primary until this instance can claim the primary lease,
+// which happens once the primary stops renewing it. It returns the last
+// state heartbeat for the caller to restore before serving as the new
+// primary. The first lease TTL is left to a primary starting alongside.
+func (s *Standby) Run(ctx context.Context) (ReplicaState, error) {
+	feedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	feed := s.store.Subscribe(feedCtx, replicationChannel)
+
+	started := time.Now()
+	ticker := time.NewTicker(s.ttl / 3)
+	defer ticker.Stop()
+	log.Printf("Standby %s mirroring the primary", s.instanceID)
+	for {
+		select {
+		case <-ctx.Done():
+			return ReplicaState{}, ctx.Err()
+		case data, ok := <-feed:
+			if !ok {
+				feed = nil
+				continue
+			}
+			s.receive(data)
+		case <-ticker.C:
+			if time.Since(started) < s.ttl {
+				continue
+			}
+			acquired, err := s.store.AcquireLease(ctx, primaryLeaseKey, s.instanceID, s.ttl)
+			if err != nil {
+				log.Printf("Error claiming primary lease: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			// The primary is gone; apply whatever it sent before it stopped
+			for {
+				select {
+				case data, ok := <-feed:
+					if ok {
+						s.receive(data)
+						continue
+					}
+				default:
+				}
+				log.Printf("Standby %s promoted to primary", s.instanceID)
+				return s.state, nil
+			}
+		}
+	}
+}
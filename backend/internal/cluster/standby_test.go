@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replicationStore combines in-memory leases with a working pub/sub bus
+type replicationStore struct {
+	*memoryLeaseStore
+	bus *memoryPubSub
+}
+
+func (s replicationStore) Publish(ctx context.Context, channel string, payload []byte) error {
+	return s.bus.Publish(ctx, channel, payload)
+}
+
+func (s replicationStore) Subscribe(ctx context.Context, channel string) <-chan []byte {
+	return s.bus.Subscribe(ctx, channel)
+}
+
+func (s replicationStore) subscribers(channel string) int {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return len(s.bus.subscribers[channel])
+}
+
+func TestStandby_MirrorsPrimaryAndTakesOverWhenLeaseExpires(t *testing.T) {
+	store := replicationStore{memoryLeaseStore: newMemoryLeaseStore(), bus: newMemoryPubSub()}
+	milestone := milestones.NewMilestone("s1", milestones.MilestoneTypeTotalReactions, 2)
+	primary := NewPrimary(store, "instance-a", 3*time.Second, func() ReplicaState {
+		return ReplicaState{
+			Sessions: []sessions.Session{
+				{ID: "s1", Name: "Finals", Status: sessions.StatusLive},
+				{ID: "s2", Status: sessions.StatusEnded},
+			},
+			Milestones: map[string][]*milestones.Milestone{"s1": {milestone}},
+		}
+	})
+	primary.heartbeat(context.Background())
+	require.True(t, primary.held)
+
+	mirror := aggregation.NewManager()
+	standby := NewStandby(store, "instance-b", 30*time.Millisecond, mirror)
+	type result struct {
+		state ReplicaState
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		state, err := standby.Run(context.Background())
+		done <- result{state, err}
+	}()
+	require.Eventually(t, func() bool { return store.subscribers(replicationChannel) == 1 }, time.Second, 5*time.Millisecond)
+
+	ctx := context.Background()
+	primary.Replicate(ctx, events.JoinSessionEvent("s1", "user-1"))
+	primary.Replicate(ctx, events.ReactionEvent("s1", "user-1", events.ReactionFire))
+	primary.Replicate(ctx, events.ReactionEvent("s1", "user-1", events.ReactionHeart))
+	primary.Replicate(ctx, events.JoinSessionEvent("s2", "user-2"))
+	primary.heartbeat(ctx)
+
+	// The standby keeps following while the primary renews its lease
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("standby promoted while the primary held the lease")
+	default:
+	}
+
+	store.expire(primaryLeaseKey)
+	var promoted result
+	select {
+	case promoted = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("standby was not promoted after the primary lease expired")
+	}
+	require.NoError(t, promoted.err)
+
+	stats, exists := mirror.GetSession("s1")
+	require.True(t, exists)
+	snapshot := stats.GetSnapshot()
+	assert.Equal(t, int64(2), snapshot.TotalReactions)
+	assert.Equal(t, 1, snapshot.ActiveUserCount)
+	_, exists = mirror.GetSession("s2")
+	assert.False(t, exists, "ended sessions are dropped from the mirror")
+	assert.Len(t, promoted.state.Sessions, 2)
+	assert.Equal(t, milestone.ID, promoted.state.Milestones["s1"][0].ID)
+
+	// The promoted instance keeps the lease it claimed as a standby
+	next := NewPrimary(store, "instance-b", 3*time.Second, func() ReplicaState { return ReplicaState{} })
+	next.heartbeat(ctx)
+	assert.True(t, next.held)
+	primary.heartbeat(ctx)
+	assert.False(t, primary.held, "the old primary notices it was replaced")
+}
@@ -0,0 +1,26 @@
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// assets holds the dashboard page, script and stylesheet
+//
+//go:embed static/*
+var assets embed.FS
+
+// Handler serves the dashboard's assets under prefix, e.g. "/dashboard/".
+// The page reads the live sessions from the dashboard WebSocket feed.
+func Handler(prefix string) http.Handler {
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(static)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,23 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ServesEmbeddedAssets(t *testing.T) {
+	handler := Handler("/dashboard/")
+	for _, path := range []string{"/dashboard/", "/dashboard/dashboard.js", "/dashboard/dashboard.css"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.NotEmpty(t, rec.Body.String(), path)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/missing.js", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,98 @@
+// Renders the live sessions streamed by the dashboard WebSocket feed,
+// reconnecting when the connection drops
+(function () {
+  "use strict";
+
+  var statusEl = document.getElementById("status");
+  var sessionsEl = document.getElementById("sessions");
+  var emptyEl = document.getElementById("empty");
+  var retryMs = 1000;
+
+  function text(tag, className, value) {
+    var el = document.createElement(tag);
+    if (className) el.className = className;
+    el.textContent = value;
+    return el;
+  }
+
+  function counter(value, label) {
+    var el = document.createElement("div");
+    el.appendChild(text("strong", "", value.toLocaleString()));
+    el.appendChild(text("small", "", label));
+    return el;
+  }
+
+  function milestone(m) {
+    var el = document.createElement("div");
+    el.className = "milestone" + (m.achieved ? " achieved" : "") + (m.locked ? " locked" : "");
+    var label = m.description || m.threshold.toLocaleString();
+    el.appendChild(text("div", "", (m.achieved ? "✓ " : "") + label + " — " +
+      m.progress.toLocaleString() + " / " + m.threshold.toLocaleString()));
+    var bar = document.createElement("div");
+    bar.className = "bar";
+    var fill = document.createElement("div");
+    fill.style.width = Math.min(100, m.threshold > 0 ? (m.progress / m.threshold) * 100 : 0) + "%";
+    bar.appendChild(fill);
+    el.appendChild(bar);
+    return el;
+  }
+
+  function card(s) {
+    var el = document.createElement("article");
+    el.className = "card";
+    el.appendChild(text("h2", "", s.name || s.session_id));
+    el.appendChild(text("div", "id", s.session_id));
+
+    var counters = document.createElement("div");
+    counters.className = "counters";
+    counters.appendChild(counter(s.active_users, "viewers (peak " + s.peak_users.toLocaleString() + ")"));
+    counters.appendChild(counter(s.total_reactions, "reactions"));
+    counters.appendChild(counter(s.reactions_per_minute, "per minute"));
+    el.appendChild(counters);
+
+    var reactions = Object.keys(s.reaction_counts).sort(function (a, b) {
+      return s.reaction_counts[b] - s.reaction_counts[a];
+    }).map(function (type) {
+      return type + " " + s.reaction_counts[type].toLocaleString();
+    });
+    if (reactions.length) el.appendChild(text("div", "reactions", reactions.join(" · ")));
+
+    s.milestones.forEach(function (m) { el.appendChild(milestone(m)); });
+    return el;
+  }
+
+  function render(payload) {
+    var users = 0, rate = 0;
+    sessionsEl.replaceChildren.apply(sessionsEl, payload.sessions.map(function (s) {
+      users += s.active_users;
+      rate += s.reactions_per_minute;
+      return card(s);
+    }));
+    document.getElementById("total-sessions").textContent = payload.sessions.length;
+    document.getElementById("total-users").textContent = users.toLocaleString();
+    document.getElementById("total-rate").textContent = rate.toLocaleString();
+    emptyEl.hidden = payload.sessions.length > 0;
+  }
+
+  function connect() {
+    var scheme = location.protocol === "https:" ? "wss://" : "ws://";
+    var ws = new WebSocket(scheme + location.host + "/dashboard/ws");
+    ws.onopen = function () {
+      retryMs = 1000;
+      statusEl.textContent = "live";
+      statusEl.className = "status live";
+    };
+    ws.onmessage = function (msg) {
+      var payload = JSON.parse(msg.data);
+      if (payload.type === "dashboard") render(payload);
+    };
+    ws.onclose = function () {
+      statusEl.textContent = "reconnecting…";
+      statusEl.className = "status";
+      setTimeout(connect, retryMs);
+      retryMs = Math.min(retryMs * 2, 30000);
+    };
+  }
+
+  connect();
+})();
@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+// subsystemLabel is the profiler label goroutines are grouped by
+const subsystemLabel = "subsystem"
+
+// Unlabeled groups goroutines started outside any labeled subsystem
+const Unlabeled = "unlabeled"
+
+// Label runs f with the subsystem profiler label. Goroutines f starts
+// inherit the label, so starting a subsystem under Label attributes all of
+// its goroutines to it in profiles and goroutine counts.
+func Label(subsystem string, f func()) {
+	pprof.Do(context.Background(), pprof.Labels(subsystemLabel, subsystem), func(context.Context) {
+		f()
+	})
+}
+
+// goroutineRecord matches the header of a stack in the debug=1 goroutine
+// profile, e.g. "12 @ 0x43a1c5 0x4082bc"
+var goroutineRecord = regexp.MustCompile(`^(\d+) @`)
+
+// GoroutinesBySubsystem counts live goroutines by subsystem label
+func GoroutinesBySubsystem() map[string]int {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return map[string]int{}
+	}
+
+	counts := make(map[string]int)
+	pending, pendingCount := false, 0
+	flush := func(subsystem string) {
+		if pending {
+			counts[subsystem] += pendingCount
+			pending = false
+		}
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := goroutineRecord.FindStringSubmatch(line); match != nil {
+			flush(Unlabeled)
+			pending = true
+			pendingCount, _ = strconv.Atoi(match[1])
+			continue
+		}
+		if labels, ok := strings.CutPrefix(line, "# labels: "); ok && pending {
+			var parsed map[string]string
+			subsystem := Unlabeled
+			if json.Unmarshal([]byte(labels), &parsed) == nil && parsed[subsystemLabel] != "" {
+				subsystem = parsed[subsystemLabel]
+			}
+			flush(subsystem)
+		}
+	}
+	flush(Unlabeled)
+	return counts
+}
+
+// Runtime summarizes the Go runtime for incident debugging
+type Runtime struct {
+	Goroutines            int            `json:"goroutines"`
+	GoroutinesBySubsystem map[string]int `json:"goroutines_by_subsystem"`
+	CPUs                  int            `json:"cpus"`
+	HeapAllocBytes        uint64         `json:"heap_alloc_bytes"`
+	HeapObjects           uint64         `json:"heap_objects"`
+	SysBytes              uint64         `json:"sys_bytes"`
+	GCCycles              uint32         `json:"gc_cycles"`
+	GCPauseTotalNs        uint64         `json:"gc_pause_total_ns"`
+}
+
+// ReadRuntime samples the runtime. It briefly stops the world to read
+// memory statistics, so it is meant for on-demand diagnostics only.
+func ReadRuntime() Runtime {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return Runtime{
+		Goroutines:            runtime.NumGoroutine(),
+		GoroutinesBySubsystem: GoroutinesBySubsystem(),
+		CPUs:                  runtime.NumCPU(),
+		HeapAllocBytes:        mem.HeapAlloc,
+		HeapObjects:           mem.HeapObjects,
+		SysBytes:              mem.Sys,
+		GCCycles:              mem.NumGC,
+		GCPauseTotalNs:        mem.PauseTotalNs,
+	}
+}
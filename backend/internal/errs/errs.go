@@ -0,0 +1,116 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code identifies a class of failure. Codes are shared by the API, the event
+// transports and aggregation, and sent to clients so they can branch on them.
+type Code string
+
+const (
+	CodeSessionEnded    Code = "session_ended"
+	CodeQueueFull       Code = "queue_full"
+	CodeRateLimited     Code = "rate_limited"
+	CodeUnauthorized    Code = "unauthorized"
+	CodeValidation      Code = "validation"
+	CodeUnavailable     Code = "unavailable"
+	CodeContentRejected Code = "content_rejected"
+	CodeBanned          Code = "banned"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeForbidden       Code = "forbidden"
+	CodeBadMethod       Code = "method_not_allowed"
+	CodeTimeout         Code = "timeout"
+	CodeUpgradeRequired Code = "upgrade_required"
+	CodeInternal        Code = "internal" // any error outside the taxonomy
+)
+
+// Error is a failure with a code. Sentinels are compared with errors.Is;
+// wrap them with fmt.Errorf("%w: ...") to add detail.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New creates a coded error, for packages declaring their own sentinels
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// The taxonomy's sentinels
+var (
+	ErrSessionEnded    = New(CodeSessionEnded, "session has ended")
+	ErrQueueFull       = New(CodeQueueFull, "event queue full")
+	ErrRateLimited     = New(CodeRateLimited, "rate limit exceeded")
+	ErrUnauthorized    = New(CodeUnauthorized, "unauthorized")
+	ErrValidation      = New(CodeValidation, "invalid request")
+	ErrContentRejected = New(CodeContentRejected, "content rejected by moderation")
+	ErrBanned          = New(CodeBanned, "banned from this session")
+	ErrNotFound        = New(CodeNotFound, "not found")
+	ErrConflict        = New(CodeConflict, "conflict")
+	ErrForbidden       = New(CodeForbidden, "forbidden")
+	ErrUnavailable     = New(CodeUnavailable, "unavailable")
+	ErrBadMethod       = New(CodeBadMethod, "method not allowed")
+	ErrTimeout         = New(CodeTimeout, "timed out")
+)
+
+// Validation reports invalid input. The message is returned to clients as
+// is, so it should explain what to fix.
+func Validation(format string, args ...interface{}) error {
+	return detailed(ErrValidation, format, args)
+}
+
+// NotFound reports that what was asked for doesn't exist or isn't enabled
+func NotFound(format string, args ...interface{}) error {
+	return detailed(ErrNotFound, format, args)
+}
+
+// Conflict reports a request clashing with the current state, such as
+// creating something that already exists
+func Conflict(format string, args ...interface{}) error {
+	return detailed(ErrConflict, format, args)
+}
+
+// Forbidden reports a caller without the privileges a request needs
+func Forbidden(format string, args ...interface{}) error {
+	return detailed(ErrForbidden, format, args)
+}
+
+// Unavailable reports a dependency that can't serve the request right now
+func Unavailable(format string, args ...interface{}) error {
+	return detailed(ErrUnavailable, format, args)
+}
+
+func detailed(sentinel *Error, format string, args []interface{}) error {
+	return &detailedError{sentinel: sentinel, message: fmt.Sprintf(format, args...)}
+}
+
+// detailedError carries a specific message while matching its sentinel
+type detailedError struct {
+	sentinel *Error
+	message  string
+}
+
+func (e *detailedError) Error() string {
+	return e.message
+}
+
+func (e *detailedError) Unwrap() error {
+	return e.sentinel
+}
+
+// CodeOf returns the code of the first coded error in err's chain, or
+// CodeInternal when there is none
+func CodeOf(err error) Code {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,36 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOf_FollowsWrappedErrors(t *testing.T) {
+	assert.Equal(t, CodeQueueFull, CodeOf(ErrQueueFull))
+	assert.Equal(t, CodeUnauthorized, CodeOf(fmt.Errorf("%w: missing token", ErrUnauthorized)))
+	assert.Equal(t, CodeInternal, CodeOf(errors.New("boom")))
+	assert.Equal(t, CodeInternal, CodeOf(nil))
+}
+
+func TestValidation_MatchesErrValidation(t *testing.T) {
+	err := Validation("count must be at most %d", 10)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, CodeValidation, CodeOf(err))
+	assert.Equal(t, "count must be at most 10", err.Error())
+}
+
+func TestDetailedErrors_MatchTheirSentinels(t *testing.T) {
+	for sentinel, err := range map[*Error]error{
+		ErrNotFound:    NotFound("session %s not found", "s1"),
+		ErrConflict:    Conflict("session already exists"),
+		ErrForbidden:   Forbidden("admin permissions required"),
+		ErrUnavailable: Unavailable("archive not available"),
+	} {
+		assert.ErrorIs(t, err, sentinel)
+		assert.Equal(t, sentinel.Code, CodeOf(err))
+	}
+	assert.Equal(t, "session s1 not found", NotFound("session %s not found", "s1").Error())
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ageBucketCount covers 1ms up to ~9 minutes in power-of-two buckets
+const ageBucketCount = 20
+
+// ageWindow is how long each histogram window accumulates before rotating;
+// quantiles cover the current and previous window
+const ageWindow = time.Minute
+
+// ageBucketBound returns the upper bound of bucket i
+func ageBucketBound(i int) time.Duration {
+	return time.Millisecond << uint(i)
+}
+
+// ageHistogram counts observed ages in exponential buckets
+type ageHistogram struct {
+	buckets [ageBucketCount + 1]int64 // last bucket holds overflow
+	count   int64
+	max     time.Duration
+}
+
+func (h *ageHistogram) observe(age time.Duration) {
+	i := 0
+	for i < ageBucketCount && age > ageBucketBound(i) {
+		i++
+	}
+	h.buckets[i]++
+	h.count++
+	if age > h.max {
+		h.max = age
+	}
+}
+
+func (h *ageHistogram) merge(other *ageHistogram) {
+	for i, n := range other.buckets {
+		h.buckets[i] += n
+	}
+	h.count += other.count
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// quantile returns the upper bound of the bucket containing quantile q
+func (h *ageHistogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(q * float64(h.count))
+	if rank >= h.count {
+		rank = h.count - 1
+	}
+	var seen int64
+	for i, n := range h.buckets {
+		seen += n
+		if seen > rank {
+			if i == ageBucketCount {
+				return h.max
+			}
+			if bound := ageBucketBound(i); bound < h.max {
+				return bound
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// AgeStats summarizes how long events of one type waited in the queue
+type AgeStats struct {
+	EventType EventType `json:"event_type"`
+	Count     int64     `json:"count"`
+	P50Ms     float64   `json:"p50_ms"`
+	P95Ms     float64   `json:"p95_ms"`
+	P99Ms     float64   `json:"p99_ms"`
+	MaxMs     float64   `json:"max_ms"`
+}
+
+// AgeTracker records event age at dequeue per event type over a rolling
+// window of one to two minutes
+type AgeTracker struct {
+	current     map[EventType]*ageHistogram
+	previous    map[EventType]*ageHistogram
+	windowStart time.Time
+	mu          sync.Mutex
+}
+
+// NewAgeTracker creates an empty age tracker
+func NewAgeTracker() *AgeTracker {
+	return &AgeTracker{
+		current:     make(map[EventType]*ageHistogram),
+		previous:    make(map[EventType]*ageHistogram),
+		windowStart: time.Now(),
+	}
+}
+
+// rotateLocked starts a new window once the current one is full. Callers
+// must hold t.mu.
+func (t *AgeTracker) rotateLocked(now time.Time) {
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < ageWindow {
+		return
+	}
+	if elapsed < 2*ageWindow {
+		t.previous = t.current
+	} else {
+		t.previous = make(map[EventType]*ageHistogram)
+	}
+	t.current = make(map[EventType]*ageHistogram)
+	t.windowStart = now
+}
+
+// Observe records the age of an event leaving the queue
+func (t *AgeTracker) Observe(eventType EventType, age time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rotateLocked(time.Now())
+	h, exists := t.current[eventType]
+	if !exists {
+		h = &ageHistogram{}
+		t.current[eventType] = h
+	}
+	h.observe(age)
+}
+
+// Stats returns age percentiles per event type plus an "all" summary
+func (t *AgeTracker) Stats() []AgeStats {
+	t.mu.Lock()
+	t.rotateLocked(time.Now())
+	merged := make(map[EventType]*ageHistogram)
+	for _, window := range []map[EventType]*ageHistogram{t.previous, t.current} {
+		for eventType, h := range window {
+			if merged[eventType] == nil {
+				merged[eventType] = &ageHistogram{}
+			}
+			merged[eventType].merge(h)
+		}
+	}
+	t.mu.Unlock()
+
+	all := &ageHistogram{}
+	stats := make([]AgeStats, 0, len(merged)+1)
+	for eventType, h := range merged {
+		all.merge(h)
+		stats = append(stats, h.stats(eventType))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].EventType < stats[j].EventType })
+	return append(stats, all.stats("all"))
+}
+
+func (h *ageHistogram) stats(eventType EventType) AgeStats {
+	return AgeStats{
+		EventType: eventType,
+		Count:     h.count,
+		P50Ms:     durationMs(h.quantile(0.50)),
+		P95Ms:     durationMs(h.quantile(0.95)),
+		P99Ms:     durationMs(h.quantile(0.99)),
+		MaxMs:     durationMs(h.max),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
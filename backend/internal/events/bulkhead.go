@@ -0,0 +1,220 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// bulkhead bounds how many workers process one session's events at once,
+// so a session with slow stages cannot occupy every worker. Events beyond
+// the limit are parked and run by the workers already in the session as
+// they finish, up to backlog per session; further events are dropped.
+type bulkhead struct {
+	limit   int
+	backlog int
+
+	mu       sync.Mutex
+	sessions map[string]*compartment
+}
+
+// compartment is one session's share of the workers
+type compartment struct {
+	active int
+	parked []*Event
+}
+
+// newBulkhead creates a bulkhead admitting limit workers per session, or
+// nil for no limit
+func newBulkhead(limit, backlog int) *bulkhead {
+	if limit <= 0 {
+		return nil
+	}
+	return &bulkhead{limit: limit, backlog: backlog, sessions: make(map[string]*compartment)}
+}
+
+// acquire reports whether the event may be processed now. Otherwise it was
+// parked for a worker already in the session, or dropped if the session's
+// backlog is full.
+func (b *bulkhead) acquire(event *Event) (run, dropped bool) {
+	if b == nil {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, exists := b.sessions[event.SessionID]
+	if !exists {
+		c = &compartment{}
+		b.sessions[event.SessionID] = c
+	}
+	switch {
+	case c.active < b.limit:
+		c.active++
+		return true, false
+	case len(c.parked) < b.backlog:
+		c.parked = append(c.parked, event)
+		return false, false
+	default:
+		return false, true
+	}
+}
+
+// release hands the worker finishing an event of sessionID the session's
+// next parked event, or frees its place if none is parked
+func (b *bulkhead) release(sessionID string) *Event {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.sessions[sessionID]
+	if c == nil {
+		return nil
+	}
+	if len(c.parked) > 0 {
+		next := c.parked[0]
+		c.parked[0] = nil
+		c.parked = c.parked[1:]
+		return next
+	}
+	c.active--
+	if c.active == 0 {
+		delete(b.sessions, sessionID)
+	}
+	return nil
+}
+
+// occupancy returns the workers in and the events parked for each session
+func (b *bulkhead) occupancy() map[string][2]int {
+	occupancy := make(map[string][2]int)
+	if b == nil {
+		return occupancy
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sessionID, c := range b.sessions {
+		occupancy[sessionID] = [2]int{c.active, len(c.parked)}
+	}
+	return occupancy
+}
+
+// SessionLoad reports how one session uses the workers: its processing
+// latency and drops over the last one to two minutes, and the workers it
+// occupies and events it has waiting right now
+type SessionLoad struct {
+	SessionID string  `json:"session_id"`
+	Active    int     `json:"active"`
+	Parked    int     `json:"parked"`
+	Processed int64   `json:"processed"`
+	Dropped   int64   `json:"dropped"`
+	P50Ms     float64 `json:"p50_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+// sessionWindow is one session's processing record for a window
+type sessionWindow struct {
+	latency ageHistogram
+	dropped int64
+}
+
+// loadTracker records processing latency and drops per session over the
+// same rolling windows as AgeTracker
+type loadTracker struct {
+	mu          sync.Mutex
+	current     map[string]*sessionWindow
+	previous    map[string]*sessionWindow
+	windowStart time.Time
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{
+		current:     make(map[string]*sessionWindow),
+		previous:    make(map[string]*sessionWindow),
+		windowStart: time.Now(),
+	}
+}
+
+// windowLocked returns the session's record in the current window,
+// rotating windows first. Callers must hold t.mu.
+func (t *loadTracker) windowLocked(sessionID string, now time.Time) *sessionWindow {
+	if elapsed := now.Sub(t.windowStart); elapsed >= ageWindow {
+		if elapsed < 2*ageWindow {
+			t.previous = t.current
+		} else {
+			t.previous = make(map[string]*sessionWindow)
+		}
+		t.current = make(map[string]*sessionWindow)
+		t.windowStart = now
+	}
+	if sessionID == "" {
+		return nil
+	}
+	w, exists := t.current[sessionID]
+	if !exists {
+		w = &sessionWindow{}
+		t.current[sessionID] = w
+	}
+	return w
+}
+
+// observe records how long processing one of the session's events took
+func (t *loadTracker) observe(sessionID string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.windowLocked(sessionID, time.Now()).latency.observe(latency)
+}
+
+// drop records an event of the session dropped by the bulkhead
+func (t *loadTracker) drop(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.windowLocked(sessionID, time.Now()).dropped++
+}
+
+// loads merges both windows with the bulkhead's occupancy, slowest first
+func (t *loadTracker) loads(occupancy map[string][2]int) []SessionLoad {
+	t.mu.Lock()
+	t.windowLocked("", time.Now())
+	merged := make(map[string]*sessionWindow)
+	for _, window := range []map[string]*sessionWindow{t.previous, t.current} {
+		for sessionID, w := range window {
+			if merged[sessionID] == nil {
+				merged[sessionID] = &sessionWindow{}
+			}
+			merged[sessionID].latency.merge(&w.latency)
+			merged[sessionID].dropped += w.dropped
+		}
+	}
+	t.mu.Unlock()
+
+	for sessionID := range occupancy {
+		if merged[sessionID] == nil {
+			merged[sessionID] = &sessionWindow{}
+		}
+	}
+	loads := make([]SessionLoad, 0, len(merged))
+	for sessionID, w := range merged {
+		loads = append(loads, SessionLoad{
+			SessionID: sessionID,
+			Active:    occupancy[sessionID][0],
+			Parked:    occupancy[sessionID][1],
+			Processed: w.latency.count,
+			Dropped:   w.dropped,
+			P50Ms:     durationMs(w.latency.quantile(0.50)),
+			P95Ms:     durationMs(w.latency.quantile(0.95)),
+			P99Ms:     durationMs(w.latency.quantile(0.99)),
+			MaxMs:     durationMs(w.latency.max),
+		})
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].P99Ms != loads[j].P99Ms {
+			return loads[i].P99Ms > loads[j].P99Ms
+		}
+		return loads[i].SessionID < loads[j].SessionID
+	})
+	return loads
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ClientVersion is a client app's major.minor.patch version. Pre-release
+// and build suffixes are not compared.
+type ClientVersion struct {
+	Major, Minor, Patch int
+}
+
+// ParseClientVersion parses versions such as "2", "2.4", "v2.4.1" or
+// "2.4.1-beta.3", reporting false for anything else
+func ParseClientVersion(s string) (ClientVersion, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return ClientVersion{}, false
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		if part == "" || len(part) > 6 {
+			return ClientVersion{}, false
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return ClientVersion{}, false
+		}
+		numbers[i] = n
+	}
+	return ClientVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, true
+}
+
+// Less reports whether v is older than other
+func (v ClientVersion) Less(other ClientVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v ClientVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// UnknownClientVersion stands in for versions that could not be parsed
+const UnknownClientVersion = "unknown"
+
+// NormalizeClientVersion returns the version a join reports in canonical
+// form, UnknownClientVersion if it is malformed, or "" if it has none
+func NormalizeClientVersion(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	v, ok := ParseClientVersion(s)
+	if !ok {
+		return UnknownClientVersion
+	}
+	return v.String()
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClientVersion(t *testing.T) {
+	for input, want := range map[string]string{
+		"2":            "2.0.0",
+		"2.4":          "2.4.0",
+		"v2.4.1":       "2.4.1",
+		"2.4.1-beta.3": "2.4.1",
+		"2.4.1+build7": "2.4.1",
+	} {
+		v, ok := ParseClientVersion(input)
+		if assert.True(t, ok, input) {
+			assert.Equal(t, want, v.String(), input)
+		}
+	}
+	for _, input := range []string{"", "latest", "1.2.3.4", "1..2", "-1", "1.x"} {
+		_, ok := ParseClientVersion(input)
+		assert.False(t, ok, input)
+	}
+
+	older, _ := ParseClientVersion("2.9.9")
+	newer, _ := ParseClientVersion("2.10")
+	assert.True(t, older.Less(newer), "components compare numerically")
+	assert.False(t, newer.Less(older))
+
+	assert.Equal(t, UnknownClientVersion, NormalizeClientVersion("nightly"))
+	assert.Empty(t, NormalizeClientVersion(" "))
+}
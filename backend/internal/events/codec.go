@@ -0,0 +1,90 @@
+package events
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Encoding selects how raw events are encoded for persisted storage
+type Encoding string
+
+const (
+	// EncodingJSON stores events as plain JSON
+	EncodingJSON Encoding = "json"
+
+	// EncodingCompact deflates the JSON against a dictionary of the field
+	// names and values that repeat in every event, which shrinks the small
+	// records typical of reactions several times over
+	EncodingCompact Encoding = "compact"
+)
+
+// compactMarker prefixes compact records. Plain JSON records start with '{',
+// so records of either encoding can be decoded without knowing which was
+// configured when they were written.
+const compactMarker = 0x01
+
+// compactDictionary primes the compressor with the text events are made of.
+// Changing it breaks decoding of records already written; add a new marker
+// instead.
+var compactDictionary = []byte(`{"id":"","type":"join_session","leave_session","presence","poll_vote","chat",` +
+	`"session_id":"","user_id":"","payload":{"reaction_type":"like","love","cheer","applause","fire","heart",` +
+	`"attributes":{"text":"","author_name":"","previous_state":"active","state":"idle","background",` +
+	`"viewer":"first_time","returning","cohort":"","poll_id":"","option_id":""},"timestamp":"2026-01-01T00:00:00.000000000Z",` +
+	`"received_at":"2026-01-01T00:00:00.000000000Z","external":true,"source_ip":"","tags":[""],"variants":{"":""},` +
+	`"type":"reaction"`)
+
+// IsValid reports whether the encoding is known
+func (e Encoding) IsValid() bool {
+	return e == EncodingJSON || e == EncodingCompact
+}
+
+var compactWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriterDict(nil, flate.BestSpeed, compactDictionary)
+		return w
+	},
+}
+
+// EncodeEvent encodes an event for persisted storage
+func EncodeEvent(event *Event, encoding Encoding) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || encoding != EncodingCompact {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(compactMarker)
+	w := compactWriters.Get().(*flate.Writer)
+	defer compactWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeEvent decodes an event written by EncodeEvent in either encoding
+func DecodeEvent(data []byte) (*Event, error) {
+	if len(data) > 0 && data[0] == compactMarker {
+		r := flate.NewReaderDict(bytes.NewReader(data[1:]), compactDictionary)
+		defer r.Close()
+		inflated, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("inflating compact event: %w", err)
+		}
+		data = inflated
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
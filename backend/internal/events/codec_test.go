@@ -0,0 +1,38 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeEvent_CompactRoundTripsAndShrinksRecords(t *testing.T) {
+	event := NewEvent(EventTypeReaction, "session-42", "user-7", &ReactionPayload{ReactionType: ReactionFire})
+	event.ReceivedAt = time.Now().UTC()
+
+	plain, err := EncodeEvent(event, EncodingJSON)
+	require.NoError(t, err)
+	compact, err := EncodeEvent(event, EncodingCompact)
+	require.NoError(t, err)
+	assert.Less(t, len(compact), len(plain)/2, "compact %d bytes, json %d bytes", len(compact), len(plain))
+
+	for _, data := range [][]byte{plain, compact} {
+		decoded, err := DecodeEvent(data)
+		require.NoError(t, err)
+		assert.Equal(t, event.ID, decoded.ID)
+		assert.Equal(t, "user-7", decoded.UserID)
+		assert.True(t, event.Timestamp.Equal(decoded.Timestamp))
+		reactionType, ok := decoded.GetReactionType()
+		require.True(t, ok)
+		assert.Equal(t, ReactionFire, reactionType)
+	}
+}
+
+func TestDecodeEvent_RejectsCorruptRecords(t *testing.T) {
+	_, err := DecodeEvent([]byte{compactMarker, 0xff, 0x00})
+	assert.Error(t, err)
+	_, err = DecodeEvent([]byte("not json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Deduper remembers recently seen event IDs so replays within the
+// idempotency window are processed only once
+type Deduper struct {
+	window time.Duration
+	seen   map[string]time.Time // eventID -> first seen
+	mu     sync.Mutex
+}
+
+// NewDeduper creates a deduper that remembers IDs for the given window
+func NewDeduper(window time.Duration) *Deduper {
+	return &Deduper{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Seen records an event ID and reports whether it was already seen within
+// the window
+func (d *Deduper) Seen(eventID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if first, exists := d.seen[eventID]; exists && now.Sub(first) < d.window {
+		return true
+	}
+	d.seen[eventID] = now
+
+	// Sweep expired IDs opportunistically to bound memory
+	if len(d.seen)%1024 == 0 {
+		for id, first := range d.seen {
+			if now.Sub(first) >= d.window {
+				delete(d.seen, id)
+			}
+		}
+	}
+	return false
+}
+
+// Forget removes an event ID so a failed event can be retried
+func (d *Deduper) Forget(eventID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, eventID)
+}
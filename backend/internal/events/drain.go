@@ -0,0 +1,210 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainReportInterval is how often drain progress is logged
+const drainReportInterval = time.Second
+
+// spillTimeout bounds how long persisting undrained events may take once
+// the drain deadline has passed
+const spillTimeout = 5 * time.Second
+
+// SpillFunc persists events left undrained at the shutdown deadline so they
+// can be replayed by the next instance to start
+type SpillFunc func(ctx context.Context, events []*Event) error
+
+// DrainProgress reports how far a shutdown drain got: of the Total events
+// queued when ingestion stopped, Drained were processed and Spilled were
+// persisted for replay. Events neither drained nor spilled were dropped, or
+// left for redelivery by durable transports.
+type DrainProgress struct {
+	Total   int64
+	Drained int64
+	Spilled int64
+	Failed  int64 // drained events whose processing failed
+}
+
+// drainCounters are the live counts behind DrainProgress
+type drainCounters struct {
+	total, drained, spilled, failed int64
+}
+
+// SetSpill sets where events still queued at the drain deadline are
+// persisted. Durable transports redeliver unacknowledged events
+// themselves, so they are never spilled.
+func (wp *WorkerPool) SetSpill(spill SpillFunc) {
+	wp.spill = spill
+}
+
+// DrainProgress reports the current or last shutdown drain
+func (wp *WorkerPool) DrainProgress() DrainProgress {
+	return DrainProgress{
+		Total:   atomic.LoadInt64(&wp.drain.total),
+		Drained: atomic.LoadInt64(&wp.drain.drained),
+		Spilled: atomic.LoadInt64(&wp.drain.spilled),
+		Failed:  atomic.LoadInt64(&wp.drain.failed),
+	}
+}
+
+// ShutdownWithDrain stops accepting events and processes the remaining
+// ones on as many goroutines as the pool has workers, logging progress as
+// it goes. Events not yet started when ctx is done are spilled if a spill
+// is set; otherwise they are dropped, or left for redelivery by durable
+// transports. Either way ctx's error is returned.
+func (wp *WorkerPool) ShutdownWithDrain(ctx context.Context) error {
+	log.Println("Shutting down worker pool with drain...")
+
+	// Close the queue to prevent new events
+	wp.queue.Close()
+
+	remaining := wp.queue.Drain()
+	atomic.StoreInt64(&wp.drain.total, int64(len(remaining)))
+	log.Printf("Draining %d remaining events on %d workers", len(remaining), wp.workerCount)
+
+	drainCtx, stop := mergeCancel(ctx, wp.work)
+	defer stop()
+	undrained := wp.drainEvents(drainCtx, remaining)
+	if len(undrained) > 0 {
+		wp.spillEvents(undrained)
+	}
+	progress := wp.DrainProgress()
+	log.Printf("Drained %d of %d events (%d failed, %d spilled)", progress.Drained, progress.Total, progress.Failed, progress.Spilled)
+
+	// Cancel context and wait for workers
+	wp.cancel()
+	if err := wp.wait(ctx); err != nil {
+		return err
+	}
+	if len(undrained) > 0 {
+		return ctx.Err()
+	}
+	log.Println("Worker pool shutdown with drain complete")
+	return nil
+}
+
+// drainEvents processes events in parallel until ctx is done, returning
+// those never started. Drained events bypass the bulkhead: nothing else is
+// competing for the workers, and it would drop events over a backlog.
+func (wp *WorkerPool) drainEvents(ctx context.Context, remaining []*Event) []*Event {
+	if len(remaining) == 0 {
+		return nil
+	}
+	workers := wp.workerCount
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *Event)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range jobs {
+				if err := wp.process(ctx, event); err != nil {
+					atomic.AddInt64(&wp.drain.failed, 1)
+					processErrorLog.Printf("Error processing remaining event %s: %v", event.ID, err)
+				}
+				atomic.AddInt64(&wp.drain.drained, 1)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(drainReportInterval)
+	defer ticker.Stop()
+	var undrained []*Event
+feed:
+	for i, event := range remaining {
+		for {
+			select {
+			case jobs <- event:
+				continue feed
+			case <-ticker.C:
+				log.Printf("Drained %d of %d events", atomic.LoadInt64(&wp.drain.drained), len(remaining))
+			case <-ctx.Done():
+				undrained = remaining[i:]
+				log.Printf("Drain deadline reached with %d of %d events not started", len(undrained), len(remaining))
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return undrained
+}
+
+// spillEvents persists events left undrained, or reports them lost
+func (wp *WorkerPool) spillEvents(undrained []*Event) {
+	if _, durable := wp.queue.(Acknowledger); durable {
+		log.Printf("Leaving %d undrained events for redelivery", len(undrained))
+		return
+	}
+	if wp.spill == nil {
+		log.Printf("Dropping %d undrained events", len(undrained))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), spillTimeout)
+	defer cancel()
+	if err := wp.spill(ctx, undrained); err != nil {
+		log.Printf("Error spilling %d undrained events, dropping them: %v", len(undrained), err)
+		return
+	}
+	atomic.AddInt64(&wp.drain.spilled, int64(len(undrained)))
+	log.Printf("Spilled %d undrained events for replay", len(undrained))
+}
+
+// SpillStore holds raw events spilled at shutdown until they are replayed
+type SpillStore interface {
+	SaveUndrainedEvents(ctx context.Context, payloads [][]byte) error
+	TakeUndrainedEvents(ctx context.Context) ([][]byte, error)
+}
+
+// StoreSpill returns a SpillFunc saving events in store with the given
+// encoding
+func StoreSpill(store SpillStore, encoding Encoding) SpillFunc {
+	return func(ctx context.Context, events []*Event) error {
+		payloads := make([][]byte, 0, len(events))
+		for _, event := range events {
+			payload, err := EncodeEvent(event, encoding)
+			if err != nil {
+				log.Printf("Error encoding undrained event %s, dropping it: %v", event.ID, err)
+				continue
+			}
+			payloads = append(payloads, payload)
+		}
+		return store.SaveUndrainedEvents(ctx, payloads)
+	}
+}
+
+// ReplaySpilled enqueues the events earlier instances spilled at shutdown,
+// returning how many were enqueued. Events that do not fit in the queue are
+// saved back for the next start.
+func ReplaySpilled(ctx context.Context, store SpillStore, queue Transport) (int, error) {
+	payloads, err := store.TakeUndrainedEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for i, payload := range payloads {
+		event, err := DecodeEvent(payload)
+		if err != nil {
+			log.Printf("Dropping undecodable spilled event: %v", err)
+			continue
+		}
+		if err := queue.Enqueue(ctx, event); err != nil {
+			if err := store.SaveUndrainedEvents(ctx, payloads[i:]); err != nil {
+				return replayed, err
+			}
+			return replayed, fmt.Errorf("re-enqueuing spilled events: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -0,0 +1,115 @@
+package events
+
+import "sync"
+
+// FeedEntry is a processed event with its position in the session's feed
+type FeedEntry struct {
+	Offset int64  `json:"offset"`
+	Event  *Event `json:"event"`
+}
+
+// sessionFeed holds one session's recent entries and live subscribers
+type sessionFeed struct {
+	next        int64
+	recent      []FeedEntry // ring of the last retain entries, oldest first
+	subscribers map[chan FeedEntry]struct{}
+}
+
+// Feed fans processed events out to export subscribers and retains recent
+// events per session so subscribers can resume from an offset
+type Feed struct {
+	retain   int
+	sessions map[string]*sessionFeed
+	mu       sync.Mutex
+}
+
+// NewFeed creates a feed retaining up to retain events per session
+func NewFeed(retain int) *Feed {
+	return &Feed{
+		retain:   retain,
+		sessions: make(map[string]*sessionFeed),
+	}
+}
+
+// sessionLocked returns the session's feed, creating it. Callers must hold f.mu.
+func (f *Feed) sessionLocked(sessionID string) *sessionFeed {
+	session, exists := f.sessions[sessionID]
+	if !exists {
+		session = &sessionFeed{subscribers: make(map[chan FeedEntry]struct{})}
+		f.sessions[sessionID] = session
+	}
+	return session
+}
+
+// Publish appends a processed event to its session's feed. Subscribers that
+// fall a full buffer behind are disconnected so they can resume by offset
+// instead of silently missing events.
+func (f *Feed) Publish(event *Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session := f.sessionLocked(event.SessionID)
+	entry := FeedEntry{Offset: session.next, Event: event}
+	session.next++
+	if f.retain > 0 {
+		session.recent = append(session.recent, entry)
+		if len(session.recent) > f.retain {
+			session.recent = session.recent[len(session.recent)-f.retain:]
+		}
+	}
+	for ch := range session.subscribers {
+		select {
+		case ch <- entry:
+		default:
+			delete(session.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns retained entries at or after from (none if from is
+// negative) followed by a channel of live entries. The channel is closed
+// when the subscriber lags or the session is removed.
+func (f *Feed) Subscribe(sessionID string, from int64, buffer int) ([]FeedEntry, <-chan FeedEntry, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session := f.sessionLocked(sessionID)
+	var backlog []FeedEntry
+	if from >= 0 {
+		for _, entry := range session.recent {
+			if entry.Offset >= from {
+				backlog = append(backlog, entry)
+			}
+		}
+	}
+
+	ch := make(chan FeedEntry, buffer)
+	session.subscribers[ch] = struct{}{}
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if current, exists := f.sessions[sessionID]; exists {
+			if _, subscribed := current.subscribers[ch]; subscribed {
+				delete(current.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+	return backlog, ch, cancel
+}
+
+// Remove drops a session's retained events and disconnects its subscribers
+func (f *Feed) Remove(sessionID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, exists := f.sessions[sessionID]
+	if !exists {
+		return
+	}
+	for ch := range session.subscribers {
+		close(ch)
+	}
+	delete(f.sessions, sessionID)
+}
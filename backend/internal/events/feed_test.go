@@ -0,0 +1,47 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeed_ReplaysFromOffsetThenStreams(t *testing.T) {
+	feed := NewFeed(3)
+	for i := 0; i < 5; i++ {
+		feed.Publish(ReactionEvent("s1", "u", ReactionFire))
+	}
+
+	// Offsets 0 and 1 have aged out of the three retained entries
+	backlog, live, cancel := feed.Subscribe("s1", 1, 4)
+	defer cancel()
+	require.Len(t, backlog, 3)
+	assert.Equal(t, int64(2), backlog[0].Offset)
+	assert.Equal(t, int64(4), backlog[2].Offset)
+
+	feed.Publish(ChatEvent("s1", "u", "hi", "A"))
+	feed.Publish(ChatEvent("s2", "u", "elsewhere", "A"))
+	entry := <-live
+	assert.Equal(t, int64(5), entry.Offset)
+	assert.Equal(t, EventTypeChat, entry.Event.Type)
+	select {
+	case entry := <-live:
+		t.Fatalf("unexpected entry from another session: %+v", entry)
+	default:
+	}
+}
+
+func TestFeed_DisconnectsLaggingSubscribers(t *testing.T) {
+	feed := NewFeed(0)
+	_, live, cancel := feed.Subscribe("s1", -1, 1)
+	defer cancel()
+
+	feed.Publish(ReactionEvent("s1", "u", ReactionFire))
+	feed.Publish(ReactionEvent("s1", "u", ReactionFire))
+
+	_, ok := <-live
+	assert.True(t, ok)
+	_, ok = <-live
+	assert.False(t, ok, "lagging subscriber should be closed")
+}
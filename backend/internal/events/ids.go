@@ -0,0 +1,96 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// IDGenerator produces unique event IDs
+type IDGenerator func() string
+
+// idGenerator is used by NewEvent; set once at startup via SetIDGenerator
+var idGenerator IDGenerator = NewUUID
+
+// SetIDGenerator replaces the generator used for new event IDs. It must be
+// called before events are created.
+func SetIDGenerator(generator IDGenerator) {
+	if generator != nil {
+		idGenerator = generator
+	}
+}
+
+// NewEventID generates an ID with the configured generator
+func NewEventID() string {
+	return idGenerator()
+}
+
+// GeneratorFor returns the ID generator for a format name ("uuid" or "ulid")
+func GeneratorFor(format string) (IDGenerator, error) {
+	switch format {
+	case "", "uuid":
+		return NewUUID, nil
+	case "ulid":
+		return NewULID, nil
+	}
+	return nil, fmt.Errorf("unknown event ID format %q", format)
+}
+
+// NewUUID generates a random UUIDv4
+func NewUUID() string {
+	return uuid.New().String()
+}
+
+// crockford is the ULID base32 alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a lexicographically sortable ULID: a 48-bit millisecond
+// timestamp followed by 80 random bits
+func NewULID() string {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], uint64(time.Now().UnixMilli())<<16)
+	rand.Read(raw[6:])
+
+	// Encode 128 bits as 26 base32 characters, most significant first
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ValidateEventID checks that a client-supplied event ID is a UUID or ULID
+func ValidateEventID(id string) error {
+	if _, err := uuid.Parse(id); err == nil && len(id) == 36 {
+		return nil
+	}
+	if len(id) == 26 && id[0] <= '7' {
+		for _, c := range strings.ToUpper(id) {
+			if !strings.ContainsRune(crockford, c) {
+				return errs.Validation("event_id %q is not a valid UUID or ULID", id)
+			}
+		}
+		return nil
+	}
+	return errs.Validation("event_id %q is not a valid UUID or ULID", id)
+}
+
+// SetExternalID replaces the generated ID with one supplied by the client or
+// upstream system, so retries of the same event can be deduplicated
+func (e *Event) SetExternalID(id string) error {
+	if err := ValidateEventID(id); err != nil {
+		return err
+	}
+	e.ID = id
+	e.External = true
+	return nil
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewULID_IsValidAndSortable(t *testing.T) {
+	first := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	second := NewULID()
+
+	assert.Len(t, first, 26)
+	assert.NoError(t, ValidateEventID(first))
+	ids := []string{second, first}
+	sort.Strings(ids)
+	assert.Equal(t, []string{first, second}, ids, "later ULIDs sort after earlier ones")
+}
+
+func TestValidateEventID(t *testing.T) {
+	assert.NoError(t, ValidateEventID(NewUUID()))
+	assert.NoError(t, ValidateEventID("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	assert.Error(t, ValidateEventID("not-an-id"))
+	assert.Error(t, ValidateEventID("81ARZ3NDEKTSV4RRFFQ69G5FAV"), "ULIDs cannot overflow 128 bits")
+	assert.Error(t, ValidateEventID("01ARZ3NDEKTSV4RRFFQ69G5FAU"), "U is not in the Crockford alphabet")
+}
+
+func TestSetIDGenerator_UsedByNewEvent(t *testing.T) {
+	SetIDGenerator(NewULID)
+	defer SetIDGenerator(NewUUID)
+
+	event := ReactionEvent("s1", "u1", ReactionFire)
+	require.Len(t, event.ID, 26)
+
+	require.NoError(t, event.SetExternalID("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	assert.True(t, event.External)
+	assert.Error(t, event.SetExternalID("../../etc"))
+}
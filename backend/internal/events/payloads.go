@@ -0,0 +1,278 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Payload is the typed body of an event. Each event type has one payload
+// schema, registered in payloadSchemas. Events hold their payload decoded;
+// it is only encoded on the way out and decoded once on the way in.
+type Payload interface {
+	EventType() EventType
+	fields() map[string]interface{}
+	load(fields map[string]interface{})
+}
+
+// ReactionPayload is the body of a reaction event. Attributes are free-form
+// tags, e.g. "team": "red", that deployments can aggregate by.
+type ReactionPayload struct {
+	ReactionType ReactionType      `json:"reaction_type"`
+	Source       ReactionSource    `json:"source,omitempty"` // UI surface the reaction was sent from
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// JoinPayload is the body of a join event
+type JoinPayload struct {
+	Cohort string `json:"cohort,omitempty"`
+	Viewer string `json:"viewer,omitempty"` // ViewerFirstTime or ViewerReturning
+
+	ClientVersion string `json:"client_version,omitempty"` // app version the user joined with
+}
+
+// LeavePayload is the body of a leave event
+type LeavePayload struct {
+	State PresenceState `json:"state,omitempty"` // last reported presence of the connection
+}
+
+// PresencePayload is the body of a presence event
+type PresencePayload struct {
+	PreviousState PresenceState `json:"previous_state"`
+	State         PresenceState `json:"state"`
+}
+
+// ChatPayload is the body of a chat event
+type ChatPayload struct {
+	Text       string `json:"text"`
+	AuthorName string `json:"author_name"`
+}
+
+// PollVotePayload is the body of a poll vote event
+type PollVotePayload struct {
+	PollID   string `json:"poll_id"`
+	OptionID string `json:"option_id"`
+}
+
+// payloadSchemas maps each event type to a constructor for its payload
+var payloadSchemas = map[EventType]func() Payload{
+	EventTypeReaction:     func() Payload { return &ReactionPayload{} },
+	EventTypeJoinSession:  func() Payload { return &JoinPayload{} },
+	EventTypeLeaveSession: func() Payload { return &LeavePayload{} },
+	EventTypePresence:     func() Payload { return &PresencePayload{} },
+	EventTypeChat:         func() Payload { return &ChatPayload{} },
+	EventTypePollVote:     func() Payload { return &PollVotePayload{} },
+}
+
+// NewPayload returns an empty payload of the event type's schema
+func NewPayload(eventType EventType) (Payload, bool) {
+	schema, exists := payloadSchemas[eventType]
+	if !exists {
+		return nil, false
+	}
+	return schema(), true
+}
+
+// NewPayloadEvent creates an event of the payload's type
+func NewPayloadEvent(sessionID, userID string, payload Payload) *Event {
+	return NewEvent(payload.EventType(), sessionID, userID, payload)
+}
+
+// SetPayload replaces the event's payload. Payload types other than the
+// event's own are ignored.
+func (e *Event) SetPayload(payload Payload) {
+	if payload.EventType() != e.Type {
+		return
+	}
+	e.Payload = payload
+}
+
+// DecodePayload returns the event's typed payload, or an empty one of its
+// schema when the event carries none. It fails only for types without a
+// schema.
+func (e *Event) DecodePayload() (Payload, bool) {
+	if e.Payload != nil && e.Payload.EventType() == e.Type {
+		return e.Payload, true
+	}
+	return NewPayload(e.Type)
+}
+
+// PayloadField returns a payload field by its wire name, for rules matching
+// on arbitrary fields
+func (e *Event) PayloadField(key string) (interface{}, bool) {
+	if e.Payload == nil {
+		return nil, false
+	}
+	value, exists := e.Payload.fields()[key]
+	return value, exists
+}
+
+// UnmarshalJSON decodes an event, decoding its payload into the schema of
+// its type so stages read typed fields. Payloads of types without a schema
+// are dropped.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type plainEvent Event
+	wire := struct {
+		*plainEvent
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}{plainEvent: (*plainEvent)(e)}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	e.Payload = nil
+	if len(wire.Payload) == 0 || string(wire.Payload) == "null" {
+		return nil
+	}
+	if _, ok := payloadSchemas[e.Type]; !ok {
+		return nil
+	}
+	payload, err := UnmarshalPayload(e.Type, wire.Payload)
+	if err != nil {
+		return fmt.Errorf("decode %s payload: %w", e.Type, err)
+	}
+	e.Payload = payload
+	return nil
+}
+
+// MarshalPayload encodes a payload in the wire encoding, currently JSON
+func MarshalPayload(payload Payload) ([]byte, error) {
+	return json.Marshal(payload.fields())
+}
+
+// UnmarshalPayload decodes a payload of the given event type from the wire
+// encoding
+func UnmarshalPayload(eventType EventType, data []byte) (Payload, error) {
+	payload, ok := NewPayload(eventType)
+	if !ok {
+		return nil, fmt.Errorf("no payload schema for event type %q", eventType)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	payload.load(fields)
+	return payload, nil
+}
+
+// stringField reads a string field. Events built in-process may hold a named
+// string type, decoded ones a plain string.
+func stringField(fields map[string]interface{}, key string) string {
+	switch v := fields[key].(type) {
+	case string:
+		return v
+	case ReactionType:
+		return string(v)
+	case PresenceState:
+		return string(v)
+	}
+	return ""
+}
+
+// setField stores non-empty values, keeping optional fields off the wire
+func setField(fields map[string]interface{}, key, value string) {
+	if value != "" {
+		fields[key] = value
+	}
+}
+
+func (ReactionPayload) EventType() EventType { return EventTypeReaction }
+
+func (p ReactionPayload) fields() map[string]interface{} {
+	fields := map[string]interface{}{"reaction_type": string(p.ReactionType)}
+	if len(p.Attributes) > 0 {
+		attributes := make(map[string]string, len(p.Attributes))
+		for key, value := range p.Attributes {
+			attributes[key] = value
+		}
+		fields["attributes"] = attributes
+	}
+	return fields
+}
+
+func (p *ReactionPayload) load(fields map[string]interface{}) {
+	p.ReactionType = ReactionType(stringField(fields, "reaction_type"))
+	p.Attributes = nil
+	switch attributes := fields["attributes"].(type) {
+	case map[string]string:
+		for key, value := range attributes {
+			p.setAttribute(key, value)
+		}
+	case map[string]interface{}: // decoded from JSON
+		for key, value := range attributes {
+			if value, ok := value.(string); ok {
+				p.setAttribute(key, value)
+			}
+		}
+	}
+}
+
+func (p *ReactionPayload) setAttribute(key, value string) {
+	if p.Attributes == nil {
+		p.Attributes = make(map[string]string)
+	}
+	p.Attributes[key] = value
+}
+
+func (JoinPayload) EventType() EventType { return EventTypeJoinSession }
+
+func (p JoinPayload) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, 3)
+	setField(fields, "cohort", p.Cohort)
+	setField(fields, "viewer", p.Viewer)
+	setField(fields, "client_version", p.ClientVersion)
+	return fields
+}
+
+func (p *JoinPayload) load(fields map[string]interface{}) {
+	p.Cohort = stringField(fields, "cohort")
+	p.Viewer = stringField(fields, "viewer")
+	p.ClientVersion = stringField(fields, "client_version")
+}
+
+func (LeavePayload) EventType() EventType { return EventTypeLeaveSession }
+
+func (p LeavePayload) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, 1)
+	setField(fields, "state", string(p.State))
+	return fields
+}
+
+func (p *LeavePayload) load(fields map[string]interface{}) {
+	p.State = PresenceState(stringField(fields, "state"))
+}
+
+func (PresencePayload) EventType() EventType { return EventTypePresence }
+
+func (p PresencePayload) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"previous_state": string(p.PreviousState),
+		"state":          string(p.State),
+	}
+}
+
+func (p *PresencePayload) load(fields map[string]interface{}) {
+	p.PreviousState = PresenceState(stringField(fields, "previous_state"))
+	p.State = PresenceState(stringField(fields, "state"))
+}
+
+func (ChatPayload) EventType() EventType { return EventTypeChat }
+
+func (p ChatPayload) fields() map[string]interface{} {
+	return map[string]interface{}{"text": p.Text, "author_name": p.AuthorName}
+}
+
+func (p *ChatPayload) load(fields map[string]interface{}) {
+	p.Text = stringField(fields, "text")
+	p.AuthorName = stringField(fields, "author_name")
+}
+
+func (PollVotePayload) EventType() EventType { return EventTypePollVote }
+
+func (p PollVotePayload) fields() map[string]interface{} {
+	return map[string]interface{}{"poll_id": p.PollID, "option_id": p.OptionID}
+}
+
+func (p *PollVotePayload) load(fields map[string]interface{}) {
+	p.PollID = stringField(fields, "poll_id")
+	p.OptionID = stringField(fields, "option_id")
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloads_RoundTripThroughWireEncoding(t *testing.T) {
+	payloads := []Payload{
+		&ReactionPayload{ReactionType: ReactionFire},
+		&ReactionPayload{ReactionType: ReactionCheer, Attributes: map[string]string{"team": "red"}},
+		&JoinPayload{Cohort: "vip", Viewer: ViewerReturning, ClientVersion: "2.4.1"},
+		&LeavePayload{State: PresenceIdle},
+		&PresencePayload{PreviousState: PresenceActive, State: PresenceBackground},
+		&ChatPayload{Text: "hello", AuthorName: "Jordan"},
+		&PollVotePayload{PollID: "poll-1", OptionID: "b"},
+	}
+	for _, payload := range payloads {
+		data, err := MarshalPayload(payload)
+		require.NoError(t, err)
+		decoded, err := UnmarshalPayload(payload.EventType(), data)
+		require.NoError(t, err)
+		assert.Equal(t, payload, decoded, string(payload.EventType()))
+	}
+
+	_, err := UnmarshalPayload("unknown", []byte(`{}`))
+	assert.Error(t, err, "event types without a schema cannot be decoded")
+}
+
+func TestPayloads_DecodeEventsReceivedAsJSON(t *testing.T) {
+	original := CohortJoinSessionEvent("s", "u", "VIP")
+	original.SetViewer(ViewerFirstTime)
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	payload, ok := decoded.DecodePayload()
+	require.True(t, ok)
+	assert.Equal(t, &JoinPayload{Cohort: "vip", Viewer: ViewerFirstTime}, payload)
+	assert.Equal(t, "vip", decoded.GetCohort())
+}
+
+func TestSetPayload_IgnoresOtherEventTypes(t *testing.T) {
+	event := ReactionEvent("s", "u", ReactionLike)
+	event.SetPayload(&ChatPayload{Text: "not a reaction"})
+
+	rt, ok := event.GetReactionType()
+	assert.True(t, ok)
+	assert.Equal(t, ReactionLike, rt)
+}
+
+func TestGetPollVote_RequiresPollAndOption(t *testing.T) {
+	vote, ok := PollVoteEvent("s", "u", "poll-1", "a").GetPollVote()
+	assert.True(t, ok)
+	assert.Equal(t, PollVotePayload{PollID: "poll-1", OptionID: "a"}, vote)
+
+	_, ok = PollVoteEvent("s", "u", "poll-1", "").GetPollVote()
+	assert.False(t, ok)
+}
+
+func TestValidateAttributes_BoundsNamesAndValues(t *testing.T) {
+	assert.NoError(t, ValidateAttributes(nil))
+	assert.NoError(t, ValidateAttributes(map[string]string{"team": "red", "seat-zone": "north"}))
+	assert.Error(t, ValidateAttributes(map[string]string{"Team": "red"}))
+	assert.Error(t, ValidateAttributes(map[string]string{"team": ""}))
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxReactionAttributes; i++ {
+		tooMany[string(rune('a'+i))] = "x"
+	}
+	assert.Error(t, ValidateAttributes(tooMany))
+}
+
+func TestEvent_DecodesItsPayloadOnceOnTheWayIn(t *testing.T) {
+	original := AttributedReactionEvent("s", "u", ReactionCheer, map[string]string{"team": "red"})
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"payload":{"reaction_type":"cheer","attributes":{"team":"red"}}`)
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, &ReactionPayload{ReactionType: ReactionCheer, Attributes: map[string]string{"team": "red"}}, decoded.Payload)
+
+	value, exists := decoded.PayloadField("reaction_type")
+	assert.True(t, exists)
+	assert.Equal(t, "cheer", value)
+
+	var unknown Event
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"custom","payload":{"x":1}}`), &unknown))
+	assert.Nil(t, unknown.Payload, "payloads without a schema are dropped")
+
+	var malformed Event
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"chat","payload":"hello"}`), &malformed))
+}
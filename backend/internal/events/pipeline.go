@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSkip is returned by a pipeline stage to stop processing an event
+// without reporting an error, e.g. when a filter drops it
+var ErrSkip = errors.New("event skipped")
+
+// Pipeline runs shared stages for every event, then the stages registered
+// for the event's type. Types without stages fall back to the default handler.
+type Pipeline struct {
+	common   []EventHandler
+	byType   map[EventType][]EventHandler
+	fallback EventHandler
+	timeout  time.Duration
+	mu       sync.RWMutex
+}
+
+// NewPipeline creates a pipeline whose unregistered event types go to
+// fallback, which may be nil to ignore them
+func NewPipeline(fallback EventHandler) *Pipeline {
+	return &Pipeline{
+		byType:   make(map[EventType][]EventHandler),
+		fallback: fallback,
+	}
+}
+
+// Use appends stages that run for every event before its type's stages
+func (p *Pipeline) Use(stages ...EventHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.common = append(p.common, stages...)
+}
+
+// Handle appends stages for one event type
+func (p *Pipeline) Handle(eventType EventType, stages ...EventHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byType[eventType] = append(p.byType[eventType], stages...)
+}
+
+// SetStageTimeout bounds how long each stage may run. Zero, the default,
+// leaves stages bounded only by the context passed to Process.
+func (p *Pipeline) SetStageTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeout = timeout
+}
+
+// Process runs the event through its stages in order, stopping at the
+// first error. ErrSkip stops processing and is reported as success. Once
+// ctx is done the remaining stages are skipped and ctx's error returned.
+func (p *Pipeline) Process(ctx context.Context, event *Event) error {
+	p.mu.RLock()
+	common := p.common
+	stages, registered := p.byType[event.Type]
+	fallback := p.fallback
+	timeout := p.timeout
+	p.mu.RUnlock()
+
+	if !registered {
+		if fallback == nil {
+			stages = nil
+		} else {
+			stages = []EventHandler{fallback}
+		}
+	}
+	for _, group := range [][]EventHandler{common, stages} {
+		for _, stage := range group {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := runStage(ctx, timeout, stage, event); err != nil {
+				if errors.Is(err, ErrSkip) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runStage runs one stage under its own deadline
+func runStage(ctx context.Context, timeout time.Duration, stage EventHandler, event *Event) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return stage(ctx, event)
+}
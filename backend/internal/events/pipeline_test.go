@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline_DispatchesByEventType(t *testing.T) {
+	var calls []string
+	stage := func(name string) EventHandler {
+		return func(context.Context, *Event) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	p := NewPipeline(stage("fallback"))
+	p.Use(stage("admit"))
+	p.Handle(EventTypeJoinSession, stage("presence"))
+	p.Handle(EventTypeReaction, stage("aggregate"), stage("milestones"))
+
+	assert.NoError(t, p.Process(context.Background(), JoinSessionEvent("s", "u")))
+	assert.NoError(t, p.Process(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	assert.NoError(t, p.Process(context.Background(), ChatEvent("s", "u", "hi", "A")))
+	assert.Equal(t, []string{
+		"admit", "presence",
+		"admit", "aggregate", "milestones",
+		"admit", "fallback",
+	}, calls)
+}
+
+func TestPipeline_StopsOnSkipAndErrors(t *testing.T) {
+	reached := false
+	p := NewPipeline(nil)
+	p.Handle(EventTypeReaction,
+		func(_ context.Context, e *Event) error {
+			if e.UserID == "blocked" {
+				return ErrSkip
+			}
+			return errors.New("boom")
+		},
+		func(context.Context, *Event) error { reached = true; return nil },
+	)
+
+	assert.NoError(t, p.Process(context.Background(), ReactionEvent("s", "blocked", ReactionFire)))
+	assert.EqualError(t, p.Process(context.Background(), ReactionEvent("s", "u", ReactionFire)), "boom")
+	assert.False(t, reached)
+	assert.NoError(t, p.Process(context.Background(), ChatEvent("s", "u", "ignored", "A")))
+}
+
+func TestPipeline_BoundsEachStage(t *testing.T) {
+	reached := false
+	p := NewPipeline(nil)
+	p.SetStageTimeout(20 * time.Millisecond)
+	p.Handle(EventTypeReaction,
+		func(ctx context.Context, _ *Event) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		func(context.Context, *Event) error { reached = true; return nil },
+	)
+
+	assert.ErrorIs(t, p.Process(context.Background(), ReactionEvent("s", "u", ReactionFire)), context.DeadlineExceeded)
+	assert.False(t, reached)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, p.Process(ctx, ReactionEvent("s", "u", ReactionFire)), context.Canceled, "no stage runs once the caller gave up")
+}
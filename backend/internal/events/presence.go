@@ -0,0 +1,50 @@
+package events
+
+// PresenceState describes how engaged a connected user currently is
+type PresenceState string
+
+const (
+	PresenceActive     PresenceState = "active"     // tab visible and recently interacted
+	PresenceIdle       PresenceState = "idle"       // tab visible but no recent interaction
+	PresenceBackground PresenceState = "background" // tab hidden or app backgrounded
+)
+
+// IsValid reports whether the state is one the server understands
+func (p PresenceState) IsValid() bool {
+	switch p {
+	case PresenceActive, PresenceIdle, PresenceBackground:
+		return true
+	}
+	return false
+}
+
+// PresenceEvent records one connection moving between presence states
+func PresenceEvent(sessionID, userID string, previous, state PresenceState) *Event {
+	return NewPayloadEvent(sessionID, userID, &PresencePayload{PreviousState: previous, State: state})
+}
+
+// GetPresence extracts the state transition from a presence event
+func (e *Event) GetPresence() (previous, state PresenceState, ok bool) {
+	payload, _ := e.DecodePayload()
+	presence, ok := payload.(*PresencePayload)
+	if !ok || !presence.PreviousState.IsValid() || !presence.State.IsValid() {
+		return "", "", false
+	}
+	return presence.PreviousState, presence.State, true
+}
+
+// PresenceLeaveSessionEvent creates a leave event for a connection whose last
+// reported state was state
+func PresenceLeaveSessionEvent(sessionID, userID string, state PresenceState) *Event {
+	return NewPayloadEvent(sessionID, userID, &LeavePayload{State: state})
+}
+
+// GetLeaveState returns the presence state of the connection that left,
+// defaulting to active for leaves that do not carry one
+func (e *Event) GetLeaveState() PresenceState {
+	payload, _ := e.DecodePayload()
+	if leave, ok := payload.(*LeavePayload); ok && leave.State.IsValid() {
+		return leave.State
+	}
+	return PresenceActive
+}
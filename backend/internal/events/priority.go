@@ -0,0 +1,74 @@
+package events
+
+import "time"
+
+// Priority is a lane of the in-process queue. Lower values are dequeued
+// first, so a flood of events in a lower lane cannot delay a higher one.
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+
+	numPriorities = 3
+)
+
+// String returns the lane's name
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	}
+	return "normal"
+}
+
+// SetPriority routes events of the given types to a lane. Types without a
+// lane are normal priority. It must be called before events are enqueued.
+func (q *Queue) SetPriority(priority Priority, types ...EventType) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, eventType := range types {
+		if priority == PriorityNormal {
+			delete(q.priorities, eventType)
+		} else {
+			q.priorities[eventType] = priority
+		}
+	}
+}
+
+// SetAgingThreshold sets how long an event waits in a lane before it is
+// promoted to the lane above, so a flood of higher priority events still
+// lets old events through. An event promoted out of the low lane then waits
+// the normal lane's threshold to be promoted again. Zero never promotes
+// the lane's events; the high lane has nowhere to be promoted to. It must
+// be called before events are enqueued.
+func (q *Queue) SetAgingThreshold(priority Priority, threshold time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.aging[priority] = threshold
+}
+
+// effectivePriority returns the lane an event in lane has been promoted to
+// after waiting for waited
+func (q *Queue) effectivePriority(lane Priority, waited time.Duration) Priority {
+	for lane > PriorityHigh {
+		threshold := q.aging[lane]
+		if threshold <= 0 || waited < threshold {
+			break
+		}
+		waited -= threshold
+		lane--
+	}
+	return lane
+}
+
+// Promoted returns how many events were dequeued ahead of a waiting higher
+// priority event because they aged past their lane's threshold
+func (q *Queue) Promoted() int64 {
+	q.headsMu.Lock()
+	defer q.headsMu.Unlock()
+	return q.promoted
+}
@@ -4,56 +4,226 @@ import (
 	"context"
 	"log"
 	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/logging"
+)
+
+// Hot-path log lines are sampled so overload cannot flood the logs
+var (
+	queueFullLog    = logging.NewSampler("Event queue full", 10, time.Second)
+	processErrorLog = logging.NewSampler("Event processing errors", 10, time.Second)
 )
 
-// Queue manages the event queue using a buffered channel
+// ErrQueueClosed is returned when enqueuing to or resizing a queue that has
+// been closed
+var ErrQueueClosed = errs.New(errs.CodeUnavailable, "event queue is closed")
+
+// Queue manages the event queue using buffered channels, one per priority
+// lane. The channels may be swapped for ones of a different size at
+// runtime, see Resize.
 type Queue struct {
-	events   chan *Event
-	size     int
-	mu       sync.RWMutex
-	closed   bool
-	draining bool
+	lanes      [numPriorities]chan *Event
+	size       int                    // capacity of each lane
+	priorities map[EventType]Priority // lanes of event types not PriorityNormal
+	aging      [numPriorities]time.Duration
+	mu         sync.RWMutex
+	closed     bool
+	draining   bool
+	ages       *AgeTracker
+
+	// heads holds the oldest event of each lane once a dequeue looked at
+	// it, so lanes can be compared by how long their events waited
+	heads    [numPriorities]*Event
+	headsMu  sync.Mutex
+	promoted int64 // events dequeued ahead of a higher lane after aging
 }
 
-// NewQueue creates a new event queue with the specified buffer size
+// NewQueue creates a new event queue whose lanes each buffer size events.
+// Every event is normal priority until SetPriority routes types to other
+// lanes.
 func NewQueue(size int) *Queue {
-	return &Queue{
-		events: make(chan *Event, size),
-		size:   size,
+	q := &Queue{
+		size:       size,
+		priorities: make(map[EventType]Priority),
+		ages:       NewAgeTracker(),
+	}
+	for i := range q.lanes {
+		q.lanes[i] = make(chan *Event, size)
 	}
+	return q
 }
 
-// Enqueue adds an event to the queue
-// Returns false if the queue is full or closed
-func (q *Queue) Enqueue(event *Event) bool {
+// Enqueue adds an event to its type's lane
+// Returns errs.ErrQueueFull if the lane is full or ErrQueueClosed if closed.
+// It never blocks, so ctx only matters to other transports.
+func (q *Queue) Enqueue(_ context.Context, event *Event) error {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
 	if q.closed {
-		return false
+		return ErrQueueClosed
 	}
 
+	lane, routed := q.priorities[event.Type]
+	if !routed {
+		lane = PriorityNormal
+	}
+	event.EnqueuedAt = time.Now()
 	select {
-	case q.events <- event:
-		return true
+	case q.lanes[lane] <- event:
+		return nil
 	default:
 		// Queue is full, event is dropped
-		log.Printf("WARNING: Event queue full, dropping event %s", event.ID)
-		return false
+		queueFullLog.Printf("WARNING: Event queue full, dropping event %s", event.ID)
+		return errs.ErrQueueFull
 	}
 }
 
-// Dequeue retrieves the next event from the queue
-// Returns nil if the queue is closed and empty
+// Dequeue retrieves the next event from the queue: the oldest event of the
+// highest lane, counting events aged past their lane's threshold as
+// promoted. Returns nil if the queue is closed and empty.
 func (q *Queue) Dequeue(ctx context.Context) (*Event, bool) {
-	select {
-	case event, ok := <-q.events:
-		return event, ok
-	case <-ctx.Done():
-		return nil, false
+	for {
+		lanes, closed := q.channels()
+		if event := q.next(lanes); event != nil {
+			q.observeAge(event)
+			return event, true
+		}
+		if closed {
+			return nil, false
+		}
+
+		var event *Event
+		var ok bool
+		var lane Priority
+		select {
+		case event, ok = <-lanes[PriorityHigh]:
+			lane = PriorityHigh
+		case event, ok = <-lanes[PriorityNormal]:
+			lane = PriorityNormal
+		case event, ok = <-lanes[PriorityLow]:
+			lane = PriorityLow
+		case <-ctx.Done():
+			return nil, false
+		}
+		if !ok {
+			// Resize swapped in a new channel or the queue closed; the next
+			// pass waits on the new one or reports the queue empty
+			continue
+		}
+		event = q.keepOrder(lane, event)
+		q.observeAge(event)
+		return event, true
 	}
 }
 
+// next takes the event to dequeue among the lanes' oldest events, or nil if
+// every lane is empty
+func (q *Queue) next(lanes [numPriorities]chan *Event) *Event {
+	q.headsMu.Lock()
+	defer q.headsMu.Unlock()
+
+	now := time.Now()
+	best, bestRank := -1, Priority(0)
+	for i := range q.heads {
+		if q.heads[i] == nil {
+			select {
+			case event, ok := <-lanes[i]:
+				if ok {
+					q.heads[i] = event
+				}
+			default:
+			}
+		}
+		if q.heads[i] == nil {
+			continue
+		}
+		rank := q.effectivePriority(Priority(i), now.Sub(q.heads[i].EnqueuedAt))
+		if best < 0 || rank < bestRank ||
+			(rank == bestRank && q.heads[i].EnqueuedAt.Before(q.heads[best].EnqueuedAt)) {
+			best, bestRank = i, rank
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	for i := 0; i < best; i++ {
+		if q.heads[i] != nil {
+			q.promoted++
+			break
+		}
+	}
+	event := q.heads[best]
+	q.heads[best] = nil
+	return event
+}
+
+// keepOrder returns the event to dequeue after one was received from a
+// lane while waiting. If another dequeue looked at the lane meanwhile, its
+// held head is older and goes first, and the received event takes its place.
+func (q *Queue) keepOrder(lane Priority, received *Event) *Event {
+	q.headsMu.Lock()
+	defer q.headsMu.Unlock()
+	head := q.heads[lane]
+	if head == nil {
+		return received
+	}
+	q.heads[lane] = received
+	return head
+}
+
+// channels returns the current lane channels and whether the queue closed
+func (q *Queue) channels() ([numPriorities]chan *Event, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.lanes, q.closed
+}
+
+// Resize swaps the queue's buffers for ones holding size events per lane,
+// carrying every queued event over in order. Enqueues wait for the swap
+// rather than being dropped, and workers blocked on the old buffers move to
+// the new ones. Shrinking below the number of events queued in a lane is
+// refused.
+func (q *Queue) Resize(size int) error {
+	if size <= 0 {
+		return errs.Validation("queue size must be positive")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	for _, lane := range q.lanes {
+		if queued := len(lane); size < queued {
+			return errs.Validation("cannot shrink queue to %d while %d events are queued", size, queued)
+		}
+	}
+
+	carried := 0
+	for i, old := range q.lanes {
+		resized := make(chan *Event, size)
+		for moved := false; !moved; {
+			select {
+			case event := <-old:
+				resized <- event
+			default:
+				moved = true
+			}
+		}
+		q.lanes[i] = resized
+		carried += len(resized)
+		close(old)
+	}
+	q.size = size
+
+	log.Printf("Event queue resized to %d events per lane (%d events carried over)", size, carried)
+	return nil
+}
+
 // Close closes the queue and prevents new events from being enqueued
 func (q *Queue) Close() {
 	q.mu.Lock()
@@ -61,33 +231,97 @@ func (q *Queue) Close() {
 
 	if !q.closed {
 		q.closed = true
-		close(q.events)
+		for _, lane := range q.lanes {
+			close(lane)
+		}
 	}
 }
 
-// Drain processes all remaining events in the queue
+// Drain processes all remaining events in the queue, highest lane first
 func (q *Queue) Drain() []*Event {
 	q.mu.Lock()
 	q.draining = true
 	q.mu.Unlock()
 
+	lanes, _ := q.channels()
 	var remaining []*Event
-	for event := range q.events {
-		remaining = append(remaining, event)
+	for i, lane := range lanes {
+		q.headsMu.Lock()
+		if head := q.heads[i]; head != nil {
+			q.heads[i] = nil
+			q.observeAge(head)
+			remaining = append(remaining, head)
+		}
+		q.headsMu.Unlock()
+		for event := range lane {
+			q.observeAge(event)
+			remaining = append(remaining, event)
+		}
 	}
 	return remaining
 }
 
+// observeAge records how long the event waited in the queue
+func (q *Queue) observeAge(event *Event) {
+	if event == nil || event.EnqueuedAt.IsZero() {
+		return
+	}
+	q.ages.Observe(event.Type, time.Since(event.EnqueuedAt))
+}
+
+// AgeStats returns recent age-at-dequeue percentiles per event type
+func (q *Queue) AgeStats() []AgeStats {
+	return q.ages.Stats()
+}
+
 // Len returns the current number of events in the queue
 func (q *Queue) Len() int {
-	return len(q.events)
+	lanes, _ := q.channels()
+	n := 0
+	for _, lane := range lanes {
+		n += len(lane)
+	}
+	q.headsMu.Lock()
+	defer q.headsMu.Unlock()
+	for _, head := range q.heads {
+		if head != nil {
+			n++
+		}
+	}
+	return n
 }
 
-// Cap returns the capacity of the queue
+// Cap returns the capacity of the queue, summed over its lanes like Len
 func (q *Queue) Cap() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.size * numPriorities
+}
+
+// LaneCap returns the capacity of each lane, the size given to NewQueue
+// and Resize
+func (q *Queue) LaneCap() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
 	return q.size
 }
 
+// Free returns how many more events the fullest of the given types' lanes
+// accepts before Enqueue reports it full
+func (q *Queue) Free(types ...EventType) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	free := q.size
+	for _, eventType := range types {
+		lane, routed := q.priorities[eventType]
+		if !routed {
+			lane = PriorityNormal
+		}
+		free = min(free, q.size-len(q.lanes[lane]))
+	}
+	return free
+}
+
 // IsClosed returns whether the queue is closed
 func (q *Queue) IsClosed() bool {
 	q.mu.RLock()
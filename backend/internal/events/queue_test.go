@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jrudman25/livepulse/internal/errs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,8 +16,7 @@ func TestQueue_EnqueueDequeue(t *testing.T) {
 	defer q.Close()
 
 	event := ChatEvent("session-1", "user-1", "hello", "Jordan")
-	ok := q.Enqueue(event)
-	assert.True(t, ok, "enqueue should succeed on empty queue")
+	assert.NoError(t, q.Enqueue(context.Background(), event), "enqueue should succeed on empty queue")
 	assert.Equal(t, 1, q.Len())
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -36,9 +36,11 @@ func TestQueue_DropsWhenFull(t *testing.T) {
 	e2 := ChatEvent("s", "u", "msg2", "A")
 	e3 := ChatEvent("s", "u", "msg3", "A")
 
-	assert.True(t, q.Enqueue(e1))
-	assert.True(t, q.Enqueue(e2))
-	assert.False(t, q.Enqueue(e3), "third enqueue should fail on a queue of capacity 2")
+	assert.NoError(t, q.Enqueue(context.Background(), e1))
+	assert.NoError(t, q.Enqueue(context.Background(), e2))
+	err := q.Enqueue(context.Background(), e3)
+	assert.ErrorIs(t, err, errs.ErrQueueFull, "third enqueue should fail on a queue of capacity 2")
+	assert.Equal(t, errs.CodeQueueFull, errs.CodeOf(err))
 	assert.Equal(t, 2, q.Len())
 }
 
@@ -47,16 +49,16 @@ func TestQueue_RejectsAfterClose(t *testing.T) {
 	q.Close()
 
 	event := ChatEvent("s", "u", "msg", "A")
-	assert.False(t, q.Enqueue(event), "enqueue should fail on closed queue")
+	assert.ErrorIs(t, q.Enqueue(context.Background(), event), ErrQueueClosed, "enqueue should fail on closed queue")
 	assert.True(t, q.IsClosed())
 }
 
 func TestQueue_DrainReturnsRemaining(t *testing.T) {
 	q := NewQueue(10)
 
-	q.Enqueue(ChatEvent("s", "u", "msg1", "A"))
-	q.Enqueue(ChatEvent("s", "u", "msg2", "A"))
-	q.Enqueue(ChatEvent("s", "u", "msg3", "A"))
+	q.Enqueue(context.Background(), ChatEvent("s", "u", "msg1", "A"))
+	q.Enqueue(context.Background(), ChatEvent("s", "u", "msg2", "A"))
+	q.Enqueue(context.Background(), ChatEvent("s", "u", "msg3", "A"))
 
 	q.Close()
 	remaining := q.Drain()
@@ -79,22 +81,22 @@ func TestQueue_ConcurrentEnqueue(t *testing.T) {
 	defer q.Close()
 
 	var wg sync.WaitGroup
-	successes := make(chan bool, 500)
+	successes := make(chan error, 500)
 
 	for i := 0; i < 500; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			e := ChatEvent("s", "u", "msg", "A")
-			successes <- q.Enqueue(e)
+			successes <- q.Enqueue(context.Background(), e)
 		}()
 	}
 	wg.Wait()
 	close(successes)
 
 	count := 0
-	for ok := range successes {
-		if ok {
+	for err := range successes {
+		if err == nil {
 			count++
 		}
 	}
@@ -102,8 +104,154 @@ func TestQueue_ConcurrentEnqueue(t *testing.T) {
 	assert.Equal(t, 500, q.Len())
 }
 
-func TestQueue_CapReturnsBufferSize(t *testing.T) {
+func TestQueue_CapSumsLanesLikeLen(t *testing.T) {
 	q := NewQueue(42)
 	defer q.Close()
-	assert.Equal(t, 42, q.Cap())
+	assert.Equal(t, 42, q.LaneCap())
+	assert.Equal(t, 3*42, q.Cap())
+}
+
+func TestQueue_FreeMeasuresTheEventsOwnLane(t *testing.T) {
+	q := NewQueue(2)
+	defer q.Close()
+	q.SetPriority(PriorityHigh, EventTypeJoinSession)
+	require.NoError(t, q.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	require.NoError(t, q.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+
+	assert.Zero(t, q.Free(EventTypeReaction))
+	assert.Equal(t, 2, q.Free(EventTypeJoinSession))
+	assert.Zero(t, q.Free(EventTypeJoinSession, EventTypeReaction), "the fullest lane bounds the space")
+	assert.Error(t, q.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	assert.NoError(t, q.Enqueue(context.Background(), JoinSessionEvent("s", "u")))
+}
+
+func TestQueue_ResizeKeepsQueuedEventsInOrder(t *testing.T) {
+	q := NewQueue(2)
+	defer q.Close()
+	e1 := ChatEvent("s", "u", "msg1", "A")
+	e2 := ChatEvent("s", "u", "msg2", "A")
+	require.NoError(t, q.Enqueue(context.Background(), e1))
+	require.NoError(t, q.Enqueue(context.Background(), e2))
+
+	assert.Error(t, q.Resize(1), "shrinking below the backlog would drop events")
+	assert.Error(t, q.Resize(0))
+	require.NoError(t, q.Resize(4))
+	assert.Equal(t, 4, q.LaneCap())
+	assert.Equal(t, 2, q.Len())
+	assert.NoError(t, q.Enqueue(context.Background(), ChatEvent("s", "u", "msg3", "A")), "the grown queue accepts more events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := q.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, e1.ID, out.ID)
+	out, ok = q.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, e2.ID, out.ID)
+
+	require.NoError(t, q.Resize(1), "shrinking to the backlog is allowed")
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestQueue_ResizeWakesBlockedDequeue(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Close()
+
+	got := make(chan *Event, 1)
+	go func() {
+		event, _ := q.Dequeue(context.Background())
+		got <- event
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, q.Resize(8))
+	event := ChatEvent("s", "u", "after resize", "A")
+	require.NoError(t, q.Enqueue(context.Background(), event))
+
+	select {
+	case out := <-got:
+		require.NotNil(t, out, "a resize must not look like the queue closing")
+		assert.Equal(t, event.ID, out.ID)
+	case <-time.After(time.Second):
+		t.Fatal("dequeue stayed blocked on the old buffer")
+	}
+}
+
+func TestQueue_ResizeAfterCloseFails(t *testing.T) {
+	q := NewQueue(1)
+	q.Close()
+	assert.ErrorIs(t, q.Resize(2), ErrQueueClosed)
+}
+
+func TestQueue_TracksAgeAtDequeue(t *testing.T) {
+	q := NewQueue(10)
+	defer q.Close()
+
+	require.NoError(t, q.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	require.NoError(t, q.Enqueue(context.Background(), ChatEvent("s", "u", "hi", "A")))
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, ok := q.Dequeue(ctx)
+	require.True(t, ok)
+	_, ok = q.Dequeue(ctx)
+	require.True(t, ok)
+
+	stats := q.AgeStats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, EventTypeChat, stats[0].EventType)
+	assert.Equal(t, EventTypeReaction, stats[1].EventType)
+	all := stats[2]
+	assert.Equal(t, EventType("all"), all.EventType)
+	assert.Equal(t, int64(2), all.Count)
+	assert.GreaterOrEqual(t, all.P50Ms, 20.0)
+	assert.GreaterOrEqual(t, all.MaxMs, all.P99Ms)
+}
+
+func TestAgeHistogram_Quantiles(t *testing.T) {
+	h := &ageHistogram{}
+	for i := 0; i < 99; i++ {
+		h.observe(time.Millisecond)
+	}
+	h.observe(3 * time.Second)
+
+	assert.Equal(t, time.Millisecond, h.quantile(0.50))
+	assert.Equal(t, time.Millisecond, h.quantile(0.95))
+	assert.Equal(t, 3*time.Second, h.quantile(0.999))
+}
+
+func TestQueue_PriorityLanesPromoteAgedEvents(t *testing.T) {
+	q := NewQueue(100)
+	defer q.Close()
+	q.SetPriority(PriorityHigh, EventTypeReaction)
+	q.SetAgingThreshold(PriorityNormal, 30*time.Millisecond)
+	ctx := context.Background()
+
+	chat := ChatEvent("s", "u", "hello", "A")
+	require.NoError(t, q.Enqueue(ctx, chat))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, q.Enqueue(ctx, ReactionEvent("s", "u", ReactionFire)))
+	}
+	out, ok := q.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, EventTypeReaction, out.Type, "the high lane goes first")
+
+	// A flood in the high lane lets the chat through once it has aged
+	time.Sleep(40 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, q.Enqueue(ctx, ReactionEvent("s", "u", ReactionFire)))
+	}
+	out, ok = q.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, chat.ID, out.ID)
+	assert.Equal(t, int64(1), q.Promoted())
+	assert.Equal(t, 9, q.Len())
+
+	q.Close()
+	remaining := q.Drain()
+	require.Len(t, remaining, 9)
+	for _, event := range remaining {
+		assert.Equal(t, EventTypeReaction, event.Type)
+	}
 }
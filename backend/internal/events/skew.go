@@ -0,0 +1,65 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatePolicy decides what happens to events whose timestamp is further in
+// the past than the allowed skew
+type LatePolicy string
+
+const (
+	// LatePolicyAccept counts the event in the current window by resetting
+	// its timestamp to the receipt time
+	LatePolicyAccept LatePolicy = "accept"
+	// LatePolicyRebucket keeps the original timestamp so time-based consumers
+	// attribute the event to the window it happened in
+	LatePolicyRebucket LatePolicy = "rebucket"
+	// LatePolicyReject drops the event
+	LatePolicyReject LatePolicy = "reject"
+)
+
+// SkewPolicy normalizes event timestamps against the server clock
+type SkewPolicy struct {
+	MaxSkew time.Duration
+	Late    LatePolicy
+}
+
+// ParseLatePolicy validates a late-event policy name
+func ParseLatePolicy(s string) (LatePolicy, error) {
+	switch policy := LatePolicy(s); policy {
+	case LatePolicyAccept, LatePolicyRebucket, LatePolicyReject:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown late event policy %q", s)
+}
+
+// Apply stamps the receipt time and corrects the event timestamp. Future
+// timestamps beyond the skew are clamped to the receipt time; late ones are
+// handled by the late policy. It returns false if the event should be dropped.
+func (p SkewPolicy) Apply(event *Event, now time.Time) bool {
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = now
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = event.ReceivedAt
+		return true
+	}
+
+	skew := event.Timestamp.Sub(event.ReceivedAt)
+	switch {
+	case skew > p.MaxSkew:
+		// Clients cannot report events that have not happened yet
+		event.Timestamp = event.ReceivedAt
+	case -skew > p.MaxSkew:
+		switch p.Late {
+		case LatePolicyReject:
+			return false
+		case LatePolicyRebucket:
+		default:
+			event.Timestamp = event.ReceivedAt
+		}
+	}
+	return true
+}
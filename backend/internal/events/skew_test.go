@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkewPolicy_ClampsFutureTimestamps(t *testing.T) {
+	now := time.Now().UTC()
+	event := ReactionEvent("s1", "u1", ReactionFire)
+	event.Timestamp = now.Add(time.Hour)
+
+	policy := SkewPolicy{MaxSkew: 30 * time.Second, Late: LatePolicyRebucket}
+	assert.True(t, policy.Apply(event, now))
+	assert.Equal(t, now, event.Timestamp)
+	assert.Equal(t, now, event.ReceivedAt)
+}
+
+func TestSkewPolicy_LateEvents(t *testing.T) {
+	now := time.Now().UTC()
+	late := now.Add(-10 * time.Minute)
+	within := now.Add(-10 * time.Second)
+
+	cases := []struct {
+		policy    LatePolicy
+		timestamp time.Time
+		kept      bool
+		expected  time.Time
+	}{
+		{LatePolicyAccept, late, true, now},
+		{LatePolicyRebucket, late, true, late},
+		{LatePolicyReject, late, false, late},
+		{LatePolicyReject, within, true, within},
+	}
+	for _, c := range cases {
+		event := ReactionEvent("s1", "u1", ReactionFire)
+		event.Timestamp = c.timestamp
+
+		policy := SkewPolicy{MaxSkew: 30 * time.Second, Late: c.policy}
+		assert.Equal(t, c.kept, policy.Apply(event, now), "policy %s", c.policy)
+		assert.Equal(t, c.expected, event.Timestamp, "policy %s", c.policy)
+	}
+}
+
+func TestSkewPolicy_KeepsOriginalReceiptTime(t *testing.T) {
+	received := time.Now().UTC().Add(-time.Second)
+	event := ReactionEvent("s1", "u1", ReactionFire)
+	event.ReceivedAt = received
+
+	// Events forwarded between instances keep the first instance's receipt time
+	SkewPolicy{MaxSkew: time.Minute}.Apply(event, time.Now().UTC())
+	assert.Equal(t, received, event.ReceivedAt)
+}
@@ -0,0 +1,54 @@
+package events
+
+import "github.com/jrudman25/livepulse/internal/errs"
+
+// ReactionSource is the UI surface a reaction was sent from, so product can
+// see which surfaces drive engagement. It is unrelated to Event.Source,
+// which names the integration behind system reactions.
+type ReactionSource string
+
+const (
+	ReactionSourceOverlayButton ReactionSource = "overlay_button"
+	ReactionSourceChatCommand   ReactionSource = "chat_command"
+	ReactionSourceMobileApp     ReactionSource = "mobile_app"
+	ReactionSourceTVRemote      ReactionSource = "tv_remote"
+	ReactionSourceWeb           ReactionSource = "web"
+	ReactionSourceOther         ReactionSource = "other"
+)
+
+// IsValid reports whether the source is one of the known surfaces
+func (rs ReactionSource) IsValid() bool {
+	switch rs {
+	case ReactionSourceOverlayButton, ReactionSourceChatCommand, ReactionSourceMobileApp,
+		ReactionSourceTVRemote, ReactionSourceWeb, ReactionSourceOther:
+		return true
+	}
+	return false
+}
+
+// ValidateReactionSource checks the source a client attached to a reaction.
+// Reactions need not name one.
+func ValidateReactionSource(source ReactionSource) error {
+	if source != "" && !source.IsValid() {
+		return errs.Validation("unknown reaction source %q; expected overlay_button, chat_command, mobile_app, tv_remote, web or other", source)
+	}
+	return nil
+}
+
+// SourcedReactionEvent creates a reaction event sent from a UI surface and
+// tagged with attributes, which must have passed ValidateReactionSource and
+// ValidateAttributes
+func SourcedReactionEvent(sessionID, userID string, reactionType ReactionType, source ReactionSource, attributes map[string]string) *Event {
+	return NewPayloadEvent(sessionID, userID, &ReactionPayload{ReactionType: reactionType, Source: source, Attributes: attributes})
+}
+
+// GetReactionSource returns the UI surface a reaction event was sent from,
+// or "" if it names none
+func (e *Event) GetReactionSource() ReactionSource {
+	payload, _ := e.DecodePayload()
+	reaction, ok := payload.(*ReactionPayload)
+	if !ok {
+		return ""
+	}
+	return reaction.Source
+}
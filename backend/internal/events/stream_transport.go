@@ -0,0 +1,196 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/logging"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Stream transport tuning: entries are read in small batches so at most
+// streamBuffer events are in flight unacknowledged per consumer, and entries
+// a crashed consumer never acknowledged are reclaimed once idle. Publishes
+// give up after streamPublishWait rather than stalling the caller.
+const (
+	streamReadCount     = 100
+	streamReadBlock     = 2 * time.Second
+	streamBuffer        = 256
+	streamClaimInterval = 30 * time.Second
+	streamClaimIdle     = time.Minute
+	streamPublishWait   = 5 * time.Second
+)
+
+// publishErrorLog samples publish failures, which come in bursts when the
+// stream is unreachable
+var publishErrorLog = logging.NewSampler("Event stream publish errors", 10, time.Second)
+
+// StreamBus is the durable log a StreamTransport runs over
+type StreamBus interface {
+	AppendStream(ctx context.Context, stream string, payload []byte, maxLen int64) error
+	EnsureGroup(ctx context.Context, stream, group string) error
+	ReadGroup(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]storage.StreamEntry, error)
+	ClaimStale(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]storage.StreamEntry, error)
+	AckStream(ctx context.Context, stream, group string, ids ...string) error
+}
+
+// StreamTransport distributes events through a durable stream so several
+// cooperating services can consume them. Every consumer group receives
+// every event, consumers within a group share them, and an event is
+// redelivered until a worker acknowledges it, so delivery is at least once.
+type StreamTransport struct {
+	bus      StreamBus
+	stream   string
+	group    string
+	consumer string
+	maxLen   int64
+
+	buffer    chan *Event
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// NewStreamTransport joins the consumer group on the stream, creating both
+// if needed, and starts reading. New groups only see events published after
+// they were created. maxLen caps the stream's length; zero keeps everything.
+func NewStreamTransport(bus StreamBus, stream, group, consumer string, maxLen int64) (*StreamTransport, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := bus.EnsureGroup(ctx, stream, group); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	t := &StreamTransport{
+		bus:      bus,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		maxLen:   maxLen,
+		buffer:   make(chan *Event, streamBuffer),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go t.read()
+	return t, nil
+}
+
+// Enqueue publishes an event to the stream. A failed publish is reported as errs.ErrQueueFull,
+// since either way the event was not accepted.
+func (t *StreamTransport) Enqueue(ctx context.Context, event *Event) error {
+	if t.ctx.Err() != nil {
+		return ErrQueueClosed
+	}
+
+	event.EnqueuedAt = time.Now()
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding event %s for the event stream: %v", event.ID, err)
+		return errs.Validation("event could not be encoded")
+	}
+	ctx, cancel := context.WithTimeout(ctx, streamPublishWait)
+	defer cancel()
+	if err := t.bus.AppendStream(ctx, t.stream, data, t.maxLen); err != nil {
+		publishErrorLog.Printf("WARNING: Failed to publish event %s to the event stream: %v", event.ID, err)
+		return errs.ErrQueueFull
+	}
+	return nil
+}
+
+// read moves stream entries into the local buffer until the transport closes
+func (t *StreamTransport) read() {
+	defer close(t.buffer)
+
+	lastClaim := time.Now()
+	for t.ctx.Err() == nil {
+		if time.Since(lastClaim) >= streamClaimInterval {
+			lastClaim = time.Now()
+			entries, err := t.bus.ClaimStale(t.ctx, t.stream, t.group, t.consumer, streamClaimIdle, streamReadCount)
+			if err != nil && t.ctx.Err() == nil {
+				log.Printf("Error reclaiming stale events from %s: %v", t.stream, err)
+			}
+			if len(entries) > 0 {
+				log.Printf("Reclaimed %d unacknowledged events from %s", len(entries), t.stream)
+			}
+			if !t.deliver(entries) {
+				return
+			}
+		}
+
+		entries, err := t.bus.ReadGroup(t.ctx, t.stream, t.group, t.consumer, streamReadCount, streamReadBlock)
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error reading event stream %s: %v", t.stream, err)
+			select {
+			case <-time.After(time.Second):
+			case <-t.ctx.Done():
+				return
+			}
+			continue
+		}
+		if !t.deliver(entries) {
+			return
+		}
+	}
+}
+
+// deliver decodes entries into the buffer, returning false once closed
+func (t *StreamTransport) deliver(entries []storage.StreamEntry) bool {
+	for _, entry := range entries {
+		event := &Event{}
+		if err := json.Unmarshal(entry.Payload, event); err != nil {
+			// Acknowledge so the bad entry is not redelivered forever
+			log.Printf("Skipping malformed event stream entry %s: %v", entry.ID, err)
+			t.bus.AckStream(t.ctx, t.stream, t.group, entry.ID)
+			continue
+		}
+		event.delivery = entry.ID
+		select {
+		case t.buffer <- event:
+		case <-t.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// Dequeue returns the next event read from the stream
+func (t *StreamTransport) Dequeue(ctx context.Context) (*Event, bool) {
+	select {
+	case event, ok := <-t.buffer:
+		return event, ok
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// Ack confirms an event was processed so it is not redelivered
+func (t *StreamTransport) Ack(event *Event) {
+	if event.delivery == "" {
+		return
+	}
+	if err := t.bus.AckStream(context.Background(), t.stream, t.group, event.delivery); err != nil {
+		log.Printf("Error acknowledging event %s: %v", event.ID, err)
+	}
+}
+
+// Close stops publishing and reading. Events still unacknowledged stay in
+// the stream for another consumer of the group.
+func (t *StreamTransport) Close() {
+	t.closeOnce.Do(t.cancel)
+}
+
+// Drain returns the events read from the stream but not yet dequeued
+func (t *StreamTransport) Drain() []*Event {
+	var remaining []*Event
+	for event := range t.buffer {
+		remaining = append(remaining, event)
+	}
+	return remaining
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStreamBus is a single-group StreamBus kept in memory
+type memoryStreamBus struct {
+	mu      sync.Mutex
+	entries []storage.StreamEntry
+	next    int
+	pending map[string]bool
+	acked   []string
+}
+
+func newMemoryStreamBus() *memoryStreamBus {
+	return &memoryStreamBus{pending: make(map[string]bool)}
+}
+
+func (b *memoryStreamBus) AppendStream(ctx context.Context, stream string, payload []byte, maxLen int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := fmt.Sprintf("%d-0", len(b.entries)+1)
+	b.entries = append(b.entries, storage.StreamEntry{Stream: stream, ID: id, Payload: payload})
+	return nil
+}
+
+func (b *memoryStreamBus) EnsureGroup(ctx context.Context, stream, group string) error {
+	return nil
+}
+
+func (b *memoryStreamBus) ReadGroup(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]storage.StreamEntry, error) {
+	deadline := time.Now().Add(block)
+	for {
+		b.mu.Lock()
+		if b.next < len(b.entries) {
+			end := min(len(b.entries), b.next+int(count))
+			entries := append([]storage.StreamEntry(nil), b.entries[b.next:end]...)
+			for _, entry := range entries {
+				b.pending[entry.ID] = true
+			}
+			b.next = end
+			b.mu.Unlock()
+			return entries, nil
+		}
+		b.mu.Unlock()
+
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (b *memoryStreamBus) ClaimStale(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]storage.StreamEntry, error) {
+	return nil, nil
+}
+
+func (b *memoryStreamBus) AckStream(ctx context.Context, stream, group string, ids ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range ids {
+		delete(b.pending, id)
+		b.acked = append(b.acked, id)
+	}
+	return nil
+}
+
+func TestStreamTransport_PublishConsumeAck(t *testing.T) {
+	bus := newMemoryStreamBus()
+	transport, err := NewStreamTransport(bus, "events:bus", "livepulse", "node-1", 0)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	event := ReactionEvent("session-1", "user-1", ReactionLike)
+	require.NoError(t, transport.Enqueue(context.Background(), event))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := transport.Dequeue(ctx)
+	require.True(t, ok)
+	assert.Equal(t, event.ID, out.ID)
+	assert.Equal(t, "1-0", out.delivery)
+
+	transport.Ack(out)
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	assert.Empty(t, bus.pending)
+	assert.Equal(t, []string{"1-0"}, bus.acked)
+}
+
+func TestStreamTransport_WorkersAckProcessedEvents(t *testing.T) {
+	bus := newMemoryStreamBus()
+	transport, err := NewStreamTransport(bus, "events:bus", "livepulse", "node-1", 0)
+	require.NoError(t, err)
+
+	var processed sync.WaitGroup
+	processed.Add(3)
+	pool := NewWorkerPool(transport, 2, func(context.Context, *Event) error {
+		processed.Done()
+		return nil
+	})
+	pool.Start()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, transport.Enqueue(context.Background(), ChatEvent("session-1", "user-1", "hi", "A")))
+	}
+	processed.Wait()
+	require.NoError(t, pool.ShutdownWithDrain(context.Background()))
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	assert.Empty(t, bus.pending)
+	assert.Len(t, bus.acked, 3)
+}
+
+func TestStreamTransport_RejectsAfterClose(t *testing.T) {
+	transport, err := NewStreamTransport(newMemoryStreamBus(), "events:bus", "livepulse", "node-1", 0)
+	require.NoError(t, err)
+	transport.Close()
+
+	assert.ErrorIs(t, transport.Enqueue(context.Background(), ChatEvent("s", "u", "late", "A")), ErrQueueClosed)
+	assert.Empty(t, transport.Drain())
+}
@@ -0,0 +1,27 @@
+package events
+
+import "strings"
+
+// SystemUserPrefix starts the user ID of events emitted by integrations, so
+// a system identity never collides with an audience member
+const SystemUserPrefix = "system:"
+
+// SystemReactionEvent creates a reaction emitted by an integration, attributed
+// to its system identity
+func SystemReactionEvent(sessionID, source string, reactionType ReactionType) *Event {
+	event := ReactionEvent(sessionID, SystemUserPrefix+source, reactionType)
+	event.Source = source
+	return event
+}
+
+// IsSystem reports whether an integration rather than an audience member
+// emitted the event
+func (e *Event) IsSystem() bool {
+	return e.Source != ""
+}
+
+// ClaimsSystemIdentity reports whether an audience event carries a user ID
+// reserved for integrations
+func (e *Event) ClaimsSystemIdentity() bool {
+	return !e.IsSystem() && strings.HasPrefix(e.UserID, SystemUserPrefix)
+}
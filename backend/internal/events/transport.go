@@ -0,0 +1,25 @@
+package events
+
+import "context"
+
+// Transport carries events from ingestion to the worker pool. Queue is the
+// in-process transport; StreamTransport shares events between services.
+type Transport interface {
+	// Enqueue hands an event to the transport, giving up once ctx is done.
+	// It returns errs.ErrQueueFull when the event was dropped for lack of
+	// room and ErrQueueClosed once the transport stops accepting events.
+	Enqueue(ctx context.Context, event *Event) error
+	// Dequeue blocks for the next event, returning false once the transport
+	// is closed and empty or ctx is done
+	Dequeue(ctx context.Context) (*Event, bool)
+	// Close stops accepting events
+	Close()
+	// Drain returns the events received but not yet dequeued after Close
+	Drain() []*Event
+}
+
+// Acknowledger is implemented by durable transports that redeliver an event
+// until the worker pool confirms it was processed
+type Acknowledger interface {
+	Ack(event *Event)
+}
@@ -1,9 +1,11 @@
 package events
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/errs"
 )
 
 // EventType represents the type of event
@@ -14,6 +16,8 @@ const (
 	EventTypeLeaveSession EventType = "leave_session"
 	EventTypeReaction     EventType = "reaction"
 	EventTypeChat         EventType = "chat"
+	EventTypePresence     EventType = "presence"
+	EventTypePollVote     EventType = "poll_vote"
 )
 
 // ReactionType represents different types of reactions
@@ -30,18 +34,73 @@ const (
 
 // Event represents a user action in a session
 type Event struct {
-	ID        string                 `json:"id"`
-	Type      EventType              `json:"type"`
-	SessionID string                 `json:"session_id"`
-	UserID    string                 `json:"user_id"`
-	Payload   map[string]interface{} `json:"payload,omitempty"`
-	Timestamp time.Time              `json:"timestamp"`
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	SessionID string    `json:"session_id"`
+	UserID    string    `json:"user_id"`
+	Payload   Payload   `json:"payload,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// ReceivedAt is when the server first received the event; Timestamp may
+	// have been set by a client or upstream producer
+	ReceivedAt time.Time `json:"received_at,omitempty"`
+
+	// External is set when ID was supplied by the client or an upstream
+	// system rather than generated, and may be replayed
+	External bool `json:"external,omitempty"`
+
+	// SourceIP is the address the event was received from, when known
+	SourceIP string `json:"source_ip,omitempty"`
+
+	// Source names the server-side integration that emitted the event on
+	// its own behalf, e.g. "trivia-bot"; empty for audience events
+	Source string `json:"source,omitempty"`
+
+	// Tags are labels applied by ingestion filter rules
+	Tags []string `json:"tags,omitempty"`
+
+	// Variants maps experiment IDs to the variant the user is bucketed into
+	Variants map[string]string `json:"variants,omitempty"`
+
+	// EnqueuedAt is when the event last entered the in-process queue
+	EnqueuedAt time.Time `json:"-"`
+
+	// delivery identifies the transport delivery to acknowledge, if any
+	delivery string
+}
+
+// SetVariant records the user's variant in an experiment
+func (e *Event) SetVariant(experimentID, variant string) {
+	if e.Variants == nil {
+		e.Variants = make(map[string]string)
+	}
+	e.Variants[experimentID] = variant
+}
+
+// AddTag labels the event, ignoring duplicates
+func (e *Event) AddTag(tag string) {
+	for _, existing := range e.Tags {
+		if existing == tag {
+			return
+		}
+	}
+	e.Tags = append(e.Tags, tag)
+}
+
+// HasTag reports whether the event carries a tag
+func (e *Event) HasTag(tag string) bool {
+	for _, existing := range e.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // NewEvent creates a new event with a generated ID and timestamp
-func NewEvent(eventType EventType, sessionID, userID string, payload map[string]interface{}) *Event {
+func NewEvent(eventType EventType, sessionID, userID string, payload Payload) *Event {
 	return &Event{
-		ID:        uuid.New().String(),
+		ID:        idGenerator(),
 		Type:      eventType,
 		SessionID: sessionID,
 		UserID:    userID,
@@ -50,11 +109,18 @@ func NewEvent(eventType EventType, sessionID, userID string, payload map[string]
 	}
 }
 
+// IsValid reports whether the reaction type is one of the supported reactions
+func (rt ReactionType) IsValid() bool {
+	switch rt {
+	case ReactionLike, ReactionLove, ReactionCheer, ReactionApplause, ReactionFire, ReactionHeart:
+		return true
+	}
+	return false
+}
+
 // ReactionEvent creates a reaction event
 func ReactionEvent(sessionID, userID string, reactionType ReactionType) *Event {
-	return NewEvent(EventTypeReaction, sessionID, userID, map[string]interface{}{
-		"reaction_type": reactionType,
-	})
+	return NewPayloadEvent(sessionID, userID, &ReactionPayload{ReactionType: reactionType})
 }
 
 // JoinSessionEvent creates a join session event
@@ -62,6 +128,84 @@ func JoinSessionEvent(sessionID, userID string) *Event {
 	return NewEvent(EventTypeJoinSession, sessionID, userID, nil)
 }
 
+// DefaultCohort is assigned to users whose join carries no cohort tag
+const DefaultCohort = "general"
+
+// cohortPattern restricts cohort tags to short lowercase identifiers
+var cohortPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// NormalizeCohort lowercases a cohort tag, falling back to DefaultCohort for
+// empty or malformed tags
+func NormalizeCohort(cohort string) string {
+	cohort = strings.ToLower(strings.TrimSpace(cohort))
+	if !cohortPattern.MatchString(cohort) {
+		return DefaultCohort
+	}
+	return cohort
+}
+
+// CohortJoinSessionEvent creates a join session event tagged with an audience cohort
+func CohortJoinSessionEvent(sessionID, userID, cohort string) *Event {
+	return NewPayloadEvent(sessionID, userID, &JoinPayload{Cohort: NormalizeCohort(cohort)})
+}
+
+// joinPayload returns the payload of a join event
+func (e *Event) joinPayload() (*JoinPayload, bool) {
+	payload, _ := e.DecodePayload()
+	join, ok := payload.(*JoinPayload)
+	return join, ok
+}
+
+// GetCohort extracts the cohort tag from a join event
+func (e *Event) GetCohort() string {
+	join, ok := e.joinPayload()
+	if !ok {
+		return DefaultCohort
+	}
+	return NormalizeCohort(join.Cohort)
+}
+
+// Viewer classifications stamped on joins from the tenant's viewer history
+const (
+	ViewerFirstTime = "first_time"
+	ViewerReturning = "returning"
+)
+
+// SetViewer tags a join with whether the user is new to the session's tenant
+func (e *Event) SetViewer(kind string) {
+	if join, ok := e.joinPayload(); ok {
+		join.Viewer = kind
+		e.SetPayload(join)
+	}
+}
+
+// GetViewer returns a join's viewer classification, or "" if it has none
+func (e *Event) GetViewer() string {
+	if join, ok := e.joinPayload(); ok {
+		return join.Viewer
+	}
+	return ""
+}
+
+// SetClientVersion tags a join with the app version the user joined with
+func (e *Event) SetClientVersion(version string) {
+	if version == "" {
+		return
+	}
+	if join, ok := e.joinPayload(); ok {
+		join.ClientVersion = version
+		e.SetPayload(join)
+	}
+}
+
+// GetClientVersion returns a join's client version, or "" if it has none
+func (e *Event) GetClientVersion() string {
+	if join, ok := e.joinPayload(); ok {
+		return join.ClientVersion
+	}
+	return ""
+}
+
 // LeaveSessionEvent creates a leave session event
 func LeaveSessionEvent(sessionID, userID string) *Event {
 	return NewEvent(EventTypeLeaveSession, sessionID, userID, nil)
@@ -69,32 +213,81 @@ func LeaveSessionEvent(sessionID, userID string) *Event {
 
 // GetReactionType extracts the reaction type from the event payload
 func (e *Event) GetReactionType() (ReactionType, bool) {
-	if e.Type != EventTypeReaction {
+	payload, _ := e.DecodePayload()
+	reaction, ok := payload.(*ReactionPayload)
+	if !ok || reaction.ReactionType == "" {
 		return "", false
 	}
-	if rt, ok := e.Payload["reaction_type"].(string); ok {
-		return ReactionType(rt), true
+	return reaction.ReactionType, true
+}
+
+// Bounds on the attributes a reaction may carry
+const (
+	MaxReactionAttributes = 8
+	maxAttributeValueLen  = 64
+)
+
+// attributeKeyPattern restricts attribute names like cohort tags
+var attributeKeyPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ValidateAttributes checks the attributes a client attached to a reaction
+func ValidateAttributes(attributes map[string]string) error {
+	if len(attributes) > MaxReactionAttributes {
+		return errs.Validation("at most %d attributes may be attached to a reaction", MaxReactionAttributes)
+	}
+	for key, value := range attributes {
+		if !attributeKeyPattern.MatchString(key) {
+			return errs.Validation("attribute name %q must be 1-32 lowercase letters, digits, '_' or '-'", key)
+		}
+		if value == "" || len(value) > maxAttributeValueLen {
+			return errs.Validation("attribute %s must be 1-%d characters", key, maxAttributeValueLen)
+		}
+	}
+	return nil
+}
+
+// AttributedReactionEvent creates a reaction event tagged with attributes,
+// which must have passed ValidateAttributes
+func AttributedReactionEvent(sessionID, userID string, reactionType ReactionType, attributes map[string]string) *Event {
+	return NewPayloadEvent(sessionID, userID, &ReactionPayload{ReactionType: reactionType, Attributes: attributes})
+}
+
+// GetAttributes returns the attributes a reaction event was tagged with
+func (e *Event) GetAttributes() map[string]string {
+	payload, _ := e.DecodePayload()
+	reaction, ok := payload.(*ReactionPayload)
+	if !ok {
+		return nil
 	}
-	return "", false
+	return reaction.Attributes
 }
 
 // ChatEvent creates a chat event
 func ChatEvent(sessionID, userID string, text string, authorName string) *Event {
-	return NewEvent(EventTypeChat, sessionID, userID, map[string]interface{}{
-		"text": text,
-		"author_name": authorName,
-	})
+	return NewPayloadEvent(sessionID, userID, &ChatPayload{Text: text, AuthorName: authorName})
 }
 
 // GetChatText extracts the text from a chat event
 func (e *Event) GetChatText() (string, string, bool) {
-	if e.Type != EventTypeChat {
+	payload, _ := e.DecodePayload()
+	chat, ok := payload.(*ChatPayload)
+	if !ok || chat.Text == "" {
 		return "", "", false
 	}
-	text, txtOk := e.Payload["text"].(string)
-	author, _ := e.Payload["author_name"].(string)
-	if txtOk {
-		return text, author, true
+	return chat.Text, chat.AuthorName, true
+}
+
+// PollVoteEvent creates a poll vote event
+func PollVoteEvent(sessionID, userID, pollID, optionID string) *Event {
+	return NewPayloadEvent(sessionID, userID, &PollVotePayload{PollID: pollID, OptionID: optionID})
+}
+
+// GetPollVote extracts the poll and chosen option from a poll vote event
+func (e *Event) GetPollVote() (PollVotePayload, bool) {
+	payload, _ := e.DecodePayload()
+	vote, ok := payload.(*PollVotePayload)
+	if !ok || vote.PollID == "" || vote.OptionID == "" {
+		return PollVotePayload{}, false
 	}
-	return "", "", false
+	return *vote, true
 }
@@ -1,8 +1,10 @@
 package events
 
 import (
+	"encoding/json"
 	"testing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestChatEventCreationAndExtraction(t *testing.T) {
@@ -30,10 +32,21 @@ func TestChatEventCreationAndExtraction(t *testing.T) {
 
 func TestGetChatText_RejectsNonChatEvents(t *testing.T) {
 	// Create a structural event simulating internal backend statistics instead of chat
-	event := NewEvent(EventTypeReaction, "123", "SYSTEM", map[string]interface{}{
-		"active_connections": 50,
-	})
+	event := NewEvent(EventTypeReaction, "123", "SYSTEM", &ReactionPayload{ReactionType: ReactionLike})
 
 	_, _, ok := event.GetChatText()
 	assert.False(t, ok, "GetChatText should immediately abort if EventType does not explicitly equal EventTypeChat")
 }
+
+func TestGetReactionType_AcceptsTypedAndDecodedPayloads(t *testing.T) {
+	typed := ReactionEvent("s", "u", ReactionFire)
+	rt, ok := typed.GetReactionType()
+	assert.True(t, ok, "in-process reaction events should expose their type")
+	assert.Equal(t, ReactionFire, rt)
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"e1","type":"reaction","session_id":"s","user_id":"u","payload":{"reaction_type":"love"}}`), &decoded))
+	rt, ok = decoded.GetReactionType()
+	assert.True(t, ok, "JSON-decoded reaction events should expose their type")
+	assert.Equal(t, ReactionLove, rt)
+}
@@ -4,33 +4,90 @@ import (
 	"context"
 	"log"
 	"sync"
+	"time"
 )
 
-// EventHandler is a function that processes an event
-type EventHandler func(*Event) error
+// EventHandler is a function that processes an event. It should give up
+// once ctx is done.
+type EventHandler func(ctx context.Context, event *Event) error
 
 // WorkerPool manages a pool of worker goroutines that process events
 type WorkerPool struct {
-	queue       *Queue
+	queue       Transport
 	workerCount int
-	handler     EventHandler
+	pipeline    *Pipeline
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
+
+	// work is the context events are processed under. It outlives ctx so
+	// workers finish their current events, and is cancelled only once a
+	// shutdown deadline passes.
+	work  context.Context
+	abort context.CancelFunc
+
+	bulkhead *bulkhead // nil when sessions may use every worker
+	load     *loadTracker
+
+	spill SpillFunc // nil drops events undrained at shutdown
+	drain drainCounters
 }
 
-// NewWorkerPool creates a new worker pool
-func NewWorkerPool(queue *Queue, workerCount int, handler EventHandler) *WorkerPool {
+// NewWorkerPool creates a new worker pool. handler processes event types
+// without a pipeline registered via Handle and may be nil.
+func NewWorkerPool(queue Transport, workerCount int, handler EventHandler) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
+	work, abort := context.WithCancel(context.Background())
 	return &WorkerPool{
 		queue:       queue,
 		workerCount: workerCount,
-		handler:     handler,
+		pipeline:    NewPipeline(handler),
 		ctx:         ctx,
 		cancel:      cancel,
+		work:        work,
+		abort:       abort,
+		load:        newLoadTracker(),
 	}
 }
 
+// Use registers stages that run for every event before its type's handlers
+func (wp *WorkerPool) Use(stages ...EventHandler) {
+	wp.pipeline.Use(stages...)
+}
+
+// Handle registers the handlers that process one event type, in order
+func (wp *WorkerPool) Handle(eventType EventType, stages ...EventHandler) {
+	wp.pipeline.Handle(eventType, stages...)
+}
+
+// SetStageTimeout bounds how long each stage of an event may run
+func (wp *WorkerPool) SetStageTimeout(timeout time.Duration) {
+	wp.pipeline.SetStageTimeout(timeout)
+}
+
+// SetSessionLimits bounds how many workers process one session's events at
+// once. Events of a session at its limit wait for those workers, up to
+// backlog per session, and are dropped beyond it. A limit of zero lets a
+// session use every worker. It must be called before Start.
+func (wp *WorkerPool) SetSessionLimits(concurrency, backlog int) {
+	wp.bulkhead = newBulkhead(concurrency, backlog)
+}
+
+// SessionLoads reports each recently active session's processing latency,
+// drops and current worker occupancy, slowest first
+func (wp *WorkerPool) SessionLoads() []SessionLoad {
+	return wp.load.loads(wp.bulkhead.occupancy())
+}
+
+// Process runs an event through the pool's pipeline on the caller's
+// goroutine, for sources that bypass the queue
+func (wp *WorkerPool) Process(ctx context.Context, event *Event) error {
+	started := time.Now()
+	err := wp.pipeline.Process(ctx, event)
+	wp.load.observe(event.SessionID, time.Since(started))
+	return err
+}
+
 // Start launches all worker goroutines
 func (wp *WorkerPool) Start() {
 	log.Printf("Starting worker pool with %d workers", wp.workerCount)
@@ -64,48 +121,88 @@ func (wp *WorkerPool) worker(id int) {
 				continue
 			}
 			
-			// Process the event
-			if err := wp.handler(event); err != nil {
-				log.Printf("Worker %d: error processing event %s: %v", id, event.ID, err)
+			run, dropped := wp.bulkhead.acquire(event)
+			if dropped {
+				wp.load.drop(event.SessionID)
+				wp.ack(event)
+				processErrorLog.Printf("Worker %d: dropped event %s, session %s is over its backlog", id, event.ID, event.SessionID)
+			}
+			if !run {
+				continue
+			}
+
+			// Process the event, then any of its session's events parked
+			// while it ran. Stages may rewrite the session ID, e.g. to the
+			// session an alias mirrors, so the slot is released under the
+			// ID it was acquired with.
+			sessionID := event.SessionID
+			for event != nil {
+				if err := wp.process(wp.work, event); err != nil {
+					processErrorLog.Printf("Worker %d: error processing event %s: %v", id, event.ID, err)
+				}
+				event = wp.bulkhead.release(sessionID)
 			}
 		}
 	}
 }
 
-// Shutdown gracefully shuts down the worker pool
-// It waits for all workers to finish processing their current events
-func (wp *WorkerPool) Shutdown() {
+// process runs a dequeued event through the pipeline, then acknowledges it
+// to durable transports. Events that fail are acknowledged too, since
+// redelivering them would fail the same way.
+func (wp *WorkerPool) process(ctx context.Context, event *Event) error {
+	err := wp.Process(ctx, event)
+	wp.ack(event)
+	return err
+}
+
+// ack acknowledges an event to durable transports
+func (wp *WorkerPool) ack(event *Event) {
+	if acker, ok := wp.queue.(Acknowledger); ok {
+		acker.Ack(event)
+	}
+}
+
+// Shutdown stops the workers once they finish their current events. If ctx
+// is done first, those events are cancelled and ctx's error returned.
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down worker pool...")
-	
+
 	// Signal all workers to stop
 	wp.cancel()
-	
-	// Wait for all workers to finish
-	wp.wg.Wait()
-	
+
+	if err := wp.wait(ctx); err != nil {
+		return err
+	}
 	log.Println("Worker pool shutdown complete")
+	return nil
 }
 
-// ShutdownWithDrain gracefully shuts down and processes remaining events
-func (wp *WorkerPool) ShutdownWithDrain() {
-	log.Println("Shutting down worker pool with drain...")
-	
-	// Close the queue to prevent new events
-	wp.queue.Close()
-	
-	// Process remaining events
-	remaining := wp.queue.Drain()
-	log.Printf("Processing %d remaining events", len(remaining))
-	
-	for _, event := range remaining {
-		if err := wp.handler(event); err != nil {
-			log.Printf("Error processing remaining event %s: %v", event.ID, err)
-		}
+// wait blocks until the workers exit. Once ctx is done, the events they are
+// processing are cancelled and wait returns without them.
+func (wp *WorkerPool) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		wp.abort()
+		log.Printf("Worker pool shutdown deadline reached, cancelled in-flight events")
+		return ctx.Err()
+	}
+}
+
+// mergeCancel returns a context done when either parent is done, keeping
+// the values and deadline of the first
+func mergeCancel(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	return merged, func() {
+		stop()
+		cancel()
 	}
-	
-	// Cancel context and wait for workers
-	wp.cancel()
-	wp.wg.Wait()
-	
-	log.Println("Worker pool shutdown with drain complete")
 }
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_ShutdownCancelsStuckEventsAtTheDeadline(t *testing.T) {
+	queue := NewQueue(4)
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	pool := NewWorkerPool(queue, 1, func(ctx context.Context, _ *Event) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	pool.Start()
+	require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.ShutdownWithDrain(ctx), context.DeadlineExceeded)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the stuck event was never cancelled")
+	}
+}
+
+func TestWorkerPool_ShutdownWaitsForEventsWithinTheDeadline(t *testing.T) {
+	queue := NewQueue(4)
+	processed := make(chan struct{}, 4)
+	pool := NewWorkerPool(queue, 1, func(ctx context.Context, _ *Event) error {
+		time.Sleep(10 * time.Millisecond)
+		processed <- struct{}{}
+		return nil
+	})
+	pool.Start()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	}
+
+	require.NoError(t, pool.ShutdownWithDrain(context.Background()))
+	assert.Len(t, processed, 3)
+}
+
+func TestWorkerPool_BulkheadKeepsASlowSessionFromStallingOthers(t *testing.T) {
+	queue := NewQueue(16)
+	release := make(chan struct{})
+	processed := make(chan string, 16)
+	pool := NewWorkerPool(queue, 3, func(ctx context.Context, event *Event) error {
+		if event.SessionID == "slow" {
+			<-release
+		}
+		processed <- event.SessionID
+		return nil
+	})
+	pool.SetSessionLimits(1, 2)
+	pool.Start()
+	defer pool.Shutdown(context.Background())
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("slow", "u", ReactionFire)))
+	}
+	require.Eventually(t, func() bool {
+		loads := pool.SessionLoads()
+		return len(loads) == 1 && loads[0].Active == 1 && loads[0].Parked == 2 && loads[0].Dropped == 1
+	}, time.Second, 5*time.Millisecond, "one slow event runs, two wait and the fourth is dropped")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("fast", "u", ReactionFire)))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case sessionID := <-processed:
+			assert.Equal(t, "fast", sessionID)
+		case <-time.After(time.Second):
+			t.Fatal("the slow session stalled the other")
+		}
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		select {
+		case sessionID := <-processed:
+			assert.Equal(t, "slow", sessionID)
+		case <-time.After(time.Second):
+			t.Fatal("parked events were not processed")
+		}
+	}
+	require.Eventually(t, func() bool {
+		for _, load := range pool.SessionLoads() {
+			if load.SessionID == "slow" {
+				return load.Processed == 3 && load.Active == 0 && load.Parked == 0
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	loads := pool.SessionLoads()
+	require.Len(t, loads, 2)
+	assert.Equal(t, "slow", loads[0].SessionID, "slowest first")
+}
+
+func TestWorkerPool_BulkheadReleasesRewrittenSessions(t *testing.T) {
+	queue := NewQueue(16)
+	processed := make(chan string, 16)
+	pool := NewWorkerPool(queue, 2, func(ctx context.Context, event *Event) error {
+		// As admission does for events sent to an alias
+		event.SessionID = "canonical"
+		processed <- event.SessionID
+		return nil
+	})
+	pool.SetSessionLimits(1, 2)
+	pool.Start()
+	defer pool.Shutdown(context.Background())
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("alias", "u", ReactionFire)))
+		select {
+		case <-processed:
+		case <-time.After(time.Second):
+			t.Fatalf("event %d of the aliased session stalled", i)
+		}
+	}
+	require.Eventually(t, func() bool {
+		for _, load := range pool.SessionLoads() {
+			if load.Active != 0 || load.Parked != 0 || load.Dropped != 0 {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond, "every slot is released")
+}
+
+type memorySpill struct {
+	payloads [][]byte
+}
+
+func (m *memorySpill) SaveUndrainedEvents(_ context.Context, payloads [][]byte) error {
+	m.payloads = append(m.payloads, payloads...)
+	return nil
+}
+
+func (m *memorySpill) TakeUndrainedEvents(context.Context) ([][]byte, error) {
+	payloads := m.payloads
+	m.payloads = nil
+	return payloads, nil
+}
+
+func TestWorkerPool_DrainRunsInParallelAndSpillsAtTheDeadline(t *testing.T) {
+	queue := NewQueue(16)
+	release := make(chan struct{})
+	started := make(chan struct{}, 16)
+	pool := NewWorkerPool(queue, 2, func(ctx context.Context, _ *Event) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	store := &memorySpill{}
+	pool.SetSpill(StoreSpill(store, EncodingJSON))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), ReactionEvent("s", "u", ReactionFire)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.ShutdownWithDrain(ctx), context.DeadlineExceeded)
+	assert.Len(t, started, 2, "both workers drain at once")
+
+	progress := pool.DrainProgress()
+	assert.Equal(t, DrainProgress{Total: 5, Drained: 2, Spilled: 3}, progress)
+	require.Len(t, store.payloads, 3)
+
+	next := NewQueue(16)
+	replayed, err := ReplaySpilled(context.Background(), store, next)
+	require.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.Equal(t, 3, next.Len())
+	assert.Empty(t, store.payloads)
+}
@@ -0,0 +1,245 @@
+package experiments
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Variant is one arm of an experiment. Weight sets its share of users and
+// defaults to 1.
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// Experiment splits a session's audience between variants. An empty
+// SessionID applies the experiment to every session.
+type Experiment struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Variants  []Variant `json:"variants"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// validate checks the experiment and fills in default weights
+func (e *Experiment) validate() error {
+	if e.ID == "" {
+		return fmt.Errorf("experiment id is required")
+	}
+	if len(e.Variants) < 2 {
+		return fmt.Errorf("at least two variants are required")
+	}
+	seen := make(map[string]bool, len(e.Variants))
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		if v.Name == "" {
+			return fmt.Errorf("variant names are required")
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %q has a negative weight", v.Name)
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+	}
+	return nil
+}
+
+// Bucket deterministically assigns a user to a variant, so the same user
+// lands in the same variant on every instance and reconnect
+func (e *Experiment) Bucket(userID string) string {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	h := fnv.New64a()
+	h.Write([]byte(e.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(userID))
+	slot := int(h.Sum64() % uint64(total))
+	for _, v := range e.Variants {
+		if slot < v.Weight {
+			return v.Name
+		}
+		slot -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1].Name
+}
+
+// appliesTo reports whether the experiment runs in the session
+func (e *Experiment) appliesTo(sessionID string) bool {
+	return e.SessionID == "" || e.SessionID == sessionID
+}
+
+// variantStats accumulates engagement for one variant
+type variantStats struct {
+	participants   map[string]bool
+	reactionCounts map[events.ReactionType]int64
+	reactions      int64
+}
+
+// VariantResult summarizes engagement for one variant
+type VariantResult struct {
+	Variant                 string                        `json:"variant"`
+	Participants            int                           `json:"participants"`
+	Reactions               int64                         `json:"reactions"`
+	ReactionCounts          map[events.ReactionType]int64 `json:"reaction_counts"`
+	ReactionsPerParticipant float64                       `json:"reactions_per_participant"`
+}
+
+// Results reports an experiment's per-variant engagement
+type Results struct {
+	Experiment Experiment      `json:"experiment"`
+	Variants   []VariantResult `json:"variants"`
+}
+
+// Manager holds running experiments and their per-variant metrics
+type Manager struct {
+	experiments map[string]*Experiment
+	stats       map[string]map[string]*variantStats // experiment ID -> variant -> stats
+	mu          sync.RWMutex
+}
+
+// NewManager creates an empty experiment manager
+func NewManager() *Manager {
+	return &Manager{
+		experiments: make(map[string]*Experiment),
+		stats:       make(map[string]map[string]*variantStats),
+	}
+}
+
+// Create starts an experiment. Re-creating an existing ID resets its metrics.
+func (m *Manager) Create(experiment Experiment) (Experiment, error) {
+	if err := experiment.validate(); err != nil {
+		return Experiment{}, err
+	}
+	experiment.CreatedAt = time.Now().UTC()
+
+	stats := make(map[string]*variantStats, len(experiment.Variants))
+	for _, v := range experiment.Variants {
+		stats[v.Name] = &variantStats{
+			participants:   make(map[string]bool),
+			reactionCounts: make(map[events.ReactionType]int64),
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.experiments[experiment.ID] = &experiment
+	m.stats[experiment.ID] = stats
+	return experiment, nil
+}
+
+// Delete stops an experiment and discards its metrics
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.experiments[id]; !exists {
+		return false
+	}
+	delete(m.experiments, id)
+	delete(m.stats, id)
+	return true
+}
+
+// List returns every running experiment ordered by ID
+func (m *Manager) List() []Experiment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Experiment, 0, len(m.experiments))
+	for _, experiment := range m.experiments {
+		list = append(list, *experiment)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Assignments returns the user's variant in every experiment running in the
+// session, keyed by experiment ID
+func (m *Manager) Assignments(sessionID, userID string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	assignments := make(map[string]string)
+	for id, experiment := range m.experiments {
+		if experiment.appliesTo(sessionID) {
+			assignments[id] = experiment.Bucket(userID)
+		}
+	}
+	return assignments
+}
+
+// Apply attaches the user's variants to the event and counts joins and
+// reactions towards those variants
+func (m *Manager) Apply(event *events.Event) {
+	if event.UserID == "" {
+		return
+	}
+	reactionType, isReaction := event.GetReactionType()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, experiment := range m.experiments {
+		if !experiment.appliesTo(event.SessionID) {
+			continue
+		}
+		variant := experiment.Bucket(event.UserID)
+		event.SetVariant(id, variant)
+
+		stats := m.stats[id][variant]
+		switch event.Type {
+		case events.EventTypeJoinSession:
+			stats.participants[event.UserID] = true
+		case events.EventTypeReaction:
+			if isReaction {
+				// Users whose join predates the experiment still participate
+				stats.participants[event.UserID] = true
+				stats.reactionCounts[reactionType]++
+				stats.reactions++
+			}
+		}
+	}
+}
+
+// Results returns per-variant engagement for an experiment
+func (m *Manager) Results(id string) (Results, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	experiment, exists := m.experiments[id]
+	if !exists {
+		return Results{}, false
+	}
+	results := Results{Experiment: *experiment}
+	for _, v := range experiment.Variants {
+		stats := m.stats[id][v.Name]
+		counts := make(map[events.ReactionType]int64, len(stats.reactionCounts))
+		for reactionType, count := range stats.reactionCounts {
+			counts[reactionType] = count
+		}
+		result := VariantResult{
+			Variant:        v.Name,
+			Participants:   len(stats.participants),
+			Reactions:      stats.reactions,
+			ReactionCounts: counts,
+		}
+		if result.Participants > 0 {
+			result.ReactionsPerParticipant = float64(stats.reactions) / float64(result.Participants)
+		}
+		results.Variants = append(results.Variants, result)
+	}
+	return results, true
+}
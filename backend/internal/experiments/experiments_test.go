@@ -0,0 +1,78 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReactionUIExperiment(t *testing.T, m *Manager) Experiment {
+	experiment, err := m.Create(Experiment{
+		ID:        "reaction-ui",
+		SessionID: "s1",
+		Variants:  []Variant{{Name: "control"}, {Name: "carousel"}},
+	})
+	require.NoError(t, err)
+	return experiment
+}
+
+func TestBucket_IsDeterministicAndSplitsUsers(t *testing.T) {
+	m := NewManager()
+	experiment := newReactionUIExperiment(t, m)
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		variant := experiment.Bucket(userID)
+		assert.Equal(t, variant, experiment.Bucket(userID))
+		counts[variant]++
+	}
+	assert.InDelta(t, 500, counts["control"], 100)
+	assert.InDelta(t, 500, counts["carousel"], 100)
+}
+
+func TestManager_AggregatesReactionsPerVariant(t *testing.T) {
+	m := NewManager()
+	experiment := newReactionUIExperiment(t, m)
+
+	for i := 0; i < 20; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		m.Apply(events.JoinSessionEvent("s1", userID))
+		reaction := events.ReactionEvent("s1", userID, events.ReactionFire)
+		m.Apply(reaction)
+		assert.Equal(t, experiment.Bucket(userID), reaction.Variants["reaction-ui"])
+	}
+
+	// Other sessions are not part of the experiment
+	other := events.ReactionEvent("s2", "user-0", events.ReactionFire)
+	m.Apply(other)
+	assert.Empty(t, other.Variants)
+
+	results, ok := m.Results("reaction-ui")
+	require.True(t, ok)
+	require.Len(t, results.Variants, 2)
+	var participants int
+	var reactions int64
+	for _, v := range results.Variants {
+		participants += v.Participants
+		reactions += v.Reactions
+		if v.Participants > 0 {
+			assert.Equal(t, 1.0, v.ReactionsPerParticipant)
+		}
+	}
+	assert.Equal(t, 20, participants)
+	assert.Equal(t, int64(20), reactions)
+}
+
+func TestCreate_RejectsInvalidExperiments(t *testing.T) {
+	m := NewManager()
+	_, err := m.Create(Experiment{ID: "one-arm", Variants: []Variant{{Name: "a"}}})
+	assert.Error(t, err)
+	_, err = m.Create(Experiment{ID: "dupes", Variants: []Variant{{Name: "a"}, {Name: "a"}}})
+	assert.Error(t, err)
+	_, err = m.Create(Experiment{Variants: []Variant{{Name: "a"}, {Name: "b"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/metrics"
+	"github.com/jrudman25/livepulse/internal/notifications"
+)
+
+// S3Destination writes each document as an object in an S3 bucket
+type S3Destination struct {
+	endpoint string
+	prefix   string
+	creds    metrics.AWSCredentials
+	client   *http.Client
+}
+
+// NewS3Destination creates a destination writing to bucket, under prefix
+func NewS3Destination(creds metrics.AWSCredentials, bucket, prefix string) *S3Destination {
+	return &S3Destination{
+		endpoint: "https://" + bucket + ".s3." + creds.Region + ".amazonaws.com",
+		prefix:   prefix,
+		creds:    creds,
+		client:   &http.Client{},
+	}
+}
+
+// Name identifies the destination in logs
+func (d *S3Destination) Name() string {
+	return "s3"
+}
+
+// Put uploads the document with PutObject
+func (d *S3Destination) Put(ctx context.Context, doc Document, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.endpoint+"/"+path.Join(d.prefix, doc.Key()), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	d.creds.Sign(req, body, "s3", time.Now())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 returned %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
+
+// WebhookDestination delivers each document as a session.exported webhook
+// notification, with the notifier's signing and retries
+type WebhookDestination struct {
+	notifier *notifications.WebhookNotifier
+}
+
+// NewWebhookDestination creates a destination sending through notifier
+func NewWebhookDestination(notifier *notifications.WebhookNotifier) *WebhookDestination {
+	return &WebhookDestination{notifier: notifier}
+}
+
+// Name identifies the destination in logs
+func (d *WebhookDestination) Name() string {
+	return "webhook"
+}
+
+// Put hands the document to the notifier, which delivers it in the
+// background
+func (d *WebhookDestination) Put(_ context.Context, doc Document, _ []byte) error {
+	d.notifier.Notify(notifications.Event{
+		Type:       notifications.TypeSessionExported,
+		SessionID:  doc.SessionID,
+		OccurredAt: doc.ExportedAt,
+		Data:       doc,
+	})
+	return nil
+}
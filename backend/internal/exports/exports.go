@@ -0,0 +1,204 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/schema"
+	"github.com/jrudman25/livepulse/internal/sessions"
+)
+
+// Kind is what an export of a session contains
+type Kind string
+
+// Export kinds
+const (
+	KindSnapshot   Kind = "snapshot"   // full statistics snapshot
+	KindHighlights Kind = "highlights" // per-minute timeline and its busiest minutes
+	KindMilestones Kind = "milestones" // milestone progress and achievements
+)
+
+// IsValid reports whether k is a known export kind
+func (k Kind) IsValid() bool {
+	switch k {
+	case KindSnapshot, KindHighlights, KindMilestones:
+		return true
+	}
+	return false
+}
+
+// highlightPeaks is how many of the busiest minutes a highlights export lists
+const highlightPeaks = 5
+
+// Highlights is the timeline of a session with its busiest minutes
+type Highlights struct {
+	Timeline []aggregation.MinuteBucket `json:"timeline"`
+	Peaks    []aggregation.MinuteBucket `json:"peaks"` // busiest first
+}
+
+// Document is one export of one session
+type Document struct {
+	Kind       Kind        `json:"kind"`
+	SessionID  string      `json:"session_id"`
+	TenantID   string      `json:"tenant_id,omitempty"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Key names the object a document is stored under, e.g.
+// "acme/session-1/snapshot/20260501T200000Z.json"
+func (d Document) Key() string {
+	tenant := d.TenantID
+	if tenant == "" {
+		tenant = "default"
+	}
+	return path.Join(tenant, d.SessionID, string(d.Kind), d.ExportedAt.UTC().Format("20060102T150405Z")+".json")
+}
+
+// Destination stores exported documents
+type Destination interface {
+	Name() string
+	Put(ctx context.Context, doc Document, body []byte) error
+}
+
+// Exporter exports every live session on cron schedules, independent of
+// sessions ending
+type Exporter struct {
+	schedules    []Schedule
+	kinds        []Kind
+	registry     *sessions.Registry
+	aggregations *aggregation.Manager
+	tracker      *milestones.Tracker
+	destinations []Destination
+}
+
+// NewExporter creates an exporter writing the given kinds of every live
+// session to destinations each time one of the schedules fires. tracker may
+// be nil, in which case milestones are not exported.
+func NewExporter(schedules []Schedule, kinds []Kind, registry *sessions.Registry, manager *aggregation.Manager, tracker *milestones.Tracker, destinations []Destination) *Exporter {
+	return &Exporter{
+		schedules:    schedules,
+		kinds:        kinds,
+		registry:     registry,
+		aggregations: manager,
+		tracker:      tracker,
+		destinations: destinations,
+	}
+}
+
+// Next returns when the exporter next runs after after
+func (e *Exporter) Next(after time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range e.schedules {
+		if at := schedule.Next(after); !at.IsZero() && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return next
+}
+
+// Start runs the exports as the schedules fire until ctx is cancelled. Each
+// run may take at most timeout; exports it could not write are logged and
+// not retried, since the next run carries current values.
+func (e *Exporter) Start(ctx context.Context, timeout time.Duration) {
+	if len(e.schedules) == 0 || len(e.destinations) == 0 {
+		return
+	}
+	go func() {
+		for {
+			next := e.Next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			runCtx, cancel := context.WithTimeout(ctx, timeout)
+			written := e.Run(runCtx, next)
+			cancel()
+			log.Printf("Scheduled export at %s wrote %d documents", next.Format(time.RFC3339), written)
+		}
+	}()
+}
+
+// Run exports every live session once, stamped at, and returns how many
+// documents were written to destinations
+func (e *Exporter) Run(ctx context.Context, at time.Time) int {
+	live := e.registry.List(sessions.Filter{Status: sessions.StatusLive})
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+
+	written := 0
+	for _, session := range live {
+		for _, kind := range e.kinds {
+			data, ok := e.collect(kind, session.ID)
+			if !ok {
+				continue
+			}
+			doc := Document{Kind: kind, SessionID: session.ID, TenantID: session.TenantID, ExportedAt: at.UTC(), Data: data}
+			body, err := json.Marshal(doc)
+			if err != nil {
+				log.Printf("Error encoding %s export of session %s: %v", kind, session.ID, err)
+				continue
+			}
+			for _, destination := range e.destinations {
+				if err := destination.Put(ctx, doc, body); err != nil {
+					log.Printf("Error exporting %s of session %s to %s: %v", kind, session.ID, destination.Name(), err)
+					continue
+				}
+				written++
+			}
+		}
+	}
+	return written
+}
+
+// collect gathers one kind of export for a session, reporting false if the
+// session has nothing of that kind on this instance
+func (e *Exporter) collect(kind Kind, sessionID string) (interface{}, bool) {
+	switch kind {
+	case KindMilestones:
+		if e.tracker == nil {
+			return nil, false
+		}
+		list := e.tracker.GetSessionMilestones(sessionID)
+		return schema.MilestonesV1From(list), list != nil
+	case KindSnapshot, KindHighlights:
+		stats, exists := e.aggregations.GetSession(sessionID)
+		if !exists {
+			return nil, false
+		}
+		if kind == KindSnapshot {
+			return schema.SnapshotV1From(stats.GetSnapshot()), true
+		}
+		timeline := stats.GetReactionsByMinute()
+		return Highlights{Timeline: timeline, Peaks: peaks(timeline, highlightPeaks)}, true
+	}
+	return nil, false
+}
+
+// peaks returns up to n of the busiest minutes with any reactions, busiest
+// first and earliest first among ties
+func peaks(timeline []aggregation.MinuteBucket, n int) []aggregation.MinuteBucket {
+	busiest := make([]aggregation.MinuteBucket, 0, len(timeline))
+	for _, bucket := range timeline {
+		if bucket.Total > 0 {
+			busiest = append(busiest, bucket)
+		}
+	}
+	sort.SliceStable(busiest, func(i, j int) bool { return busiest[i].Total > busiest[j].Total })
+	if len(busiest) > n {
+		busiest = busiest[:n]
+	}
+	return busiest
+}
@@ -0,0 +1,116 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/metrics"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDestination records the documents put to it
+type memoryDestination struct {
+	mu   sync.Mutex
+	docs map[string][]byte
+}
+
+func (d *memoryDestination) Name() string { return "memory" }
+
+func (d *memoryDestination) Put(_ context.Context, doc Document, body []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.docs[doc.Key()] = body
+	return nil
+}
+
+func TestExporter_RunExportsEveryLiveSession(t *testing.T) {
+	registry := sessions.NewRegistry()
+	registry.Create(sessions.Session{ID: "show-1", TenantID: "acme"})
+	registry.Create(sessions.Session{ID: "show-2"})
+	registry.Create(sessions.Session{ID: "ended"})
+	registry.End("ended")
+
+	manager := aggregation.NewManager()
+	for _, sessionID := range []string{"show-1", "ended"} {
+		manager.ProcessEvent(events.JoinSessionEvent(sessionID, "u1"))
+		manager.ProcessEvent(events.ReactionEvent(sessionID, "u1", events.ReactionFire))
+	}
+	tracker := milestones.NewTracker(nil)
+	tracker.InitializeSession("show-1", []int{100})
+
+	destination := &memoryDestination{docs: make(map[string][]byte)}
+	kinds := []Kind{KindSnapshot, KindHighlights, KindMilestones}
+	exporter := NewExporter(nil, kinds, registry, manager, tracker, []Destination{destination})
+	at := time.Date(2026, 5, 1, 21, 0, 0, 0, time.UTC)
+
+	// show-2 has no statistics or milestones on this instance yet
+	assert.Equal(t, 3, exporter.Run(context.Background(), at))
+	require.Contains(t, destination.docs, "acme/show-1/snapshot/20260501T210000Z.json")
+	require.Contains(t, destination.docs, "acme/show-1/milestones/20260501T210000Z.json")
+
+	var highlights struct {
+		Kind Kind       `json:"kind"`
+		Data Highlights `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(destination.docs["acme/show-1/highlights/20260501T210000Z.json"], &highlights))
+	assert.Equal(t, KindHighlights, highlights.Kind)
+	require.Len(t, highlights.Data.Peaks, 1)
+	assert.Equal(t, int64(1), highlights.Data.Peaks[0].Total)
+}
+
+func TestExporter_NextIsTheEarliestSchedule(t *testing.T) {
+	hourly, _ := ParseSchedule("@hourly")
+	quarterly, _ := ParseSchedule("*/15 * * * *")
+	exporter := NewExporter([]Schedule{hourly, quarterly}, nil, sessions.NewRegistry(), aggregation.NewManager(), nil, nil)
+	assert.Equal(t, time.Date(2026, 5, 1, 20, 15, 0, 0, time.UTC), exporter.Next(time.Date(2026, 5, 1, 20, 7, 0, 0, time.UTC)))
+}
+
+func TestPeaks_OrdersBusiestMinutesFirst(t *testing.T) {
+	timeline := []aggregation.MinuteBucket{{Minute: 0, Total: 3}, {Minute: 1}, {Minute: 2, Total: 9}, {Minute: 3, Total: 3}}
+	busiest := peaks(timeline, 2)
+	require.Len(t, busiest, 2)
+	assert.Equal(t, 2, busiest[0].Minute)
+	assert.Equal(t, 0, busiest[1].Minute, "earlier minute wins a tie")
+}
+
+func TestS3Destination_PutsSignedObjects(t *testing.T) {
+	var method, objectPath, auth, contentHash string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, objectPath = r.Method, r.URL.Path
+		auth, contentHash = r.Header.Get("Authorization"), r.Header.Get("X-Amz-Content-Sha256")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	destination := NewS3Destination(metrics.AWSCredentials{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"}, "exports", "livepulse")
+	destination.endpoint = server.URL
+	doc := Document{Kind: KindSnapshot, SessionID: "show-1", ExportedAt: time.Date(2026, 5, 1, 21, 0, 0, 0, time.UTC)}
+	require.NoError(t, destination.Put(context.Background(), doc, []byte(`{}`)))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/livepulse/default/show-1/snapshot/20260501T210000Z.json", objectPath)
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20"), auth)
+	assert.Contains(t, auth, "/us-east-1/s3/aws4_request")
+	assert.Equal(t, "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", contentHash)
+	assert.Equal(t, `{}`, string(body))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	destination.endpoint = failing.URL
+	assert.ErrorContains(t, destination.Put(context.Background(), doc, []byte(`{}`)), "403")
+}
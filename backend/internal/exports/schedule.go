@@ -0,0 +1,43 @@
+package exports
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is a standard five-field cron expression (minute, hour, day of
+// month, month and day of week) or a descriptor such as @hourly, evaluated
+// in UTC
+type Schedule struct {
+	expr     string
+	schedule cron.Schedule
+}
+
+// ParseSchedule parses a schedule, refusing ones that never fire
+func ParseSchedule(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	parsed, err := cron.ParseStandard(spec)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	s := Schedule{expr: spec, schedule: parsed}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return Schedule{}, fmt.Errorf("schedule %q never fires", expr)
+	}
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after after that the schedule fires, or the
+// zero time if it does not fire within five years
+func (s Schedule) Next(after time.Time) time.Time {
+	// Schedules without a CRON_TZ follow the location of the time given
+	return s.schedule.Next(after.UTC())
+}
@@ -0,0 +1,32 @@
+package exports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_NextIsInUTC(t *testing.T) {
+	daily, err := ParseSchedule(" 30 8 * * * ")
+	require.NoError(t, err)
+	assert.Equal(t, "30 8 * * *", daily.String())
+
+	from := time.Date(2026, 5, 1, 20, 7, 30, 0, time.FixedZone("PDT", -7*60*60))
+	next := daily.Next(from)
+	assert.Equal(t, time.Date(2026, 5, 2, 8, 30, 0, 0, time.UTC), next)
+	assert.Equal(t, time.UTC, next.Location())
+
+	hourly, err := ParseSchedule("@hourly")
+	require.NoError(t, err)
+	at := time.Date(2026, 5, 1, 21, 0, 0, 0, time.UTC)
+	assert.Equal(t, at.Add(time.Hour), hourly.Next(at), "strictly after")
+}
+
+func TestParseSchedule_RejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "0 0 30 2 *"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,198 @@
+package filters
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sync"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Action is what a matching rule does to an event
+type Action string
+
+const (
+	ActionDrop Action = "drop"
+	ActionTag  Action = "tag"
+)
+
+// Rule matches events by their fields. Every non-empty condition must match.
+type Rule struct {
+	ID            string                `json:"id"`
+	Action        Action                `json:"action"`
+	Tag           string                `json:"tag,omitempty"` // applied by tag rules
+	UserIDPattern string                `json:"user_id_pattern,omitempty"`
+	EventTypes    []events.EventType    `json:"event_types,omitempty"`
+	ReactionTypes []events.ReactionType `json:"reaction_types,omitempty"`
+	PayloadField  string                `json:"payload_field,omitempty"`
+	PayloadValue  string                `json:"payload_value,omitempty"`
+	SourceCIDR    string                `json:"source_cidr,omitempty"`
+
+	userPattern *regexp.Regexp
+	network     *net.IPNet
+}
+
+// compile validates the rule and prepares its patterns
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule id is required")
+	}
+	switch r.Action {
+	case ActionDrop:
+	case ActionTag:
+		if r.Tag == "" {
+			return fmt.Errorf("tag rules require a tag")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.UserIDPattern != "" {
+		pattern, err := regexp.Compile(r.UserIDPattern)
+		if err != nil {
+			return fmt.Errorf("invalid user_id_pattern: %v", err)
+		}
+		r.userPattern = pattern
+	}
+	if r.SourceCIDR != "" {
+		_, network, err := net.ParseCIDR(r.SourceCIDR)
+		if err != nil {
+			return fmt.Errorf("invalid source_cidr: %v", err)
+		}
+		r.network = network
+	}
+	if r.PayloadValue != "" && r.PayloadField == "" {
+		return fmt.Errorf("payload_value requires payload_field")
+	}
+	return nil
+}
+
+// Matches reports whether the event satisfies every condition of the rule
+func (r *Rule) Matches(event *events.Event) bool {
+	if r.userPattern != nil && !r.userPattern.MatchString(event.UserID) {
+		return false
+	}
+	if len(r.EventTypes) > 0 && !containsEventType(r.EventTypes, event.Type) {
+		return false
+	}
+	if len(r.ReactionTypes) > 0 {
+		reactionType, ok := event.GetReactionType()
+		if !ok || !containsReactionType(r.ReactionTypes, reactionType) {
+			return false
+		}
+	}
+	if r.PayloadField != "" {
+		value, exists := event.PayloadField(r.PayloadField)
+		if !exists || (r.PayloadValue != "" && fmt.Sprint(value) != r.PayloadValue) {
+			return false
+		}
+	}
+	if r.network != nil {
+		ip := net.ParseIP(event.SourceIP)
+		if ip == nil || !r.network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsEventType(types []events.EventType, t events.EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsReactionType(types []events.ReactionType, t events.ReactionType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine evaluates accept/deny rules against incoming events. Rules can be
+// changed at runtime without a redeploy.
+type Engine struct {
+	rules []*Rule
+	mu    sync.RWMutex
+}
+
+// NewEngine creates an engine with the given initial rules
+func NewEngine(rules []Rule) (*Engine, error) {
+	engine := &Engine{}
+	for _, rule := range rules {
+		if err := engine.Put(rule); err != nil {
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+// Put adds a rule, replacing any existing rule with the same ID
+func (e *Engine) Put(rule Rule) error {
+	if err := rule.compile(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, existing := range e.rules {
+		if existing.ID == rule.ID {
+			e.rules[i] = &rule
+			return nil
+		}
+	}
+	e.rules = append(e.rules, &rule)
+	return nil
+}
+
+// Delete removes a rule, reporting whether it existed
+func (e *Engine) Delete(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, existing := range e.rules {
+		if existing.ID == id {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// List returns copies of every rule in evaluation order
+func (e *Engine) List() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]Rule, len(e.rules))
+	for i, rule := range e.rules {
+		rules[i] = *rule
+	}
+	return rules
+}
+
+// Apply tags the event with every matching tag rule and reports whether it
+// should be kept. Any matching drop rule drops the event.
+func (e *Engine) Apply(event *events.Event) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		if !rule.Matches(event) {
+			continue
+		}
+		switch rule.Action {
+		case ActionDrop:
+			return false
+		case ActionTag:
+			event.AddTag(rule.Tag)
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_DropsTestTraffic(t *testing.T) {
+	engine, err := NewEngine([]Rule{
+		{ID: "load-tests", Action: ActionDrop, UserIDPattern: "^loadtest-"},
+		{ID: "office", Action: ActionDrop, SourceCIDR: "10.0.0.0/8", ReactionTypes: []events.ReactionType{events.ReactionFire}},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, engine.Apply(events.ReactionEvent("s1", "loadtest-42", events.ReactionLike)))
+	assert.True(t, engine.Apply(events.ReactionEvent("s1", "viewer", events.ReactionLike)))
+
+	fromOffice := events.ReactionEvent("s1", "viewer", events.ReactionFire)
+	fromOffice.SourceIP = "10.1.2.3"
+	assert.False(t, engine.Apply(fromOffice))
+
+	likeFromOffice := events.ReactionEvent("s1", "viewer", events.ReactionLike)
+	likeFromOffice.SourceIP = "10.1.2.3"
+	assert.True(t, engine.Apply(likeFromOffice), "every condition must match")
+}
+
+func TestEngine_TagsMatchingPayloads(t *testing.T) {
+	engine, err := NewEngine([]Rule{
+		{ID: "vip", Action: ActionTag, Tag: "vip", EventTypes: []events.EventType{events.EventTypeJoinSession}, PayloadField: "cohort", PayloadValue: "vip"},
+	})
+	require.NoError(t, err)
+
+	event := events.CohortJoinSessionEvent("s1", "u1", "vip")
+	assert.True(t, engine.Apply(event))
+	assert.True(t, event.HasTag("vip"))
+
+	other := events.CohortJoinSessionEvent("s1", "u2", "press")
+	engine.Apply(other)
+	assert.False(t, other.HasTag("vip"))
+}
+
+func TestEngine_RuntimeChanges(t *testing.T) {
+	engine, _ := NewEngine(nil)
+	require.Error(t, engine.Put(Rule{ID: "bad", Action: ActionDrop, UserIDPattern: "("}))
+	require.Error(t, engine.Put(Rule{ID: "notag", Action: ActionTag}))
+
+	require.NoError(t, engine.Put(Rule{ID: "block", Action: ActionDrop, UserIDPattern: "^bot"}))
+	assert.False(t, engine.Apply(events.JoinSessionEvent("s1", "bot-1")))
+
+	assert.True(t, engine.Delete("block"))
+	assert.True(t, engine.Apply(events.JoinSessionEvent("s1", "bot-1")))
+	assert.Empty(t, engine.List())
+}
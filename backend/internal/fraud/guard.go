@@ -0,0 +1,311 @@
+package fraud
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Tier is the enforcement level applied to a user based on their score
+type Tier string
+
+const (
+	TierNormal Tier = "normal"
+	TierStrict Tier = "strict" // tighter reaction rate limit
+	TierShadow Tier = "shadow" // reactions accepted but not counted
+)
+
+// Verdict is the outcome of checking a single reaction
+type Verdict int
+
+const (
+	VerdictAllow Verdict = iota
+	VerdictReject
+	VerdictShadow
+)
+
+// Err returns errs.ErrRateLimited for rejected reactions. Shadowed
+// reactions report no error, since the user must not learn of the shadow.
+func (v Verdict) Err() error {
+	if v == VerdictReject {
+		return errs.ErrRateLimited
+	}
+	return nil
+}
+
+// Points added to a user's score for each kind of offence
+const (
+	velocityViolationPoints = 1
+	anomalyFlagPoints       = 5
+	banPoints               = 20
+)
+
+// scoreHalfLife controls how quickly old offences stop counting
+const scoreHalfLife = 30 * 24 * time.Hour
+
+// cleanTTL is how long a user the store holds no score for is trusted to
+// still have none, so reconnecting clean users skip the store
+const cleanTTL = 10 * time.Minute
+
+// Store persists fraud scores across sessions and restarts
+type Store interface {
+	LoadFraudScore(ctx context.Context, userID string) (*storage.FraudRecord, error)
+	SaveFraudScore(ctx context.Context, record storage.FraudRecord) error
+}
+
+// Limits configures reaction rate limits and the score thresholds for each tier
+type Limits struct {
+	ReactionsPerSecond       float64
+	StrictReactionsPerSecond float64
+	Burst                    float64
+	StrictScore              float64
+	ShadowScore              float64
+}
+
+// bucket is a token bucket limiting one user's reactions in one session
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Guard scores users across sessions and enforces per-tier reaction limits
+type Guard struct {
+	store   Store
+	limits  Limits
+	scores  map[string]*storage.FraudRecord // userID -> cached score
+	clean   map[string]time.Time            // userID -> when the store held no score
+	dirty   map[string]bool                 // userID -> score changed since last flush
+	buckets map[string]*bucket              // sessionID|userID -> rate limit state
+	mu      sync.Mutex
+}
+
+// NewGuard creates a new fraud guard. A nil store keeps scores in memory only.
+func NewGuard(store Store, limits Limits) *Guard {
+	return &Guard{
+		store:   store,
+		limits:  limits,
+		scores:  make(map[string]*storage.FraudRecord),
+		clean:   make(map[string]time.Time),
+		dirty:   make(map[string]bool),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// decayedPoints applies exponential decay to a score since it last changed
+func decayedPoints(record *storage.FraudRecord, now time.Time) float64 {
+	elapsed := now.Sub(record.UpdatedAt)
+	if elapsed <= 0 {
+		return record.Points
+	}
+	return record.Points * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+// tierFor maps a score to its enforcement tier
+func (g *Guard) tierFor(points float64) Tier {
+	switch {
+	case g.limits.ShadowScore > 0 && points >= g.limits.ShadowScore:
+		return TierShadow
+	case g.limits.StrictScore > 0 && points >= g.limits.StrictScore:
+		return TierStrict
+	default:
+		return TierNormal
+	}
+}
+
+// OnJoin loads the user's persisted score and returns the tier that will be
+// applied to them for this session
+func (g *Guard) OnJoin(ctx context.Context, userID string) Tier {
+	g.mu.Lock()
+	_, cached := g.scores[userID]
+	if checkedAt, ok := g.clean[userID]; ok && time.Since(checkedAt) < cleanTTL {
+		cached = true
+	}
+	g.mu.Unlock()
+
+	if !cached && g.store != nil {
+		record, err := g.store.LoadFraudScore(ctx, userID)
+		if err != nil {
+			log.Printf("Error loading fraud score for %s: %v", userID, err)
+		}
+		g.mu.Lock()
+		if record != nil {
+			if _, exists := g.scores[userID]; !exists {
+				g.scores[userID] = record
+			}
+		} else if err == nil {
+			g.clean[userID] = time.Now()
+		}
+		g.mu.Unlock()
+	}
+
+	tier := g.UserTier(userID)
+	if tier != TierNormal {
+		log.Printf("User %s joined with fraud tier %s", userID, tier)
+	}
+	return tier
+}
+
+// UserTier returns the tier currently applied to a user
+func (g *Guard) UserTier(userID string) Tier {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	record, exists := g.scores[userID]
+	if !exists {
+		return TierNormal
+	}
+	return g.tierFor(decayedPoints(record, time.Now().UTC()))
+}
+
+// CheckReaction applies the user's tier to a reaction. Exceeding the rate
+// limit rejects the reaction and counts as a velocity violation.
+func (g *Guard) CheckReaction(sessionID, userID string) Verdict {
+	tier := g.UserTier(userID)
+	if tier == TierShadow {
+		return VerdictShadow
+	}
+
+	rate := g.limits.ReactionsPerSecond
+	if tier == TierStrict {
+		rate = g.limits.StrictReactionsPerSecond
+	}
+	if rate <= 0 {
+		return VerdictAllow
+	}
+
+	now := time.Now()
+	key := sessionID + "|" + userID
+
+	g.mu.Lock()
+	b, exists := g.buckets[key]
+	if !exists {
+		b = &bucket{tokens: g.limits.Burst, last: now}
+		g.buckets[key] = b
+	}
+	b.tokens = math.Min(g.limits.Burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	g.mu.Unlock()
+
+	if !allowed {
+		g.record(userID, func(r *storage.FraudRecord) {
+			r.VelocityViolations++
+			r.Points += velocityViolationPoints
+		})
+		return VerdictReject
+	}
+	return VerdictAllow
+}
+
+// FlagAnomaly records an anomaly detected for a user
+func (g *Guard) FlagAnomaly(userID, reason string) {
+	log.Printf("Fraud anomaly flagged for %s: %s", userID, reason)
+	g.record(userID, func(r *storage.FraudRecord) {
+		r.AnomalyFlags++
+		r.Points += anomalyFlagPoints
+	})
+}
+
+// RecordBan adds a moderator ban to the user's history
+func (g *Guard) RecordBan(userID string) {
+	g.record(userID, func(r *storage.FraudRecord) {
+		r.Bans++
+		r.Points += banPoints
+	})
+}
+
+// record applies a change to a user's decayed score and marks it for persistence
+func (g *Guard) record(userID string, apply func(*storage.FraudRecord)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now().UTC()
+	record, exists := g.scores[userID]
+	if !exists {
+		record = &storage.FraudRecord{UserID: userID, UpdatedAt: now}
+		g.scores[userID] = record
+	}
+	record.Points = decayedPoints(record, now)
+	record.UpdatedAt = now
+	apply(record)
+	g.dirty[userID] = true
+}
+
+// ForgetSession releases rate limit state for a session that ended
+func (g *Guard) ForgetSession(sessionID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	prefix := sessionID + "|"
+	for key := range g.buckets {
+		if strings.HasPrefix(key, prefix) {
+			delete(g.buckets, key)
+		}
+	}
+}
+
+// Start periodically persists changed scores until the context is cancelled
+func (g *Guard) Start(ctx context.Context, interval time.Duration) {
+	if g.store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				g.Flush(context.Background())
+				return
+			case <-ticker.C:
+				g.Flush(ctx)
+			}
+		}
+	}()
+}
+
+// Flush writes every changed score to the store
+func (g *Guard) Flush(ctx context.Context) {
+	if g.store == nil {
+		return
+	}
+
+	g.mu.Lock()
+	// Idle buckets have refilled completely, so they carry no state
+	idleCutoff := time.Now().Add(-5 * time.Minute)
+	for key, b := range g.buckets {
+		if b.last.Before(idleCutoff) {
+			delete(g.buckets, key)
+		}
+	}
+	for userID, checkedAt := range g.clean {
+		if time.Since(checkedAt) >= cleanTTL {
+			delete(g.clean, userID)
+		}
+	}
+	pending := make([]storage.FraudRecord, 0, len(g.dirty))
+	for userID := range g.dirty {
+		pending = append(pending, *g.scores[userID])
+	}
+	g.dirty = make(map[string]bool)
+	g.mu.Unlock()
+
+	for _, record := range pending {
+		if err := g.store.SaveFraudScore(ctx, record); err != nil {
+			log.Printf("Error saving fraud score for %s: %v", record.UserID, err)
+			// Retry on the next flush
+			g.mu.Lock()
+			g.dirty[record.UserID] = true
+			g.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is an in-process Store used to simulate persistence
+type memoryStore struct {
+	records map[string]storage.FraudRecord
+	loads   int
+}
+
+func (m *memoryStore) LoadFraudScore(_ context.Context, userID string) (*storage.FraudRecord, error) {
+	m.loads++
+	if r, ok := m.records[userID]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SaveFraudScore(_ context.Context, r storage.FraudRecord) error {
+	m.records[r.UserID] = r
+	return nil
+}
+
+func testLimits() Limits {
+	return Limits{
+		ReactionsPerSecond:       10,
+		StrictReactionsPerSecond: 1,
+		Burst:                    3,
+		StrictScore:              1.5,
+		ShadowScore:              50,
+	}
+}
+
+func TestGuard_VelocityViolationsAccumulateAndPersist(t *testing.T) {
+	store := &memoryStore{records: make(map[string]storage.FraudRecord)}
+	guard := NewGuard(store, testLimits())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, VerdictAllow, guard.CheckReaction("s1", "spammer"))
+	}
+	assert.Equal(t, VerdictReject, guard.CheckReaction("s1", "spammer"), "burst exhausted")
+	assert.Equal(t, VerdictReject, guard.CheckReaction("s1", "spammer"))
+
+	guard.Flush(context.Background())
+	assert.Equal(t, 2, store.records["spammer"].VelocityViolations)
+	assert.Equal(t, TierStrict, guard.UserTier("spammer"))
+}
+
+func TestGuard_RepeatOffenderIsShadowedOnJoin(t *testing.T) {
+	store := &memoryStore{records: map[string]storage.FraudRecord{
+		"repeat": {UserID: "repeat", Points: 80, Bans: 4, UpdatedAt: time.Now().UTC()},
+	}}
+	guard := NewGuard(store, testLimits())
+
+	assert.Equal(t, TierShadow, guard.OnJoin(context.Background(), "repeat"))
+	assert.Equal(t, VerdictShadow, guard.CheckReaction("s2", "repeat"))
+	assert.Equal(t, TierNormal, guard.OnJoin(context.Background(), "newcomer"))
+}
+
+func TestGuard_CleanUsersAreNotLookedUpOnEveryJoin(t *testing.T) {
+	store := &memoryStore{records: make(map[string]storage.FraudRecord)}
+	guard := NewGuard(store, testLimits())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, TierNormal, guard.OnJoin(context.Background(), "clean"))
+	}
+	assert.Equal(t, 1, store.loads, "reconnects reuse the empty lookup")
+}
+
+func TestGuard_ScoresDecayOverTime(t *testing.T) {
+	store := &memoryStore{records: map[string]storage.FraudRecord{
+		"reformed": {UserID: "reformed", Points: 80, UpdatedAt: time.Now().Add(-6 * scoreHalfLife)},
+	}}
+	guard := NewGuard(store, testLimits())
+
+	assert.Equal(t, TierNormal, guard.OnJoin(context.Background(), "reformed"), "80 points after six half-lives is ~1.25")
+}
@@ -0,0 +1,176 @@
+package fraud
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// sourceKey salts IP hashes so reports cannot be reversed by hashing the
+// whole address space. It changes on restart, as do the reports.
+var sourceKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// HashIP returns the pseudonymous form of an address used in source reports
+func HashIP(ip string) string {
+	mac := hmac.New(sha256.New, sourceKey)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// ssCounter is one monitored item of a space-saving sketch
+type ssCounter struct {
+	count int64
+	err   int64 // upper bound on how much count overestimates
+}
+
+// spaceSaving approximates the most frequent items of a stream in fixed
+// memory: once full, a new item replaces the least counted one and inherits
+// its count as error. Any item seen more than total/capacity times is
+// guaranteed to be monitored.
+type spaceSaving struct {
+	capacity int
+	counters map[string]*ssCounter
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{capacity: capacity, counters: make(map[string]*ssCounter, capacity)}
+}
+
+// add counts one occurrence of item
+func (s *spaceSaving) add(item string) {
+	if c, exists := s.counters[item]; exists {
+		c.count++
+		return
+	}
+	if len(s.counters) < s.capacity {
+		s.counters[item] = &ssCounter{count: 1}
+		return
+	}
+
+	var minItem string
+	var minCounter *ssCounter
+	for key, c := range s.counters {
+		if minCounter == nil || c.count < minCounter.count {
+			minItem, minCounter = key, c
+		}
+	}
+	delete(s.counters, minItem)
+	s.counters[item] = &ssCounter{count: minCounter.count + 1, err: minCounter.count}
+}
+
+// top returns up to n items, most counted first
+func (s *spaceSaving) top(n int, total int64) []SourceCount {
+	counts := make([]SourceCount, 0, len(s.counters))
+	for item, c := range s.counters {
+		counts = append(counts, SourceCount{
+			Source: item,
+			Count:  c.count,
+			Error:  c.err,
+			Share:  float64(c.count) / float64(total),
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Source < counts[j].Source
+	})
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// SourceCount is an approximate event count for one source. The true count
+// lies between Count-Error and Count.
+type SourceCount struct {
+	Source string  `json:"source"`
+	Count  int64   `json:"count"`
+	Error  int64   `json:"error"`
+	Share  float64 `json:"share"` // of all events in the session
+}
+
+// SourceReport lists the heaviest event sources of a session
+type SourceReport struct {
+	SessionID string        `json:"session_id"`
+	Events    int64         `json:"events"`
+	Clients   []SourceCount `json:"clients"`
+	IPs       []SourceCount `json:"ips"` // hashed with HashIP
+}
+
+// sessionSources holds the sketches of one session
+type sessionSources struct {
+	total   int64
+	clients *spaceSaving
+	ips     *spaceSaving
+}
+
+// SourceTracker keeps approximate top-K event sources per session, by client
+// (the authenticated user) and by hashed IP, so load spikes can be traced to
+// a handful of sources in fixed memory per session
+type SourceTracker struct {
+	capacity int
+	sessions map[string]*sessionSources
+	mu       sync.Mutex
+}
+
+// NewSourceTracker creates a tracker monitoring up to capacity sources of
+// each kind per session
+func NewSourceTracker(capacity int) *SourceTracker {
+	return &SourceTracker{
+		capacity: capacity,
+		sessions: make(map[string]*sessionSources),
+	}
+}
+
+// Record counts an event against its sources
+func (t *SourceTracker) Record(event *events.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sources, exists := t.sessions[event.SessionID]
+	if !exists {
+		sources = &sessionSources{clients: newSpaceSaving(t.capacity), ips: newSpaceSaving(t.capacity)}
+		t.sessions[event.SessionID] = sources
+	}
+	sources.total++
+	if event.UserID != "" {
+		sources.clients.add(event.UserID)
+	}
+	if event.SourceIP != "" {
+		sources.ips.add(HashIP(event.SourceIP))
+	}
+}
+
+// Top returns up to n of a session's heaviest sources of each kind
+func (t *SourceTracker) Top(sessionID string, n int) (SourceReport, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sources, exists := t.sessions[sessionID]
+	if !exists {
+		return SourceReport{}, false
+	}
+	return SourceReport{
+		SessionID: sessionID,
+		Events:    sources.total,
+		Clients:   sources.clients.top(n, sources.total),
+		IPs:       sources.ips.top(n, sources.total),
+	}, true
+}
+
+// Remove forgets a session's sources once it has ended
+func (t *SourceTracker) Remove(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, sessionID)
+}
@@ -0,0 +1,48 @@
+package fraud
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceTracker_FindsHeavySourcesInFixedMemory(t *testing.T) {
+	tracker := NewSourceTracker(8)
+
+	// Two noisy sources hidden among many one-off clients
+	for i := 0; i < 500; i++ {
+		tracker.Record(&events.Event{SessionID: "s1", UserID: "bot-1", SourceIP: "203.0.113.7"})
+		if i%2 == 0 {
+			tracker.Record(&events.Event{SessionID: "s1", UserID: "bot-2", SourceIP: "203.0.113.7"})
+		}
+		tracker.Record(&events.Event{SessionID: "s1", UserID: fmt.Sprintf("viewer-%d", i), SourceIP: fmt.Sprintf("198.51.100.%d", i%250)})
+	}
+
+	report, ok := tracker.Top("s1", 2)
+	require.True(t, ok)
+	assert.Equal(t, int64(1250), report.Events)
+	require.Len(t, report.Clients, 2)
+	assert.Equal(t, "bot-1", report.Clients[0].Source)
+	assert.Equal(t, "bot-2", report.Clients[1].Source)
+	assert.GreaterOrEqual(t, report.Clients[0].Count, int64(500))
+	assert.LessOrEqual(t, report.Clients[0].Count-report.Clients[0].Error, int64(500))
+
+	require.NotEmpty(t, report.IPs)
+	assert.Equal(t, HashIP("203.0.113.7"), report.IPs[0].Source)
+	assert.InDelta(t, 0.6, report.IPs[0].Share, 0.05)
+
+	tracker.sessions["s1"].clients.add("late")
+	assert.Len(t, tracker.sessions["s1"].clients.counters, 8, "sketch must not grow past its capacity")
+}
+
+func TestSourceTracker_RemoveForgetsSession(t *testing.T) {
+	tracker := NewSourceTracker(4)
+	tracker.Record(&events.Event{SessionID: "s1", UserID: "u1"})
+	tracker.Remove("s1")
+
+	_, ok := tracker.Top("s1", 10)
+	assert.False(t, ok)
+}
@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Record is a single event read from a partitioned stream
+type Record struct {
+	Partition string // stream, shard or topic-partition
+	Offset    string // offset or sequence number within the partition
+	Event     *events.Event
+}
+
+// Source reads batches of records from a partitioned stream (Redis Streams,
+// Kafka, Kinesis) starting after the given per-partition offsets
+type Source interface {
+	Read(ctx context.Context, offsets map[string]string) ([]Record, error)
+}
+
+// CheckpointStore persists the last committed offset of each partition
+type CheckpointStore interface {
+	LoadCheckpoints(ctx context.Context, consumer string) (map[string]string, error)
+	SaveCheckpoint(ctx context.Context, consumer, partition, offset string) error
+}
+
+// Consumer feeds stream records to an event handler and commits offsets only
+// after the records were processed successfully, so a restart resumes where
+// it left off. Records replayed between processing and commit are dropped
+// by the deduper as long as they fall within the idempotency window.
+type Consumer struct {
+	name           string
+	source         Source
+	store          CheckpointStore
+	handler        events.EventHandler
+	deduper        *events.Deduper
+	commitInterval time.Duration
+	retryBackoff   time.Duration
+
+	offsets   map[string]string // partition -> last processed offset
+	committed map[string]string // partition -> last committed offset
+}
+
+// NewConsumer creates a checkpointing stream consumer
+func NewConsumer(name string, source Source, store CheckpointStore, handler events.EventHandler, idempotencyWindow, commitInterval time.Duration) *Consumer {
+	return &Consumer{
+		name:           name,
+		source:         source,
+		store:          store,
+		handler:        handler,
+		deduper:        events.NewDeduper(idempotencyWindow),
+		commitInterval: commitInterval,
+		retryBackoff:   500 * time.Millisecond,
+		offsets:        make(map[string]string),
+		committed:      make(map[string]string),
+	}
+}
+
+// Run consumes until the context is cancelled, committing a final
+// checkpoint on the way out
+func (c *Consumer) Run(ctx context.Context) error {
+	checkpoints, err := c.store.LoadCheckpoints(ctx, c.name)
+	if err != nil {
+		return err
+	}
+	for partition, offset := range checkpoints {
+		c.offsets[partition] = offset
+		c.committed[partition] = offset
+	}
+	log.Printf("Stream consumer %s resuming from %d checkpoints", c.name, len(checkpoints))
+
+	lastCommit := time.Now()
+	defer c.commit(context.Background())
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		records, err := c.source.Read(ctx, c.offsets)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Stream consumer %s read error: %v", c.name, err)
+			c.sleep(ctx)
+			continue
+		}
+
+		for _, record := range records {
+			if !c.process(ctx, record) {
+				return nil
+			}
+			c.offsets[record.Partition] = record.Offset
+		}
+
+		if time.Since(lastCommit) >= c.commitInterval {
+			c.commit(ctx)
+			lastCommit = time.Now()
+		}
+	}
+}
+
+// process handles one record, retrying until it succeeds. It returns false
+// only if the context was cancelled before the record was processed.
+func (c *Consumer) process(ctx context.Context, record Record) bool {
+	if record.Event == nil || c.deduper.Seen(record.Event.ID) {
+		return true
+	}
+	for {
+		err := c.handler(ctx, record.Event)
+		if err == nil {
+			return true
+		}
+		log.Printf("Stream consumer %s failed on %s@%s, retrying: %v", c.name, record.Partition, record.Offset, err)
+		if !c.sleep(ctx) {
+			c.deduper.Forget(record.Event.ID)
+			return false
+		}
+	}
+}
+
+// commit saves every partition offset that advanced since the last commit
+func (c *Consumer) commit(ctx context.Context) {
+	for partition, offset := range c.offsets {
+		if c.committed[partition] == offset {
+			continue
+		}
+		if err := c.store.SaveCheckpoint(ctx, c.name, partition, offset); err != nil {
+			log.Printf("Stream consumer %s failed to checkpoint %s@%s: %v", c.name, partition, offset, err)
+			continue
+		}
+		c.committed[partition] = offset
+	}
+}
+
+// sleep waits for the retry backoff, returning false if cancelled first
+func (c *Consumer) sleep(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(c.retryBackoff):
+		return true
+	}
+}
@@ -0,0 +1,149 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource replays a fixed partition of records after the given offset
+type sliceSource struct {
+	records []Record
+	cancel  context.CancelFunc
+}
+
+func (s *sliceSource) Read(_ context.Context, offsets map[string]string) ([]Record, error) {
+	var out []Record
+	started := offsets["p0"] == ""
+	for _, r := range s.records {
+		if started {
+			out = append(out, r)
+		}
+		if r.Offset == offsets["p0"] {
+			started = true
+		}
+	}
+	if len(out) == 0 {
+		// Nothing left to read, stop the consumer
+		s.cancel()
+	}
+	return out, nil
+}
+
+type memoryCheckpoints struct {
+	saved map[string]string
+	mu    sync.Mutex
+}
+
+func (m *memoryCheckpoints) LoadCheckpoints(_ context.Context, _ string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]string, len(m.saved))
+	for k, v := range m.saved {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (m *memoryCheckpoints) SaveCheckpoint(_ context.Context, _, partition, offset string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved[partition] = offset
+	return nil
+}
+
+func recordsFor(n int) []Record {
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{
+			Partition: "p0",
+			Offset:    string(rune('a' + i)),
+			Event:     events.ReactionEvent("s1", "u1", events.ReactionFire),
+		}
+	}
+	return records
+}
+
+func TestConsumer_ResumesFromCheckpoint(t *testing.T) {
+	records := recordsFor(5)
+	store := &memoryCheckpoints{saved: map[string]string{"p0": "b"}}
+
+	var processed []string
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &sliceSource{records: records, cancel: cancel}
+	consumer := NewConsumer("test", source, store, func(_ context.Context, e *events.Event) error {
+		processed = append(processed, e.ID)
+		return nil
+	}, time.Minute, time.Hour)
+
+	require.NoError(t, consumer.Run(ctx))
+	assert.Equal(t, []string{records[2].Event.ID, records[3].Event.ID, records[4].Event.ID}, processed)
+	assert.Equal(t, "e", store.saved["p0"], "final offset should be committed on shutdown")
+}
+
+func TestConsumer_DoesNotCommitPastFailures(t *testing.T) {
+	records := recordsFor(3)
+	store := &memoryCheckpoints{saved: map[string]string{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &sliceSource{records: records, cancel: func() {}}
+	consumer := NewConsumer("test", source, store, func(_ context.Context, e *events.Event) error {
+		if e.ID == records[1].Event.ID {
+			cancel()
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}, time.Minute, time.Hour)
+	consumer.retryBackoff = time.Millisecond
+
+	require.NoError(t, consumer.Run(ctx))
+	assert.Equal(t, "a", store.saved["p0"], "only the record before the failure is committed")
+}
+
+func TestConsumer_DropsReplaysWithinWindow(t *testing.T) {
+	records := recordsFor(2)
+	// Same event delivered twice under different offsets, as after a crash
+	records = append(records, Record{Partition: "p0", Offset: "z", Event: records[0].Event})
+	store := &memoryCheckpoints{saved: map[string]string{}}
+
+	count := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &sliceSource{records: records, cancel: cancel}
+	consumer := NewConsumer("test", source, store, func(context.Context, *events.Event) error {
+		count++
+		return nil
+	}, time.Minute, time.Hour)
+
+	require.NoError(t, consumer.Run(ctx))
+	assert.Equal(t, 2, count)
+}
+
+type memoryStreams struct {
+	entries []storage.StreamEntry
+}
+
+func (m *memoryStreams) ReadStreams(_ context.Context, _ map[string]string, _ []string, _ int64, _ time.Duration) ([]storage.StreamEntry, error) {
+	return m.entries, nil
+}
+
+func TestRedisStreamSource_DoesNotTrustSourceFromProducers(t *testing.T) {
+	reader := &memoryStreams{entries: []storage.StreamEntry{{
+		Stream:  "events",
+		ID:      "1-0",
+		Payload: []byte(`{"type":"reaction","session_id":"s1","user_id":"system:bot","source":"bot","external":true,"payload":{"reaction_type":"fire"}}`),
+	}}}
+
+	records, err := NewRedisStreamSource(reader, []string{"events"}).Read(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NotNil(t, records[0].Event)
+	assert.False(t, records[0].Event.IsSystem())
+	assert.True(t, records[0].Event.ClaimsSystemIdentity(), "a spoofed system identity is still caught at admission")
+}
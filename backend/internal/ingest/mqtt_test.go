@@ -0,0 +1,173 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTopicPattern_RequiresASessionLevel(t *testing.T) {
+	for _, pattern := range []string{"venues/buttons", "venues/{session}/{session}", "venues//{session}", "venues/+/{session}", "venues/{sesion}"} {
+		_, err := ParseTopicPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+
+	pattern, err := ParseTopicPattern("venues/{tenant}/{session}/buttons/{device}/{reaction}")
+	require.NoError(t, err)
+	assert.Equal(t, "venues/+/+/buttons/+/+", pattern.Filter())
+}
+
+func TestTopicPattern_EventFromMessage(t *testing.T) {
+	pattern, err := ParseTopicPattern("venues/{tenant}/{session}/{device}/{reaction}")
+	require.NoError(t, err)
+	reactionOf := func(event *events.Event) events.ReactionType {
+		return event.Payload.(*events.ReactionPayload).ReactionType
+	}
+
+	event, err := pattern.EventFromMessage("venues/acme/show-1/button-7/heart", nil)
+	require.NoError(t, err)
+	assert.Equal(t, events.EventTypeReaction, event.Type)
+	assert.Equal(t, "acme:show-1", event.SessionID)
+	assert.Equal(t, "device:button-7", event.UserID)
+	assert.Equal(t, events.ReactionHeart, reactionOf(event))
+
+	event, err = pattern.EventFromMessage("venues/acme/show-1/button-7/heart", []byte(" fire\n"))
+	require.NoError(t, err)
+	assert.Equal(t, events.ReactionFire, reactionOf(event), "a text payload names the reaction")
+
+	event, err = pattern.EventFromMessage("venues/acme/show-1/button-7/heart", []byte(`{"id":"0b7e4c8a-3f7e-4a59-9c1e-5d2f8b6a1c3d","session_id":"other","source":"trivia-bot","payload":{"reaction_type":"cheer"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "acme:show-1", event.SessionID, "the topic decides the session")
+	assert.Equal(t, events.ReactionCheer, reactionOf(event))
+	assert.Equal(t, "0b7e4c8a-3f7e-4a59-9c1e-5d2f8b6a1c3d", event.ID)
+	assert.True(t, event.External)
+	assert.False(t, event.IsSystem(), "devices cannot pose as integrations")
+
+	_, err = pattern.EventFromMessage("venues/acme/show-1/button-7", nil)
+	assert.Error(t, err, "topic with a level missing")
+	_, err = pattern.EventFromMessage("venues/acme/show-1/button-7/heart", []byte(`{"payload":`))
+	assert.Error(t, err)
+
+	bare, err := ParseTopicPattern("shows/{session}")
+	require.NoError(t, err)
+	_, err = bare.EventFromMessage("shows/show-1", []byte("heart"))
+	assert.Error(t, err, "no device or user to attribute the reaction to")
+	_, err = bare.EventFromMessage("shows/show-1", []byte(`{"user_id":"u1"}`))
+	assert.Error(t, err, "no reaction type")
+}
+
+// fakeBroker accepts one MQTT client, grants its subscriptions and publishes
+// the given messages at QoS 1, reporting the packet IDs it gets PUBACKs for
+func fakeBroker(t *testing.T, messages map[uint16][2]string) (string, <-chan []string, <-chan uint16) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	subscribed, acked := make(chan []string, 1), make(chan uint16, len(messages))
+	go func() {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer netConn.Close()
+		conn := &mqttConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+		if packet, err := conn.read(); err != nil || packet.kind != mqttConnect {
+			return
+		}
+		conn.write(mqttConnack<<4, []byte{0, 0})
+
+		packet, err := conn.read()
+		if err != nil || packet.kind != mqttSubscribe {
+			return
+		}
+		var filters []string
+		for rest := packet.body[2:]; len(rest) > 0; rest = rest[1:] {
+			var filter string
+			if filter, rest, err = readMQTTString(rest); err != nil {
+				return
+			}
+			filters = append(filters, filter)
+		}
+		subscribed <- filters
+		conn.write(mqttSuback<<4, append(packet.body[:2:2], 1))
+
+		for id, message := range messages {
+			body := appendMQTTString(nil, message[0])
+			body = binary.BigEndian.AppendUint16(body, id)
+			conn.write(mqttPublish<<4|0x02, append(body, message[1]...))
+		}
+		for {
+			packet, err := conn.read()
+			if err != nil {
+				return
+			}
+			switch packet.kind {
+			case mqttPuback:
+				acked <- binary.BigEndian.Uint16(packet.body)
+			case mqttPingreq:
+				conn.write(mqttPingresp<<4, nil)
+			}
+		}
+	}()
+	return "tcp://" + listener.Addr().String(), subscribed, acked
+}
+
+func TestMQTTSubscriber_AcknowledgesMessagesAfterProcessing(t *testing.T) {
+	brokerURL, subscribed, acked := fakeBroker(t, map[uint16][2]string{
+		1: {"venues/show-1/button-7", "heart"},
+		2: {"unrelated/topic", "heart"},
+	})
+	pattern, err := ParseTopicPattern("venues/{session}/{device}")
+	require.NoError(t, err)
+
+	processed := make(chan *events.Event, 2)
+	attempts := 0
+	handler := func(_ context.Context, event *events.Event) error {
+		attempts++
+		if attempts == 1 {
+			return context.DeadlineExceeded
+		}
+		processed <- event
+		return nil
+	}
+	subscriber := NewMQTTSubscriber(brokerURL, "livepulse-test", []TopicPattern{pattern}, 1, time.Second, handler)
+	subscriber.retryBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		subscriber.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	assert.Equal(t, []string{"venues/+/+"}, <-subscribed)
+
+	var ids []uint16
+	for range 2 {
+		select {
+		case id := <-acked:
+			ids = append(ids, id)
+		case <-time.After(2 * time.Second):
+			t.Fatal("messages were not acknowledged")
+		}
+	}
+	assert.ElementsMatch(t, []uint16{1, 2}, ids, "unmapped messages are dropped but acknowledged")
+
+	require.Len(t, processed, 1, "the failed attempt was retried")
+	event := <-processed
+	assert.Equal(t, "show-1", event.SessionID)
+	assert.Equal(t, "device:button-7", event.UserID)
+}
@@ -0,0 +1,56 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// StreamReader reads raw entries from Redis streams
+type StreamReader interface {
+	ReadStreams(ctx context.Context, offsets map[string]string, streams []string, count int64, block time.Duration) ([]storage.StreamEntry, error)
+}
+
+// RedisStreamSource reads JSON events published to Redis streams, using each
+// stream as a partition and entry IDs as offsets
+type RedisStreamSource struct {
+	reader  StreamReader
+	streams []string
+}
+
+// NewRedisStreamSource creates a source over the given stream keys
+func NewRedisStreamSource(reader StreamReader, streams []string) *RedisStreamSource {
+	return &RedisStreamSource{reader: reader, streams: streams}
+}
+
+// Read returns the next batch of records after the given offsets
+func (s *RedisStreamSource) Read(ctx context.Context, offsets map[string]string) ([]Record, error) {
+	entries, err := s.reader.ReadStreams(ctx, offsets, s.streams, 500, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(entries))
+	for _, entry := range entries {
+		record := Record{Partition: entry.Stream, Offset: entry.ID}
+		var event events.Event
+		if err := json.Unmarshal(entry.Payload, &event); err != nil {
+			// Keep the record so its offset still advances past the bad entry
+			log.Printf("Skipping malformed stream entry %s@%s: %v", entry.Stream, entry.ID, err)
+		} else {
+			// Stream producers are untrusted, never integrations
+			event.Source, event.External = "", false
+			// Upstream IDs are kept for idempotency, invalid ones are replaced
+			if event.SetExternalID(event.ID) != nil {
+				event.ID = events.NewEventID()
+			}
+			record.Event = &event
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
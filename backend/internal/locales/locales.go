@@ -0,0 +1,150 @@
+package locales
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is served when no requested language is available. Its
+// translations also fill in keys other locales are missing.
+const DefaultLocale = "en"
+
+// translationFiles holds one JSON object of message keys per locale
+//
+//go:embed translations/*.json
+var translationFiles embed.FS
+
+// Catalog holds a locale's display strings. Messages may contain {name}
+// placeholders filled in by Format.
+type Catalog struct {
+	Locale   string
+	Messages map[string]string
+}
+
+// catalogs maps lowercase locale tags to their catalogs
+var catalogs = loadCatalogs()
+
+// loadCatalogs parses the embedded translations. A malformed file panics on
+// startup, which the package tests catch before it ships.
+func loadCatalogs() map[string]*Catalog {
+	entries, err := translationFiles.ReadDir("translations")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]*Catalog, len(entries))
+	for _, entry := range entries {
+		data, err := translationFiles.ReadFile(path.Join("translations", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		catalog := &Catalog{Locale: strings.TrimSuffix(entry.Name(), ".json")}
+		if err := json.Unmarshal(data, &catalog.Messages); err != nil {
+			panic("locales: " + entry.Name() + ": " + err.Error())
+		}
+		loaded[strings.ToLower(catalog.Locale)] = catalog
+	}
+	for _, catalog := range loaded {
+		for key, message := range loaded[DefaultLocale].Messages {
+			if _, ok := catalog.Messages[key]; !ok {
+				catalog.Messages[key] = message
+			}
+		}
+	}
+	return loaded
+}
+
+// Available returns the supported locales
+func Available() []string {
+	names := make([]string, 0, len(catalogs))
+	for _, catalog := range catalogs {
+		names = append(names, catalog.Locale)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default returns the catalog of DefaultLocale
+func Default() *Catalog {
+	return catalogs[DefaultLocale]
+}
+
+// Lookup returns the catalog for a language tag such as "pt-BR", falling
+// back to its primary language ("pt")
+func Lookup(tag string) (*Catalog, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if catalog, ok := catalogs[tag]; ok {
+		return catalog, true
+	}
+	if primary, _, found := strings.Cut(tag, "-"); found {
+		catalog, ok := catalogs[primary]
+		return catalog, ok
+	}
+	return nil, false
+}
+
+// Negotiate picks the best available catalog for an Accept-Language header,
+// honouring quality values, and falls back to the default locale
+func Negotiate(acceptLanguage string) *Catalog {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			prefs = append(prefs, preference{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].quality > prefs[j].quality })
+
+	for _, pref := range prefs {
+		if pref.tag == "*" {
+			break
+		}
+		if catalog, ok := Lookup(pref.tag); ok {
+			return catalog
+		}
+	}
+	return Default()
+}
+
+// Format returns the message for key with each {name} placeholder replaced
+// by the value following name in args. Unknown keys are returned as is.
+func (c *Catalog) Format(key string, args ...string) string {
+	message, ok := c.Messages[key]
+	if !ok {
+		return key
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		message = strings.ReplaceAll(message, "{"+args[i]+"}", args[i+1])
+	}
+	return message
+}
+
+// Prefixed returns every message whose key starts with prefix, keyed by the
+// rest of the key, e.g. Prefixed("reaction.") maps "like" to its label
+func (c *Catalog) Prefixed(prefix string) map[string]string {
+	result := make(map[string]string)
+	for key, message := range c.Messages {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			result[name] = message
+		}
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package locales
+
+import (
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslations_CoverEveryDefaultKey(t *testing.T) {
+	entries, err := translationFiles.ReadDir("translations")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		data, err := translationFiles.ReadFile(path.Join("translations", entry.Name()))
+		require.NoError(t, err)
+		var messages map[string]string
+		require.NoError(t, json.Unmarshal(data, &messages), entry.Name())
+		for key := range Default().Messages {
+			assert.Contains(t, messages, key, "%s should translate %s", entry.Name(), key)
+		}
+	}
+}
+
+func TestNegotiate_PrefersHighestQualityAvailableLanguage(t *testing.T) {
+	assert.Equal(t, "fr", Negotiate("fr-CA,fr;q=0.9,en;q=0.8").Locale)
+	assert.Equal(t, "de", Negotiate("xx;q=1,de;q=0.7,es;q=0.5").Locale)
+	assert.Equal(t, "es", Negotiate("de;q=0.2, es-MX").Locale)
+	assert.Equal(t, "en", Negotiate("de;q=0, xx").Locale)
+	assert.Equal(t, "en", Negotiate("").Locale)
+	assert.Equal(t, "en", Negotiate("*").Locale)
+}
+
+func TestFormat_FillsPlaceholders(t *testing.T) {
+	catalog, ok := Lookup("es")
+	assert.True(t, ok)
+	assert.Equal(t, "1000 reacciones en 30 segundos", catalog.Format("milestone.reactions_in_window",
+		"threshold", "1000", "window", catalog.Format("window.seconds", "n", "30")))
+	assert.Equal(t, "missing.key", catalog.Format("missing.key"))
+}
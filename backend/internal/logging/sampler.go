@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Sampler limits a hot-path log line to a few occurrences per interval, so
+// overload does not turn into millions of identical lines. Occurrences beyond
+// the limit are counted and reported in one summary line when the interval
+// ends.
+type Sampler struct {
+	name     string
+	limit    int
+	interval time.Duration
+
+	windowStart time.Time
+	logged      int
+	suppressed  int64
+	mu          sync.Mutex
+}
+
+// NewSampler creates a sampler logging at most limit lines per interval.
+// The name identifies the suppressed line in summaries.
+func NewSampler(name string, limit int, interval time.Duration) *Sampler {
+	return &Sampler{name: name, limit: limit, interval: interval}
+}
+
+// Printf logs like log.Printf unless the interval's limit is used up
+func (s *Sampler) Printf(format string, args ...interface{}) {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.interval {
+		s.windowStart = now
+		s.logged = 0
+	}
+	if s.logged < s.limit {
+		s.logged++
+		s.mu.Unlock()
+		log.Printf(format, args...)
+		return
+	}
+
+	s.suppressed++
+	if s.suppressed == 1 {
+		time.AfterFunc(s.windowStart.Add(s.interval).Sub(now), s.flush)
+	}
+	s.mu.Unlock()
+}
+
+// flush reports the occurrences suppressed since the last summary
+func (s *Sampler) flush() {
+	s.mu.Lock()
+	suppressed := s.suppressed
+	s.suppressed = 0
+	s.mu.Unlock()
+
+	if suppressed > 0 {
+		log.Printf("%s: suppressed %d more occurrences in the last %s", s.name, suppressed, s.interval)
+	}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer collects log output written from several goroutines
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSampler_LimitsLinesAndSummarizes(t *testing.T) {
+	var out syncBuffer
+	original := log.Writer()
+	log.SetOutput(&out)
+	defer log.SetOutput(original)
+
+	sampler := NewSampler("queue full", 3, 50*time.Millisecond)
+	for i := 0; i < 100; i++ {
+		sampler.Printf("dropping event %d", i)
+	}
+	assert.Equal(t, 3, strings.Count(out.String(), "dropping event"))
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "queue full: suppressed 97 more occurrences")
+	}, time.Second, 5*time.Millisecond)
+
+	// A new interval logs again
+	sampler.Printf("dropping event late")
+	assert.Contains(t, out.String(), "dropping event late")
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// cloudWatchBatch bounds the metric data sent in one PutMetricData call
+const cloudWatchBatch = 1000
+
+// CloudWatchExporter publishes samples as CloudWatch custom metrics,
+// dimensioned by SessionId (and ReactionType for per-type counts)
+type CloudWatchExporter struct {
+	endpoint  string
+	namespace string
+	creds     AWSCredentials
+	client    *http.Client
+}
+
+// NewCloudWatchExporter creates an exporter publishing to namespace
+func NewCloudWatchExporter(creds AWSCredentials, namespace string) *CloudWatchExporter {
+	return &CloudWatchExporter{
+		endpoint:  "https://monitoring." + creds.Region + ".amazonaws.com/",
+		namespace: namespace,
+		creds:     creds,
+		client:    &http.Client{},
+	}
+}
+
+// Name identifies the exporter in logs
+func (e *CloudWatchExporter) Name() string {
+	return "cloudwatch"
+}
+
+// cloudWatchDatum is one metric value
+type cloudWatchDatum struct {
+	name       string
+	value      int64
+	unit       string
+	dimensions [][2]string
+	at         time.Time
+}
+
+// Export publishes every sample's metrics in as few calls as possible
+func (e *CloudWatchExporter) Export(ctx context.Context, samples []Sample) error {
+	var data []cloudWatchDatum
+	for _, s := range samples {
+		session := [2]string{"SessionId", s.SessionID}
+		data = append(data,
+			cloudWatchDatum{"ActiveUsers", int64(s.ActiveUsers), "Count", [][2]string{session}, s.Time},
+			cloudWatchDatum{"TotalReactions", s.TotalReactions, "Count", [][2]string{session}, s.Time},
+			cloudWatchDatum{"ReactionsPerMinute", s.ReactionsPerMinute, "Count", [][2]string{session}, s.Time},
+		)
+		for _, reactionType := range s.sortedReactionTypes() {
+			dims := [][2]string{session, {"ReactionType", string(reactionType)}}
+			data = append(data, cloudWatchDatum{"Reactions", s.ReactionCounts[reactionType], "Count", dims, s.Time})
+		}
+	}
+
+	for start := 0; start < len(data); start += cloudWatchBatch {
+		if err := e.put(ctx, data[start:min(start+cloudWatchBatch, len(data))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put sends one PutMetricData call using the query protocol
+func (e *CloudWatchExporter) put(ctx context.Context, data []cloudWatchDatum) error {
+	form := url.Values{
+		"Action":    {"PutMetricData"},
+		"Version":   {"2010-08-01"},
+		"Namespace": {e.namespace},
+	}
+	for i, d := range data {
+		prefix := "MetricData.member." + strconv.Itoa(i+1) + "."
+		form.Set(prefix+"MetricName", d.name)
+		form.Set(prefix+"Value", strconv.FormatInt(d.value, 10))
+		form.Set(prefix+"Unit", d.unit)
+		form.Set(prefix+"Timestamp", d.at.UTC().Format(time.RFC3339))
+		for j, dim := range d.dimensions {
+			dimPrefix := prefix + "Dimensions.member." + strconv.Itoa(j+1) + "."
+			form.Set(dimPrefix+"Name", dim[0])
+			form.Set(dimPrefix+"Value", dim[1])
+		}
+	}
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	e.creds.Sign(req, body, "monitoring", time.Now())
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// influxMeasurement is the measurement every sample is written to
+const influxMeasurement = "livepulse_session"
+
+// InfluxExporter writes samples to an InfluxDB v2 bucket using the line
+// protocol write API
+type InfluxExporter struct {
+	writeURL string
+	token    string
+	client   *http.Client
+}
+
+// NewInfluxExporter creates an exporter for the given server, org and bucket
+func NewInfluxExporter(serverURL, token, org, bucket string) *InfluxExporter {
+	query := url.Values{"org": {org}, "bucket": {bucket}, "precision": {"s"}}
+	return &InfluxExporter{
+		writeURL: strings.TrimSuffix(serverURL, "/") + "/api/v2/write?" + query.Encode(),
+		token:    token,
+		client:   &http.Client{},
+	}
+}
+
+// Name identifies the exporter in logs
+func (e *InfluxExporter) Name() string {
+	return "influxdb"
+}
+
+// Export writes one line per sample
+func (e *InfluxExporter) Export(ctx context.Context, samples []Sample) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.writeURL, bytes.NewReader(influxLines(samples)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Token "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// influxTagEscaper escapes the characters the line protocol reserves in tags
+var influxTagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// influxLines encodes samples in the line protocol, e.g.
+// livepulse_session,session_id=s1 active_users=3i,total_reactions=10i,... 1700000000
+func influxLines(samples []Sample) []byte {
+	var b bytes.Buffer
+	for _, s := range samples {
+		b.WriteString(influxMeasurement)
+		b.WriteString(",session_id=")
+		b.WriteString(influxTagEscaper.Replace(s.SessionID))
+		fmt.Fprintf(&b, " active_users=%di,total_reactions=%di,reactions_per_minute=%di", s.ActiveUsers, s.TotalReactions, s.ReactionsPerMinute)
+		for _, reactionType := range s.sortedReactionTypes() {
+			fmt.Fprintf(&b, ",reactions_%s=%di", reactionType, s.ReactionCounts[reactionType])
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(s.Time.Unix(), 10))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Sample is one session's metrics at a point in time
+type Sample struct {
+	SessionID          string
+	Time               time.Time
+	ActiveUsers        int
+	TotalReactions     int64
+	ReactionsPerMinute int64
+	ReactionCounts     map[events.ReactionType]int64
+}
+
+// Exporter writes samples to an external time-series store
+type Exporter interface {
+	Name() string
+	Export(ctx context.Context, samples []Sample) error
+}
+
+// Collect samples every session aggregated by this instance, ordered by
+// session ID
+func Collect(manager *aggregation.Manager, now time.Time) []Sample {
+	snapshots := manager.GetAllSessions()
+	samples := make([]Sample, 0, len(snapshots))
+	for sessionID, snapshot := range snapshots {
+		sample := Sample{
+			SessionID:      sessionID,
+			Time:           now,
+			ActiveUsers:    snapshot.ActiveUserCount,
+			TotalReactions: snapshot.TotalReactions,
+			ReactionCounts: snapshot.ReactionCounts,
+		}
+		if stats, ok := manager.GetSession(sessionID); ok {
+			sample.ReactionsPerMinute = stats.GetReactionVelocity(time.Minute)
+		}
+		samples = append(samples, sample)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].SessionID < samples[j].SessionID })
+	return samples
+}
+
+// Pusher periodically exports session metrics
+type Pusher struct {
+	manager   *aggregation.Manager
+	exporters []Exporter
+}
+
+// NewPusher creates a pusher sending the manager's sessions to exporters
+func NewPusher(manager *aggregation.Manager, exporters []Exporter) *Pusher {
+	return &Pusher{manager: manager, exporters: exporters}
+}
+
+// Start exports every interval until ctx is cancelled. An export that
+// fails is logged and not retried; the next one carries current values.
+func (p *Pusher) Start(ctx context.Context, interval time.Duration) {
+	if len(p.exporters) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Push(ctx, interval)
+			}
+		}
+	}()
+}
+
+// Push exports the current metrics once, giving each exporter at most
+// timeout
+func (p *Pusher) Push(ctx context.Context, timeout time.Duration) {
+	samples := Collect(p.manager, time.Now().UTC())
+	if len(samples) == 0 {
+		return
+	}
+	for _, exporter := range p.exporters {
+		exportCtx, cancel := context.WithTimeout(ctx, timeout)
+		if err := exporter.Export(exportCtx, samples); err != nil {
+			log.Printf("Error exporting metrics to %s: %v", exporter.Name(), err)
+		}
+		cancel()
+	}
+}
+
+// sortedReactionTypes returns the reaction types of a sample in a stable order
+func (s Sample) sortedReactionTypes() []events.ReactionType {
+	types := make([]events.ReactionType, 0, len(s.ReactionCounts))
+	for reactionType := range s.ReactionCounts {
+		types = append(types, reactionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
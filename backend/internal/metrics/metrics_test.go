@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxExporter_WritesLineProtocol(t *testing.T) {
+	manager := aggregation.NewManager()
+	manager.ProcessEvent(events.JoinSessionEvent("show 1", "u1"))
+	manager.ProcessEvent(events.ReactionEvent("show 1", "u1", events.ReactionFire))
+	manager.ProcessEvent(events.ReactionEvent("show 1", "u1", events.ReactionFire))
+	manager.ProcessEvent(events.ReactionEvent("show 1", "u1", events.ReactionLike))
+	samples := Collect(manager, time.Unix(1700000000, 0))
+	require.Len(t, samples, 1)
+
+	var body, auth, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, auth, query = string(data), r.Header.Get("Authorization"), r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exporter := NewInfluxExporter(server.URL, "secret", "acme", "live")
+	require.NoError(t, exporter.Export(context.Background(), samples))
+	assert.Equal(t, "Token secret", auth)
+	assert.Equal(t, "bucket=live&org=acme&precision=s", query)
+	assert.Equal(t, `livepulse_session,session_id=show\ 1 active_users=1i,total_reactions=3i,reactions_per_minute=3i,`+
+		"reactions_applause=0i,reactions_cheer=0i,reactions_fire=2i,reactions_heart=0i,reactions_like=1i,reactions_love=0i 1700000000\n", body)
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timestreamBatch is the most records one WriteRecords call accepts
+const timestreamBatch = 100
+
+// TimestreamExporter writes samples to an Amazon Timestream table as
+// multi-measure records dimensioned by session_id
+type TimestreamExporter struct {
+	database string
+	table    string
+	creds    AWSCredentials
+	client   *http.Client
+
+	// Timestream requires writes to go to a discovered cell endpoint
+	mu              sync.Mutex
+	endpoint        string
+	endpointExpires time.Time
+}
+
+// NewTimestreamExporter creates an exporter writing to database.table
+func NewTimestreamExporter(creds AWSCredentials, database, table string) *TimestreamExporter {
+	return &TimestreamExporter{database: database, table: table, creds: creds, client: &http.Client{}}
+}
+
+// Name identifies the exporter in logs
+func (e *TimestreamExporter) Name() string {
+	return "timestream"
+}
+
+// timestreamMeasure is one value of a multi-measure record
+type timestreamMeasure struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+	Type  string `json:"Type"`
+}
+
+// timestreamRecord is one session's sample
+type timestreamRecord struct {
+	Dimensions       []map[string]string `json:"Dimensions"`
+	MeasureName      string              `json:"MeasureName"`
+	MeasureValueType string              `json:"MeasureValueType"`
+	MeasureValues    []timestreamMeasure `json:"MeasureValues"`
+	Time             string              `json:"Time"`
+	TimeUnit         string              `json:"TimeUnit"`
+}
+
+// Export writes one record per sample
+func (e *TimestreamExporter) Export(ctx context.Context, samples []Sample) error {
+	records := make([]timestreamRecord, 0, len(samples))
+	for _, s := range samples {
+		bigint := func(name string, value int64) timestreamMeasure {
+			return timestreamMeasure{Name: name, Value: strconv.FormatInt(value, 10), Type: "BIGINT"}
+		}
+		measures := []timestreamMeasure{
+			bigint("active_users", int64(s.ActiveUsers)),
+			bigint("total_reactions", s.TotalReactions),
+			bigint("reactions_per_minute", s.ReactionsPerMinute),
+		}
+		for _, reactionType := range s.sortedReactionTypes() {
+			measures = append(measures, bigint("reactions_"+string(reactionType), s.ReactionCounts[reactionType]))
+		}
+		records = append(records, timestreamRecord{
+			Dimensions:       []map[string]string{{"Name": "session_id", "Value": s.SessionID}},
+			MeasureName:      "session",
+			MeasureValueType: "MULTI",
+			MeasureValues:    measures,
+			Time:             strconv.FormatInt(s.Time.UnixMilli(), 10),
+			TimeUnit:         "MILLISECONDS",
+		})
+	}
+
+	endpoint, err := e.discoverEndpoint(ctx)
+	if err != nil {
+		return fmt.Errorf("discovering endpoint: %w", err)
+	}
+	for start := 0; start < len(records); start += timestreamBatch {
+		request := map[string]interface{}{
+			"DatabaseName": e.database,
+			"TableName":    e.table,
+			"Records":      records[start:min(start+timestreamBatch, len(records))],
+		}
+		if err := e.call(ctx, endpoint, "WriteRecords", request, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discoverEndpoint returns the cached ingest endpoint, refreshing it once
+// its cache period ends
+func (e *TimestreamExporter) discoverEndpoint(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.endpoint != "" && time.Now().Before(e.endpointExpires) {
+		return e.endpoint, nil
+	}
+
+	var resp struct {
+		Endpoints []struct {
+			Address              string `json:"Address"`
+			CachePeriodInMinutes int64  `json:"CachePeriodInMinutes"`
+		} `json:"Endpoints"`
+	}
+	discovery := "ingest.timestream." + e.creds.Region + ".amazonaws.com"
+	if err := e.call(ctx, discovery, "DescribeEndpoints", map[string]interface{}{}, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Endpoints) == 0 {
+		return "", fmt.Errorf("no endpoints returned")
+	}
+	e.endpoint = resp.Endpoints[0].Address
+	e.endpointExpires = time.Now().Add(time.Duration(resp.Endpoints[0].CachePeriodInMinutes) * time.Minute)
+	return e.endpoint, nil
+}
+
+// call invokes a Timestream Write API operation using the JSON protocol
+func (e *TimestreamExporter) call(ctx context.Context, host, operation string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "Timestream_20181101."+operation)
+	e.creds.Sign(req, body, "timestream", time.Now())
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", operation, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
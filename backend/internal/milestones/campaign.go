@@ -0,0 +1,216 @@
+package milestones
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Campaign groups sessions whose stats count towards shared milestones,
+// e.g. every talk of a conference week
+type Campaign struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	SessionIDs []string     `json:"session_ids"`
+	Milestones []*Milestone `json:"milestones"` // SessionID holds the campaign ID
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// CampaignAchievement represents a campaign milestone that was just achieved
+type CampaignAchievement struct {
+	Campaign     *Campaign  `json:"campaign"`
+	Milestone    *Milestone `json:"milestone"`
+	AchievedAt   time.Time  `json:"achieved_at"`
+	CurrentValue int64      `json:"current_value"`
+}
+
+// CampaignNotificationHandler is called when a campaign milestone is achieved
+type CampaignNotificationHandler func(*CampaignAchievement)
+
+// sessionTotals is the last observed contribution of one session
+type sessionTotals struct {
+	reactionCounts map[events.ReactionType]int64
+	totalReactions int64
+	activeUsers    int64
+}
+
+// campaignState holds a campaign and the latest totals of its sessions.
+// Totals are kept after a session ends so its contribution is not lost.
+type campaignState struct {
+	campaign *Campaign
+	totals   map[string]sessionTotals
+}
+
+// CampaignTracker aggregates per-session stats into campaign milestones
+type CampaignTracker struct {
+	campaigns  map[string]*campaignState
+	bySession  map[string][]string // sessionID -> campaign IDs
+	mu         sync.RWMutex
+	notifyFunc CampaignNotificationHandler
+}
+
+// NewCampaignTracker creates a new campaign tracker
+func NewCampaignTracker(notifyFunc CampaignNotificationHandler) *CampaignTracker {
+	return &CampaignTracker{
+		campaigns:  make(map[string]*campaignState),
+		bySession:  make(map[string][]string),
+		notifyFunc: notifyFunc,
+	}
+}
+
+// CreateCampaign registers a campaign with its sessions and milestones
+func (t *CampaignTracker) CreateCampaign(id, name string, sessionIDs []string, definitions []Definition) (*Campaign, error) {
+	for _, definition := range definitions {
+		if err := definition.Validate(); err != nil {
+			return nil, err
+		}
+		if definition.Type != MilestoneTypeTotalReactions && definition.Type != MilestoneTypeConcurrentUsers {
+			return nil, fmt.Errorf("%s milestones do not apply to campaigns", definition.Type)
+		}
+		if len(definition.Requires) > 0 {
+			return nil, fmt.Errorf("campaign milestones cannot require others")
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.campaigns[id]; exists {
+		return nil, fmt.Errorf("campaign %q already exists", id)
+	}
+
+	campaign := &Campaign{ID: id, Name: name, CreatedAt: time.Now().UTC()}
+	for _, definition := range definitions {
+		campaign.Milestones = append(campaign.Milestones, definition.Build(id))
+	}
+	t.campaigns[id] = &campaignState{campaign: campaign, totals: make(map[string]sessionTotals)}
+	for _, sessionID := range sessionIDs {
+		t.addSessionLocked(id, sessionID)
+	}
+	return campaign, nil
+}
+
+// AddSession adds a session to an existing campaign
+func (t *CampaignTracker) AddSession(campaignID, sessionID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.campaigns[campaignID]; !exists {
+		return fmt.Errorf("campaign %q not found", campaignID)
+	}
+	t.addSessionLocked(campaignID, sessionID)
+	return nil
+}
+
+// addSessionLocked links a session to a campaign. Callers must hold t.mu.
+func (t *CampaignTracker) addSessionLocked(campaignID, sessionID string) {
+	state := t.campaigns[campaignID]
+	for _, existing := range state.campaign.SessionIDs {
+		if existing == sessionID {
+			return
+		}
+	}
+	state.campaign.SessionIDs = append(state.campaign.SessionIDs, sessionID)
+	t.bySession[sessionID] = append(t.bySession[sessionID], campaignID)
+}
+
+// Observe records a session's current stats and checks the milestones of
+// every campaign the session belongs to
+func (t *CampaignTracker) Observe(sessionID string, stats *aggregation.SessionStats) {
+	t.mu.RLock()
+	_, member := t.bySession[sessionID]
+	t.mu.RUnlock()
+	if !member {
+		return
+	}
+
+	totals := sessionTotals{
+		reactionCounts: stats.GetAllReactionCounts(),
+		totalReactions: stats.GetTotalReactions(),
+		activeUsers:    int64(stats.GetActiveUserCount()),
+	}
+
+	var achievements []*CampaignAchievement
+
+	t.mu.Lock()
+	for _, campaignID := range t.bySession[sessionID] {
+		state := t.campaigns[campaignID]
+		state.totals[sessionID] = totals
+		achievements = append(achievements, state.check()...)
+	}
+	t.mu.Unlock()
+
+	for _, achievement := range achievements {
+		log.Printf("Campaign milestone achieved! Campaign: %s, Type: %s, Threshold: %d, Current: %d",
+			achievement.Campaign.ID, achievement.Milestone.Type, achievement.Milestone.Threshold, achievement.CurrentValue)
+		if t.notifyFunc != nil {
+			go t.notifyFunc(achievement)
+		}
+	}
+}
+
+// check updates campaign milestone progress from the summed session totals
+func (c *campaignState) check() []*CampaignAchievement {
+	counts := make(map[events.ReactionType]int64)
+	var totalReactions, activeUsers int64
+	for _, totals := range c.totals {
+		for reactionType, count := range totals.reactionCounts {
+			counts[reactionType] += count
+		}
+		totalReactions += totals.totalReactions
+		activeUsers += totals.activeUsers
+	}
+
+	var achievements []*CampaignAchievement
+	for _, milestone := range c.campaign.Milestones {
+		var currentValue int64
+		switch milestone.Type {
+		case MilestoneTypeTotalReactions:
+			currentValue = milestone.ReactionValue(counts, totalReactions)
+		case MilestoneTypeConcurrentUsers:
+			currentValue = activeUsers
+		}
+
+		if milestone.UpdateProgress(currentValue) {
+			achievements = append(achievements, &CampaignAchievement{
+				Campaign:     c.campaign,
+				Milestone:    milestone,
+				AchievedAt:   time.Now().UTC(),
+				CurrentValue: currentValue,
+			})
+		}
+	}
+	return achievements
+}
+
+// GetCampaign returns a campaign with its current milestone progress
+func (t *CampaignTracker) GetCampaign(campaignID string) (*Campaign, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	state, exists := t.campaigns[campaignID]
+	if !exists {
+		return nil, false
+	}
+
+	// Return a copy to avoid race conditions
+	campaign := *state.campaign
+	campaign.SessionIDs = append([]string(nil), state.campaign.SessionIDs...)
+	campaign.Milestones = make([]*Milestone, len(state.campaign.Milestones))
+	for i, milestone := range state.campaign.Milestones {
+		m := *milestone
+		campaign.Milestones[i] = &m
+	}
+	return &campaign, true
+}
+
+// CampaignsForSession returns the IDs of campaigns a session belongs to
+func (t *CampaignTracker) CampaignsForSession(sessionID string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]string(nil), t.bySession[sessionID]...)
+}
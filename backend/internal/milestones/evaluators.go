@@ -0,0 +1,99 @@
+package milestones
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+)
+
+// MilestoneEvaluator computes a milestone's current value from a session's
+// statistics; the milestone is achieved once the value reaches its threshold.
+// The milestone is passed so evaluators can read their own parameters, as
+// reaction weights and velocity windows are read by the built-in types.
+type MilestoneEvaluator interface {
+	Evaluate(milestone *Milestone, stats *aggregation.SessionStats) int64
+}
+
+// EvaluatorFunc adapts a function to MilestoneEvaluator
+type EvaluatorFunc func(milestone *Milestone, stats *aggregation.SessionStats) int64
+
+// Evaluate calls f
+func (f EvaluatorFunc) Evaluate(milestone *Milestone, stats *aggregation.SessionStats) int64 {
+	return f(milestone, stats)
+}
+
+// customEvaluator is a milestone type registered by the deployment
+type customEvaluator struct {
+	evaluator MilestoneEvaluator
+	unit      string // describes the threshold, e.g. "minutes average watch time"
+}
+
+var (
+	builtinEvaluators = map[MilestoneType]MilestoneEvaluator{
+		MilestoneTypeTotalReactions: EvaluatorFunc(func(m *Milestone, stats *aggregation.SessionStats) int64 {
+			counts, total := stats.GetAllReactionCounts(), stats.GetTotalReactions()
+			if system := stats.GetSystemReactions(); m.countSystem && system != nil {
+				for reactionType, count := range system.ReactionCounts {
+					counts[reactionType] += count
+				}
+				total += system.Total
+			}
+			return m.ReactionValue(counts, total)
+		}),
+		MilestoneTypeConcurrentUsers: EvaluatorFunc(func(_ *Milestone, stats *aggregation.SessionStats) int64 {
+			return int64(stats.GetActiveUserCount())
+		}),
+		MilestoneTypeSessionDuration: EvaluatorFunc(func(_ *Milestone, stats *aggregation.SessionStats) int64 {
+			return int64(time.Since(stats.StartTime).Minutes())
+		}),
+		MilestoneTypeReactionVelocity: EvaluatorFunc(func(m *Milestone, stats *aggregation.SessionStats) int64 {
+			return stats.GetReactionVelocity(m.Window())
+		}),
+	}
+
+	customEvaluators   = make(map[MilestoneType]customEvaluator)
+	customEvaluatorsMu sync.RWMutex
+)
+
+// RegisterEvaluator adds a custom milestone type that sessions can then be
+// created with. unit describes the threshold in generated descriptions, e.g.
+// "minutes average watch time". Built-in types cannot be replaced.
+func RegisterEvaluator(milestoneType MilestoneType, unit string, evaluator MilestoneEvaluator) error {
+	if _, builtin := builtinEvaluators[milestoneType]; builtin {
+		return fmt.Errorf("milestone type %q is built in", milestoneType)
+	}
+	if milestoneType == "" || evaluator == nil {
+		return fmt.Errorf("custom milestone types need a name and an evaluator")
+	}
+
+	customEvaluatorsMu.Lock()
+	defer customEvaluatorsMu.Unlock()
+	customEvaluators[milestoneType] = customEvaluator{evaluator: evaluator, unit: unit}
+	return nil
+}
+
+// lookupEvaluator returns the evaluator of a milestone type
+func lookupEvaluator(milestoneType MilestoneType) (MilestoneEvaluator, bool) {
+	if evaluator, builtin := builtinEvaluators[milestoneType]; builtin {
+		return evaluator, true
+	}
+	custom, ok := lookupCustom(milestoneType)
+	return custom.evaluator, ok
+}
+
+// lookupCustom returns a registered custom milestone type
+func lookupCustom(milestoneType MilestoneType) (customEvaluator, bool) {
+	customEvaluatorsMu.RLock()
+	defer customEvaluatorsMu.RUnlock()
+	custom, ok := customEvaluators[milestoneType]
+	return custom, ok
+}
+
+// AverageWatchMinutes evaluates the average time, in whole minutes, that the
+// session's active users have been watching. Deployments that want
+// "average watch time" milestones register it under a type of their choice.
+var AverageWatchMinutes = EvaluatorFunc(func(_ *Milestone, stats *aggregation.SessionStats) int64 {
+	return int64(stats.GetAverageWatchTime().Minutes())
+})
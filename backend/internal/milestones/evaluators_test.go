@@ -0,0 +1,59 @@
+package milestones
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterEvaluator_RejectsBuiltinAndIncompleteTypes(t *testing.T) {
+	constant := EvaluatorFunc(func(*Milestone, *aggregation.SessionStats) int64 { return 1 })
+
+	assert.Error(t, RegisterEvaluator(MilestoneTypeTotalReactions, "reactions", constant))
+	assert.Error(t, RegisterEvaluator("", "things", constant))
+	assert.Error(t, RegisterEvaluator("test_incomplete", "things", nil))
+
+	assert.Error(t, Definition{Type: "test_unregistered", Threshold: 1}.Validate())
+}
+
+func TestTracker_DispatchesCustomTypesToTheirEvaluator(t *testing.T) {
+	var evaluated *Milestone
+	require.NoError(t, RegisterEvaluator("test_backstage_passes", "backstage passes", EvaluatorFunc(
+		func(m *Milestone, _ *aggregation.SessionStats) int64 {
+			evaluated = m
+			return 7
+		})))
+
+	definition := Definition{Type: "test_backstage_passes", Threshold: 5}
+	require.NoError(t, definition.Validate())
+	assert.Error(t, Definition{Type: "test_backstage_passes", Threshold: 5, WindowSeconds: 60}.Validate())
+
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", nil)
+	tracker.AddMilestones("s1", []Definition{definition})
+	tracker.CheckMilestones("s1", aggregation.NewSessionStats("s1"))
+
+	achieved := tracker.GetAchievedMilestones("s1")
+	require.Len(t, achieved, 1)
+	assert.Equal(t, "5 backstage passes", achieved[0].Description)
+	assert.Equal(t, int64(7), achieved[0].Progress)
+	require.NotNil(t, evaluated)
+	assert.Equal(t, achieved[0].ID, evaluated.ID, "the evaluator is passed its milestone")
+}
+
+func TestAverageWatchMinutes(t *testing.T) {
+	stats := aggregation.NewSessionStats("s1")
+	assert.Equal(t, int64(0), AverageWatchMinutes.Evaluate(nil, stats), "no one is watching")
+
+	stats.AddUser("u1")
+	stats.AddUser("u2")
+	stats.JoinTimes["u1"] = time.Now().Add(-10 * time.Minute)
+	stats.JoinTimes["u2"] = time.Now().Add(-20 * time.Minute)
+	assert.Equal(t, int64(15), AverageWatchMinutes.Evaluate(nil, stats))
+
+	stats.RemoveUser("u2")
+	assert.Equal(t, int64(10), AverageWatchMinutes.Evaluate(nil, stats), "only active users count")
+}
@@ -0,0 +1,87 @@
+package milestones
+
+import (
+	"math"
+	"time"
+)
+
+// Progress rates are sampled at most once per interval and smoothed with an
+// exponentially weighted moving average, so a burst of reactions moves the
+// prediction without taking it over
+const (
+	forecastSampleInterval = 5 * time.Second
+	forecastSmoothing      = 0.3
+)
+
+// rateEstimate tracks the smoothed rate at which a milestone progresses
+type rateEstimate struct {
+	perSecond float64
+	samples   int
+	value     int64
+	sampledAt time.Time
+}
+
+// observe records the milestone's value at now
+func (e *rateEstimate) observe(value int64, now time.Time) {
+	if e.sampledAt.IsZero() {
+		e.value, e.sampledAt = value, now
+		return
+	}
+	elapsed := now.Sub(e.sampledAt)
+	if elapsed < forecastSampleInterval {
+		return
+	}
+	instant := float64(value-e.value) / elapsed.Seconds()
+	if e.samples == 0 {
+		e.perSecond = instant
+	} else {
+		e.perSecond = forecastSmoothing*instant + (1-forecastSmoothing)*e.perSecond
+	}
+	e.samples++
+	e.value, e.sampledAt = value, now
+}
+
+// at returns the rate as of now. Milestones are only checked as events
+// arrive, so each interval since the last sample counts as one without
+// progress.
+func (e rateEstimate) at(now time.Time) float64 {
+	if e.samples == 0 {
+		return 0
+	}
+	idle := math.Floor(now.Sub(e.sampledAt).Seconds() / forecastSampleInterval.Seconds())
+	return e.perSecond * math.Pow(1-forecastSmoothing, idle)
+}
+
+// Forecast predicts when a milestone will be achieved at its current rate
+// of progress. ExpectedAt is unset while progress is flat or falling.
+type Forecast struct {
+	RatePerMinute float64    `json:"rate_per_minute"`
+	ETASeconds    float64    `json:"eta_seconds,omitempty"`
+	ExpectedAt    *time.Time `json:"expected_at,omitempty"`
+
+	// OnTrack reports whether the milestone is expected before the deadline
+	// it was forecast against, if one was given
+	OnTrack *bool `json:"on_track,omitempty"`
+}
+
+// Forecast predicts when the milestone will be achieved, checking the
+// prediction against deadline unless it is zero. Achieved milestones have
+// no forecast.
+func (m *Milestone) Forecast(now, deadline time.Time) *Forecast {
+	if m.Achieved {
+		return nil
+	}
+	rate := m.rate.at(now)
+	forecast := &Forecast{RatePerMinute: math.Round(rate*60*10) / 10}
+	if rate > 0 {
+		eta := float64(m.Threshold-m.Progress) / rate
+		expectedAt := now.Add(time.Duration(eta * float64(time.Second))).UTC()
+		forecast.ETASeconds = math.Round(eta)
+		forecast.ExpectedAt = &expectedAt
+	}
+	if !deadline.IsZero() {
+		onTrack := forecast.ExpectedAt != nil && !forecast.ExpectedAt.After(deadline)
+		forecast.OnTrack = &onTrack
+	}
+	return forecast
+}
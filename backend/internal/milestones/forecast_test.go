@@ -0,0 +1,69 @@
+package milestones
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMilestone_ForecastsFromTheSmoothedRate(t *testing.T) {
+	start := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	milestone := NewMilestone("s1", MilestoneTypeTotalReactions, 10000)
+
+	assert.Nil(t, milestone.Forecast(start, time.Time{}).ExpectedAt, "no rate before the first interval")
+
+	// 10 reactions a second for two intervals
+	milestone.rate.observe(0, start)
+	milestone.rate.observe(50, start.Add(forecastSampleInterval))
+	milestone.rate.observe(100, start.Add(2*forecastSampleInterval))
+	milestone.Progress = 100
+	now := start.Add(2 * forecastSampleInterval)
+
+	forecast := milestone.Forecast(now, time.Time{})
+	assert.Equal(t, 600.0, forecast.RatePerMinute)
+	assert.Equal(t, 990.0, forecast.ETASeconds)
+	require.NotNil(t, forecast.ExpectedAt)
+	assert.Equal(t, now.Add(990*time.Second), *forecast.ExpectedAt)
+	assert.Nil(t, forecast.OnTrack)
+
+	onTrack := milestone.Forecast(now, now.Add(time.Hour))
+	require.NotNil(t, onTrack.OnTrack)
+	assert.True(t, *onTrack.OnTrack)
+	late := milestone.Forecast(now, now.Add(10*time.Minute))
+	assert.False(t, *late.OnTrack)
+
+	// A burst moves the rate without taking it over
+	milestone.rate.observe(600, now.Add(forecastSampleInterval))
+	assert.InDelta(t, 0.3*100+0.7*10, milestone.rate.at(now.Add(forecastSampleInterval)), 0.001)
+}
+
+func TestMilestone_ForecastDecaysWhileTheSessionIsQuiet(t *testing.T) {
+	start := time.Now()
+	milestone := NewMilestone("s1", MilestoneTypeTotalReactions, 1000)
+	milestone.rate.observe(0, start)
+	milestone.rate.observe(50, start.Add(forecastSampleInterval))
+
+	sampled := start.Add(forecastSampleInterval)
+	assert.InDelta(t, 10, milestone.rate.at(sampled), 0.001)
+	assert.InDelta(t, 10*0.7*0.7, milestone.rate.at(sampled.Add(2*forecastSampleInterval)), 0.001)
+
+	falling := NewMilestone("s1", MilestoneTypeConcurrentUsers, 1000)
+	falling.rate.observe(500, start)
+	falling.rate.observe(400, start.Add(forecastSampleInterval))
+	forecast := falling.Forecast(sampled, sampled.Add(time.Hour))
+	assert.Nil(t, forecast.ExpectedAt, "shrinking audiences never get there")
+	assert.False(t, *forecast.OnTrack)
+}
+
+func TestTracker_AchievedMilestonesHaveNoForecast(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", []int{10, 1000})
+	tracker.CheckMilestones("s1", reactions("s1", 20))
+
+	milestones := tracker.GetSessionMilestones("s1")
+	require.Len(t, milestones, 2)
+	assert.Nil(t, milestones[0].Forecast(time.Now(), time.Time{}))
+	assert.NotNil(t, milestones[1].Forecast(time.Now(), time.Time{}))
+}
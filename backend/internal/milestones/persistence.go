@@ -0,0 +1,366 @@
+package milestones
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// announceTimeout bounds persisting achievements before they are announced
+const announceTimeout = 5 * time.Second
+
+// ErrVersionConflict is returned when a session's milestones kept being
+// written by another writer for every attempt
+var ErrVersionConflict = errors.New("milestones were written concurrently")
+
+// Store persists each session's milestones with a version bumped by every
+// write. A write names the version it was based on and is refused if
+// another instance or goroutine wrote first, so nothing is overwritten.
+type Store interface {
+	// SaveMilestoneState writes a session's milestones if their stored
+	// version is still version, 0 for a session never written, returning
+	// the new version, or false if the version moved on
+	SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error)
+	// LoadMilestoneStates returns the given sessions' milestones and
+	// versions, or every session's when none are given
+	LoadMilestoneStates(ctx context.Context, sessionIDs ...string) (map[string]storage.MilestoneState, error)
+	// DeleteMilestoneStates deletes the milestones of removed sessions
+	DeleteMilestoneStates(ctx context.Context, sessionIDs []string) error
+}
+
+// PersistStats counts milestone writes and the version conflicts they ran
+// into
+type PersistStats struct {
+	Writes     int64 `json:"writes"`
+	Conflicts  int64 `json:"conflicts"`
+	Retries    int64 `json:"retries"`               // writes retried after merging the stored milestones
+	Failures   int64 `json:"failures"`              // writes abandoned until the next persist
+	Duplicates int64 `json:"duplicates_suppressed"` // achievements another writer stored first
+}
+
+// persistCounters are the live counts behind PersistStats
+type persistCounters struct {
+	writes, conflicts, retries, failures, duplicates int64
+}
+
+// SetStore persists milestones to store, retrying a write that hits a
+// version conflict up to attempts times in all. New achievements are
+// written before they are announced, and one another writer stored first
+// is not announced again.
+func (t *Tracker) SetStore(store Store, attempts int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if attempts < 1 {
+		attempts = 1
+	}
+	t.store = store
+	t.persistAttempts = attempts
+}
+
+// PersistStats returns the store's write and conflict counters
+func (t *Tracker) PersistStats() PersistStats {
+	return PersistStats{
+		Writes:     atomic.LoadInt64(&t.persisted.writes),
+		Conflicts:  atomic.LoadInt64(&t.persisted.conflicts),
+		Retries:    atomic.LoadInt64(&t.persisted.retries),
+		Failures:   atomic.LoadInt64(&t.persisted.failures),
+		Duplicates: atomic.LoadInt64(&t.persisted.duplicates),
+	}
+}
+
+// Persisting reports whether milestones are persisted to a store
+func (t *Tracker) Persisting() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.store != nil
+}
+
+// Load replaces the milestones of every stored session with the stored
+// ones and returns how many sessions were loaded, keeping their progress
+// and achievements so they are not announced again
+func (t *Tracker) Load(ctx context.Context) (int, error) {
+	t.mu.RLock()
+	store := t.store
+	t.mu.RUnlock()
+	if store == nil {
+		return 0, nil
+	}
+
+	states, err := store.LoadMilestoneStates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	loaded := 0
+	for sessionID, state := range states {
+		var milestones []*Milestone
+		if err := json.Unmarshal(state.Data, &milestones); err != nil {
+			log.Printf("Skipping corrupt milestones for session %s: %v", sessionID, err)
+			continue
+		}
+		t.milestones[sessionID] = milestones
+		t.versions[sessionID] = state.Version
+		delete(t.dirty, sessionID)
+		loaded++
+	}
+	return loaded, nil
+}
+
+// Persist writes the milestones of every session changed since they were
+// last written and deletes those of removed sessions. Sessions whose write
+// fails are written again by the next persist.
+func (t *Tracker) Persist(ctx context.Context) error {
+	t.mu.RLock()
+	store := t.store
+	t.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	changed := make([]string, 0, len(t.dirty))
+	for sessionID := range t.dirty {
+		changed = append(changed, sessionID)
+	}
+	removed := make([]string, 0, len(t.removedState))
+	for sessionID := range t.removedState {
+		removed = append(removed, sessionID)
+	}
+	t.removedState = make(map[string]bool)
+	t.mu.Unlock()
+
+	if err := store.DeleteMilestoneStates(ctx, removed); err != nil {
+		// Retry the deletions with the next persist
+		t.mu.Lock()
+		for _, sessionID := range removed {
+			if _, recreated := t.milestones[sessionID]; !recreated {
+				t.removedState[sessionID] = true
+			}
+		}
+		t.mu.Unlock()
+		return err
+	}
+
+	failed := 0
+	var lastErr error
+	for _, sessionID := range changed {
+		if err := t.persistSession(ctx, store, sessionID); err != nil {
+			atomic.AddInt64(&t.persisted.failures, 1)
+			failed++
+			lastErr = err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("persisting milestones of %d sessions: %w", failed, lastErr)
+	}
+	return nil
+}
+
+// StartPersisting periodically persists changed milestones until the
+// context is cancelled
+func (t *Tracker) StartPersisting(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Persist(ctx); err != nil {
+					log.Printf("Error persisting milestones: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// lockSession waits for the session's write lock until ctx is done, and
+// returns the function releasing it
+func (t *Tracker) lockSession(ctx context.Context, sessionID string) (func(), error) {
+	t.mu.Lock()
+	lock, exists := t.persisting[sessionID]
+	if !exists {
+		lock = make(chan struct{}, 1)
+		t.persisting[sessionID] = lock
+	}
+	t.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return func() {
+		t.mu.Lock()
+		if _, tracked := t.milestones[sessionID]; !tracked && t.persisting[sessionID] == lock {
+			delete(t.persisting, sessionID)
+		}
+		t.mu.Unlock()
+		<-lock
+	}, nil
+}
+
+// persistSession conditionally writes a session's milestones. On a
+// conflict the stored milestones are merged in and the write is retried
+// against their version. Writes of a session are serialized, so a
+// tracker's own writes never conflict with each other, while other
+// sessions are written concurrently.
+func (t *Tracker) persistSession(ctx context.Context, store Store, sessionID string) error {
+	unlock, err := t.lockSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for attempt := 1; ; attempt++ {
+		t.mu.Lock()
+		milestones, exists := t.milestones[sessionID]
+		if !exists {
+			t.mu.Unlock()
+			return nil
+		}
+		data, err := json.Marshal(milestones)
+		version := t.versions[sessionID]
+		delete(t.dirty, sessionID)
+		attempts := t.persistAttempts
+		t.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		newVersion, saved, err := store.SaveMilestoneState(ctx, sessionID, data, version)
+		if err == nil && saved {
+			atomic.AddInt64(&t.persisted.writes, 1)
+			t.mu.Lock()
+			if _, exists := t.milestones[sessionID]; exists {
+				t.versions[sessionID] = newVersion
+			}
+			t.mu.Unlock()
+			return nil
+		}
+
+		if err == nil {
+			atomic.AddInt64(&t.persisted.conflicts, 1)
+			if attempt >= attempts {
+				err = fmt.Errorf("session %s: %w after %d attempts", sessionID, ErrVersionConflict, attempts)
+			} else {
+				var states map[string]storage.MilestoneState
+				if states, err = store.LoadMilestoneStates(ctx, sessionID); err == nil {
+					stored, found := states[sessionID]
+					t.mergeStored(sessionID, stored, found)
+					atomic.AddInt64(&t.persisted.retries, 1)
+					continue
+				}
+			}
+		}
+		t.markDirty(sessionID)
+		return err
+	}
+}
+
+// mergeStored folds the milestones another writer stored into the
+// session's, so the retried write keeps what they achieved. The first
+// achievement stored wins: a later one made here adopts its time and is
+// marked as achieved elsewhere so it is not announced twice.
+func (t *Tracker) mergeStored(sessionID string, state storage.MilestoneState, found bool) {
+	var stored []*Milestone
+	if found {
+		if err := json.Unmarshal(state.Data, &stored); err != nil {
+			log.Printf("Overwriting corrupt stored milestones for session %s: %v", sessionID, err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	milestones, exists := t.milestones[sessionID]
+	if !exists {
+		return
+	}
+	t.versions[sessionID] = state.Version
+
+	byID := make(map[string]*Milestone, len(milestones))
+	for _, milestone := range milestones {
+		byID[milestone.ID] = milestone
+	}
+	for _, other := range stored {
+		milestone, known := byID[other.ID]
+		if !known {
+			milestones = append(milestones, other) // added by another instance
+			continue
+		}
+		if other.Achieved && !(milestone.Achieved && sameTime(milestone.AchievedAt, other.AchievedAt)) {
+			milestone.Achieved = true
+			milestone.AchievedAt = other.AchievedAt
+			milestone.achievedElsewhere = true
+		}
+		if other.Progress > milestone.Progress {
+			milestone.Progress = other.Progress
+		}
+		if milestone.Locked && !other.Locked {
+			milestone.Locked = false
+			milestone.UnlockedAt = other.UnlockedAt
+		}
+	}
+	t.milestones[sessionID] = milestones
+}
+
+// sameTime reports whether two optional times are the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// markDirty schedules a session's milestones for the next persist
+func (t *Tracker) markDirty(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.milestones[sessionID]; exists {
+		t.dirty[sessionID] = true
+	}
+}
+
+// announce writes a session's new achievements before notifying them,
+// skipping any that another writer stored first so each is announced once.
+// If the store cannot be written they are announced regardless.
+func (t *Tracker) announce(store Store, sessionID string, achievements []*MilestoneAchievement) {
+	ctx, cancel := context.WithTimeout(context.Background(), announceTimeout)
+	defer cancel()
+
+	if err := t.persistSession(ctx, store, sessionID); err != nil {
+		atomic.AddInt64(&t.persisted.failures, 1)
+		log.Printf("Error persisting milestones of session %s, announcing anyway: %v", sessionID, err)
+	}
+
+	first := achievements[:0]
+	t.mu.RLock()
+	for _, achievement := range achievements {
+		if milestone := findMilestone(t.milestones[sessionID], achievement.Milestone.ID); milestone != nil && milestone.achievedElsewhere {
+			atomic.AddInt64(&t.persisted.duplicates, 1)
+			log.Printf("Milestone %s of session %s was already achieved by another instance", achievement.Milestone.ID, sessionID)
+			continue
+		}
+		first = append(first, achievement)
+	}
+	t.mu.RUnlock()
+	t.notifyAchievements(sessionID, first)
+}
+
+// findMilestone returns the milestone with the given ID, or nil
+func findMilestone(milestones []*Milestone, id string) *Milestone {
+	for _, milestone := range milestones {
+		if milestone.ID == id {
+			return milestone
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package milestones
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a Store keeping versioned milestones in memory
+type memoryStore struct {
+	mu     sync.Mutex
+	states map[string]storage.MilestoneState
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{states: make(map[string]storage.MilestoneState)}
+}
+
+func (m *memoryStore) SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states[sessionID].Version != version {
+		return 0, false, nil
+	}
+	m.states[sessionID] = storage.MilestoneState{Data: data, Version: version + 1}
+	return version + 1, true, nil
+}
+
+func (m *memoryStore) LoadMilestoneStates(ctx context.Context, sessionIDs ...string) (map[string]storage.MilestoneState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states := make(map[string]storage.MilestoneState)
+	for sessionID, state := range m.states {
+		if len(sessionIDs) == 0 || sessionID == sessionIDs[0] {
+			states[sessionID] = state
+		}
+	}
+	return states, nil
+}
+
+func (m *memoryStore) DeleteMilestoneStates(ctx context.Context, sessionIDs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sessionID := range sessionIDs {
+		delete(m.states, sessionID)
+	}
+	return nil
+}
+
+func TestTracker_SharedStoreAnnouncesEachAchievementOnce(t *testing.T) {
+	store := newMemoryStore()
+	announced := make(chan *MilestoneAchievement, 4)
+	notify := func(a *MilestoneAchievement) { announced <- a }
+	first, second := NewTracker(notify), NewTracker(notify)
+	for _, tracker := range []*Tracker{first, second} {
+		tracker.SetStore(store, 3)
+		tracker.InitializeSession("s1", []int{10})
+		require.NoError(t, tracker.Persist(context.Background()))
+	}
+	assert.Equal(t, int64(1), second.PersistStats().Conflicts, "the second writer had not seen the first's write")
+
+	first.CheckMilestones("s1", reactions("s1", 12))
+	var achievement *MilestoneAchievement
+	select {
+	case achievement = <-announced:
+	case <-time.After(time.Second):
+		t.Fatal("milestone was not announced")
+	}
+
+	second.CheckMilestones("s1", reactions("s1", 15))
+	require.Eventually(t, func() bool { return second.PersistStats().Duplicates == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, announced, "the achievement is announced by the first writer only")
+
+	milestone := second.GetSessionMilestones("s1")[0]
+	assert.True(t, milestone.Achieved)
+	assert.True(t, milestone.AchievedAt.Equal(*achievement.Milestone.AchievedAt), "the first stored achievement wins")
+	assert.Equal(t, int64(15), milestone.Progress)
+
+	reloaded := NewTracker(nil)
+	reloaded.SetStore(store, 3)
+	loaded, err := reloaded.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+	assert.True(t, reloaded.GetSessionMilestones("s1")[0].Achieved)
+}
+
+func TestTracker_PersistMergesConcurrentWrites(t *testing.T) {
+	store := newMemoryStore()
+	first, second := NewTracker(nil), NewTracker(nil)
+	first.SetStore(store, 3)
+	second.SetStore(store, 3)
+	first.InitializeSession("s1", []int{10})
+	second.InitializeSession("s1", []int{10})
+	require.NoError(t, second.AddMilestones("s1", []Definition{{Type: MilestoneTypeConcurrentUsers, Threshold: 5}}))
+
+	require.NoError(t, second.Persist(context.Background()))
+	require.NoError(t, first.Persist(context.Background()))
+
+	stats := first.PersistStats()
+	assert.Equal(t, int64(1), stats.Conflicts)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.Len(t, first.GetSessionMilestones("s1"), 2, "milestones the other writer added are kept")
+	assert.Equal(t, int64(2), store.states["s1"].Version)
+}
+
+func TestTracker_PersistGivesUpAfterItsAttempts(t *testing.T) {
+	store := &conflictingStore{newMemoryStore()}
+	tracker := NewTracker(nil)
+	tracker.SetStore(store, 2)
+	tracker.InitializeSession("s1", []int{10})
+
+	err := tracker.Persist(context.Background())
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	stats := tracker.PersistStats()
+	assert.Equal(t, int64(2), stats.Conflicts)
+	assert.Equal(t, int64(1), stats.Failures)
+
+	// The session stays due, and is written once the conflicts stop
+	require.NoError(t, tracker.Persist(context.Background()))
+	assert.Equal(t, int64(1), tracker.PersistStats().Writes)
+}
+
+// conflictingStore refuses the first two writes of each session as if
+// another writer had just got there first
+type conflictingStore struct {
+	*memoryStore
+}
+
+func (c *conflictingStore) SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error) {
+	c.mu.Lock()
+	state := c.states[sessionID]
+	if state.Version < 2 {
+		c.states[sessionID] = storage.MilestoneState{Data: []byte(`[]`), Version: state.Version + 1}
+		c.mu.Unlock()
+		return 0, false, nil
+	}
+	c.mu.Unlock()
+	return c.memoryStore.SaveMilestoneState(ctx, sessionID, data, version)
+}
+
+// stallingStore holds writes of one session until released
+type stallingStore struct {
+	*memoryStore
+	stalled string
+	release chan struct{}
+}
+
+func (s *stallingStore) SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error) {
+	if sessionID == s.stalled {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		}
+	}
+	return s.memoryStore.SaveMilestoneState(ctx, sessionID, data, version)
+}
+
+func TestTracker_SlowWritesDoNotDelayOtherSessionsAnnouncements(t *testing.T) {
+	store := &stallingStore{memoryStore: newMemoryStore(), stalled: "slow", release: make(chan struct{})}
+	announced := make(chan *MilestoneAchievement, 1)
+	tracker := NewTracker(func(a *MilestoneAchievement) { announced <- a })
+	tracker.SetStore(store, 3)
+	tracker.InitializeSession("slow", []int{10})
+	tracker.InitializeSession("s1", []int{10})
+
+	persisted := make(chan error, 1)
+	go func() { persisted <- tracker.Persist(context.Background()) }()
+
+	tracker.CheckMilestones("s1", reactions("s1", 12))
+	select {
+	case achievement := <-announced:
+		assert.Equal(t, "s1", achievement.SessionID)
+	case <-time.After(time.Second):
+		t.Fatal("the announcement waited on another session's write")
+	}
+
+	close(store.release)
+	require.NoError(t, <-persisted)
+}
@@ -1,6 +1,7 @@
 package milestones
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -11,21 +12,54 @@ import (
 // NotificationHandler is called when a milestone is achieved
 type NotificationHandler func(*MilestoneAchievement)
 
+// UnlockHandler is called when a milestone's prerequisites are achieved
+type UnlockHandler func(*MilestoneUnlock)
+
 // Tracker tracks milestones for sessions
 type Tracker struct {
-	milestones map[string][]*Milestone // sessionID -> milestones
-	mu         sync.RWMutex
-	notifyFunc NotificationHandler
+	milestones  map[string][]*Milestone // sessionID -> milestones
+	mu          sync.RWMutex
+	notifyFunc  NotificationHandler
+	unlockFunc  UnlockHandler
+	countSystem bool // total_reactions milestones include integration reactions
+
+	store           Store
+	persistAttempts int
+	persisting      map[string]chan struct{} // sessionID -> one-slot lock serializing its writes, so versions advance in order
+	versions        map[string]int64         // sessionID -> stored version last read or written
+	dirty           map[string]bool          // sessions changed since they were last written
+	removedState    map[string]bool          // removed sessions whose stored milestones are to be deleted
+	persisted       persistCounters
 }
 
 // NewTracker creates a new milestone tracker
 func NewTracker(notifyFunc NotificationHandler) *Tracker {
 	return &Tracker{
-		milestones: make(map[string][]*Milestone),
-		notifyFunc: notifyFunc,
+		milestones:   make(map[string][]*Milestone),
+		notifyFunc:   notifyFunc,
+		persisting:   make(map[string]chan struct{}),
+		versions:     make(map[string]int64),
+		dirty:        make(map[string]bool),
+		removedState: make(map[string]bool),
 	}
 }
 
+// SetCountSystemReactions makes total_reactions milestones count reactions
+// emitted by integrations as well as the audience's
+func (t *Tracker) SetCountSystemReactions(count bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.countSystem = count
+}
+
+// SetUnlockHandler sets the handler told about milestones unlocked by
+// their prerequisites
+func (t *Tracker) SetUnlockHandler(handler UnlockHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unlockFunc = handler
+}
+
 // InitializeSession sets up milestones for a session
 func (t *Tracker) InitializeSession(sessionID string, thresholds []int) {
 	t.mu.Lock()
@@ -40,58 +74,117 @@ func (t *Tracker) InitializeSession(sessionID string, thresholds []int) {
 	}
 
 	t.milestones[sessionID] = milestones
+	t.dirty[sessionID] = true
+	delete(t.removedState, sessionID)
 	log.Printf("Initialized %d milestones for session %s", len(milestones), sessionID)
 }
 
-// CheckMilestones checks if any milestones were achieved based on current stats
+// CheckMilestones checks if any milestones were achieved based on current
+// stats. Progress is updated under the tracker lock, so checks do not race
+// resets, exports or readers; notifications get a copy of the milestone.
+// Milestones unlocked by an achievement are evaluated in the same check.
 func (t *Tracker) CheckMilestones(sessionID string, stats *aggregation.SessionStats) {
-	t.mu.RLock()
-	sessionMilestones, exists := t.milestones[sessionID]
-	t.mu.RUnlock()
+	var achievements []*MilestoneAchievement
+	var unlocks []*MilestoneUnlock
+	now := time.Now()
 
-	if !exists {
-		return
-	}
+	t.mu.Lock()
+	milestones := t.milestones[sessionID]
+	evaluated := make(map[*Milestone]bool, len(milestones))
+	for changed := true; changed; {
+		changed = false
+		for _, milestone := range milestones {
+			if milestone.Locked {
+				if !prerequisitesAchieved(milestones, milestone) {
+					continue
+				}
+				unlocked := now.UTC()
+				milestone.Locked = false
+				milestone.UnlockedAt = &unlocked
+				copied := *milestone
+				unlocks = append(unlocks, &MilestoneUnlock{Milestone: &copied, SessionID: sessionID, UnlockedAt: unlocked})
+				t.dirty[sessionID] = true
+			}
+			if milestone.Achieved || evaluated[milestone] {
+				continue // Already achieved or checked
+			}
+			evaluated[milestone] = true
 
-	totalReactions := stats.GetTotalReactions()
-	activeUsers := int64(stats.GetActiveUserCount())
+			evaluator, known := lookupEvaluator(milestone.Type)
+			if !known {
+				continue
+			}
+			milestone.countSystem = t.countSystem
+			currentValue := evaluator.Evaluate(milestone, stats)
+			milestone.rate.observe(currentValue, now)
+			if currentValue != milestone.Progress {
+				t.dirty[sessionID] = true
+			}
 
-	for _, milestone := range sessionMilestones {
-		if milestone.Achieved {
-			continue // Already achieved
+			// Update progress and check if just achieved
+			if milestone.UpdateProgress(currentValue) {
+				achieved := *milestone
+				achievements = append(achievements, &MilestoneAchievement{
+					Milestone:    &achieved,
+					SessionID:    sessionID,
+					AchievedAt:   time.Now().UTC(),
+					CurrentValue: currentValue,
+				})
+				changed = true // may unlock others
+			}
 		}
+	}
+	unlockFunc := t.unlockFunc
+	store := t.store
+	t.mu.Unlock()
 
-		var currentValue int64
-		switch milestone.Type {
-		case MilestoneTypeTotalReactions:
-			currentValue = totalReactions
-		case MilestoneTypeConcurrentUsers:
-			currentValue = activeUsers
-		case MilestoneTypeSessionDuration:
-			currentValue = int64(time.Since(stats.StartTime).Minutes())
+	// With a store, achievements are only announced once written, so an
+	// achievement another instance made first is not announced again
+	if store != nil && len(achievements) > 0 {
+		go t.announce(store, sessionID, achievements)
+	} else {
+		t.notifyAchievements(sessionID, achievements)
+	}
+	for _, unlock := range unlocks {
+		log.Printf("Milestone unlocked! Session: %s, Milestone: %s", sessionID, unlock.Milestone.ID)
+		if unlockFunc != nil {
+			go unlockFunc(unlock)
 		}
+	}
+}
 
-		// Update progress and check if just achieved
-		if milestone.UpdateProgress(currentValue) {
-			achievement := &MilestoneAchievement{
-				Milestone:    milestone,
-				SessionID:    sessionID,
-				AchievedAt:   time.Now().UTC(),
-				CurrentValue: currentValue,
-			}
+// notifyAchievements logs and notifies a session's achievements
+func (t *Tracker) notifyAchievements(sessionID string, achievements []*MilestoneAchievement) {
+	for _, achievement := range achievements {
+		log.Printf("Milestone achieved! Session: %s, Type: %s, Threshold: %d, Current: %d",
+			sessionID, achievement.Milestone.Type, achievement.Milestone.Threshold, achievement.CurrentValue)
 
-			log.Printf("Milestone achieved! Session: %s, Type: %s, Threshold: %d, Current: %d",
-				sessionID, milestone.Type, milestone.Threshold, currentValue)
+		// Notify about the achievement
+		if t.notifyFunc != nil {
+			go t.notifyFunc(achievement)
+		}
+	}
+}
 
-			// Notify about the achievement
-			if t.notifyFunc != nil {
-				go t.notifyFunc(achievement)
+// prerequisitesAchieved reports whether every milestone m requires is
+// achieved
+func prerequisitesAchieved(milestones []*Milestone, m *Milestone) bool {
+	for _, required := range m.Requires {
+		achieved := false
+		for _, other := range milestones {
+			if other.ID == required {
+				achieved = other.Achieved
+				break
 			}
 		}
+		if !achieved {
+			return false
+		}
 	}
+	return true
 }
 
-// GetSessionMilestones returns all milestones for a session
+// GetSessionMilestones returns copies of all milestones for a session
 func (t *Tracker) GetSessionMilestones(sessionID string) []*Milestone {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -100,14 +193,10 @@ func (t *Tracker) GetSessionMilestones(sessionID string) []*Milestone {
 	if !exists {
 		return nil
 	}
-
-	// Return a copy to avoid race conditions
-	result := make([]*Milestone, len(milestones))
-	copy(result, milestones)
-	return result
+	return copyMilestones(milestones)
 }
 
-// GetAchievedMilestones returns only the achieved milestones for a session
+// GetAchievedMilestones returns copies of the achieved milestones for a session
 func (t *Tracker) GetAchievedMilestones(sessionID string) []*Milestone {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -123,7 +212,47 @@ func (t *Tracker) GetAchievedMilestones(sessionID string) []*Milestone {
 			achieved = append(achieved, m)
 		}
 	}
-	return achieved
+	return copyMilestones(achieved)
+}
+
+// copyMilestones copies milestones so callers can read them after the lock
+// is released. Definition fields such as weights are shared, as they never
+// change after a milestone is built.
+func copyMilestones(milestones []*Milestone) []*Milestone {
+	if milestones == nil {
+		return nil
+	}
+	copies := make([]*Milestone, len(milestones))
+	for i, m := range milestones {
+		milestone := *m
+		copies[i] = &milestone
+	}
+	return copies
+}
+
+// Export returns copies of every session's milestones, for replication.
+// Progress is written under the same lock, so each copy is consistent.
+func (t *Tracker) Export() map[string][]*Milestone {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	exported := make(map[string][]*Milestone, len(t.milestones))
+	for sessionID, milestones := range t.milestones {
+		exported[sessionID] = copyMilestones(milestones)
+	}
+	return exported
+}
+
+// Restore replaces the milestones of every session in exported, keeping
+// their progress and achievements so they are not announced again
+func (t *Tracker) Restore(exported map[string][]*Milestone) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for sessionID, milestones := range exported {
+		t.milestones[sessionID] = copyMilestones(milestones)
+		t.dirty[sessionID] = true
+	}
 }
 
 // RemoveSession removes milestone tracking for a session
@@ -131,6 +260,11 @@ func (t *Tracker) RemoveSession(sessionID string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	delete(t.milestones, sessionID)
+	delete(t.versions, sessionID)
+	delete(t.dirty, sessionID)
+	if t.store != nil {
+		t.removedState[sessionID] = true
+	}
 }
 
 // AddCustomMilestone adds a custom milestone to a session
@@ -140,4 +274,108 @@ func (t *Tracker) AddCustomMilestone(sessionID string, milestoneType MilestoneTy
 
 	milestone := NewMilestone(sessionID, milestoneType, threshold)
 	t.milestones[sessionID] = append(t.milestones[sessionID], milestone)
+	t.dirty[sessionID] = true
+}
+
+// AddMilestones adds milestones built from client definitions to a session.
+// Prerequisites must name milestones of the session or of the same batch
+// and may not form a cycle; otherwise nothing is added. Milestones whose
+// prerequisites are already achieved start unlocked.
+func (t *Tracker) AddMilestones(sessionID string, definitions []Definition) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	existing := t.milestones[sessionID]
+	built := make([]*Milestone, len(definitions))
+	for i, definition := range definitions {
+		built[i] = definition.Build(sessionID)
+	}
+	milestones := append(append([]*Milestone(nil), existing...), built...)
+	if err := checkPrerequisites(milestones); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, milestone := range built {
+		if milestone.Locked && prerequisitesAchieved(milestones, milestone) {
+			milestone.Locked = false
+			milestone.UnlockedAt = &now
+		}
+	}
+	t.milestones[sessionID] = milestones
+	t.dirty[sessionID] = true
+	return nil
+}
+
+// checkPrerequisites verifies that every prerequisite names a milestone in
+// the list and that no milestone depends on itself, directly or not
+func checkPrerequisites(milestones []*Milestone) error {
+	byID := make(map[string]*Milestone, len(milestones))
+	for _, milestone := range milestones {
+		byID[milestone.ID] = milestone
+	}
+	for _, milestone := range milestones {
+		for _, required := range milestone.Requires {
+			if byID[required] == nil {
+				return fmt.Errorf("milestone %s requires unknown milestone %s", milestone.ID, required)
+			}
+		}
+	}
+
+	// Depth-first search, where visiting marks milestones on the current path
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(milestones))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("milestone %s depends on itself", id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, required := range byID[id].Requires {
+			if err := visit(required); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		return nil
+	}
+	for _, milestone := range milestones {
+		if err := visit(milestone.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset makes a session's milestones of the given types achievable again,
+// or all of them when no types are given, after its counters were reset
+func (t *Tracker) Reset(sessionID string, types ...MilestoneType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, milestone := range t.milestones[sessionID] {
+		matches := len(types) == 0
+		for _, milestoneType := range types {
+			matches = matches || milestone.Type == milestoneType
+		}
+		if matches {
+			milestone.Achieved = false
+			milestone.AchievedAt = nil
+			milestone.Progress = 0
+			milestone.rate = rateEstimate{}
+			milestone.achievedElsewhere = false
+			t.dirty[sessionID] = true
+			if len(milestone.Requires) > 0 {
+				milestone.Locked = true
+				milestone.UnlockedAt = nil
+			}
+		}
+	}
 }
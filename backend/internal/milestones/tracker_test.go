@@ -0,0 +1,205 @@
+package milestones
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reactions(sessionID string, n int) *aggregation.SessionStats {
+	stats := aggregation.NewSessionStats(sessionID)
+	for i := 0; i < n; i++ {
+		stats.IncrementReaction(events.ReactionFire)
+	}
+	return stats
+}
+
+func TestTracker_AchievesOnceUntilReset(t *testing.T) {
+	achieved := make(chan *MilestoneAchievement, 4)
+	tracker := NewTracker(func(a *MilestoneAchievement) { achieved <- a })
+	tracker.InitializeSession("s1", []int{10})
+
+	tracker.CheckMilestones("s1", reactions("s1", 12))
+	tracker.CheckMilestones("s1", reactions("s1", 15))
+	select {
+	case a := <-achieved:
+		assert.Equal(t, int64(12), a.CurrentValue)
+		assert.True(t, a.Milestone.Achieved)
+	case <-time.After(time.Second):
+		t.Fatal("milestone was not announced")
+	}
+
+	tracker.Reset("s1")
+	milestones := tracker.GetSessionMilestones("s1")
+	require.Len(t, milestones, 1)
+	assert.False(t, milestones[0].Achieved)
+
+	tracker.CheckMilestones("s1", reactions("s1", 10))
+	select {
+	case <-achieved:
+	case <-time.After(time.Second):
+		t.Fatal("reset milestone was not announced again")
+	}
+	assert.Empty(t, achieved, "each achievement is announced once")
+}
+
+// Run with -race: checks, resets and readers touch the same milestones
+func TestTracker_ConcurrentCheckResetAndRead(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", []int{5, 50, 500})
+	stats := reactions("s1", 100)
+
+	var wg sync.WaitGroup
+	for _, run := range []func(){
+		func() { tracker.CheckMilestones("s1", stats) },
+		func() { tracker.Reset("s1") },
+		func() { tracker.Export() },
+		func() {
+			for _, m := range tracker.GetSessionMilestones("s1") {
+				_ = m.Achieved && m.Progress > 0
+			}
+		},
+		func() { tracker.GetAchievedMilestones("s1") },
+	} {
+		wg.Add(1)
+		go func(run func()) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				run()
+			}
+		}(run)
+	}
+	wg.Wait()
+}
+
+func TestTracker_ExportIsASnapshot(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", []int{10, 100})
+	tracker.CheckMilestones("s1", reactions("s1", 20))
+
+	exported := tracker.Export()
+	tracker.CheckMilestones("s1", reactions("s1", 150))
+	tracker.RemoveSession("s1")
+
+	require.Len(t, exported["s1"], 2)
+	assert.True(t, exported["s1"][0].Achieved)
+	assert.Equal(t, int64(20), exported["s1"][1].Progress, "later progress does not leak into the export")
+	assert.False(t, exported["s1"][1].Achieved)
+}
+
+func TestTracker_RestoreKeepsAchievements(t *testing.T) {
+	primary := NewTracker(nil)
+	primary.InitializeSession("s1", []int{10, 100})
+	primary.CheckMilestones("s1", reactions("s1", 20))
+	exported := primary.Export()
+
+	announced := make(chan *MilestoneAchievement, 4)
+	standby := NewTracker(func(a *MilestoneAchievement) { announced <- a })
+	standby.Restore(exported)
+
+	// Mutating the export afterwards does not reach the restored tracker
+	exported["s1"][1].Progress = 99
+	assert.Equal(t, int64(20), standby.GetSessionMilestones("s1")[1].Progress)
+
+	standby.CheckMilestones("s1", reactions("s1", 120))
+	select {
+	case a := <-announced:
+		assert.Equal(t, int64(100), a.Milestone.Threshold, "only the newly reached milestone is announced")
+	case <-time.After(time.Second):
+		t.Fatal("milestone was not announced")
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, announced)
+	assert.Len(t, standby.GetAchievedMilestones("s1"), 2)
+}
+
+func TestTracker_CountsSystemReactionsWhenConfigured(t *testing.T) {
+	stats := reactions("s1", 8)
+	for i := 0; i < 4; i++ {
+		stats.RecordSystemReaction("trivia-bot", events.ReactionApplause)
+	}
+
+	organic := NewTracker(nil)
+	organic.InitializeSession("s1", []int{10})
+	organic.CheckMilestones("s1", stats)
+	assert.Equal(t, int64(8), organic.GetSessionMilestones("s1")[0].Progress, "integration reactions are excluded by default")
+
+	counted := NewTracker(nil)
+	counted.SetCountSystemReactions(true)
+	counted.InitializeSession("s1", []int{10})
+	counted.CheckMilestones("s1", stats)
+	assert.True(t, counted.GetSessionMilestones("s1")[0].Achieved)
+	assert.Equal(t, int64(12), counted.GetSessionMilestones("s1")[0].Progress)
+}
+
+func TestTracker_UnlocksChainedMilestones(t *testing.T) {
+	achieved := make(chan *MilestoneAchievement, 4)
+	unlocked := make(chan *MilestoneUnlock, 4)
+	tracker := NewTracker(func(a *MilestoneAchievement) { achieved <- a })
+	tracker.SetUnlockHandler(func(u *MilestoneUnlock) { unlocked <- u })
+	tracker.InitializeSession("s1", []int{10})
+	require.NoError(t, tracker.AddMilestones("s1", []Definition{
+		{Type: MilestoneTypeTotalReactions, Threshold: 50, Requires: []string{"total_reactions_20"}},
+		{Type: MilestoneTypeTotalReactions, Threshold: 20, Requires: []string{"s1_total_reactions_10"}},
+	}))
+
+	tracker.CheckMilestones("s1", reactions("s1", 15))
+	stretch := tracker.GetSessionMilestones("s1")[1]
+	assert.True(t, stretch.Locked)
+	assert.Zero(t, stretch.Progress, "locked milestones are not evaluated")
+
+	// One check achieves 10, unlocks and achieves 20, then unlocks 50
+	tracker.CheckMilestones("s1", reactions("s1", 25))
+	var unlocks []string
+	for range 2 {
+		select {
+		case u := <-unlocked:
+			unlocks = append(unlocks, u.Milestone.ID)
+		case <-time.After(time.Second):
+			t.Fatal("milestone unlock was not announced")
+		}
+	}
+	assert.ElementsMatch(t, []string{"s1_total_reactions_20", "s1_total_reactions_50"}, unlocks)
+
+	milestones := tracker.GetSessionMilestones("s1")
+	assert.True(t, milestones[2].Achieved)
+	assert.False(t, milestones[1].Locked)
+	assert.False(t, milestones[1].Achieved)
+	assert.Equal(t, int64(25), milestones[1].Progress)
+	assert.NotNil(t, milestones[1].UnlockedAt)
+	for range 2 {
+		select {
+		case <-achieved:
+		case <-time.After(time.Second):
+			t.Fatal("milestone was not announced")
+		}
+	}
+
+	tracker.Reset("s1")
+	assert.True(t, tracker.GetSessionMilestones("s1")[1].Locked, "reset chains lock again")
+}
+
+func TestTracker_RejectsBrokenMilestoneChains(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", []int{10})
+
+	assert.ErrorContains(t, tracker.AddMilestones("s1", []Definition{
+		{Type: MilestoneTypeTotalReactions, Threshold: 50, Requires: []string{"total_reactions_30"}},
+	}), "unknown milestone")
+	assert.ErrorContains(t, tracker.AddMilestones("s1", []Definition{
+		{Type: MilestoneTypeTotalReactions, Threshold: 20, Requires: []string{"total_reactions_30"}},
+		{Type: MilestoneTypeTotalReactions, Threshold: 30, Requires: []string{"total_reactions_20"}},
+	}), "depends on itself")
+	assert.Len(t, tracker.GetSessionMilestones("s1"), 1, "nothing is added from a rejected batch")
+
+	tracker.CheckMilestones("s1", reactions("s1", 10))
+	require.NoError(t, tracker.AddMilestones("s1", []Definition{
+		{Type: MilestoneTypeTotalReactions, Threshold: 20, Requires: []string{"total_reactions_10"}},
+	}))
+	assert.False(t, tracker.GetSessionMilestones("s1")[1].Locked, "prerequisites already achieved")
+}
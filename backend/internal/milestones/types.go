@@ -1,17 +1,26 @@
 package milestones
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/locales"
 )
 
 // MilestoneType represents different types of milestones
 type MilestoneType string
 
 const (
-	MilestoneTypeTotalReactions  MilestoneType = "total_reactions"
-	MilestoneTypeConcurrentUsers MilestoneType = "concurrent_users"
-	MilestoneTypeSessionDuration MilestoneType = "session_duration"
+	MilestoneTypeTotalReactions   MilestoneType = "total_reactions"
+	MilestoneTypeConcurrentUsers  MilestoneType = "concurrent_users"
+	MilestoneTypeSessionDuration  MilestoneType = "session_duration"
+	MilestoneTypeReactionVelocity MilestoneType = "reaction_velocity"
 )
 
 // Milestone represents a goal that can be achieved
@@ -24,8 +33,146 @@ type Milestone struct {
 	Achieved    bool          `json:"achieved"`
 	AchievedAt  *time.Time    `json:"achieved_at,omitempty"`
 	Description string        `json:"description"`
+
+	// ReactionWeights limits a total_reactions milestone to the listed
+	// reaction types, each counting with its weight. Empty counts every reaction.
+	ReactionWeights map[events.ReactionType]int64 `json:"reaction_weights,omitempty"`
+
+	// WindowSeconds is the rolling window a reaction_velocity milestone
+	// counts reactions over, e.g. 60 for "1,000 reactions in one minute"
+	WindowSeconds int `json:"window_seconds,omitempty"`
+
+	Presentation *Presentation `json:"presentation,omitempty"`
+
+	// Requires lists the IDs of milestones that must be achieved before this
+	// one becomes active, e.g. a stretch goal. It stays Locked, and is not
+	// evaluated, until they all are.
+	Requires   []string   `json:"requires,omitempty"`
+	Locked     bool       `json:"locked,omitempty"`
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+
+	rate              rateEstimate // smoothed progress rate, for forecasts
+	countSystem       bool         // integration reactions count towards total_reactions
+	achievedElsewhere bool         // another writer stored the achievement first, and announced it
+}
+
+// Presentation carries optional branding that overlay clients use to render
+// the celebration when a milestone is achieved
+type Presentation struct {
+	Icon        string `json:"icon,omitempty"`
+	Color       string `json:"color,omitempty"` // hex, e.g. "#FF5500"
+	AnimationID string `json:"animation_id,omitempty"`
+	SoundCue    string `json:"sound_cue,omitempty"`
+
+	// Priority orders celebrations of milestones achieved together;
+	// higher ones are celebrated first
+	Priority int `json:"priority,omitempty"`
+}
+
+// colorPattern matches #RGB and #RRGGBB hex colors
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks the presentation fields are well formed
+func (p *Presentation) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Color != "" && !colorPattern.MatchString(p.Color) {
+		return fmt.Errorf("color %q must be a hex color like #FF5500", p.Color)
+	}
+	return nil
+}
+
+// Definition describes a milestone to create, as supplied by API clients
+type Definition struct {
+	Type         MilestoneType `json:"type"`
+	Threshold    int64         `json:"threshold"`
+	Description  string        `json:"description,omitempty"`
+	Presentation *Presentation `json:"presentation,omitempty"`
+
+	ReactionWeights map[events.ReactionType]int64 `json:"reaction_weights,omitempty"`
+
+	WindowSeconds int `json:"window_seconds,omitempty"` // reaction_velocity only
+
+	// Requires names the milestones that unlock this one, by ID with or
+	// without the session prefix, e.g. "total_reactions_1000"
+	Requires []string `json:"requires,omitempty"`
+}
+
+// Validate checks the definition can be turned into a milestone
+func (d Definition) Validate() error {
+	switch d.Type {
+	case MilestoneTypeTotalReactions, MilestoneTypeConcurrentUsers, MilestoneTypeSessionDuration:
+		if d.WindowSeconds != 0 {
+			return fmt.Errorf("window_seconds only applies to %s milestones", MilestoneTypeReactionVelocity)
+		}
+	case MilestoneTypeReactionVelocity:
+		maxWindow := int(aggregation.MaxVelocityWindow / time.Second)
+		if d.WindowSeconds <= 0 || d.WindowSeconds > maxWindow {
+			return fmt.Errorf("window_seconds must be between 1 and %d", maxWindow)
+		}
+	default:
+		if _, custom := lookupCustom(d.Type); !custom {
+			return fmt.Errorf("unknown milestone type %q", d.Type)
+		}
+		if d.WindowSeconds != 0 {
+			return fmt.Errorf("window_seconds only applies to %s milestones", MilestoneTypeReactionVelocity)
+		}
+	}
+	if d.Threshold <= 0 {
+		return fmt.Errorf("milestone threshold must be positive")
+	}
+	if len(d.ReactionWeights) > 0 && d.Type != MilestoneTypeTotalReactions {
+		return fmt.Errorf("reaction_weights only apply to %s milestones", MilestoneTypeTotalReactions)
+	}
+	for reactionType, weight := range d.ReactionWeights {
+		if !reactionType.IsValid() {
+			return fmt.Errorf("unknown reaction type %q", reactionType)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("weight for %q must be positive", reactionType)
+		}
+	}
+	if len(d.Requires) > maxPrerequisites {
+		return fmt.Errorf("a milestone may require at most %d others", maxPrerequisites)
+	}
+	for _, required := range d.Requires {
+		if required == "" {
+			return fmt.Errorf("required milestone IDs must not be empty")
+		}
+	}
+	return d.Presentation.Validate()
+}
+
+// Build creates the milestone described by the definition
+func (d Definition) Build(sessionID string) *Milestone {
+	milestone := NewMilestone(sessionID, d.Type, d.Threshold)
+	if len(d.ReactionWeights) > 0 {
+		milestone.ReactionWeights = d.ReactionWeights
+		milestone.ID += "_" + weightsKey(d.ReactionWeights)
+	}
+	if d.WindowSeconds > 0 {
+		milestone.WindowSeconds = d.WindowSeconds
+		milestone.ID += "_" + strconv.Itoa(d.WindowSeconds) + "s"
+	}
+	milestone.Description = milestone.describe(locales.Default())
+	if d.Description != "" {
+		milestone.Description = d.Description
+	}
+	milestone.Presentation = d.Presentation
+	for _, required := range d.Requires {
+		if !strings.HasPrefix(required, sessionID+"_") {
+			required = sessionID + "_" + required
+		}
+		milestone.Requires = append(milestone.Requires, required)
+	}
+	milestone.Locked = len(milestone.Requires) > 0
+	return milestone
 }
 
+// maxPrerequisites bounds the milestones one milestone may require
+const maxPrerequisites = 16
+
 // MilestoneAchievement represents a milestone that was just achieved
 type MilestoneAchievement struct {
 	Milestone    *Milestone `json:"milestone"`
@@ -34,6 +181,14 @@ type MilestoneAchievement struct {
 	CurrentValue int64      `json:"current_value"`
 }
 
+// MilestoneUnlock represents a milestone whose prerequisites were just
+// achieved, so it now counts towards its goal
+type MilestoneUnlock struct {
+	Milestone  *Milestone `json:"milestone"`
+	SessionID  string     `json:"session_id"`
+	UnlockedAt time.Time  `json:"unlocked_at"`
+}
+
 // NewMilestone creates a new milestone
 func NewMilestone(sessionID string, milestoneType MilestoneType, threshold int64) *Milestone {
 	return &Milestone{
@@ -54,23 +209,111 @@ func generateMilestoneID(sessionID string, milestoneType MilestoneType, threshol
 
 // generateDescription creates a human-readable description
 func generateDescription(milestoneType MilestoneType, threshold int64) string {
-	switch milestoneType {
-	case MilestoneTypeTotalReactions:
-		return formatNumber(threshold) + " total reactions"
-	case MilestoneTypeConcurrentUsers:
-		return formatNumber(threshold) + " concurrent users"
-	case MilestoneTypeSessionDuration:
-		return formatNumber(threshold) + " minutes session duration"
+	return (&Milestone{Type: milestoneType, Threshold: threshold}).describe(locales.Default())
+}
+
+// describe generates the milestone's description in the catalog's language
+func (m *Milestone) describe(c *locales.Catalog) string {
+	threshold := formatNumber(m.Threshold)
+	if len(m.ReactionWeights) > 0 {
+		return c.Format("milestone.weighted_reactions", "threshold", threshold, "reactions", weightsLabel(m.ReactionWeights))
+	}
+	if m.WindowSeconds > 0 {
+		return c.Format("milestone.reactions_in_window", "threshold", threshold, "window", windowLabel(c, m.WindowSeconds))
+	}
+	switch m.Type {
+	case MilestoneTypeTotalReactions, MilestoneTypeConcurrentUsers, MilestoneTypeSessionDuration, MilestoneTypeReactionVelocity:
+		return c.Format("milestone."+string(m.Type), "threshold", threshold)
+	}
+	// Custom types register an untranslated unit
+	if custom, ok := lookupCustom(m.Type); ok && custom.unit != "" {
+		return threshold + " " + custom.unit
+	}
+	return c.Format("milestone.unknown")
+}
+
+// LocalizedDescription returns the description in the catalog's language.
+// Descriptions set explicitly by a definition are returned unchanged.
+func (m *Milestone) LocalizedDescription(c *locales.Catalog) string {
+	if m.Description != m.describe(locales.Default()) {
+		return m.Description
+	}
+	return m.describe(c)
+}
+
+// windowLabel describes a velocity window, e.g. "one minute" or "30 seconds"
+func windowLabel(c *locales.Catalog, seconds int) string {
+	switch {
+	case seconds == 1:
+		return c.Format("window.one_second")
+	case seconds == 60:
+		return c.Format("window.one_minute")
+	case seconds%60 == 0:
+		return c.Format("window.minutes", "n", strconv.Itoa(seconds/60))
 	default:
-		return "Unknown milestone"
+		return c.Format("window.seconds", "n", strconv.Itoa(seconds))
 	}
 }
 
+// Window returns the velocity window of a reaction_velocity milestone
+func (m *Milestone) Window() time.Duration {
+	if m.WindowSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(m.WindowSeconds) * time.Second
+}
+
 // formatNumber formats a number with commas for readability
 func formatNumber(n int64) string {
 	return strconv.FormatInt(n, 10)
 }
 
+// sortedReactionTypes returns the weighted reaction types in a stable order
+func sortedReactionTypes(weights map[events.ReactionType]int64) []events.ReactionType {
+	types := make([]events.ReactionType, 0, len(weights))
+	for reactionType := range weights {
+		types = append(types, reactionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// weightsKey encodes reaction weights for milestone IDs, e.g. "applause1cheer2"
+func weightsKey(weights map[events.ReactionType]int64) string {
+	var b strings.Builder
+	for _, reactionType := range sortedReactionTypes(weights) {
+		b.WriteString(string(reactionType))
+		b.WriteString(strconv.FormatInt(weights[reactionType], 10))
+	}
+	return b.String()
+}
+
+// weightsLabel describes the counted reactions, e.g. "applause+cheer"
+func weightsLabel(weights map[events.ReactionType]int64) string {
+	parts := make([]string, 0, len(weights))
+	for _, reactionType := range sortedReactionTypes(weights) {
+		if weight := weights[reactionType]; weight != 1 {
+			parts = append(parts, fmt.Sprintf("%dx%s", weight, reactionType))
+		} else {
+			parts = append(parts, string(reactionType))
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+// ReactionValue computes the milestone's value from per-type reaction
+// counts, falling back to the grand total for unweighted milestones
+func (m *Milestone) ReactionValue(counts map[events.ReactionType]int64, total int64) int64 {
+	if len(m.ReactionWeights) == 0 {
+		return total
+	}
+	var value int64
+	for reactionType, weight := range m.ReactionWeights {
+		value += counts[reactionType] * weight
+	}
+	return value
+}
+
 // UpdateProgress updates the milestone progress
 func (m *Milestone) UpdateProgress(currentValue int64) bool {
 	m.Progress = currentValue
@@ -0,0 +1,88 @@
+package milestones
+
+import (
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinition_ValidatesReactionWeights(t *testing.T) {
+	weights := map[events.ReactionType]int64{events.ReactionApplause: 1, events.ReactionCheer: 2}
+	valid := Definition{Type: MilestoneTypeTotalReactions, Threshold: 100, ReactionWeights: weights}
+	assert.NoError(t, valid.Validate())
+
+	for name, definition := range map[string]Definition{
+		"not total reactions": {Type: MilestoneTypeConcurrentUsers, Threshold: 100, ReactionWeights: weights},
+		"unknown reaction":    {Type: MilestoneTypeTotalReactions, Threshold: 100, ReactionWeights: map[events.ReactionType]int64{"shrug": 1}},
+		"zero weight":         {Type: MilestoneTypeTotalReactions, Threshold: 100, ReactionWeights: map[events.ReactionType]int64{events.ReactionCheer: 0}},
+		"negative weight":     {Type: MilestoneTypeTotalReactions, Threshold: 100, ReactionWeights: map[events.ReactionType]int64{events.ReactionCheer: -2}},
+	} {
+		assert.Error(t, definition.Validate(), name)
+	}
+}
+
+func TestDefinition_BuildsWeightedMilestones(t *testing.T) {
+	milestone := Definition{
+		Type:            MilestoneTypeTotalReactions,
+		Threshold:       100,
+		ReactionWeights: map[events.ReactionType]int64{events.ReactionCheer: 2, events.ReactionApplause: 1},
+	}.Build("s1")
+
+	assert.Equal(t, "s1_total_reactions_100_applause1cheer2", milestone.ID)
+	assert.Equal(t, "100 applause+2xcheer reactions", milestone.Description)
+
+	counts := map[events.ReactionType]int64{events.ReactionApplause: 10, events.ReactionCheer: 5, events.ReactionFire: 50}
+	assert.Equal(t, int64(20), milestone.ReactionValue(counts, 65), "only weighted types count")
+	assert.Equal(t, int64(65), NewMilestone("s1", MilestoneTypeTotalReactions, 100).ReactionValue(counts, 65))
+}
+
+func TestTracker_AchievesWeightedMilestonesFromTheirReactions(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.InitializeSession("s1", nil)
+	tracker.AddMilestones("s1", []Definition{{
+		Type:            MilestoneTypeTotalReactions,
+		Threshold:       10,
+		ReactionWeights: map[events.ReactionType]int64{events.ReactionApplause: 5},
+	}})
+
+	stats := reactions("s1", 20)
+	tracker.CheckMilestones("s1", stats)
+	require.Len(t, tracker.GetAchievedMilestones("s1"), 0, "fire reactions carry no weight")
+
+	stats.IncrementReaction(events.ReactionApplause)
+	stats.IncrementReaction(events.ReactionApplause)
+	tracker.CheckMilestones("s1", stats)
+	achieved := tracker.GetAchievedMilestones("s1")
+	require.Len(t, achieved, 1)
+	assert.Equal(t, int64(10), achieved[0].Progress)
+}
+
+func TestPresentation_Validate(t *testing.T) {
+	var none *Presentation
+	assert.NoError(t, none.Validate())
+	for _, color := range []string{"", "#F50", "#FF5500", "#ff5500"} {
+		assert.NoError(t, (&Presentation{Color: color}).Validate(), color)
+	}
+	for _, color := range []string{"FF5500", "#FF550", "#GG5500", "red"} {
+		assert.Error(t, (&Presentation{Color: color}).Validate(), color)
+	}
+
+	invalid := Definition{Type: MilestoneTypeTotalReactions, Threshold: 10, Presentation: &Presentation{Color: "orange"}}
+	assert.Error(t, invalid.Validate())
+}
+
+func TestDefinition_BuildCarriesPresentation(t *testing.T) {
+	presentation := &Presentation{Icon: "trophy", Color: "#FF5500", AnimationID: "confetti", SoundCue: "fanfare"}
+	milestone := Definition{
+		Type:         MilestoneTypeConcurrentUsers,
+		Threshold:    50,
+		Description:  "Full house",
+		Presentation: presentation,
+	}.Build("s1")
+
+	assert.Equal(t, presentation, milestone.Presentation)
+	assert.Equal(t, "Full house", milestone.Description)
+	assert.Nil(t, NewMilestone("s1", MilestoneTypeConcurrentUsers, 50).Presentation)
+}
@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	goaway "github.com/TwiN/go-away"
+)
+
+// PatternFilter matches text against regular expressions
+type PatternFilter struct {
+	name     string
+	action   Action
+	patterns []*regexp.Regexp
+}
+
+// NewPatternFilter compiles patterns into a filter applying action to text
+// matching any of them. Masking replaces each match with asterisks.
+func NewPatternFilter(action Action, patterns []string) (*PatternFilter, error) {
+	f := &PatternFilter{name: "patterns", action: action}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content filter pattern %q: %v", pattern, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// NewWordListFilter matches whole words case-insensitively
+func NewWordListFilter(action Action, words []string) *PatternFilter {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	f := &PatternFilter{name: "words", action: action}
+	if len(quoted) > 0 {
+		f.patterns = []*regexp.Regexp{regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+	}
+	return f
+}
+
+// Check applies the filter's action if any pattern matches
+func (f *PatternFilter) Check(_ context.Context, content Content) (Verdict, error) {
+	text := content.Text
+	var matched string
+	for _, re := range f.patterns {
+		if match := re.FindString(text); match != "" {
+			if matched == "" {
+				matched = re.String()
+			}
+			if f.action == ActionMask {
+				text = re.ReplaceAllStringFunc(text, maskWord)
+			}
+		}
+	}
+	if matched == "" {
+		return Verdict{Action: ActionAllow}, nil
+	}
+	return Verdict{Action: f.action, Text: text, Filter: f.name, Reason: "matched " + matched}, nil
+}
+
+// maskWord hides a word, keeping its length
+func maskWord(word string) string {
+	return strings.Repeat("*", utf8.RuneCountInString(word))
+}
+
+// ProfanityFilter detects profanity with go-away's built-in dictionary,
+// including common obfuscations
+type ProfanityFilter struct {
+	action Action
+}
+
+// NewProfanityFilter creates a profanity filter applying action
+func NewProfanityFilter(action Action) *ProfanityFilter {
+	return &ProfanityFilter{action: action}
+}
+
+// Check applies the filter's action to profane text
+func (f *ProfanityFilter) Check(_ context.Context, content Content) (Verdict, error) {
+	if !goaway.IsProfane(content.Text) {
+		return Verdict{Action: ActionAllow}, nil
+	}
+	verdict := Verdict{Action: f.action, Text: content.Text, Filter: "profanity", Reason: "profanity"}
+	if f.action == ActionMask {
+		verdict.Text = goaway.Censor(content.Text)
+	}
+	return verdict, nil
+}
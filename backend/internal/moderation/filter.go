@@ -0,0 +1,86 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Action is what a filter does with the text it matched
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionMask   Action = "mask"   // publish with the matched text hidden
+	ActionFlag   Action = "flag"   // publish and queue for moderator review
+	ActionReject Action = "reject" // do not publish
+)
+
+// ParseAction validates a configured action
+func ParseAction(value string) (Action, error) {
+	switch action := Action(value); action {
+	case ActionAllow, ActionMask, ActionFlag, ActionReject:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown content filter action %q", value)
+}
+
+// Content is user-written text submitted with an event
+type Content struct {
+	SessionID string           `json:"session_id"`
+	UserID    string           `json:"user_id"`
+	EventType events.EventType `json:"event_type"`
+	Text      string           `json:"text"`
+}
+
+// Verdict is a filter's decision. Text is the text to publish, which masking
+// filters rewrite; Filter and Reason say what matched.
+type Verdict struct {
+	Action Action `json:"action"`
+	Text   string `json:"text,omitempty"`
+	Filter string `json:"filter,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ContentFilter inspects text events before they are published
+type ContentFilter interface {
+	Check(ctx context.Context, content Content) (Verdict, error)
+}
+
+// Chain runs filters in order. Each sees the text as masked by the ones
+// before it; the first rejection ends the chain, and a flag from any filter
+// flags the content. Filters that fail are skipped, so an unreachable
+// external filter does not stop chat.
+type Chain []ContentFilter
+
+// Check applies every filter in the chain
+func (c Chain) Check(ctx context.Context, content Content) Verdict {
+	result := Verdict{Action: ActionAllow, Text: content.Text}
+	for _, filter := range c {
+		verdict, err := filter.Check(ctx, content)
+		if err != nil {
+			log.Printf("Content filter error, skipping it: %v", err)
+			continue
+		}
+		switch verdict.Action {
+		case ActionReject:
+			verdict.Text = ""
+			return verdict
+		case ActionFlag:
+			result.Action, result.Filter, result.Reason = ActionFlag, verdict.Filter, verdict.Reason
+		case ActionMask:
+			if result.Action == ActionAllow {
+				result.Action, result.Filter, result.Reason = ActionMask, verdict.Filter, verdict.Reason
+			}
+		default:
+			continue
+		}
+		if verdict.Text != "" {
+			result.Text = verdict.Text
+			content.Text = verdict.Text
+		}
+	}
+	return result
+}
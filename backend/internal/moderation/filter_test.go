@@ -0,0 +1,77 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chat(text string) Content {
+	return Content{SessionID: "s1", UserID: "u1", EventType: events.EventTypeChat, Text: text}
+}
+
+func TestWordListFilter_MasksWholeWordsOnly(t *testing.T) {
+	filter := NewWordListFilter(ActionMask, []string{"spoiler", "leak"})
+
+	verdict, err := filter.Check(context.Background(), chat("SPOILER: the leak is real, not a leakage"))
+	require.NoError(t, err)
+	assert.Equal(t, ActionMask, verdict.Action)
+	assert.Equal(t, "*******: the **** is real, not a leakage", verdict.Text)
+
+	verdict, err = filter.Check(context.Background(), chat("nothing to see"))
+	require.NoError(t, err)
+	assert.Equal(t, ActionAllow, verdict.Action)
+}
+
+func TestChain_MasksThenFlagsThenRejects(t *testing.T) {
+	words := NewWordListFilter(ActionMask, []string{"darn"})
+	links, err := NewPatternFilter(ActionFlag, []string{`https?://`})
+	require.NoError(t, err)
+	scams, err := NewPatternFilter(ActionReject, []string{`(?i)free crypto`})
+	require.NoError(t, err)
+	chain := Chain{words, links, scams}
+
+	verdict := chain.Check(context.Background(), chat("darn, see https://example.com"))
+	assert.Equal(t, ActionFlag, verdict.Action)
+	assert.Equal(t, "****, see https://example.com", verdict.Text)
+	assert.Equal(t, "patterns", verdict.Filter)
+
+	verdict = chain.Check(context.Background(), chat("darn FREE CRYPTO here"))
+	assert.Equal(t, ActionReject, verdict.Action)
+	assert.Empty(t, verdict.Text)
+
+	verdict = chain.Check(context.Background(), chat("great show"))
+	assert.Equal(t, ActionAllow, verdict.Action)
+	assert.Equal(t, "great show", verdict.Text)
+}
+
+func TestHTTPFilter_UsesServiceVerdictAndFailsOpen(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var content Content
+		json.NewDecoder(r.Body).Decode(&content)
+		if content.Text == "boom" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(Verdict{Action: ActionMask, Text: "[removed]", Reason: "toxicity"})
+	}))
+	defer service.Close()
+	chain := Chain{NewHTTPFilter(service.URL, time.Second)}
+
+	verdict := chain.Check(context.Background(), chat("something rude"))
+	assert.Equal(t, ActionMask, verdict.Action)
+	assert.Equal(t, "[removed]", verdict.Text)
+	assert.Equal(t, "http", verdict.Filter)
+
+	// A failing service lets the message through unchanged
+	verdict = chain.Check(context.Background(), chat("boom"))
+	assert.Equal(t, ActionAllow, verdict.Action)
+	assert.Equal(t, "boom", verdict.Text)
+}
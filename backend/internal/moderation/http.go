@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPFilter delegates decisions to an external moderation service. The
+// service receives each Content as a JSON POST and answers with a Verdict;
+// masking verdicts must carry the rewritten text.
+type HTTPFilter struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPFilter creates a filter calling url, waiting at most timeout
+func NewHTTPFilter(url string, timeout time.Duration) *HTTPFilter {
+	return &HTTPFilter{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Check asks the service for a verdict
+func (f *HTTPFilter) Check(ctx context.Context, content Content) (Verdict, error) {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return Verdict{}, fmt.Errorf("content filter service returned status %d", resp.StatusCode)
+	}
+
+	var verdict Verdict
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("invalid content filter response: %v", err)
+	}
+	if _, err := ParseAction(string(verdict.Action)); err != nil {
+		return Verdict{}, err
+	}
+	if verdict.Action == ActionMask && verdict.Text == "" {
+		return Verdict{}, fmt.Errorf("content filter service masked without returning text")
+	}
+	if verdict.Filter == "" {
+		verdict.Filter = "http"
+	}
+	return verdict, nil
+}
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// nonPublicRanges are networks session webhooks may not reach besides
+// loopback, private, link-local and multicast addresses
+var nonPublicRanges = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),     // "this" network
+	mustCIDR("100.64.0.0/10"), // carrier-grade NAT
+	mustCIDR("192.0.0.0/24"),  // IETF protocol assignments
+	mustCIDR("198.18.0.0/15"), // benchmarking
+	mustCIDR("240.0.0.0/4"),   // reserved, including broadcast
+	mustCIDR("64:ff9b::/96"),  // NAT64, which can embed any IPv4 address
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// publicAddress reports whether ip is routable on the public internet, so
+// producer-supplied webhooks cannot reach the deployment's own network or
+// cloud metadata endpoints such as 169.254.169.254
+func publicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicRanges {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Replaced in tests, which deliver to local endpoints
+var (
+	allowAddress = publicAddress
+	lookupIP     = net.DefaultResolver.LookupIPAddr
+)
+
+// resolvePublic resolves host, failing unless every address it resolves to
+// may receive session webhooks
+func resolvePublic(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := lookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !allowAddress(addr.IP) {
+			return nil, fmt.Errorf("%s resolves to non-public address %s", host, addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// newSessionWebhookClient returns the client delivering to session
+// webhooks. Addresses are checked again when dialing, since the name may
+// resolve elsewhere than at registration, and every redirect is dialed the
+// same way. Proxies are not used, as they would dial on the client's behalf.
+func newSessionWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := resolvePublic(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("webhook redirected off https")
+			}
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. APNs rejects
+// tokens older than an hour and refreshes more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsProvider sends alerts to iOS devices through the Apple Push
+// Notification service, authenticating with a token signing key
+type APNsProvider struct {
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+	endpoint string
+	client   *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProvider creates a provider signing with the .p8 key identified by
+// keyID of the team, alerting the app with bundle ID topic. sandbox sends
+// through the development environment.
+func NewAPNsProvider(keyPEM []byte, keyID, teamID, topic string, sandbox bool) (*APNsProvider, error) {
+	der, err := decodePEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("apns key: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns key is not an ECDSA key")
+	}
+	endpoint := "https://api.push.apple.com"
+	if sandbox {
+		endpoint = "https://api.sandbox.push.apple.com"
+	}
+	return &APNsProvider{
+		keyID:    keyID,
+		teamID:   teamID,
+		topic:    topic,
+		key:      key,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Platform identifies the devices the provider reaches
+func (p *APNsProvider) Platform() string {
+	return PlatformAPNs
+}
+
+// Send delivers msg to the device with the device token
+func (p *APNsProvider) Send(ctx context.Context, token string, msg PushMessage) error {
+	bearer, err := p.providerToken()
+	if err != nil {
+		return fmt.Errorf("apns provider token: %w", err)
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&failure)
+	switch {
+	case resp.StatusCode == http.StatusGone, failure.Reason == "BadDeviceToken", failure.Reason == "Unregistered":
+		return ErrUnregistered
+	case failure.Reason == "ExpiredProviderToken":
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+	}
+	return fmt.Errorf("apns returned %d: %s", resp.StatusCode, failure.Reason)
+}
+
+// providerToken returns the signed token authenticating requests, signing a
+// new one once the current one is apnsTokenLifetime old
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.token != "" && now.Sub(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	token, err := signJWT(
+		map[string]string{"alg": "ES256", "kid": p.keyID},
+		map[string]interface{}{"iss": p.teamID, "iat": now.Unix()},
+		func(digest []byte) ([]byte, error) {
+			r, s, err := ecdsa.Sign(rand.Reader, p.key, digest)
+			if err != nil {
+				return nil, err
+			}
+			// JWS wants the fixed-width concatenation of r and s, not DER
+			size := (p.key.Curve.Params().BitSize + 7) / 8
+			signature := make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+			return signature, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.issuedAt = token, now
+	return token, nil
+}
@@ -0,0 +1,188 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fcmScope is the OAuth scope of the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMProvider sends alerts to Android and web devices through the Firebase
+// Cloud Messaging HTTP v1 API, authenticating as a service account
+type FCMProvider struct {
+	clientEmail string
+	key         *rsa.PrivateKey
+	tokenURL    string
+	endpoint    string
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// fcmCredentials is the part of a service account key file FCM needs
+type fcmCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCMProvider creates a provider from the JSON key of a service account
+// allowed to send messages for its project
+func NewFCMProvider(credentialsJSON []byte) (*FCMProvider, error) {
+	var creds fcmCredentials
+	if err := json.Unmarshal(credentialsJSON, &creds); err != nil {
+		return nil, fmt.Errorf("malformed service account key: %w", err)
+	}
+	if creds.ProjectID == "" || creds.ClientEmail == "" || creds.PrivateKey == "" {
+		return nil, errors.New("service account key needs project_id, client_email and private_key")
+	}
+	der, err := decodePEM([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("service account private_key: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("service account private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private_key is not an RSA key")
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &FCMProvider{
+		clientEmail: creds.ClientEmail,
+		key:         key,
+		tokenURL:    creds.TokenURI,
+		endpoint:    "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(creds.ProjectID) + "/messages:send",
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Platform identifies the devices the provider reaches
+func (p *FCMProvider) Platform() string {
+	return PlatformFCM
+}
+
+// Send delivers msg to the device with the registration token
+func (p *FCMProvider) Send(ctx context.Context, token string, msg PushMessage) error {
+	accessToken, err := p.authorize(ctx)
+	if err != nil {
+		return fmt.Errorf("fcm authorization: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrUnregistered
+	case resp.StatusCode == http.StatusUnauthorized:
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("fcm returned %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
+
+// authorize returns an OAuth access token, exchanging a signed assertion for
+// a new one shortly before the current one expires
+func (p *FCMProvider) authorize(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.accessToken != "" && now.Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	assertion, err := signJWT(
+		map[string]string{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   p.clientEmail,
+			"scope": fcmScope,
+			"aud":   p.tokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest)
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, detail)
+	}
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&grant); err != nil {
+		return "", err
+	}
+	if grant.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+	p.accessToken = grant.AccessToken
+	// Renew a minute early so requests in flight never carry an expired token
+	p.expiresAt = now.Add(time.Duration(grant.ExpiresIn)*time.Second - time.Minute)
+	return p.accessToken, nil
+}
@@ -0,0 +1,204 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// outboxClaimLimit bounds how many deliveries one poll attempts
+const outboxClaimLimit = 50
+
+// Store persists notifications until every endpoint has accepted them
+type Store interface {
+	EnqueueNotifications(ctx context.Context, deliveries []storage.NotificationDelivery) error
+	ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]storage.NotificationDelivery, error)
+	CompleteNotification(ctx context.Context, id int64) error
+	RetryNotification(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	FailNotification(ctx context.Context, id int64, lastError string) error
+	ListFailedNotifications(ctx context.Context, limit int) ([]storage.NotificationDelivery, error)
+	RedriveNotifications(ctx context.Context, ids []int64) (int64, error)
+}
+
+// RetryPolicy controls redelivery of notifications an endpoint did not
+// accept. The delay doubles after each failed attempt, from Backoff up to
+// MaxBackoff; after MaxAttempts the delivery fails until it is redriven.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// delay returns how long to wait after the given number of failed attempts
+func (p RetryPolicy) delay(attempts int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempts && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// outbox delivers notifications from a Store
+type outbox struct {
+	store  Store
+	policy RetryPolicy
+	wake   chan struct{}
+}
+
+// SetStore makes delivery durable: notifications are written to store before
+// they are sent and retried per policy until delivered. Start must be called
+// for them to be sent.
+func (n *WebhookNotifier) SetStore(store Store, policy RetryPolicy) {
+	n.outbox = &outbox{store: store, policy: policy, wake: make(chan struct{}, 1)}
+}
+
+// Start delivers stored notifications in the background, checking for due
+// retries every interval, until ctx is cancelled
+func (n *WebhookNotifier) Start(ctx context.Context, interval time.Duration) {
+	if n == nil || n.outbox == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			n.deliverDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-n.outbox.wake:
+			}
+		}
+	}()
+}
+
+// enqueue stores one delivery of body per target and wakes the sender.
+// Deliveries are signed when stored, so they stay verifiable with their
+// endpoint's secret after the endpoint is unregistered.
+func (n *WebhookNotifier) enqueue(event Event, body []byte, targets []webhookTarget) error {
+	now := time.Now().UTC()
+	deliveries := make([]storage.NotificationDelivery, 0, len(targets))
+	for _, target := range targets {
+		deliveries = append(deliveries, storage.NotificationDelivery{
+			Type:          event.Type,
+			SessionID:     event.SessionID,
+			URL:           target.url,
+			Body:          body,
+			Signature:     target.signature(body),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.outbox.store.EnqueueNotifications(ctx, deliveries); err != nil {
+		return err
+	}
+	n.outbox.signal()
+	return nil
+}
+
+// signal wakes the sender without blocking
+func (o *outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverDue sends every due delivery, in batches, until none are left
+func (n *WebhookNotifier) deliverDue(ctx context.Context) {
+	// A claim stays hidden until every attempt in its batch could have timed out
+	lease := 2 * n.client.Timeout
+	for ctx.Err() == nil {
+		deliveries, err := n.outbox.store.ClaimNotifications(ctx, outboxClaimLimit, lease)
+		if err != nil {
+			log.Printf("Error claiming webhook deliveries: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func(d storage.NotificationDelivery) {
+				defer wg.Done()
+				n.attempt(ctx, d)
+			}(d)
+		}
+		wg.Wait()
+
+		if len(deliveries) < outboxClaimLimit {
+			return
+		}
+	}
+}
+
+// attempt sends one claimed delivery and records the outcome
+func (n *WebhookNotifier) attempt(ctx context.Context, d storage.NotificationDelivery) {
+	sendCtx, cancel := context.WithTimeout(ctx, n.client.Timeout)
+	signature := d.Signature
+	if signature == "" {
+		// Stored before deliveries carried their signature
+		signature = webhookTarget{url: d.URL, secret: n.secret}.signature(d.Body)
+	}
+	err := n.send(sendCtx, d.URL, signature, d.Body)
+	cancel()
+
+	// Record the outcome even while shutting down so the delivery is not
+	// attempted again before its lease ends
+	storeCtx, storeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer storeCancel()
+
+	if err == nil {
+		if err := n.outbox.store.CompleteNotification(storeCtx, d.ID); err != nil {
+			log.Printf("Error completing webhook delivery %d: %v", d.ID, err)
+		}
+		return
+	}
+
+	policy := n.outbox.policy
+	if d.Attempts >= policy.MaxAttempts {
+		log.Printf("Webhook %s to %s failed after %d attempts: %v", d.Type, d.URL, d.Attempts, err)
+		if err := n.outbox.store.FailNotification(storeCtx, d.ID, err.Error()); err != nil {
+			log.Printf("Error failing webhook delivery %d: %v", d.ID, err)
+		}
+		return
+	}
+	next := time.Now().Add(policy.delay(d.Attempts))
+	if err := n.outbox.store.RetryNotification(storeCtx, d.ID, err.Error(), next); err != nil {
+		log.Printf("Error rescheduling webhook delivery %d: %v", d.ID, err)
+	}
+}
+
+// Failed returns up to limit deliveries that ran out of attempts, oldest
+// first. It returns nil when delivery is not durable.
+func (n *WebhookNotifier) Failed(ctx context.Context, limit int) ([]storage.NotificationDelivery, error) {
+	if n == nil || n.outbox == nil {
+		return nil, nil
+	}
+	return n.outbox.store.ListFailedNotifications(ctx, limit)
+}
+
+// Redrive retries failed deliveries from scratch, either those with the
+// given IDs or all of them when ids is empty, and returns how many it
+// requeued
+func (n *WebhookNotifier) Redrive(ctx context.Context, ids []int64) (int64, error) {
+	if n == nil || n.outbox == nil {
+		return 0, nil
+	}
+	count, err := n.outbox.store.RedriveNotifications(ctx, ids)
+	if err == nil && count > 0 {
+		n.outbox.signal()
+	}
+	return count, err
+}
+
+// Durable reports whether notifications are stored until delivered
+func (n *WebhookNotifier) Durable() bool {
+	return n != nil && n.outbox != nil
+}
@@ -0,0 +1,163 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Push platforms devices subscribe with
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// pushConcurrency bounds the alerts sent at once for one notification
+const pushConcurrency = 8
+
+// ErrUnregistered is returned by a PushProvider when the device token is no
+// longer valid, e.g. because the app was uninstalled
+var ErrUnregistered = errors.New("device token is no longer registered")
+
+// PushMessage is an alert shown on a subscribed device. Data is handed to
+// the app, e.g. to open the session the alert is about.
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushProvider sends alerts through one platform's push service
+type PushProvider interface {
+	Platform() string
+	Send(ctx context.Context, token string, msg PushMessage) error
+}
+
+// PushSubscriptionStore lists the devices subscribed to each session
+type PushSubscriptionStore interface {
+	ListPushSubscriptions(ctx context.Context, sessionID string) ([]storage.PushSubscription, error)
+	RemovePushToken(ctx context.Context, token string) error
+}
+
+// PushNotifier sends mobile push alerts to the devices subscribed to a
+// session when it goes live and when it reaches a milestone. It is a Sink
+// of the WebhookNotifier.
+type PushNotifier struct {
+	store     PushSubscriptionStore
+	providers map[string]PushProvider
+	timeout   time.Duration
+}
+
+// NewPushNotifier creates a notifier alerting the subscribers in store
+// through providers, one per platform
+func NewPushNotifier(store PushSubscriptionStore, providers ...PushProvider) *PushNotifier {
+	p := &PushNotifier{
+		store:     store,
+		providers: make(map[string]PushProvider, len(providers)),
+		timeout:   10 * time.Second,
+	}
+	for _, provider := range providers {
+		p.providers[provider.Platform()] = provider
+	}
+	return p
+}
+
+// Supports reports whether alerts can be sent to devices of platform
+func (p *PushNotifier) Supports(platform string) bool {
+	return p != nil && p.providers[platform] != nil
+}
+
+// Notify alerts the session's subscribers in the background if the event is
+// one devices are alerted of
+func (p *PushNotifier) Notify(event Event) {
+	if p == nil || event.SessionID == "" {
+		return
+	}
+	msg, ok := pushMessageFor(event)
+	if !ok {
+		return
+	}
+	go p.deliver(event, msg)
+}
+
+// deliver sends msg to every device subscribed to the event's session,
+// dropping the subscriptions of tokens the push service no longer knows
+func (p *PushNotifier) deliver(event Event, msg PushMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	subs, err := p.store.ListPushSubscriptions(ctx, event.SessionID)
+	cancel()
+	if err != nil {
+		log.Printf("Error listing push subscriptions of %s: %v", event.SessionID, err)
+		return
+	}
+
+	sem := make(chan struct{}, pushConcurrency)
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		provider := p.providers[sub.Platform]
+		if provider == nil {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(sub storage.PushSubscription) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+			defer cancel()
+			err := provider.Send(ctx, sub.Token, msg)
+			switch {
+			case errors.Is(err, ErrUnregistered):
+				if err := p.store.RemovePushToken(ctx, sub.Token); err != nil {
+					log.Printf("Error removing unregistered %s push token: %v", sub.Platform, err)
+				}
+			case err != nil:
+				log.Printf("Error sending %s push alert %s to a device of %s: %v", sub.Platform, event.Type, sub.UserID, err)
+			}
+		}(sub)
+	}
+	wg.Wait()
+}
+
+// pushFields are the parts of notification data alerts are written from
+type pushFields struct {
+	Name      string `json:"name"`
+	Milestone struct {
+		ID          string `json:"id"`
+		Description string `json:"description"`
+	} `json:"milestone"`
+}
+
+// pushMessageFor writes the alert for an event, reporting false for events
+// devices are not alerted of
+func pushMessageFor(event Event) (PushMessage, bool) {
+	var fields pushFields
+	if body, err := json.Marshal(event.Data); err == nil {
+		json.Unmarshal(body, &fields)
+	}
+	data := map[string]string{"type": event.Type, "session_id": event.SessionID}
+
+	switch event.Type {
+	case TypeSessionCreated:
+		title := "A stream you follow is live"
+		if fields.Name != "" {
+			title = fields.Name + " is live"
+		}
+		return PushMessage{Title: title, Body: "Tap to join in and react live.", Data: data}, true
+	case TypeMilestoneAchieved:
+		body := "The audience just reached a milestone!"
+		if fields.Milestone.Description != "" {
+			body = "We just hit " + fields.Milestone.Description + "!"
+		}
+		data["milestone_id"] = fields.Milestone.ID
+		return PushMessage{Title: "Milestone reached", Body: body, Data: data}, true
+	}
+	return PushMessage{}, false
+}
@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPushStore is an in-process PushSubscriptionStore
+type memoryPushStore struct {
+	mu   sync.Mutex
+	subs []storage.PushSubscription
+}
+
+func (m *memoryPushStore) ListPushSubscriptions(_ context.Context, sessionID string) ([]storage.PushSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []storage.PushSubscription
+	for _, sub := range m.subs {
+		if sub.SessionID == sessionID {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryPushStore) RemovePushToken(_ context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.subs[:0]
+	for _, sub := range m.subs {
+		if sub.Token != token {
+			kept = append(kept, sub)
+		}
+	}
+	m.subs = kept
+	return nil
+}
+
+// pushSent is one alert handed to a fakeProvider
+type pushSent struct {
+	token string
+	msg   PushMessage
+}
+
+// fakeProvider records alerts, rejecting tokens in unregistered
+type fakeProvider struct {
+	platform     string
+	unregistered map[string]bool
+	sent         chan pushSent
+}
+
+func (f *fakeProvider) Platform() string { return f.platform }
+
+func (f *fakeProvider) Send(_ context.Context, token string, msg PushMessage) error {
+	f.sent <- pushSent{token: token, msg: msg}
+	if f.unregistered[token] {
+		return ErrUnregistered
+	}
+	return nil
+}
+
+func TestPushNotifier_AlertsSubscribersThroughTheWebhookNotifier(t *testing.T) {
+	store := &memoryPushStore{subs: []storage.PushSubscription{
+		{UserID: "u1", SessionID: "s1", Platform: PlatformFCM, Token: "fcm-1"},
+		{UserID: "u2", SessionID: "s1", Platform: PlatformAPNs, Token: "apns-1"},
+		{UserID: "u3", SessionID: "s2", Platform: PlatformFCM, Token: "fcm-2"},
+	}}
+	provider := &fakeProvider{platform: PlatformFCM, unregistered: map[string]bool{"fcm-1": true}, sent: make(chan pushSent, 4)}
+
+	// No webhook URLs: sinks still see every notification
+	notifier := NewWebhookNotifier(nil, "")
+	notifier.AddSink(NewPushNotifier(store, provider))
+	notifier.Notify(Event{
+		Type:      TypeMilestoneAchieved,
+		SessionID: "s1",
+		Data: map[string]interface{}{
+			"milestone": map[string]interface{}{"id": "s1-m1", "description": "1000 reactions"},
+		},
+	})
+
+	select {
+	case sent := <-provider.sent:
+		assert.Equal(t, "fcm-1", sent.token, "only the session's devices on configured platforms are alerted")
+		assert.Equal(t, "We just hit 1000 reactions!", sent.msg.Body)
+		assert.Equal(t, map[string]string{"type": TypeMilestoneAchieved, "session_id": "s1", "milestone_id": "s1-m1"}, sent.msg.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no alert was sent")
+	}
+	assert.Eventually(t, func() bool {
+		subs, _ := store.ListPushSubscriptions(context.Background(), "s1")
+		return len(subs) == 1 && subs[0].Token == "apns-1"
+	}, 2*time.Second, 10*time.Millisecond, "the unregistered token is dropped")
+	assert.Empty(t, provider.sent)
+}
+
+func TestPushMessageFor(t *testing.T) {
+	msg, ok := pushMessageFor(Event{Type: TypeSessionCreated, SessionID: "s1", Data: map[string]string{"name": "Friday Trivia"}})
+	require.True(t, ok)
+	assert.Equal(t, "Friday Trivia is live", msg.Title)
+
+	msg, ok = pushMessageFor(Event{Type: TypeSessionCreated, SessionID: "s1"})
+	require.True(t, ok)
+	assert.Equal(t, "A stream you follow is live", msg.Title)
+
+	_, ok = pushMessageFor(Event{Type: TypeSessionEnded, SessionID: "s1"})
+	assert.False(t, ok, "devices are not alerted of every notification")
+}
+
+func TestAPNsProvider_SendsSignedAlerts(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	var requests []*http.Request
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests, bodies = append(requests, r), append(bodies, body)
+		if strings.HasSuffix(r.URL.Path, "/stale") {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAPNsProvider(keyPEM, "KEY123", "TEAM456", "com.example.livepulse", true)
+	require.NoError(t, err)
+	provider.endpoint = server.URL
+
+	msg := PushMessage{Title: "Friday Trivia is live", Body: "Tap to join in and react live.", Data: map[string]string{"session_id": "s1"}}
+	require.NoError(t, provider.Send(context.Background(), "device-1", msg))
+	assert.ErrorIs(t, provider.Send(context.Background(), "stale", msg), ErrUnregistered)
+
+	require.Len(t, requests, 2)
+	req := requests[0]
+	assert.Equal(t, "/3/device/device-1", req.URL.Path)
+	assert.Equal(t, "com.example.livepulse", req.Header.Get("apns-topic"))
+	assert.Equal(t, "alert", req.Header.Get("apns-push-type"))
+	assert.Equal(t, "s1", bodies[0]["session_id"])
+	assert.Equal(t, map[string]interface{}{"title": msg.Title, "body": msg.Body}, bodies[0]["aps"].(map[string]interface{})["alert"])
+
+	// The provider token is an ES256 JWT of the team, verifiable with the key
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "bearer ")
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.JSONEq(t, `{"alg":"ES256","kid":"KEY123"}`, string(header))
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.Contains(t, string(claims), `"iss":"TEAM456"`)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	require.Len(t, signature, 64)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+	assert.Equal(t, req.Header.Get("Authorization"), requests[1].Header.Get("Authorization"), "the provider token is reused")
+}
@@ -0,0 +1,231 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// MaxSessionWebhooks bounds the endpoints one session may register
+const MaxSessionWebhooks = 5
+
+// SessionWebhookTypes are the notification types a session's own webhooks
+// may subscribe to: those about that session alone
+var SessionWebhookTypes = []string{
+	TypeSessionCreated,
+	TypeSessionEnded,
+	TypeMilestoneAchieved,
+	TypeMilestoneUnlocked,
+	TypeSessionExported,
+}
+
+// SessionWebhook is an endpoint registered by a session's creator that
+// receives that session's notifications only, signed with its own secret
+type SessionWebhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Types     []string  `json:"types,omitempty"` // empty subscribes to every session type
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook receives notifications of a type
+func (h SessionWebhook) Subscribes(eventType string) bool {
+	if len(h.Types) == 0 {
+		return isSessionWebhookType(eventType)
+	}
+	for _, t := range h.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the webhook's URL, types and secret. The URL must be
+// https and resolve to public addresses only.
+func (h SessionWebhook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || u.Hostname() == "" || u.Scheme != "https" {
+		return errs.Validation("webhook url must be an https URL")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := resolvePublic(ctx, u.Hostname()); err != nil {
+		return errs.Validation("webhook url must resolve to a public address")
+	}
+	for _, t := range h.Types {
+		if !isSessionWebhookType(t) {
+			return errs.Validation("webhook type %q is not a session notification type", t)
+		}
+	}
+	if len(h.Secret) > 256 {
+		return errs.Validation("webhook secret exceeds 256 characters")
+	}
+	return nil
+}
+
+func isSessionWebhookType(eventType string) bool {
+	for _, t := range SessionWebhookTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionWebhookStore persists session webhooks so they survive restarts
+// and failovers along with their sessions
+type SessionWebhookStore interface {
+	SaveSessionWebhooks(ctx context.Context, hooks []storage.SessionWebhook) error
+	DeleteSessionWebhook(ctx context.Context, sessionID, id string) error
+	DeleteSessionWebhooks(ctx context.Context, sessionID string) error
+	ListSessionWebhooks(ctx context.Context) ([]storage.SessionWebhook, error)
+}
+
+// SetSessionWebhookStore stores session webhooks as they are registered
+// and removed. LoadSessionWebhooks restores those stored earlier.
+func (n *WebhookNotifier) SetSessionWebhookStore(store SessionWebhookStore) {
+	n.hookStore = store
+}
+
+// LoadSessionWebhooks restores the session webhooks in the store, e.g. on
+// startup or after promotion from standby
+func (n *WebhookNotifier) LoadSessionWebhooks(ctx context.Context) (int, error) {
+	if n == nil || n.hookStore == nil {
+		return 0, nil
+	}
+	stored, err := n.hookStore.ListSessionWebhooks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n.hooksMu.Lock()
+	defer n.hooksMu.Unlock()
+	for _, h := range stored {
+		n.sessionHooks[h.SessionID] = append(n.sessionHooks[h.SessionID], SessionWebhook{
+			ID:        h.ID,
+			URL:       h.URL,
+			Types:     h.Types,
+			Secret:    h.Secret,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+	return len(stored), nil
+}
+
+// RegisterSessionWebhooks adds endpoints receiving a session's
+// notifications. Webhooks without a secret are given a random one. It
+// returns the registered webhooks, whose secrets are not shown again.
+func (n *WebhookNotifier) RegisterSessionWebhooks(ctx context.Context, sessionID string, hooks []SessionWebhook) ([]SessionWebhook, error) {
+	if n == nil {
+		return nil, errs.Unavailable("webhooks are not configured")
+	}
+	for _, hook := range hooks {
+		if err := hook.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	n.hooksMu.Lock()
+	defer n.hooksMu.Unlock()
+	if len(n.sessionHooks[sessionID])+len(hooks) > MaxSessionWebhooks {
+		return nil, errs.Validation("a session may register at most %d webhooks", MaxSessionWebhooks)
+	}
+	now := time.Now().UTC()
+	registered := make([]SessionWebhook, 0, len(hooks))
+	for _, hook := range hooks {
+		hook.ID = uuid.New().String()
+		hook.CreatedAt = now
+		if hook.Secret == "" {
+			hook.Secret = newWebhookSecret()
+		}
+		registered = append(registered, hook)
+	}
+	if n.hookStore != nil {
+		stored := make([]storage.SessionWebhook, 0, len(registered))
+		for _, hook := range registered {
+			stored = append(stored, storage.SessionWebhook{
+				ID:        hook.ID,
+				SessionID: sessionID,
+				URL:       hook.URL,
+				Types:     hook.Types,
+				Secret:    hook.Secret,
+				CreatedAt: hook.CreatedAt,
+			})
+		}
+		if err := n.hookStore.SaveSessionWebhooks(ctx, stored); err != nil {
+			log.Printf("Error storing webhooks for session %s: %v", sessionID, err)
+			return nil, errs.Unavailable("failed to store webhooks")
+		}
+	}
+	n.sessionHooks[sessionID] = append(n.sessionHooks[sessionID], registered...)
+	return registered, nil
+}
+
+// SessionWebhooks returns the endpoints registered for a session
+func (n *WebhookNotifier) SessionWebhooks(sessionID string) []SessionWebhook {
+	if n == nil {
+		return nil
+	}
+	n.hooksMu.RLock()
+	defer n.hooksMu.RUnlock()
+	return append([]SessionWebhook(nil), n.sessionHooks[sessionID]...)
+}
+
+// RemoveSessionWebhook unregisters one of a session's endpoints, reporting
+// whether it was registered. Deliveries already stored are still sent.
+func (n *WebhookNotifier) RemoveSessionWebhook(ctx context.Context, sessionID, id string) bool {
+	if n == nil {
+		return false
+	}
+	n.hooksMu.Lock()
+	defer n.hooksMu.Unlock()
+	hooks := n.sessionHooks[sessionID]
+	for i, hook := range hooks {
+		if hook.ID == id {
+			hooks = append(hooks[:i:i], hooks[i+1:]...)
+			if len(hooks) == 0 {
+				delete(n.sessionHooks, sessionID)
+			} else {
+				n.sessionHooks[sessionID] = hooks
+			}
+			if n.hookStore != nil {
+				if err := n.hookStore.DeleteSessionWebhook(ctx, sessionID, id); err != nil {
+					log.Printf("Error removing stored webhook %s of session %s: %v", id, sessionID, err)
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveSessionWebhooks unregisters every endpoint of a session
+func (n *WebhookNotifier) RemoveSessionWebhooks(ctx context.Context, sessionID string) {
+	if n == nil {
+		return
+	}
+	n.hooksMu.Lock()
+	_, registered := n.sessionHooks[sessionID]
+	delete(n.sessionHooks, sessionID)
+	n.hooksMu.Unlock()
+	if registered && n.hookStore != nil {
+		if err := n.hookStore.DeleteSessionWebhooks(ctx, sessionID); err != nil {
+			log.Printf("Error removing stored webhooks of session %s: %v", sessionID, err)
+		}
+	}
+}
+
+// newWebhookSecret returns a random signing secret
+func newWebhookSecret() string {
+	var buf [32]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,168 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedWebhook is one request a test endpoint received
+type receivedWebhook struct {
+	body      []byte
+	signature string
+}
+
+func webhookEndpoint(t *testing.T) (string, chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 8)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{body: body, signature: r.Header.Get("X-LivePulse-Signature")}
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL, received
+}
+
+// sessionWebhookEndpoint is an https test endpoint that notifier's session
+// webhook client may reach despite being local
+func sessionWebhookEndpoint(t *testing.T, notifier *WebhookNotifier) (string, chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 8)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{body: body, signature: r.Header.Get("X-LivePulse-Signature")}
+	}))
+	t.Cleanup(ts.Close)
+
+	allowAddress = func(net.IP) bool { return true }
+	t.Cleanup(func() { allowAddress = publicAddress })
+	notifier.hooks.Transport.(*http.Transport).TLSClientConfig = ts.Client().Transport.(*http.Transport).TLSClientConfig
+	return ts.URL, received
+}
+
+func TestSessionWebhook_RejectsNonPublicAddresses(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "64:ff9b::a9fe:a9fe"} {
+		assert.False(t, publicAddress(net.ParseIP(ip)), ip)
+	}
+	assert.True(t, publicAddress(net.ParseIP("203.0.113.7")))
+	assert.True(t, publicAddress(net.ParseIP("2001:db8::1")))
+
+	assert.Error(t, SessionWebhook{URL: "http://203.0.113.7/hook"}.Validate(), "https is required")
+	assert.Error(t, SessionWebhook{URL: "https://169.254.169.254/latest/meta-data"}.Validate())
+	assert.NoError(t, SessionWebhook{URL: "https://203.0.113.7/hook"}.Validate())
+
+	// Names are checked again when dialing, in case they now resolve elsewhere
+	lookupIP = func(context.Context, string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+	t.Cleanup(func() { lookupIP = net.DefaultResolver.LookupIPAddr })
+	err := NewWebhookNotifier(nil, "").send(context.Background(), "https://rebound.example/hook", "", []byte("{}"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "non-public address")
+}
+
+func TestWebhookNotifier_DeliversToSessionWebhooksWithTheirSecrets(t *testing.T) {
+	globalURL, global := webhookEndpoint(t)
+	notifier := NewWebhookNotifier([]string{globalURL}, "deployment-secret")
+	sessionURL, scoped := sessionWebhookEndpoint(t, notifier)
+
+	_, err := notifier.RegisterSessionWebhooks(context.Background(), "s1", []SessionWebhook{{URL: sessionURL, Types: []string{"nope"}}})
+	assert.Error(t, err, "only session notification types can be subscribed to")
+	hooks, err := notifier.RegisterSessionWebhooks(context.Background(), "s1", []SessionWebhook{{URL: sessionURL, Types: []string{TypeMilestoneAchieved}, Secret: "s1-secret"}})
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.NotEmpty(t, hooks[0].ID)
+
+	wait := func(ch chan receivedWebhook) *receivedWebhook {
+		select {
+		case got := <-ch:
+			return &got
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	notifier.Notify(Event{Type: TypeMilestoneAchieved, SessionID: "s1"})
+	got := wait(scoped)
+	require.NotNil(t, got)
+	assert.Equal(t, "sha256="+Sign("s1-secret", got.body), got.signature, "session webhooks are signed with their own secret")
+	got = wait(global)
+	require.NotNil(t, got)
+	assert.Equal(t, "sha256="+Sign("deployment-secret", got.body), got.signature)
+
+	notifier.Notify(Event{Type: TypeMilestoneAchieved, SessionID: "s2"})
+	notifier.Notify(Event{Type: TypeSessionEnded, SessionID: "s1"})
+	require.NotNil(t, wait(global))
+	require.NotNil(t, wait(global))
+	assert.Nil(t, wait(scoped), "other sessions and unsubscribed types are not delivered")
+
+	assert.True(t, notifier.RemoveSessionWebhook(context.Background(), "s1", hooks[0].ID))
+	assert.Empty(t, notifier.SessionWebhooks("s1"))
+}
+
+type memoryHookStore struct {
+	hooks []storage.SessionWebhook
+}
+
+func (m *memoryHookStore) SaveSessionWebhooks(_ context.Context, hooks []storage.SessionWebhook) error {
+	m.hooks = append(m.hooks, hooks...)
+	return nil
+}
+
+func (m *memoryHookStore) DeleteSessionWebhook(_ context.Context, sessionID, id string) error {
+	for i, h := range m.hooks {
+		if h.SessionID == sessionID && h.ID == id {
+			m.hooks = append(m.hooks[:i], m.hooks[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryHookStore) DeleteSessionWebhooks(_ context.Context, sessionID string) error {
+	kept := m.hooks[:0]
+	for _, h := range m.hooks {
+		if h.SessionID != sessionID {
+			kept = append(kept, h)
+		}
+	}
+	m.hooks = kept
+	return nil
+}
+
+func (m *memoryHookStore) ListSessionWebhooks(context.Context) ([]storage.SessionWebhook, error) {
+	return m.hooks, nil
+}
+
+func TestWebhookNotifier_RestoresStoredSessionWebhooks(t *testing.T) {
+	store := &memoryHookStore{}
+	before := NewWebhookNotifier(nil, "")
+	before.SetSessionWebhookStore(store)
+	hooks, err := before.RegisterSessionWebhooks(context.Background(), "s1", []SessionWebhook{
+		{URL: "https://203.0.113.1/a"},
+		{URL: "https://203.0.113.1/b", Types: []string{TypeSessionEnded}},
+	})
+	require.NoError(t, err)
+	before.RegisterSessionWebhooks(context.Background(), "s2", []SessionWebhook{{URL: "https://203.0.113.1/c"}})
+	before.RemoveSessionWebhook(context.Background(), "s1", hooks[0].ID)
+	before.RemoveSessionWebhooks(context.Background(), "s2")
+
+	// A restarted or promoted instance picks up where the last one left off
+	after := NewWebhookNotifier(nil, "")
+	after.SetSessionWebhookStore(store)
+	restored, err := after.LoadSessionWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	require.Len(t, after.SessionWebhooks("s1"), 1)
+	assert.Equal(t, hooks[1].ID, after.SessionWebhooks("s1")[0].ID)
+	assert.Equal(t, hooks[1].Secret, after.SessionWebhooks("s1")[0].Secret, "deliveries stay signed with the same secret")
+	assert.Empty(t, after.SessionWebhooks("s2"))
+}
@@ -0,0 +1,198 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Lifecycle and achievement notification types
+const (
+	TypeSessionCreated    = "session.created"
+	TypeSessionEnded      = "session.ended"
+	TypeMilestoneAchieved = "milestone.achieved"
+	TypeMilestoneUnlocked = "milestone.unlocked"
+
+	TypeCampaignMilestoneAchieved = "campaign.milestone.achieved"
+
+	// TypeSessionExported carries a scheduled export of a live session
+	TypeSessionExported = "session.exported"
+)
+
+// Event is the JSON body delivered to webhook endpoints
+type Event struct {
+	Type       string      `json:"type"`
+	SessionID  string      `json:"session_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// WebhookNotifier delivers notifications to the configured webhook URLs
+type WebhookNotifier struct {
+	urls   []string
+	secret string
+	client *http.Client
+	hooks  *http.Client // delivers to session webhooks, public addresses only
+	outbox *outbox      // nil delivers each notification once, without storing it
+	sinks  []Sink
+
+	sessionHooks map[string][]SessionWebhook // session ID -> its own endpoints
+	hookStore    SessionWebhookStore         // nil keeps them in memory only
+	hooksMu      sync.RWMutex
+}
+
+// Sink receives every notification alongside the webhook endpoints, e.g. to
+// deliver it over another channel
+type Sink interface {
+	Notify(event Event)
+}
+
+// NewWebhookNotifier creates a notifier for the given endpoints. Requests are
+// signed with HMAC-SHA256 when a secret is configured.
+func NewWebhookNotifier(urls []string, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		urls:   urls,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+		hooks:  newSessionWebhookClient(10 * time.Second),
+
+		sessionHooks: make(map[string][]SessionWebhook),
+	}
+}
+
+// AddSink forwards every notification to sink. Sinks must be added before
+// notifications are sent.
+func (n *WebhookNotifier) AddSink(sink Sink) {
+	n.sinks = append(n.sinks, sink)
+}
+
+// Notify delivers an event to every endpoint in the background. With a
+// store, the event is stored for delivery first; if that fails it is still
+// sent once.
+func (n *WebhookNotifier) Notify(event Event) {
+	if n == nil {
+		return
+	}
+	for _, sink := range n.sinks {
+		sink.Notify(event)
+	}
+	targets := n.targets(event)
+	if len(targets) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling webhook event %s: %v", event.Type, err)
+		return
+	}
+
+	if n.outbox != nil {
+		err := n.enqueue(event, body, targets)
+		if err == nil {
+			return
+		}
+		log.Printf("Error storing webhook %s for delivery, sending without retries: %v", event.Type, err)
+	}
+
+	for _, target := range targets {
+		go func(target webhookTarget) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := n.send(ctx, target.url, target.signature(body), body); err != nil {
+				log.Printf("Error delivering webhook %s to %s: %v", event.Type, target.url, err)
+			}
+		}(target)
+	}
+}
+
+// webhookTarget is an endpoint a notification is delivered to, signed with
+// the endpoint's secret
+type webhookTarget struct {
+	url    string
+	secret string
+}
+
+// signature returns the X-LivePulse-Signature value for body, or "" when
+// the target has no secret
+func (t webhookTarget) signature(body []byte) string {
+	if t.secret == "" {
+		return ""
+	}
+	return "sha256=" + Sign(t.secret, body)
+}
+
+// targets returns the endpoints an event goes to: every deployment-wide
+// endpoint, then the event's session's own endpoints subscribed to its type
+func (n *WebhookNotifier) targets(event Event) []webhookTarget {
+	targets := make([]webhookTarget, 0, len(n.urls))
+	for _, url := range n.urls {
+		targets = append(targets, webhookTarget{url: url, secret: n.secret})
+	}
+	if event.SessionID == "" {
+		return targets
+	}
+
+	n.hooksMu.RLock()
+	defer n.hooksMu.RUnlock()
+	for _, hook := range n.sessionHooks[event.SessionID] {
+		if hook.Subscribes(event.Type) {
+			targets = append(targets, webhookTarget{url: hook.URL, secret: hook.Secret})
+		}
+	}
+	return targets
+}
+
+// send posts a single payload to one endpoint, with its signature if any
+func (n *WebhookNotifier) send(ctx context.Context, url, signature string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signature != "" {
+		req.Header.Set("X-LivePulse-Signature", signature)
+	}
+
+	client := n.hooks
+	if n.deploymentURL(url) {
+		client = n.client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deploymentURL reports whether url is a configured deployment-wide
+// endpoint, trusted to be anywhere, rather than a producer's session webhook
+func (n *WebhookNotifier) deploymentURL(url string) bool {
+	for _, u := range n.urls {
+		if u == url {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret, so
+// receivers can verify the X-LivePulse-Signature header
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// ArchiveV1From decodes an archived session into the v1 contract. Archives
+// are stored in the v1 shape, and those written before the contract existed
+// share its field names, so both decode alike.
+func ArchiveV1From(archived *storage.SessionSnapshot) (ArchiveV1, error) {
+	v1 := ArchiveV1{
+		SessionID:      archived.SessionID,
+		TenantID:       archived.TenantID,
+		Name:           archived.Name,
+		Tags:           archived.Tags,
+		PeakUsers:      archived.PeakUsers,
+		PeakAt:         archived.PeakAt,
+		TotalReactions: archived.TotalReactions,
+		EndedAt:        archived.EndedAt,
+	}
+	if len(archived.Snapshot) > 0 {
+		v1.Snapshot = &SnapshotV1{}
+		if err := json.Unmarshal(archived.Snapshot, v1.Snapshot); err != nil {
+			return ArchiveV1{}, fmt.Errorf("decoding snapshot of session %s: %w", archived.SessionID, err)
+		}
+	}
+	if len(archived.Milestones) > 0 {
+		if err := json.Unmarshal(archived.Milestones, &v1.Milestones); err != nil {
+			return ArchiveV1{}, fmt.Errorf("decoding milestones of session %s: %w", archived.SessionID, err)
+		}
+	}
+	if len(archived.Retention) > 0 {
+		if err := json.Unmarshal(archived.Retention, &v1.Retention); err != nil {
+			return ArchiveV1{}, fmt.Errorf("decoding retention of session %s: %w", archived.SessionID, err)
+		}
+	}
+	return v1, nil
+}
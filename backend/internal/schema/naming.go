@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// Naming is the case external JSON field names are written in. Only the
+// fields of schema types are renamed; map keys such as reaction types,
+// cohorts and dimension values are data and keep their case.
+type Naming string
+
+const (
+	NamingSnake Naming = "snake_case"
+	NamingCamel Naming = "camel_case"
+)
+
+// ParseNaming parses a Naming, accepting "snake" and "camel" as shorthands
+func ParseNaming(s string) (Naming, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "snake", "snake_case":
+		return NamingSnake, nil
+	case "camel", "camel_case", "camelcase":
+		return NamingCamel, nil
+	}
+	return "", errs.Validation("unknown field naming %q; expected snake_case or camel_case", s)
+}
+
+// Marshal encodes v with its field names in the given case
+func Marshal(v interface{}, naming Naming) ([]byte, error) {
+	if naming != NamingCamel {
+		return json.Marshal(v)
+	}
+	return json.Marshal(camelTree(reflect.ValueOf(v)))
+}
+
+// camelTree rebuilds v as generic JSON values with camelCase field names
+func camelTree(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return camelTree(v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty := parseTag(field)
+			if name == "-" || omitEmpty && (v.Field(i).IsZero() || isEmptyCollection(v.Field(i))) {
+				continue
+			}
+			fields[camelCase(name)] = camelTree(v.Field(i))
+		}
+		return fields
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[iter.Key().String()] = camelTree(iter.Value())
+		}
+		return entries
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = camelTree(v.Index(i))
+		}
+		return items
+	}
+	return v.Interface()
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// parseTag returns a field's JSON name and whether it is omitted when empty
+func parseTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+options+",", ",omitempty,")
+}
+
+// isEmptyCollection reports an empty but non-nil map or slice, which
+// omitempty also omits
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// camelCase converts a snake_case name to camelCase
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
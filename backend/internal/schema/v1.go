@@ -0,0 +1,307 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/milestones"
+)
+
+// V1 is the first versioned external contract. Its field names and types
+// match the JSON the API served before the contract existed, so existing
+// consumers parse it unchanged. Fields may be added to a version but are
+// never renamed, retyped or removed; those changes need a new version.
+const V1 = "v1"
+
+// SnapshotV1 is a session's statistics as served to external consumers
+type SnapshotV1 struct {
+	SessionID           string                            `json:"session_id"`
+	ActiveUserCount     int                               `json:"active_user_count"`
+	PeakConcurrentUsers int                               `json:"peak_concurrent_users"`
+	Peak                *PeakV1                           `json:"peak,omitempty"`
+	SustainedPeakUsers  int                               `json:"sustained_peak_users"`
+	TotalReactions      int64                             `json:"total_reactions"`
+	WeightedReactions   int64                             `json:"weighted_reactions"`
+	HypeScore           int64                             `json:"hype_score"`
+	Boost               *BoostV1                          `json:"boost,omitempty"`
+	ReactionCounts      map[string]int64                  `json:"reaction_counts"`
+	SystemReactions     *SystemReactionsV1                `json:"system_reactions,omitempty"`
+	StartTime           time.Time                         `json:"start_time"`
+	LastActivity        time.Time                         `json:"last_activity"`
+	DurationSeconds     float64                           `json:"duration_seconds"`
+	Version             int64                             `json:"version"`
+	Cohorts             map[string]CohortV1               `json:"cohorts,omitempty"`
+	UniqueUsers         int64                             `json:"unique_users"`
+	UniqueUsersApprox   bool                              `json:"unique_users_approximate,omitempty"`
+	Accuracy            string                            `json:"accuracy"`
+	ErrorBounds         *ErrorBoundsV1                    `json:"error_bounds,omitempty"`
+	WatchingUserCount   int                               `json:"watching_user_count"`
+	Presence            map[string]int                    `json:"presence,omitempty"`
+	Viewers             *ViewersV1                        `json:"viewers,omitempty"`
+	Dimensions          map[string]map[string]DimensionV1 `json:"dimensions,omitempty"`
+	Trend               *TrendV1                          `json:"trend,omitempty"`
+	ReactionSources     map[string]SourceV1               `json:"reaction_sources,omitempty"`
+	ClientVersions      map[string]int64                  `json:"client_versions,omitempty"` // joins per client app version
+}
+
+// PeakV1 is the moment a session reached its peak concurrency
+type PeakV1 struct {
+	Users              int       `json:"users"`
+	At                 time.Time `json:"at"`
+	ReactionsPerMinute int64     `json:"reactions_per_minute"`
+	TotalReactions     int64     `json:"total_reactions"`
+}
+
+// BoostV1 is a running boost of a session's reaction weights
+type BoostV1 struct {
+	ID         string    `json:"id"`
+	Multiplier int       `json:"multiplier"`
+	Label      string    `json:"label,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+// SystemReactionsV1 counts the reactions emitted by integrations
+type SystemReactionsV1 struct {
+	Total          int64            `json:"total"`
+	ReactionCounts map[string]int64 `json:"reaction_counts"`
+	Sources        map[string]int64 `json:"sources"`
+}
+
+// CohortV1 is one audience cohort's engagement
+type CohortV1 struct {
+	ActiveUserCount int              `json:"active_user_count"`
+	ReactionCounts  map[string]int64 `json:"reaction_counts"`
+}
+
+// ErrorBoundsV1 qualifies the numbers of approximate sessions
+type ErrorBoundsV1 struct {
+	UniqueUsersRelativeError  float64 `json:"unique_users_relative_error"`
+	UserReactionsMaxOvercount int64   `json:"user_reactions_max_overcount"`
+	UserReactionsConfidence   float64 `json:"user_reactions_confidence"`
+}
+
+// ViewersV1 splits a session's users into first-time and returning viewers
+type ViewersV1 struct {
+	FirstTime int64 `json:"first_time"`
+	Returning int64 `json:"returning"`
+}
+
+// DimensionV1 is the reactions carrying one value of a dimension
+type DimensionV1 struct {
+	TotalReactions int64            `json:"total_reactions"`
+	ReactionCounts map[string]int64 `json:"reaction_counts"`
+}
+
+// SourceV1 is the reactions sent from one UI surface
+type SourceV1 struct {
+	TotalReactions int64            `json:"total_reactions"`
+	ReactionCounts map[string]int64 `json:"reaction_counts"`
+}
+
+// TrendV1 compares the current reaction rate with the session's baseline
+type TrendV1 struct {
+	CurrentPerMinute  int64   `json:"current_per_minute"`
+	BaselinePerMinute float64 `json:"baseline_per_minute"`
+	ChangePercent     float64 `json:"change_percent"`
+	BaselineMinutes   int     `json:"baseline_minutes"`
+}
+
+// MilestoneV1 is a milestone and its progress as served to external
+// consumers
+type MilestoneV1 struct {
+	ID              string           `json:"id"`
+	SessionID       string           `json:"session_id"`
+	Type            string           `json:"type"`
+	Threshold       int64            `json:"threshold"`
+	Progress        int64            `json:"progress"`
+	Achieved        bool             `json:"achieved"`
+	AchievedAt      *time.Time       `json:"achieved_at,omitempty"`
+	Description     string           `json:"description"`
+	ReactionWeights map[string]int64 `json:"reaction_weights,omitempty"`
+	WindowSeconds   int              `json:"window_seconds,omitempty"`
+	Presentation    *PresentationV1  `json:"presentation,omitempty"`
+	Requires        []string         `json:"requires,omitempty"`
+	Locked          bool             `json:"locked,omitempty"`
+	UnlockedAt      *time.Time       `json:"unlocked_at,omitempty"`
+	Forecast        *ForecastV1      `json:"forecast,omitempty"`
+}
+
+// PresentationV1 is the branding of a milestone's celebration
+type PresentationV1 struct {
+	Icon        string `json:"icon,omitempty"`
+	Color       string `json:"color,omitempty"`
+	AnimationID string `json:"animation_id,omitempty"`
+	SoundCue    string `json:"sound_cue,omitempty"`
+	Priority    int    `json:"priority,omitempty"`
+}
+
+// ForecastV1 predicts when an open milestone will be achieved
+type ForecastV1 struct {
+	RatePerMinute float64    `json:"rate_per_minute"`
+	ETASeconds    float64    `json:"eta_seconds,omitempty"`
+	ExpectedAt    *time.Time `json:"expected_at,omitempty"`
+	OnTrack       *bool      `json:"on_track,omitempty"`
+}
+
+// countsOf copies counts keyed by a string type
+func countsOf[K ~string, V any](counts map[K]V) map[string]V {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[string]V, len(counts))
+	for k, v := range counts {
+		out[string(k)] = v
+	}
+	return out
+}
+
+// SnapshotV1From converts a snapshot to the v1 contract
+func SnapshotV1From(s aggregation.StatsSnapshot) SnapshotV1 {
+	v1 := SnapshotV1{
+		SessionID:           s.SessionID,
+		ActiveUserCount:     s.ActiveUserCount,
+		PeakConcurrentUsers: s.PeakConcurrentUsers,
+		SustainedPeakUsers:  s.SustainedPeakUsers,
+		TotalReactions:      s.TotalReactions,
+		WeightedReactions:   s.WeightedReactions,
+		HypeScore:           s.HypeScore,
+		ReactionCounts:      countsOf(s.ReactionCounts),
+		StartTime:           s.StartTime,
+		LastActivity:        s.LastActivity,
+		DurationSeconds:     s.Duration,
+		Version:             s.Version,
+		UniqueUsers:         s.UniqueUsers,
+		UniqueUsersApprox:   s.UniqueUsersApprox,
+		Accuracy:            string(s.Accuracy),
+		WatchingUserCount:   s.WatchingUserCount,
+		Presence:            countsOf(s.Presence),
+		ClientVersions:      s.ClientVersions,
+	}
+	if p := s.Peak; p != nil {
+		v1.Peak = &PeakV1{Users: p.Users, At: p.At, ReactionsPerMinute: p.ReactionsPerMinute, TotalReactions: p.TotalReactions}
+	}
+	if b := s.Boost; b != nil {
+		v1.Boost = &BoostV1{ID: b.ID, Multiplier: b.Multiplier, Label: b.Label, StartedAt: b.StartedAt, EndsAt: b.EndsAt}
+	}
+	if sys := s.SystemReactions; sys != nil {
+		v1.SystemReactions = &SystemReactionsV1{Total: sys.Total, ReactionCounts: countsOf(sys.ReactionCounts), Sources: sys.Sources}
+	}
+	if s.Cohorts != nil {
+		v1.Cohorts = make(map[string]CohortV1, len(s.Cohorts))
+		for cohort, c := range s.Cohorts {
+			v1.Cohorts[cohort] = CohortV1{ActiveUserCount: c.ActiveUserCount, ReactionCounts: countsOf(c.ReactionCounts)}
+		}
+	}
+	if b := s.ErrorBounds; b != nil {
+		v1.ErrorBounds = &ErrorBoundsV1{
+			UniqueUsersRelativeError:  b.UniqueUsersRelativeError,
+			UserReactionsMaxOvercount: b.UserReactionsMaxOvercount,
+			UserReactionsConfidence:   b.UserReactionsConfidence,
+		}
+	}
+	if v := s.Viewers; v != nil {
+		v1.Viewers = &ViewersV1{FirstTime: v.FirstTime, Returning: v.Returning}
+	}
+	if s.Dimensions != nil {
+		v1.Dimensions = make(map[string]map[string]DimensionV1, len(s.Dimensions))
+		for dimension, values := range s.Dimensions {
+			v1.Dimensions[dimension] = make(map[string]DimensionV1, len(values))
+			for value, d := range values {
+				v1.Dimensions[dimension][value] = DimensionV1{TotalReactions: d.TotalReactions, ReactionCounts: countsOf(d.ReactionCounts)}
+			}
+		}
+	}
+	if s.ReactionSources != nil {
+		v1.ReactionSources = make(map[string]SourceV1, len(s.ReactionSources))
+		for source, r := range s.ReactionSources {
+			v1.ReactionSources[string(source)] = SourceV1{TotalReactions: r.TotalReactions, ReactionCounts: countsOf(r.ReactionCounts)}
+		}
+	}
+	if t := s.Trend; t != nil {
+		v1.Trend = &TrendV1{
+			CurrentPerMinute:  t.CurrentPerMinute,
+			BaselinePerMinute: t.BaselinePerMinute,
+			ChangePercent:     t.ChangePercent,
+			BaselineMinutes:   t.BaselineMinutes,
+		}
+	}
+	return v1
+}
+
+// MilestoneV1From converts a milestone and its forecast, if any, to the v1
+// contract
+func MilestoneV1From(m *milestones.Milestone, forecast *milestones.Forecast) MilestoneV1 {
+	v1 := MilestoneV1{
+		ID:              m.ID,
+		SessionID:       m.SessionID,
+		Type:            string(m.Type),
+		Threshold:       m.Threshold,
+		Progress:        m.Progress,
+		Achieved:        m.Achieved,
+		AchievedAt:      m.AchievedAt,
+		Description:     m.Description,
+		ReactionWeights: countsOf(m.ReactionWeights),
+		WindowSeconds:   m.WindowSeconds,
+		Requires:        m.Requires,
+		Locked:          m.Locked,
+		UnlockedAt:      m.UnlockedAt,
+	}
+	if p := m.Presentation; p != nil {
+		v1.Presentation = &PresentationV1{Icon: p.Icon, Color: p.Color, AnimationID: p.AnimationID, SoundCue: p.SoundCue, Priority: p.Priority}
+	}
+	if f := forecast; f != nil {
+		v1.Forecast = &ForecastV1{RatePerMinute: f.RatePerMinute, ETASeconds: f.ETASeconds, ExpectedAt: f.ExpectedAt, OnTrack: f.OnTrack}
+	}
+	return v1
+}
+
+// MilestonesV1From converts a session's milestones to the v1 contract
+func MilestonesV1From(list []*milestones.Milestone) []MilestoneV1 {
+	v1 := make([]MilestoneV1, 0, len(list))
+	for _, m := range list {
+		v1 = append(v1, MilestoneV1From(m, nil))
+	}
+	return v1
+}
+
+// RetentionPointV1 is one minute of a session's audience drop-off curve
+type RetentionPointV1 struct {
+	Minute       int       `json:"minute"`
+	Start        time.Time `json:"start"`
+	Joined       int64     `json:"joined"`
+	Left         int64     `json:"left"`
+	Present      int64     `json:"present"`
+	RetentionPct float64   `json:"retention_pct"`
+}
+
+// RetentionV1From converts a drop-off curve to the v1 contract
+func RetentionV1From(curve []aggregation.RetentionPoint) []RetentionPointV1 {
+	v1 := make([]RetentionPointV1, 0, len(curve))
+	for _, p := range curve {
+		v1 = append(v1, RetentionPointV1{
+			Minute:       p.Minute,
+			Start:        p.Start,
+			Joined:       p.Joined,
+			Left:         p.Left,
+			Present:      p.Present,
+			RetentionPct: p.RetentionPct,
+		})
+	}
+	return v1
+}
+
+// ArchiveV1 is the final record of an ended session
+type ArchiveV1 struct {
+	SessionID      string             `json:"session_id"`
+	TenantID       string             `json:"tenant_id"`
+	Name           string             `json:"name"`
+	Tags           []string           `json:"tags,omitempty"`
+	PeakUsers      int                `json:"peak_users"`
+	PeakAt         *time.Time         `json:"peak_at,omitempty"`
+	TotalReactions int64              `json:"total_reactions"`
+	Snapshot       *SnapshotV1        `json:"snapshot,omitempty"`
+	Milestones     []MilestoneV1      `json:"milestones,omitempty"`
+	Retention      []RetentionPointV1 `json:"retention,omitempty"`
+	EndedAt        time.Time          `json:"ended_at"`
+}
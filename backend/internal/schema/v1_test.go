@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fill sets every exported field reachable from v to a non-zero value
+func fill(v reflect.Value) {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(3)
+	case reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fill(value)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, value)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+func topLevelKeys(t *testing.T, body []byte) []string {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSnapshotV1_MatchesLegacyJSON(t *testing.T) {
+	var snapshot aggregation.StatsSnapshot
+	fill(reflect.ValueOf(&snapshot).Elem())
+
+	legacy, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	v1, err := json.Marshal(SnapshotV1From(snapshot))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(legacy), string(v1))
+
+	assert.Equal(t, []string{
+		"accuracy", "active_user_count", "boost", "client_versions", "cohorts", "dimensions", "duration_seconds",
+		"error_bounds", "hype_score", "last_activity", "peak", "peak_concurrent_users", "presence",
+		"reaction_counts", "reaction_sources", "session_id", "start_time", "sustained_peak_users", "system_reactions", "total_reactions",
+		"trend", "unique_users", "unique_users_approximate", "version", "viewers",
+		"watching_user_count", "weighted_reactions",
+	}, topLevelKeys(t, v1))
+}
+
+func TestMilestoneV1_MatchesLegacyJSON(t *testing.T) {
+	var milestone milestones.Milestone
+	var forecast milestones.Forecast
+	fill(reflect.ValueOf(&milestone).Elem())
+	fill(reflect.ValueOf(&forecast).Elem())
+
+	legacy, err := json.Marshal(struct {
+		*milestones.Milestone
+		Forecast *milestones.Forecast `json:"forecast,omitempty"`
+	}{&milestone, &forecast})
+	require.NoError(t, err)
+	v1, err := json.Marshal(MilestoneV1From(&milestone, &forecast))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(legacy), string(v1))
+}
+
+func TestMarshal_CamelCaseRenamesFieldsButNotData(t *testing.T) {
+	snapshot := SnapshotV1From(aggregation.StatsSnapshot{
+		SessionID:       "s1",
+		ActiveUserCount: 2,
+		ReactionCounts:  map[events.ReactionType]int64{"thumbs_up": 4},
+		Cohorts:         map[string]aggregation.CohortStats{"vip_members": {ActiveUserCount: 1}},
+	})
+
+	body, err := Marshal(snapshot, NamingCamel)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+
+	assert.Equal(t, "s1", decoded["sessionId"])
+	assert.EqualValues(t, 2, decoded["activeUserCount"])
+	assert.Equal(t, map[string]interface{}{"thumbs_up": 4.0}, decoded["reactionCounts"])
+	assert.Contains(t, decoded["cohorts"], "vip_members")
+	assert.Contains(t, decoded, "startTime")
+	assert.NotContains(t, decoded, "peak")
+	assert.NotContains(t, decoded, "active_user_count")
+
+	body, err = Marshal(snapshot, NamingSnake)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"active_user_count":2`)
+}
+
+func TestParseNaming(t *testing.T) {
+	naming, err := ParseNaming("camel")
+	require.NoError(t, err)
+	assert.Equal(t, NamingCamel, naming)
+
+	naming, err = ParseNaming("")
+	require.NoError(t, err)
+	assert.Equal(t, NamingSnake, naming)
+
+	_, err = ParseNaming("kebab")
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package serverless
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	awsevents "github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Handler ingests events in AWS Lambda, triggered by API Gateway (REST or
+// HTTP APIs) or an SQS queue. Bodies are an api.IngestFrame or a single
+// api.IngestEvent; events are validated as the cluster would and written
+// to the sink.
+type Handler struct {
+	sink Sink
+}
+
+// NewHandler creates a handler writing to sink
+func NewHandler(sink Sink) *Handler {
+	return &Handler{sink: sink}
+}
+
+// trigger holds enough of an invocation payload to tell its source apart
+type trigger struct {
+	Records []struct {
+		EventSource string `json:"eventSource"`
+	} `json:"Records"`
+	Version        string          `json:"version"`
+	RequestContext json.RawMessage `json:"requestContext"`
+}
+
+// Handle processes one invocation, for lambda.Start
+func (h *Handler) Handle(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var t trigger
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("decoding invocation: %w", err)
+	}
+
+	switch {
+	case len(t.Records) > 0 && t.Records[0].EventSource == "aws:sqs":
+		var event awsevents.SQSEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decoding SQS event: %w", err)
+		}
+		return h.handleSQS(ctx, event), nil
+	case t.RequestContext != nil && t.Version == "2.0":
+		var req awsevents.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("decoding API Gateway request: %w", err)
+		}
+		status, body := h.handleHTTP(ctx, req.Body, req.IsBase64Encoded)
+		return awsevents.APIGatewayV2HTTPResponse{StatusCode: status, Headers: jsonHeaders, Body: body}, nil
+	case t.RequestContext != nil:
+		var req awsevents.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("decoding API Gateway request: %w", err)
+		}
+		status, body := h.handleHTTP(ctx, req.Body, req.IsBase64Encoded)
+		return awsevents.APIGatewayProxyResponse{StatusCode: status, Headers: jsonHeaders, Body: body}, nil
+	}
+	return nil, fmt.Errorf("unsupported trigger: expected an API Gateway request or SQS event")
+}
+
+var jsonHeaders = map[string]string{"Content-Type": "application/json"}
+
+// handleSQS writes each message's events, reporting the messages whose
+// write failed so only those are redelivered. This needs the event source
+// mapping's ReportBatchItemFailures response type. Malformed or invalid
+// events, and batches the deployment rejects, are dropped, since
+// redelivering them cannot help.
+func (h *Handler) handleSQS(ctx context.Context, event awsevents.SQSEvent) awsevents.SQSEventResponse {
+	response := awsevents.SQSEventResponse{BatchItemFailures: []awsevents.SQSBatchItemFailure{}}
+	for _, msg := range event.Records {
+		batch, err := parseBatch([]byte(msg.Body))
+		if err != nil {
+			log.Printf("Dropping malformed SQS message %s: %v", msg.MessageId, err)
+			continue
+		}
+		// Redelivered messages keep their ID, so derived event IDs let the
+		// cluster discard events a failed attempt already wrote
+		messageID := msg.MessageId
+		valid, rejections := validate(batch, func(i int) string {
+			return uuid.NewSHA1(uuid.NameSpaceOID, []byte(messageID+"/"+strconv.Itoa(i))).String()
+		})
+		if len(rejections) > 0 {
+			log.Printf("Dropping %d invalid events of SQS message %s, e.g. %s", len(rejections), messageID, rejections[0].Error)
+		}
+		if len(valid) == 0 {
+			continue
+		}
+		if err := h.sink.Write(ctx, valid); errors.Is(err, ErrBatchRejected) {
+			log.Printf("Dropping events of SQS message %s: %v", messageID, err)
+		} else if err != nil {
+			log.Printf("Failed to write events of SQS message %s: %v", messageID, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, awsevents.SQSBatchItemFailure{ItemIdentifier: messageID})
+		}
+	}
+	return response
+}
+
+// handleHTTP writes a request's events, returning the status and body of
+// the response: the batch result, or an api.ErrorResponse
+func (h *Handler) handleHTTP(ctx context.Context, body string, isBase64 bool) (int, string) {
+	data := []byte(body)
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return errorBody(http.StatusBadRequest, errs.Validation("invalid request body"))
+		}
+		data = decoded
+	}
+	batch, err := parseBatch(data)
+	if err != nil {
+		return errorBody(http.StatusBadRequest, err)
+	}
+
+	valid, rejections := validate(batch, func(int) string { return events.NewEventID() })
+	if len(valid) > 0 {
+		if err := h.sink.Write(ctx, valid); errors.Is(err, ErrBatchRejected) {
+			log.Printf("Failed to write %d events: %v", len(valid), err)
+			return errorBody(http.StatusBadGateway, errs.Unavailable("events were refused by the deployment"))
+		} else if err != nil {
+			log.Printf("Failed to write %d events: %v", len(valid), err)
+			return errorBody(http.StatusServiceUnavailable, errs.Unavailable("events could not be delivered, retry later"))
+		}
+	}
+	result, _ := json.Marshal(api.IngestBatchResult{Accepted: len(valid), Rejected: len(rejections), Rejections: rejections})
+	return http.StatusOK, string(result)
+}
+
+// errorBody returns a status with an api.ErrorResponse body for err
+func errorBody(status int, err error) (int, string) {
+	body, _ := json.Marshal(api.ErrorResponse{Code: errs.CodeOf(err), Message: err.Error()})
+	return status, string(body)
+}
+
+// parseBatch decodes an api.IngestFrame, or a single api.IngestEvent
+func parseBatch(data []byte) ([]api.IngestEvent, error) {
+	var frame api.IngestFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return nil, errs.Validation("invalid request body")
+	}
+	if frame.Events == nil {
+		var event api.IngestEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, errs.Validation("invalid request body")
+		}
+		frame.Events = []api.IngestEvent{event}
+	}
+	if len(frame.Events) == 0 {
+		return nil, errs.Validation("events are required")
+	}
+	if len(frame.Events) > api.MaxIngestBatch {
+		return nil, errs.Validation("a batch carries at most %d events", api.MaxIngestBatch)
+	}
+	return frame.Events, nil
+}
+
+// validate splits a batch into the events the cluster would accept and
+// rejections for the rest. Events without an ID get one from idFor, so a
+// retried write is deduplicated instead of counted twice.
+func validate(batch []api.IngestEvent, idFor func(i int) string) ([]api.IngestEvent, []api.IngestRejection) {
+	var valid []api.IngestEvent
+	var rejections []api.IngestRejection
+	for i, in := range batch {
+		if in.EventID == "" {
+			in.EventID = idFor(i)
+		}
+		if _, err := in.ToEvent(); err != nil {
+			rejections = append(rejections, api.IngestRejection{Index: i, Error: err.Error(), Code: errs.CodeOf(err)})
+			continue
+		}
+		valid = append(valid, in)
+	}
+	return valid, rejections
+}
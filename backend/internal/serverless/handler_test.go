@@ -0,0 +1,136 @@
+package serverless
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	awsevents "github.com/aws/aws-lambda-go/events"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps the batches written, failing those of a session
+type recordingSink struct {
+	batches     [][]api.IngestEvent
+	failSession string
+}
+
+func (s *recordingSink) Write(ctx context.Context, batch []api.IngestEvent) error {
+	if batch[0].SessionID == s.failSession {
+		return errors.New("sink unavailable")
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func invoke(t *testing.T, h *Handler, payload interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	response, err := h.Handle(context.Background(), data)
+	require.NoError(t, err)
+	return response
+}
+
+func TestHandler_APIGatewayValidatesAndWrites(t *testing.T) {
+	sink := &recordingSink{}
+	h := NewHandler(sink)
+
+	body := `{"events":[{"type":"reaction","session_id":"s1","user_id":"u1","reaction_type":"fire"},{"type":"reaction","session_id":"s1","user_id":"u1","reaction_type":"bogus"}]}`
+	response := invoke(t, h, map[string]interface{}{
+		"version":         "2.0",
+		"requestContext":  map[string]interface{}{"http": map[string]string{"method": "POST"}},
+		"body":            base64.StdEncoding.EncodeToString([]byte(body)),
+		"isBase64Encoded": true,
+	})
+
+	resp, ok := response.(awsevents.APIGatewayV2HTTPResponse)
+	require.True(t, ok, "HTTP API requests get HTTP API responses")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result api.IngestBatchResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &result))
+	assert.Equal(t, 1, result.Accepted)
+	require.Len(t, result.Rejections, 1)
+	assert.Equal(t, 1, result.Rejections[0].Index)
+	assert.Equal(t, errs.CodeValidation, result.Rejections[0].Code)
+
+	require.Len(t, sink.batches, 1)
+	require.Len(t, sink.batches[0], 1)
+	assert.NotEmpty(t, sink.batches[0][0].EventID, "events are given IDs so retries are deduplicated")
+
+	// REST APIs, single events and failing sinks
+	sink.failSession = "s2"
+	response = invoke(t, h, map[string]interface{}{
+		"httpMethod":     "POST",
+		"requestContext": map[string]string{"stage": "prod"},
+		"body":           `{"type":"join_session","session_id":"s2","user_id":"u1"}`,
+	})
+	proxy, ok := response.(awsevents.APIGatewayProxyResponse)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, proxy.StatusCode)
+
+	response = invoke(t, h, map[string]interface{}{"requestContext": map[string]string{}, "body": "not json"})
+	assert.Equal(t, http.StatusBadRequest, response.(awsevents.APIGatewayProxyResponse).StatusCode)
+}
+
+func TestHandler_SQSReportsOnlyFailedMessages(t *testing.T) {
+	sink := &recordingSink{failSession: "s2"}
+	h := NewHandler(sink)
+
+	event := awsevents.SQSEvent{Records: []awsevents.SQSMessage{
+		{MessageId: "m1", EventSource: "aws:sqs", Body: `{"type":"reaction","session_id":"s1","user_id":"u1","reaction_type":"fire"}`},
+		{MessageId: "m2", EventSource: "aws:sqs", Body: `{"type":"reaction","session_id":"s2","user_id":"u1","reaction_type":"fire"}`},
+		{MessageId: "m3", EventSource: "aws:sqs", Body: `garbage`},
+	}}
+	resp, ok := invoke(t, h, event).(awsevents.SQSEventResponse)
+	require.True(t, ok)
+	require.Len(t, resp.BatchItemFailures, 1, "malformed messages are dropped rather than redelivered")
+	assert.Equal(t, "m2", resp.BatchItemFailures[0].ItemIdentifier)
+
+	// A redelivered message gets the same event IDs
+	firstID := sink.batches[0][0].EventID
+	event.Records = event.Records[:1]
+	invoke(t, h, event)
+	require.Len(t, sink.batches, 2)
+	assert.Equal(t, firstID, sink.batches[1][0].EventID)
+}
+
+func TestForwardSink_RejectsBatchesOnClientErrors(t *testing.T) {
+	var status int
+	var authorization string
+	cluster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"accepted":1}`))
+	}))
+	defer cluster.Close()
+	sink := NewForwardSink(cluster.URL, "ingest-key-1")
+	batch := []api.IngestEvent{{Type: "reaction", SessionID: "s1", UserID: "u1", ReactionType: "fire"}}
+
+	status = http.StatusOK
+	require.NoError(t, sink.Write(context.Background(), batch))
+	assert.Equal(t, "Bearer ingest-key-1", authorization)
+
+	status = http.StatusUnauthorized
+	assert.ErrorIs(t, sink.Write(context.Background(), batch), ErrBatchRejected)
+	for _, retryable := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		status = retryable
+		err := sink.Write(context.Background(), batch)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrBatchRejected, "%d is retried", retryable)
+	}
+
+	// SQS does not redeliver rejected batches forever
+	status = http.StatusForbidden
+	resp := invoke(t, NewHandler(sink), awsevents.SQSEvent{Records: []awsevents.SQSMessage{
+		{MessageId: "m1", EventSource: "aws:sqs", Body: `{"type":"reaction","session_id":"s1","user_id":"u1","reaction_type":"fire"}`},
+	}}).(awsevents.SQSEventResponse)
+	assert.Empty(t, resp.BatchItemFailures)
+}
@@ -0,0 +1,128 @@
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// Sink delivers validated events to a LivePulse deployment. An error means
+// the batch should be retried, unless it wraps ErrBatchRejected; events
+// already delivered are deduplicated by their IDs.
+type Sink interface {
+	Write(ctx context.Context, batch []api.IngestEvent) error
+}
+
+// ErrBatchRejected is returned for batches the deployment refused outright,
+// e.g. for a bad credential, which retrying cannot fix
+var ErrBatchRejected = errors.New("batch rejected")
+
+// ForwardSink posts batches to a cluster's ingestion API as a producer
+type ForwardSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewForwardSink creates a sink posting to the cluster at baseURL with one
+// of the cluster's ingest keys
+func NewForwardSink(baseURL, token string) *ForwardSink {
+	return &ForwardSink{
+		url:    strings.TrimSuffix(baseURL, "/") + "/api/ingest/events",
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Write posts the batch. Events the cluster rejects as invalid are logged
+// and dropped; a full queue fails the batch so it is retried. Client errors
+// other than timeouts and rate limits reject the whole batch.
+func (s *ForwardSink) Write(ctx context.Context, batch []api.IngestEvent) error {
+	body, err := json.Marshal(api.IngestFrame{Events: batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return fmt.Errorf("%w: cluster returned %d: %s", ErrBatchRejected, resp.StatusCode, detail)
+		}
+		return fmt.Errorf("cluster returned %d: %s", resp.StatusCode, detail)
+	}
+
+	var result api.IngestBatchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding ingestion result: %w", err)
+	}
+	for _, rejection := range result.Rejections {
+		if rejection.Code == errs.CodeQueueFull {
+			return errs.ErrQueueFull
+		}
+	}
+	if result.Rejected > 0 {
+		log.Printf("Cluster rejected %d of %d forwarded events, e.g. %s", result.Rejected, len(batch), result.Rejections[0].Error)
+	}
+	return nil
+}
+
+// StreamAppender appends entries to a Redis stream
+type StreamAppender interface {
+	AppendStream(ctx context.Context, stream string, payload []byte, maxLen int64) error
+}
+
+// StreamSink appends events to the stream clusters read with stream
+// ingestion, skipping the cluster's HTTP API entirely
+type StreamSink struct {
+	appender StreamAppender
+	stream   string
+	maxLen   int64
+}
+
+// NewStreamSink creates a sink appending to stream, trimmed to roughly
+// maxLen entries
+func NewStreamSink(appender StreamAppender, stream string, maxLen int64) *StreamSink {
+	return &StreamSink{appender: appender, stream: stream, maxLen: maxLen}
+}
+
+// Write appends each event as the JSON stream ingestion decodes
+func (s *StreamSink) Write(ctx context.Context, batch []api.IngestEvent) error {
+	for _, in := range batch {
+		event, err := in.ToEvent()
+		if err != nil {
+			continue // validated by the handler already
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := s.appender.AppendStream(ctx, s.stream, data, s.maxLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sessions
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// MaxAliases bounds how many alias IDs one session can be simulcast under
+const MaxAliases = 16
+
+// AliasIDs namespaces the local alias IDs of a session by its tenant,
+// rejecting malformed or repeated ones and the session's own ID. Aliases
+// always belong to the tenant of the session they mirror.
+func AliasIDs(sessionID string, aliases []string) ([]string, error) {
+	if len(aliases) > MaxAliases {
+		return nil, errs.Validation("a session may have at most %d aliases", MaxAliases)
+	}
+	tenantID := TenantOf(sessionID)
+	var ids []string
+	for _, alias := range aliases {
+		if strings.Contains(alias, NamespaceSeparator) {
+			return nil, errs.Validation("alias %q must not contain a tenant prefix", alias)
+		}
+		id := NamespacedID(tenantID, alias)
+		if err := ValidateID(id); err != nil {
+			return nil, err
+		}
+		if id == sessionID || slices.Contains(ids, id) {
+			return nil, errs.Validation("alias %q repeats a session ID", alias)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// checkAliasesLocked reports an alias that is already a session or mirrors
+// a session other than id. Callers must hold r.mu.
+func (r *Registry) checkAliasesLocked(id string, aliases []string) error {
+	for _, alias := range aliases {
+		if _, exists := r.sessions[alias]; exists {
+			return errs.Conflict("alias %s is already a session", alias)
+		}
+		if canonical, taken := r.aliases[alias]; taken && canonical != id {
+			return errs.Conflict("alias %s already mirrors session %s", alias, canonical)
+		}
+	}
+	return nil
+}
+
+// CheckAliases reports whether aliases, as returned by AliasIDs, are free
+// to mirror session id
+func (r *Registry) CheckAliases(id string, aliases []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.checkAliasesLocked(id, aliases)
+}
+
+// SetAliases replaces the IDs a session is simulcast under. Events sent to
+// an alias count toward the session, and its broadcasts reach the clients
+// of every alias.
+func (r *Registry) SetAliases(id string, aliases []string) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists {
+		return Session{}, errs.NotFound("session %s not found", id)
+	}
+	if session.Status == StatusEnded {
+		return Session{}, errs.Conflict("session %s has ended", id)
+	}
+	if err := r.checkAliasesLocked(id, aliases); err != nil {
+		return Session{}, err
+	}
+	for _, alias := range session.Aliases {
+		delete(r.aliases, alias)
+	}
+	session.Aliases = slices.Clone(aliases)
+	r.indexAliasesLocked(session)
+	return *session, nil
+}
+
+// indexAliasesLocked maps the session's aliases to it. Callers must hold
+// r.mu.
+func (r *Registry) indexAliasesLocked(session *Session) {
+	for _, alias := range session.Aliases {
+		r.aliases[alias] = session.ID
+	}
+}
+
+// Canonical returns the session an alias mirrors, or id itself if it is not
+// an alias
+func (r *Registry) Canonical(id string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if canonical, ok := r.aliases[id]; ok {
+		return canonical
+	}
+	return id
+}
+
+// Channels returns every ID clients may watch a session under: its
+// canonical ID followed by its aliases
+func (r *Registry) Channels(id string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if canonical, ok := r.aliases[id]; ok {
+		id = canonical
+	}
+	session, exists := r.sessions[id]
+	if !exists || len(session.Aliases) == 0 {
+		return []string{id}
+	}
+	return append([]string{id}, session.Aliases...)
+}
@@ -0,0 +1,63 @@
+package sessions
+
+import (
+	"sort"
+	"time"
+)
+
+// Ban bars a user from taking part in a session
+type Ban struct {
+	UserID   string    `json:"user_id"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedAt time.Time `json:"banned_at"`
+}
+
+// Ban bars a user from a session. It returns false if they already were.
+func (r *Registry) Ban(sessionID, userID, reason string) (Ban, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, banned := r.bans[sessionID][userID]; banned {
+		return existing, false
+	}
+	if r.bans[sessionID] == nil {
+		r.bans[sessionID] = make(map[string]Ban)
+	}
+	ban := Ban{UserID: userID, Reason: reason, BannedAt: time.Now().UTC()}
+	r.bans[sessionID][userID] = ban
+	return ban, true
+}
+
+// Unban lifts a ban, returning false if the user was not banned
+func (r *Registry) Unban(sessionID, userID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, banned := r.bans[sessionID][userID]; !banned {
+		return false
+	}
+	delete(r.bans[sessionID], userID)
+	return true
+}
+
+// IsBanned reports whether a user is barred from a session
+func (r *Registry) IsBanned(sessionID, userID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, banned := r.bans[sessionID][userID]
+	return banned
+}
+
+// Bans lists a session's bans, oldest first
+func (r *Registry) Bans(sessionID string) []Ban {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bans := make([]Ban, 0, len(r.bans[sessionID]))
+	for _, ban := range r.bans[sessionID] {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].BannedAt.Before(bans[j].BannedAt) })
+	return bans
+}
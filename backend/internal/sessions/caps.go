@@ -0,0 +1,107 @@
+package sessions
+
+import "sync"
+
+// CapVerdict is the outcome of admitting a reaction against a session's caps
+type CapVerdict int
+
+const (
+	CapAllow          CapVerdict = iota
+	CapSessionSoldOut            // the session's total reaction cap is used up
+	CapUserSoldOut               // the user's per-session reaction cap is used up
+)
+
+// Code returns the rejection code sent to clients, or "" for CapAllow
+func (v CapVerdict) Code() string {
+	switch v {
+	case CapSessionSoldOut:
+		return "session_sold_out"
+	case CapUserSoldOut:
+		return "user_sold_out"
+	}
+	return ""
+}
+
+// capUsage counts the reactions admitted for one capped session
+type capUsage struct {
+	total int64
+	users map[string]int64
+}
+
+// CapHistory reports the reactions sessions counted before their caps were
+// tracked by this process, e.g. stats restored from a checkpoint or
+// mirrored to a standby that was promoted
+type CapHistory interface {
+	SessionReactions(sessionID string) int64
+	UserReactions(sessionID, userID string) int64
+}
+
+// ReactionCaps enforces the reaction caps sessions were created with. Only
+// capped sessions are tracked, and admission is exact: a reaction is counted
+// against the caps in the same step that admits it.
+type ReactionCaps struct {
+	usage   map[string]*capUsage // sessionID -> admitted reactions
+	history CapHistory           // nil starts every session's usage at zero
+	mu      sync.Mutex
+}
+
+// NewReactionCaps creates an empty reaction cap gate
+func NewReactionCaps() *ReactionCaps {
+	return &ReactionCaps{
+		usage: make(map[string]*capUsage),
+	}
+}
+
+// SetHistory seeds each session's usage from history the first time one of
+// its reactions is admitted, so a restart or failover does not grant a
+// capped session a fresh allowance
+func (c *ReactionCaps) SetHistory(history CapHistory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = history
+}
+
+// Admit counts a reaction against the session's caps. soldOut is true when
+// this reaction took the last slot of the session cap, so the sell-out can
+// be announced exactly once.
+func (c *ReactionCaps) Admit(session Session, userID string) (verdict CapVerdict, soldOut bool) {
+	if session.ReactionCap <= 0 && session.UserReactionCap <= 0 {
+		return CapAllow, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	usage, exists := c.usage[session.ID]
+	if !exists {
+		usage = &capUsage{users: make(map[string]int64)}
+		if c.history != nil {
+			usage.total = c.history.SessionReactions(session.ID)
+		}
+		c.usage[session.ID] = usage
+	}
+	userUsage, tracked := usage.users[userID]
+	if !tracked && c.history != nil {
+		// Every reaction of a capped session is admitted here before it is
+		// counted, so history holds none admitted since tracking began
+		userUsage = c.history.UserReactions(session.ID, userID)
+		usage.users[userID] = userUsage
+	}
+	if session.ReactionCap > 0 && usage.total >= session.ReactionCap {
+		return CapSessionSoldOut, false
+	}
+	if session.UserReactionCap > 0 && userUsage >= session.UserReactionCap {
+		return CapUserSoldOut, false
+	}
+
+	usage.total++
+	usage.users[userID]++
+	return CapAllow, session.ReactionCap > 0 && usage.total == session.ReactionCap
+}
+
+// Remove forgets a session's usage once it has ended
+func (c *ReactionCaps) Remove(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.usage, sessionID)
+}
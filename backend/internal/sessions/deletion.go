@@ -0,0 +1,64 @@
+package sessions
+
+import "time"
+
+// Delete soft-deletes a session: it is hidden from listings and refuses
+// joins and events, but keeps its metadata, aliases and bans until it is
+// restored or purged. It returns false if the session is unknown or
+// already deleted.
+func (r *Registry) Delete(id string) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists || session.DeletedAt != nil {
+		return Session{}, false
+	}
+	now := time.Now().UTC()
+	session.DeletedAt = &now
+	return *session, true
+}
+
+// Undelete restores a soft-deleted session as it was before it was
+// deleted. It returns false if the session is unknown or not deleted.
+func (r *Registry) Undelete(id string) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists || session.DeletedAt == nil {
+		return Session{}, false
+	}
+	session.DeletedAt = nil
+	return *session, true
+}
+
+// IsDeleted reports whether a session has been soft-deleted
+func (r *Registry) IsDeleted(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, exists := r.sessions[id]
+	return exists && session.DeletedAt != nil
+}
+
+// Purge forgets the sessions deleted before cutoff, with their aliases and
+// bans, and returns them
+func (r *Registry) Purge(cutoff time.Time) []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged []Session
+	for id, session := range r.sessions {
+		if session.DeletedAt == nil || !session.DeletedAt.Before(cutoff) {
+			continue
+		}
+		for _, alias := range session.Aliases {
+			delete(r.aliases, alias)
+		}
+		delete(r.sessions, id)
+		delete(r.bans, id)
+		purged = append(purged, *session)
+	}
+	return purged
+}
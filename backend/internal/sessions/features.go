@@ -0,0 +1,79 @@
+package sessions
+
+import (
+	"context"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Features toggles optional parts of a live show. The zero value is the
+// default experience: polls and chat on, leaderboard hidden and reactions
+// attributed to the users who sent them.
+type Features struct {
+	PollsDisabled      bool `json:"polls_disabled,omitempty"`
+	ChatDisabled       bool `json:"chat_disabled,omitempty"`
+	LeaderboardVisible bool `json:"leaderboard_visible,omitempty"`
+	AnonymousReactions bool `json:"anonymous_reactions,omitempty"`
+}
+
+// Accepts reports whether the features let events of the given type into a
+// session. Poll votes and chat messages are turned away while their feature
+// is off; every other event is accepted.
+func (f Features) Accepts(eventType events.EventType) bool {
+	switch eventType {
+	case events.EventTypePollVote:
+		return !f.PollsDisabled
+	case events.EventTypeChat:
+		return !f.ChatDisabled
+	}
+	return true
+}
+
+// FeaturesUpdate changes some of a session's features, leaving nil fields
+// as they are
+type FeaturesUpdate struct {
+	PollsDisabled      *bool `json:"polls_disabled,omitempty"`
+	ChatDisabled       *bool `json:"chat_disabled,omitempty"`
+	LeaderboardVisible *bool `json:"leaderboard_visible,omitempty"`
+	AnonymousReactions *bool `json:"anonymous_reactions,omitempty"`
+}
+
+// Apply returns the features with the update's non-nil fields set
+func (u FeaturesUpdate) Apply(f Features) Features {
+	for dst, src := range map[*bool]*bool{
+		&f.PollsDisabled:      u.PollsDisabled,
+		&f.ChatDisabled:       u.ChatDisabled,
+		&f.LeaderboardVisible: u.LeaderboardVisible,
+		&f.AnonymousReactions: u.AnonymousReactions,
+	} {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	return f
+}
+
+// AdmitFeatures is a pipeline stage skipping events the session's features
+// turn away, so polls and chat switched off mid-show stop being counted or
+// stored. Events of unknown sessions are judged by the default features.
+func (r *Registry) AdmitFeatures(_ context.Context, event *events.Event) error {
+	session, _ := r.Get(event.SessionID)
+	if !session.Features.Accepts(event.Type) {
+		return events.ErrSkip
+	}
+	return nil
+}
+
+// SetFeatures applies an update to a session's features mid-show. It
+// returns false if the session is unknown or has ended.
+func (r *Registry) SetFeatures(id string, update FeaturesUpdate) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists || session.Status == StatusEnded {
+		return Session{}, false
+	}
+	session.Features = update.Apply(session.Features)
+	return *session, true
+}
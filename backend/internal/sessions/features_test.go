@@ -0,0 +1,54 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures_AcceptsTurnsAwayDisabledPollsAndChat(t *testing.T) {
+	var defaults Features
+	for _, eventType := range []events.EventType{events.EventTypePollVote, events.EventTypeChat, events.EventTypeReaction} {
+		assert.True(t, defaults.Accepts(eventType), eventType)
+	}
+
+	off := true
+	registry := NewRegistry()
+	registry.Create(Session{ID: "s1"})
+	session, ok := registry.SetFeatures("s1", FeaturesUpdate{PollsDisabled: &off})
+	require.True(t, ok)
+	assert.False(t, session.Features.Accepts(events.EventTypePollVote))
+	assert.True(t, session.Features.Accepts(events.EventTypeChat))
+
+	session, _ = registry.SetFeatures("s1", FeaturesUpdate{ChatDisabled: &off})
+	assert.False(t, session.Features.Accepts(events.EventTypePollVote))
+	assert.False(t, session.Features.Accepts(events.EventTypeChat))
+	assert.True(t, session.Features.Accepts(events.EventTypeReaction))
+	assert.True(t, session.Features.Accepts(events.EventTypeJoinSession))
+}
+
+func TestRegistry_AdmitFeaturesSkipsDisabledEvents(t *testing.T) {
+	off := true
+	registry := NewRegistry()
+	registry.Create(Session{ID: "s1"})
+	registry.SetFeatures("s1", FeaturesUpdate{PollsDisabled: &off})
+
+	assert.ErrorIs(t, registry.AdmitFeatures(context.Background(), events.PollVoteEvent("s1", "u1", "poll-1", "a")), events.ErrSkip)
+	assert.NoError(t, registry.AdmitFeatures(context.Background(), events.ChatEvent("s1", "u1", "hi", "")))
+	assert.NoError(t, registry.AdmitFeatures(context.Background(), events.PollVoteEvent("other", "u1", "poll-1", "a")))
+}
+
+func TestRegistry_SetFeaturesRefusesEndedSessions(t *testing.T) {
+	off := true
+	registry := NewRegistry()
+	registry.Create(Session{ID: "s1"})
+	registry.End("s1")
+
+	_, ok := registry.SetFeatures("s1", FeaturesUpdate{ChatDisabled: &off})
+	assert.False(t, ok)
+	_, ok = registry.SetFeatures("missing", FeaturesUpdate{ChatDisabled: &off})
+	assert.False(t, ok)
+}
@@ -0,0 +1,78 @@
+package sessions
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// MaxIDLength bounds session IDs, including any tenant prefix
+const MaxIDLength = 128
+
+// NamespaceSeparator joins a tenant prefix to a tenant-local session ID
+const NamespaceSeparator = ":"
+
+var (
+	localIDPattern  = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	tenantIDPattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+)
+
+// reservedTenants are namespaces kept for internal use; local IDs starting
+// with an underscore are reserved as well
+var reservedTenants = map[string]bool{"livepulse": true, "internal": true, "system": true}
+
+// ValidateTenantID checks that a tenant ID can be used as a namespace
+func ValidateTenantID(tenantID string) error {
+	if !tenantIDPattern.MatchString(tenantID) {
+		return errs.Validation("tenant_id must be 1-32 lowercase letters, digits or dashes")
+	}
+	if reservedTenants[tenantID] {
+		return errs.Validation("tenant_id %q is reserved", tenantID)
+	}
+	return nil
+}
+
+// ValidateID checks a session ID's length, characters and namespace. IDs are
+// either a bare local ID or "<tenant>:<local ID>".
+func ValidateID(id string) error {
+	if id == "" {
+		return errs.Validation("session_id is required")
+	}
+	if len(id) > MaxIDLength {
+		return errs.Validation("session_id must be at most %d characters", MaxIDLength)
+	}
+	tenantID, localID, namespaced := strings.Cut(id, NamespaceSeparator)
+	if namespaced {
+		if err := ValidateTenantID(tenantID); err != nil {
+			return err
+		}
+	} else {
+		localID = tenantID
+	}
+	if !localIDPattern.MatchString(localID) {
+		return errs.Validation("session_id may only contain letters, digits, '.', '_' and '-' after the tenant prefix")
+	}
+	if strings.HasPrefix(localID, "_") {
+		return errs.Validation("session IDs starting with '_' are reserved")
+	}
+	return nil
+}
+
+// NamespacedID scopes a local session ID to a tenant so integrators choosing
+// the same local ID never share stats
+func NamespacedID(tenantID, localID string) string {
+	if tenantID == "" {
+		return localID
+	}
+	return tenantID + NamespaceSeparator + localID
+}
+
+// TenantOf returns the tenant prefix of a namespaced session ID
+func TenantOf(id string) string {
+	tenantID, _, namespaced := strings.Cut(id, NamespaceSeparator)
+	if !namespaced {
+		return ""
+	}
+	return tenantID
+}
@@ -0,0 +1,265 @@
+package sessions
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status represents the lifecycle state of a session
+type Status string
+
+const (
+	StatusLive    Status = "live"
+	StatusClosing Status = "closing" // no new joins, late reactions still counted
+	StatusEnded   Status = "ended"
+)
+
+// Session holds the metadata describing a live session
+type Session struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	Status    Status     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosingAt *time.Time `json:"closing_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // soft-deleted, purged after the retention period
+
+	// Broadcast pacing bounds; zero uses the deployment defaults
+	BroadcastMinInterval time.Duration `json:"broadcast_min_interval,omitempty"`
+	BroadcastMaxInterval time.Duration `json:"broadcast_max_interval,omitempty"`
+
+	// Reaction caps for gamified scarcity; zero leaves reactions unlimited
+	ReactionCap     int64 `json:"reaction_cap,omitempty"`
+	UserReactionCap int64 `json:"user_reaction_cap,omitempty"`
+
+	// Optional features, adjustable while the session is live
+	Features Features `json:"features"`
+
+	// Free-form labels such as an event series, sport or language, used
+	// to roll up stats across sessions
+	Tags []string `json:"tags,omitempty"`
+
+	// IDs the session is simulcast under, sharing its stats
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Registry tracks metadata and lifecycle state for every known session
+type Registry struct {
+	sessions map[string]*Session
+	bans     map[string]map[string]Ban // sessionID -> userID -> ban
+	aliases  map[string]string         // alias -> canonical session ID
+	mu       sync.RWMutex
+}
+
+// NewRegistry creates a new session registry
+func NewRegistry() *Registry {
+	return &Registry{
+		sessions: make(map[string]*Session),
+		bans:     make(map[string]map[string]Ban),
+		aliases:  make(map[string]string),
+	}
+}
+
+// Create registers a new live session from the given metadata
+func (r *Registry) Create(session Session) Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session.Status = StatusLive
+	session.CreatedAt = time.Now().UTC()
+	session.ClosingAt = nil
+	session.EndedAt = nil
+	r.sessions[session.ID] = &session
+	return session
+}
+
+// CreateIfAbsent registers a new live session unless one with the same ID
+// already exists, in which case it returns the existing session and false
+func (r *Registry) CreateIfAbsent(session Session) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.sessions[session.ID]; exists {
+		return *existing, false
+	}
+	session.Status = StatusLive
+	session.CreatedAt = time.Now().UTC()
+	session.ClosingAt = nil
+	session.EndedAt = nil
+	r.sessions[session.ID] = &session
+	return session, true
+}
+
+// Restore registers sessions mirrored from another instance, replacing
+// any already known under the same IDs
+func (r *Registry) Restore(sessions []Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range sessions {
+		session := sessions[i]
+		if previous, exists := r.sessions[session.ID]; exists {
+			for _, alias := range previous.Aliases {
+				delete(r.aliases, alias)
+			}
+		}
+		r.sessions[session.ID] = &session
+		r.indexAliasesLocked(&session)
+	}
+}
+
+// Touch registers a session that was started implicitly by incoming events
+// (e.g. Ticketmaster event rooms) so it can be managed like any other
+func (r *Registry) Touch(id string) Session {
+	r.mu.RLock()
+	session, exists := r.sessions[id]
+	r.mu.RUnlock()
+	if exists {
+		return *session
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Double-check after acquiring write lock
+	if session, exists := r.sessions[id]; exists {
+		return *session
+	}
+	session = &Session{
+		ID:        id,
+		Status:    StatusLive,
+		CreatedAt: time.Now().UTC(),
+	}
+	r.sessions[id] = session
+	return *session
+}
+
+// All returns copies of every session, deleted ones included, for
+// replication
+func (r *Registry) All() []Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		result = append(result, *session)
+	}
+	return result
+}
+
+// Get returns a copy of a session's metadata
+func (r *Registry) Get(id string) (Session, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, exists := r.sessions[id]
+	if !exists {
+		return Session{}, false
+	}
+	return *session, true
+}
+
+// IsEnded reports whether a session has been ended
+func (r *Registry) IsEnded(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, exists := r.sessions[id]
+	return exists && session.Status == StatusEnded
+}
+
+// AcceptsJoins reports whether new users may join a session. Unknown
+// sessions accept joins since they are started implicitly; deleted ones
+// never do.
+func (r *Registry) AcceptsJoins(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, exists := r.sessions[id]
+	return !exists || (session.Status == StatusLive && session.DeletedAt == nil)
+}
+
+// Close moves a live session into the closing state. It returns false if
+// the session is unknown or already closing or ended.
+func (r *Registry) Close(id string) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists || session.Status != StatusLive {
+		return Session{}, false
+	}
+	now := time.Now().UTC()
+	session.Status = StatusClosing
+	session.ClosingAt = &now
+	return *session, true
+}
+
+// End marks a live or closing session as ended. It returns false if the
+// session is unknown or was already ended, so callers finalize each session
+// exactly once.
+func (r *Registry) End(id string) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists || session.Status == StatusEnded {
+		return Session{}, false
+	}
+	now := time.Now().UTC()
+	session.Status = StatusEnded
+	session.EndedAt = &now
+	return *session, true
+}
+
+// List returns copies of every session matching the filter. Deleted
+// sessions are only listed by filters selecting them.
+func (r *Registry) List(filter Filter) []Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now().UTC()
+	var result []Session
+	for _, session := range r.sessions {
+		if filter.Matches(*session, now) {
+			result = append(result, *session)
+		}
+	}
+	return result
+}
+
+// Filter selects sessions for bulk operations. Zero-valued fields match all
+// sessions that are not deleted.
+type Filter struct {
+	TenantID   string        `json:"tenant_id,omitempty"`
+	NamePrefix string        `json:"name_prefix,omitempty"`
+	OlderThan  time.Duration `json:"older_than,omitempty"`
+	Status     Status        `json:"status,omitempty"`
+	Tag        string        `json:"tag,omitempty"`
+	Deleted    bool          `json:"deleted,omitempty"` // list soft-deleted sessions instead
+}
+
+// Matches reports whether a session satisfies every condition of the filter
+func (f Filter) Matches(session Session, now time.Time) bool {
+	if f.Deleted != (session.DeletedAt != nil) {
+		return false
+	}
+	if f.TenantID != "" && session.TenantID != f.TenantID {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(session.Name, f.NamePrefix) {
+		return false
+	}
+	if f.OlderThan > 0 && now.Sub(session.CreatedAt) < f.OlderThan {
+		return false
+	}
+	if f.Status != "" && session.Status != f.Status {
+		return false
+	}
+	if f.Tag != "" && !session.HasTag(f.Tag) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	registry := NewRegistry()
+	registry.Create(Session{ID: "s1", Name: "Finals"})
+	assert.True(t, registry.AcceptsJoins("s1"))
+	assert.True(t, registry.AcceptsJoins("unknown"), "unknown sessions start implicitly")
+
+	_, created := registry.CreateIfAbsent(Session{ID: "s1", Name: "Imposter"})
+	assert.False(t, created)
+	session, _ := registry.Get("s1")
+	assert.Equal(t, "Finals", session.Name)
+
+	session, closed := registry.Close("s1")
+	require.True(t, closed)
+	assert.Equal(t, StatusClosing, session.Status)
+	assert.False(t, registry.AcceptsJoins("s1"))
+	assert.False(t, registry.IsEnded("s1"))
+	_, closed = registry.Close("s1")
+	assert.False(t, closed, "only live sessions close")
+
+	session, ended := registry.End("s1")
+	require.True(t, ended)
+	assert.NotNil(t, session.EndedAt)
+	assert.True(t, registry.IsEnded("s1"))
+	_, ended = registry.End("s1")
+	assert.False(t, ended, "sessions are finalized once")
+}
+
+func TestRegistry_ListFilters(t *testing.T) {
+	registry := NewRegistry()
+	registry.Create(Session{ID: "a", Name: "NBA Finals", TenantID: "acme", Tags: []string{"basketball"}})
+	registry.Create(Session{ID: "b", Name: "NBA Draft", TenantID: "acme"})
+	registry.Create(Session{ID: "c", Name: "NBA Finals Replay", TenantID: "globex"})
+	registry.End("b")
+
+	ids := func(filter Filter) []string {
+		var ids []string
+		for _, session := range registry.List(filter) {
+			ids = append(ids, session.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, ids(Filter{}))
+	assert.ElementsMatch(t, []string{"a", "b"}, ids(Filter{TenantID: "acme"}))
+	assert.ElementsMatch(t, []string{"a", "c"}, ids(Filter{NamePrefix: "NBA Finals"}))
+	assert.ElementsMatch(t, []string{"b"}, ids(Filter{Status: StatusEnded}))
+	assert.ElementsMatch(t, []string{"a"}, ids(Filter{Tag: "basketball"}))
+	assert.Empty(t, ids(Filter{OlderThan: time.Hour}))
+}
+
+func TestRegistry_SoftDelete(t *testing.T) {
+	registry := NewRegistry()
+	registry.Create(Session{ID: "a"})
+	_, err := registry.SetAliases("a", []string{"a-es"})
+	require.NoError(t, err)
+	registry.Create(Session{ID: "b"})
+
+	_, deleted := registry.Delete("a")
+	require.True(t, deleted)
+	assert.True(t, registry.IsDeleted("a"))
+	assert.False(t, registry.AcceptsJoins("a"))
+	assert.Len(t, registry.List(Filter{}), 1, "deleted sessions are hidden from listings")
+	assert.Len(t, registry.List(Filter{Deleted: true}), 1)
+	assert.Len(t, registry.All(), 2)
+	_, deleted = registry.Delete("a")
+	assert.False(t, deleted)
+
+	session, restored := registry.Undelete("a")
+	require.True(t, restored)
+	assert.Nil(t, session.DeletedAt)
+	assert.True(t, registry.AcceptsJoins("a"))
+	_, restored = registry.Undelete("b")
+	assert.False(t, restored, "only deleted sessions are restored")
+
+	registry.Delete("a")
+	assert.Empty(t, registry.Purge(time.Now().Add(-time.Hour)))
+	purged := registry.Purge(time.Now().Add(time.Second))
+	require.Len(t, purged, 1)
+	assert.Equal(t, "a", purged[0].ID)
+	_, exists := registry.Get("a")
+	assert.False(t, exists)
+	assert.Equal(t, "a-es", registry.Canonical("a-es"), "purged sessions release their aliases")
+}
+
+func TestRegistry_Bans(t *testing.T) {
+	registry := NewRegistry()
+	ban, added := registry.Ban("s1", "u1", "spam")
+	require.True(t, added)
+	assert.Equal(t, "spam", ban.Reason)
+
+	again, added := registry.Ban("s1", "u1", "more spam")
+	assert.False(t, added)
+	assert.Equal(t, "spam", again.Reason, "the original ban stands")
+
+	assert.True(t, registry.IsBanned("s1", "u1"))
+	assert.False(t, registry.IsBanned("s2", "u1"), "bans are per session")
+	assert.Len(t, registry.Bans("s1"), 1)
+
+	assert.True(t, registry.Unban("s1", "u1"))
+	assert.False(t, registry.Unban("s1", "u1"))
+	assert.False(t, registry.IsBanned("s1", "u1"))
+	assert.Empty(t, registry.Bans("s1"))
+}
+
+func TestRegistry_AliasesMirrorOneSession(t *testing.T) {
+	registry := NewRegistry()
+	registry.Create(Session{ID: "acme:show", TenantID: "acme"})
+	registry.Create(Session{ID: "acme:other", TenantID: "acme"})
+
+	aliases, err := AliasIDs("acme:show", []string{"show-yt", "show-twitch"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme:show-yt", "acme:show-twitch"}, aliases, "aliases stay in the session's tenant")
+	for _, invalid := range [][]string{{"show"}, {"show-yt", "show-yt"}, {"globex:show-yt"}, {""}} {
+		_, err := AliasIDs("acme:show", invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	session, err := registry.SetAliases("acme:show", aliases)
+	require.NoError(t, err)
+	assert.Equal(t, aliases, session.Aliases)
+	assert.Equal(t, "acme:show", registry.Canonical("acme:show-yt"))
+	assert.Equal(t, "acme:other", registry.Canonical("acme:other"))
+	assert.Equal(t, []string{"acme:show", "acme:show-yt", "acme:show-twitch"}, registry.Channels("acme:show-twitch"))
+	assert.Equal(t, []string{"acme:other"}, registry.Channels("acme:other"))
+
+	_, err = registry.SetAliases("acme:other", []string{"acme:show-yt"})
+	assert.Error(t, err, "an alias mirrors one session")
+	_, err = registry.SetAliases("acme:other", []string{"acme:show"})
+	assert.Error(t, err, "sessions cannot become aliases")
+
+	// Replacing aliases frees the dropped ones
+	_, err = registry.SetAliases("acme:show", []string{"acme:show-yt"})
+	require.NoError(t, err)
+	assert.Equal(t, "acme:show-twitch", registry.Canonical("acme:show-twitch"))
+	_, err = registry.SetAliases("acme:other", []string{"acme:show-twitch"})
+	assert.NoError(t, err)
+
+	// Mirrored sessions bring their aliases along
+	replica := NewRegistry()
+	replica.Restore(registry.List(Filter{}))
+	assert.Equal(t, "acme:show", replica.Canonical("acme:show-yt"))
+}
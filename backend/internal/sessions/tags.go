@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jrudman25/livepulse/internal/errs"
+)
+
+// MaxTags bounds how many tags a session can carry
+const MaxTags = 16
+
+// Tags are lowercase and may use ':' to group them, e.g. "sport:basketball"
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9:._-]{0,63}$`)
+
+// NormalizeTags lowercases, trims and de-duplicates tags, rejecting any that
+// are malformed
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxTags {
+		return nil, errs.Validation("a session may carry at most %d tags", MaxTags)
+	}
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, errs.Validation("tag %q must be 1-64 lowercase letters, digits, ':', '.', '_' or '-'", tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// HasTag reports whether the session carries tag
+func (s Session) HasTag(tag string) bool {
+	return slices.Contains(s.Tags, tag)
+}
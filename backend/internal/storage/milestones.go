@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Each session's milestones are stored in a hash holding their serialized
+// state and its version. The index set lists every stored session.
+const (
+	milestoneStatePrefix = "milestones:state:"
+	milestoneStateIndex  = "milestones:states"
+)
+
+// MilestoneState is a session's serialized milestones and the version they
+// were written at
+type MilestoneState struct {
+	Data    []byte
+	Version int64
+}
+
+// saveMilestoneStateScript writes a session's milestones only if their
+// stored version is still the one the writer read, and bumps it
+var saveMilestoneStateScript = redis.NewScript(`
+local current = tonumber(redis.call("HGET", KEYS[1], "version") or "0")
+if current ~= tonumber(ARGV[1]) then
+	return -1
+end
+redis.call("HSET", KEYS[1], "version", current + 1, "data", ARGV[2])
+redis.call("SADD", KEYS[2], ARGV[3])
+return current + 1
+`)
+
+// SaveMilestoneState writes a session's milestones if their stored version
+// is still version, 0 for a session never written, and returns the new
+// version. It reports false, writing nothing, if another writer moved the
+// version on.
+func (rc *RedisClient) SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error) {
+	res, err := saveMilestoneStateScript.Run(ctx, rc.client, []string{milestoneStatePrefix + sessionID, milestoneStateIndex}, version, data, sessionID).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if res < 0 {
+		return 0, false, nil
+	}
+	return res, true, nil
+}
+
+// LoadMilestoneStates returns the stored milestones of the given sessions,
+// or of every stored session when none are given. Sessions without stored
+// milestones are left out.
+func (rc *RedisClient) LoadMilestoneStates(ctx context.Context, sessionIDs ...string) (map[string]MilestoneState, error) {
+	if len(sessionIDs) == 0 {
+		all, err := rc.client.SMembers(ctx, milestoneStateIndex).Result()
+		if err != nil {
+			return nil, err
+		}
+		sessionIDs = all
+	}
+	states := make(map[string]MilestoneState, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return states, nil
+	}
+
+	pipe := rc.client.Pipeline()
+	reads := make([]*redis.SliceCmd, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		reads[i] = pipe.HMGet(ctx, milestoneStatePrefix+sessionID, "version", "data")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	for i, read := range reads {
+		values := read.Val()
+		version, okVersion := values[0].(string)
+		data, okData := values[1].(string)
+		if !okVersion || !okData {
+			continue
+		}
+		parsed, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			continue
+		}
+		states[sessionIDs[i]] = MilestoneState{Data: []byte(data), Version: parsed}
+	}
+	return states, nil
+}
+
+// DeleteMilestoneStates deletes the stored milestones of removed sessions
+func (rc *RedisClient) DeleteMilestoneStates(ctx context.Context, sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	_, err := rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, sessionID := range sessionIDs {
+			pipe.Del(ctx, milestoneStatePrefix+sessionID)
+			pipe.SRem(ctx, milestoneStateIndex, sessionID)
+		}
+		return nil
+	})
+	return err
+}
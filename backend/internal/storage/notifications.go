@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Notification delivery states. Delivered notifications are removed from the
+// outbox, so only pending and failed ones are stored.
+const (
+	NotificationPending = "pending"
+	NotificationFailed  = "failed"
+)
+
+// NotificationDelivery is one webhook notification waiting in the outbox for
+// delivery to one endpoint
+type NotificationDelivery struct {
+	ID            int64     `json:"id"`
+	Type          string    `json:"type"`
+	SessionID     string    `json:"session_id,omitempty"`
+	URL           string    `json:"url"`
+	Body          []byte    `json:"-"`
+	Signature     string    `json:"-"` // X-LivePulse-Signature, empty when unsigned
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+const notificationColumns = `id, type, COALESCE(session_id, ''), url, body, COALESCE(signature, ''), status, attempts, COALESCE(last_error, ''), next_attempt_at, created_at`
+
+// EnqueueNotifications adds pending deliveries to the outbox
+func (db *PostgresClient) EnqueueNotifications(ctx context.Context, deliveries []NotificationDelivery) error {
+	query := `
+		INSERT INTO notification_outbox (type, session_id, url, body, signature, status, next_attempt_at, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), 'pending', $6, $7)
+	`
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, d := range deliveries {
+		if _, err := tx.Exec(ctx, query, d.Type, d.SessionID, d.URL, string(d.Body), d.Signature, d.NextAttemptAt, d.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ClaimNotifications takes up to limit due deliveries and counts an attempt
+// for each. Claimed deliveries are hidden from other claims for lease, so a
+// delivery abandoned by a crashed instance is retried once the lease ends.
+func (db *PostgresClient) ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]NotificationDelivery, error) {
+	query := `
+		UPDATE notification_outbox SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 microsecond'
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + notificationColumns
+	return db.queryNotifications(ctx, query, limit, lease.Microseconds())
+}
+
+// CompleteNotification removes a delivered notification from the outbox
+func (db *PostgresClient) CompleteNotification(ctx context.Context, id int64) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM notification_outbox WHERE id = $1`, id)
+	return err
+}
+
+// RetryNotification reschedules a failed delivery attempt
+func (db *PostgresClient) RetryNotification(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	query := `UPDATE notification_outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	_, err := db.pool.Exec(ctx, query, id, lastError, nextAttemptAt)
+	return err
+}
+
+// FailNotification parks a delivery that ran out of attempts until it is
+// redriven
+func (db *PostgresClient) FailNotification(ctx context.Context, id int64, lastError string) error {
+	query := `UPDATE notification_outbox SET status = 'failed', last_error = $2 WHERE id = $1`
+	_, err := db.pool.Exec(ctx, query, id, lastError)
+	return err
+}
+
+// ListFailedNotifications returns up to limit failed deliveries, oldest first
+func (db *PostgresClient) ListFailedNotifications(ctx context.Context, limit int) ([]NotificationDelivery, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notification_outbox WHERE status = 'failed' ORDER BY id LIMIT $1`
+	return db.queryNotifications(ctx, query, limit)
+}
+
+// RedriveNotifications returns failed deliveries to the outbox with a fresh
+// set of attempts, either those with the given IDs or all of them when ids
+// is empty. It returns how many were redriven.
+func (db *PostgresClient) RedriveNotifications(ctx context.Context, ids []int64) (int64, error) {
+	query := `
+		UPDATE notification_outbox SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE status = 'failed' AND (cardinality($1::bigint[]) = 0 OR id = ANY($1))
+	`
+	if ids == nil {
+		ids = []int64{}
+	}
+	tag, err := db.pool.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// queryNotifications scans deliveries selected with notificationColumns
+func (db *PostgresClient) queryNotifications(ctx context.Context, query string, args ...interface{}) ([]NotificationDelivery, error) {
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []NotificationDelivery
+	for rows.Next() {
+		var d NotificationDelivery
+		var body string
+		if err := rows.Scan(&d.ID, &d.Type, &d.SessionID, &d.URL, &body, &d.Signature, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.Body = []byte(body)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
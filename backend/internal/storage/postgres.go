@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -43,10 +45,19 @@ type Favorite struct {
 
 // NewPostgresClient establishes a connection pool to Neon/PostgreSQL
 func NewPostgresClient(ctx context.Context, databaseURL string) (*PostgresClient, error) {
+	return newPostgresClient(ctx, databaseURL, "")
+}
+
+// newPostgresClient connects with the given search_path, or the server's
+// default when schema is empty
+func newPostgresClient(ctx context.Context, databaseURL, schema string) (*PostgresClient, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database url: %w", err)
 	}
+	if schema != "" {
+		config.ConnConfig.RuntimeParams["search_path"] = schema
+	}
 
 	// Optimize for Serverless Postgres
 	config.MaxConns = 25
@@ -92,11 +103,110 @@ func (db *PostgresClient) InitSchema(ctx context.Context) error {
 
 	ALTER TABLE events ADD COLUMN IF NOT EXISTS location VARCHAR(255);
 	ALTER TABLE events ADD COLUMN IF NOT EXISTS country VARCHAR(10);
+
+	CREATE TABLE IF NOT EXISTS user_fraud_scores (
+		user_id VARCHAR(255) PRIMARY KEY,
+		points DOUBLE PRECISION NOT NULL DEFAULT 0,
+		velocity_violations INTEGER NOT NULL DEFAULT 0,
+		anomaly_flags INTEGER NOT NULL DEFAULT 0,
+		bans INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS admin_actions (
+		id BIGSERIAL PRIMARY KEY,
+		actor VARCHAR(255) NOT NULL,
+		action VARCHAR(100) NOT NULL,
+		target VARCHAR(255) NOT NULL,
+		reason TEXT,
+		details JSONB,
+		occurred_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_admin_actions_occurred_at ON admin_actions (occurred_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_admin_actions_actor ON admin_actions (actor, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_admin_actions_target ON admin_actions (target, id DESC);
+
+	-- The action log is append-only: updates and deletes are silently discarded
+	CREATE OR REPLACE RULE admin_actions_no_update AS ON UPDATE TO admin_actions DO INSTEAD NOTHING;
+	CREATE OR REPLACE RULE admin_actions_no_delete AS ON DELETE TO admin_actions DO INSTEAD NOTHING;
+
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		type VARCHAR(100) NOT NULL,
+		session_id VARCHAR(255),
+		url TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+	ALTER TABLE notification_outbox ADD COLUMN IF NOT EXISTS signature TEXT;
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox (next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_failed ON notification_outbox (id) WHERE status = 'failed';
+
+	CREATE TABLE IF NOT EXISTS session_webhooks (
+		id VARCHAR(64) PRIMARY KEY,
+		session_id VARCHAR(255) NOT NULL,
+		url TEXT NOT NULL,
+		types TEXT[],
+		secret TEXT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_session_webhooks_session ON session_webhooks (session_id);
+
+	CREATE TABLE IF NOT EXISTS push_subscriptions (
+		session_id VARCHAR(255) NOT NULL,
+		token TEXT NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		platform VARCHAR(10) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		PRIMARY KEY (session_id, token)
+	);
+	CREATE INDEX IF NOT EXISTS idx_push_subscriptions_token ON push_subscriptions (token);
 	`
-	_, err := db.pool.Exec(ctx, queries)
+	_, err := db.pool.Exec(ctx, queries+tenantTables)
 	return err
 }
 
+// tenantTables holds tenant-scoped data, which isolated tenants keep in their
+// own schema
+const tenantTables = `
+	CREATE TABLE IF NOT EXISTS session_snapshots (
+		session_id VARCHAR(255) PRIMARY KEY,
+		tenant_id VARCHAR(255),
+		name VARCHAR(255),
+		snapshot JSONB NOT NULL,
+		milestones JSONB,
+		ended_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	ALTER TABLE session_snapshots ADD COLUMN IF NOT EXISTS peak_users INTEGER;
+	ALTER TABLE session_snapshots ADD COLUMN IF NOT EXISTS total_reactions BIGINT;
+	UPDATE session_snapshots SET
+		peak_users = COALESCE((snapshot->>'peak_concurrent_users')::int, 0),
+		total_reactions = COALESCE((snapshot->>'total_reactions')::bigint, 0)
+	WHERE peak_users IS NULL OR total_reactions IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_session_snapshots_ended_at ON session_snapshots (ended_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_session_snapshots_tenant_ended_at ON session_snapshots (tenant_id, ended_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_session_snapshots_peak_users ON session_snapshots (peak_users DESC);
+	CREATE INDEX IF NOT EXISTS idx_session_snapshots_total_reactions ON session_snapshots (total_reactions DESC);
+	ALTER TABLE session_snapshots ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+	CREATE INDEX IF NOT EXISTS idx_session_snapshots_tags ON session_snapshots USING GIN (tags);
+	ALTER TABLE session_snapshots ADD COLUMN IF NOT EXISTS peak_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE session_snapshots ADD COLUMN IF NOT EXISTS retention JSONB;
+
+	CREATE TABLE IF NOT EXISTS viewer_history (
+		tenant_id VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		first_session_id VARCHAR(255) NOT NULL,
+		first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		PRIMARY KEY (tenant_id, user_id)
+	);
+`
+
 // InsertEvent cleanly inserts or updates an event in Postgres
 func (db *PostgresClient) InsertEvent(ctx context.Context, e Event) error {
 	query := `
@@ -113,6 +223,378 @@ func (db *PostgresClient) InsertEvent(ctx context.Context, e Event) error {
 	return err
 }
 
+// SessionSnapshot is the finalized record of a session written when it ends
+type SessionSnapshot struct {
+	SessionID      string          `json:"session_id"`
+	TenantID       string          `json:"tenant_id"`
+	Name           string          `json:"name"`
+	Tags           []string        `json:"tags,omitempty"`
+	PeakUsers      int             `json:"peak_users"`
+	PeakAt         *time.Time      `json:"peak_at,omitempty"` // when the peak was reached
+	TotalReactions int64           `json:"total_reactions"`
+	Snapshot       json.RawMessage `json:"snapshot,omitempty"`
+	Milestones     json.RawMessage `json:"milestones,omitempty"`
+	Retention      json.RawMessage `json:"retention,omitempty"` // audience drop-off curve
+	EndedAt        time.Time       `json:"ended_at"`
+}
+
+// SaveSessionSnapshot persists the final statistics of an ended session
+func (db *PostgresClient) SaveSessionSnapshot(ctx context.Context, s SessionSnapshot) error {
+	query := `
+		INSERT INTO session_snapshots (session_id, tenant_id, name, tags, peak_users, peak_at, total_reactions, snapshot, milestones, retention, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (session_id) DO UPDATE SET
+			tags = EXCLUDED.tags,
+			peak_users = EXCLUDED.peak_users,
+			peak_at = EXCLUDED.peak_at,
+			total_reactions = EXCLUDED.total_reactions,
+			snapshot = EXCLUDED.snapshot,
+			milestones = EXCLUDED.milestones,
+			retention = EXCLUDED.retention,
+			ended_at = EXCLUDED.ended_at;
+	`
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	_, err := db.pool.Exec(ctx, query, s.SessionID, s.TenantID, s.Name, tags, s.PeakUsers, s.PeakAt, s.TotalReactions, s.Snapshot, s.Milestones, s.Retention, s.EndedAt)
+	return err
+}
+
+// GetSessionSnapshot fetches the archived record of an ended session,
+// returning nil if the session was never archived
+func (db *PostgresClient) GetSessionSnapshot(ctx context.Context, sessionID string) (*SessionSnapshot, error) {
+	var s SessionSnapshot
+	query := `SELECT session_id, tenant_id, name, tags, COALESCE(peak_users, 0), peak_at, COALESCE(total_reactions, 0), snapshot, milestones, retention, ended_at FROM session_snapshots WHERE session_id = $1`
+	err := db.pool.QueryRow(ctx, query, sessionID).Scan(&s.SessionID, &s.TenantID, &s.Name, &s.Tags, &s.PeakUsers, &s.PeakAt, &s.TotalReactions, &s.Snapshot, &s.Milestones, &s.Retention, &s.EndedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Archive search orderings
+const (
+	ArchiveSortEndedAt        = "ended_at"
+	ArchiveSortPeakUsers      = "peak_users"
+	ArchiveSortTotalReactions = "total_reactions"
+)
+
+// archiveSortColumns maps each ordering to its ORDER BY clause; session_id
+// breaks ties so offset pagination is stable
+var archiveSortColumns = map[string]string{
+	ArchiveSortEndedAt:        "ended_at DESC, session_id",
+	ArchiveSortPeakUsers:      "peak_users DESC, ended_at DESC, session_id",
+	ArchiveSortTotalReactions: "total_reactions DESC, ended_at DESC, session_id",
+}
+
+// ValidArchiveSort reports whether sort is a supported archive ordering
+func ValidArchiveSort(sort string) bool {
+	_, ok := archiveSortColumns[sort]
+	return ok
+}
+
+// ArchiveQuery filters a search over archived sessions. Zero values leave
+// the corresponding filter unset.
+type ArchiveQuery struct {
+	TenantID          string
+	EndedAfter        time.Time
+	EndedBefore       time.Time
+	MinPeakUsers      int
+	MinTotalReactions int64
+	Tag               string
+	Sort              string
+	Offset            int
+	Limit             int
+}
+
+// conditions renders the query's filters as a WHERE clause and its arguments
+func (q ArchiveQuery) conditions() (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if q.TenantID != "" {
+		add("tenant_id = $%d", q.TenantID)
+	}
+	if !q.EndedAfter.IsZero() {
+		add("ended_at >= $%d", q.EndedAfter)
+	}
+	if !q.EndedBefore.IsZero() {
+		add("ended_at < $%d", q.EndedBefore)
+	}
+	if q.MinPeakUsers > 0 {
+		add("peak_users >= $%d", q.MinPeakUsers)
+	}
+	if q.MinTotalReactions > 0 {
+		add("total_reactions >= $%d", q.MinTotalReactions)
+	}
+	if q.Tag != "" {
+		add("tags @> ARRAY[$%d]::text[]", q.Tag)
+	}
+	if len(where) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// SearchSessionSnapshots returns archived sessions matching q, without their
+// snapshot bodies, along with whether more results follow the page
+func (db *PostgresClient) SearchSessionSnapshots(ctx context.Context, q ArchiveQuery) ([]SessionSnapshot, bool, error) {
+	where, args := q.conditions()
+
+	order, ok := archiveSortColumns[q.Sort]
+	if !ok {
+		order = archiveSortColumns[ArchiveSortEndedAt]
+	}
+	query := `SELECT session_id, tenant_id, name, tags, COALESCE(peak_users, 0), peak_at, COALESCE(total_reactions, 0), ended_at FROM session_snapshots` + where
+	// Fetch one extra row to learn whether another page exists
+	args = append(args, q.Limit+1, q.Offset)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)-1, len(args))
+
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var results []SessionSnapshot
+	for rows.Next() {
+		var s SessionSnapshot
+		if err := rows.Scan(&s.SessionID, &s.TenantID, &s.Name, &s.Tags, &s.PeakUsers, &s.PeakAt, &s.TotalReactions, &s.EndedAt); err != nil {
+			return nil, false, err
+		}
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	more := len(results) > q.Limit
+	if more {
+		results = results[:q.Limit]
+	}
+	return results, more, nil
+}
+
+// ArchiveRollup sums the final metrics of a set of archived sessions
+type ArchiveRollup struct {
+	Sessions       int              `json:"sessions"`
+	PeakUsers      int              `json:"peak_users"` // sum of each session's peak
+	TotalReactions int64            `json:"total_reactions"`
+	ReactionCounts map[string]int64 `json:"reaction_counts"`
+}
+
+// RollupSessionSnapshots sums the archived sessions matching q, typically
+// every session sharing a tag over a date range. Sort and paging are ignored.
+func (db *PostgresClient) RollupSessionSnapshots(ctx context.Context, q ArchiveQuery) (ArchiveRollup, error) {
+	where, args := q.conditions()
+	rollup := ArchiveRollup{ReactionCounts: make(map[string]int64)}
+
+	query := `SELECT COUNT(*), COALESCE(SUM(peak_users), 0), COALESCE(SUM(total_reactions), 0) FROM session_snapshots` + where
+	if err := db.pool.QueryRow(ctx, query, args...).Scan(&rollup.Sessions, &rollup.PeakUsers, &rollup.TotalReactions); err != nil {
+		return ArchiveRollup{}, err
+	}
+
+	query = `SELECT counts.key, SUM(counts.value::bigint) FROM session_snapshots, jsonb_each_text(snapshot->'reaction_counts') AS counts` + where + ` GROUP BY counts.key`
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return ArchiveRollup{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reaction string
+		var count int64
+		if err := rows.Scan(&reaction, &count); err != nil {
+			return ArchiveRollup{}, err
+		}
+		rollup.ReactionCounts[reaction] = count
+	}
+	return rollup, rows.Err()
+}
+
+// FraudRecord is a user's accumulated fraud score across sessions
+type FraudRecord struct {
+	UserID             string    `json:"user_id"`
+	Points             float64   `json:"points"`
+	VelocityViolations int       `json:"velocity_violations"`
+	AnomalyFlags       int       `json:"anomaly_flags"`
+	Bans               int       `json:"bans"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// LoadFraudScore fetches a user's fraud score, returning nil if they have none
+func (db *PostgresClient) LoadFraudScore(ctx context.Context, userID string) (*FraudRecord, error) {
+	var r FraudRecord
+	query := `SELECT user_id, points, velocity_violations, anomaly_flags, bans, updated_at FROM user_fraud_scores WHERE user_id = $1`
+	err := db.pool.QueryRow(ctx, query, userID).Scan(&r.UserID, &r.Points, &r.VelocityViolations, &r.AnomalyFlags, &r.Bans, &r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// SaveFraudScore upserts a user's fraud score
+func (db *PostgresClient) SaveFraudScore(ctx context.Context, r FraudRecord) error {
+	query := `
+		INSERT INTO user_fraud_scores (user_id, points, velocity_violations, anomaly_flags, bans, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			points = EXCLUDED.points,
+			velocity_violations = EXCLUDED.velocity_violations,
+			anomaly_flags = EXCLUDED.anomaly_flags,
+			bans = EXCLUDED.bans,
+			updated_at = EXCLUDED.updated_at;
+	`
+	_, err := db.pool.Exec(ctx, query, r.UserID, r.Points, r.VelocityViolations, r.AnomalyFlags, r.Bans, r.UpdatedAt)
+	return err
+}
+
+// ViewerVisit is one user joining one of a tenant's sessions
+type ViewerVisit struct {
+	TenantID  string
+	UserID    string
+	SessionID string
+	SeenAt    time.Time
+}
+
+// RecordViewers notes that users joined sessions of the tenant and returns,
+// per user, the ID of the first session they were ever seen in. The first
+// session never changes, so reconnects and replays classify a join the same
+// way. A user's earliest visit in the batch is the one recorded.
+func (db *PostgresClient) RecordViewers(ctx context.Context, tenantID string, visits []ViewerVisit) (map[string]string, error) {
+	earliest := make(map[string]ViewerVisit, len(visits))
+	for _, visit := range visits {
+		if current, exists := earliest[visit.UserID]; !exists || visit.SeenAt.Before(current.SeenAt) {
+			earliest[visit.UserID] = visit
+		}
+	}
+	userIDs := make([]string, 0, len(earliest))
+	sessionIDs := make([]string, 0, len(earliest))
+	seenAt := make([]time.Time, 0, len(earliest))
+	for _, visit := range earliest {
+		userIDs = append(userIDs, visit.UserID)
+		sessionIDs = append(sessionIDs, visit.SessionID)
+		seenAt = append(seenAt, visit.SeenAt)
+	}
+
+	query := `
+		INSERT INTO viewer_history (tenant_id, user_id, first_session_id, first_seen_at, last_seen_at)
+		SELECT $1, v.user_id, v.session_id, v.seen_at, v.seen_at
+		FROM unnest($2::text[], $3::text[], $4::timestamptz[]) AS v(user_id, session_id, seen_at)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE SET
+			last_seen_at = GREATEST(viewer_history.last_seen_at, EXCLUDED.last_seen_at)
+		RETURNING user_id, first_session_id;
+	`
+	rows, err := db.pool.Query(ctx, query, tenantID, userIDs, sessionIDs, seenAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firstSessions := make(map[string]string, len(earliest))
+	for rows.Next() {
+		var userID, firstSessionID string
+		if err := rows.Scan(&userID, &firstSessionID); err != nil {
+			return nil, err
+		}
+		firstSessions[userID] = firstSessionID
+	}
+	return firstSessions, rows.Err()
+}
+
+// AdminAction is a single entry in the moderation and admin action log
+type AdminAction struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Target     string          `json:"target"`
+	Reason     string          `json:"reason,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// AppendAdminAction adds an entry to the append-only admin action log
+func (db *PostgresClient) AppendAdminAction(ctx context.Context, a AdminAction) error {
+	query := `
+		INSERT INTO admin_actions (actor, action, target, reason, details, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := db.pool.Exec(ctx, query, a.Actor, a.Action, a.Target, a.Reason, a.Details, a.OccurredAt)
+	return err
+}
+
+// AdminActionQuery filters the admin action log. Zero values leave the
+// corresponding filter unset; BeforeID pages backwards from an entry.
+type AdminActionQuery struct {
+	Actor    string
+	Action   string
+	Target   string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// ListAdminActions returns matching admin actions, newest first
+func (db *PostgresClient) ListAdminActions(ctx context.Context, q AdminActionQuery) ([]AdminAction, error) {
+	var where []string
+	var args []interface{}
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if q.Actor != "" {
+		add("actor = $%d", q.Actor)
+	}
+	if q.Action != "" {
+		add("action = $%d", q.Action)
+	}
+	if q.Target != "" {
+		add("target = $%d", q.Target)
+	}
+	if !q.Since.IsZero() {
+		add("occurred_at >= $%d", q.Since)
+	}
+	if !q.Until.IsZero() {
+		add("occurred_at < $%d", q.Until)
+	}
+	if q.BeforeID > 0 {
+		add("id < $%d", q.BeforeID)
+	}
+
+	query := `SELECT id, actor, action, target, COALESCE(reason, ''), details, occurred_at FROM admin_actions`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []AdminAction
+	for rows.Next() {
+		var a AdminAction
+		if err := rows.Scan(&a.ID, &a.Actor, &a.Action, &a.Target, &a.Reason, &a.Details, &a.OccurredAt); err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
 // Close gracefully closes the database pool
 func (db *PostgresClient) Close() {
 	if db.pool != nil {
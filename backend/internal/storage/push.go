@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// PushSubscription is a device subscribed to a session's push alerts, the
+// session acting as the device's topic
+type PushSubscription struct {
+	UserID    string    `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	Platform  string    `json:"platform"` // "fcm" or "apns"
+	Token     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddPushSubscription subscribes a device to a session, replacing any
+// earlier subscription of the same token
+func (db *PostgresClient) AddPushSubscription(ctx context.Context, sub PushSubscription) error {
+	query := `
+		INSERT INTO push_subscriptions (session_id, token, user_id, platform, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (session_id, token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform
+	`
+	_, err := db.pool.Exec(ctx, query, sub.SessionID, sub.Token, sub.UserID, sub.Platform, sub.CreatedAt)
+	return err
+}
+
+// RemovePushSubscription unsubscribes one of a user's devices from a session
+func (db *PostgresClient) RemovePushSubscription(ctx context.Context, userID, sessionID, token string) error {
+	query := `DELETE FROM push_subscriptions WHERE user_id = $1 AND session_id = $2 AND token = $3`
+	_, err := db.pool.Exec(ctx, query, userID, sessionID, token)
+	return err
+}
+
+// RemovePushToken drops every subscription of a device token, e.g. once the
+// push service reports the app was uninstalled
+func (db *PostgresClient) RemovePushToken(ctx context.Context, token string) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE token = $1`, token)
+	return err
+}
+
+// ListPushSubscriptions returns the devices subscribed to a session
+func (db *PostgresClient) ListPushSubscriptions(ctx context.Context, sessionID string) ([]PushSubscription, error) {
+	query := `SELECT user_id, session_id, platform, token, created_at FROM push_subscriptions WHERE session_id = $1`
+	rows, err := db.pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []PushSubscription
+	for rows.Next() {
+		var sub PushSubscription
+		if err := rows.Scan(&sub.UserID, &sub.SessionID, &sub.Platform, &sub.Token, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
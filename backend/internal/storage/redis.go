@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -84,6 +85,45 @@ func (rc *RedisClient) GetRecentChat(ctx context.Context, sessionID string) ([]C
 	return messages, nil
 }
 
+// FlaggedMessage is a published chat message a content filter queued for
+// moderator review
+type FlaggedMessage struct {
+	ChatMessage
+	Filter    string    `json:"filter"`
+	Reason    string    `json:"reason,omitempty"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+// SaveFlaggedMessage adds a message to the session's moderation queue,
+// keeping the latest 500
+func (rc *RedisClient) SaveFlaggedMessage(ctx context.Context, sessionID string, msg FlaggedMessage) error {
+	key := fmt.Sprintf("chat:flagged:%s", sessionID)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := rc.client.RPush(ctx, key, data).Err(); err != nil {
+		return err
+	}
+	return rc.client.LTrim(ctx, key, -500, -1).Err()
+}
+
+// GetFlaggedMessages fetches the session's moderation queue, oldest first
+func (rc *RedisClient) GetFlaggedMessages(ctx context.Context, sessionID string) ([]FlaggedMessage, error) {
+	results, err := rc.client.LRange(ctx, fmt.Sprintf("chat:flagged:%s", sessionID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]FlaggedMessage, 0, len(results))
+	for _, res := range results {
+		var msg FlaggedMessage
+		if err := json.Unmarshal([]byte(res), &msg); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
 // SetChatTTL configures the chat key to expire some time after the event ends
 func (rc *RedisClient) SetChatTTL(ctx context.Context, sessionID string, expireAt time.Time) error {
 	key := fmt.Sprintf("chat:%s", sessionID)
@@ -91,7 +131,298 @@ func (rc *RedisClient) SetChatTTL(ctx context.Context, sessionID string, expireA
 	deletionTime := expireAt.Add(1 * time.Hour)
 	
 	// ExpireAt explicitly schedules the key for deletion at a specific time
-	return rc.client.ExpireAt(ctx, key, deletionTime).Err()
+	if err := rc.client.ExpireAt(ctx, key, deletionTime).Err(); err != nil {
+		return err
+	}
+	return rc.client.ExpireAt(ctx, fmt.Sprintf("chat:flagged:%s", sessionID), deletionTime).Err()
+}
+
+// renewLeaseScript extends a lease only if it is still held by the caller
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript deletes a lease only if it is still held by the caller
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLease claims a key for owner if nobody currently holds it
+func (rc *RedisClient) AcquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return rc.client.SetNX(ctx, key, owner, ttl).Result()
+}
+
+// RenewLease extends a lease held by owner, returning false if it was lost
+func (rc *RedisClient) RenewLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	res, err := renewLeaseScript.Run(ctx, rc.client, []string{key}, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// ReleaseLease deletes a lease if it is still held by owner
+func (rc *RedisClient) ReleaseLease(ctx context.Context, key, owner string) error {
+	return releaseLeaseScript.Run(ctx, rc.client, []string{key}, owner).Err()
+}
+
+// LeaseOwner returns the current holder of a lease, or "" if unclaimed
+func (rc *RedisClient) LeaseOwner(ctx context.Context, key string) (string, error) {
+	owner, err := rc.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return owner, err
+}
+
+// Publish sends a payload to every subscriber of a pub/sub channel
+func (rc *RedisClient) Publish(ctx context.Context, channel string, payload []byte) error {
+	return rc.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe streams payloads published to a channel until ctx is cancelled
+func (rc *RedisClient) Subscribe(ctx context.Context, channel string) <-chan []byte {
+	pubsub := rc.client.Subscribe(ctx, channel)
+	out := make(chan []byte, 256)
+
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				out <- []byte(msg.Payload)
+			}
+		}
+	}()
+
+	return out
+}
+
+// StreamEntry is a single raw entry read from a Redis stream
+type StreamEntry struct {
+	Stream  string
+	ID      string
+	Payload []byte
+}
+
+// ReadStreams reads entries after the given per-stream IDs, blocking up to
+// block for new data. Streams without an offset start from the beginning.
+func (rc *RedisClient) ReadStreams(ctx context.Context, offsets map[string]string, streams []string, count int64, block time.Duration) ([]StreamEntry, error) {
+	args := make([]string, 0, len(streams)*2)
+	args = append(args, streams...)
+	for _, stream := range streams {
+		offset := offsets[stream]
+		if offset == "" {
+			offset = "0"
+		}
+		args = append(args, offset)
+	}
+
+	results, err := rc.client.XRead(ctx, &redis.XReadArgs{
+		Streams: args,
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []StreamEntry
+	for _, stream := range results {
+		entries = append(entries, streamEntries(stream.Stream, stream.Messages)...)
+	}
+	return entries, nil
+}
+
+// AppendStream adds an entry to a stream, trimming it to roughly maxLen
+// entries when maxLen is positive
+func (rc *RedisClient) AppendStream(ctx context.Context, stream string, payload []byte, maxLen int64) error {
+	return rc.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: map[string]interface{}{"event": payload},
+	}).Err()
+}
+
+// EnsureGroup creates a consumer group reading new entries of a stream,
+// creating the stream too if needed. Existing groups are left as they are.
+func (rc *RedisClient) EnsureGroup(ctx context.Context, stream, group string) error {
+	err := rc.client.XGroupCreateMkStream(ctx, stream, group, "$").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// ReadGroup reads entries not yet delivered to any consumer of the group,
+// blocking up to block for new data
+func (rc *RedisClient) ReadGroup(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]StreamEntry, error) {
+	results, err := rc.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{stream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []StreamEntry
+	for _, result := range results {
+		entries = append(entries, streamEntries(result.Stream, result.Messages)...)
+	}
+	return entries, nil
+}
+
+// ClaimStale takes over entries other consumers of the group received but
+// did not acknowledge within minIdle, e.g. because they crashed
+func (rc *RedisClient) ClaimStale(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]StreamEntry, error) {
+	messages, _, err := rc.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return streamEntries(stream, messages), nil
+}
+
+// AckStream marks entries as processed by the group
+func (rc *RedisClient) AckStream(ctx context.Context, stream, group string, ids ...string) error {
+	return rc.client.XAck(ctx, stream, group, ids...).Err()
+}
+
+// streamEntries converts stream messages carrying an "event" field
+func streamEntries(stream string, messages []redis.XMessage) []StreamEntry {
+	entries := make([]StreamEntry, 0, len(messages))
+	for _, msg := range messages {
+		payload, _ := msg.Values["event"].(string)
+		entries = append(entries, StreamEntry{Stream: stream, ID: msg.ID, Payload: []byte(payload)})
+	}
+	return entries
+}
+
+// LoadCheckpoints returns the committed offset of every partition for a consumer
+func (rc *RedisClient) LoadCheckpoints(ctx context.Context, consumer string) (map[string]string, error) {
+	return rc.client.HGetAll(ctx, fmt.Sprintf("checkpoints:%s", consumer)).Result()
+}
+
+// SaveCheckpoint commits a partition offset for a consumer
+func (rc *RedisClient) SaveCheckpoint(ctx context.Context, consumer, partition, offset string) error {
+	return rc.client.HSet(ctx, fmt.Sprintf("checkpoints:%s", consumer), partition, offset).Err()
+}
+
+// Each session's serialized state is checkpointed under its own key, so
+// instances owning different sessions never overwrite each other. The index
+// set lists every checkpointed session.
+const (
+	statsCheckpointPrefix = "stats:checkpoint:"
+	statsCheckpointIndex  = "stats:checkpoints"
+)
+
+// SaveSessionStates writes the checkpoints of the given sessions and deletes
+// those of removed sessions in one transaction. Other sessions' checkpoints
+// are left as they are.
+func (rc *RedisClient) SaveSessionStates(ctx context.Context, states map[string][]byte, removed []string) error {
+	if len(states) == 0 && len(removed) == 0 {
+		return nil
+	}
+	_, err := rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for sessionID, data := range states {
+			pipe.Set(ctx, statsCheckpointPrefix+sessionID, data, 0)
+			pipe.SAdd(ctx, statsCheckpointIndex, sessionID)
+		}
+		for _, sessionID := range removed {
+			pipe.Del(ctx, statsCheckpointPrefix+sessionID)
+			pipe.SRem(ctx, statsCheckpointIndex, sessionID)
+		}
+		return nil
+	})
+	return err
+}
+
+// LoadSessionStates returns the checkpoints of the given sessions, or of
+// every checkpointed session when none are given. Sessions without a
+// checkpoint are left out.
+func (rc *RedisClient) LoadSessionStates(ctx context.Context, sessionIDs ...string) (map[string][]byte, error) {
+	if len(sessionIDs) == 0 {
+		all, err := rc.client.SMembers(ctx, statsCheckpointIndex).Result()
+		if err != nil {
+			return nil, err
+		}
+		sessionIDs = all
+	}
+	states := make(map[string][]byte, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return states, nil
+	}
+
+	keys := make([]string, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		keys[i] = statsCheckpointPrefix + sessionID
+	}
+	values, err := rc.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if data, ok := value.(string); ok {
+			states[sessionIDs[i]] = []byte(data)
+		}
+	}
+	return states, nil
+}
+
+// auditLogTTL bounds how long raw events of audited sessions are kept
+const auditLogTTL = 24 * time.Hour
+
+// AppendAuditEvent appends a raw event to an audited session's event log
+func (rc *RedisClient) AppendAuditEvent(ctx context.Context, sessionID string, payload []byte) error {
+	key := fmt.Sprintf("audit:events:%s", sessionID)
+	pipe := rc.client.Pipeline()
+	pipe.RPush(ctx, key, payload)
+	pipe.Expire(ctx, key, auditLogTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LoadAuditEvents returns every logged raw event of an audited session
+func (rc *RedisClient) LoadAuditEvents(ctx context.Context, sessionID string) ([][]byte, error) {
+	values, err := rc.client.LRange(ctx, fmt.Sprintf("audit:events:%s", sessionID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	payloads := make([][]byte, len(values))
+	for i, value := range values {
+		payloads[i] = []byte(value)
+	}
+	return payloads, nil
 }
 
 // Close gracefully closes the redis client
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// SessionWebhook is an endpoint registered to receive one session's
+// notifications, kept so it survives restarts and failovers
+type SessionWebhook struct {
+	ID        string
+	SessionID string
+	URL       string
+	Types     []string
+	Secret    string
+	CreatedAt time.Time
+}
+
+// SaveSessionWebhooks stores newly registered session webhooks
+func (db *PostgresClient) SaveSessionWebhooks(ctx context.Context, hooks []SessionWebhook) error {
+	query := `
+		INSERT INTO session_webhooks (id, session_id, url, types, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, h := range hooks {
+		if _, err := tx.Exec(ctx, query, h.ID, h.SessionID, h.URL, h.Types, h.Secret, h.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteSessionWebhook removes one of a session's webhooks
+func (db *PostgresClient) DeleteSessionWebhook(ctx context.Context, sessionID, id string) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM session_webhooks WHERE session_id = $1 AND id = $2`, sessionID, id)
+	return err
+}
+
+// DeleteSessionWebhooks removes every webhook of a session
+func (db *PostgresClient) DeleteSessionWebhooks(ctx context.Context, sessionID string) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM session_webhooks WHERE session_id = $1`, sessionID)
+	return err
+}
+
+// ListSessionWebhooks returns every stored session webhook in the order
+// they were registered
+func (db *PostgresClient) ListSessionWebhooks(ctx context.Context) ([]SessionWebhook, error) {
+	query := `SELECT id, session_id, url, types, secret, created_at FROM session_webhooks ORDER BY created_at, id`
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []SessionWebhook
+	for rows.Next() {
+		var h SessionWebhook
+		if err := rows.Scan(&h.ID, &h.SessionID, &h.URL, &h.Types, &h.Secret, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
@@ -0,0 +1,36 @@
+package storage
+
+import "context"
+
+// undrainedEventsKey lists the raw events an instance could not process
+// before shutting down, oldest first
+const undrainedEventsKey = "events:undrained"
+
+// SaveUndrainedEvents appends raw events left unprocessed at shutdown so the
+// next instance to start can replay them
+func (rc *RedisClient) SaveUndrainedEvents(ctx context.Context, payloads [][]byte) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(payloads))
+	for i, payload := range payloads {
+		values[i] = payload
+	}
+	return rc.client.RPush(ctx, undrainedEventsKey, values...).Err()
+}
+
+// TakeUndrainedEvents removes and returns every raw event left unprocessed
+// by earlier instances
+func (rc *RedisClient) TakeUndrainedEvents(ctx context.Context) ([][]byte, error) {
+	pipe := rc.client.TxPipeline()
+	values := pipe.LRange(ctx, undrainedEventsKey, 0, -1)
+	pipe.Del(ctx, undrainedEventsKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	payloads := make([][]byte, len(values.Val()))
+	for i, value := range values.Val() {
+		payloads[i] = []byte(value)
+	}
+	return payloads, nil
+}
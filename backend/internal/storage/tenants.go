@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NewTenantPostgresClient connects to an isolated tenant's schema. Its
+// connections use the schema as search_path, so tenant-scoped tables resolve
+// there; a tenant-specific database URL keeps its credentials separate too.
+func NewTenantPostgresClient(ctx context.Context, databaseURL, schema string) (*PostgresClient, error) {
+	return newPostgresClient(ctx, databaseURL, pgx.Identifier{schema}.Sanitize())
+}
+
+// InitTenantSchema creates the tenant's schema and its tenant-scoped tables
+func (db *PostgresClient) InitTenantSchema(ctx context.Context, schema string) error {
+	if _, err := db.pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return fmt.Errorf("unable to create schema %s: %w", schema, err)
+	}
+	_, err := db.pool.Exec(ctx, tenantTables)
+	return err
+}
+
+// TenantDatabases routes tenant-scoped data, i.e. session archives and
+// viewer history, to the storage of tenants with isolated storage and to
+// the shared database for everyone else
+type TenantDatabases struct {
+	shared   *PostgresClient
+	isolated map[string]*PostgresClient
+}
+
+// NewTenantDatabases creates a router sending every tenant to shared until
+// isolated tenants are added
+func NewTenantDatabases(shared *PostgresClient) *TenantDatabases {
+	return &TenantDatabases{shared: shared, isolated: make(map[string]*PostgresClient)}
+}
+
+// Isolate routes a tenant's data to its own database. It must be called
+// before the router is used.
+func (t *TenantDatabases) Isolate(tenantID string, db *PostgresClient) {
+	t.isolated[tenantID] = db
+}
+
+// For returns the database holding a tenant's data
+func (t *TenantDatabases) For(tenantID string) *PostgresClient {
+	if db, isolated := t.isolated[tenantID]; isolated {
+		return db
+	}
+	return t.shared
+}
+
+// IsIsolated reports whether a tenant keeps its data apart from the shared database
+func (t *TenantDatabases) IsIsolated(tenantID string) bool {
+	_, isolated := t.isolated[tenantID]
+	return isolated
+}
+
+// Close closes the isolated tenants' connection pools; the shared client is
+// closed by its owner
+func (t *TenantDatabases) Close() {
+	for _, db := range t.isolated {
+		db.Close()
+	}
+}
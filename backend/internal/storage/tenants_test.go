@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantDatabases_RoutesIsolatedTenants(t *testing.T) {
+	shared, isolated := &PostgresClient{}, &PostgresClient{}
+	databases := NewTenantDatabases(shared)
+	databases.Isolate("acme", isolated)
+
+	assert.Same(t, isolated, databases.For("acme"))
+	assert.True(t, databases.IsIsolated("acme"))
+	assert.Same(t, shared, databases.For("globex"))
+	assert.False(t, databases.IsIsolated("globex"))
+	assert.Same(t, shared, databases.For(""), "untenanted data stays shared")
+}
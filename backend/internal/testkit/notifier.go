@@ -0,0 +1,75 @@
+package testkit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/notifications"
+)
+
+// Notifier records the notifications a server sends instead of delivering
+// them to webhook endpoints
+type Notifier struct {
+	mu      sync.Mutex
+	events  []notifications.Event
+	changed chan struct{} // closed and replaced on every notification
+}
+
+// NewNotifier creates a notifier with nothing recorded
+func NewNotifier() *Notifier {
+	return &Notifier{changed: make(chan struct{})}
+}
+
+// Webhook returns a webhook notifier without endpoints that records every
+// notification here, for code that takes a *notifications.WebhookNotifier
+func (n *Notifier) Webhook() *notifications.WebhookNotifier {
+	webhook := notifications.NewWebhookNotifier(nil, "")
+	webhook.AddSink(n)
+	return webhook
+}
+
+// Notify records an event
+func (n *Notifier) Notify(event notifications.Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// Events returns the recorded events of a type in the order sent, or
+// every recorded event for an empty type
+func (n *Notifier) Events(eventType string) []notifications.Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var matched []notifications.Event
+	for _, event := range n.events {
+		if eventType == "" || event.Type == eventType {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+// WaitFor returns the first recorded event of a type for the session,
+// waiting up to timeout for it to be sent
+func (n *Notifier) WaitFor(eventType, sessionID string, timeout time.Duration) (notifications.Event, bool) {
+	deadline := time.After(timeout)
+	for {
+		n.mu.Lock()
+		changed := n.changed
+		for _, event := range n.events {
+			if event.Type == eventType && event.SessionID == sessionID {
+				n.mu.Unlock()
+				return event, true
+			}
+		}
+		n.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return notifications.Event{}, false
+		}
+	}
+}
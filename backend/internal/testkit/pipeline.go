@@ -0,0 +1,222 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/aggregation"
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/milestones"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/jrudman25/livepulse/internal/sessions"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Stores are the backends a pipeline persists to. A nil State or
+// Milestones keeps that state in memory only, and a nil Archive skips
+// archiving the snapshots of ended sessions.
+type Stores struct {
+	State      aggregation.StateStore
+	Milestones milestones.Store
+	Archive    *storage.PostgresClient
+}
+
+// celebrationGap is the server's default CELEBRATION_MIN_GAP
+const celebrationGap = 4 * time.Second
+
+// Pipeline is the core of a server's event path wired in process: events
+// enqueued pass the session admission checks, are aggregated by workers
+// and checked for milestones, achievements are celebrated in the session's
+// clients and sent to the Notifier, and state is checkpointed to the
+// stores. It leaves out the server's deployment-specific stages: skew,
+// dedupe, filters, cluster routing, fraud, reaction caps, waves, presence
+// and chat.
+type Pipeline struct {
+	Queue        *events.Queue
+	Workers      *events.WorkerPool
+	Aggregation  *aggregation.Manager
+	Milestones   *milestones.Tracker
+	Hub          *api.WebSocketHub
+	Celebrations *api.CelebrationCoordinator
+	Sessions     *sessions.Registry
+	Server       *api.Server
+	Notifier     *Notifier
+
+	stores Stores
+	cancel context.CancelFunc
+}
+
+// NewPipeline wires a pipeline persisting to stores. Call Start before
+// ingesting events and Close when done.
+func NewPipeline(stores Stores) *Pipeline {
+	p := &Pipeline{
+		Queue:       events.NewQueue(1000),
+		Aggregation: aggregation.NewManager(),
+		Hub:         api.NewWebSocketHub(),
+		Sessions:    sessions.NewRegistry(),
+		Notifier:    NewNotifier(),
+		stores:      stores,
+	}
+	p.Celebrations = api.NewCelebrationCoordinator(p.Hub, celebrationGap)
+	notifier := p.Notifier.Webhook()
+
+	p.Milestones = milestones.NewTracker(p.Celebrations.Broadcaster(func(achievement *milestones.MilestoneAchievement) {
+		notifier.Notify(notifications.Event{
+			Type:       notifications.TypeMilestoneAchieved,
+			SessionID:  achievement.SessionID,
+			OccurredAt: achievement.AchievedAt,
+			Data:       achievement,
+		})
+	}))
+	p.Milestones.SetUnlockHandler(api.MilestoneUnlockBroadcaster(p.Hub, func(unlock *milestones.MilestoneUnlock) {
+		notifier.Notify(notifications.Event{
+			Type:       notifications.TypeMilestoneUnlocked,
+			SessionID:  unlock.SessionID,
+			OccurredAt: unlock.UnlockedAt,
+			Data:       unlock,
+		})
+	}))
+	if stores.Milestones != nil {
+		p.Milestones.SetStore(stores.Milestones, 3)
+	}
+	p.Server = api.NewServer(p.Queue, p.Aggregation, p.Milestones, p.Hub, stores.Archive, nil, p.Sessions, notifier)
+
+	admit := func(_ context.Context, event *events.Event) error {
+		if err := sessions.ValidateID(event.SessionID); err != nil {
+			return events.ErrSkip
+		}
+		event.SessionID = p.Sessions.Canonical(event.SessionID)
+		if p.Sessions.IsEnded(event.SessionID) || p.Sessions.IsDeleted(event.SessionID) {
+			return events.ErrSkip
+		}
+		if event.ClaimsSystemIdentity() {
+			return events.ErrSkip
+		}
+		if event.Type != events.EventTypeLeaveSession && p.Sessions.IsBanned(event.SessionID, event.UserID) {
+			return events.ErrSkip
+		}
+		p.Sessions.Touch(event.SessionID)
+		return nil
+	}
+	aggregate := func(_ context.Context, event *events.Event) error {
+		return p.Aggregation.ProcessEvent(event)
+	}
+	checkMilestones := func(_ context.Context, event *events.Event) error {
+		if stats, exists := p.Aggregation.GetSession(event.SessionID); exists {
+			p.Milestones.CheckMilestones(event.SessionID, stats)
+		}
+		return nil
+	}
+	p.Workers = events.NewWorkerPool(p.Queue, 2, nil)
+	p.Workers.Use(admit)
+	p.Workers.Handle(events.EventTypeJoinSession, aggregate, checkMilestones)
+	p.Workers.Handle(events.EventTypeLeaveSession, aggregate, checkMilestones)
+	p.Workers.Handle(events.EventTypeReaction, aggregate, checkMilestones)
+	return p
+}
+
+// Start launches the workers and the celebration queue
+func (p *Pipeline) Start() {
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.Celebrations.Start(ctx)
+	p.Workers.Start()
+}
+
+// Close stops the workers once the events already enqueued are processed,
+// then the celebration queue
+func (p *Pipeline) Close(ctx context.Context) error {
+	err := p.Workers.Shutdown(ctx)
+	if p.cancel != nil {
+		p.cancel()
+	}
+	return err
+}
+
+// CreateSession registers a live session with milestones at thresholds
+// total reactions
+func (p *Pipeline) CreateSession(sessionID string, thresholds ...int) {
+	p.Sessions.Create(sessions.Session{ID: sessionID})
+	if len(thresholds) > 0 {
+		p.Milestones.InitializeSession(sessionID, thresholds)
+	}
+}
+
+// Ingest enqueues events as the server's ingest endpoints do
+func (p *Pipeline) Ingest(ctx context.Context, evs ...*events.Event) error {
+	for _, event := range evs {
+		if err := p.Queue.Enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the messages broadcast to a session's clients from now
+// on and a function that stops them
+func (p *Pipeline) Subscribe(sessionID string) (<-chan []byte, func()) {
+	return p.Hub.GetOrCreateSessionHub(sessionID).Subscribe(64)
+}
+
+// WaitForReactions reports whether the session's total reactions reach
+// total within timeout
+func (p *Pipeline) WaitForReactions(sessionID string, total int64, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if stats, exists := p.Aggregation.GetSession(sessionID); exists && stats.GetTotalReactions() >= total {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Checkpoint writes aggregation and milestone state to the stores, as the
+// server does periodically and on shutdown
+func (p *Pipeline) Checkpoint(ctx context.Context) error {
+	if p.stores.State != nil {
+		if err := p.Aggregation.Checkpoint(ctx, p.stores.State); err != nil {
+			return err
+		}
+	}
+	return p.Milestones.Persist(ctx)
+}
+
+// Restore loads the state checkpointed to the stores, as a server does on
+// startup, and returns how many sessions' aggregates were restored
+func (p *Pipeline) Restore(ctx context.Context) (int, error) {
+	restored := 0
+	if p.stores.State != nil {
+		var err error
+		if restored, err = p.Aggregation.Restore(ctx, p.stores.State, nil); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := p.Milestones.Load(ctx); err != nil {
+		return restored, err
+	}
+	return restored, nil
+}
+
+// NextMessage returns the next message of a type among those received on a
+// subscription, skipping others, waiting up to timeout for it
+func NextMessage(updates <-chan []byte, messageType string, timeout time.Duration) ([]byte, bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case data := <-updates:
+			var message struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(data, &message) == nil && message.Type == messageType {
+				return data, true
+			}
+		case <-deadline:
+			return nil, false
+		}
+	}
+}
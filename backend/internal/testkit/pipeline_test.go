@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/api"
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_IngestToRestore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	pipeline := NewPipeline(Stores{State: store, Milestones: store})
+	pipeline.Start()
+	defer pipeline.Close(ctx)
+
+	pipeline.CreateSession("s1", 3)
+	updates, unsubscribe := pipeline.Subscribe("s1")
+	defer unsubscribe()
+
+	require.NoError(t, pipeline.Ingest(ctx, events.JoinSessionEvent("s1", "u1")))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pipeline.Ingest(ctx, events.ReactionEvent("s1", "u1", events.ReactionFire)))
+	}
+	require.True(t, pipeline.WaitForReactions("s1", 3, time.Second))
+
+	data, ok := NextMessage(updates, api.MessageTypeMilestoneAchieved, time.Second)
+	require.True(t, ok, "the milestone is broadcast to the session")
+	var message api.MilestoneAchievedMessage
+	require.NoError(t, json.Unmarshal(data, &message))
+	assert.Equal(t, int64(3), message.Milestone.Threshold)
+	_, ok = pipeline.Notifier.WaitFor(notifications.TypeMilestoneAchieved, "s1", time.Second)
+	assert.True(t, ok, "the milestone is sent to webhooks")
+
+	require.NoError(t, pipeline.Checkpoint(ctx))
+	assert.Equal(t, []string{"s1"}, store.SessionIDs())
+
+	// A server starting from the same store resumes warm, without
+	// celebrating the milestone again
+	next := NewPipeline(Stores{State: store, Milestones: store})
+	restored, err := next.Restore(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	stats, exists := next.Aggregation.GetSession("s1")
+	require.True(t, exists)
+	assert.Equal(t, int64(3), stats.GetTotalReactions())
+	require.Len(t, next.Milestones.GetAchievedMilestones("s1"), 1)
+
+	next.Start()
+	defer next.Close(ctx)
+	require.NoError(t, next.Ingest(ctx, events.ReactionEvent("s1", "u1", events.ReactionFire)))
+	require.True(t, next.WaitForReactions("s1", 4, time.Second))
+	_, ok = next.Notifier.WaitFor(notifications.TypeMilestoneAchieved, "s1", 100*time.Millisecond)
+	assert.False(t, ok)
+}
+
+func TestPipeline_AdmitsEventsLikeTheServer(t *testing.T) {
+	ctx := context.Background()
+	pipeline := NewPipeline(Stores{})
+	pipeline.Start()
+	defer pipeline.Close(ctx)
+
+	pipeline.CreateSession("s1")
+	pipeline.CreateSession("ended")
+	pipeline.Sessions.End("ended")
+	pipeline.Sessions.Ban("s1", "banned", "spam")
+
+	require.NoError(t, pipeline.Ingest(ctx,
+		events.ReactionEvent("ended", "u1", events.ReactionFire),
+		events.ReactionEvent("s1", "banned", events.ReactionFire),
+		events.ReactionEvent("s1", "u1", events.ReactionFire),
+	))
+	require.True(t, pipeline.WaitForReactions("s1", 1, time.Second))
+	require.NoError(t, pipeline.Close(ctx))
+
+	stats, _ := pipeline.Aggregation.GetSession("s1")
+	assert.Equal(t, int64(1), stats.GetTotalReactions(), "banned users' reactions are skipped")
+	_, exists := pipeline.Aggregation.GetSession("ended")
+	assert.False(t, exists, "ended sessions are not revived")
+}
@@ -0,0 +1,137 @@
+package testkit
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Store is an in-memory stand-in for Redis as the store of aggregation
+// checkpoints and milestone state. It satisfies aggregation.StateStore and
+// milestones.Store, and can be shared by pipelines to act as one cluster.
+type Store struct {
+	mu         sync.Mutex
+	states     map[string][]byte
+	milestones map[string]storage.MilestoneState
+	err        error
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		states:     make(map[string][]byte),
+		milestones: make(map[string]storage.MilestoneState),
+	}
+}
+
+// Fail makes every call return err until Fail(nil) is called, to simulate
+// an outage of the store
+func (s *Store) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// SessionIDs returns the sessions with a checkpoint, sorted
+func (s *Store) SessionIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.states))
+	for sessionID := range s.states {
+		ids = append(ids, sessionID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SaveSessionStates writes checkpoints and deletes those of removed sessions
+func (s *Store) SaveSessionStates(ctx context.Context, states map[string][]byte, removed []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	for sessionID, data := range states {
+		s.states[sessionID] = append([]byte(nil), data...)
+	}
+	for _, sessionID := range removed {
+		delete(s.states, sessionID)
+	}
+	return nil
+}
+
+// LoadSessionStates returns the given sessions' checkpoints, or every
+// checkpoint when none are given
+func (s *Store) LoadSessionStates(ctx context.Context, sessionIDs ...string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	states := make(map[string][]byte)
+	if len(sessionIDs) == 0 {
+		for sessionID, data := range s.states {
+			states[sessionID] = data
+		}
+		return states, nil
+	}
+	for _, sessionID := range sessionIDs {
+		if data, exists := s.states[sessionID]; exists {
+			states[sessionID] = data
+		}
+	}
+	return states, nil
+}
+
+// SaveMilestoneState writes a session's milestones if their stored version
+// is still version, like the Redis implementation's compare-and-set
+func (s *Store) SaveMilestoneState(ctx context.Context, sessionID string, data []byte, version int64) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, false, s.err
+	}
+	if s.milestones[sessionID].Version != version {
+		return 0, false, nil
+	}
+	s.milestones[sessionID] = storage.MilestoneState{Data: append([]byte(nil), data...), Version: version + 1}
+	return version + 1, true, nil
+}
+
+// LoadMilestoneStates returns the given sessions' milestones and versions,
+// or every session's when none are given
+func (s *Store) LoadMilestoneStates(ctx context.Context, sessionIDs ...string) (map[string]storage.MilestoneState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	states := make(map[string]storage.MilestoneState)
+	if len(sessionIDs) == 0 {
+		for sessionID, state := range s.milestones {
+			states[sessionID] = state
+		}
+		return states, nil
+	}
+	for _, sessionID := range sessionIDs {
+		if state, exists := s.milestones[sessionID]; exists {
+			states[sessionID] = state
+		}
+	}
+	return states, nil
+}
+
+// DeleteMilestoneStates deletes the milestones of removed sessions
+func (s *Store) DeleteMilestoneStates(ctx context.Context, sessionIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	for _, sessionID := range sessionIDs {
+		delete(s.milestones, sessionID)
+	}
+	return nil
+}
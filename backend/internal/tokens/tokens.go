@@ -0,0 +1,289 @@
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verification errors. Tokens that are malformed or signed with a key the
+// service does not hold are likely meant for another verifier, such as the
+// identity provider.
+var (
+	ErrMalformed    = errors.New("malformed viewer token")
+	ErrUnknownKey   = errors.New("viewer token signed with an unknown key")
+	ErrSignature    = errors.New("invalid viewer token signature")
+	ErrExpired      = errors.New("viewer token expired")
+	ErrWrongSession = errors.New("viewer token is for another session")
+)
+
+// Claims identify the viewer a token was issued to and the one session it
+// grants access to
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"` // user ID
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// JWK is the public half of a signing key, as published for verifiers
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// KeySet is a JWKS document
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// key is an ES256 key and its ID, the RFC 7638 thumbprint of its public half
+type key struct {
+	id      string
+	private *ecdsa.PrivateKey
+	public  JWK
+}
+
+// Service issues and verifies short-lived ES256 viewer tokens scoped to a
+// session. The first key signs; the others only verify, so a key rotated
+// out keeps its tokens valid until they expire.
+type Service struct {
+	issuer string
+	ttl    time.Duration
+
+	mu   sync.RWMutex
+	keys []*key // the signing key first
+}
+
+// NewService creates a service issuing tokens valid for ttl, signed with
+// the first of keys
+func NewService(issuer string, ttl time.Duration, keys []*ecdsa.PrivateKey) (*Service, error) {
+	s := &Service{issuer: issuer, ttl: ttl}
+	if err := s.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetKeys replaces the keys, rotating in a new signing key. Keep the
+// previous signing key listed for at least one TTL so the tokens it signed
+// are still accepted.
+func (s *Service) SetKeys(privateKeys []*ecdsa.PrivateKey) error {
+	if len(privateKeys) == 0 {
+		return errors.New("at least one viewer token key is required")
+	}
+	keys := make([]*key, 0, len(privateKeys))
+	for _, private := range privateKeys {
+		if private.Curve != elliptic.P256() {
+			return errors.New("viewer token keys must be ECDSA P-256 keys")
+		}
+		public, err := publicJWK(&private.PublicKey)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, &key{id: public.Kid, private: private, public: public})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	return nil
+}
+
+// TTL returns how long issued tokens stay valid
+func (s *Service) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue signs a token granting userID access to sessionID until TTL from
+// now, and returns it with its expiry
+func (s *Service) Issue(sessionID, userID string, now time.Time) (string, time.Time, error) {
+	s.mu.RLock()
+	signing := s.keys[0]
+	s.mu.RUnlock()
+
+	expiresAt := now.Add(s.ttl).Truncate(time.Second).UTC()
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": signing.id})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims, err := json.Marshal(Claims{
+		Issuer:    s.issuer,
+		Subject:   userID,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(input))
+	r, sig, err := ecdsa.Sign(rand.Reader, signing.private, digest[:])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// JWS wants the fixed-width concatenation of r and s, not DER
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), expiresAt, nil
+}
+
+// Verify checks a token's signature and expiry and that it was issued for
+// sessionID, and returns its claims
+func (s *Service) Verify(token, sessionID string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrMalformed
+	}
+	if header.Alg != "ES256" {
+		return Claims{}, ErrUnknownKey
+	}
+	public := s.lookup(header.Kid)
+	if public == nil {
+		return Claims{}, ErrUnknownKey
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return Claims{}, ErrSignature
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, sig := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(public, digest[:], r, sig) {
+		return Claims{}, ErrSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrMalformed
+	}
+	if claims.Issuer != s.issuer || claims.Subject == "" {
+		return Claims{}, ErrSignature
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpired
+	}
+	if claims.SessionID != sessionID {
+		return Claims{}, ErrWrongSession
+	}
+	return claims, nil
+}
+
+// lookup returns the public key with the given ID, or nil
+func (s *Service) lookup(kid string) *ecdsa.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.id == kid {
+			return &k.private.PublicKey
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys tokens may be signed with
+func (s *Service) JWKS() KeySet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := KeySet{Keys: make([]JWK, len(s.keys))}
+	for i, k := range s.keys {
+		set.Keys[i] = k.public
+	}
+	return set
+}
+
+// decodeSegment decodes a base64url JSON token segment into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// publicJWK describes a P-256 public key as a JWK identified by its RFC
+// 7638 thumbprint
+func publicJWK(public *ecdsa.PublicKey) (JWK, error) {
+	ecdhKey, err := public.ECDH()
+	if err != nil {
+		return JWK{}, err
+	}
+	point := ecdhKey.Bytes() // 0x04 || X || Y
+	jwk := JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(point[1:33]),
+		Y:   base64.RawURLEncoding.EncodeToString(point[33:]),
+		Use: "sig",
+		Alg: "ES256",
+	}
+	// The thumbprint hashes the required members in lexicographic order
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`, jwk.Crv, jwk.Kty, jwk.X, jwk.Y)))
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	return jwk, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 or SEC 1 ECDSA private key
+func ParsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "EC PRIVATE KEY" {
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an ECDSA key")
+	}
+	return private, nil
+}
+
+// LoadKeyFiles reads the PEM private keys at paths, in order
+func LoadKeyFiles(paths []string) ([]*ecdsa.PrivateKey, error) {
+	keys := make([]*ecdsa.PrivateKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("viewer token key: %w", err)
+		}
+		private, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("viewer token key %s: %w", path, err)
+		}
+		keys = append(keys, private)
+	}
+	return keys, nil
+}
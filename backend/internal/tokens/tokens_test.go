@@ -0,0 +1,100 @@
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func TestService_IssuesTokensScopedToASession(t *testing.T) {
+	service, err := NewService("livepulse", 5*time.Minute, []*ecdsa.PrivateKey{newKey(t)})
+	require.NoError(t, err)
+	now := time.Now()
+
+	token, expiresAt, err := service.Issue("s1", "user_1", now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(5*time.Minute), expiresAt, time.Second)
+
+	claims, err := service.Verify(token, "s1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "user_1", claims.Subject)
+	assert.Equal(t, "s1", claims.SessionID)
+
+	_, err = service.Verify(token, "s2", now)
+	assert.True(t, errors.Is(err, ErrWrongSession))
+	_, err = service.Verify(token, "s1", now.Add(6*time.Minute))
+	assert.True(t, errors.Is(err, ErrExpired))
+
+	parts := strings.Split(token, ".")
+	forged, _, err := service.Issue("s1", "user_2", now)
+	require.NoError(t, err)
+	_, err = service.Verify(parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2], "s1", now)
+	assert.True(t, errors.Is(err, ErrSignature), "claims cannot be swapped under another signature")
+
+	_, err = service.Verify("not-a-jwt", "s1", now)
+	assert.True(t, errors.Is(err, ErrMalformed))
+}
+
+func TestService_RotatedKeysVerifyUntilRemoved(t *testing.T) {
+	oldKey, newKey := newKey(t), newKey(t)
+	service, err := NewService("livepulse", time.Minute, []*ecdsa.PrivateKey{oldKey})
+	require.NoError(t, err)
+	now := time.Now()
+	oldToken, _, err := service.Issue("s1", "user_1", now)
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetKeys([]*ecdsa.PrivateKey{newKey, oldKey}))
+	_, err = service.Verify(oldToken, "s1", now)
+	assert.NoError(t, err, "tokens signed before the rotation stay valid")
+	newToken, _, err := service.Issue("s1", "user_1", now)
+	require.NoError(t, err)
+
+	set := service.JWKS()
+	require.Len(t, set.Keys, 2)
+	assert.NotEqual(t, set.Keys[0].Kid, set.Keys[1].Kid)
+	assert.Equal(t, "ES256", set.Keys[0].Alg)
+
+	require.NoError(t, service.SetKeys([]*ecdsa.PrivateKey{newKey}))
+	_, err = service.Verify(oldToken, "s1", now)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	_, err = service.Verify(newToken, "s1", now)
+	assert.NoError(t, err)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := newKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	for _, block := range []*pem.Block{{Type: "PRIVATE KEY", Bytes: pkcs8}, {Type: "EC PRIVATE KEY", Bytes: sec1}} {
+		parsed, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		require.NoError(t, err, block.Type)
+		assert.True(t, parsed.Equal(key), block.Type)
+	}
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewService("livepulse", time.Minute, []*ecdsa.PrivateKey{p384})
+	assert.Error(t, err, "only P-256 keys sign ES256")
+}
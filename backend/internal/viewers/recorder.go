@@ -0,0 +1,123 @@
+package viewers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/logging"
+	"github.com/jrudman25/livepulse/internal/storage"
+)
+
+// Recorder tuning: visits wait in a bounded buffer and are written in
+// batches of up to maxBatch per flush
+const (
+	bufferSize = 10000
+	maxBatch   = 500
+)
+
+// droppedLog samples visits dropped while the buffer is full, which come in
+// bursts when the database falls behind
+var droppedLog = logging.NewSampler("Viewer history visits dropped", 10, time.Second)
+
+// Store records a tenant's viewer history, returning each user's first
+// session
+type Store interface {
+	RecordViewers(ctx context.Context, tenantID string, visits []storage.ViewerVisit) (map[string]string, error)
+}
+
+// Recorder writes viewer history off the event pipeline. Joins are queued
+// without blocking and written in batches per tenant; once a batch is
+// written, each visit is reported as a first-time or returning viewer.
+type Recorder struct {
+	stores   func(tenantID string) Store
+	classify func(visit storage.ViewerVisit, kind string)
+	visits   chan storage.ViewerVisit
+}
+
+// NewRecorder creates a recorder writing each tenant's visits to the store
+// stores returns and passing classifications to classify
+func NewRecorder(stores func(tenantID string) Store, classify func(visit storage.ViewerVisit, kind string)) *Recorder {
+	return &Recorder{
+		stores:   stores,
+		classify: classify,
+		visits:   make(chan storage.ViewerVisit, bufferSize),
+	}
+}
+
+// Record queues a visit. It never blocks: when the buffer is full the visit
+// is dropped and the user simply stays unclassified for the session.
+func (r *Recorder) Record(visit storage.ViewerVisit) {
+	select {
+	case r.visits <- visit:
+	default:
+		droppedLog.Printf("WARNING: Viewer history buffer full, dropping visit of %s to session %s", visit.UserID, visit.SessionID)
+	}
+}
+
+// Start writes queued visits every interval, or as soon as a full batch is
+// waiting, until the context is cancelled. Visits still queued then are
+// written before it returns.
+func (r *Recorder) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		batch := make([]storage.ViewerVisit, 0, maxBatch)
+		for {
+			select {
+			case <-ctx.Done():
+				r.flush(context.Background(), r.drain(batch))
+				return
+			case visit := <-r.visits:
+				batch = append(batch, visit)
+				if len(batch) < maxBatch {
+					continue
+				}
+			case <-ticker.C:
+			}
+			r.flush(ctx, batch)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// drain appends every visit still queued to batch
+func (r *Recorder) drain(batch []storage.ViewerVisit) []storage.ViewerVisit {
+	for {
+		select {
+		case visit := <-r.visits:
+			batch = append(batch, visit)
+		default:
+			return batch
+		}
+	}
+}
+
+// flush writes a batch, one query per tenant, and classifies its visits
+func (r *Recorder) flush(ctx context.Context, batch []storage.ViewerVisit) {
+	byTenant := make(map[string][]storage.ViewerVisit)
+	for _, visit := range batch {
+		byTenant[visit.TenantID] = append(byTenant[visit.TenantID], visit)
+	}
+
+	for tenantID, visits := range byTenant {
+		firstSessions, err := r.stores(tenantID).RecordViewers(ctx, tenantID, visits)
+		if err != nil {
+			log.Printf("Error recording viewer history for %d visits of tenant %q: %v", len(visits), tenantID, err)
+			continue
+		}
+		for _, visit := range visits {
+			firstSessionID, known := firstSessions[visit.UserID]
+			if !known {
+				continue
+			}
+			if firstSessionID == visit.SessionID {
+				r.classify(visit, events.ViewerFirstTime)
+			} else {
+				r.classify(visit, events.ViewerReturning)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package viewers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/jrudman25/livepulse/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps each user's first session like the viewer_history table
+type memoryStore struct {
+	first   map[string]string
+	batches int
+	fail    bool
+	mu      sync.Mutex
+}
+
+func (m *memoryStore) RecordViewers(_ context.Context, _ string, visits []storage.ViewerVisit) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return nil, errors.New("database unavailable")
+	}
+	m.batches++
+	firstSessions := make(map[string]string)
+	for _, visit := range visits {
+		if _, seen := m.first[visit.UserID]; !seen {
+			m.first[visit.UserID] = visit.SessionID
+		}
+		firstSessions[visit.UserID] = m.first[visit.UserID]
+	}
+	return firstSessions, nil
+}
+
+type classification struct {
+	sessionID, userID, kind string
+}
+
+func TestRecorder_ClassifiesVisitsInBatchesPerTenant(t *testing.T) {
+	tenants := map[string]*memoryStore{
+		"":     {first: map[string]string{"regular": "older-session"}},
+		"acme": {first: map[string]string{}},
+	}
+	classified := make(chan classification, 10)
+	recorder := NewRecorder(
+		func(tenantID string) Store { return tenants[tenantID] },
+		func(visit storage.ViewerVisit, kind string) {
+			classified <- classification{visit.SessionID, visit.UserID, kind}
+		},
+	)
+
+	now := time.Now()
+	recorder.Record(storage.ViewerVisit{UserID: "regular", SessionID: "s1", SeenAt: now})
+	recorder.Record(storage.ViewerVisit{UserID: "newcomer", SessionID: "s1", SeenAt: now})
+	recorder.Record(storage.ViewerVisit{TenantID: "acme", UserID: "regular", SessionID: "acme:s1", SeenAt: now})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recorder.Start(ctx, 10*time.Millisecond)
+
+	got := make(map[classification]bool)
+	for i := 0; i < 3; i++ {
+		select {
+		case c := <-classified:
+			got[c] = true
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 3 visits were classified", i)
+		}
+	}
+	assert.Equal(t, map[classification]bool{
+		{"s1", "regular", events.ViewerReturning}:      true,
+		{"s1", "newcomer", events.ViewerFirstTime}:     true,
+		{"acme:s1", "regular", events.ViewerFirstTime}: true,
+	}, got, "viewer history is kept per tenant")
+	assert.Equal(t, 1, tenants[""].batches, "a tenant's queued visits are written together")
+}
+
+func TestRecorder_LeavesVisitsUnclassifiedWhenTheWriteFails(t *testing.T) {
+	store := &memoryStore{first: map[string]string{}, fail: true}
+	classified := 0
+	recorder := NewRecorder(func(string) Store { return store }, func(storage.ViewerVisit, string) { classified++ })
+
+	recorder.Record(storage.ViewerVisit{UserID: "u1", SessionID: "s1"})
+	recorder.flush(context.Background(), recorder.drain(nil))
+	assert.Zero(t, classified)
+}
+
+func TestRecorder_RecordNeverBlocks(t *testing.T) {
+	recorder := NewRecorder(func(string) Store { return &memoryStore{} }, func(storage.ViewerVisit, string) {})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < bufferSize+10; i++ {
+			recorder.Record(storage.ViewerVisit{UserID: "u", SessionID: "s1"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a full buffer")
+	}
+	require.Len(t, recorder.visits, bufferSize)
+}
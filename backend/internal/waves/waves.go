@@ -0,0 +1,237 @@
+package waves
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+)
+
+// Bounds on the countdown before a wave and the window its reactions are
+// counted in
+const (
+	MinDelay      = time.Second
+	MaxDelay      = time.Minute
+	DefaultDelay  = 10 * time.Second
+	MinWindow     = 250 * time.Millisecond
+	MaxWindow     = 10 * time.Second
+	DefaultWindow = 2 * time.Second
+)
+
+// maxWavesPerSession bounds how many finished waves a session keeps
+const maxWavesPerSession = 20
+
+// CompletionGrace is how long after a wave's window closes its result is
+// reported, so reactions received in the window but still queued for the
+// workers are counted
+const CompletionGrace = 3 * time.Second
+
+// ErrInProgress is returned when a session already has a wave that has not
+// finished
+var ErrInProgress = errors.New("a wave is already in progress for this session")
+
+// Wave is a synchronized reaction moment: clients count down to At and
+// react together, and reactions received within Window after At count
+// towards the wave. Participation is the percentage of the audience active
+// when the window opened who reacted in it. A wave completes
+// CompletionGrace after its window closes.
+type Wave struct {
+	ID                string              `json:"id"`
+	SessionID         string              `json:"session_id"`
+	SuggestedReaction events.ReactionType `json:"suggested_reaction,omitempty"`
+	At                time.Time           `json:"at"`
+	Window            time.Duration       `json:"-"`
+	WindowSeconds     float64             `json:"window_seconds"`
+	Audience          int                 `json:"audience"`
+	Participants      int                 `json:"participants"`
+	Reactions         int64               `json:"reactions"`
+	Participation     float64             `json:"participation"`
+	Completed         bool                `json:"completed"`
+
+	participants map[string]bool
+	timers       []*time.Timer // open and complete, stopped by Cancel
+}
+
+// counts reports whether a reaction received at t, of the given type,
+// lands in the wave
+func (w *Wave) counts(t time.Time, reactionType events.ReactionType) bool {
+	if t.Before(w.At) || !t.Before(w.At.Add(w.Window)) {
+		return false
+	}
+	return w.SuggestedReaction == "" || reactionType == w.SuggestedReaction
+}
+
+// Manager schedules waves and measures the reactions landing in them
+type Manager struct {
+	audience   func(sessionID string) int
+	onComplete func(wave Wave)
+	waves      map[string][]*Wave // sessionID -> waves, oldest first
+	mu         sync.Mutex
+}
+
+// NewManager creates a wave manager. audience returns a session's active
+// user count when a wave's window opens; onComplete, if set, is called with
+// each wave once its window has closed.
+func NewManager(audience func(sessionID string) int, onComplete func(wave Wave)) *Manager {
+	return &Manager{
+		audience:   audience,
+		onComplete: onComplete,
+		waves:      make(map[string][]*Wave),
+	}
+}
+
+// Schedule starts a countdown to a wave delay from now, counting reactions
+// of the suggested type, or of any type when it is empty
+func (m *Manager) Schedule(sessionID string, reaction events.ReactionType, delay, window time.Duration) (Wave, error) {
+	if delay < MinDelay || delay > MaxDelay {
+		return Wave{}, fmt.Errorf("delay must be between %s and %s", MinDelay, MaxDelay)
+	}
+	if window < MinWindow || window > MaxWindow {
+		return Wave{}, fmt.Errorf("window must be between %s and %s", MinWindow, MaxWindow)
+	}
+	if reaction != "" && !reaction.IsValid() {
+		return Wave{}, fmt.Errorf("unknown reaction type %q", reaction)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	waves := m.waves[sessionID]
+	if len(waves) > 0 && !waves[len(waves)-1].Completed {
+		return Wave{}, ErrInProgress
+	}
+	wave := &Wave{
+		ID:                events.NewEventID(),
+		SessionID:         sessionID,
+		SuggestedReaction: reaction,
+		At:                time.Now().UTC().Add(delay),
+		Window:            window,
+		WindowSeconds:     window.Seconds(),
+		participants:      make(map[string]bool),
+	}
+	waves = append(waves, wave)
+	if len(waves) > maxWavesPerSession {
+		waves = waves[1:]
+	}
+	m.waves[sessionID] = waves
+
+	wave.timers = []*time.Timer{
+		time.AfterFunc(delay, func() { m.open(wave) }),
+		time.AfterFunc(delay+window+CompletionGrace, func() { m.complete(wave) }),
+	}
+	return *wave, nil
+}
+
+// Cancel forgets a wave that has not completed, e.g. when clients could not
+// be told about it, without reporting a result
+func (m *Manager) Cancel(sessionID, waveID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	waves := m.waves[sessionID]
+	for i, wave := range waves {
+		if wave.ID != waveID || wave.Completed {
+			continue
+		}
+		for _, timer := range wave.timers {
+			timer.Stop()
+		}
+		m.waves[sessionID] = append(waves[:i:i], waves[i+1:]...)
+		return
+	}
+}
+
+// open records the audience the wave's participation is measured against
+func (m *Manager) open(wave *Wave) {
+	audience := m.audience(wave.SessionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wave.Audience = audience
+}
+
+// complete closes the wave's window and reports its participation
+func (m *Manager) complete(wave *Wave) {
+	m.mu.Lock()
+	if wave.Audience > 0 {
+		wave.Participation = float64(wave.Participants) / float64(wave.Audience) * 100
+		if wave.Participation > 100 {
+			wave.Participation = 100 // users who joined during the window
+		}
+	}
+	wave.Completed = true
+	wave.participants = nil
+	wave.timers = nil
+	completed := *wave
+	m.mu.Unlock()
+
+	if m.onComplete != nil {
+		m.onComplete(completed)
+	}
+}
+
+// Observe counts a reaction towards the session's wave if it was received
+// during the wave's window, however long it was queued before the workers
+// processed it. Reactions without a user still count towards the wave's
+// reactions, but not its participants.
+func (m *Manager) Observe(event *events.Event) {
+	reactionType, ok := event.GetReactionType()
+	if !ok {
+		return
+	}
+	receivedAt := event.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = event.Timestamp
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	waves := m.waves[event.SessionID]
+	if len(waves) == 0 {
+		return
+	}
+	wave := waves[len(waves)-1]
+	if wave.Completed || !wave.counts(receivedAt, reactionType) {
+		return
+	}
+	wave.Reactions++
+	if event.UserID != "" && !wave.participants[event.UserID] {
+		wave.participants[event.UserID] = true
+		wave.Participants++
+	}
+}
+
+// Get returns a copy of a session's wave
+func (m *Manager) Get(sessionID, waveID string) (Wave, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, wave := range m.waves[sessionID] {
+		if wave.ID == waveID {
+			return *wave, true
+		}
+	}
+	return Wave{}, false
+}
+
+// List returns copies of a session's recent waves, oldest first
+func (m *Manager) List(sessionID string) []Wave {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	waves := make([]Wave, len(m.waves[sessionID]))
+	for i, wave := range m.waves[sessionID] {
+		waves[i] = *wave
+	}
+	return waves
+}
+
+// Remove forgets an ended session's waves
+func (m *Manager) Remove(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.waves, sessionID)
+}
@@ -0,0 +1,123 @@
+package waves
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jrudman25/livepulse/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reaction(sessionID, userID string, reactionType events.ReactionType, at time.Time) *events.Event {
+	event := events.NewEvent(events.EventTypeReaction, sessionID, userID, &events.ReactionPayload{ReactionType: reactionType})
+	event.ReceivedAt = at
+	return event
+}
+
+func TestManager_ScheduleValidatesAndAllowsOneWaveAtATime(t *testing.T) {
+	manager := NewManager(func(string) int { return 0 }, nil)
+
+	_, err := manager.Schedule("s1", "", 100*time.Millisecond, DefaultWindow)
+	assert.Error(t, err, "delay too short")
+	_, err = manager.Schedule("s1", "", DefaultDelay, time.Minute)
+	assert.Error(t, err, "window too long")
+	_, err = manager.Schedule("s1", "shrug", DefaultDelay, DefaultWindow)
+	assert.Error(t, err, "unknown reaction")
+
+	wave, err := manager.Schedule("s1", events.ReactionFire, DefaultDelay, DefaultWindow)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultDelay), wave.At, time.Second)
+
+	_, err = manager.Schedule("s1", "", DefaultDelay, DefaultWindow)
+	assert.ErrorIs(t, err, ErrInProgress)
+	_, err = manager.Schedule("s2", "", DefaultDelay, DefaultWindow)
+	assert.NoError(t, err, "waves are per session")
+}
+
+func TestManager_MeasuresParticipationInTheSyncWindow(t *testing.T) {
+	completed := make(chan Wave, 1)
+	manager := NewManager(func(string) int { return 4 }, func(wave Wave) { completed <- wave })
+	scheduled, err := manager.Schedule("s1", events.ReactionCheer, DefaultDelay, DefaultWindow)
+	require.NoError(t, err)
+	wave := manager.waves["s1"][0]
+	at := scheduled.At
+
+	manager.Observe(reaction("s1", "early", events.ReactionCheer, at.Add(-time.Millisecond)))
+	manager.Observe(reaction("s1", "u1", events.ReactionCheer, at))
+	manager.Observe(reaction("s1", "u1", events.ReactionCheer, at.Add(time.Second)))
+	manager.Observe(reaction("s1", "u2", events.ReactionCheer, at.Add(DefaultWindow-time.Millisecond)))
+	manager.Observe(reaction("s1", "u3", events.ReactionFire, at.Add(time.Second)))
+	manager.Observe(reaction("s1", "late", events.ReactionCheer, at.Add(DefaultWindow)))
+	manager.Observe(reaction("s2", "u4", events.ReactionCheer, at.Add(time.Second)))
+
+	manager.open(wave)
+	manager.complete(wave)
+	select {
+	case result := <-completed:
+		assert.True(t, result.Completed)
+		assert.Equal(t, 4, result.Audience)
+		assert.Equal(t, 2, result.Participants, "u1 counts once; other reaction types and late reactions do not count")
+		assert.Equal(t, int64(3), result.Reactions)
+		assert.Equal(t, 50.0, result.Participation)
+	case <-time.After(time.Second):
+		t.Fatal("wave result was not reported")
+	}
+
+	manager.Observe(reaction("s1", "u5", events.ReactionCheer, at.Add(time.Second)))
+	stored, exists := manager.Get("s1", scheduled.ID)
+	require.True(t, exists)
+	assert.Equal(t, 2, stored.Participants, "completed waves stop counting")
+	assert.Len(t, manager.List("s1"), 1)
+
+	manager.Remove("s1")
+	assert.Empty(t, manager.List("s1"))
+}
+
+func TestManager_CapsParticipationAtTheAudience(t *testing.T) {
+	manager := NewManager(func(string) int { return 1 }, nil)
+	scheduled, err := manager.Schedule("s1", "", DefaultDelay, DefaultWindow)
+	require.NoError(t, err)
+	wave := manager.waves["s1"][0]
+
+	manager.open(wave)
+	manager.Observe(reaction("s1", "u1", events.ReactionLike, scheduled.At))
+	manager.Observe(reaction("s1", "joined-late", events.ReactionFire, scheduled.At))
+	manager.complete(wave)
+
+	result, _ := manager.Get("s1", scheduled.ID)
+	assert.Equal(t, 2, result.Participants)
+	assert.Equal(t, 100.0, result.Participation)
+}
+
+func TestManager_CountsQueuedReactionsByWhenTheyWereReceived(t *testing.T) {
+	manager := NewManager(func(string) int { return 2 }, nil)
+	scheduled, err := manager.Schedule("s1", "", DefaultDelay, DefaultWindow)
+	require.NoError(t, err)
+	wave := manager.waves["s1"][0]
+	manager.open(wave)
+
+	// Processed once the window has closed, but received inside it
+	manager.Observe(reaction("s1", "u1", events.ReactionFire, scheduled.At.Add(DefaultWindow/2)))
+	manager.complete(wave)
+
+	result, _ := manager.Get("s1", scheduled.ID)
+	assert.Equal(t, 1, result.Participants)
+	assert.Equal(t, 2.0, result.WindowSeconds)
+}
+
+func TestManager_CancelForgetsAPendingWave(t *testing.T) {
+	completed := make(chan Wave, 1)
+	manager := NewManager(func(string) int { return 0 }, func(wave Wave) { completed <- wave })
+	scheduled, err := manager.Schedule("s1", "", DefaultDelay, DefaultWindow)
+	require.NoError(t, err)
+
+	manager.Cancel("s1", scheduled.ID)
+	assert.Empty(t, manager.List("s1"))
+	_, err = manager.Schedule("s1", "", DefaultDelay, DefaultWindow)
+	assert.NoError(t, err, "a cancelled wave does not block the next")
+	for _, wave := range manager.waves["s1"] {
+		assert.NotEqual(t, scheduled.ID, wave.ID)
+	}
+	assert.Empty(t, completed)
+}
@@ -10,11 +10,23 @@ export type ChatMessage = {
   timestamp: string;
 };
 
+export type SessionFeatures = {
+  polls_disabled?: boolean;
+  chat_disabled?: boolean;
+  leaderboard_visible?: boolean;
+  anonymous_reactions?: boolean;
+};
+
 export type WSEvent = 
   | { type: "chat"; message: ChatMessage }
-  | { type: "reaction"; user_id: string; reaction_type: string; timestamp: string }
-  | { type: "milestone_achieved"; milestone: any; achieved_at: string }
-  | { type: "error"; message: string };
+  | { type: "stats_update"; snapshot: any; reaction_deltas: Record<string, number>; next_interval_ms: number }
+  | { type: "milestone_achieved"; session_id: string; milestone: any; achieved_at: string; current_value: number; presentation?: any }
+  | { type: "control"; id: string; session_id: string; text: string; suggested_reaction?: string; sent_at: string }
+  | { type: "reaction_rejected"; session_id: string; event_id: string; code: "session_sold_out" | "user_sold_out"; cap: number }
+  | { type: "reaction_cap_reached"; session_id: string; cap: number; reached_at: string }
+  | { type: "features_updated"; session_id: string; features: SessionFeatures; updated_at: string }
+  | { type: "stats_reset"; scope: "all" | "reactions" | "peak_users"; snapshot: any }
+  | { type: "error"; message: string; code?: string };
 
 export function useWebSocket(sessionId: string) {
   const { getToken } = useAuth();